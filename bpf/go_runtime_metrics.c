@@ -0,0 +1,170 @@
+#include "utils.h"
+
+char __license[] SEC("license") = "Dual MIT/GPL";
+
+// Number of buckets of the latency histograms, including the +Inf bucket
+#define GO_RT_BUCKETS 10
+#define MAX_STATS_PIDS 4096
+#define MAX_PENDING_STARTS (1 << 16)
+
+// Each histogram bucket only accounts the observations that are not accounted
+// by the previous buckets. The user space adds them up.
+typedef struct go_rt_histogram {
+    u64 counts[GO_RT_BUCKETS];
+    u64 sum_ns;
+} go_rt_histogram_t;
+
+// Statistics of a Go process since it was instrumented. They are periodically
+// read by the goruntime Collector of the infraolly package.
+typedef struct go_rt_stats {
+    u64 goroutines_created;
+    u64 goroutines_exited;
+    u64 gc_cycles;
+    go_rt_histogram_t stw_pauses;
+    go_rt_histogram_t sched_latency;
+} go_rt_stats_t;
+
+// Start times are keyed by the goroutine address. The stop-the-world pauses
+// are stored with a zero goroutine address.
+typedef struct go_rt_start_key {
+    u64 goroutine;
+    u32 pid;
+    u32 _pad;
+} go_rt_start_key_t;
+
+const go_rt_stats_t *unused_1 __attribute__((unused));
+
+// Upper bounds of the histogram buckets, excluding the +Inf bucket. Provided by the user space.
+volatile const u64 bucket_bounds_ns[GO_RT_BUCKETS - 1];
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, u32); // pid
+    __type(value, go_rt_stats_t);
+    __uint(max_entries, MAX_STATS_PIDS);
+    __uint(pinning, LIBBPF_PIN_BY_NAME);
+} go_rt_stats SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, go_rt_start_key_t);
+    __type(value, u64); // start time
+    __uint(max_entries, MAX_PENDING_STARTS);
+} go_rt_starts SEC(".maps");
+
+static __always_inline go_rt_stats_t *current_stats() {
+    u32 pid = bpf_get_current_pid_tgid() >> 32;
+    go_rt_stats_t *stats = bpf_map_lookup_elem(&go_rt_stats, &pid);
+    if (stats) {
+        return stats;
+    }
+    go_rt_stats_t empty = {0};
+    bpf_map_update_elem(&go_rt_stats, &pid, &empty, BPF_NOEXIST);
+    return bpf_map_lookup_elem(&go_rt_stats, &pid);
+}
+
+static __always_inline void store_start(u64 goroutine) {
+    go_rt_start_key_t key = {
+        .goroutine = goroutine,
+        .pid = bpf_get_current_pid_tgid() >> 32,
+    };
+    u64 now = bpf_ktime_get_ns();
+    bpf_map_update_elem(&go_rt_starts, &key, &now, BPF_ANY);
+}
+
+// Returns the time passed since the start of the given goroutine (or stop-the-world pause), and forgets it.
+// Returns false if the start is unknown.
+static __always_inline bool since_start(u64 goroutine, u64 *elapsed) {
+    go_rt_start_key_t key = {
+        .goroutine = goroutine,
+        .pid = bpf_get_current_pid_tgid() >> 32,
+    };
+    u64 *start = bpf_map_lookup_elem(&go_rt_starts, &key);
+    if (!start) {
+        return false;
+    }
+    *elapsed = bpf_ktime_get_ns() - *start;
+    bpf_map_delete_elem(&go_rt_starts, &key);
+    return true;
+}
+
+static __always_inline void observe(go_rt_histogram_t *h, u64 value) {
+    u32 bucket = GO_RT_BUCKETS - 1;
+    #pragma unroll
+    for (u32 i = 0; i < GO_RT_BUCKETS - 1; i++) {
+        if (value <= bucket_bounds_ns[i]) {
+            bucket = i;
+            break;
+        }
+    }
+    __sync_fetch_and_add(&h->counts[bucket], 1);
+    __sync_fetch_and_add(&h->sum_ns, value);
+}
+
+SEC("uprobe/runtime_newproc1")
+int uprobe_rt_newproc(struct pt_regs *ctx) {
+    go_rt_stats_t *stats = current_stats();
+    if (stats) {
+        __sync_fetch_and_add(&stats->goroutines_created, 1);
+    }
+    return 0;
+}
+
+SEC("uprobe/runtime_goexit1")
+int uprobe_rt_goexit(struct pt_regs *ctx) {
+    go_rt_stats_t *stats = current_stats();
+    if (stats) {
+        __sync_fetch_and_add(&stats->goroutines_exited, 1);
+    }
+    return 0;
+}
+
+// GC cycles are accounted at mark termination, as runtime.gcStart might return without starting a new cycle
+SEC("uprobe/runtime_gcMarkTermination")
+int uprobe_rt_gc_mark_term(struct pt_regs *ctx) {
+    go_rt_stats_t *stats = current_stats();
+    if (stats) {
+        __sync_fetch_and_add(&stats->gc_cycles, 1);
+    }
+    return 0;
+}
+
+SEC("uprobe/runtime_stopTheWorldWithSema")
+int uprobe_rt_stw_start(struct pt_regs *ctx) {
+    store_start(0);
+    return 0;
+}
+
+SEC("uprobe/runtime_startTheWorldWithSema")
+int uprobe_rt_stw_end(struct pt_regs *ctx) {
+    u64 elapsed = 0;
+    if (!since_start(0, &elapsed)) {
+        return 0;
+    }
+    go_rt_stats_t *stats = current_stats();
+    if (stats) {
+        observe(&stats->stw_pauses, elapsed);
+    }
+    return 0;
+}
+
+// func runqput(pp *p, gp *g, next bool)
+SEC("uprobe/runtime_runqput")
+int uprobe_rt_runqput(struct pt_regs *ctx) {
+    store_start((u64)GO_PARAM2(ctx));
+    return 0;
+}
+
+// func execute(gp *g, inheritTime bool)
+SEC("uprobe/runtime_execute")
+int uprobe_rt_execute(struct pt_regs *ctx) {
+    u64 elapsed = 0;
+    if (!since_start((u64)GO_PARAM1(ctx), &elapsed)) {
+        return 0;
+    }
+    go_rt_stats_t *stats = current_stats();
+    if (stats) {
+        observe(&stats->sched_latency, elapsed);
+    }
+    return 0;
+}
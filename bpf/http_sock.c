@@ -7,6 +7,7 @@
 #include "tcp_info.h"
 #include "http_sock.h"
 #include "http_ssl.h"
+#include "protocol_dns.h"
 
 char __license[] SEC("license") = "Dual MIT/GPL";
 
@@ -39,6 +40,14 @@ struct {
     __type(value, recv_args_t);
 } active_recv_args SEC(".maps");
 
+// Temporary tracking of udp_recvmsg arguments
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __uint(max_entries, MAX_CONCURRENT_REQUESTS);
+    __type(key, u64);
+    __type(value, recv_args_t);
+} active_udp_recv_args SEC(".maps");
+
 typedef struct send_args {
     pid_connection_info_t p_conn;
     u64 size;
@@ -616,6 +625,62 @@ int BPF_KRETPROBE(kretprobe_sys_clone, int tid) {
     return 0;
 }
 
+// The DNS queries are sent with udp_sendmsg or udpv6_sendmsg, which have the same signature.
+// We only track the messages sent to the DNS port.
+SEC("kprobe/udp_sendmsg")
+int BPF_KPROBE(kprobe_udp_sendmsg, struct sock *sk, struct msghdr *msg, size_t size) {
+    u64 id = bpf_get_current_pid_tgid();
+
+    if (!valid_pid(id)) {
+        return 0;
+    }
+
+    bpf_dbg_printk("=== kprobe udp_sendmsg=%d sock=%llx size %d===", id, sk, size);
+
+    handle_dns_query(sk, msg, size);
+
+    return 0;
+}
+
+//int udp_recvmsg(struct sock *sk, struct msghdr *msg, size_t len, int flags, int *addr_len)
+SEC("kprobe/udp_recvmsg")
+int BPF_KPROBE(kprobe_udp_recvmsg, struct sock *sk, struct msghdr *msg) {
+    u64 id = bpf_get_current_pid_tgid();
+
+    if (!valid_pid(id)) {
+        return 0;
+    }
+
+    recv_args_t args = {
+        .sock_ptr = (u64)sk,
+        .iovec_ptr = (u64)(msg)
+    };
+
+    bpf_map_update_elem(&active_udp_recv_args, &id, &args, BPF_ANY);
+
+    return 0;
+}
+
+SEC("kretprobe/udp_recvmsg")
+int BPF_KRETPROBE(kretprobe_udp_recvmsg, int copied_len) {
+    u64 id = bpf_get_current_pid_tgid();
+
+    if (!valid_pid(id)) {
+        return 0;
+    }
+
+    recv_args_t *args = bpf_map_lookup_elem(&active_udp_recv_args, &id);
+
+    if (args && args->iovec_ptr && copied_len > 0) {
+        bpf_dbg_printk("=== udp_recvmsg ret id=%d sock=%llx copied_len %d ===", id, args->sock_ptr, copied_len);
+        handle_dns_response((struct sock *)args->sock_ptr, (struct msghdr *)args->iovec_ptr, copied_len);
+    }
+
+    bpf_map_delete_elem(&active_udp_recv_args, &id);
+
+    return 0;
+}
+
 SEC("kprobe/sys_exit")
 int BPF_KPROBE(kprobe_sys_exit, int status) {
     u64 id = bpf_get_current_pid_tgid();
@@ -17,6 +17,9 @@
 
 #define K_TCP_MAX_LEN 256
 #define K_TCP_RES_LEN 128
+// dns_req_t must not exceed 512 bytes, which is the maximum size supported by bpf_memset and bpf_memcpy
+#define K_DNS_MAX_LEN 128
+#define K_DNS_RES_LEN 256
 
 #define CONN_INFO_FLAG_TRACE 0x1
 
@@ -119,6 +122,28 @@ typedef struct tcp_req {
     tp_info_t tp;
 } tcp_req_t;
 
+// DNS queries over UDP, with their responses
+typedef struct dns_req {
+    u8 flags; // Must be fist we use it to tell what kind of packet we have on the ring buffer
+    connection_info_t conn_info;
+    u64 start_monotime_ns;
+    u64 end_monotime_ns;
+    unsigned char buf[K_DNS_MAX_LEN] __attribute__ ((aligned (8))); // ringbuffer memcpy complains unless this is 8 byte aligned
+    unsigned char rbuf[K_DNS_RES_LEN] __attribute__ ((aligned (8))); // ringbuffer memcpy complains unless this is 8 byte aligned
+    u32 len;
+    u32 resp_len;
+    pid_info pid;
+    tp_info_t tp;
+} dns_req_t;
+
+// DNS queries are matched to their responses by socket and query identifier
+typedef struct dns_req_key {
+    u64 sock_ptr;
+    u32 pid;
+    u16 id;
+    u8  _pad[2];
+} dns_req_key_t;
+
 typedef struct call_protocol_args {
     pid_connection_info_t pid_conn;
     unsigned char small_buf[MIN_HTTP2_SIZE];
@@ -0,0 +1,113 @@
+#include "vmlinux.h"
+#include "bpf_helpers.h"
+#include "bpf_core_read.h"
+
+char __license[] SEC("license") = "Dual MIT/GPL";
+
+#define ETH_P_IPV4 0x0800
+#define ETH_P_IPV6 0x86DD
+#define IP_PROTO_TCP 6
+#define IP_PROTO_UDP 17
+#define IP_PROTO_SCTP 132
+#define NETWORK_HEADER_UNSET 0xFFFF
+
+// Flow and reason of the dropped packets. IPv4 addresses are mapped into IPv6.
+// Ports are stored in network byte order.
+typedef struct drop_key {
+    u8 src_ip[16];
+    u8 dst_ip[16];
+    u16 src_port;
+    u16 dst_port;
+    u8 protocol;
+    u8 _pad[3];
+    u32 reason;
+} drop_key_t;
+
+typedef struct drop_value {
+    u64 packets;
+    u64 bytes;
+} drop_value_t;
+
+const drop_key_t *unused_1 __attribute__((unused));
+const drop_value_t *unused_2 __attribute__((unused));
+
+// Lowest drop reason code, as reported by the tracepoint format. Lower codes (e.g. SKB_CONSUMED)
+// don't represent dropped packets. Provided by the user space.
+volatile const u32 min_reason;
+
+// The max_entries is overridden by the user space, from the cache size of the network configuration
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, drop_key_t);
+    __type(value, drop_value_t);
+    __uint(max_entries, 1 << 10);
+} net_drops SEC(".maps");
+
+// Accounts the dropped packets and bytes, indexed by flow and drop reason
+SEC("tracepoint/skb/kfree_skb")
+int tp_kfree_skb(struct trace_event_raw_kfree_skb *ctx) {
+    u32 reason = ctx->reason;
+    if (reason < min_reason) {
+        return 0;
+    }
+    drop_key_t key = {.reason = reason};
+    u16 eth_protocol = ctx->protocol;
+
+    struct sk_buff *skb = (struct sk_buff *)ctx->skbaddr;
+    unsigned char *head = BPF_CORE_READ(skb, head);
+    u16 network_header = BPF_CORE_READ(skb, network_header);
+    u32 len = BPF_CORE_READ(skb, len);
+    if (network_header == NETWORK_HEADER_UNSET) {
+        return 0;
+    }
+    unsigned char *hdr = head + network_header;
+
+    if (eth_protocol == ETH_P_IPV4) {
+        struct iphdr ip;
+        if (bpf_probe_read_kernel(&ip, sizeof(ip), hdr)) {
+            return 0;
+        }
+        key.protocol = ip.protocol;
+        key.src_ip[10] = 0xFF;
+        key.src_ip[11] = 0xFF;
+        key.dst_ip[10] = 0xFF;
+        key.dst_ip[11] = 0xFF;
+        __builtin_memcpy(&key.src_ip[12], &ip.saddr, sizeof(ip.saddr));
+        __builtin_memcpy(&key.dst_ip[12], &ip.daddr, sizeof(ip.daddr));
+        // the header length is stored in 32-bit words. Access ihl as a u8 (linux/include/linux/skbuff.h)
+        hdr += (*(u8 *)&ip & 0x0F) * 4;
+    } else if (eth_protocol == ETH_P_IPV6) {
+        // extension headers are not parsed, so the ports are only read when the
+        // transport header follows the fixed header
+        struct ipv6hdr ip;
+        if (bpf_probe_read_kernel(&ip, sizeof(ip), hdr)) {
+            return 0;
+        }
+        key.protocol = ip.nexthdr;
+        __builtin_memcpy(key.src_ip, &ip.saddr, sizeof(key.src_ip));
+        __builtin_memcpy(key.dst_ip, &ip.daddr, sizeof(key.dst_ip));
+        hdr += sizeof(ip);
+    } else {
+        return 0;
+    }
+
+    // the source and destination ports are at the start of the TCP, UDP and SCTP headers
+    if (key.protocol == IP_PROTO_TCP || key.protocol == IP_PROTO_UDP ||
+        key.protocol == IP_PROTO_SCTP) {
+        u16 ports[2];
+        if (!bpf_probe_read_kernel(ports, sizeof(ports), hdr)) {
+            key.src_port = ports[0];
+            key.dst_port = ports[1];
+        }
+    }
+
+    drop_value_t *value = bpf_map_lookup_elem(&net_drops, &key);
+    if (value) {
+        __sync_fetch_and_add(&value->packets, 1);
+        __sync_fetch_and_add(&value->bytes, len);
+        return 0;
+    }
+    drop_value_t new_value = {.packets = 1, .bytes = len};
+    bpf_map_update_elem(&net_drops, &key, &new_value, BPF_NOEXIST);
+    return 0;
+}
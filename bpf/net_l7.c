@@ -0,0 +1,110 @@
+#include "vmlinux.h"
+#include "bpf_helpers.h"
+#include "bpf_endian.h"
+
+char __license[] SEC("license") = "Dual MIT/GPL";
+
+#define ETH_P_IPV4 0x0800
+#define ETH_P_IPV6 0x86DD
+#define IP_PROTO_TCP 6
+#define ETH_HEADER_LEN 14
+#define IP_FRAG_OFFSET_MASK 0x1FFF
+
+// Maximum number of payload bytes that are captured from the first packet of each connection
+// direction. Must be kept in sync with L7SamplePayloadLen in l7_spec.go
+#define L7_SAMPLE_PAYLOAD_LEN 1024
+
+// Connection direction. IPv4 addresses are mapped into IPv6. Ports are stored in network byte order.
+typedef struct l7_key {
+    u8 src_ip[16];
+    u8 dst_ip[16];
+    u16 src_port;
+    u16 dst_port;
+} l7_key_t;
+
+const l7_key_t *unused_1 __attribute__((unused));
+
+// Connection directions whose first payload has been already sampled.
+// The max_entries is overridden by the user space, from the cache size of the network configuration
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, l7_key_t);
+    __type(value, u8);
+    __uint(max_entries, 1 << 10);
+} net_l7_seen SEC(".maps");
+
+// Captures the Ethernet, IP and TCP headers, plus the first L7_SAMPLE_PAYLOAD_LEN payload bytes,
+// of the first TCP packet with payload that is sent in each direction of a connection. The rest
+// of packets are discarded. Each connection direction is remembered in an LRU map, so it is
+// captured only once even if the packet is seen from multiple interfaces.
+SEC("socket")
+int sf_l7_sample(struct __sk_buff *skb) {
+    struct ethhdr eth;
+    if (bpf_skb_load_bytes(skb, 0, &eth, sizeof(eth))) {
+        return 0;
+    }
+    l7_key_t key = {};
+    // offset of the TCP header, and end of the IP packet, as Ethernet frames might be padded
+    u32 tcp_start, ip_end;
+
+    if (eth.h_proto == bpf_htons(ETH_P_IPV4)) {
+        struct iphdr ip;
+        if (bpf_skb_load_bytes(skb, ETH_HEADER_LEN, &ip, sizeof(ip))) {
+            return 0;
+        }
+        // fragments other than the first one don't have TCP header
+        if (ip.protocol != IP_PROTO_TCP ||
+            (bpf_ntohs(ip.frag_off) & IP_FRAG_OFFSET_MASK) != 0) {
+            return 0;
+        }
+        key.src_ip[10] = 0xFF;
+        key.src_ip[11] = 0xFF;
+        key.dst_ip[10] = 0xFF;
+        key.dst_ip[11] = 0xFF;
+        __builtin_memcpy(&key.src_ip[12], &ip.saddr, sizeof(ip.saddr));
+        __builtin_memcpy(&key.dst_ip[12], &ip.daddr, sizeof(ip.daddr));
+        // the header length is stored in 32-bit words. Access ihl as a u8 (linux/include/linux/skbuff.h)
+        tcp_start = ETH_HEADER_LEN + (*(u8 *)&ip & 0x0F) * 4;
+        ip_end = ETH_HEADER_LEN + bpf_ntohs(ip.tot_len);
+    } else if (eth.h_proto == bpf_htons(ETH_P_IPV6)) {
+        // extension headers are not parsed, so only the packets whose TCP header
+        // follows the fixed header are sampled
+        struct ipv6hdr ip;
+        if (bpf_skb_load_bytes(skb, ETH_HEADER_LEN, &ip, sizeof(ip))) {
+            return 0;
+        }
+        if (ip.nexthdr != IP_PROTO_TCP) {
+            return 0;
+        }
+        __builtin_memcpy(key.src_ip, &ip.saddr, sizeof(key.src_ip));
+        __builtin_memcpy(key.dst_ip, &ip.daddr, sizeof(key.dst_ip));
+        tcp_start = ETH_HEADER_LEN + sizeof(ip);
+        ip_end = tcp_start + bpf_ntohs(ip.payload_len);
+    } else {
+        return 0;
+    }
+
+    struct tcphdr tcp;
+    if (bpf_skb_load_bytes(skb, tcp_start, &tcp, sizeof(tcp))) {
+        return 0;
+    }
+    key.src_port = tcp.source;
+    key.dst_port = tcp.dest;
+    // the TCP header length is stored in 32-bit words. Access doff as the upper
+    // nibble of the byte that follows the acknowledgement number
+    u32 payload_start = tcp_start + (((u8 *)&tcp)[12] >> 4) * 4;
+    if (payload_start >= ip_end) {
+        return 0;
+    }
+
+    // only the first packet with payload of each connection direction is sampled
+    if (bpf_map_lookup_elem(&net_l7_seen, &key)) {
+        return 0;
+    }
+    u8 seen = 1;
+    // the connection direction might have been sampled concurrently from another CPU
+    if (bpf_map_update_elem(&net_l7_seen, &key, &seen, BPF_NOEXIST)) {
+        return 0;
+    }
+    return payload_start + L7_SAMPLE_PAYLOAD_LEN;
+}
@@ -0,0 +1,61 @@
+#include "vmlinux.h"
+#include "bpf_helpers.h"
+
+char __license[] SEC("license") = "Dual MIT/GPL";
+
+// maximum depth of the sampled user and kernel stacks
+#define MAX_STACK_DEPTH 127
+#define MAX_PROFILED_PIDS 4096
+
+typedef struct profile_sample {
+    u32 pid;
+    u32 tid;
+    u64 time; // from the same clock as the spans
+    s32 user_len; // length in bytes of the user stack. Negative if it couldn't be read
+    s32 kernel_len; // length in bytes of the kernel stack. Negative if it couldn't be read
+    u64 user_stack[MAX_STACK_DEPTH];
+    u64 kernel_stack[MAX_STACK_DEPTH];
+} profile_sample_t;
+
+const profile_sample_t *unused_1 __attribute__((unused));
+
+// if true, only the processes in the profiled_pids map are sampled
+volatile const u32 filter_pids;
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, u32); // tgid
+    __type(value, u32);
+    __uint(max_entries, MAX_PROFILED_PIDS);
+} profiled_pids SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_RINGBUF);
+    __uint(max_entries, 1 << 22);
+} samples SEC(".maps");
+
+// Triggered by a CPU clock perf event in each CPU. It submits the user and kernel stacks of the running thread.
+SEC("perf_event")
+int beyla_profile(void *ctx) {
+    u64 id = bpf_get_current_pid_tgid();
+    u32 tgid = id >> 32;
+
+    if (filter_pids && !bpf_map_lookup_elem(&profiled_pids, &tgid)) {
+        return 0;
+    }
+
+    profile_sample_t *sample = bpf_ringbuf_reserve(&samples, sizeof(profile_sample_t), 0);
+    if (!sample) {
+        return 0;
+    }
+
+    sample->pid = tgid;
+    sample->tid = (u32)id;
+    sample->time = bpf_ktime_get_ns();
+    sample->user_len = bpf_get_stack(ctx, sample->user_stack, sizeof(sample->user_stack), BPF_F_USER_STACK);
+    sample->kernel_len = bpf_get_stack(ctx, sample->kernel_stack, sizeof(sample->kernel_stack), 0);
+
+    bpf_ringbuf_submit(sample, 0);
+
+    return 0;
+}
@@ -0,0 +1,164 @@
+#ifndef PROTOCOL_DNS
+#define PROTOCOL_DNS
+
+#include "vmlinux.h"
+#include "bpf_helpers.h"
+#include "bpf_builtins.h"
+#include "http_types.h"
+#include "ringbuf.h"
+#include "pid.h"
+#include "sockaddr.h"
+#include "protocol_common.h"
+#include "trace_common.h"
+
+#define DNS_PORT 53
+#define DNS_HEADER_LEN 12
+
+// Keeps track of the DNS queries that are waiting for a response
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, dns_req_key_t);
+    __type(value, dns_req_t);
+    __uint(max_entries, MAX_CONCURRENT_SHARED_REQUESTS);
+} ongoing_dns_req SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+    __type(key, int);
+    __type(value, dns_req_t);
+    __uint(max_entries, 1);
+} dns_req_mem SEC(".maps");
+
+static __always_inline dns_req_t* empty_dns_req() {
+    int zero = 0;
+    dns_req_t *value = bpf_map_lookup_elem(&dns_req_mem, &zero);
+    if (value) {
+        bpf_memset(value, 0, sizeof(dns_req_t));
+    }
+    return value;
+}
+
+static __always_inline u16 dns_id(u8 *buf) {
+    return ((u16)buf[0] << 8) | buf[1];
+}
+
+// The destination of a UDP message is provided either in the msg_name of the message
+// (e.g. sendto), or by the socket itself, if it was previously connected.
+static __always_inline u16 udp_dest_port(struct sock *sk, struct msghdr *msg) {
+    void *msg_name = 0;
+    BPF_CORE_READ_INTO(&msg_name, msg, msg_name);
+    if (msg_name) {
+        return get_sockaddr_port((struct sockaddr *)msg_name);
+    }
+    u16 dport = 0;
+    BPF_CORE_READ_INTO(&dport, sk, __sk_common.skc_dport);
+    return bpf_ntohs(dport);
+}
+
+static __always_inline void udp_dest_info(struct sock *sk, struct msghdr *msg, connection_info_t *info) {
+    parse_sock_info(sk, info);
+
+    void *msg_name = 0;
+    BPF_CORE_READ_INTO(&msg_name, msg, msg_name);
+    if (!msg_name) {
+        return;
+    }
+    short unsigned int sa_family = 0;
+    BPF_CORE_READ_INTO(&sa_family, (struct sockaddr *)msg_name, sa_family);
+    if (sa_family == AF_INET) {
+        u32 ip4_d_l = 0;
+        BPF_CORE_READ_INTO(&ip4_d_l, (struct sockaddr_in *)msg_name, sin_addr.s_addr);
+        bpf_memcpy(info->d_addr, ip4ip6_prefix, sizeof(ip4ip6_prefix));
+        bpf_memcpy(info->d_addr + sizeof(ip4ip6_prefix), &ip4_d_l, sizeof(ip4_d_l));
+    } else if (sa_family == AF_INET6) {
+        BPF_CORE_READ_INTO(&info->d_addr, (struct sockaddr_in6 *)msg_name, sin6_addr.in6_u.u6_addr8);
+    }
+    info->d_port = get_sockaddr_port((struct sockaddr *)msg_name);
+}
+
+// Stores the DNS query until its response is received
+static __always_inline void handle_dns_query(struct sock *sk, struct msghdr *msg, size_t size) {
+    if (size < DNS_HEADER_LEN || udp_dest_port(sk, msg) != DNS_PORT) {
+        return;
+    }
+
+    u8* buf = iovec_memory();
+    if (!buf) {
+        return;
+    }
+    int len = read_msghdr_buf(msg, buf, size);
+    if (len < DNS_HEADER_LEN) {
+        return;
+    }
+
+    dns_req_t *req = empty_dns_req();
+    if (!req) {
+        return;
+    }
+
+    u64 id = bpf_get_current_pid_tgid();
+    dns_req_key_t key = {
+        .sock_ptr = (u64)sk,
+        .pid = pid_from_pid_tgid(id),
+        .id = dns_id(buf),
+    };
+
+    req->flags = EVENT_DNS_REQUEST;
+    req->start_monotime_ns = bpf_ktime_get_ns();
+    req->len = size;
+    udp_dest_info(sk, msg, &req->conn_info);
+    task_pid(&req->pid);
+    bpf_probe_read(req->buf, K_DNS_MAX_LEN, buf);
+
+    tp_info_pid_t *server_tp = find_parent_trace();
+    if (server_tp && server_tp->valid) {
+        bpf_memcpy(req->tp.trace_id, server_tp->tp.trace_id, sizeof(req->tp.trace_id));
+        bpf_memcpy(req->tp.parent_id, server_tp->tp.span_id, sizeof(req->tp.parent_id));
+        urand_bytes(req->tp.span_id, SPAN_ID_SIZE_BYTES);
+    }
+
+    bpf_dbg_printk("Tracking DNS query id=%d, sock=%llx", key.id, sk);
+    bpf_map_update_elem(&ongoing_dns_req, &key, req, BPF_ANY);
+}
+
+// Submits the DNS query together with its response
+static __always_inline void handle_dns_response(struct sock *sk, struct msghdr *msg, int copied_len) {
+    if (copied_len < DNS_HEADER_LEN) {
+        return;
+    }
+
+    u8* buf = iovec_memory();
+    if (!buf) {
+        return;
+    }
+    int len = read_msghdr_buf(msg, buf, copied_len);
+    if (len < DNS_HEADER_LEN) {
+        return;
+    }
+
+    u64 id = bpf_get_current_pid_tgid();
+    dns_req_key_t key = {
+        .sock_ptr = (u64)sk,
+        .pid = pid_from_pid_tgid(id),
+        .id = dns_id(buf),
+    };
+
+    dns_req_t *existing = bpf_map_lookup_elem(&ongoing_dns_req, &key);
+    if (!existing) {
+        return;
+    }
+
+    existing->end_monotime_ns = bpf_ktime_get_ns();
+    existing->resp_len = copied_len;
+    dns_req_t *trace = bpf_ringbuf_reserve(&events, sizeof(dns_req_t), 0);
+    if (trace) {
+        bpf_dbg_printk("Sending DNS trace id=%d, response length %d", key.id, copied_len);
+
+        bpf_memcpy(trace, existing, sizeof(dns_req_t));
+        bpf_probe_read(trace->rbuf, K_DNS_RES_LEN, buf);
+        bpf_ringbuf_submit(trace, get_flags());
+    }
+    bpf_map_delete_elem(&ongoing_dns_req, &key);
+}
+
+#endif
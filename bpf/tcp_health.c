@@ -0,0 +1,315 @@
+#include "vmlinux.h"
+#include "bpf_helpers.h"
+#include "bpf_tracing.h"
+#include "bpf_core_read.h"
+
+char __license[] SEC("license") = "Dual MIT/GPL";
+
+// Number of buckets of the duration histograms, including the +Inf bucket
+#define TCP_HEALTH_BUCKETS 10
+#define MAX_HEALTH_PIDS 4096
+#define MAX_HEALTH_SOCKETS (1 << 16)
+#define MAX_STATS_GROUPS (1 << 14)
+
+#define IP_PROTO_TCP 6
+
+// TCP states, as defined in include/net/tcp_states.h
+#define TCP_STATE_ESTABLISHED 1
+#define TCP_STATE_SYN_SENT 2
+#define TCP_STATE_SYN_RECV 3
+#define TCP_STATE_CLOSE 7
+
+#define ROLE_CLIENT 0
+#define ROLE_SERVER 1
+
+// Groups the connections of a process by role and peer. The port is always the port of the
+// server side (the remote port for clients, the local port for servers), to avoid creating a
+// group for each ephemeral port.
+typedef struct tcp_health_key {
+    u32 pid; // zero for the server connections that haven't been accepted yet
+    u16 port;
+    u8 role;
+    u8 _pad;
+    u8 addr[16]; // IPv4 addresses are mapped into IPv6
+} tcp_health_key_t;
+
+// Each histogram bucket only accounts the observations that are not accounted
+// by the previous buckets. The user space adds them up.
+typedef struct tcp_health_histogram {
+    u64 counts[TCP_HEALTH_BUCKETS];
+    u64 sum_ns;
+} tcp_health_histogram_t;
+
+// Statistics of a group of connections. They are periodically read by the
+// tcphealth Collector of the infraolly package.
+typedef struct tcp_health_stats {
+    u64 retransmits;
+    u64 resets_received;
+    u64 resets_sent;
+    u64 failures_refused;
+    u64 failures_timeout;
+    tcp_health_histogram_t setup_duration;
+    tcp_health_histogram_t rtt;
+} tcp_health_stats_t;
+
+const tcp_health_key_t *unused_1 __attribute__((unused));
+const tcp_health_stats_t *unused_2 __attribute__((unused));
+
+// If true, the connections of all the processes are accounted, regardless of tcp_health_pids
+volatile const u8 system_wide;
+// Upper bounds of the histogram buckets, excluding the +Inf bucket. Provided by the user space.
+volatile const u64 bucket_bounds_ns[TCP_HEALTH_BUCKETS - 1];
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, u32); // pid
+    __type(value, u32);
+    __uint(max_entries, MAX_HEALTH_PIDS);
+} tcp_health_pids SEC(".maps");
+
+// stats group of the accounted sockets
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, u64); // struct sock address
+    __type(value, tcp_health_key_t);
+    __uint(max_entries, MAX_HEALTH_SOCKETS);
+} tcp_health_socks SEC(".maps");
+
+// server connections that have been established but not accepted yet
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, u64); // struct sock address
+    __type(value, tcp_health_key_t);
+    __uint(max_entries, MAX_HEALTH_SOCKETS);
+} tcp_health_pending SEC(".maps");
+
+// client connections that are waiting for the SYN-ACK
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, u64); // struct sock address
+    __type(value, u64); // time when the SYN was sent
+    __uint(max_entries, MAX_HEALTH_SOCKETS);
+} tcp_health_connects SEC(".maps");
+
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, tcp_health_key_t);
+    __type(value, tcp_health_stats_t);
+    __uint(max_entries, MAX_STATS_GROUPS);
+    __uint(pinning, LIBBPF_PIN_BY_NAME);
+} tcp_health_stats SEC(".maps");
+
+static __always_inline bool allowed_pid(u32 pid) {
+    return system_wide || bpf_map_lookup_elem(&tcp_health_pids, &pid);
+}
+
+// Returns the stats of the connection group of the given socket, creating them if they
+// don't exist yet. Returns NULL if the socket is not accounted.
+static __always_inline tcp_health_stats_t *sock_stats(u64 sk) {
+    tcp_health_key_t *key = bpf_map_lookup_elem(&tcp_health_socks, &sk);
+    if (!key) {
+        return NULL;
+    }
+    tcp_health_stats_t *stats = bpf_map_lookup_elem(&tcp_health_stats, key);
+    if (stats) {
+        return stats;
+    }
+    tcp_health_stats_t empty = {0};
+    bpf_map_update_elem(&tcp_health_stats, key, &empty, BPF_NOEXIST);
+    return bpf_map_lookup_elem(&tcp_health_stats, key);
+}
+
+static __always_inline void observe(tcp_health_histogram_t *h, u64 value) {
+    u32 bucket = TCP_HEALTH_BUCKETS - 1;
+    #pragma unroll
+    for (u32 i = 0; i < TCP_HEALTH_BUCKETS - 1; i++) {
+        if (value <= bucket_bounds_ns[i]) {
+            bucket = i;
+            break;
+        }
+    }
+    __sync_fetch_and_add(&h->counts[bucket], 1);
+    __sync_fetch_and_add(&h->sum_ns, value);
+}
+
+static __always_inline void forget_sock(u64 sk) {
+    bpf_map_delete_elem(&tcp_health_connects, &sk);
+    bpf_map_delete_elem(&tcp_health_socks, &sk);
+    bpf_map_delete_elem(&tcp_health_pending, &sk);
+}
+
+// Tracks the TCP state transitions:
+//   - a client connection is accounted when the SYN is sent, as it happens in the context of
+//     the process that invokes connect.
+//   - the time between the SYN is sent and the connection is established is recorded as the setup duration.
+//   - a server connection is marked as pending when it is established, until the process accepts it.
+//   - a connection that is closed while waiting for the SYN-ACK is accounted as a failure by timeout,
+//     unless it has already been accounted as refused by the tcp_receive_reset tracepoint.
+SEC("tracepoint/sock/inet_sock_set_state")
+int tp_tcp_set_state(struct trace_event_raw_inet_sock_set_state *ctx) {
+    if (ctx->protocol != IP_PROTO_TCP) {
+        return 0;
+    }
+    u64 sk = (u64)ctx->skaddr;
+    int oldstate = ctx->oldstate;
+    int newstate = ctx->newstate;
+
+    if (newstate == TCP_STATE_SYN_SENT) {
+        u32 pid = bpf_get_current_pid_tgid() >> 32;
+        if (!allowed_pid(pid)) {
+            return 0;
+        }
+        tcp_health_key_t key = {
+            .pid = pid,
+            .port = ctx->dport,
+            .role = ROLE_CLIENT,
+        };
+        bpf_probe_read_kernel(key.addr, sizeof(key.addr), ctx->daddr_v6);
+        bpf_map_update_elem(&tcp_health_socks, &sk, &key, BPF_ANY);
+        u64 now = bpf_ktime_get_ns();
+        bpf_map_update_elem(&tcp_health_connects, &sk, &now, BPF_ANY);
+        return 0;
+    }
+
+    if (newstate == TCP_STATE_CLOSE) {
+        if (oldstate == TCP_STATE_SYN_SENT && bpf_map_lookup_elem(&tcp_health_connects, &sk)) {
+            tcp_health_stats_t *stats = sock_stats(sk);
+            if (stats) {
+                __sync_fetch_and_add(&stats->failures_timeout, 1);
+            }
+        }
+        forget_sock(sk);
+        return 0;
+    }
+
+    if (newstate != TCP_STATE_ESTABLISHED) {
+        return 0;
+    }
+
+    if (oldstate == TCP_STATE_SYN_RECV) {
+        // the PID is unknown until the connection is accepted
+        tcp_health_key_t key = {
+            .port = ctx->sport,
+            .role = ROLE_SERVER,
+        };
+        bpf_probe_read_kernel(key.addr, sizeof(key.addr), ctx->daddr_v6);
+        bpf_map_update_elem(&tcp_health_pending, &sk, &key, BPF_ANY);
+    } else if (oldstate == TCP_STATE_SYN_SENT) {
+        u64 *start = bpf_map_lookup_elem(&tcp_health_connects, &sk);
+        if (!start) {
+            return 0;
+        }
+        u64 elapsed = bpf_ktime_get_ns() - *start;
+        bpf_map_delete_elem(&tcp_health_connects, &sk);
+        tcp_health_stats_t *stats = sock_stats(sk);
+        if (stats) {
+            observe(&stats->setup_duration, elapsed);
+        }
+    }
+    return 0;
+}
+
+// The tcp_retransmit_skb and tcp_send_reset tracepoints shared the tcp_event_sk_skb event class
+// until newer kernels gave each of them its own class. The flavors below match the new classes.
+// The socket is read with BPF_CORE_READ, as the verifier doesn't allow dereferencing the context
+// from a computed offset.
+struct trace_event_raw_tcp_retransmit_skb___new {
+    const void *skaddr;
+} __attribute__((preserve_access_index));
+
+struct trace_event_raw_tcp_send_reset___new {
+    const void *skaddr;
+} __attribute__((preserve_access_index));
+
+SEC("tracepoint/tcp/tcp_retransmit_skb")
+int tp_tcp_retransmit(void *ctx) {
+    u64 sk;
+    if (bpf_core_type_exists(struct trace_event_raw_tcp_retransmit_skb___new)) {
+        sk = (u64)BPF_CORE_READ((struct trace_event_raw_tcp_retransmit_skb___new *)ctx, skaddr);
+    } else {
+        sk = (u64)BPF_CORE_READ((struct trace_event_raw_tcp_event_sk_skb *)ctx, skaddr);
+    }
+    tcp_health_stats_t *stats = sock_stats(sk);
+    if (stats) {
+        __sync_fetch_and_add(&stats->retransmits, 1);
+    }
+    return 0;
+}
+
+SEC("tracepoint/tcp/tcp_send_reset")
+int tp_tcp_send_reset(void *ctx) {
+    u64 sk;
+    if (bpf_core_type_exists(struct trace_event_raw_tcp_send_reset___new)) {
+        sk = (u64)BPF_CORE_READ((struct trace_event_raw_tcp_send_reset___new *)ctx, skaddr);
+    } else {
+        sk = (u64)BPF_CORE_READ((struct trace_event_raw_tcp_event_sk_skb *)ctx, skaddr);
+    }
+    tcp_health_stats_t *stats = sock_stats(sk);
+    if (stats) {
+        __sync_fetch_and_add(&stats->resets_sent, 1);
+    }
+    return 0;
+}
+
+// If the connection was waiting for the SYN-ACK, the reset is accounted as a refused connection
+SEC("tracepoint/tcp/tcp_receive_reset")
+int tp_tcp_receive_reset(struct trace_event_raw_tcp_event_sk *ctx) {
+    u64 sk = (u64)ctx->skaddr;
+    bool connecting = bpf_map_lookup_elem(&tcp_health_connects, &sk) != NULL;
+    if (connecting) {
+        bpf_map_delete_elem(&tcp_health_connects, &sk);
+    }
+    tcp_health_stats_t *stats = sock_stats(sk);
+    if (!stats) {
+        return 0;
+    }
+    if (connecting) {
+        __sync_fetch_and_add(&stats->failures_refused, 1);
+    } else {
+        __sync_fetch_and_add(&stats->resets_received, 1);
+    }
+    return 0;
+}
+
+// Accounts the server connections when they are returned by inet_csk_accept,
+// as it happens in the context of the accepting process.
+SEC("kretprobe/inet_csk_accept")
+int kretprobe_tcp_accept(struct pt_regs *ctx) {
+    u64 sk = (u64)PT_REGS_RC(ctx);
+    if (!sk) {
+        return 0;
+    }
+    u32 pid = bpf_get_current_pid_tgid() >> 32;
+    if (!allowed_pid(pid)) {
+        return 0;
+    }
+    tcp_health_key_t *pending = bpf_map_lookup_elem(&tcp_health_pending, &sk);
+    if (!pending) {
+        return 0;
+    }
+    tcp_health_key_t key = *pending;
+    key.pid = pid;
+    bpf_map_update_elem(&tcp_health_socks, &sk, &key, BPF_ANY);
+    bpf_map_delete_elem(&tcp_health_pending, &sk);
+    return 0;
+}
+
+// Records the smoothed round-trip time of the accounted sockets each time that tcp_sendmsg
+// is invoked. The kernel stores it in microseconds, left-shifted 3 bits.
+SEC("kprobe/tcp_sendmsg")
+int kprobe_tcp_sendmsg(struct pt_regs *ctx) {
+    struct tcp_sock *tp = (struct tcp_sock *)PT_REGS_PARM1(ctx);
+    u64 sk = (u64)tp;
+    if (!bpf_map_lookup_elem(&tcp_health_socks, &sk)) {
+        return 0;
+    }
+    u64 srtt_us = BPF_CORE_READ(tp, srtt_us) >> 3;
+    if (!srtt_us) {
+        return 0;
+    }
+    tcp_health_stats_t *stats = sock_stats(sk);
+    if (stats) {
+        observe(&stats->rtt, srtt_us * 1000);
+    }
+    return 0;
+}
@@ -29,6 +29,11 @@ func main() {
 
 	slog.Info("Grafana Beyla", "Version", buildinfo.Version, "Revision", buildinfo.Revision, "OpenTelemetry SDK Version", otelsdk.Version())
 
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		replay(&lvl, os.Args[2:])
+		return
+	}
+
 	if err := beyla.CheckOSSupport(); err != nil {
 		slog.Error("can't start Beyla", "error", err)
 		os.Exit(-1)
@@ -74,6 +79,15 @@ func main() {
 	// child process isn't found.
 	ctx, _ := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 
+	if config.ConfigReload.Enabled {
+		if *configPath == "" {
+			slog.Warn("configuration reload is enabled but no configuration file was provided. Ignoring it")
+		} else {
+			config.Reloads = &beyla.Reloader{}
+			go config.Reloads.Watch(ctx, *configPath, config)
+		}
+	}
+
 	if err := components.RunBeyla(ctx, config); err != nil {
 		slog.Error("Beyla can't start", "error", err)
 		os.Exit(-1)
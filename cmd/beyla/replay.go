@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/components"
+)
+
+// replay runs the "beyla replay [-config <file>] <capture file>" command, which feeds a capture
+// of raw eBPF events through the application pipeline, without eBPF nor administrative privileges.
+func replay(lvl *slog.LevelVar, args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := flags.String("config", "", "path to the configuration file")
+	flags.Usage = func() {
+		slog.Info("usage: beyla replay [-config <file>] <capture file>")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(-1)
+	}
+
+	if cfg := os.Getenv("BEYLA_CONFIG_PATH"); cfg != "" {
+		configPath = &cfg
+	}
+	config := loadConfig(configPath)
+	// replay does not discover any process, but the configuration validation
+	// requires some selection criteria for the Application Observability mode
+	if !config.Enabled(beyla.FeatureAppO11y) {
+		config.Discovery.SystemWide = true
+	}
+	if err := config.Validate(); err != nil {
+		slog.Error("wrong Beyla configuration", "error", err)
+		os.Exit(-1)
+	}
+	if err := lvl.UnmarshalText([]byte(config.LogLevel)); err != nil {
+		slog.Error("unknown log level specified, choices are [DEBUG, INFO, WARN, ERROR]", "error", err)
+		os.Exit(-1)
+	}
+
+	capture, err := os.Open(flags.Arg(0))
+	if err != nil {
+		slog.Error("can't open capture file", "error", err)
+		os.Exit(-1)
+	}
+	defer capture.Close()
+
+	ctx, _ := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	if err := components.ReplayBeyla(ctx, config, capture); err != nil {
+		slog.Error("Beyla can't replay the capture", "error", err)
+		// nolint:gocritic
+		os.Exit(-1)
+	}
+}
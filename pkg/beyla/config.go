@@ -20,6 +20,8 @@ import (
 	"github.com/grafana/beyla/pkg/internal/filter"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
 	"github.com/grafana/beyla/pkg/internal/infraolly/process"
+	"github.com/grafana/beyla/pkg/internal/profile"
+	"github.com/grafana/beyla/pkg/internal/slo"
 	"github.com/grafana/beyla/pkg/internal/traces"
 	"github.com/grafana/beyla/pkg/kubeflags"
 	"github.com/grafana/beyla/pkg/services"
@@ -50,6 +52,9 @@ var DefaultConfig = Config{
 		BpfBaseDir:         "/var/run/beyla",
 		BpfPath:            fmt.Sprintf("beyla-%d", os.Getpid()),
 		HTTPRequestTimeout: 30 * time.Second,
+		Capture: ebpfcommon.CaptureConfig{
+			MaxSize: 100 * 1024 * 1024,
+		},
 	},
 	Grafana: otel.GrafanaConfig{
 		OTLP: otel.GrafanaOTLP{
@@ -76,6 +81,8 @@ var DefaultConfig = Config{
 		// TODO: keep OTEL expiration disabled by default until we address
 		// this issue: https://github.com/grafana/beyla/issues/1065
 		TTL: 100 * 365 * 24 * time.Hour,
+
+		DiskQueue: otel.DefaultDiskQueueConfig,
 	},
 	Traces: otel.TracesConfig{
 		Protocol:           otel.ProtocolUnset,
@@ -86,6 +93,8 @@ var DefaultConfig = Config{
 		Instrumentations: []string{
 			instrumentations.InstrumentationALL,
 		},
+
+		DiskQueue: otel.DefaultDiskQueueConfig,
 	},
 	Prometheus: prom.PrometheusConfig{
 		Path:     "/metrics",
@@ -96,9 +105,24 @@ var DefaultConfig = Config{
 		},
 		TTL:                         defaultMetricsTTL,
 		SpanMetricsServiceCacheSize: 10000,
+		RemoteWrite:                 prom.DefaultRemoteWriteConfig,
 	},
 	Printer:      false, // Deprecated: use TracePrinter instead
 	TracePrinter: debug.TracePrinterDisabled,
+	SpanStream: debug.SpanStreamConfig{
+		Path:            "/spans",
+		MaxClients:      10,
+		ClientBufferLen: 1024,
+	},
+	SLO: slo.Config{
+		EvaluationInterval: 30 * time.Second,
+		BurnRateWindows:    slo.DefaultBurnRateWindows,
+	},
+	Profiling: profile.Config{
+		SampleFrequency: 99,
+		Interval:        15 * time.Second,
+		Path:            "/profiles",
+	},
 	InternalMetrics: imetrics.Config{
 		Prometheus: imetrics.PrometheusConfig{
 			Port: 0, // disabled by default
@@ -123,6 +147,17 @@ var DefaultConfig = Config{
 		RunMode:  process.RunModePrivileged,
 		Interval: 5 * time.Second,
 	},
+	ConfigReload: ConfigReloadConfig{
+		PollInterval: 10 * time.Second,
+	},
+	TailSampling: transform.TailSamplingConfig{
+		DecisionWait: 10 * time.Second,
+		MaxTraces:    10000,
+		Policies: transform.TailSamplingPolicies{
+			Errors:        true,
+			Probabilistic: 0.1,
+		},
+	},
 }
 
 type Config struct {
@@ -141,11 +176,25 @@ type Config struct {
 	// Routes is an optional node. If not set, data will be directly forwarded to exporters.
 	Routes       *transform.RoutesConfig       `yaml:"routes"`
 	NameResolver *transform.NameResolverConfig `yaml:"name_resolver"`
-	Metrics      otel.MetricsConfig            `yaml:"otel_metrics_export"`
-	Traces       otel.TracesConfig             `yaml:"otel_traces_export"`
-	Prometheus   prom.PrometheusConfig         `yaml:"prometheus_export"`
-	Printer      debug.PrintEnabled            `yaml:"print_traces" env:"BEYLA_PRINT_TRACES"`
-	TracePrinter debug.TracePrinter            `yaml:"trace_printer" env:"BEYLA_TRACE_PRINTER"`
+	// RedisKeys groups the keys of the Redis requests by pattern
+	RedisKeys    transform.RedisKeysConfig `yaml:"redis_keys"`
+	Metrics      otel.MetricsConfig        `yaml:"otel_metrics_export"`
+	Traces       otel.TracesConfig         `yaml:"otel_traces_export"`
+	Prometheus   prom.PrometheusConfig     `yaml:"prometheus_export"`
+	Printer      debug.PrintEnabled        `yaml:"print_traces" env:"BEYLA_PRINT_TRACES"`
+	TracePrinter debug.TracePrinter        `yaml:"trace_printer" env:"BEYLA_TRACE_PRINTER"`
+
+	// SpanStream is an optional HTTP endpoint that streams the processed spans to debugging clients
+	SpanStream debug.SpanStreamConfig `yaml:"span_stream"`
+
+	// TailSampling is an optional node. If not enabled, all the traces are forwarded to the exporters.
+	TailSampling transform.TailSamplingConfig `yaml:"tail_sampling"`
+
+	// SLO evaluates the error budget and burn rates of the configured Service Level Objectives
+	SLO slo.Config `yaml:"slo"`
+
+	// Profiling samples the CPU of the instrumented processes and exports the profiles
+	Profiling profile.Config `yaml:"profiling"`
 
 	// Exec allows selecting the instrumented executable whose complete path contains the Exec value.
 	Exec       services.RegexpAttr `yaml:"executable_name" env:"BEYLA_EXECUTABLE_NAME"`
@@ -182,8 +231,15 @@ type Config struct {
 	// and both the "application" and "application_process" features are enabled
 	Processes process.CollectConfig `yaml:"processes"`
 
+	// ConfigReload enables the hot reload of some sections of the configuration file
+	ConfigReload ConfigReloadConfig `yaml:"config_reload"`
+
 	// Grafana Agent specific configuration
 	TracesReceiver TracesReceiverConfig `yaml:"-"`
+
+	// Reloads notifies the components that support hot reload about configuration changes.
+	// It is nil if the hot reload is disabled.
+	Reloads *Reloader `yaml:"-"`
 }
 
 type Consumer interface {
@@ -237,15 +293,39 @@ func (c *Config) Validate() error {
 	if c.EBPF.BatchLength == 0 {
 		return ConfigError("BEYLA_BPF_BATCH_LENGTH must be at least 1")
 	}
+	if err := ebpfcommon.CheckProtocolDetectors(c.EBPF.DisabledProtocolDetectors); err != nil {
+		return ConfigError(fmt.Sprintf("invalid value for disabled_protocol_detectors: %s", err.Error()))
+	}
 
 	if c.Enabled(FeatureNetO11y) && !c.Grafana.OTLP.MetricsEnabled() && !c.Metrics.Enabled() &&
-		!c.Prometheus.Enabled() && !c.NetworkFlows.Print {
+		!c.Prometheus.Enabled() && !c.NetworkFlows.IPFIX.Enabled() && !c.NetworkFlows.Print {
 		return ConfigError("enabling network metrics requires to enable at least the OpenTelemetry" +
 			" metrics exporter: grafana, otel_metrics_export or prometheus_export sections in the YAML configuration file; or the" +
-			" OTEL_EXPORTER_OTLP_ENDPOINT, OTEL_EXPORTER_OTLP_METRICS_ENDPOINT or BEYLA_PROMETHEUS_PORT environment variables. For debugging" +
+			" OTEL_EXPORTER_OTLP_ENDPOINT, OTEL_EXPORTER_OTLP_METRICS_ENDPOINT or BEYLA_PROMETHEUS_PORT environment variables." +
+			" Network flows can also be sent to an IPFIX collector with BEYLA_NETWORK_IPFIX_ENDPOINT. For debugging" +
 			" purposes, you can also set BEYLA_NETWORK_PRINT_FLOWS=true")
 	}
 
+	if err := c.NetworkFlows.IPFIX.Validate(); err != nil {
+		return ConfigError(fmt.Sprintf("invalid network ipfix configuration: %s", err.Error()))
+	}
+
+	if err := c.SLO.Validate(); err != nil {
+		return ConfigError(fmt.Sprintf("invalid slo configuration: %s", err.Error()))
+	}
+
+	if err := c.Prometheus.RemoteWrite.Validate(); err != nil {
+		return ConfigError(fmt.Sprintf("invalid prometheus_export configuration: %s", err.Error()))
+	}
+
+	if err := c.SpanStream.Validate(); err != nil {
+		return ConfigError(fmt.Sprintf("invalid span stream configuration: %s", err.Error()))
+	}
+
+	if err := c.Profiling.Validate(); err != nil {
+		return ConfigError(fmt.Sprintf("invalid profiling configuration: %s", err.Error()))
+	}
+
 	if !c.TracePrinter.Valid() {
 		return ConfigError(fmt.Sprintf("invalid value for trace_printer: '%s'", c.TracePrinter))
 	}
@@ -264,8 +344,8 @@ func (c *Config) Validate() error {
 	if c.Enabled(FeatureAppO11y) && !c.Printer.Enabled() &&
 		!c.Grafana.OTLP.MetricsEnabled() && !c.Grafana.OTLP.TracesEnabled() &&
 		!c.Metrics.Enabled() && !c.Traces.Enabled() &&
-		!c.Prometheus.Enabled() && !c.TracePrinter.Enabled() {
-		return ConfigError("you need to define at least one exporter: trace_printer," +
+		!c.Prometheus.Enabled() && !c.TracePrinter.Enabled() && !c.SpanStream.Enabled() {
+		return ConfigError("you need to define at least one exporter: trace_printer, span_stream," +
 			" grafana, otel_metrics_export, otel_traces_export or prometheus_export")
 	}
 
@@ -280,6 +360,29 @@ func (c *Config) otelNetO11yEnabled() bool {
 	return (c.Metrics.Enabled() || c.Grafana.OTLP.MetricsEnabled()) && c.Metrics.NetworkMetricsEnabled()
 }
 
+// GoRuntimeMetricsEnabled returns true if any metrics exporter has enabled both the
+// "application" and "application_go_runtime" features
+func (c *Config) GoRuntimeMetricsEnabled() bool {
+	return (c.Metrics.EndpointEnabled() && c.Metrics.OTelMetricsEnabled() && c.Metrics.GoRuntimeMetricsEnabled()) ||
+		(c.Prometheus.EndpointEnabled() && c.Prometheus.OTelMetricsEnabled() && c.Prometheus.GoRuntimeMetricsEnabled())
+}
+
+// GoSchedulerMetricsEnabled returns true if any metrics exporter reporting the Go runtime metrics
+// has also enabled the "application_go_scheduler" feature
+func (c *Config) GoSchedulerMetricsEnabled() bool {
+	return (c.Metrics.EndpointEnabled() && c.Metrics.OTelMetricsEnabled() &&
+		c.Metrics.GoRuntimeMetricsEnabled() && c.Metrics.GoSchedulerMetricsEnabled()) ||
+		(c.Prometheus.EndpointEnabled() && c.Prometheus.OTelMetricsEnabled() &&
+			c.Prometheus.GoRuntimeMetricsEnabled() && c.Prometheus.GoSchedulerMetricsEnabled())
+}
+
+// TCPConnectionMetricsEnabled returns true if any metrics exporter has enabled both the
+// "application" and "tcp_connection" features
+func (c *Config) TCPConnectionMetricsEnabled() bool {
+	return (c.Metrics.EndpointEnabled() && c.Metrics.OTelMetricsEnabled() && c.Metrics.TCPConnectionMetricsEnabled()) ||
+		(c.Prometheus.EndpointEnabled() && c.Prometheus.OTelMetricsEnabled() && c.Prometheus.TCPConnectionMetricsEnabled())
+}
+
 // Enabled checks if a given Beyla feature is enabled according to the global configuration
 func (c *Config) Enabled(feature Feature) bool {
 	switch feature {
@@ -311,6 +414,10 @@ func (c *Config) ExternalLogger(handler slog.Handler, tracing bool) {
 // 3 - Environment variables
 func LoadConfig(file io.Reader) (*Config, error) {
 	cfg := DefaultConfig
+	// copy the pointer-based default sections, so the YAML parser does not overwrite
+	// the DefaultConfig values that would be shared by successive LoadConfig invocations
+	routes, nameResolver := *DefaultConfig.Routes, *DefaultConfig.NameResolver
+	cfg.Routes, cfg.NameResolver = &routes, &nameResolver
 	if file != nil {
 		cfgBuf, err := io.ReadAll(file)
 		if err != nil {
@@ -23,6 +23,8 @@ import (
 	"github.com/grafana/beyla/pkg/internal/imetrics"
 	"github.com/grafana/beyla/pkg/internal/infraolly/process"
 	"github.com/grafana/beyla/pkg/internal/netolly/transform/cidr"
+	"github.com/grafana/beyla/pkg/internal/profile"
+	"github.com/grafana/beyla/pkg/internal/slo"
 	"github.com/grafana/beyla/pkg/internal/traces"
 	"github.com/grafana/beyla/pkg/kubeflags"
 	"github.com/grafana/beyla/pkg/transform"
@@ -109,12 +111,29 @@ network:
 		EnforceSysCaps:   true,
 		Printer:          false,
 		TracePrinter:     "json",
+		SpanStream: debug.SpanStreamConfig{
+			Path:            "/spans",
+			MaxClients:      10,
+			ClientBufferLen: 1024,
+		},
+		SLO: slo.Config{
+			EvaluationInterval: 30 * time.Second,
+			BurnRateWindows:    slo.DefaultBurnRateWindows,
+		},
+		Profiling: profile.Config{
+			SampleFrequency: 99,
+			Interval:        15 * time.Second,
+			Path:            "/profiles",
+		},
 		EBPF: ebpfcommon.TracerConfig{
 			BatchLength:        100,
 			BatchTimeout:       time.Second,
 			BpfBaseDir:         "/var/run/beyla",
 			BpfPath:            DefaultConfig.EBPF.BpfPath,
 			HTTPRequestTimeout: 30 * time.Second,
+			Capture: ebpfcommon.CaptureConfig{
+				MaxSize: 100 * 1024 * 1024,
+			},
 		},
 		Grafana: otel.GrafanaConfig{
 			OTLP: otel.GrafanaOTLP{
@@ -129,8 +148,9 @@ network:
 			Protocol:          otel.ProtocolUnset,
 			ReportersCacheLen: ReporterLRUSize,
 			Buckets: otel.Buckets{
-				DurationHistogram:    []float64{0, 1, 2},
-				RequestSizeHistogram: otel.DefaultBuckets.RequestSizeHistogram,
+				DurationHistogram:          []float64{0, 1, 2},
+				RequestSizeHistogram:       otel.DefaultBuckets.RequestSizeHistogram,
+				RedisPipelineSizeHistogram: otel.DefaultBuckets.RedisPipelineSizeHistogram,
 			},
 			Features: []string{"application"},
 			Instrumentations: []string{
@@ -138,6 +158,7 @@ network:
 			},
 			HistogramAggregation: "base2_exponential_bucket_histogram",
 			TTL:                  defaultMetricsTTL,
+			DiskQueue:            otel.DefaultDiskQueueConfig,
 		},
 		Traces: otel.TracesConfig{
 			Protocol:           otel.ProtocolUnset,
@@ -149,6 +170,7 @@ network:
 			Instrumentations: []string{
 				instrumentations.InstrumentationALL,
 			},
+			DiskQueue: otel.DefaultDiskQueueConfig,
 		},
 		Prometheus: prom.PrometheusConfig{
 			Path:     "/metrics",
@@ -158,9 +180,11 @@ network:
 			},
 			TTL:                         time.Second,
 			SpanMetricsServiceCacheSize: 10000,
+			RemoteWrite:                 prom.DefaultRemoteWriteConfig,
 			Buckets: otel.Buckets{
-				DurationHistogram:    otel.DefaultBuckets.DurationHistogram,
-				RequestSizeHistogram: []float64{0, 10, 20, 22},
+				DurationHistogram:          otel.DefaultBuckets.DurationHistogram,
+				RequestSizeHistogram:       []float64{0, 10, 20, 22},
+				RedisPipelineSizeHistogram: otel.DefaultBuckets.RedisPipelineSizeHistogram,
 			}},
 		InternalMetrics: imetrics.Config{
 			Prometheus: imetrics.PrometheusConfig{
@@ -200,6 +224,17 @@ network:
 			RunMode:  process.RunModePrivileged,
 			Interval: 5 * time.Second,
 		},
+		ConfigReload: ConfigReloadConfig{
+			PollInterval: 10 * time.Second,
+		},
+		TailSampling: transform.TailSamplingConfig{
+			DecisionWait: 10 * time.Second,
+			MaxTraces:    10000,
+			Policies: transform.TailSamplingPolicies{
+				Errors:        true,
+				Probabilistic: 0.1,
+			},
+		},
 	}, cfg)
 }
 
@@ -324,7 +359,7 @@ func TestConfigValidate_TracePrinter(t *testing.T) {
 		},
 		{
 			env:      envMap{"BEYLA_EXECUTABLE_NAME": "foo"},
-			errorMsg: "you need to define at least one exporter: trace_printer, grafana, otel_metrics_export, otel_traces_export or prometheus_export",
+			errorMsg: "you need to define at least one exporter: trace_printer, span_stream, grafana, otel_metrics_export, otel_traces_export or prometheus_export",
 		},
 	}
 
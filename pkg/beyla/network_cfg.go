@@ -21,6 +21,7 @@ package beyla
 import (
 	"time"
 
+	"github.com/grafana/beyla/pkg/internal/netolly/export/ipfix"
 	"github.com/grafana/beyla/pkg/internal/netolly/flow"
 	"github.com/grafana/beyla/pkg/internal/netolly/transform/cidr"
 )
@@ -85,6 +86,18 @@ type NetworkConfig struct {
 	// again from a different interface.
 	// If the value is not set, it will default to 2 * CacheActiveTimeout
 	DeduperFCTTL time.Duration `yaml:"deduper_fc_ttl" env:"BEYLA_NETWORK_DEDUPER_FC_TTL"`
+	// HandshakeTimeout specifies how long a TCP SYN can wait for its SYN-ACK before the
+	// connection is reported as half-open.
+	// If the value is not set, it will default to 2 * CacheActiveTimeout
+	HandshakeTimeout time.Duration `yaml:"handshake_timeout" env:"BEYLA_NETWORK_HANDSHAKE_TIMEOUT"`
+	// Drops enables the tracing of the network packets that are dropped by the kernel (for example
+	// by a Kubernetes NetworkPolicy or an iptables rule). They are reported with their drop reason.
+	// It requires Linux kernel 5.17 or higher.
+	Drops bool `yaml:"drops" env:"BEYLA_NETWORK_DROPS"`
+	// L7Protocol enables the detection of the application protocol of the TCP connections
+	// (e.g. HTTP, gRPC, Kafka...) from their first payload bytes. The protocol is reported
+	// in the "l7.protocol" attribute of the network flows.
+	L7Protocol bool `yaml:"l7_protocol" env:"BEYLA_NETWORK_L7_PROTOCOL"`
 	// Direction allows selecting which flows to trace according to its direction. Accepted values
 	// are "ingress", "egress" or "both" (default).
 	Direction string `yaml:"direction" env:"BEYLA_NETWORK_DIRECTION"`
@@ -107,6 +120,9 @@ type NetworkConfig struct {
 	// for external traffic.
 	ReverseDNS flow.ReverseDNS `yaml:"reverse_dns"`
 
+	// IPFIX sends the network flows to an IPFIX or NetFlow v9 collector
+	IPFIX ipfix.Config `yaml:"ipfix"`
+
 	// Print the network flows in the Standard Output, if true
 	Print bool `yaml:"print_flows" env:"BEYLA_NETWORK_PRINT_FLOWS"`
 
@@ -135,4 +151,10 @@ var defaultNetworkConfig = NetworkConfig{
 		CacheLen: 256,
 		CacheTTL: time.Hour,
 	},
+	IPFIX: ipfix.Config{
+		Transport:       ipfix.TransportUDP,
+		Version:         ipfix.VersionIPFIX,
+		EnterpriseID:    ipfix.DefaultEnterpriseID,
+		TemplateRefresh: 10 * time.Minute,
+	},
 }
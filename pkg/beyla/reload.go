@@ -0,0 +1,193 @@
+package beyla
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/grafana/beyla/pkg/internal/transform/route"
+)
+
+// ConfigReloadConfig configures the hot reload of the configuration file
+type ConfigReloadConfig struct {
+	// Enabled makes Beyla to watch the configuration file, as well as the SIGHUP signal,
+	// and re-apply the configuration sections that can be safely changed at runtime.
+	Enabled bool `yaml:"enabled" env:"BEYLA_CONFIG_RELOAD_ENABLED"`
+	// PollInterval specifies how often the configuration file is checked for changes
+	PollInterval time.Duration `yaml:"poll_interval" env:"BEYLA_CONFIG_RELOAD_POLL_INTERVAL"`
+}
+
+func rlog() *slog.Logger {
+	return slog.With("component", "beyla.Reloader")
+}
+
+// Reloader notifies the subscribed components each time a new configuration is successfully loaded.
+// Only the following sections of the configuration can be reloaded at runtime:
+// discovery.services, discovery.exclude_services, routes (except routes.learning), filter.application
+// and attributes.select.
+// Any change to other sections is rejected.
+type Reloader struct {
+	mt   sync.Mutex
+	subs []func(*Config)
+}
+
+// SubscribeReloads returns a channel that receives the given configuration section each time
+// a new configuration is loaded. If the subscriber does not read the channel in time, it will only
+// receive the latest version of the configuration.
+// If the Reloader is nil, it returns a nil channel, which never receives any update.
+func SubscribeReloads[T any](r *Reloader, section func(*Config) T) <-chan T {
+	if r == nil {
+		return nil
+	}
+	ch := make(chan T, 1)
+	r.mt.Lock()
+	defer r.mt.Unlock()
+	r.subs = append(r.subs, func(cfg *Config) {
+		// discard any previous, non-consumed update
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- section(cfg)
+	})
+	return ch
+}
+
+func (r *Reloader) publish(cfg *Config) {
+	r.mt.Lock()
+	defer r.mt.Unlock()
+	for _, notify := range r.subs {
+		notify(cfg)
+	}
+}
+
+// Watch polls the configuration file in the provided path for changes, and listens for
+// SIGHUP signals. Each time the file changes, or the signal is received, it reloads the configuration
+// and, if it only changed in reloadable sections, notifies the new configuration to the subscribers.
+// This function blocks until the passed context is canceled.
+func (r *Reloader) Watch(ctx context.Context, path string, current *Config) {
+	log := rlog().With("path", path)
+	interval := current.ConfigReload.PollInterval
+	if interval <= 0 {
+		interval = DefaultConfig.ConfigReload.PollInterval
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastContent, err := os.ReadFile(path)
+	if err != nil {
+		log.Warn("can't read configuration file. Will retry later", "error", err)
+	}
+	log.Debug("watching configuration file for changes", "interval", interval)
+	for {
+		forced := false
+		select {
+		case <-ctx.Done():
+			log.Debug("context canceled. Stopping configuration watcher")
+			return
+		case <-hup:
+			log.Info("received SIGHUP. Reloading configuration")
+			forced = true
+		case <-ticker.C:
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			log.Warn("can't read configuration file", "error", err)
+			continue
+		}
+		if !forced && bytes.Equal(content, lastContent) {
+			continue
+		}
+		lastContent = content
+		newCfg, err := r.reload(current, content)
+		if err != nil {
+			log.Error("configuration not reloaded", "error", err)
+			continue
+		}
+		log.Info("configuration reloaded")
+		current = newCfg
+	}
+}
+
+// reload parses and validates the new configuration, checks that it only changes
+// reloadable sections, and notifies it to the subscribers
+func (r *Reloader) reload(current *Config, content []byte) (*Config, error) {
+	newCfg, err := LoadConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	if err := newCfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if changed := nonReloadableChanges(current, newCfg); len(changed) > 0 {
+		return nil, fmt.Errorf("the following configuration sections changed and they require restarting"+
+			" Beyla: %s", strings.Join(changed, ", "))
+	}
+	// keep the runtime-provided values that are not part of the configuration file
+	newCfg.Prometheus.Registry = current.Prometheus.Registry
+	newCfg.TracesReceiver = current.TracesReceiver
+	newCfg.Reloads = current.Reloads
+	r.publish(newCfg)
+	return newCfg, nil
+}
+
+// metricsExportEnabled returns whether any metrics exporter is enabled
+func (c *Config) metricsExportEnabled() bool {
+	return c.Metrics.Enabled() || c.Grafana.OTLP.MetricsEnabled() || c.Prometheus.Enabled()
+}
+
+func routesLearning(c *Config) route.LearnerConfig {
+	if c.Routes == nil {
+		return route.LearnerConfig{}
+	}
+	return c.Routes.Learning
+}
+
+// nonReloadableChanges returns the YAML names of the top-level configuration sections that changed
+// between the old and new configuration, ignoring the sections that can be reloaded at runtime.
+func nonReloadableChanges(old, new *Config) []string {
+	cmp := *new
+	cmp.Discovery.Services = old.Discovery.Services
+	cmp.Discovery.ExcludeServices = old.Discovery.ExcludeServices
+	cmp.Routes = old.Routes
+	cmp.Filters.Application = old.Filters.Application
+	// the metrics exporters can't change the attributes of their metrics at runtime,
+	// so the attributes selection is only reloadable when traces are the only signal
+	if !old.metricsExportEnabled() && !new.metricsExportEnabled() {
+		cmp.Attributes.Select = old.Attributes.Select
+	}
+	// ignoring runtime-provided values that are not part of the configuration file
+	cmp.Prometheus.Registry = old.Prometheus.Registry
+	cmp.TracesReceiver = old.TracesReceiver
+	cmp.Reloads = old.Reloads
+
+	var changed []string
+	// the routes learner keeps running with the configuration it was created with
+	if routesLearning(old) != routesLearning(new) {
+		changed = append(changed, "routes.learning")
+	}
+	ov, nv := reflect.ValueOf(old).Elem(), reflect.ValueOf(&cmp).Elem()
+	for i := 0; i < ov.NumField(); i++ {
+		if reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+		field := ov.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+		changed = append(changed, name)
+	}
+	return changed
+}
@@ -0,0 +1,194 @@
+package beyla
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/transform"
+)
+
+const baseReloadConfig = `
+trace_printer: text
+discovery:
+  services:
+    - open_ports: 8080
+routes:
+  patterns: ["/users/{id}"]
+`
+
+func loadReloadConfig(t *testing.T, content string) *Config {
+	cfg, err := LoadConfig(bytes.NewBufferString(content))
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+	return cfg
+}
+
+func TestReload_AcceptsReloadableChanges(t *testing.T) {
+	// attributes.select is only reloadable if no metrics exporter is enabled. Other tests
+	// might have left environment variables that enable them
+	for _, env := range []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_METRICS_ENDPOINT",
+		"GRAFANA_CLOUD_SUBMIT", "BEYLA_PROMETHEUS_PORT"} {
+		t.Setenv(env, "")
+	}
+	current := loadReloadConfig(t, baseReloadConfig)
+	r := &Reloader{}
+	routes := SubscribeReloads(r, func(c *Config) *transform.RoutesConfig { return c.Routes })
+	newCfg, err := r.reload(current, []byte(`
+trace_printer: text
+discovery:
+  services:
+    - open_ports: 8080
+    - open_ports: 9090
+  exclude_services:
+    - exe_path: foo
+routes:
+  patterns: ["/users/{id}", "/products/{id}"]
+filter:
+  application:
+    url_path:
+      match: "/users/*"
+attributes:
+  select:
+    http_server_request_duration:
+      include: ["*"]
+`))
+	require.NoError(t, err)
+	assert.Len(t, newCfg.Discovery.Services, 2)
+
+	select {
+	case rc := <-routes:
+		assert.Equal(t, []string{"/users/{id}", "/products/{id}"}, rc.Patterns)
+	default:
+		require.Fail(t, "expected a routes update")
+	}
+	// the previous configuration must not be modified
+	assert.Equal(t, []string{"/users/{id}"}, current.Routes.Patterns)
+}
+
+func TestReload_RejectsNonReloadableChanges(t *testing.T) {
+	current := loadReloadConfig(t, baseReloadConfig)
+	r := &Reloader{}
+	routes := SubscribeReloads(r, func(c *Config) *transform.RoutesConfig { return c.Routes })
+	_, err := r.reload(current, []byte(`
+trace_printer: json
+discovery:
+  services:
+    - open_ports: 8080
+  poll_interval: 3s
+routes:
+  patterns: ["/products/{id}"]
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "trace_printer")
+	assert.Contains(t, err.Error(), "discovery")
+	assert.NotContains(t, err.Error(), "routes")
+	select {
+	case <-routes:
+		require.Fail(t, "rejected configurations must not be notified")
+	default:
+		// ok!
+	}
+}
+
+func TestReload_RejectsRoutesLearningChanges(t *testing.T) {
+	current := loadReloadConfig(t, baseReloadConfig)
+	r := &Reloader{}
+	_, err := r.reload(current, []byte(baseReloadConfig+`  unmatched: learned
+  learning:
+    max_distinct_values: 5
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "routes.learning")
+
+	// the unmatched mode can still be changed
+	_, err = r.reload(current, []byte(baseReloadConfig+`  unmatched: learned
+`))
+	require.NoError(t, err)
+}
+
+func TestReload_AttributesSelectionWithMetrics(t *testing.T) {
+	withMetrics := baseReloadConfig + `
+prometheus_export:
+  port: 8999
+`
+	current := loadReloadConfig(t, withMetrics)
+	r := &Reloader{}
+	_, err := r.reload(current, []byte(withMetrics+`
+attributes:
+  select:
+    http_server_request_duration:
+      include: ["*"]
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "attributes")
+
+	// other reloadable sections can still be changed
+	_, err = r.reload(current, []byte(withMetrics+`
+filter:
+  application:
+    url_path:
+      match: "/users/*"
+`))
+	require.NoError(t, err)
+}
+
+func TestReload_RejectsInvalidConfig(t *testing.T) {
+	current := loadReloadConfig(t, baseReloadConfig)
+	r := &Reloader{}
+	_, err := r.reload(current, []byte(`routes: [this is not valid`))
+	require.Error(t, err)
+	_, err = r.reload(current, []byte(`routes:
+  patterns: ["/users/{id}"]
+`))
+	require.Error(t, err)
+}
+
+func TestSubscribeReloads_KeepsLatest(t *testing.T) {
+	r := &Reloader{}
+	ch := SubscribeReloads(r, func(c *Config) string { return c.LogLevel })
+	r.publish(&Config{LogLevel: "INFO"})
+	r.publish(&Config{LogLevel: "DEBUG"})
+	assert.Equal(t, "DEBUG", <-ch)
+
+	assert.Nil(t, SubscribeReloads[string](nil, func(c *Config) string { return c.LogLevel }))
+}
+
+func TestReloader_Watch(t *testing.T) {
+	const reloadSection = `
+config_reload:
+  poll_interval: 10ms
+`
+	cfgPath := path.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte(baseReloadConfig+reloadSection), 0o644))
+	current := loadReloadConfig(t, baseReloadConfig+reloadSection)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := &Reloader{}
+	routes := SubscribeReloads(r, func(c *Config) *transform.RoutesConfig { return c.Routes })
+	go r.Watch(ctx, cfgPath, current)
+	// give time to the watcher to read the initial version of the file
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, os.WriteFile(cfgPath, []byte(`
+trace_printer: text
+discovery:
+  services:
+    - open_ports: 8080
+routes:
+  patterns: ["/products/{id}"]
+`+reloadSection), 0o644))
+	select {
+	case rc := <-routes:
+		assert.Equal(t, []string{"/products/{id}"}, rc.Patterns)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timeout while waiting for a configuration reload")
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"log/slog"
 	"sync"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/grafana/beyla/pkg/beyla"
 	"github.com/grafana/beyla/pkg/export/attributes"
+	"github.com/grafana/beyla/pkg/export/prom"
 	"github.com/grafana/beyla/pkg/internal/appolly"
 	"github.com/grafana/beyla/pkg/internal/connector"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
@@ -131,6 +134,14 @@ func buildCommonContextInfo(
 
 	attributeGroups(config, ctxInfo)
 
+	if config.Prometheus.RemoteWrite.Enabled() {
+		var gatherer prometheus.Gatherer = config.Prometheus.Registry
+		if config.Prometheus.Registry == nil {
+			gatherer = promMgr.Registry(config.Prometheus.Port, config.Prometheus.Path)
+		}
+		go prom.NewRemoteWriter(&config.Prometheus.RemoteWrite, gatherer).Run(ctx)
+	}
+
 	if config.Attributes.HostID.Override == "" {
 		ctxInfo.FetchHostID(ctx, config.Attributes.HostID.FetchTimeout)
 	} else {
@@ -161,4 +172,7 @@ func attributeGroups(config *beyla.Config, ctxInfo *global.ContextInfo) {
 	if config.NetworkFlows.CIDRs.Enabled() {
 		ctxInfo.MetricAttributeGroups.Add(attributes.GroupNetCIDR)
 	}
+	if config.NetworkFlows.L7Protocol {
+		ctxInfo.MetricAttributeGroups.Add(attributes.GroupNetL7Protocol)
+	}
 }
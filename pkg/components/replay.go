@@ -0,0 +1,19 @@
+package components
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/internal/appolly"
+)
+
+// ReplayBeyla feeds a capture of raw eBPF events (see the ebpf.capture configuration section)
+// through the Application Observability pipeline. It does not require loading any eBPF
+// program, so it can run without administrative privileges.
+func ReplayBeyla(ctx context.Context, cfg *beyla.Config, capture io.Reader) error {
+	slog.Info("replaying captured eBPF events in Application Observability mode")
+	ctxInfo := buildCommonContextInfo(ctx, cfg)
+	return appolly.New(ctx, ctxInfo, cfg).Replay(capture)
+}
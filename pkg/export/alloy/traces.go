@@ -20,14 +20,33 @@ func TracesReceiver(
 	cfg *beyla.TracesReceiverConfig,
 	userAttribSelection attributes.Selection,
 ) pipe.FinalProvider[[]request.Span] {
-	return (&tracesReceiver{ctx: ctx, cfg: cfg, attributes: userAttribSelection, hostID: ctxInfo.HostID}).provideLoop
+	return ReloadableTracesReceiver(ctx, ctxInfo, cfg, userAttribSelection, nil)
+}
+
+// ReloadableTracesReceiver works as TracesReceiver, but it also replaces the user-provided attribute selection
+// each time a new attributes.Selection is received from the attrUpdates channel.
+func ReloadableTracesReceiver(
+	ctx context.Context,
+	ctxInfo *global.ContextInfo,
+	cfg *beyla.TracesReceiverConfig,
+	userAttribSelection attributes.Selection,
+	attrUpdates <-chan attributes.Selection,
+) pipe.FinalProvider[[]request.Span] {
+	return (&tracesReceiver{
+		ctx:         ctx,
+		cfg:         cfg,
+		attributes:  userAttribSelection,
+		attrUpdates: attrUpdates,
+		hostID:      ctxInfo.HostID,
+	}).provideLoop
 }
 
 type tracesReceiver struct {
-	ctx        context.Context
-	cfg        *beyla.TracesReceiverConfig
-	attributes attributes.Selection
-	hostID     string
+	ctx         context.Context
+	cfg         *beyla.TracesReceiverConfig
+	attributes  attributes.Selection
+	attrUpdates <-chan attributes.Selection
+	hostID      string
 }
 
 func (tr *tracesReceiver) provideLoop() (pipe.FinalFunc[[]request.Span], error) {
@@ -41,18 +60,31 @@ func (tr *tracesReceiver) provideLoop() (pipe.FinalFunc[[]request.Span], error)
 			slog.Error("error fetching user defined attributes", "error", err)
 		}
 
-		for spans := range in {
-			for i := range spans {
-				span := &spans[i]
-				if span.IgnoreSpan == request.IgnoreTraces {
+		for {
+			select {
+			case selection := <-tr.attrUpdates:
+				newAttrs, err := otel.GetUserSelectedAttributes(selection)
+				if err != nil {
+					slog.Error("can't reload user defined attributes. Keeping the previous ones", "error", err)
 					continue
 				}
+				traceAttrs = newAttrs
+			case spans, ok := <-in:
+				if !ok {
+					return
+				}
+				for i := range spans {
+					span := &spans[i]
+					if span.IgnoreSpan == request.IgnoreTraces {
+						continue
+					}
 
-				for _, tc := range tr.cfg.Traces {
-					traces := otel.GenerateTraces(span, tr.hostID, traceAttrs)
-					err := tc.ConsumeTraces(tr.ctx, traces)
-					if err != nil {
-						slog.Error("error sending trace to consumer", "error", err)
+					for _, tc := range tr.cfg.Traces {
+						traces := otel.GenerateTraces(span, tr.hostID, traceAttrs)
+						err := tc.ConsumeTraces(tr.ctx, traces)
+						if err != nil {
+							slog.Error("error sending trace to consumer", "error", err)
+						}
 					}
 				}
 			}
@@ -17,6 +17,7 @@ const (
 	GroupHTTPRoutes
 	GroupNetIfaceDirection
 	GroupNetCIDR
+	GroupNetL7Protocol
 	GroupPeerInfo // TODO Beyla 2.0: remove when we remove ReportPeerInfo configuration option
 	GroupTarget   // TODO Beyla 2.0: remove when we remove ReportTarget configuration option
 	GroupTraces
@@ -38,6 +39,7 @@ func getDefinitions(groups AttrGroups) map[Section]AttrReportGroup {
 	ifaceDirEnabled := groups.Has(GroupNetIfaceDirection)
 	peerInfoEnabled := groups.Has(GroupPeerInfo)
 	cidrEnabled := groups.Has(GroupNetCIDR)
+	l7ProtoEnabled := groups.Has(GroupNetL7Protocol)
 
 	// attributes to be reported exclusively for prometheus exporters
 	var prometheusAttributes = AttrReportGroup{
@@ -91,6 +93,15 @@ func getDefinitions(groups AttrGroups) map[Section]AttrReportGroup {
 		},
 	}
 
+	// network L7 protocol attribute is only enabled if the protocol
+	// detection of the flows is enabled
+	var networkL7Protocol = AttrReportGroup{
+		Disabled: !l7ProtoEnabled,
+		Attributes: map[attr.Name]Default{
+			attr.L7Protocol: true,
+		},
+	}
+
 	// attributes to be reported exclusively for application metrics when
 	// kubernetes metadata is enabled
 	var appKubeAttributes = AttrReportGroup{
@@ -194,9 +205,25 @@ func getDefinitions(groups AttrGroups) map[Section]AttrReportGroup {
 		},
 	}
 
+	var messagingPartitionAttributes = AttrReportGroup{
+		SubGroups: []*AttrReportGroup{&messagingAttributes},
+		Attributes: map[attr.Name]Default{
+			attr.MessagingPartition: true,
+		},
+	}
+
+	var tcpConnectionAttributes = AttrReportGroup{
+		SubGroups: []*AttrReportGroup{&appAttributes, &appKubeAttributes},
+		Attributes: map[attr.Name]Default{
+			attr.TCPRole:            true,
+			attr.NetworkPeerAddress: true,
+			attr.ServerPort:         true,
+		},
+	}
+
 	return map[Section]AttrReportGroup{
 		BeylaNetworkFlow.Section: {
-			SubGroups: []*AttrReportGroup{&networkCIDR, &networkKubeAttributes},
+			SubGroups: []*AttrReportGroup{&networkCIDR, &networkL7Protocol, &networkKubeAttributes},
 			Attributes: map[attr.Name]Default{
 				attr.Direction:      true,
 				attr.BeylaIP:        false,
@@ -244,9 +271,35 @@ func getDefinitions(groups AttrGroups) map[Section]AttrReportGroup {
 		DBClientDuration.Section: {
 			SubGroups: []*AttrReportGroup{&appAttributes, &appKubeAttributes},
 			Attributes: map[attr.Name]Default{
-				attr.DBOperation: true,
-				attr.DBSystem:    true,
-				attr.ErrorType:   true,
+				attr.DBOperation:       true,
+				attr.DBSystem:          true,
+				attr.ErrorType:         true,
+				attr.DBCollectionName:  false,
+				attr.DBRedisKeyPattern: false,
+			},
+		},
+		RedisCacheLookups.Section: {
+			SubGroups: []*AttrReportGroup{&appAttributes, &appKubeAttributes},
+			Attributes: map[attr.Name]Default{
+				attr.DBOperation:        true,
+				attr.DBRedisCacheResult: true,
+				attr.DBRedisKeyPattern:  false,
+			},
+		},
+		RedisPipelineSize.Section: {
+			SubGroups: []*AttrReportGroup{&appAttributes, &appKubeAttributes},
+			Attributes: map[attr.Name]Default{
+				attr.DBOperation:       true,
+				attr.DBRedisKeyPattern: false,
+			},
+		},
+		DNSLookupDuration.Section: {
+			SubGroups: []*AttrReportGroup{&appAttributes, &appKubeAttributes},
+			Attributes: map[attr.Name]Default{
+				attr.DNSQuestionType: true,
+				attr.DNSResponseCode: true,
+				attr.ServerAddr:      false,
+				attr.DNSQuestionName: false,
 			},
 		},
 		MessagingPublishDuration.Section: {
@@ -255,6 +308,21 @@ func getDefinitions(groups AttrGroups) map[Section]AttrReportGroup {
 		MessagingProcessDuration.Section: {
 			SubGroups: []*AttrReportGroup{&messagingAttributes},
 		},
+		MessagingKafkaMessages.Section: {
+			SubGroups:  []*AttrReportGroup{&messagingPartitionAttributes},
+			Attributes: map[attr.Name]Default{attr.MessagingOpType: true},
+		},
+		MessagingKafkaMessagesSize.Section: {
+			SubGroups:  []*AttrReportGroup{&messagingPartitionAttributes},
+			Attributes: map[attr.Name]Default{attr.MessagingOpType: true},
+		},
+		MessagingKafkaOffset.Section: {
+			SubGroups:  []*AttrReportGroup{&messagingPartitionAttributes},
+			Attributes: map[attr.Name]Default{attr.MessagingOpType: true},
+		},
+		MessagingKafkaConsumerLag.Section: {
+			SubGroups: []*AttrReportGroup{&messagingPartitionAttributes},
+		},
 		Traces.Section: {
 			Attributes: map[attr.Name]Default{
 				attr.DBQueryText: false,
@@ -266,6 +334,18 @@ func getDefinitions(groups AttrGroups) map[Section]AttrReportGroup {
 		ProcessMemoryVirtual.Section:  {SubGroups: []*AttrReportGroup{&processAttributes}},
 		ProcessDiskIO.Section:         {SubGroups: []*AttrReportGroup{&processAttributes}},
 		ProcessNetIO.Section:          {SubGroups: []*AttrReportGroup{&processAttributes}},
+
+		GoRuntimeGoroutinesCreated.Section: {SubGroups: []*AttrReportGroup{&appAttributes, &appKubeAttributes}},
+		GoRuntimeGoroutinesExited.Section:  {SubGroups: []*AttrReportGroup{&appAttributes, &appKubeAttributes}},
+		GoRuntimeGCCycles.Section:          {SubGroups: []*AttrReportGroup{&appAttributes, &appKubeAttributes}},
+		GoRuntimeSTWPauseDuration.Section:  {SubGroups: []*AttrReportGroup{&appAttributes, &appKubeAttributes}},
+		GoRuntimeSchedLatency.Section:      {SubGroups: []*AttrReportGroup{&appAttributes, &appKubeAttributes}},
+
+		TCPConnectionRTT.Section:           {SubGroups: []*AttrReportGroup{&tcpConnectionAttributes}},
+		TCPConnectionSetupDuration.Section: {SubGroups: []*AttrReportGroup{&tcpConnectionAttributes}},
+		TCPConnectionRetransmits.Section:   {SubGroups: []*AttrReportGroup{&tcpConnectionAttributes}},
+		TCPConnectionResets.Section:        {SubGroups: []*AttrReportGroup{&tcpConnectionAttributes}},
+		TCPConnectionFailures.Section:      {SubGroups: []*AttrReportGroup{&tcpConnectionAttributes}},
 	}
 }
 
@@ -26,6 +26,28 @@ var (
 		Prom:    "beyla_network_flow_bytes_total",
 		OTEL:    "beyla.network.flow.bytes",
 	}
+	// The following network flow metrics share the section of BeylaNetworkFlow,
+	// so they are decorated with the same attributes
+	BeylaNetworkFlowRTT = Name{
+		Section: BeylaNetworkFlow.Section,
+		Prom:    "beyla_network_flow_rtt_seconds",
+		OTEL:    "beyla.network.flow.rtt",
+	}
+	BeylaNetworkFlowResets = Name{
+		Section: BeylaNetworkFlow.Section,
+		Prom:    "beyla_network_flow_resets_total",
+		OTEL:    "beyla.network.flow.resets",
+	}
+	BeylaNetworkFlowHalfOpen = Name{
+		Section: BeylaNetworkFlow.Section,
+		Prom:    "beyla_network_flow_half_open_total",
+		OTEL:    "beyla.network.flow.half_open",
+	}
+	BeylaNetworkFlowDrops = Name{
+		Section: BeylaNetworkFlow.Section,
+		Prom:    "beyla_network_flow_drops_total",
+		OTEL:    "beyla.network.flow.drops",
+	}
 	HTTPServerRequestSize = Name{
 		Section: "http.server.request.body.size",
 		Prom:    "http_server_request_body_size_bytes",
@@ -91,6 +113,71 @@ var (
 		Prom:    "process_network_io_bytes_total",
 		OTEL:    "process.network.io",
 	}
+	GoRuntimeGoroutinesCreated = Name{
+		Section: "process.runtime.go.goroutines.created",
+		Prom:    "process_runtime_go_goroutines_created_total",
+		OTEL:    "process.runtime.go.goroutines.created",
+	}
+	GoRuntimeGoroutinesExited = Name{
+		Section: "process.runtime.go.goroutines.exited",
+		Prom:    "process_runtime_go_goroutines_exited_total",
+		OTEL:    "process.runtime.go.goroutines.exited",
+	}
+	GoRuntimeGCCycles = Name{
+		Section: "process.runtime.go.gc.cycles",
+		Prom:    "process_runtime_go_gc_cycles_total",
+		OTEL:    "process.runtime.go.gc.cycles",
+	}
+	GoRuntimeSTWPauseDuration = Name{
+		Section: "process.runtime.go.stw.pause.duration",
+		Prom:    "process_runtime_go_stw_pause_duration_seconds",
+		OTEL:    "process.runtime.go.stw.pause.duration",
+	}
+	GoRuntimeSchedLatency = Name{
+		Section: "process.runtime.go.sched.latency",
+		Prom:    "process_runtime_go_sched_latency_seconds",
+		OTEL:    "process.runtime.go.sched.latency",
+	}
+	TCPConnectionRTT = Name{
+		Section: "tcp.connection.rtt",
+		Prom:    "tcp_connection_rtt_seconds",
+		OTEL:    "tcp.connection.rtt",
+	}
+	TCPConnectionSetupDuration = Name{
+		Section: "tcp.connection.setup.duration",
+		Prom:    "tcp_connection_setup_duration_seconds",
+		OTEL:    "tcp.connection.setup.duration",
+	}
+	TCPConnectionRetransmits = Name{
+		Section: "tcp.connection.retransmits",
+		Prom:    "tcp_connection_retransmits_total",
+		OTEL:    "tcp.connection.retransmits",
+	}
+	TCPConnectionResets = Name{
+		Section: "tcp.connection.resets",
+		Prom:    "tcp_connection_resets_total",
+		OTEL:    "tcp.connection.resets",
+	}
+	TCPConnectionFailures = Name{
+		Section: "tcp.connection.failures",
+		Prom:    "tcp_connection_failures_total",
+		OTEL:    "tcp.connection.failures",
+	}
+	RedisCacheLookups = Name{
+		Section: "db.client.redis.cache.lookups",
+		Prom:    "db_client_redis_cache_lookups_total",
+		OTEL:    "db.client.redis.cache.lookups",
+	}
+	RedisPipelineSize = Name{
+		Section: "db.client.redis.pipeline.size",
+		Prom:    "db_client_redis_pipeline_size",
+		OTEL:    "db.client.redis.pipeline.size",
+	}
+	DNSLookupDuration = Name{
+		Section: "dns.lookup.duration",
+		Prom:    "dns_lookup_duration_seconds",
+		OTEL:    "dns.lookup.duration",
+	}
 	MessagingPublishDuration = Name{
 		Section: "messaging.publish.duration",
 		Prom:    "messaging_publish_duration_seconds",
@@ -101,8 +188,49 @@ var (
 		Prom:    "messaging_process_duration_seconds",
 		OTEL:    "messaging.process.duration",
 	}
+	MessagingKafkaMessages = Name{
+		Section: "messaging.kafka.messages",
+		Prom:    "messaging_kafka_messages_total",
+		OTEL:    "messaging.kafka.messages",
+	}
+	MessagingKafkaMessagesSize = Name{
+		Section: "messaging.kafka.messages.size",
+		Prom:    "messaging_kafka_messages_size_bytes_total",
+		OTEL:    "messaging.kafka.messages.size",
+	}
+	MessagingKafkaOffset = Name{
+		Section: "messaging.kafka.offset",
+		Prom:    "messaging_kafka_offset",
+		OTEL:    "messaging.kafka.offset",
+	}
+	MessagingKafkaConsumerLag = Name{
+		Section: "messaging.kafka.consumer.lag",
+		Prom:    "messaging_kafka_consumer_lag",
+		OTEL:    "messaging.kafka.consumer.lag",
+	}
+	SLOTarget = Name{
+		Section: "slo.target",
+		Prom:    "slo_target",
+		OTEL:    "slo.target",
+	}
+	SLOErrorBudgetRemaining = Name{
+		Section: "slo.error_budget.remaining",
+		Prom:    "slo_error_budget_remaining",
+		OTEL:    "slo.error_budget.remaining",
+	}
+	SLOBurnRate = Name{
+		Section: "slo.burn_rate",
+		Prom:    "slo_burn_rate",
+		OTEL:    "slo.burn_rate",
+	}
 )
 
+// Matches returns true if the user-provided metric name refers to this metric. The name
+// can be written in any of the formats accepted by the attributes.select section.
+func (n Name) Matches(name string) bool {
+	return normalizeMetric(Section(name)) == n.Section
+}
+
 // normalizeMetric will facilitate the user-input in the attributes.enable section.
 // The user can specify the Prometheus or OTEL notation, and can include or not
 // the units and aggregations for the metrics. Beyla will accept all the inputs
@@ -53,6 +53,13 @@ const (
 	MessagingOpType        = Name("messaging.operation.type")
 	MessagingSystem        = Name(semconv.MessagingSystemKey)
 	MessagingDestination   = Name(semconv.MessagingDestinationNameKey)
+	MessagingPartition     = Name("messaging.destination.partition.id")
+	DNSQuestionName        = Name("dns.question.name")
+	DNSQuestionType        = Name("dns.question.type")
+	DNSResponseCode        = Name("dns.response_code")
+	DNSAnswers             = Name("dns.answers")
+	DBRedisKeyPattern      = Name("db.redis.key_pattern")
+	DBRedisCacheResult     = Name("db.redis.cache.result")
 
 	K8sNamespaceName    = Name("k8s.namespace.name")
 	K8sPodName          = Name("k8s.pod.name")
@@ -144,6 +151,39 @@ const (
 	ServiceInstanceID = Name(semconv.ServiceInstanceIDKey)
 )
 
+// Go build information attributes, which are reported as resource attributes of the Go services
+const (
+	ServiceVersion = Name(semconv.ServiceVersionKey)
+	VCSRevision    = Name("vcs.revision")
+	GoModulePath   = Name("go.module.path")
+)
+
+// TCP connection health attributes
+const (
+	NetworkPeerAddress = Name(semconv2.NetworkPeerAddressKey)
+	// TCPRole values: client or server
+	TCPRole = Name("tcp.role")
+)
+
+// DropReason of the network packets that are dropped by the kernel, as reported by the
+// skb/kfree_skb tracepoint (e.g. NETFILTER_DROP, NO_SOCKET...)
+const DropReason = Name("drop.reason")
+
+// L7Protocol of the TCP connection of a network flow, as detected from its first payload bytes
+// (e.g. http, http2, tls, kafka...)
+const L7Protocol = Name("l7.protocol")
+
+// Service Level Objectives attributes
+const (
+	SLOName      = Name("slo.name")
+	SLOIndicator = Name("slo.sli")
+	SLOWindow    = Name("slo.window")
+)
+
+// OTELMetricOverflow labels the series where the label sets that exceed the cardinality limit of a metric are folded,
+// as defined by the OpenTelemetry SDK specification
+const OTELMetricOverflow = Name("otel.metric.overflow")
+
 // traces related attributes
 const (
 	// SQL
@@ -0,0 +1,358 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gobwas/glob"
+	"github.com/mariomac/pipes/pipe"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// keepAlivePeriod of the span stream connections, to prevent proxies and clients from closing them
+// when no spans are matching the client filters
+const keepAlivePeriod = 15 * time.Second
+
+// SpanStreamConfig enables an HTTP endpoint that streams, as server-sent events, the JSON
+// representation of the spans processed by Beyla, together with the service that generated them. Clients can filter the spans through the
+// following query parameters, which can be repeated to accept multiple values:
+//   - service: name of the service
+//   - namespace: namespace of the service
+//   - type: type of the span (e.g. HTTP, HTTPClient, GRPC, SQLClient...), case-insensitive
+//   - route: glob pattern for the route of the span, or its path if the route is unknown (e.g. /users/*)
+//   - status: status code (e.g. 404), status code class (e.g. 5xx) or "error"
+type SpanStreamConfig struct {
+	// Port where the span stream is served. The endpoint is disabled if zero.
+	Port int    `yaml:"port" env:"BEYLA_SPAN_STREAM_PORT"`
+	Path string `yaml:"path" env:"BEYLA_SPAN_STREAM_PATH"`
+	// MaxClients that can be simultaneously attached to the stream
+	MaxClients int `yaml:"max_clients" env:"BEYLA_SPAN_STREAM_MAX_CLIENTS"`
+	// ClientBufferLen is the number of spans that are buffered for each client. The spans
+	// that do not fit in the buffer of a slow client are dropped.
+	ClientBufferLen int `yaml:"client_buffer_len" env:"BEYLA_SPAN_STREAM_CLIENT_BUFFER_LEN"`
+}
+
+func (c *SpanStreamConfig) Enabled() bool {
+	return c.Port != 0
+}
+
+// Validate returns error if the span stream is enabled and its path can't be served
+func (c *SpanStreamConfig) Validate() error {
+	if c.Enabled() && !strings.HasPrefix(c.Path, "/") {
+		return fmt.Errorf("span_stream.path must start with '/'. Got: %q", c.Path)
+	}
+	return nil
+}
+
+func sslog() *slog.Logger {
+	return slog.With("component", "debug.SpanStream")
+}
+
+// SpanStreamNode returns a pipeline node that serves the received spans to the
+// clients attached to the span stream HTTP endpoint.
+func SpanStreamNode(ctx context.Context, cfg *SpanStreamConfig) pipe.FinalProvider[[]request.Span] {
+	return func() (pipe.FinalFunc[[]request.Span], error) {
+		if !cfg.Enabled() {
+			return pipe.IgnoreFinal[[]request.Span](), nil
+		}
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Port))
+		if err != nil {
+			return nil, fmt.Errorf("starting span stream endpoint: %w", err)
+		}
+		ss := newSpanStream(cfg)
+		go ss.serve(ctx, listener)
+		return ss.broadcastLoop, nil
+	}
+}
+
+type spanStream struct {
+	cfg  *SpanStreamConfig
+	log  *slog.Logger
+	done chan struct{}
+
+	mt      sync.RWMutex
+	clients map[*streamClient]struct{}
+}
+
+// streamedSpan adds, to the JSON representation of the spans, the service that
+// generated them
+type streamedSpan struct {
+	ServiceName      string        `json:"serviceName"`
+	ServiceNamespace string        `json:"serviceNamespace"`
+	ServiceInstance  string        `json:"serviceInstance"`
+	Span             *request.Span `json:"span"`
+}
+
+type streamClient struct {
+	filter  *spanFilter
+	events  chan []byte
+	dropped atomic.Int64
+}
+
+func newSpanStream(cfg *SpanStreamConfig) *spanStream {
+	return &spanStream{
+		cfg:     cfg,
+		log:     sslog(),
+		done:    make(chan struct{}),
+		clients: map[*streamClient]struct{}{},
+	}
+}
+
+func (ss *spanStream) serve(ctx context.Context, listener net.Listener) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(ss.cfg.Path, ss.handle)
+	server := http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-ss.done:
+		}
+		if err := server.Close(); err != nil {
+			ss.log.Warn("error closing HTTP server", "error", err)
+		}
+	}()
+	ss.log.Info("serving span stream", "address", listener.Addr().String(), "path", ss.cfg.Path)
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		ss.log.Error("span stream HTTP server ended unexpectedly", "error", err)
+	}
+}
+
+// broadcastLoop forwards each span to the clients whose filter accepts it
+func (ss *spanStream) broadcastLoop(input <-chan []request.Span) {
+	defer close(ss.done)
+	for spans := range input {
+		ss.broadcast(spans)
+	}
+}
+
+func (ss *spanStream) broadcast(spans []request.Span) {
+	ss.mt.RLock()
+	defer ss.mt.RUnlock()
+	if len(ss.clients) == 0 {
+		return
+	}
+	for i := range spans {
+		span := &spans[i]
+		// the span is serialized only once, and only if a client is interested in it
+		var data []byte
+		for client := range ss.clients {
+			if !client.filter.matches(span) {
+				continue
+			}
+			if data == nil {
+				var err error
+				if data, err = json.Marshal(streamedSpan{
+					ServiceName:      span.ServiceID.Name,
+					ServiceNamespace: span.ServiceID.Namespace,
+					ServiceInstance:  span.ServiceID.Instance,
+					Span:             span,
+				}); err != nil {
+					ss.log.Warn("can't serialize span to JSON", "error", err)
+					break
+				}
+			}
+			select {
+			case client.events <- data:
+			default:
+				client.dropped.Add(1)
+			}
+		}
+	}
+}
+
+func (ss *spanStream) attach(filter *spanFilter) (*streamClient, bool) {
+	ss.mt.Lock()
+	defer ss.mt.Unlock()
+	if ss.cfg.MaxClients > 0 && len(ss.clients) >= ss.cfg.MaxClients {
+		return nil, false
+	}
+	client := &streamClient{filter: filter, events: make(chan []byte, max(ss.cfg.ClientBufferLen, 1))}
+	ss.clients[client] = struct{}{}
+	return client, true
+}
+
+func (ss *spanStream) detach(client *streamClient) {
+	ss.mt.Lock()
+	defer ss.mt.Unlock()
+	delete(ss.clients, client)
+}
+
+func (ss *spanStream) handle(rw http.ResponseWriter, req *http.Request) {
+	filter, err := parseSpanFilter(req.URL.Query())
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		http.Error(rw, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	client, ok := ss.attach(filter)
+	if !ok {
+		http.Error(rw, "too many clients attached to the span stream", http.StatusServiceUnavailable)
+		return
+	}
+	defer ss.detach(client)
+
+	llog := ss.log.With("remoteAddr", req.RemoteAddr)
+	llog.Debug("client attached to the span stream", "query", req.URL.RawQuery)
+	defer llog.Debug("client detached from the span stream")
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.Header().Set("Connection", "keep-alive")
+	rw.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAlivePeriod)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case <-req.Context().Done():
+			return
+		case <-ss.done:
+			return
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(rw, ": keep-alive\n\n")
+		case data := <-client.events:
+			if dropped := client.dropped.Swap(0); dropped > 0 {
+				// notifies the client about the spans that didn't fit into its buffer
+				_, err = fmt.Fprintf(rw, "event: dropped\ndata: %d\n\n", dropped)
+			}
+			if err == nil {
+				_, err = fmt.Fprintf(rw, "data: %s\n\n", data)
+			}
+		}
+		if err != nil {
+			llog.Debug("can't write into the span stream", "error", err)
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// spanFilter accepts the spans matching all the defined conditions. For each condition, the
+// span must match any of its values.
+type spanFilter struct {
+	services   []string
+	namespaces []string
+	types      []string
+	routes     []glob.Glob
+	statuses   []statusMatcher
+}
+
+type statusMatcher func(span *request.Span) bool
+
+func parseSpanFilter(query url.Values) (*spanFilter, error) {
+	f := spanFilter{
+		services:   query["service"],
+		namespaces: query["namespace"],
+		types:      query["type"],
+	}
+	for _, route := range query["route"] {
+		g, err := glob.Compile(route)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route pattern %q: %w", route, err)
+		}
+		f.routes = append(f.routes, g)
+	}
+	for _, status := range query["status"] {
+		matcher, err := parseStatusMatcher(status)
+		if err != nil {
+			return nil, err
+		}
+		f.statuses = append(f.statuses, matcher)
+	}
+	return &f, nil
+}
+
+func parseStatusMatcher(status string) (statusMatcher, error) {
+	status = strings.ToLower(strings.TrimSpace(status))
+	if status == "error" {
+		return func(span *request.Span) bool {
+			return request.SpanStatusCode(span) == codes.Error
+		}, nil
+	}
+	if len(status) == 3 && strings.HasSuffix(status, "xx") {
+		class, err := strconv.Atoi(status[:1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid status class %q", status)
+		}
+		return func(span *request.Span) bool {
+			return span.Status/100 == class
+		}, nil
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return nil, fmt.Errorf("invalid status %q. Expected a code (e.g. 404), a class (e.g. 5xx) or 'error'", status)
+	}
+	return func(span *request.Span) bool {
+		return span.Status == code
+	}, nil
+}
+
+func (f *spanFilter) matches(span *request.Span) bool {
+	if len(f.services) > 0 && !slices.Contains(f.services, span.ServiceID.Name) {
+		return false
+	}
+	if len(f.namespaces) > 0 && !slices.Contains(f.namespaces, span.ServiceID.Namespace) {
+		return false
+	}
+	if len(f.types) > 0 && !anyEqualFold(f.types, span.Type.String()) {
+		return false
+	}
+	if len(f.routes) > 0 && !anyRouteMatch(f.routes, span) {
+		return false
+	}
+	if len(f.statuses) > 0 && !anyStatusMatch(f.statuses, span) {
+		return false
+	}
+	return true
+}
+
+func anyEqualFold(values []string, val string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, val) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyRouteMatch(patterns []glob.Glob, span *request.Span) bool {
+	route := span.Route
+	if route == "" {
+		route = span.Path
+	}
+	for _, p := range patterns {
+		if p.Match(route) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyStatusMatch(matchers []statusMatcher, span *request.Span) bool {
+	for _, m := range matchers {
+		if m(span) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package debug
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func TestSpanFilter(t *testing.T) {
+	users := &request.Span{Type: request.EventTypeHTTP, Route: "/users/{id}", Path: "/users/33", Status: 200,
+		ServiceID: svc.ID{Name: "users", Namespace: "shop"}}
+	usersErr := &request.Span{Type: request.EventTypeHTTP, Route: "/users/{id}", Path: "/users/44", Status: 503,
+		ServiceID: svc.ID{Name: "users", Namespace: "shop"}}
+	orders := &request.Span{Type: request.EventTypeHTTPClient, Path: "/orders", Status: 404,
+		ServiceID: svc.ID{Name: "frontend", Namespace: "shop"}}
+	query := &request.Span{Type: request.EventTypeSQLClient, Path: "SELECT", Status: 0,
+		ServiceID: svc.ID{Name: "users", Namespace: "db"}}
+
+	type testCase struct {
+		query   string
+		matches []*request.Span
+	}
+	for _, tc := range []testCase{
+		{query: "", matches: []*request.Span{users, usersErr, orders, query}},
+		{query: "service=users", matches: []*request.Span{users, usersErr, query}},
+		{query: "service=users&service=frontend&namespace=shop", matches: []*request.Span{users, usersErr, orders}},
+		{query: "type=httpclient&type=SQLClient", matches: []*request.Span{orders, query}},
+		{query: "route=/users/*", matches: []*request.Span{users, usersErr}},
+		{query: "route=/orders", matches: []*request.Span{orders}},
+		// as in the rest of Beyla, wildcards also match the path separators
+		{query: "route=/*", matches: []*request.Span{users, usersErr, orders}},
+		{query: "status=5xx", matches: []*request.Span{usersErr}},
+		{query: "status=404&status=200", matches: []*request.Span{users, orders}},
+		{query: "status=error&service=users", matches: []*request.Span{usersErr}},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			values, err := url.ParseQuery(tc.query)
+			require.NoError(t, err)
+			filter, err := parseSpanFilter(values)
+			require.NoError(t, err)
+			var matches []*request.Span
+			for _, s := range []*request.Span{users, usersErr, orders, query} {
+				if filter.matches(s) {
+					matches = append(matches, s)
+				}
+			}
+			assert.Equal(t, tc.matches, matches)
+		})
+	}
+}
+
+func TestSpanFilter_Invalid(t *testing.T) {
+	for _, query := range []string{"status=abc", "status=axx", "route=[a-"} {
+		values, err := url.ParseQuery(query)
+		require.NoError(t, err)
+		_, err = parseSpanFilter(values)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestSpanStreamConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&SpanStreamConfig{Port: 8999, Path: "/spans"}).Validate())
+	assert.NoError(t, (&SpanStreamConfig{Path: ""}).Validate())
+	assert.Error(t, (&SpanStreamConfig{Port: 8999, Path: ""}).Validate())
+	assert.Error(t, (&SpanStreamConfig{Port: 8999, Path: "spans"}).Validate())
+}
+
+func TestSpanStream(t *testing.T) {
+	ss := newSpanStream(&SpanStreamConfig{Path: "/spans", MaxClients: 1, ClientBufferLen: 10})
+	server := httptest.NewServer(http.HandlerFunc(ss.handle))
+	defer server.Close()
+
+	input := make(chan []request.Span, 10)
+	go ss.broadcastLoop(input)
+	defer close(input)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/spans?service=users", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// only one client is accepted
+	second, err := http.Get(server.URL + "/spans")
+	require.NoError(t, err)
+	second.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, second.StatusCode)
+
+	input <- []request.Span{
+		{Type: request.EventTypeHTTP, Method: "GET", Path: "/ignored", ServiceID: svc.ID{Name: "other"}},
+		{Type: request.EventTypeHTTP, Method: "GET", Path: "/users", Status: 200, ServiceID: svc.ID{Name: "users"}},
+	}
+
+	lines := bufio.NewScanner(resp.Body)
+	require.True(t, lines.Scan())
+	line := lines.Text()
+	require.True(t, strings.HasPrefix(line, "data: "), line)
+	event := struct {
+		ServiceName string `json:"serviceName"`
+		Span        struct {
+			Type       string            `json:"type"`
+			Attributes map[string]string `json:"attributes"`
+		} `json:"span"`
+	}{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+	assert.Equal(t, "users", event.ServiceName)
+	assert.Equal(t, "HTTP", event.Span.Type)
+	assert.Equal(t, "/users", event.Span.Attributes["url"])
+}
@@ -0,0 +1,159 @@
+package expire
+
+import (
+	"log/slog"
+
+	"github.com/grafana/beyla/pkg/export/attributes"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+// CardinalityLimits caps the number of distinct label sets (series) that a metric can store for
+// each service. Once a limit is reached, the new label sets of the service are folded into a single
+// overflow series, labeled as otel.metric.overflow="true".
+type CardinalityLimits struct {
+	// MaxSeries of each metric, for each service. Zero means no limit.
+	MaxSeries int `yaml:"max_series" env:"MAX_SERIES"`
+	// Metrics overrides MaxSeries for the given metric names. The metric names can be written in
+	// the same formats as in the attributes.select section.
+	Metrics map[string]int `yaml:"metrics"`
+	// Services limits the number of series, for each metric, of the services with the
+	// given names. The services can be specified as "name" or "namespace/name".
+	Services map[string]int `yaml:"services"`
+}
+
+func (c *CardinalityLimits) Enabled() bool {
+	return c.MaxSeries > 0 || len(c.Metrics) > 0 || len(c.Services) > 0
+}
+
+// SeriesLimit returns the series limit of a given metric, or nil if it is not limited.
+// The onOverflow function is invoked each time that a label set is folded into the overflow series.
+func (c *CardinalityLimits) SeriesLimit(
+	metric attributes.Name,
+	labelNames []string,
+	onOverflow func(service *svc.ID, label string),
+) *SeriesLimit {
+	if c == nil || !c.Enabled() {
+		return nil
+	}
+	metricLimit := c.MaxSeries
+	for name, limit := range c.Metrics {
+		if metric.Matches(name) {
+			metricLimit = limit
+		}
+	}
+	if metricLimit <= 0 && len(c.Services) == 0 {
+		return nil
+	}
+	return &SeriesLimit{
+		LabelNames: labelNames,
+		OnOverflow: onOverflow,
+		For: func(service *svc.ID) int {
+			serviceLimit, ok := c.Services[service.String()]
+			if !ok {
+				serviceLimit = c.Services[service.Name]
+			}
+			// the lowest of both limits is applied
+			if metricLimit <= 0 || (serviceLimit > 0 && serviceLimit < metricLimit) {
+				return serviceLimit
+			}
+			return metricLimit
+		},
+	}
+}
+
+// SeriesLimit caps the number of series that a service can store in an ExpiryMap
+type SeriesLimit struct {
+	// LabelNames of the series, to report which label causes the overflow
+	LabelNames []string
+	// For returns the maximum number of series of the given service. Zero means no limit.
+	For func(service *svc.ID) int
+	// OnOverflow is invoked each time that a label set is folded into the overflow series
+	OnOverflow func(service *svc.ID, label string)
+}
+
+// serviceSeries accounts the series that are stored for a given service
+type serviceSeries struct {
+	limit int
+	count int
+	// distinct values of each label, and the number of series using each value.
+	// Used to find which label is responsible of the overflow.
+	values      []map[string]int
+	overflowing bool
+}
+
+// seriesLimiter is not safe for concurrent use. It is protected by the ExpiryMap lock.
+type seriesLimiter struct {
+	log      *slog.Logger
+	limit    *SeriesLimit
+	services map[string]*serviceSeries
+}
+
+func newSeriesLimiter(limit *SeriesLimit) *seriesLimiter {
+	return &seriesLimiter{
+		log:      slog.With("component", "expire.SeriesLimiter"),
+		limit:    limit,
+		services: map[string]*serviceSeries{},
+	}
+}
+
+// admit returns true and accounts the new label set, if the service didn't exceed its limit.
+func (sl *seriesLimiter) admit(service *svc.ID, serviceKey string, lbls []string) bool {
+	ss, ok := sl.services[serviceKey]
+	if !ok {
+		ss = &serviceSeries{limit: sl.limit.For(service), values: make([]map[string]int, len(sl.limit.LabelNames))}
+		for i := range ss.values {
+			ss.values[i] = map[string]int{}
+		}
+		sl.services[serviceKey] = ss
+	}
+	if ss.limit > 0 && ss.count >= ss.limit {
+		label := ss.topLabel(sl.limit.LabelNames)
+		if !ss.overflowing {
+			ss.overflowing = true
+			sl.log.Warn("service reached the maximum number of series for a metric. New label sets are"+
+				" reported as an overflow series", "service", serviceKey, "limit", ss.limit, "label", label)
+		}
+		if sl.limit.OnOverflow != nil {
+			sl.limit.OnOverflow(service, label)
+		}
+		return false
+	}
+	ss.count++
+	for i := range ss.values {
+		if i < len(lbls) {
+			ss.values[i][lbls[i]]++
+		}
+	}
+	return true
+}
+
+func (sl *seriesLimiter) release(serviceKey string, lbls []string) {
+	ss, ok := sl.services[serviceKey]
+	if !ok {
+		return
+	}
+	ss.count--
+	ss.overflowing = false
+	if ss.count <= 0 {
+		delete(sl.services, serviceKey)
+		return
+	}
+	for i := range ss.values {
+		if i < len(lbls) {
+			if ss.values[i][lbls[i]]--; ss.values[i][lbls[i]] <= 0 {
+				delete(ss.values[i], lbls[i])
+			}
+		}
+	}
+}
+
+// topLabel returns the label with the highest number of distinct values
+func (ss *serviceSeries) topLabel(names []string) string {
+	top, topValues := "", 0
+	for i, values := range ss.values {
+		if len(values) > topValues && i < len(names) {
+			top, topValues = names[i], len(values)
+		}
+	}
+	return top
+}
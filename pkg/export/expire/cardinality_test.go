@@ -0,0 +1,77 @@
+package expire
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/export/attributes"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func TestCardinalityLimits_SeriesLimit(t *testing.T) {
+	limits := CardinalityLimits{
+		MaxSeries: 100,
+		Metrics:   map[string]int{"http_server_request_duration_seconds": 50},
+		Services:  map[string]int{"noisy": 10, "prod/huge": 500},
+	}
+	assert.Nil(t, (&CardinalityLimits{}).SeriesLimit(attributes.HTTPServerDuration, nil, nil))
+
+	limit := limits.SeriesLimit(attributes.HTTPServerDuration, nil, nil)
+	require.NotNil(t, limit)
+	assert.Equal(t, 50, limit.For(&svc.ID{Name: "foo"}))
+	assert.Equal(t, 10, limit.For(&svc.ID{Name: "noisy", Namespace: "dev"}))
+	// the lowest limit is applied
+	assert.Equal(t, 50, limit.For(&svc.ID{Name: "huge", Namespace: "prod"}))
+
+	limit = limits.SeriesLimit(attributes.RPCServerDuration, nil, nil)
+	require.NotNil(t, limit)
+	assert.Equal(t, 100, limit.For(&svc.ID{Name: "foo"}))
+	assert.Equal(t, 100, limit.For(&svc.ID{Name: "huge", Namespace: "prod"}))
+
+	// only service limits
+	limit = (&CardinalityLimits{Services: map[string]int{"noisy": 10}}).SeriesLimit(attributes.RPCServerDuration, nil, nil)
+	require.NotNil(t, limit)
+	assert.Equal(t, 0, limit.For(&svc.ID{Name: "foo"}))
+	assert.Equal(t, 10, limit.For(&svc.ID{Name: "noisy"}))
+}
+
+func TestExpiryMap_Limited(t *testing.T) {
+	now := time.Now()
+	clock := func() time.Time { return now }
+	type overflow struct{ service, label string }
+	var overflows []overflow
+	limits := CardinalityLimits{MaxSeries: 2}
+	em := NewLimitedExpiryMap[string](clock, time.Minute, limits.SeriesLimit(attributes.HTTPServerDuration,
+		[]string{"method", "path"}, func(service *svc.ID, label string) {
+			overflows = append(overflows, overflow{service: service.String(), label: label})
+		}))
+	instancer := func(val string) func() string {
+		return func() string { return val }
+	}
+	overflowInstancer := instancer("overflow")
+	foo, bar := &svc.ID{Name: "foo"}, &svc.ID{Name: "bar"}
+
+	assert.Equal(t, "a", em.GetOrCreateLimited(foo, []string{"GET", "/a"}, instancer("a"), overflowInstancer))
+	assert.Equal(t, "b", em.GetOrCreateLimited(foo, []string{"GET", "/b"}, instancer("b"), overflowInstancer))
+	// existing series are still accessible
+	assert.Equal(t, "a", em.GetOrCreateLimited(foo, []string{"GET", "/a"}, instancer("x"), overflowInstancer))
+	// new series of the service are folded into the overflow
+	assert.Equal(t, "overflow", em.GetOrCreateLimited(foo, []string{"GET", "/c"}, instancer("c"), overflowInstancer))
+	assert.Equal(t, "overflow", em.GetOrCreateLimited(foo, []string{"GET", "/d"}, instancer("d"), overflowInstancer))
+	assert.Equal(t, []overflow{{service: "foo", label: "path"}, {service: "foo", label: "path"}}, overflows)
+	// other services have their own limits
+	assert.Equal(t, "c", em.GetOrCreateLimited(bar, []string{"GET", "/c"}, instancer("c"), overflowInstancer))
+	assert.ElementsMatch(t, []string{"a", "b", "c", "overflow"}, em.All())
+
+	// when the series expire, new series are accepted again
+	now = now.Add(30 * time.Second)
+	em.GetOrCreateLimited(foo, []string{"GET", "/a"}, instancer("a"), overflowInstancer)
+	now = now.Add(45 * time.Second)
+	assert.ElementsMatch(t, []string{"b", "c", "overflow"}, em.DeleteExpired())
+	assert.Equal(t, "e", em.GetOrCreateLimited(foo, []string{"POST", "/e"}, instancer("e"), overflowInstancer))
+	assert.Equal(t, "overflow", em.GetOrCreateLimited(foo, []string{"GET", "/f"}, instancer("f"), overflowInstancer))
+	assert.Len(t, overflows, 3)
+}
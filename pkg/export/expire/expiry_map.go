@@ -4,8 +4,14 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/grafana/beyla/pkg/internal/svc"
 )
 
+// overflowKey stores the overflow series of a limited ExpiryMap. It can't collide with
+// any label set key, as the label values never contain a null character.
+const overflowKey = "\x00overflow"
+
 type Clock func() time.Time
 
 // ExpiryMap stores elements in a synchronized map, and removes them if they haven't been
@@ -16,12 +22,16 @@ type ExpiryMap[T any] struct {
 	mt      sync.RWMutex
 	ttl     time.Duration
 	entries map[string]*entry[T]
+	// limiter is nil if the number of series is not limited
+	limiter *seriesLimiter
 }
 
 type entry[T any] struct {
 	lastAccess  time.Time
 	labelValues []string
 	val         T
+	// service key, for the entries accounted by the limiter
+	service string
 }
 
 // NewExpiryMap creates an expiry map given a Clock implementation and a TTL.
@@ -36,6 +46,16 @@ func NewExpiryMap[T any](clock Clock, ttl time.Duration) *ExpiryMap[T] {
 	return em
 }
 
+// NewLimitedExpiryMap works as NewExpiryMap, but the number of entries that each service can create
+// through the GetOrCreateLimited method is limited. If the limit is nil, the number of entries is not limited.
+func NewLimitedExpiryMap[T any](clock Clock, ttl time.Duration, limit *SeriesLimit) *ExpiryMap[T] {
+	em := NewExpiryMap[T](clock, ttl)
+	if limit != nil {
+		em.limiter = newSeriesLimiter(limit)
+	}
+	return em
+}
+
 // GetOrCreate returns the stored object for the given slice of label
 // values. If that combination of
 // label values is accessed for the first time, a new instance is created.
@@ -64,6 +84,49 @@ func (ex *ExpiryMap[T]) GetOrCreate(lbls []string, instancer func() T) T {
 	return instance
 }
 
+// GetOrCreateLimited works as GetOrCreate, but the new label sets are accounted for the given service. If the
+// service reached its maximum number of label sets, the overflow instance is returned, which is created
+// on demand by the overflow function.
+func (ex *ExpiryMap[T]) GetOrCreateLimited(service *svc.ID, lbls []string, instancer, overflow func() T) T {
+	if ex.limiter == nil {
+		return ex.GetOrCreate(lbls, instancer)
+	}
+	now := ex.clock()
+
+	h := labelsKey(lbls)
+	ex.mt.RLock()
+	e, ok := ex.entries[h]
+	ex.mt.RUnlock()
+	ex.mt.Lock()
+	defer ex.mt.Unlock()
+	if !ok {
+		// checking again, in case another goroutine created the entry in the meantime
+		e, ok = ex.entries[h]
+	}
+	if ok {
+		e.lastAccess = now
+		return e.val
+	}
+	serviceKey := service.String()
+	if !ex.limiter.admit(service, serviceKey, lbls) {
+		oe, ok := ex.entries[overflowKey]
+		if !ok {
+			oe = &entry[T]{val: overflow()}
+			ex.entries[overflowKey] = oe
+		}
+		oe.lastAccess = now
+		return oe.val
+	}
+	instance := instancer()
+	ex.entries[h] = &entry[T]{
+		labelValues: lbls,
+		lastAccess:  now,
+		val:         instance,
+		service:     serviceKey,
+	}
+	return instance
+}
+
 // DeleteExpired entries and return their label set
 func (ex *ExpiryMap[T]) DeleteExpired() []T {
 	var delKeys []string
@@ -79,6 +142,7 @@ func (ex *ExpiryMap[T]) DeleteExpired() []T {
 	ex.mt.RUnlock()
 	ex.mt.Lock()
 	for _, k := range delKeys {
+		ex.release(k)
 		delete(ex.entries, k)
 	}
 	ex.mt.Unlock()
@@ -92,11 +156,22 @@ func (ex *ExpiryMap[T]) DeleteAll() []T {
 	entries := make([]T, 0, len(ex.entries))
 	for k, e := range ex.entries {
 		entries = append(entries, e.val)
+		ex.release(k)
 		delete(ex.entries, k)
 	}
 	return entries
 }
 
+// release the limiter accounting of the entry with the given key. It must be invoked with the lock held.
+func (ex *ExpiryMap[T]) release(key string) {
+	if ex.limiter == nil {
+		return
+	}
+	if e, ok := ex.entries[key]; ok && e.service != "" {
+		ex.limiter.release(e.service, e.labelValues)
+	}
+}
+
 // All returns an array with all the stored entries. It might contain expired entries
 // if DeleteExpired is not invoked before it.
 // TODO: use https://tip.golang.org/wiki/RangefuncExperiment when available
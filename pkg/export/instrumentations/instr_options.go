@@ -9,6 +9,8 @@ const (
 	InstrumentationSQL   = "sql"
 	InstrumentationRedis = "redis"
 	InstrumentationKafka = "kafka"
+	InstrumentationMongo = "mongo"
+	InstrumentationDNS   = "dns"
 )
 
 const (
@@ -18,6 +20,8 @@ const (
 	flagSQL
 	flagRedis
 	flagKafka
+	flagMongo
+	flagDNS
 )
 
 func strToFlag(str string) InstrumentationSelection {
@@ -34,6 +38,10 @@ func strToFlag(str string) InstrumentationSelection {
 		return flagRedis
 	case InstrumentationKafka:
 		return flagKafka
+	case InstrumentationMongo:
+		return flagMongo
+	case InstrumentationDNS:
+		return flagDNS
 	}
 	return 0
 }
@@ -63,8 +71,12 @@ func (s InstrumentationSelection) RedisEnabled() bool {
 	return s&flagRedis != 0
 }
 
+func (s InstrumentationSelection) MongoEnabled() bool {
+	return s&flagMongo != 0
+}
+
 func (s InstrumentationSelection) DBEnabled() bool {
-	return s.SQLEnabled() || s.RedisEnabled()
+	return s.SQLEnabled() || s.RedisEnabled() || s.MongoEnabled()
 }
 
 func (s InstrumentationSelection) KafkaEnabled() bool {
@@ -74,3 +86,7 @@ func (s InstrumentationSelection) KafkaEnabled() bool {
 func (s InstrumentationSelection) MQEnabled() bool {
 	return s.KafkaEnabled()
 }
+
+func (s InstrumentationSelection) DNSEnabled() bool {
+	return s&flagDNS != 0
+}
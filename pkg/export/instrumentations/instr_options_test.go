@@ -16,7 +16,9 @@ func TestInstrumentationSelection(t *testing.T) {
 	assert.False(t, is.KafkaEnabled())
 	assert.False(t, is.MQEnabled())
 
-	is = NewInstrumentationSelection([]string{"grpc", "kafka"})
+	assert.False(t, is.DNSEnabled())
+
+	is = NewInstrumentationSelection([]string{"grpc", "kafka", "dns"})
 	assert.False(t, is.HTTPEnabled())
 	assert.False(t, is.SQLEnabled())
 	assert.False(t, is.DBEnabled())
@@ -24,6 +26,7 @@ func TestInstrumentationSelection(t *testing.T) {
 	assert.True(t, is.GRPCEnabled())
 	assert.True(t, is.KafkaEnabled())
 	assert.True(t, is.MQEnabled())
+	assert.True(t, is.DNSEnabled())
 }
 
 func TestInstrumentationSelection_All(t *testing.T) {
@@ -35,6 +38,7 @@ func TestInstrumentationSelection_All(t *testing.T) {
 	assert.True(t, is.GRPCEnabled())
 	assert.True(t, is.KafkaEnabled())
 	assert.True(t, is.MQEnabled())
+	assert.True(t, is.DNSEnabled())
 }
 
 func TestInstrumentationSelection_None(t *testing.T) {
@@ -46,4 +50,5 @@ func TestInstrumentationSelection_None(t *testing.T) {
 	assert.False(t, is.GRPCEnabled())
 	assert.False(t, is.KafkaEnabled())
 	assert.False(t, is.MQEnabled())
+	assert.False(t, is.DNSEnabled())
 }
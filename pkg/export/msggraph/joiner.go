@@ -0,0 +1,152 @@
+package msggraph
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/grafana/beyla/pkg/export/expire"
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// maxPublications limits the memory used to remember the publications that are pending to be joined
+const maxPublications = 10_000
+
+// purgesPerTTL limits how often the publications are purged when the Joiner is full, as each
+// purge scans all of them. Meanwhile, the new publications are discarded.
+const purgesPerTTL = 10
+
+type partitionKey struct {
+	node      string
+	partition int
+}
+
+// publication time of the messages, as reported by the producer span, and the time
+// it was seen by the Joiner, used for expiration
+type publication struct {
+	published time.Time
+	seen      time.Time
+}
+
+// batch of messages published in a partition
+type batch struct {
+	publication
+	firstOffset int64
+	lastOffset  int64
+}
+
+// Joiner matches the consumer spans with the producer spans that published the consumed messages,
+// to calculate the latency between the publication and the processing of the messages.
+// Messages are matched by their trace ID, when the trace context is propagated in the messages,
+// or by their partition offsets, when they could be parsed from the captured messages.
+// Only the publications observed by the same Beyla instance can be matched.
+// It is not safe for concurrent use.
+type Joiner struct {
+	clock expire.Clock
+	ttl   time.Duration
+
+	byTrace     map[trace.TraceID]publication
+	byPartition map[partitionKey][]batch
+	stored      int
+	lastPurge   time.Time
+}
+
+// NewJoiner returns a Joiner that forgets the publications older than the provided TTL.
+func NewJoiner(clock expire.Clock, ttl time.Duration) *Joiner {
+	return &Joiner{
+		clock:       clock,
+		ttl:         ttl,
+		byTrace:     map[trace.TraceID]publication{},
+		byPartition: map[partitionKey][]batch{},
+		lastPurge:   clock(),
+	}
+}
+
+// Join remembers the publications from producer spans, and returns the time since the messages
+// of a consumer span were published. The second value is false if the span is not a consumer,
+// or no publication could be matched.
+func (j *Joiner) Join(span *request.Span) (time.Duration, bool) {
+	node, producer, ok := Node(span)
+	if !ok {
+		return 0, false
+	}
+	if producer {
+		j.published(node, span)
+		return 0, false
+	}
+	return j.processed(node, span)
+}
+
+func (j *Joiner) published(node string, span *request.Span) {
+	now := j.clock()
+	sincePurge := now.Sub(j.lastPurge)
+	if sincePurge > j.ttl || (j.stored >= maxPublications && sincePurge > j.ttl/purgesPerTTL) {
+		j.purge(now)
+	}
+	if j.stored >= maxPublications {
+		return
+	}
+	pub := publication{published: span.Timings().End, seen: now}
+	if span.TraceID.IsValid() {
+		if _, ok := j.byTrace[span.TraceID]; !ok {
+			j.stored++
+		}
+		j.byTrace[span.TraceID] = pub
+	}
+	if info := span.Messaging; info != nil && info.Offset >= 0 && info.Messages > 0 {
+		key := partitionKey{node: node, partition: info.Partition}
+		j.byPartition[key] = append(j.byPartition[key], batch{
+			publication: pub,
+			firstOffset: info.Offset - int64(info.Messages) + 1,
+			lastOffset:  info.Offset,
+		})
+		j.stored++
+	}
+}
+
+func (j *Joiner) processed(node string, span *request.Span) (time.Duration, bool) {
+	var pub publication
+	ok := false
+	if span.TraceID.IsValid() {
+		pub, ok = j.byTrace[span.TraceID]
+	}
+	if info := span.Messaging; !ok && info != nil && info.Offset >= 0 && info.Messages > 0 {
+		first := info.Offset - int64(info.Messages) + 1
+		// the oldest published batch that was consumed
+		for _, b := range j.byPartition[partitionKey{node: node, partition: info.Partition}] {
+			if b.firstOffset <= info.Offset && b.lastOffset >= first {
+				pub, ok = b.publication, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return 0, false
+	}
+	return max(span.Timings().End.Sub(pub.published), 0), true
+}
+
+// purge forgets the publications that are older than the TTL
+func (j *Joiner) purge(now time.Time) {
+	j.lastPurge = now
+	oldest := now.Add(-j.ttl)
+	for id, pub := range j.byTrace {
+		if pub.seen.Before(oldest) {
+			delete(j.byTrace, id)
+			j.stored--
+		}
+	}
+	for key, batches := range j.byPartition {
+		// batches are sorted by the time they were seen
+		i := 0
+		for i < len(batches) && batches[i].seen.Before(oldest) {
+			i++
+		}
+		j.stored -= i
+		if i == len(batches) {
+			delete(j.byPartition, key)
+		} else if i > 0 {
+			j.byPartition[key] = append(batches[:0:0], batches[i:]...)
+		}
+	}
+}
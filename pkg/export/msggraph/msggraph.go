@@ -0,0 +1,113 @@
+// Package msggraph provides the service graph edges for the services that communicate
+// asynchronously through a messaging system. Producer and consumer spans are not directly
+// connected, so each destination (e.g. a Kafka topic) is represented as a virtual node
+// between them: orders-api → [kafka:orders] → billing-worker
+package msggraph
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/grafana/beyla/pkg/export/attributes"
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+const (
+	kindProducer = "SPAN_KIND_PRODUCER"
+	kindConsumer = "SPAN_KIND_CONSUMER"
+)
+
+// Edge of the service graph between a service and the virtual node of a messaging destination
+type Edge struct {
+	Client          string
+	ClientNamespace string
+	Server          string
+	ServerNamespace string
+}
+
+// Node returns the name of the virtual service graph node that represents the messaging
+// destination of a producer or consumer span (e.g. kafka:orders). The second value is true
+// if the span is a producer. The last value is false if the span is neither a producer nor
+// a consumer, or its destination is unknown.
+func Node(span *request.Span) (string, bool, bool) {
+	if span.Path == "" {
+		return "", false, false
+	}
+	var producer bool
+	switch span.ServiceGraphKind() {
+	case kindProducer:
+		producer = true
+	case kindConsumer:
+		producer = false
+	default:
+		return "", false, false
+	}
+	return system(span) + ":" + span.Path, producer, true
+}
+
+// EdgeOf returns the service graph edge from the producer service to the destination node,
+// or from the destination node to the consumer service. It returns false if the span is
+// neither a producer nor a consumer.
+func EdgeOf(span *request.Span) (Edge, bool) {
+	node, producer, ok := Node(span)
+	if !ok {
+		return Edge{}, false
+	}
+	if producer {
+		return Edge{
+			Client:          request.SpanPeer(span),
+			ClientNamespace: span.ServiceID.Namespace,
+			Server:          node,
+		}, true
+	}
+	return Edge{
+		Client:          node,
+		Server:          request.SpanPeer(span),
+		ServerNamespace: span.ServiceID.Namespace,
+	}, true
+}
+
+// IsClientSide returns whether the span must be accounted as the client side of its service graph
+// edge. Consumers are the server side of the edge that goes from the destination node.
+func IsClientSide(span *request.Span) bool {
+	if _, producer, ok := Node(span); ok {
+		return producer
+	}
+	return span.IsClientSpan()
+}
+
+// SpanOTELGetters extends request.SpanOTELGetters by replacing the client and server
+// attributes of producer and consumer spans by the edges to/from their destination node.
+func SpanOTELGetters(name attr.Name) (attributes.Getter[*request.Span, attribute.KeyValue], bool) {
+	getter, ok := request.SpanOTELGetters(name)
+	if !ok {
+		return nil, false
+	}
+	var edgeGetter func(Edge) attribute.KeyValue
+	switch name {
+	case attr.Client:
+		edgeGetter = func(e Edge) attribute.KeyValue { return request.ClientMetric(e.Client) }
+	case attr.ClientNamespace:
+		edgeGetter = func(e Edge) attribute.KeyValue { return request.ClientNamespaceMetric(e.ClientNamespace) }
+	case attr.Server:
+		edgeGetter = func(e Edge) attribute.KeyValue { return request.ServerMetric(e.Server) }
+	case attr.ServerNamespace:
+		edgeGetter = func(e Edge) attribute.KeyValue { return request.ServerNamespaceMetric(e.ServerNamespace) }
+	default:
+		return getter, true
+	}
+	return func(span *request.Span) attribute.KeyValue {
+		if edge, ok := EdgeOf(span); ok {
+			return edgeGetter(edge)
+		}
+		return getter(span)
+	}, true
+}
+
+func system(span *request.Span) string {
+	switch span.Type {
+	case request.EventTypeKafkaClient, request.EventTypeKafkaServer:
+		return "kafka"
+	}
+	return "unknown"
+}
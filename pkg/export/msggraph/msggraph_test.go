@@ -0,0 +1,154 @@
+package msggraph
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func publishSpan(end time.Duration, info *request.MessagingInfo) *request.Span {
+	return &request.Span{
+		Type: request.EventTypeKafkaClient, Method: request.MessagingPublish, Path: "orders",
+		PeerName: "orders-api", ServiceID: svc.ID{Name: "orders-api", Namespace: "shop"},
+		End: end.Nanoseconds(), Messaging: info,
+	}
+}
+
+func processSpan(end time.Duration, info *request.MessagingInfo) *request.Span {
+	return &request.Span{
+		Type: request.EventTypeKafkaClient, Method: request.MessagingProcess, Path: "orders",
+		PeerName: "billing-worker", ServiceID: svc.ID{Name: "billing-worker", Namespace: "billing"},
+		End: end.Nanoseconds(), Messaging: info,
+	}
+}
+
+func TestEdgeOf(t *testing.T) {
+	edge, ok := EdgeOf(publishSpan(0, nil))
+	require.True(t, ok)
+	assert.Equal(t, Edge{Client: "orders-api", ClientNamespace: "shop", Server: "kafka:orders"}, edge)
+	assert.True(t, IsClientSide(publishSpan(0, nil)))
+
+	edge, ok = EdgeOf(processSpan(0, nil))
+	require.True(t, ok)
+	assert.Equal(t, Edge{Client: "kafka:orders", Server: "billing-worker", ServerNamespace: "billing"}, edge)
+	assert.False(t, IsClientSide(processSpan(0, nil)))
+
+	// non-messaging spans keep their usual edges
+	httpClient := &request.Span{Type: request.EventTypeHTTPClient, PeerName: "orders-api", HostName: "users"}
+	_, ok = EdgeOf(httpClient)
+	assert.False(t, ok)
+	assert.True(t, IsClientSide(httpClient))
+
+	// spans with unknown destination can't be connected
+	noTopic := processSpan(0, nil)
+	noTopic.Path = ""
+	_, ok = EdgeOf(noTopic)
+	assert.False(t, ok)
+}
+
+func TestSpanOTELGetters(t *testing.T) {
+	server, ok := SpanOTELGetters(attr.Server)
+	require.True(t, ok)
+	assert.Equal(t, "kafka:orders", server(publishSpan(0, nil)).Value.AsString())
+	assert.Equal(t, "billing-worker", server(processSpan(0, nil)).Value.AsString())
+	assert.Equal(t, "users", server(&request.Span{Type: request.EventTypeHTTPClient, HostName: "users"}).Value.AsString())
+
+	client, ok := SpanOTELGetters(attr.Client)
+	require.True(t, ok)
+	assert.Equal(t, "orders-api", client(publishSpan(0, nil)).Value.AsString())
+	assert.Equal(t, "kafka:orders", client(processSpan(0, nil)).Value.AsString())
+}
+
+func TestJoiner_ByOffset(t *testing.T) {
+	now := time.Now()
+	j := NewJoiner(func() time.Time { return now }, time.Minute)
+
+	_, ok := j.Join(publishSpan(10*time.Second, &request.MessagingInfo{Partition: 1, Messages: 3, Offset: 12}))
+	assert.False(t, ok)
+	_, ok = j.Join(publishSpan(11*time.Second, &request.MessagingInfo{Partition: 1, Messages: 2, Offset: 14}))
+	assert.False(t, ok)
+
+	// fetch of offsets 11 to 13 matches the first publication
+	latency, ok := j.Join(processSpan(12*time.Second, &request.MessagingInfo{Partition: 1, Messages: 3, Offset: 13}))
+	require.True(t, ok)
+	assert.InDelta(t, 2*time.Second, latency, float64(time.Millisecond))
+
+	// fetch of offset 14 matches the second publication
+	latency, ok = j.Join(processSpan(12*time.Second, &request.MessagingInfo{Partition: 1, Messages: 1, Offset: 14}))
+	require.True(t, ok)
+	assert.InDelta(t, time.Second, latency, float64(time.Millisecond))
+
+	// other partitions or offsets don't match
+	_, ok = j.Join(processSpan(12*time.Second, &request.MessagingInfo{Partition: 2, Messages: 1, Offset: 14}))
+	assert.False(t, ok)
+	_, ok = j.Join(processSpan(12*time.Second, &request.MessagingInfo{Partition: 1, Messages: 1, Offset: 15}))
+	assert.False(t, ok)
+	_, ok = j.Join(processSpan(12*time.Second, nil))
+	assert.False(t, ok)
+}
+
+func TestJoiner_ByTraceID(t *testing.T) {
+	now := time.Now()
+	j := NewJoiner(func() time.Time { return now }, time.Minute)
+
+	producer := publishSpan(10*time.Second, nil)
+	producer.TraceID = trace.TraceID{1, 2, 3}
+	j.Join(producer)
+
+	consumer := processSpan(10*time.Second+500*time.Millisecond, nil)
+	consumer.TraceID = trace.TraceID{1, 2, 3}
+	latency, ok := j.Join(consumer)
+	require.True(t, ok)
+	assert.InDelta(t, 500*time.Millisecond, latency, float64(time.Millisecond))
+
+	consumer.TraceID = trace.TraceID{3, 2, 1}
+	_, ok = j.Join(consumer)
+	assert.False(t, ok)
+}
+
+func TestJoiner_Expiration(t *testing.T) {
+	now := time.Now()
+	j := NewJoiner(func() time.Time { return now }, time.Minute)
+
+	j.Join(publishSpan(10*time.Second, &request.MessagingInfo{Partition: 1, Messages: 1, Offset: 1}))
+	now = now.Add(50 * time.Second)
+	j.Join(publishSpan(60*time.Second, &request.MessagingInfo{Partition: 1, Messages: 1, Offset: 2}))
+	now = now.Add(50 * time.Second)
+	// triggers the removal of the first publication
+	j.Join(publishSpan(110*time.Second, &request.MessagingInfo{Partition: 2, Messages: 1, Offset: 1}))
+	assert.Equal(t, 2, j.stored)
+
+	_, ok := j.Join(processSpan(120*time.Second, &request.MessagingInfo{Partition: 1, Messages: 1, Offset: 1}))
+	assert.False(t, ok)
+	_, ok = j.Join(processSpan(120*time.Second, &request.MessagingInfo{Partition: 1, Messages: 1, Offset: 2}))
+	assert.True(t, ok)
+}
+
+func TestJoiner_Full(t *testing.T) {
+	now := time.Now()
+	j := NewJoiner(func() time.Time { return now }, time.Minute)
+	for i := 0; i < maxPublications; i++ {
+		j.Join(publishSpan(10*time.Second, &request.MessagingInfo{Partition: 1, Messages: 1, Offset: int64(i)}))
+	}
+	lastPurge := j.lastPurge
+
+	// when the Joiner is full, new publications are discarded without purging for each of them
+	now = now.Add(time.Second)
+	j.Join(publishSpan(11*time.Second, &request.MessagingInfo{Partition: 2, Messages: 1, Offset: 1}))
+	assert.Equal(t, maxPublications, j.stored)
+	assert.Equal(t, lastPurge, j.lastPurge)
+
+	// the old publications are eventually purged
+	now = now.Add(time.Minute)
+	j.Join(publishSpan(71*time.Second, &request.MessagingInfo{Partition: 2, Messages: 1, Offset: 2}))
+	assert.Equal(t, 1, j.stored)
+	_, ok := j.Join(processSpan(72*time.Second, &request.MessagingInfo{Partition: 2, Messages: 1, Offset: 2}))
+	assert.True(t, ok)
+}
@@ -0,0 +1,134 @@
+package otel
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// bucketedHistograms accumulates the histograms whose observations have been already bucketed
+// by the eBPF probes, and provides them as cumulative histograms to the metrics reader, which
+// merges them with the data of the rest of instruments of the MeterProvider.
+type bucketedHistograms struct {
+	mt    sync.Mutex
+	start time.Time
+	// ttl of the data points that haven't been updated. Zero means that they never expire.
+	ttl time.Duration
+
+	histograms []*bucketedHistogram
+}
+
+// bucketedHistogram is a histogram metric, with a data point for each different attribute set
+type bucketedHistogram struct {
+	parent      *bucketedHistograms
+	name        string
+	description string
+	bounds      []float64
+	points      map[attribute.Distinct]*cumulativeHistogram
+}
+
+type cumulativeHistogram struct {
+	attrs attribute.Set
+	// counts of each bucket, including the +Inf bucket. They are not cumulative.
+	counts     []uint64
+	sumNs      uint64
+	lastUpdate time.Time
+}
+
+func newBucketedHistograms(start time.Time, ttl time.Duration) *bucketedHistograms {
+	return &bucketedHistograms{start: start, ttl: ttl}
+}
+
+// histogram creates a new histogram metric with the given bounds in seconds, without the +Inf bucket
+func (bh *bucketedHistograms) histogram(name, description string, bounds []float64) *bucketedHistogram {
+	bh.mt.Lock()
+	defer bh.mt.Unlock()
+	h := &bucketedHistogram{
+		parent:      bh,
+		name:        name,
+		description: description,
+		bounds:      bounds,
+		points:      map[attribute.Distinct]*cumulativeHistogram{},
+	}
+	bh.histograms = append(bh.histograms, h)
+	return h
+}
+
+// add the non-cumulative counts of each bucket, and the sum of the observations in nanoseconds,
+// to the data point of the given attributes
+func (h *bucketedHistogram) add(attrs attribute.Set, counts []uint64, sumNs uint64, now time.Time) {
+	h.parent.mt.Lock()
+	defer h.parent.mt.Unlock()
+	point, ok := h.points[attrs.Equivalent()]
+	if !ok {
+		point = &cumulativeHistogram{attrs: attrs, counts: make([]uint64, len(h.bounds)+1)}
+		h.points[attrs.Equivalent()] = point
+	}
+	for i := range point.counts {
+		point.counts[i] += counts[i]
+	}
+	point.sumNs += sumNs
+	point.lastUpdate = now
+}
+
+// Produce implements the metric.Producer interface
+func (bh *bucketedHistograms) Produce(_ context.Context) ([]metricdata.ScopeMetrics, error) {
+	bh.mt.Lock()
+	defer bh.mt.Unlock()
+	now := timeNow()
+	var metrics []metricdata.Metrics
+	for _, h := range bh.histograms {
+		if m, ok := h.metric(bh.start, now, bh.ttl); ok {
+			metrics = append(metrics, m)
+		}
+	}
+	if len(metrics) == 0 {
+		return nil, nil
+	}
+	return []metricdata.ScopeMetrics{{
+		Scope:   instrumentation.Scope{Name: reporterName},
+		Metrics: metrics,
+	}}, nil
+}
+
+// metric returns the data points of the histogram, after removing those that have expired
+func (h *bucketedHistogram) metric(start, now time.Time, ttl time.Duration) (metricdata.Metrics, bool) {
+	points := make([]metricdata.HistogramDataPoint[float64], 0, len(h.points))
+	for k, p := range h.points {
+		if ttl > 0 && now.Sub(p.lastUpdate) > ttl {
+			delete(h.points, k)
+			continue
+		}
+		count := uint64(0)
+		for _, c := range p.counts {
+			count += c
+		}
+		counts := make([]uint64, len(p.counts))
+		copy(counts, p.counts)
+		points = append(points, metricdata.HistogramDataPoint[float64]{
+			Attributes:   p.attrs,
+			StartTime:    start,
+			Time:         p.lastUpdate,
+			Count:        count,
+			Bounds:       h.bounds,
+			BucketCounts: counts,
+			Sum:          float64(p.sumNs) / 1e9,
+		})
+	}
+	if len(points) == 0 {
+		return metricdata.Metrics{}, false
+	}
+	return metricdata.Metrics{
+		Name:        h.name,
+		Description: h.description,
+		Unit:        "s",
+		Data: metricdata.Histogram[float64]{
+			Temporality: metricdata.CumulativeTemporality,
+			DataPoints:  points,
+		},
+	}, true
+}
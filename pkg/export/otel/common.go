@@ -47,8 +47,9 @@ const (
 // Buckets defines the histograms bucket boundaries, and allows users to
 // redefine them
 type Buckets struct {
-	DurationHistogram    []float64 `yaml:"duration_histogram"`
-	RequestSizeHistogram []float64 `yaml:"request_size_histogram"`
+	DurationHistogram          []float64 `yaml:"duration_histogram"`
+	RequestSizeHistogram       []float64 `yaml:"request_size_histogram"`
+	RedisPipelineSizeHistogram []float64 `yaml:"redis_pipeline_size_histogram"`
 }
 
 var DefaultBuckets = Buckets{
@@ -57,6 +58,8 @@ var DefaultBuckets = Buckets{
 	DurationHistogram: []float64{0, 0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10},
 
 	RequestSizeHistogram: []float64{0, 32, 64, 128, 256, 512, 1024, 2048, 4096, 8192},
+
+	RedisPipelineSizeHistogram: []float64{1, 2, 4, 8, 16, 32, 64, 128, 256},
 }
 
 func getAppResourceAttrs(hostID string, service *svc.ID) []attribute.KeyValue {
@@ -83,6 +86,13 @@ func getResourceAttrs(hostID string, service *svc.ID) []attribute.KeyValue {
 		attrs = append(attrs, semconv.ServiceNamespace(service.Namespace))
 	}
 
+	if service.RuntimeName != "" {
+		attrs = append(attrs, semconv.ProcessRuntimeName(service.RuntimeName))
+	}
+	if service.RuntimeVersion != "" {
+		attrs = append(attrs, semconv.ProcessRuntimeVersion(service.RuntimeVersion))
+	}
+
 	for k, v := range service.Metadata {
 		attrs = append(attrs, k.OTEL().String(v))
 	}
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/beyla/pkg/internal/svc"
 )
 
 func TestOtlpOptions_AsMetricHTTP(t *testing.T) {
@@ -84,3 +86,16 @@ func TestOtlpOptions_AsTraceGRPC(t *testing.T) {
 		})
 	}
 }
+
+func TestResourceAttrs_Runtime(t *testing.T) {
+	attrs := attrsToMap(getAppResourceAttrs("host", &svc.ID{
+		Name: "svc", SDKLanguage: svc.InstrumentableJava, RuntimeName: "java", RuntimeVersion: "21.0.2",
+	})).AsRaw()
+	assert.Equal(t, "java", attrs["process.runtime.name"])
+	assert.Equal(t, "21.0.2", attrs["process.runtime.version"])
+
+	// runtime attributes are omitted if they are unknown
+	attrs = attrsToMap(getAppResourceAttrs("host", &svc.ID{Name: "svc", SDKLanguage: svc.InstrumentableGeneric})).AsRaw()
+	assert.NotContains(t, attrs, "process.runtime.name")
+	assert.NotContains(t, attrs, "process.runtime.version")
+}
@@ -0,0 +1,199 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/grafana/beyla/pkg/internal/diskqueue"
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+)
+
+const (
+	signalTraces           = "traces"
+	signalMetrics          = "metrics"
+	signalNetworkMetrics   = "network_metrics"
+	signalProcessMetrics   = "process_metrics"
+	signalSLOMetrics       = "slo_metrics"
+	signalGoRuntimeMetrics = "go_runtime_metrics"
+	signalTCPHealthMetrics = "tcp_health_metrics"
+)
+
+// DiskQueueConfig enables an optional write-ahead queue in the local disk, where the OTLP exporters
+// store the data that can't be submitted because the remote endpoint is unavailable. The stored data
+// is replayed, in order, when the endpoint recovers.
+type DiskQueueConfig struct {
+	Enabled bool `yaml:"enabled" env:"ENABLED"`
+	// Directory where the queued data is stored. Each signal is stored in a different subdirectory.
+	Directory string `yaml:"directory" env:"DIRECTORY"`
+	// MaxSize in bytes of the queue for each signal. When it is exceeded, the oldest data is dropped.
+	MaxSize int64 `yaml:"max_size" env:"MAX_SIZE"`
+	// MaxAge of the queued data. Older data is dropped without being submitted.
+	MaxAge time.Duration `yaml:"max_age" env:"MAX_AGE"`
+	// RetryInterval is the time between attempts to replay the queued data
+	RetryInterval time.Duration `yaml:"retry_interval" env:"RETRY_INTERVAL"`
+}
+
+var DefaultDiskQueueConfig = DiskQueueConfig{
+	Enabled:       false,
+	Directory:     "/var/lib/beyla/otlp_queue",
+	MaxSize:       256 * 1024 * 1024,
+	MaxAge:        24 * time.Hour,
+	RetryInterval: 5 * time.Second,
+}
+
+// exportQueue submits data of type T through the send function, storing it in a disk queue
+// when the submission fails.
+type exportQueue[T any] struct {
+	log      *slog.Logger
+	cfg      *DiskQueueConfig
+	signal   string
+	queue    *diskqueue.Queue
+	internal imetrics.Reporter
+
+	send   func(context.Context, T) error
+	encode func(T) ([]byte, error)
+	decode func([]byte) (T, error)
+
+	// guarantees that the live data is submitted in order, either directly or through the queue
+	mt sync.Mutex
+}
+
+func newExportQueue[T any](
+	cfg *DiskQueueConfig,
+	signal string,
+	internal imetrics.Reporter,
+	send func(context.Context, T) error,
+	encode func(T) ([]byte, error),
+	decode func([]byte) (T, error),
+) (*exportQueue[T], error) {
+	if internal == nil {
+		internal = imetrics.NoopReporter{}
+	}
+	queue, err := diskqueue.Open(diskqueue.Config{
+		Dir:     filepath.Join(cfg.Directory, signal),
+		MaxSize: cfg.MaxSize,
+		MaxAge:  cfg.MaxAge,
+		DepthChanged: func(depth int) {
+			internal.OTELExportQueueDepth(signal, depth)
+		},
+		Dropped: func(bytes int) {
+			internal.OTELExportQueueDroppedBytes(signal, bytes)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("opening %s disk queue: %w", signal, err)
+	}
+	return &exportQueue[T]{
+		log:      slog.With("component", "otel.exportQueue", "signal", signal),
+		cfg:      cfg,
+		signal:   signal,
+		queue:    queue,
+		internal: internal,
+		send:     send,
+		encode:   encode,
+		decode:   decode,
+	}, nil
+}
+
+// export submits the data directly, unless there is older data waiting in the queue, or the
+// submission fails. In that case, the data is stored at the tail of the queue.
+func (eq *exportQueue[T]) export(ctx context.Context, data T) error {
+	eq.mt.Lock()
+	defer eq.mt.Unlock()
+	if eq.queue.Len() == 0 {
+		err := eq.send(ctx, data)
+		if err == nil {
+			return nil
+		}
+		eq.log.Debug("export failed. Storing data in the disk queue", "error", err)
+	}
+	record, err := eq.encode(data)
+	if err != nil {
+		return fmt.Errorf("encoding data for the disk queue: %w", err)
+	}
+	return eq.queue.Push(record)
+}
+
+// replayLoop periodically tries to submit the queued data, until the context is cancelled
+func (eq *exportQueue[T]) replayLoop(ctx context.Context) {
+	ticker := time.NewTicker(eq.cfg.RetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			eq.replay(ctx)
+		}
+	}
+}
+
+// replay submits the queued data in order, until the queue is empty or a submission fails.
+// It does not hold the mutex while submitting, so a slow endpoint does not block the live exports,
+// which are queued after the replayed data as long as the queue is not empty.
+func (eq *exportQueue[T]) replay(ctx context.Context) {
+	for {
+		record, seq, ok := eq.queue.Peek()
+		if !ok {
+			return
+		}
+		data, err := eq.decode(record)
+		if err != nil {
+			eq.log.Warn("can't decode queued data. Dropping it", "error", err)
+			eq.internal.OTELExportQueueDroppedBytes(eq.signal, len(record))
+			eq.queue.Pop(seq)
+			continue
+		}
+		if err := eq.send(ctx, data); err != nil {
+			eq.log.Debug("can't replay queued data. Will retry later", "error", err)
+			return
+		}
+		eq.queue.Pop(seq)
+	}
+}
+
+func newTracesExportQueue(
+	cfg *DiskQueueConfig, internal imetrics.Reporter, send func(context.Context, ptrace.Traces) error,
+) (*exportQueue[ptrace.Traces], error) {
+	marshaler, unmarshaler := ptrace.ProtoMarshaler{}, ptrace.ProtoUnmarshaler{}
+	return newExportQueue(cfg, signalTraces, internal, send,
+		marshaler.MarshalTraces, unmarshaler.UnmarshalTraces)
+}
+
+// queuedMetricsExporter wraps a metrics exporter, storing into a disk queue the metrics
+// that can't be exported.
+type queuedMetricsExporter struct {
+	metric.Exporter
+	queue *exportQueue[*metricdata.ResourceMetrics]
+}
+
+// queueMetricsExporter wraps the metrics exporter into a queuedMetricsExporter if the disk queue is enabled.
+// The replay of the queued data stops when the passed context is cancelled.
+func queueMetricsExporter(
+	ctx context.Context, cfg *MetricsConfig, signal string, internal imetrics.Reporter, in metric.Exporter,
+) (metric.Exporter, error) {
+	if !cfg.DiskQueue.Enabled {
+		return in, nil
+	}
+	queue, err := newExportQueue(&cfg.DiskQueue, signal, internal, in.Export,
+		encodeResourceMetrics, decodeResourceMetrics)
+	if err != nil {
+		return nil, err
+	}
+	go queue.replayLoop(ctx)
+	return &queuedMetricsExporter{Exporter: in, queue: queue}, nil
+}
+
+// Export never returns an error if the data could be stored in the queue,
+// as it will be eventually submitted
+func (qe *queuedMetricsExporter) Export(ctx context.Context, md *metricdata.ResourceMetrics) error {
+	return qe.queue.export(ctx, md)
+}
@@ -0,0 +1,168 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+func TestExportQueue_ReplaysInOrder(t *testing.T) {
+	available := false
+	var sent []string
+	send := func(_ context.Context, s string) error {
+		if !available {
+			return errors.New("endpoint unavailable")
+		}
+		sent = append(sent, s)
+		return nil
+	}
+	identity := func(s string) ([]byte, error) { return []byte(s), nil }
+	parse := func(b []byte) (string, error) { return string(b), nil }
+	cfg := DefaultDiskQueueConfig
+	cfg.Directory = t.TempDir()
+	eq, err := newExportQueue(&cfg, signalTraces, nil, send, identity, parse)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, eq.export(ctx, "one"))
+	require.NoError(t, eq.export(ctx, "two"))
+	eq.replay(ctx)
+	assert.Empty(t, sent)
+	assert.Equal(t, 2, eq.queue.Len())
+
+	// new data is queued after the older data, even if the endpoint is available again
+	available = true
+	require.NoError(t, eq.export(ctx, "three"))
+	assert.Empty(t, sent)
+
+	eq.replay(ctx)
+	assert.Equal(t, []string{"one", "two", "three"}, sent)
+	assert.Zero(t, eq.queue.Len())
+
+	// with an empty queue, data is submitted directly
+	require.NoError(t, eq.export(ctx, "four"))
+	assert.Equal(t, []string{"one", "two", "three", "four"}, sent)
+}
+
+func TestExportQueue_ReplayDoesNotBlockExport(t *testing.T) {
+	replaying := make(chan struct{})
+	unblock := make(chan struct{})
+	var sent []string
+	send := func(_ context.Context, s string) error {
+		if s == "queued" {
+			close(replaying)
+			<-unblock
+		}
+		sent = append(sent, s)
+		return nil
+	}
+	identity := func(s string) ([]byte, error) { return []byte(s), nil }
+	parse := func(b []byte) (string, error) { return string(b), nil }
+	cfg := DefaultDiskQueueConfig
+	cfg.Directory = t.TempDir()
+	eq, err := newExportQueue(&cfg, signalTraces, nil, send, identity, parse)
+	require.NoError(t, err)
+	require.NoError(t, eq.queue.Push([]byte("queued")))
+
+	ctx := context.Background()
+	replayed := make(chan struct{})
+	go func() {
+		eq.replay(ctx)
+		close(replayed)
+	}()
+	<-replaying
+	// while the replayed data is being sent, new data is queued without waiting
+	exported := make(chan error)
+	go func() { exported <- eq.export(ctx, "live") }()
+	select {
+	case err := <-exported:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "export blocked by the replay")
+	}
+	close(unblock)
+	<-replayed
+	assert.Equal(t, []string{"queued", "live"}, sent)
+	assert.Zero(t, eq.queue.Len())
+}
+
+func TestMetricsCodec(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0).UTC()
+	now := start.Add(time.Minute)
+	attrs := attribute.NewSet(attribute.String("http.route", "/users"), attribute.Int64("http.status_code", 200))
+	md := &metricdata.ResourceMetrics{
+		Resource: resource.NewWithAttributes("https://opentelemetry.io/schemas/1.19.0",
+			attribute.String("service.name", "foo"), attribute.Bool("flag", true)),
+		ScopeMetrics: []metricdata.ScopeMetrics{{
+			Scope: instrumentation.Scope{Name: reporterName, Version: "1.0"},
+			Metrics: []metricdata.Metrics{{
+				Name: "requests",
+				Unit: "1",
+				Data: metricdata.Sum[int64]{
+					Temporality: metricdata.CumulativeTemporality,
+					IsMonotonic: true,
+					DataPoints: []metricdata.DataPoint[int64]{
+						{Attributes: attrs, StartTime: start, Time: now, Value: 33},
+					},
+				},
+			}, {
+				Name: "load",
+				Data: metricdata.Gauge[float64]{
+					DataPoints: []metricdata.DataPoint[float64]{
+						{Attributes: attrs, Time: now, Value: 0.75},
+					},
+				},
+			}, {
+				Name:        "duration",
+				Description: "request duration",
+				Unit:        "s",
+				Data: metricdata.Histogram[float64]{
+					Temporality: metricdata.CumulativeTemporality,
+					DataPoints: []metricdata.HistogramDataPoint[float64]{{
+						Attributes:   attrs,
+						StartTime:    start,
+						Time:         now,
+						Count:        3,
+						Bounds:       []float64{0.1, 1},
+						BucketCounts: []uint64{1, 1, 1},
+						Min:          metricdata.NewExtrema(0.05),
+						Sum:          3.5,
+					}},
+				},
+			}, {
+				Name: "size",
+				Data: metricdata.ExponentialHistogram[int64]{
+					Temporality: metricdata.DeltaTemporality,
+					DataPoints: []metricdata.ExponentialHistogramDataPoint[int64]{{
+						Attributes:     attrs,
+						StartTime:      start,
+						Time:           now,
+						Count:          4,
+						Sum:            1000,
+						Max:            metricdata.NewExtrema[int64](600),
+						Scale:          2,
+						ZeroCount:      1,
+						PositiveBucket: metricdata.ExponentialBucket{Offset: 3, Counts: []uint64{1, 2}},
+					}},
+				},
+			}},
+		}},
+	}
+
+	record, err := encodeResourceMetrics(md)
+	require.NoError(t, err)
+	decoded, err := decodeResourceMetrics(record)
+	require.NoError(t, err)
+	assert.Equal(t, md, decoded)
+
+	_, err = decodeResourceMetrics([]byte("garbage"))
+	assert.Error(t, err)
+}
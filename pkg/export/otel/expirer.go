@@ -9,11 +9,16 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/grafana/beyla/pkg/export/attributes"
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
 	"github.com/grafana/beyla/pkg/export/expire"
+	"github.com/grafana/beyla/pkg/internal/svc"
 )
 
 var timeNow = time.Now
 
+// overflowAttributes of the data points where the attribute sets that exceed the cardinality limit are folded
+var overflowAttributes = attribute.NewSet(attr.OTELMetricOverflow.OTEL().Bool(true))
+
 func plog() *slog.Logger {
 	return slog.With("component", "otel.Expirer")
 }
@@ -40,6 +45,9 @@ type Expirer[Record any, Metric removableMetric[ValType], ValType any] struct {
 	clock          expire.Clock
 	lastExpiration time.Time
 	ttl            time.Duration
+
+	// service whose attribute sets are limited. Nil if the expirer is not limited.
+	service *svc.ID
 }
 
 // NewExpirer creates an expirer that wraps data points of a given type. Its labeled instances are dropped
@@ -69,6 +77,18 @@ func NewExpirer[Record any, Metric removableMetric[ValType], ValType any](
 	return &exp
 }
 
+// WithSeriesLimit limits the number of attribute sets that the expirer can create for the given service. Any
+// attribute set exceeding the limit is folded into a data point with the otel.metric.overflow="true" attribute.
+// A nil limit leaves the expirer unlimited.
+func (ex *Expirer[Record, Metric, ValType]) WithSeriesLimit(service *svc.ID, limit *expire.SeriesLimit) *Expirer[Record, Metric, ValType] {
+	if limit == nil {
+		return ex
+	}
+	ex.service = service
+	ex.entries = expire.NewLimitedExpiryMap[attribute.Set](ex.clock, ex.ttl, limit)
+	return ex
+}
+
 // ForRecord returns the data point for the given eBPF record. If that record
 // is accessed for the first time, a new data point is created.
 // If not, a cached copy is returned and the "last access" cache time is updated.
@@ -84,10 +104,16 @@ func (ex *Expirer[Record, Metric, ValType]) ForRecord(r Record, extraAttrs ...at
 		ex.lastExpiration = now
 	}
 	recordAttrs, attrValues := ex.recordAttributes(r, extraAttrs...)
-	return ex.metric, ex.entries.GetOrCreate(attrValues, func() attribute.Set {
+	instancer := func() attribute.Set {
 		ex.log.With("labelValues", attrValues).Debug("storing new metric label set")
 		return recordAttrs
-	})
+	}
+	if ex.service != nil {
+		return ex.metric, ex.entries.GetOrCreateLimited(ex.service, attrValues, instancer, func() attribute.Set {
+			return overflowAttributes
+		})
+	}
+	return ex.metric, ex.entries.GetOrCreate(attrValues, instancer)
 }
 
 func (ex *Expirer[Record, Metric, ValType]) recordAttributes(m Record, extraAttrs ...attribute.KeyValue) (attribute.Set, []string) {
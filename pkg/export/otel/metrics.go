@@ -23,7 +23,9 @@ import (
 
 	"github.com/grafana/beyla/pkg/export/attributes"
 	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+	"github.com/grafana/beyla/pkg/export/expire"
 	"github.com/grafana/beyla/pkg/export/instrumentations"
+	"github.com/grafana/beyla/pkg/export/msggraph"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
 	"github.com/grafana/beyla/pkg/internal/pipe/global"
 	"github.com/grafana/beyla/pkg/internal/request"
@@ -48,6 +50,9 @@ const (
 	ServiceGraphFailed = "traces_service_graph_request_failed_total"
 	ServiceGraphTotal  = "traces_service_graph_request_total"
 
+	// ServiceGraphMessaging measures the time between the publication of a message and its processing
+	ServiceGraphMessaging = "traces_service_graph_request_messaging_system"
+
 	UsualPortGRPC = "4317"
 	UsualPortHTTP = "4318"
 
@@ -59,6 +64,19 @@ const (
 	FeatureSpan        = "application_span"
 	FeatureGraph       = "application_service_graph"
 	FeatureProcess     = "application_process"
+	FeatureGoRuntime   = "application_go_runtime"
+
+	// FeatureGoScheduler enables the scheduler latency metric of the Go runtime. It instruments
+	// functions that are invoked each time a goroutine is scheduled, so it adds a noticeable overhead.
+	FeatureGoScheduler = "application_go_scheduler"
+
+	// FeatureTCPConnection enables the health metrics of the TCP connections of the
+	// instrumented processes: round-trip time, retransmissions, resets and connection failures
+	FeatureTCPConnection = "tcp_connection"
+
+	// FeatureGraphMessaging enables the service graph metrics, connecting producers and consumers
+	// through virtual nodes that represent their messaging destinations
+	FeatureGraphMessaging = "application_service_graph_messaging"
 )
 
 type MetricsConfig struct {
@@ -101,6 +119,12 @@ type MetricsConfig struct {
 	// removed from the metrics set.
 	TTL time.Duration `yaml:"ttl" env:"BEYLA_OTEL_METRICS_TTL"`
 
+	// DiskQueue stores the metrics that can't be exported while the OTLP endpoint is unavailable
+	DiskQueue DiskQueueConfig `yaml:"disk_queue" envPrefix:"BEYLA_OTEL_METRICS_DISK_QUEUE_"`
+
+	// CardinalityLimits caps the number of attribute sets of the application metrics, for each service
+	CardinalityLimits expire.CardinalityLimits `yaml:"cardinality_limits" envPrefix:"BEYLA_OTEL_METRICS_CARDINALITY_"`
+
 	// Grafana configuration needs to be explicitly set up before building the graph
 	Grafana *GrafanaOTLP `yaml:"-"`
 }
@@ -146,7 +170,11 @@ func (m *MetricsConfig) SpanMetricsEnabled() bool {
 }
 
 func (m *MetricsConfig) ServiceGraphMetricsEnabled() bool {
-	return slices.Contains(m.Features, FeatureGraph)
+	return slices.Contains(m.Features, FeatureGraph) || m.ServiceGraphMessagingEnabled()
+}
+
+func (m *MetricsConfig) ServiceGraphMessagingEnabled() bool {
+	return slices.Contains(m.Features, FeatureGraphMessaging)
 }
 
 func (m *MetricsConfig) OTelMetricsEnabled() bool {
@@ -157,6 +185,18 @@ func (m *MetricsConfig) NetworkMetricsEnabled() bool {
 	return slices.Contains(m.Features, FeatureNetwork)
 }
 
+func (m *MetricsConfig) GoRuntimeMetricsEnabled() bool {
+	return slices.Contains(m.Features, FeatureGoRuntime)
+}
+
+func (m *MetricsConfig) GoSchedulerMetricsEnabled() bool {
+	return slices.Contains(m.Features, FeatureGoScheduler)
+}
+
+func (m *MetricsConfig) TCPConnectionMetricsEnabled() bool {
+	return slices.Contains(m.Features, FeatureTCPConnection)
+}
+
 func (m *MetricsConfig) Enabled() bool {
 	return m.EndpointEnabled() && (m.OTelMetricsEnabled() || m.SpanMetricsEnabled() || m.ServiceGraphMetricsEnabled() || m.NetworkMetricsEnabled())
 }
@@ -171,6 +211,7 @@ type MetricsReporter struct {
 	exporter   metric.Exporter
 	reporters  ReporterPool[*svc.ID, *Metrics]
 	is         instrumentations.InstrumentationSelection
+	internal   imetrics.Reporter
 
 	// user-selected fields for each of the reported metrics
 	attrHTTPDuration          []attributes.Field[*request.Span, attribute.KeyValue]
@@ -182,6 +223,16 @@ type MetricsReporter struct {
 	attrMessagingProcess      []attributes.Field[*request.Span, attribute.KeyValue]
 	attrHTTPRequestSize       []attributes.Field[*request.Span, attribute.KeyValue]
 	attrHTTPClientRequestSize []attributes.Field[*request.Span, attribute.KeyValue]
+	attrKafkaMessages         []attributes.Field[*request.Span, attribute.KeyValue]
+	attrKafkaMessagesSize     []attributes.Field[*request.Span, attribute.KeyValue]
+	attrKafkaOffset           []attributes.Field[*request.Span, attribute.KeyValue]
+	attrKafkaConsumerLag      []attributes.Field[*request.Span, attribute.KeyValue]
+	attrDNSLookup             []attributes.Field[*request.Span, attribute.KeyValue]
+	attrRedisCacheLookups     []attributes.Field[*request.Span, attribute.KeyValue]
+	attrRedisPipelineSize     []attributes.Field[*request.Span, attribute.KeyValue]
+
+	// joins producer and consumer spans for the messaging service graph
+	msgJoiner *msggraph.Joiner
 }
 
 // Metrics is a set of metrics associated to a given OTEL MeterProvider.
@@ -201,6 +252,13 @@ type Metrics struct {
 	msgProcessDuration    *Expirer[*request.Span, instrument.Float64Histogram, float64]
 	httpRequestSize       *Expirer[*request.Span, instrument.Float64Histogram, float64]
 	httpClientRequestSize *Expirer[*request.Span, instrument.Float64Histogram, float64]
+	kafkaMessages         *Expirer[*request.Span, instrument.Int64Counter, int64]
+	kafkaMessagesSize     *Expirer[*request.Span, instrument.Int64Counter, int64]
+	kafkaOffset           *Expirer[*request.Span, instrument.Int64Gauge, int64]
+	kafkaConsumerLag      *Expirer[*request.Span, instrument.Int64Gauge, int64]
+	dnsLookupDuration     *Expirer[*request.Span, instrument.Float64Histogram, float64]
+	redisCacheLookups     *Expirer[*request.Span, instrument.Int64Counter, int64]
+	redisPipelineSize     *Expirer[*request.Span, instrument.Int64Histogram, int64]
 	// trace span metrics
 	spanMetricsLatency    *Expirer[*request.Span, instrument.Float64Histogram, float64]
 	spanMetricsCallsTotal *Expirer[*request.Span, instrument.Int64Counter, int64]
@@ -209,6 +267,7 @@ type Metrics struct {
 	serviceGraphServer    *Expirer[*request.Span, instrument.Float64Histogram, float64]
 	serviceGraphFailed    *Expirer[*request.Span, instrument.Int64Counter, int64]
 	serviceGraphTotal     *Expirer[*request.Span, instrument.Int64Counter, int64]
+	serviceGraphMessaging *Expirer[*request.Span, instrument.Float64Histogram, float64]
 	tracesTargetInfo      instrument.Int64UpDownCounter
 }
 
@@ -253,6 +312,10 @@ func newMetricsReporter(
 		is:         is,
 		attributes: attribProvider,
 		hostID:     ctxInfo.HostID,
+		internal:   ctxInfo.Metrics,
+	}
+	if mr.internal == nil {
+		mr.internal = imetrics.NoopReporter{}
 	}
 	// initialize attribute getters
 	if is.HTTPEnabled() {
@@ -277,11 +340,35 @@ func newMetricsReporter(
 			request.SpanOTELGetters, mr.attributes.For(attributes.DBClientDuration))
 	}
 
+	if is.RedisEnabled() {
+		mr.attrRedisCacheLookups = attributes.OpenTelemetryGetters(
+			request.SpanOTELGetters, mr.attributes.For(attributes.RedisCacheLookups))
+		mr.attrRedisPipelineSize = attributes.OpenTelemetryGetters(
+			request.SpanOTELGetters, mr.attributes.For(attributes.RedisPipelineSize))
+	}
+
 	if is.MQEnabled() {
 		mr.attrMessagingPublish = attributes.OpenTelemetryGetters(
 			request.SpanOTELGetters, mr.attributes.For(attributes.MessagingPublishDuration))
 		mr.attrMessagingProcess = attributes.OpenTelemetryGetters(
 			request.SpanOTELGetters, mr.attributes.For(attributes.MessagingProcessDuration))
+		mr.attrKafkaMessages = attributes.OpenTelemetryGetters(
+			request.SpanOTELGetters, mr.attributes.For(attributes.MessagingKafkaMessages))
+		mr.attrKafkaMessagesSize = attributes.OpenTelemetryGetters(
+			request.SpanOTELGetters, mr.attributes.For(attributes.MessagingKafkaMessagesSize))
+		mr.attrKafkaOffset = attributes.OpenTelemetryGetters(
+			request.SpanOTELGetters, mr.attributes.For(attributes.MessagingKafkaOffset))
+		mr.attrKafkaConsumerLag = attributes.OpenTelemetryGetters(
+			request.SpanOTELGetters, mr.attributes.For(attributes.MessagingKafkaConsumerLag))
+	}
+
+	if is.DNSEnabled() {
+		mr.attrDNSLookup = attributes.OpenTelemetryGetters(
+			request.SpanOTELGetters, mr.attributes.For(attributes.DNSLookupDuration))
+	}
+
+	if cfg.ServiceGraphMessagingEnabled() {
+		mr.msgJoiner = msggraph.NewJoiner(timeNow, cfg.TTL)
 	}
 
 	mr.reporters = NewReporterPool[*svc.ID, *Metrics](cfg.ReportersCacheLen, cfg.TTL, timeNow,
@@ -305,7 +392,11 @@ func newMetricsReporter(
 	if err != nil {
 		return nil, err
 	}
-	mr.exporter = instrumentMetricsExporter(ctxInfo.Metrics, exporter)
+	mr.exporter, err = queueMetricsExporter(ctx, cfg, signalMetrics, ctxInfo.Metrics,
+		instrumentMetricsExporter(ctxInfo.Metrics, exporter))
+	if err != nil {
+		return nil, err
+	}
 
 	return &mr, nil
 }
@@ -340,6 +431,12 @@ func (mr *MetricsReporter) otelMetricOptions(mlog *slog.Logger) []metric.Option
 		)
 	}
 
+	if mr.is.RedisEnabled() {
+		opts = append(opts,
+			metric.WithView(otelHistogramConfig(attributes.RedisPipelineSize.OTEL, mr.cfg.Buckets.RedisPipelineSizeHistogram, useExponentialHistograms)),
+		)
+	}
+
 	if mr.is.MQEnabled() {
 		opts = append(opts,
 			metric.WithView(otelHistogramConfig(attributes.MessagingPublishDuration.OTEL, mr.cfg.Buckets.DurationHistogram, useExponentialHistograms)),
@@ -347,6 +444,12 @@ func (mr *MetricsReporter) otelMetricOptions(mlog *slog.Logger) []metric.Option
 		)
 	}
 
+	if mr.is.DNSEnabled() {
+		opts = append(opts,
+			metric.WithView(otelHistogramConfig(attributes.DNSLookupDuration.OTEL, mr.cfg.Buckets.DurationHistogram, useExponentialHistograms)),
+		)
+	}
+
 	return opts
 }
 
@@ -372,6 +475,7 @@ func (mr *MetricsReporter) graphMetricOptions(mlog *slog.Logger) []metric.Option
 	return []metric.Option{
 		metric.WithView(otelHistogramConfig(ServiceGraphClient, mr.cfg.Buckets.DurationHistogram, useExponentialHistograms)),
 		metric.WithView(otelHistogramConfig(ServiceGraphServer, mr.cfg.Buckets.DurationHistogram, useExponentialHistograms)),
+		metric.WithView(otelHistogramConfig(ServiceGraphMessaging, mr.cfg.Buckets.DurationHistogram, useExponentialHistograms)),
 	}
 }
 
@@ -387,28 +491,32 @@ func (mr *MetricsReporter) setupOtelMeters(m *Metrics, meter instrument.Meter) e
 			return fmt.Errorf("creating http duration histogram metric: %w", err)
 		}
 		m.httpDuration = NewExpirer[*request.Span, instrument.Float64Histogram, float64](
-			m.ctx, httpDuration, mr.attrHTTPDuration, timeNow, mr.cfg.TTL)
+			m.ctx, httpDuration, mr.attrHTTPDuration, timeNow, mr.cfg.TTL).
+			WithSeriesLimit(m.service, mr.seriesLimit(attributes.HTTPServerDuration, mr.attrHTTPDuration))
 
 		httpClientDuration, err := meter.Float64Histogram(attributes.HTTPClientDuration.OTEL, instrument.WithUnit("s"))
 		if err != nil {
 			return fmt.Errorf("creating http duration histogram metric: %w", err)
 		}
 		m.httpClientDuration = NewExpirer[*request.Span, instrument.Float64Histogram, float64](
-			m.ctx, httpClientDuration, mr.attrHTTPClientDuration, timeNow, mr.cfg.TTL)
+			m.ctx, httpClientDuration, mr.attrHTTPClientDuration, timeNow, mr.cfg.TTL).
+			WithSeriesLimit(m.service, mr.seriesLimit(attributes.HTTPClientDuration, mr.attrHTTPClientDuration))
 
 		httpRequestSize, err := meter.Float64Histogram(attributes.HTTPServerRequestSize.OTEL, instrument.WithUnit("By"))
 		if err != nil {
 			return fmt.Errorf("creating http size histogram metric: %w", err)
 		}
 		m.httpRequestSize = NewExpirer[*request.Span, instrument.Float64Histogram, float64](
-			m.ctx, httpRequestSize, mr.attrHTTPRequestSize, timeNow, mr.cfg.TTL)
+			m.ctx, httpRequestSize, mr.attrHTTPRequestSize, timeNow, mr.cfg.TTL).
+			WithSeriesLimit(m.service, mr.seriesLimit(attributes.HTTPServerRequestSize, mr.attrHTTPRequestSize))
 
 		httpClientRequestSize, err := meter.Float64Histogram(attributes.HTTPClientRequestSize.OTEL, instrument.WithUnit("By"))
 		if err != nil {
 			return fmt.Errorf("creating http size histogram metric: %w", err)
 		}
 		m.httpClientRequestSize = NewExpirer[*request.Span, instrument.Float64Histogram, float64](
-			m.ctx, httpClientRequestSize, mr.attrHTTPClientRequestSize, timeNow, mr.cfg.TTL)
+			m.ctx, httpClientRequestSize, mr.attrHTTPClientRequestSize, timeNow, mr.cfg.TTL).
+			WithSeriesLimit(m.service, mr.seriesLimit(attributes.HTTPClientRequestSize, mr.attrHTTPClientRequestSize))
 	}
 
 	if mr.is.GRPCEnabled() {
@@ -417,14 +525,16 @@ func (mr *MetricsReporter) setupOtelMeters(m *Metrics, meter instrument.Meter) e
 			return fmt.Errorf("creating grpc duration histogram metric: %w", err)
 		}
 		m.grpcDuration = NewExpirer[*request.Span, instrument.Float64Histogram, float64](
-			m.ctx, grpcDuration, mr.attrGRPCServer, timeNow, mr.cfg.TTL)
+			m.ctx, grpcDuration, mr.attrGRPCServer, timeNow, mr.cfg.TTL).
+			WithSeriesLimit(m.service, mr.seriesLimit(attributes.RPCServerDuration, mr.attrGRPCServer))
 
 		grpcClientDuration, err := meter.Float64Histogram(attributes.RPCClientDuration.OTEL, instrument.WithUnit("s"))
 		if err != nil {
 			return fmt.Errorf("creating grpc duration histogram metric: %w", err)
 		}
 		m.grpcClientDuration = NewExpirer[*request.Span, instrument.Float64Histogram, float64](
-			m.ctx, grpcClientDuration, mr.attrGRPCClient, timeNow, mr.cfg.TTL)
+			m.ctx, grpcClientDuration, mr.attrGRPCClient, timeNow, mr.cfg.TTL).
+			WithSeriesLimit(m.service, mr.seriesLimit(attributes.RPCClientDuration, mr.attrGRPCClient))
 	}
 
 	if mr.is.DBEnabled() {
@@ -433,7 +543,26 @@ func (mr *MetricsReporter) setupOtelMeters(m *Metrics, meter instrument.Meter) e
 			return fmt.Errorf("creating db client duration histogram metric: %w", err)
 		}
 		m.dbClientDuration = NewExpirer[*request.Span, instrument.Float64Histogram, float64](
-			m.ctx, dbClientDuration, mr.attrDBClient, timeNow, mr.cfg.TTL)
+			m.ctx, dbClientDuration, mr.attrDBClient, timeNow, mr.cfg.TTL).
+			WithSeriesLimit(m.service, mr.seriesLimit(attributes.DBClientDuration, mr.attrDBClient))
+	}
+
+	if mr.is.RedisEnabled() {
+		redisCacheLookups, err := meter.Int64Counter(attributes.RedisCacheLookups.OTEL, instrument.WithUnit("{lookup}"))
+		if err != nil {
+			return fmt.Errorf("creating redis cache lookups counter: %w", err)
+		}
+		m.redisCacheLookups = NewExpirer[*request.Span, instrument.Int64Counter, int64](
+			m.ctx, redisCacheLookups, mr.attrRedisCacheLookups, timeNow, mr.cfg.TTL).
+			WithSeriesLimit(m.service, mr.seriesLimit(attributes.RedisCacheLookups, mr.attrRedisCacheLookups))
+
+		redisPipelineSize, err := meter.Int64Histogram(attributes.RedisPipelineSize.OTEL, instrument.WithUnit("{command}"))
+		if err != nil {
+			return fmt.Errorf("creating redis pipeline size histogram metric: %w", err)
+		}
+		m.redisPipelineSize = NewExpirer[*request.Span, instrument.Int64Histogram, int64](
+			m.ctx, redisPipelineSize, mr.attrRedisPipelineSize, timeNow, mr.cfg.TTL).
+			WithSeriesLimit(m.service, mr.seriesLimit(attributes.RedisPipelineSize, mr.attrRedisPipelineSize))
 	}
 
 	if mr.is.MQEnabled() {
@@ -442,19 +571,83 @@ func (mr *MetricsReporter) setupOtelMeters(m *Metrics, meter instrument.Meter) e
 			return fmt.Errorf("creating messaging client publish duration histogram metric: %w", err)
 		}
 		m.msgPublishDuration = NewExpirer[*request.Span, instrument.Float64Histogram, float64](
-			m.ctx, msgPublishDuration, mr.attrMessagingPublish, timeNow, mr.cfg.TTL)
+			m.ctx, msgPublishDuration, mr.attrMessagingPublish, timeNow, mr.cfg.TTL).
+			WithSeriesLimit(m.service, mr.seriesLimit(attributes.MessagingPublishDuration, mr.attrMessagingPublish))
 
 		msgProcessDuration, err := meter.Float64Histogram(attributes.MessagingProcessDuration.OTEL, instrument.WithUnit("s"))
 		if err != nil {
 			return fmt.Errorf("creating messaging client process duration histogram metric: %w", err)
 		}
 		m.msgProcessDuration = NewExpirer[*request.Span, instrument.Float64Histogram, float64](
-			m.ctx, msgProcessDuration, mr.attrMessagingProcess, timeNow, mr.cfg.TTL)
+			m.ctx, msgProcessDuration, mr.attrMessagingProcess, timeNow, mr.cfg.TTL).
+			WithSeriesLimit(m.service, mr.seriesLimit(attributes.MessagingProcessDuration, mr.attrMessagingProcess))
+
+		if err := mr.setupKafkaMeters(m, meter); err != nil {
+			return err
+		}
+	}
+
+	if mr.is.DNSEnabled() {
+		dnsLookupDuration, err := meter.Float64Histogram(attributes.DNSLookupDuration.OTEL, instrument.WithUnit("s"))
+		if err != nil {
+			return fmt.Errorf("creating dns lookup duration histogram metric: %w", err)
+		}
+		m.dnsLookupDuration = NewExpirer[*request.Span, instrument.Float64Histogram, float64](
+			m.ctx, dnsLookupDuration, mr.attrDNSLookup, timeNow, mr.cfg.TTL).
+			WithSeriesLimit(m.service, mr.seriesLimit(attributes.DNSLookupDuration, mr.attrDNSLookup))
 	}
 
 	return nil
 }
 
+// seriesLimit returns the cardinality limit of an application metric, or nil if it is not limited
+func (mr *MetricsReporter) seriesLimit(
+	metric attributes.Name, getters []attributes.Field[*request.Span, attribute.KeyValue],
+) *expire.SeriesLimit {
+	names := make([]string, 0, len(getters))
+	for _, g := range getters {
+		names = append(names, g.ExposedName)
+	}
+	return mr.cfg.CardinalityLimits.SeriesLimit(metric, names, func(service *svc.ID, label string) {
+		mr.internal.MetricSeriesOverflow(metric.OTEL, service.String(), label)
+	})
+}
+
+func (mr *MetricsReporter) setupKafkaMeters(m *Metrics, meter instrument.Meter) error {
+	kafkaMessages, err := meter.Int64Counter(attributes.MessagingKafkaMessages.OTEL, instrument.WithUnit("{message}"))
+	if err != nil {
+		return fmt.Errorf("creating kafka messages counter: %w", err)
+	}
+	m.kafkaMessages = NewExpirer[*request.Span, instrument.Int64Counter, int64](
+		m.ctx, kafkaMessages, mr.attrKafkaMessages, timeNow, mr.cfg.TTL).
+		WithSeriesLimit(m.service, mr.seriesLimit(attributes.MessagingKafkaMessages, mr.attrKafkaMessages))
+
+	kafkaMessagesSize, err := meter.Int64Counter(attributes.MessagingKafkaMessagesSize.OTEL, instrument.WithUnit("By"))
+	if err != nil {
+		return fmt.Errorf("creating kafka messages size counter: %w", err)
+	}
+	m.kafkaMessagesSize = NewExpirer[*request.Span, instrument.Int64Counter, int64](
+		m.ctx, kafkaMessagesSize, mr.attrKafkaMessagesSize, timeNow, mr.cfg.TTL).
+		WithSeriesLimit(m.service, mr.seriesLimit(attributes.MessagingKafkaMessagesSize, mr.attrKafkaMessagesSize))
+
+	kafkaOffset, err := meter.Int64Gauge(attributes.MessagingKafkaOffset.OTEL)
+	if err != nil {
+		return fmt.Errorf("creating kafka offset gauge: %w", err)
+	}
+	m.kafkaOffset = NewExpirer[*request.Span, instrument.Int64Gauge, int64](
+		m.ctx, kafkaOffset, mr.attrKafkaOffset, timeNow, mr.cfg.TTL).
+		WithSeriesLimit(m.service, mr.seriesLimit(attributes.MessagingKafkaOffset, mr.attrKafkaOffset))
+
+	kafkaConsumerLag, err := meter.Int64Gauge(attributes.MessagingKafkaConsumerLag.OTEL, instrument.WithUnit("{message}"))
+	if err != nil {
+		return fmt.Errorf("creating kafka consumer lag gauge: %w", err)
+	}
+	m.kafkaConsumerLag = NewExpirer[*request.Span, instrument.Int64Gauge, int64](
+		m.ctx, kafkaConsumerLag, mr.attrKafkaConsumerLag, timeNow, mr.cfg.TTL).
+		WithSeriesLimit(m.service, mr.seriesLimit(attributes.MessagingKafkaConsumerLag, mr.attrKafkaConsumerLag))
+	return nil
+}
+
 func (mr *MetricsReporter) setupSpanMeters(m *Metrics, meter instrument.Meter) error {
 	if !mr.cfg.SpanMetricsEnabled() {
 		return nil
@@ -530,6 +723,15 @@ func (mr *MetricsReporter) setupGraphMeters(m *Metrics, meter instrument.Meter)
 	m.serviceGraphTotal = NewExpirer[*request.Span, instrument.Int64Counter, int64](
 		m.ctx, serviceGraphTotal, serviceGraphAttrs, timeNow, mr.cfg.TTL)
 
+	if mr.cfg.ServiceGraphMessagingEnabled() {
+		serviceGraphMessaging, err := meter.Float64Histogram(ServiceGraphMessaging, instrument.WithUnit("s"))
+		if err != nil {
+			return fmt.Errorf("creating service graph messaging histogram: %w", err)
+		}
+		m.serviceGraphMessaging = NewExpirer[*request.Span, instrument.Float64Histogram, float64](
+			m.ctx, serviceGraphMessaging, serviceGraphAttrs, timeNow, mr.cfg.TTL)
+	}
+
 	if m.tracesTargetInfo == nil {
 		m.tracesTargetInfo, err = meter.Int64UpDownCounter(TracesTargetInfo)
 		if err != nil {
@@ -745,8 +947,13 @@ func (mr *MetricsReporter) spanMetricAttributes() []attributes.Field[*request.Sp
 }
 
 func (mr *MetricsReporter) serviceGraphAttributes() []attributes.Field[*request.Span, attribute.KeyValue] {
+	getters := request.SpanOTELGetters
+	if mr.cfg.ServiceGraphMessagingEnabled() {
+		// producers and consumers are connected to the virtual node of their messaging destination
+		getters = msggraph.SpanOTELGetters
+	}
 	return attributes.OpenTelemetryGetters(
-		request.SpanOTELGetters, []attr.Name{
+		getters, []attr.Name{
 			attr.Client,
 			attr.ClientNamespace,
 			attr.Server,
@@ -788,11 +995,14 @@ func (r *Metrics) record(span *request.Span, mr *MetricsReporter) {
 				httpClientRequestSize, attrs := r.httpClientRequestSize.ForRecord(span)
 				httpClientRequestSize.Record(r.ctx, float64(span.RequestLength()), instrument.WithAttributeSet(attrs))
 			}
-		case request.EventTypeRedisServer, request.EventTypeRedisClient, request.EventTypeSQLClient:
+		case request.EventTypeRedisServer, request.EventTypeRedisClient, request.EventTypeSQLClient, request.EventTypeMongoClient:
 			if mr.is.DBEnabled() {
 				dbClientDuration, attrs := r.dbClientDuration.ForRecord(span)
 				dbClientDuration.Record(r.ctx, duration, instrument.WithAttributeSet(attrs))
 			}
+			if mr.is.RedisEnabled() && span.Redis != nil {
+				r.recordRedis(span)
+			}
 		case request.EventTypeKafkaClient, request.EventTypeKafkaServer:
 			if mr.is.MQEnabled() {
 				switch span.Method {
@@ -803,6 +1013,14 @@ func (r *Metrics) record(span *request.Span, mr *MetricsReporter) {
 					msgProcessDuration, attrs := r.msgProcessDuration.ForRecord(span)
 					msgProcessDuration.Record(r.ctx, duration, instrument.WithAttributeSet(attrs))
 				}
+				if span.Messaging != nil {
+					r.recordKafkaPartition(span)
+				}
+			}
+		case request.EventTypeDNSClient:
+			if mr.is.DNSEnabled() {
+				dnsLookupDuration, attrs := r.dnsLookupDuration.ForRecord(span)
+				dnsLookupDuration.Record(r.ctx, duration, instrument.WithAttributeSet(attrs))
 			}
 		}
 	}
@@ -819,7 +1037,7 @@ func (r *Metrics) record(span *request.Span, mr *MetricsReporter) {
 	}
 
 	if mr.cfg.ServiceGraphMetricsEnabled() {
-		if span.IsClientSpan() {
+		if mr.graphClientSide(span) {
 			sgc, attrs := r.serviceGraphClient.ForRecord(span)
 			sgc.Record(r.ctx, duration, instrument.WithAttributeSet(attrs))
 		} else {
@@ -832,6 +1050,55 @@ func (r *Metrics) record(span *request.Span, mr *MetricsReporter) {
 			sgf, attrs := r.serviceGraphFailed.ForRecord(span)
 			sgf.Add(r.ctx, 1, instrument.WithAttributeSet(attrs))
 		}
+		if mr.msgJoiner != nil {
+			if latency, ok := mr.msgJoiner.Join(span); ok {
+				sgm, attrs := r.serviceGraphMessaging.ForRecord(span)
+				sgm.Record(r.ctx, latency.Seconds(), instrument.WithAttributeSet(attrs))
+			}
+		}
+	}
+}
+
+// graphClientSide returns whether the span is reported as the client side of its service graph edge
+func (mr *MetricsReporter) graphClientSide(span *request.Span) bool {
+	if mr.cfg.ServiceGraphMessagingEnabled() {
+		return msggraph.IsClientSide(span)
+	}
+	return span.IsClientSpan()
+}
+
+// recordRedis records the metrics that are only available when the Redis
+// commands could be parsed from the request
+func (r *Metrics) recordRedis(span *request.Span) {
+	if span.Redis.CacheResult != "" {
+		lookups, attrs := r.redisCacheLookups.ForRecord(span)
+		lookups.Add(r.ctx, 1, instrument.WithAttributeSet(attrs))
+	}
+	if span.Redis.PipelineSize > 0 {
+		pipelineSize, attrs := r.redisPipelineSize.ForRecord(span)
+		pipelineSize.Record(r.ctx, int64(span.Redis.PipelineSize), instrument.WithAttributeSet(attrs))
+	}
+}
+
+// recordKafkaPartition records the metrics that are only available when the
+// partition information could be parsed from the Kafka messages
+func (r *Metrics) recordKafkaPartition(span *request.Span) {
+	info := span.Messaging
+	if info.Messages > 0 {
+		messages, attrs := r.kafkaMessages.ForRecord(span)
+		messages.Add(r.ctx, int64(info.Messages), instrument.WithAttributeSet(attrs))
+	}
+	if info.Bytes > 0 {
+		size, attrs := r.kafkaMessagesSize.ForRecord(span)
+		size.Add(r.ctx, int64(info.Bytes), instrument.WithAttributeSet(attrs))
+	}
+	if info.Offset >= 0 {
+		offset, attrs := r.kafkaOffset.ForRecord(span)
+		offset.Record(r.ctx, info.Offset, instrument.WithAttributeSet(attrs))
+	}
+	if lag, ok := info.ConsumerLag(); ok {
+		consumerLag, attrs := r.kafkaConsumerLag.ForRecord(span)
+		consumerLag.Record(r.ctx, lag, instrument.WithAttributeSet(attrs))
 	}
 }
 
@@ -968,7 +1235,7 @@ func setMetricsProtocol(cfg *MetricsConfig) {
 	os.Setenv(envMetricsProtocol, string(cfg.GuessProtocol()))
 }
 
-func cleanupMetrics(ctx context.Context, m *Expirer[*request.Span, instrument.Float64Histogram, float64]) {
+func cleanupMetrics[M removableMetric[V], V any](ctx context.Context, m *Expirer[*request.Span, M, V]) {
 	if m != nil {
 		m.RemoveAllMetrics(ctx)
 	}
@@ -984,4 +1251,12 @@ func (r *Metrics) cleanupAllMetricsInstances() {
 	cleanupMetrics(r.ctx, r.msgProcessDuration)
 	cleanupMetrics(r.ctx, r.httpRequestSize)
 	cleanupMetrics(r.ctx, r.httpClientRequestSize)
+	cleanupMetrics(r.ctx, r.kafkaMessages)
+	cleanupMetrics(r.ctx, r.kafkaMessagesSize)
+	cleanupMetrics(r.ctx, r.kafkaOffset)
+	cleanupMetrics(r.ctx, r.kafkaConsumerLag)
+	cleanupMetrics(r.ctx, r.dnsLookupDuration)
+	cleanupMetrics(r.ctx, r.redisCacheLookups)
+	cleanupMetrics(r.ctx, r.redisPipelineSize)
+	cleanupMetrics(r.ctx, r.serviceGraphMessaging)
 }
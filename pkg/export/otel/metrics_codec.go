@@ -0,0 +1,411 @@
+package otel
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// The OpenTelemetry SDK metrics can't be directly serialized, as they contain interfaces
+// and opaque attribute sets. They are converted to the stored* types below before being
+// gob-encoded into the disk queue. Exemplars are not stored, as Beyla does not generate them.
+
+type storedMetricKind uint8
+
+const (
+	storedGauge storedMetricKind = iota
+	storedSum
+	storedHistogram
+	storedExpHistogram
+)
+
+type storedResourceMetrics struct {
+	SchemaURL string
+	Resource  []storedAttr
+	Scopes    []storedScopeMetrics
+}
+
+type storedScopeMetrics struct {
+	Name      string
+	Version   string
+	SchemaURL string
+	Metrics   []storedMetric
+}
+
+type storedMetric struct {
+	Name        string
+	Description string
+	Unit        string
+	Kind        storedMetricKind
+	Int         bool
+	Temporality metricdata.Temporality
+	Monotonic   bool
+	Points      []storedPoint
+}
+
+type storedNumber struct {
+	Int   int64
+	Float float64
+}
+
+type storedPoint struct {
+	Attrs     []storedAttr
+	StartTime time.Time
+	Time      time.Time
+	// Value of gauges and sums
+	Value storedNumber
+	// histograms
+	Count        uint64
+	Sum          storedNumber
+	Min, Max     *storedNumber
+	Bounds       []float64
+	BucketCounts []uint64
+	// exponential histograms
+	Scale         int32
+	ZeroCount     uint64
+	ZeroThreshold float64
+	Positive      storedBuckets
+	Negative      storedBuckets
+}
+
+type storedBuckets struct {
+	Offset int32
+	Counts []uint64
+}
+
+type storedAttr struct {
+	Key          string
+	Type         attribute.Type
+	Bool         bool
+	Int64        int64
+	Float64      float64
+	String       string
+	BoolSlice    []bool
+	Int64Slice   []int64
+	Float64Slice []float64
+	StringSlice  []string
+}
+
+func encodeResourceMetrics(md *metricdata.ResourceMetrics) ([]byte, error) {
+	srm := storedResourceMetrics{}
+	if md.Resource != nil {
+		srm.SchemaURL = md.Resource.SchemaURL()
+		srm.Resource = storeAttrs(md.Resource.Iter())
+	}
+	for _, sm := range md.ScopeMetrics {
+		ssm := storedScopeMetrics{
+			Name:      sm.Scope.Name,
+			Version:   sm.Scope.Version,
+			SchemaURL: sm.Scope.SchemaURL,
+		}
+		for i := range sm.Metrics {
+			m, err := storeMetric(&sm.Metrics[i])
+			if err != nil {
+				return nil, err
+			}
+			ssm.Metrics = append(ssm.Metrics, m)
+		}
+		srm.Scopes = append(srm.Scopes, ssm)
+	}
+	buf := bytes.Buffer{}
+	if err := gob.NewEncoder(&buf).Encode(&srm); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeResourceMetrics(record []byte) (*metricdata.ResourceMetrics, error) {
+	srm := storedResourceMetrics{}
+	if err := gob.NewDecoder(bytes.NewReader(record)).Decode(&srm); err != nil {
+		return nil, err
+	}
+	md := &metricdata.ResourceMetrics{
+		Resource: resource.NewWithAttributes(srm.SchemaURL, loadAttrs(srm.Resource)...),
+	}
+	for _, ssm := range srm.Scopes {
+		sm := metricdata.ScopeMetrics{
+			Scope: instrumentation.Scope{
+				Name:      ssm.Name,
+				Version:   ssm.Version,
+				SchemaURL: ssm.SchemaURL,
+			},
+		}
+		for i := range ssm.Metrics {
+			sm.Metrics = append(sm.Metrics, loadMetric(&ssm.Metrics[i]))
+		}
+		md.ScopeMetrics = append(md.ScopeMetrics, sm)
+	}
+	return md, nil
+}
+
+func storeMetric(m *metricdata.Metrics) (storedMetric, error) {
+	sm := storedMetric{Name: m.Name, Description: m.Description, Unit: m.Unit}
+	switch data := m.Data.(type) {
+	case metricdata.Gauge[int64]:
+		sm.Kind, sm.Int, sm.Points = storedGauge, true, storeDataPoints(data.DataPoints)
+	case metricdata.Gauge[float64]:
+		sm.Kind, sm.Points = storedGauge, storeDataPoints(data.DataPoints)
+	case metricdata.Sum[int64]:
+		sm.Kind, sm.Int, sm.Points = storedSum, true, storeDataPoints(data.DataPoints)
+		sm.Temporality, sm.Monotonic = data.Temporality, data.IsMonotonic
+	case metricdata.Sum[float64]:
+		sm.Kind, sm.Points = storedSum, storeDataPoints(data.DataPoints)
+		sm.Temporality, sm.Monotonic = data.Temporality, data.IsMonotonic
+	case metricdata.Histogram[int64]:
+		sm.Kind, sm.Int, sm.Points = storedHistogram, true, storeHistogramPoints(data.DataPoints)
+		sm.Temporality = data.Temporality
+	case metricdata.Histogram[float64]:
+		sm.Kind, sm.Points = storedHistogram, storeHistogramPoints(data.DataPoints)
+		sm.Temporality = data.Temporality
+	case metricdata.ExponentialHistogram[int64]:
+		sm.Kind, sm.Int, sm.Points = storedExpHistogram, true, storeExpHistogramPoints(data.DataPoints)
+		sm.Temporality = data.Temporality
+	case metricdata.ExponentialHistogram[float64]:
+		sm.Kind, sm.Points = storedExpHistogram, storeExpHistogramPoints(data.DataPoints)
+		sm.Temporality = data.Temporality
+	default:
+		return sm, fmt.Errorf("unsupported metric data type %T for metric %s", m.Data, m.Name)
+	}
+	return sm, nil
+}
+
+func loadMetric(sm *storedMetric) metricdata.Metrics {
+	m := metricdata.Metrics{Name: sm.Name, Description: sm.Description, Unit: sm.Unit}
+	if sm.Int {
+		m.Data = loadAggregation[int64](sm)
+	} else {
+		m.Data = loadAggregation[float64](sm)
+	}
+	return m
+}
+
+func loadAggregation[N int64 | float64](sm *storedMetric) metricdata.Aggregation {
+	switch sm.Kind {
+	case storedSum:
+		return metricdata.Sum[N]{
+			DataPoints:  loadDataPoints[N](sm.Points),
+			Temporality: sm.Temporality,
+			IsMonotonic: sm.Monotonic,
+		}
+	case storedHistogram:
+		return metricdata.Histogram[N]{
+			DataPoints:  loadHistogramPoints[N](sm.Points),
+			Temporality: sm.Temporality,
+		}
+	case storedExpHistogram:
+		return metricdata.ExponentialHistogram[N]{
+			DataPoints:  loadExpHistogramPoints[N](sm.Points),
+			Temporality: sm.Temporality,
+		}
+	default:
+		return metricdata.Gauge[N]{DataPoints: loadDataPoints[N](sm.Points)}
+	}
+}
+
+func storeDataPoints[N int64 | float64](dps []metricdata.DataPoint[N]) []storedPoint {
+	points := make([]storedPoint, 0, len(dps))
+	for i := range dps {
+		dp := &dps[i]
+		points = append(points, storedPoint{
+			Attrs:     storeAttrs(dp.Attributes.Iter()),
+			StartTime: dp.StartTime,
+			Time:      dp.Time,
+			Value:     storeNumber(dp.Value),
+		})
+	}
+	return points
+}
+
+func loadDataPoints[N int64 | float64](points []storedPoint) []metricdata.DataPoint[N] {
+	dps := make([]metricdata.DataPoint[N], 0, len(points))
+	for i := range points {
+		p := &points[i]
+		dps = append(dps, metricdata.DataPoint[N]{
+			Attributes: attribute.NewSet(loadAttrs(p.Attrs)...),
+			StartTime:  p.StartTime,
+			Time:       p.Time,
+			Value:      loadNumber[N](p.Value),
+		})
+	}
+	return dps
+}
+
+func storeHistogramPoints[N int64 | float64](dps []metricdata.HistogramDataPoint[N]) []storedPoint {
+	points := make([]storedPoint, 0, len(dps))
+	for i := range dps {
+		dp := &dps[i]
+		points = append(points, storedPoint{
+			Attrs:        storeAttrs(dp.Attributes.Iter()),
+			StartTime:    dp.StartTime,
+			Time:         dp.Time,
+			Count:        dp.Count,
+			Sum:          storeNumber(dp.Sum),
+			Min:          storeExtrema(dp.Min),
+			Max:          storeExtrema(dp.Max),
+			Bounds:       dp.Bounds,
+			BucketCounts: dp.BucketCounts,
+		})
+	}
+	return points
+}
+
+func loadHistogramPoints[N int64 | float64](points []storedPoint) []metricdata.HistogramDataPoint[N] {
+	dps := make([]metricdata.HistogramDataPoint[N], 0, len(points))
+	for i := range points {
+		p := &points[i]
+		dps = append(dps, metricdata.HistogramDataPoint[N]{
+			Attributes:   attribute.NewSet(loadAttrs(p.Attrs)...),
+			StartTime:    p.StartTime,
+			Time:         p.Time,
+			Count:        p.Count,
+			Sum:          loadNumber[N](p.Sum),
+			Min:          loadExtrema[N](p.Min),
+			Max:          loadExtrema[N](p.Max),
+			Bounds:       p.Bounds,
+			BucketCounts: p.BucketCounts,
+		})
+	}
+	return dps
+}
+
+func storeExpHistogramPoints[N int64 | float64](dps []metricdata.ExponentialHistogramDataPoint[N]) []storedPoint {
+	points := make([]storedPoint, 0, len(dps))
+	for i := range dps {
+		dp := &dps[i]
+		points = append(points, storedPoint{
+			Attrs:         storeAttrs(dp.Attributes.Iter()),
+			StartTime:     dp.StartTime,
+			Time:          dp.Time,
+			Count:         dp.Count,
+			Sum:           storeNumber(dp.Sum),
+			Min:           storeExtrema(dp.Min),
+			Max:           storeExtrema(dp.Max),
+			Scale:         dp.Scale,
+			ZeroCount:     dp.ZeroCount,
+			ZeroThreshold: dp.ZeroThreshold,
+			Positive:      storedBuckets{Offset: dp.PositiveBucket.Offset, Counts: dp.PositiveBucket.Counts},
+			Negative:      storedBuckets{Offset: dp.NegativeBucket.Offset, Counts: dp.NegativeBucket.Counts},
+		})
+	}
+	return points
+}
+
+func loadExpHistogramPoints[N int64 | float64](points []storedPoint) []metricdata.ExponentialHistogramDataPoint[N] {
+	dps := make([]metricdata.ExponentialHistogramDataPoint[N], 0, len(points))
+	for i := range points {
+		p := &points[i]
+		dps = append(dps, metricdata.ExponentialHistogramDataPoint[N]{
+			Attributes:     attribute.NewSet(loadAttrs(p.Attrs)...),
+			StartTime:      p.StartTime,
+			Time:           p.Time,
+			Count:          p.Count,
+			Sum:            loadNumber[N](p.Sum),
+			Min:            loadExtrema[N](p.Min),
+			Max:            loadExtrema[N](p.Max),
+			Scale:          p.Scale,
+			ZeroCount:      p.ZeroCount,
+			ZeroThreshold:  p.ZeroThreshold,
+			PositiveBucket: metricdata.ExponentialBucket{Offset: p.Positive.Offset, Counts: p.Positive.Counts},
+			NegativeBucket: metricdata.ExponentialBucket{Offset: p.Negative.Offset, Counts: p.Negative.Counts},
+		})
+	}
+	return dps
+}
+
+func storeNumber[N int64 | float64](n N) storedNumber {
+	switch v := any(n).(type) {
+	case int64:
+		return storedNumber{Int: v}
+	case float64:
+		return storedNumber{Float: v}
+	}
+	return storedNumber{}
+}
+
+func loadNumber[N int64 | float64](sn storedNumber) N {
+	var n N
+	switch p := any(&n).(type) {
+	case *int64:
+		*p = sn.Int
+	case *float64:
+		*p = sn.Float
+	}
+	return n
+}
+
+func storeExtrema[N int64 | float64](e metricdata.Extrema[N]) *storedNumber {
+	v, ok := e.Value()
+	if !ok {
+		return nil
+	}
+	sn := storeNumber(v)
+	return &sn
+}
+
+func loadExtrema[N int64 | float64](sn *storedNumber) metricdata.Extrema[N] {
+	if sn == nil {
+		return metricdata.Extrema[N]{}
+	}
+	return metricdata.NewExtrema(loadNumber[N](*sn))
+}
+
+func storeAttrs(it attribute.Iterator) []storedAttr {
+	attrs := make([]storedAttr, 0, it.Len())
+	for it.Next() {
+		kv := it.Attribute()
+		sa := storedAttr{Key: string(kv.Key), Type: kv.Value.Type()}
+		switch sa.Type {
+		case attribute.BOOL:
+			sa.Bool = kv.Value.AsBool()
+		case attribute.INT64:
+			sa.Int64 = kv.Value.AsInt64()
+		case attribute.FLOAT64:
+			sa.Float64 = kv.Value.AsFloat64()
+		case attribute.BOOLSLICE:
+			sa.BoolSlice = kv.Value.AsBoolSlice()
+		case attribute.INT64SLICE:
+			sa.Int64Slice = kv.Value.AsInt64Slice()
+		case attribute.FLOAT64SLICE:
+			sa.Float64Slice = kv.Value.AsFloat64Slice()
+		case attribute.STRINGSLICE:
+			sa.StringSlice = kv.Value.AsStringSlice()
+		default:
+			sa.Type, sa.String = attribute.STRING, kv.Value.Emit()
+		}
+		attrs = append(attrs, sa)
+	}
+	return attrs
+}
+
+func loadAttrs(attrs []storedAttr) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for i := range attrs {
+		sa := &attrs[i]
+		key := attribute.Key(sa.Key)
+		switch sa.Type {
+		case attribute.BOOL:
+			kvs = append(kvs, key.Bool(sa.Bool))
+		case attribute.INT64:
+			kvs = append(kvs, key.Int64(sa.Int64))
+		case attribute.FLOAT64:
+			kvs = append(kvs, key.Float64(sa.Float64))
+		case attribute.BOOLSLICE:
+			kvs = append(kvs, key.BoolSlice(sa.BoolSlice))
+		case attribute.INT64SLICE:
+			kvs = append(kvs, key.Int64Slice(sa.Int64Slice))
+		case attribute.FLOAT64SLICE:
+			kvs = append(kvs, key.Float64Slice(sa.Float64Slice))
+		case attribute.STRINGSLICE:
+			kvs = append(kvs, key.StringSlice(sa.StringSlice))
+		default:
+			kvs = append(kvs, key.String(sa.String))
+		}
+	}
+	return kvs
+}
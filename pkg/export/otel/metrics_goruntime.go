@@ -0,0 +1,260 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/mariomac/pipes/pipe"
+	"go.opentelemetry.io/otel/attribute"
+	metric2 "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.19.0"
+
+	"github.com/grafana/beyla/pkg/export/attributes"
+	"github.com/grafana/beyla/pkg/export/expire"
+	"github.com/grafana/beyla/pkg/internal/infraolly/goruntime"
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+// GoRuntimeMetricsConfig extends MetricsConfig for the Go runtime metrics
+type GoRuntimeMetricsConfig struct {
+	Metrics            *MetricsConfig
+	AttributeSelectors attributes.Selection
+}
+
+func (mc *GoRuntimeMetricsConfig) Enabled() bool {
+	return mc.Metrics != nil && mc.Metrics.EndpointEnabled() && mc.Metrics.OTelMetricsEnabled() &&
+		mc.Metrics.GoRuntimeMetricsEnabled()
+}
+
+func grmlog() *slog.Logger {
+	return slog.With("component", "otel.GoRuntimeMetricsExporter")
+}
+
+type goRuntimeMetricsExporter struct {
+	ctx   context.Context
+	cfg   *GoRuntimeMetricsConfig
+	clock *expire.CachedClock
+
+	hostID string
+
+	exporter  metric.Exporter
+	reporters ReporterPool[*svc.ID, *goRuntimeMetrics]
+
+	log *slog.Logger
+
+	attrGoroutinesCreated []attributes.Field[*goruntime.Status, attribute.KeyValue]
+	attrGoroutinesExited  []attributes.Field[*goruntime.Status, attribute.KeyValue]
+	attrGCCycles          []attributes.Field[*goruntime.Status, attribute.KeyValue]
+	attrSTWPauses         []attributes.Field[*goruntime.Status, attribute.KeyValue]
+	attrSchedLatency      []attributes.Field[*goruntime.Status, attribute.KeyValue]
+}
+
+type goRuntimeMetrics struct {
+	ctx      context.Context
+	provider *metric.MeterProvider
+
+	// don't forget to add the cleanup code in cleanupAllMetricsInstances function
+	goroutinesCreated *Expirer[*goruntime.Status, metric2.Int64Counter, int64]
+	goroutinesExited  *Expirer[*goruntime.Status, metric2.Int64Counter, int64]
+	gcCycles          *Expirer[*goruntime.Status, metric2.Int64Counter, int64]
+
+	// the latency histograms are already bucketed by the eBPF probes, so they
+	// are provided to the metrics reader as aggregated data.
+	// schedLatency is nil unless the application_go_scheduler feature is enabled.
+	stwPauses    *bucketedHistogram
+	schedLatency *bucketedHistogram
+}
+
+// GoRuntimeMetricsExporterProvider returns a pipeline node that exports the Go runtime
+// status of the instrumented services as OpenTelemetry metrics
+func GoRuntimeMetricsExporterProvider(
+	ctx context.Context,
+	ctxInfo *global.ContextInfo,
+	cfg *GoRuntimeMetricsConfig,
+) pipe.FinalProvider[[]*goruntime.Status] {
+	return func() (pipe.FinalFunc[[]*goruntime.Status], error) {
+		if !cfg.Enabled() {
+			// This node is not going to be instantiated. Let the pipes library just ignore it.
+			return pipe.IgnoreFinal[[]*goruntime.Status](), nil
+		}
+		return newGoRuntimeMetricsExporter(ctx, ctxInfo, cfg)
+	}
+}
+
+func newGoRuntimeMetricsExporter(
+	ctx context.Context,
+	ctxInfo *global.ContextInfo,
+	cfg *GoRuntimeMetricsConfig,
+) (pipe.FinalFunc[[]*goruntime.Status], error) {
+	SetupInternalOTELSDKLogger(cfg.Metrics.SDKLogLevel)
+
+	log := grmlog()
+	log.Debug("instantiating Go runtime metrics exporter provider")
+
+	// only user-provided attributes (or default set) will decorate the metrics
+	attrProv, err := attributes.NewAttrSelector(ctxInfo.MetricAttributeGroups, cfg.AttributeSelectors)
+	if err != nil {
+		return nil, fmt.Errorf("go runtime OTEL exporter attributes: %w", err)
+	}
+
+	mr := &goRuntimeMetricsExporter{
+		log:    log,
+		ctx:    ctx,
+		cfg:    cfg,
+		hostID: ctxInfo.HostID,
+		clock:  expire.NewCachedClock(timeNow),
+		attrGoroutinesCreated: attributes.OpenTelemetryGetters(goruntime.OTELGetters,
+			attrProv.For(attributes.GoRuntimeGoroutinesCreated)),
+		attrGoroutinesExited: attributes.OpenTelemetryGetters(goruntime.OTELGetters,
+			attrProv.For(attributes.GoRuntimeGoroutinesExited)),
+		attrGCCycles: attributes.OpenTelemetryGetters(goruntime.OTELGetters,
+			attrProv.For(attributes.GoRuntimeGCCycles)),
+		attrSTWPauses: attributes.OpenTelemetryGetters(goruntime.OTELGetters,
+			attrProv.For(attributes.GoRuntimeSTWPauseDuration)),
+		attrSchedLatency: attributes.OpenTelemetryGetters(goruntime.OTELGetters,
+			attrProv.For(attributes.GoRuntimeSchedLatency)),
+	}
+
+	mr.reporters = NewReporterPool[*svc.ID, *goRuntimeMetrics](cfg.Metrics.ReportersCacheLen, cfg.Metrics.TTL, timeNow,
+		func(id svc.UID, v *expirable[*goRuntimeMetrics]) {
+			llog := log.With("service", id)
+			llog.Debug("evicting metrics reporter from cache")
+			v.value.cleanupAllMetricsInstances()
+			go func() {
+				// shutting down also flushes the pending metrics
+				if err := v.value.provider.Shutdown(ctx); err != nil {
+					llog.Warn("error shutting down evicted metrics provider", "error", err)
+				}
+			}()
+		}, mr.newMetricSet)
+
+	mr.exporter, err = InstantiateMetricsExporter(ctx, cfg.Metrics, log)
+	if err != nil {
+		log.Error("instantiating metrics exporter", "error", err)
+		return nil, err
+	}
+	mr.exporter, err = queueMetricsExporter(ctx, cfg.Metrics, signalGoRuntimeMetrics, ctxInfo.Metrics, mr.exporter)
+	if err != nil {
+		return nil, err
+	}
+
+	return mr.Do, nil
+}
+
+func (me *goRuntimeMetricsExporter) newMetricSet(service *svc.ID) (*goRuntimeMetrics, error) {
+	log := me.log.With("service", service)
+	log.Debug("creating new Metrics exporter")
+	resources := resource.NewWithAttributes(semconv.SchemaURL, getAppResourceAttrs(me.hostID, service)...)
+	histograms := newBucketedHistograms(timeNow(), me.cfg.Metrics.TTL)
+	opts := []metric.Option{
+		metric.WithResource(resources),
+		metric.WithReader(metric.NewPeriodicReader(me.exporter,
+			metric.WithInterval(me.cfg.Metrics.Interval),
+			metric.WithProducer(histograms))),
+	}
+
+	m := goRuntimeMetrics{
+		ctx:      me.ctx,
+		provider: metric.NewMeterProvider(opts...),
+		stwPauses: histograms.histogram(attributes.GoRuntimeSTWPauseDuration.OTEL,
+			"Duration of the stop-the-world pauses of the Go runtime, caused by the garbage collector or other runtime operations",
+			goruntime.BucketBounds()),
+	}
+	if me.cfg.Metrics.GoSchedulerMetricsEnabled() {
+		m.schedLatency = histograms.histogram(attributes.GoRuntimeSchedLatency.OTEL,
+			"Time that the goroutines spend in the scheduler run queues before running",
+			goruntime.BucketBounds())
+	}
+
+	meter := m.provider.Meter(reporterName)
+
+	if goroutinesCreated, err := meter.Int64Counter(
+		attributes.GoRuntimeGoroutinesCreated.OTEL,
+		metric2.WithDescription("Goroutines that have been created since the process was instrumented"),
+		metric2.WithUnit("{goroutine}"),
+	); err != nil {
+		log.Error("creating counter for "+attributes.GoRuntimeGoroutinesCreated.OTEL, "error", err)
+		return nil, err
+	} else {
+		m.goroutinesCreated = NewExpirer[*goruntime.Status, metric2.Int64Counter, int64](
+			me.ctx, goroutinesCreated, me.attrGoroutinesCreated, timeNow, me.cfg.Metrics.TTL)
+	}
+
+	if goroutinesExited, err := meter.Int64Counter(
+		attributes.GoRuntimeGoroutinesExited.OTEL,
+		metric2.WithDescription("Goroutines that have exited since the process was instrumented"),
+		metric2.WithUnit("{goroutine}"),
+	); err != nil {
+		log.Error("creating counter for "+attributes.GoRuntimeGoroutinesExited.OTEL, "error", err)
+		return nil, err
+	} else {
+		m.goroutinesExited = NewExpirer[*goruntime.Status, metric2.Int64Counter, int64](
+			me.ctx, goroutinesExited, me.attrGoroutinesExited, timeNow, me.cfg.Metrics.TTL)
+	}
+
+	if gcCycles, err := meter.Int64Counter(
+		attributes.GoRuntimeGCCycles.OTEL,
+		metric2.WithDescription("Completed garbage collection cycles"),
+		metric2.WithUnit("{cycle}"),
+	); err != nil {
+		log.Error("creating counter for "+attributes.GoRuntimeGCCycles.OTEL, "error", err)
+		return nil, err
+	} else {
+		m.gcCycles = NewExpirer[*goruntime.Status, metric2.Int64Counter, int64](
+			me.ctx, gcCycles, me.attrGCCycles, timeNow, me.cfg.Metrics.TTL)
+	}
+	return &m, nil
+}
+
+// Do reads all the Go runtime status data points and create the metrics accordingly
+func (me *goRuntimeMetricsExporter) Do(in <-chan []*goruntime.Status) {
+	for i := range in {
+		me.clock.Update()
+		for _, s := range i {
+			reporter, err := me.reporters.For(s.Service)
+			if err != nil {
+				me.log.Error("unexpected error creating OTEL resource. Ignoring metric",
+					"error", err, "service", s.Service)
+				continue
+			}
+			me.observeMetric(reporter, s)
+		}
+	}
+}
+
+func (me *goRuntimeMetricsExporter) observeMetric(reporter *goRuntimeMetrics, s *goruntime.Status) {
+	goroutinesCreated, attrs := reporter.goroutinesCreated.ForRecord(s)
+	goroutinesCreated.Add(me.ctx, int64(s.GoroutinesCreatedDelta), metric2.WithAttributeSet(attrs))
+
+	goroutinesExited, attrs := reporter.goroutinesExited.ForRecord(s)
+	goroutinesExited.Add(me.ctx, int64(s.GoroutinesExitedDelta), metric2.WithAttributeSet(attrs))
+
+	gcCycles, attrs := reporter.gcCycles.ForRecord(s)
+	gcCycles.Add(me.ctx, int64(s.GCCyclesDelta), metric2.WithAttributeSet(attrs))
+
+	now := me.clock.Time()
+	reporter.stwPauses.add(attributeSet(s, me.attrSTWPauses),
+		s.STWPausesDelta.Counts[:], s.STWPausesDelta.SumNs, now)
+	if reporter.schedLatency != nil {
+		reporter.schedLatency.add(attributeSet(s, me.attrSchedLatency),
+			s.SchedLatencyDelta.Counts[:], s.SchedLatencyDelta.SumNs, now)
+	}
+}
+
+func (r *goRuntimeMetrics) cleanupAllMetricsInstances() {
+	r.goroutinesCreated.RemoveAllMetrics(r.ctx)
+	r.goroutinesExited.RemoveAllMetrics(r.ctx)
+	r.gcCycles.RemoveAllMetrics(r.ctx)
+}
+
+func attributeSet[T any](record T, fields []attributes.Field[T, attribute.KeyValue]) attribute.Set {
+	kvs := make([]attribute.KeyValue, 0, len(fields))
+	for _, f := range fields {
+		kvs = append(kvs, f.Get(record))
+	}
+	return attribute.NewSet(kvs...)
+}
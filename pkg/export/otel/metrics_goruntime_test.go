@@ -0,0 +1,82 @@
+package otel
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mariomac/guara/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/export/instrumentations"
+	"github.com/grafana/beyla/pkg/internal/infraolly/goruntime"
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
+	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/test/collector"
+)
+
+func TestGoRuntimeMetrics(t *testing.T) {
+	os.Setenv("OTEL_METRIC_EXPORT_INTERVAL", "100")
+	defer restoreEnvAfterExecution()()
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+
+	otlp, err := collector.Start(ctx)
+	require.NoError(t, err)
+
+	// GIVEN an OTEL Go runtime metrics exporter
+	otelExporter, err := GoRuntimeMetricsExporterProvider(
+		ctx, &global.ContextInfo{}, &GoRuntimeMetricsConfig{
+			Metrics: &MetricsConfig{
+				ReportersCacheLen: 100,
+				CommonEndpoint:    otlp.ServerEndpoint,
+				MetricsProtocol:   ProtocolHTTPProtobuf,
+				Features:          []string{FeatureApplication, FeatureGoRuntime, FeatureGoScheduler},
+				TTL:               3 * time.Minute,
+				Instrumentations: []string{
+					instrumentations.InstrumentationALL,
+				},
+			},
+		})()
+	require.NoError(t, err)
+
+	statuses := make(chan []*goruntime.Status, 20)
+	go otelExporter(statuses)
+
+	// WHEN it receives the Go runtime status of a service
+	statuses <- []*goruntime.Status{{
+		Service:                &svc.ID{UID: "cart", Name: "cart", Namespace: "shop"},
+		GoroutinesCreatedDelta: 12,
+		GoroutinesExitedDelta:  4,
+		GCCyclesDelta:          3,
+		STWPausesDelta:         goruntime.Histogram{Counts: [goruntime.NumBuckets]uint64{2, 1}, SumNs: 60_000},
+		SchedLatencyDelta:      goruntime.Histogram{Counts: [goruntime.NumBuckets]uint64{4, 0, 0, 0, 0, 0, 0, 0, 0, 1}, SumNs: 200_020_000},
+	}}
+
+	// THEN the goroutines, GC cycles and latency histograms are exported
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		records := map[string]collector.MetricRecord{}
+		for len(records) < 5 {
+			metric := readChan(t, otlp.Records(), timeout)
+			records[metric.Name] = metric
+		}
+		for _, metric := range records {
+			assert.Equal(t, "cart", metric.ResourceAttributes["service.name"])
+			assert.Equal(t, "shop", metric.ResourceAttributes["service.namespace"])
+		}
+		assert.EqualValues(t, 12, records["process.runtime.go.goroutines.created"].IntVal)
+		assert.EqualValues(t, 4, records["process.runtime.go.goroutines.exited"].IntVal)
+		assert.EqualValues(t, 3, records["process.runtime.go.gc.cycles"].IntVal)
+
+		pauses := records["process.runtime.go.stw.pause.duration"]
+		assert.Equal(t, "s", pauses.Unit)
+		assert.Equal(t, 3, pauses.Count)
+		assert.InDelta(t, 0.00006, pauses.FloatVal, 1e-12)
+
+		latency := records["process.runtime.go.sched.latency"]
+		assert.Equal(t, 5, latency.Count)
+		assert.InDelta(t, 0.20002, latency.FloatVal, 1e-12)
+	})
+}
@@ -15,6 +15,7 @@ import (
 	semconv "go.opentelemetry.io/otel/semconv/v1.19.0"
 
 	"github.com/grafana/beyla/pkg/export/attributes"
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
 	"github.com/grafana/beyla/pkg/export/expire"
 	"github.com/grafana/beyla/pkg/internal/netolly/ebpf"
 	"github.com/grafana/beyla/pkg/internal/pipe/global"
@@ -52,10 +53,11 @@ func newResource(hostID string) *resource.Resource {
 	return resource.NewWithAttributes(semconv.SchemaURL, attrs...)
 }
 
-func newMeterProvider(res *resource.Resource, exporter *metric.Exporter, interval time.Duration) (*metric.MeterProvider, error) {
+func newMeterProvider(res *resource.Resource, exporter *metric.Exporter, interval time.Duration, views ...metric.View) (*metric.MeterProvider, error) {
 	meterProvider := metric.NewMeterProvider(
 		metric.WithResource(res),
 		metric.WithReader(metric.NewPeriodicReader(*exporter, metric.WithInterval(interval))),
+		metric.WithView(views...),
 	)
 	return meterProvider, nil
 }
@@ -63,6 +65,10 @@ func newMeterProvider(res *resource.Resource, exporter *metric.Exporter, interva
 type netMetricsExporter struct {
 	ctx       context.Context
 	metrics   *Expirer[*ebpf.Record, metric2.Int64Counter, float64]
+	rtt       *Expirer[*ebpf.Record, metric2.Float64Histogram, float64]
+	resets    *Expirer[*ebpf.Record, metric2.Int64Counter, float64]
+	halfOpen  *Expirer[*ebpf.Record, metric2.Int64Counter, float64]
+	drops     *Expirer[*ebpf.Record, metric2.Int64Counter, float64]
 	clock     *expire.CachedClock
 	expireTTL time.Duration
 }
@@ -87,8 +93,14 @@ func newMetricsExporter(ctx context.Context, ctxInfo *global.ContextInfo, cfg *N
 		log.Error("", "error", err)
 		return nil, err
 	}
+	exporter, err = queueMetricsExporter(ctx, cfg.Metrics, signalNetworkMetrics, ctxInfo.Metrics, exporter)
+	if err != nil {
+		return nil, err
+	}
 
-	provider, err := newMeterProvider(newResource(ctxInfo.HostID), &exporter, cfg.Metrics.Interval)
+	provider, err := newMeterProvider(newResource(ctxInfo.HostID), &exporter, cfg.Metrics.Interval,
+		otelHistogramConfig(attributes.BeylaNetworkFlowRTT.OTEL, cfg.Metrics.Buckets.DurationHistogram,
+			isExponentialAggregation(cfg.Metrics, log)))
 
 	if err != nil {
 		log.Error("", "error", err)
@@ -114,11 +126,47 @@ func newMetricsExporter(ctx context.Context, ctxInfo *global.ContextInfo, cfg *N
 		log.Error("creating observable counter", "error", err)
 		return nil, err
 	}
+	rttMetric, err := ebpfEvents.Float64Histogram(attributes.BeylaNetworkFlowRTT.OTEL,
+		metric2.WithDescription("approximate time between the SYN and the SYN-ACK of the TCP connections opened by the network flows, measured from the start of the flows in both directions"),
+		metric2.WithUnit("s"),
+	)
+	if err != nil {
+		log.Error("creating histogram", "error", err)
+		return nil, err
+	}
+	resetsMetric, err := ebpfEvents.Int64Counter(attributes.BeylaNetworkFlowResets.OTEL,
+		metric2.WithDescription("network flows that contain a TCP packet with the RST flag"),
+		metric2.WithUnit("{flows}"),
+	)
+	if err != nil {
+		log.Error("creating counter", "error", err)
+		return nil, err
+	}
+	halfOpenMetric, err := ebpfEvents.Int64Counter(attributes.BeylaNetworkFlowHalfOpen.OTEL,
+		metric2.WithDescription("TCP connections whose SYN has not been answered"),
+		metric2.WithUnit("{connections}"),
+	)
+	if err != nil {
+		log.Error("creating counter", "error", err)
+		return nil, err
+	}
+	dropsMetric, err := ebpfEvents.Int64Counter(attributes.BeylaNetworkFlowDrops.OTEL,
+		metric2.WithDescription("packets from a source network endpoint to a destination network endpoint that were dropped by the kernel"),
+		metric2.WithUnit("{packets}"),
+	)
+	if err != nil {
+		log.Error("creating counter", "error", err)
+		return nil, err
+	}
 	expirer := NewExpirer[*ebpf.Record, metric2.Int64Counter, float64](ctx, bytesMetric, attrs, clock.Time, cfg.Metrics.TTL)
 	log.Debug("restricting attributes not in this list", "attributes", cfg.AttributeSelectors)
 	return &netMetricsExporter{
 		ctx:       ctx,
 		metrics:   expirer,
+		rtt:       NewExpirer[*ebpf.Record, metric2.Float64Histogram, float64](ctx, rttMetric, attrs, clock.Time, cfg.Metrics.TTL),
+		resets:    NewExpirer[*ebpf.Record, metric2.Int64Counter, float64](ctx, resetsMetric, attrs, clock.Time, cfg.Metrics.TTL),
+		halfOpen:  NewExpirer[*ebpf.Record, metric2.Int64Counter, float64](ctx, halfOpenMetric, attrs, clock.Time, cfg.Metrics.TTL),
+		drops:     NewExpirer[*ebpf.Record, metric2.Int64Counter, float64](ctx, dropsMetric, attrs, clock.Time, cfg.Metrics.TTL),
 		clock:     clock,
 		expireTTL: cfg.Metrics.TTL,
 	}, nil
@@ -128,8 +176,28 @@ func (me *netMetricsExporter) Do(in <-chan []*ebpf.Record) {
 	for i := range in {
 		me.clock.Update()
 		for _, v := range i {
-			flowBytes, attrs := me.metrics.ForRecord(v)
-			flowBytes.Add(me.ctx, int64(v.Metrics.Bytes), metric2.WithAttributeSet(attrs))
+			if v.DropReason != "" {
+				drops, attrs := me.drops.ForRecord(v, attr.DropReason.OTEL().String(v.DropReason))
+				drops.Add(me.ctx, int64(v.Metrics.Packets), metric2.WithAttributeSet(attrs))
+				continue
+			}
+			// records that only report handshake information must not account bytes
+			if !v.IsHandshakeReport() {
+				flowBytes, attrs := me.metrics.ForRecord(v)
+				flowBytes.Add(me.ctx, int64(v.Metrics.Bytes), metric2.WithAttributeSet(attrs))
+			}
+			if v.HandshakeRTT > 0 {
+				rtt, attrs := me.rtt.ForRecord(v)
+				rtt.Record(me.ctx, v.HandshakeRTT.Seconds(), metric2.WithAttributeSet(attrs))
+			}
+			if v.Metrics.IsReset() {
+				resets, attrs := me.resets.ForRecord(v)
+				resets.Add(me.ctx, 1, metric2.WithAttributeSet(attrs))
+			}
+			if v.HalfOpen {
+				halfOpen, attrs := me.halfOpen.ForRecord(v)
+				halfOpen.Add(me.ctx, 1, metric2.WithAttributeSet(attrs))
+			}
 		}
 	}
 }
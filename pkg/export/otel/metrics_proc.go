@@ -187,6 +187,10 @@ func newProcMetricsExporter(
 		log.Error("instantiating metrics exporter", "error", err)
 		return nil, err
 	}
+	mr.exporter, err = queueMetricsExporter(ctx, cfg.Metrics, signalProcessMetrics, ctxInfo.Metrics, mr.exporter)
+	if err != nil {
+		return nil, err
+	}
 
 	return mr.Do, nil
 }
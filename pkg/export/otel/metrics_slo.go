@@ -0,0 +1,138 @@
+package otel
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/mariomac/pipes/pipe"
+	metric2 "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.19.0"
+
+	"github.com/grafana/beyla/pkg/export/attributes"
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+	"github.com/grafana/beyla/pkg/export/expire"
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
+	"github.com/grafana/beyla/pkg/internal/slo"
+)
+
+// SLOMetricsConfig extends MetricsConfig for the Service Level Objectives metrics
+type SLOMetricsConfig struct {
+	Metrics *MetricsConfig
+	SLO     *slo.Config
+}
+
+func (mc *SLOMetricsConfig) Enabled() bool {
+	return mc.Metrics != nil && mc.Metrics.EndpointEnabled() && mc.SLO != nil && mc.SLO.Enabled()
+}
+
+func slolog() *slog.Logger {
+	return slog.With("component", "otel.SLOMetricsExporter")
+}
+
+type sloMetricsExporter struct {
+	ctx   context.Context
+	clock *expire.CachedClock
+
+	target               *Expirer[*slo.Status, metric2.Float64Gauge, float64]
+	errorBudgetRemaining *Expirer[*slo.Status, metric2.Float64Gauge, float64]
+	burnRate             *Expirer[*slo.Status, metric2.Float64Gauge, float64]
+}
+
+// SLOMetricsExporterProvider returns a pipeline node that exports the status of the
+// Service Level Objectives as OpenTelemetry metrics. As the objectives can group multiple
+// services, the service name and namespace are reported as metric attributes.
+func SLOMetricsExporterProvider(
+	ctx context.Context,
+	ctxInfo *global.ContextInfo,
+	cfg *SLOMetricsConfig,
+) pipe.FinalProvider[[]*slo.Status] {
+	return func() (pipe.FinalFunc[[]*slo.Status], error) {
+		if !cfg.Enabled() {
+			// This node is not going to be instantiated. Let the pipes library just ignore it.
+			return pipe.IgnoreFinal[[]*slo.Status](), nil
+		}
+		exporter, err := newSLOMetricsExporter(ctx, ctxInfo, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return exporter.Do, nil
+	}
+}
+
+func sloResource(hostID string) *resource.Resource {
+	return resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName("beyla-slo"),
+		semconv.ServiceInstanceID(uuid.New().String()),
+		semconv.TelemetrySDKLanguageKey.String(semconv.TelemetrySDKLanguageGo.Value.AsString()),
+		semconv.TelemetrySDKNameKey.String("beyla"),
+		semconv.HostID(hostID),
+	)
+}
+
+func newSLOMetricsExporter(ctx context.Context, ctxInfo *global.ContextInfo, cfg *SLOMetricsConfig) (*sloMetricsExporter, error) {
+	SetupInternalOTELSDKLogger(cfg.Metrics.SDKLogLevel)
+
+	log := slolog()
+	log.Debug("instantiating SLO metrics exporter provider")
+	exporter, err := InstantiateMetricsExporter(ctx, cfg.Metrics, log)
+	if err != nil {
+		log.Error("instantiating metrics exporter", "error", err)
+		return nil, err
+	}
+	exporter, err = queueMetricsExporter(ctx, cfg.Metrics, signalSLOMetrics, ctxInfo.Metrics, exporter)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := newMeterProvider(sloResource(ctxInfo.HostID), &exporter, cfg.Metrics.Interval)
+	if err != nil {
+		log.Error("creating meter provider", "error", err)
+		return nil, err
+	}
+	meter := provider.Meter("slo")
+	attrs := attributes.OpenTelemetryGetters(slo.OTELGetters, slo.AttributeNames)
+	clock := expire.NewCachedClock(timeNow)
+
+	me := &sloMetricsExporter{ctx: ctx, clock: clock}
+	for _, g := range []struct {
+		name   attributes.Name
+		desc   string
+		target **Expirer[*slo.Status, metric2.Float64Gauge, float64]
+	}{
+		{name: attributes.SLOTarget, target: &me.target,
+			desc: "Target ratio of good requests of a Service Level Objective"},
+		{name: attributes.SLOErrorBudgetRemaining, target: &me.errorBudgetRemaining,
+			desc: "Ratio of the error budget that remains available during the Service Level Objective window"},
+		{name: attributes.SLOBurnRate, target: &me.burnRate,
+			desc: "Rate at which the error budget of a Service Level Objective is consumed during the last time window"},
+	} {
+		gauge, err := meter.Float64Gauge(g.name.OTEL, metric2.WithUnit("1"), metric2.WithDescription(g.desc))
+		if err != nil {
+			log.Error("creating gauge for "+g.name.OTEL, "error", err)
+			return nil, err
+		}
+		*g.target = NewExpirer[*slo.Status, metric2.Float64Gauge, float64](ctx, gauge, attrs, clock.Time, cfg.Metrics.TTL)
+	}
+	return me, nil
+}
+
+func (me *sloMetricsExporter) Do(in <-chan []*slo.Status) {
+	for statuses := range in {
+		me.clock.Update()
+		for _, st := range statuses {
+			me.observe(st)
+		}
+	}
+}
+
+func (me *sloMetricsExporter) observe(st *slo.Status) {
+	target, attrs := me.target.ForRecord(st)
+	target.Record(me.ctx, st.Target, metric2.WithAttributeSet(attrs))
+	budget, attrs := me.errorBudgetRemaining.ForRecord(st)
+	budget.Record(me.ctx, st.ErrorBudgetRemaining, metric2.WithAttributeSet(attrs))
+	for _, br := range st.BurnRates {
+		burnRate, attrs := me.burnRate.ForRecord(st, attr.SLOWindow.OTEL().String(slo.WindowLabel(br.Window)))
+		burnRate.Record(me.ctx, br.Rate, metric2.WithAttributeSet(attrs))
+	}
+}
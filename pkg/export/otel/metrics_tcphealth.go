@@ -0,0 +1,267 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/mariomac/pipes/pipe"
+	"go.opentelemetry.io/otel/attribute"
+	metric2 "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.19.0"
+
+	"github.com/grafana/beyla/pkg/export/attributes"
+	attr2 "github.com/grafana/beyla/pkg/export/attributes/names"
+	"github.com/grafana/beyla/pkg/export/expire"
+	"github.com/grafana/beyla/pkg/internal/infraolly/tcphealth"
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+var (
+	tcpFailureRefusedAttr = attr2.ErrorType.OTEL().String("refused")
+	tcpFailureTimeoutAttr = attr2.ErrorType.OTEL().String("timeout")
+)
+
+// TCPHealthMetricsConfig extends MetricsConfig for the TCP connection health metrics
+type TCPHealthMetricsConfig struct {
+	Metrics            *MetricsConfig
+	AttributeSelectors attributes.Selection
+}
+
+func (mc *TCPHealthMetricsConfig) Enabled() bool {
+	return mc.Metrics != nil && mc.Metrics.EndpointEnabled() && mc.Metrics.OTelMetricsEnabled() &&
+		mc.Metrics.TCPConnectionMetricsEnabled()
+}
+
+func thmlog() *slog.Logger {
+	return slog.With("component", "otel.TCPHealthMetricsExporter")
+}
+
+type tcpHealthMetricsExporter struct {
+	ctx   context.Context
+	cfg   *TCPHealthMetricsConfig
+	clock *expire.CachedClock
+
+	hostID string
+
+	exporter  metric.Exporter
+	reporters ReporterPool[*svc.ID, *tcpHealthMetrics]
+
+	log *slog.Logger
+
+	attrRTT           []attributes.Field[*tcphealth.Status, attribute.KeyValue]
+	attrSetupDuration []attributes.Field[*tcphealth.Status, attribute.KeyValue]
+	attrRetransmits   []attributes.Field[*tcphealth.Status, attribute.KeyValue]
+	attrResets        []attributes.Field[*tcphealth.Status, attribute.KeyValue]
+	attrFailures      []attributes.Field[*tcphealth.Status, attribute.KeyValue]
+}
+
+type tcpHealthMetrics struct {
+	ctx      context.Context
+	provider *metric.MeterProvider
+
+	// don't forget to add the cleanup code in cleanupAllMetricsInstances function
+	retransmits *Expirer[*tcphealth.Status, metric2.Int64Counter, int64]
+	resets      *Expirer[*tcphealth.Status, metric2.Int64Counter, int64]
+	failures    *Expirer[*tcphealth.Status, metric2.Int64Counter, int64]
+
+	// the duration histograms are already bucketed by the eBPF probes, so they
+	// are provided to the metrics reader as aggregated data
+	rtt           *bucketedHistogram
+	setupDuration *bucketedHistogram
+}
+
+// TCPHealthMetricsExporterProvider returns a pipeline node that exports the health of the
+// TCP connections of the instrumented services as OpenTelemetry metrics
+func TCPHealthMetricsExporterProvider(
+	ctx context.Context,
+	ctxInfo *global.ContextInfo,
+	cfg *TCPHealthMetricsConfig,
+) pipe.FinalProvider[[]*tcphealth.Status] {
+	return func() (pipe.FinalFunc[[]*tcphealth.Status], error) {
+		if !cfg.Enabled() {
+			// This node is not going to be instantiated. Let the pipes library just ignore it.
+			return pipe.IgnoreFinal[[]*tcphealth.Status](), nil
+		}
+		return newTCPHealthMetricsExporter(ctx, ctxInfo, cfg)
+	}
+}
+
+func newTCPHealthMetricsExporter(
+	ctx context.Context,
+	ctxInfo *global.ContextInfo,
+	cfg *TCPHealthMetricsConfig,
+) (pipe.FinalFunc[[]*tcphealth.Status], error) {
+	SetupInternalOTELSDKLogger(cfg.Metrics.SDKLogLevel)
+
+	log := thmlog()
+	log.Debug("instantiating TCP health metrics exporter provider")
+
+	// only user-provided attributes (or default set) will decorate the metrics
+	attrProv, err := attributes.NewAttrSelector(ctxInfo.MetricAttributeGroups, cfg.AttributeSelectors)
+	if err != nil {
+		return nil, fmt.Errorf("TCP health OTEL exporter attributes: %w", err)
+	}
+
+	mr := &tcpHealthMetricsExporter{
+		log:    log,
+		ctx:    ctx,
+		cfg:    cfg,
+		hostID: ctxInfo.HostID,
+		clock:  expire.NewCachedClock(timeNow),
+		attrRTT: attributes.OpenTelemetryGetters(tcphealth.OTELGetters,
+			attrProv.For(attributes.TCPConnectionRTT)),
+		attrSetupDuration: attributes.OpenTelemetryGetters(tcphealth.OTELGetters,
+			attrProv.For(attributes.TCPConnectionSetupDuration)),
+		attrRetransmits: attributes.OpenTelemetryGetters(tcphealth.OTELGetters,
+			attrProv.For(attributes.TCPConnectionRetransmits)),
+		attrResets: attributes.OpenTelemetryGetters(tcphealth.OTELGetters,
+			attrProv.For(attributes.TCPConnectionResets)),
+		attrFailures: attributes.OpenTelemetryGetters(tcphealth.OTELGetters,
+			attrProv.For(attributes.TCPConnectionFailures)),
+	}
+
+	mr.reporters = NewReporterPool[*svc.ID, *tcpHealthMetrics](cfg.Metrics.ReportersCacheLen, cfg.Metrics.TTL, timeNow,
+		func(id svc.UID, v *expirable[*tcpHealthMetrics]) {
+			llog := log.With("service", id)
+			llog.Debug("evicting metrics reporter from cache")
+			v.value.cleanupAllMetricsInstances()
+			go func() {
+				// shutting down also flushes the pending metrics
+				if err := v.value.provider.Shutdown(ctx); err != nil {
+					llog.Warn("error shutting down evicted metrics provider", "error", err)
+				}
+			}()
+		}, mr.newMetricSet)
+
+	mr.exporter, err = InstantiateMetricsExporter(ctx, cfg.Metrics, log)
+	if err != nil {
+		log.Error("instantiating metrics exporter", "error", err)
+		return nil, err
+	}
+	mr.exporter, err = queueMetricsExporter(ctx, cfg.Metrics, signalTCPHealthMetrics, ctxInfo.Metrics, mr.exporter)
+	if err != nil {
+		return nil, err
+	}
+
+	return mr.Do, nil
+}
+
+func (me *tcpHealthMetricsExporter) newMetricSet(service *svc.ID) (*tcpHealthMetrics, error) {
+	log := me.log.With("service", service)
+	log.Debug("creating new Metrics exporter")
+	resources := resource.NewWithAttributes(semconv.SchemaURL, getAppResourceAttrs(me.hostID, service)...)
+	histograms := newBucketedHistograms(timeNow(), me.cfg.Metrics.TTL)
+	opts := []metric.Option{
+		metric.WithResource(resources),
+		metric.WithReader(metric.NewPeriodicReader(me.exporter,
+			metric.WithInterval(me.cfg.Metrics.Interval),
+			metric.WithProducer(histograms))),
+	}
+
+	m := tcpHealthMetrics{
+		ctx:      me.ctx,
+		provider: metric.NewMeterProvider(opts...),
+		rtt: histograms.histogram(attributes.TCPConnectionRTT.OTEL,
+			"Smoothed round-trip time of the TCP connections, sampled each time that data is sent",
+			tcphealth.BucketBounds()),
+		setupDuration: histograms.histogram(attributes.TCPConnectionSetupDuration.OTEL,
+			"Time between sending the SYN of a TCP connection and its establishment",
+			tcphealth.BucketBounds()),
+	}
+
+	meter := m.provider.Meter(reporterName)
+
+	if retransmits, err := meter.Int64Counter(
+		attributes.TCPConnectionRetransmits.OTEL,
+		metric2.WithDescription("Retransmitted TCP segments"),
+		metric2.WithUnit("{segment}"),
+	); err != nil {
+		log.Error("creating counter for "+attributes.TCPConnectionRetransmits.OTEL, "error", err)
+		return nil, err
+	} else {
+		m.retransmits = NewExpirer[*tcphealth.Status, metric2.Int64Counter, int64](
+			me.ctx, retransmits, me.attrRetransmits, timeNow, me.cfg.Metrics.TTL)
+	}
+
+	if resets, err := meter.Int64Counter(
+		attributes.TCPConnectionResets.OTEL,
+		metric2.WithDescription("TCP resets that have been sent or received"),
+		metric2.WithUnit("{reset}"),
+	); err != nil {
+		log.Error("creating counter for "+attributes.TCPConnectionResets.OTEL, "error", err)
+		return nil, err
+	} else {
+		m.resets = NewExpirer[*tcphealth.Status, metric2.Int64Counter, int64](
+			me.ctx, resets, me.attrResets, timeNow, me.cfg.Metrics.TTL)
+	}
+
+	if failures, err := meter.Int64Counter(
+		attributes.TCPConnectionFailures.OTEL,
+		metric2.WithDescription("TCP connection attempts that were refused or timed out"),
+		metric2.WithUnit("{connection}"),
+	); err != nil {
+		log.Error("creating counter for "+attributes.TCPConnectionFailures.OTEL, "error", err)
+		return nil, err
+	} else {
+		m.failures = NewExpirer[*tcphealth.Status, metric2.Int64Counter, int64](
+			me.ctx, failures, me.attrFailures, timeNow, me.cfg.Metrics.TTL)
+	}
+	return &m, nil
+}
+
+// Do reads all the TCP health status data points and create the metrics accordingly
+func (me *tcpHealthMetricsExporter) Do(in <-chan []*tcphealth.Status) {
+	for i := range in {
+		me.clock.Update()
+		for _, s := range i {
+			reporter, err := me.reporters.For(s.Service)
+			if err != nil {
+				me.log.Error("unexpected error creating OTEL resource. Ignoring metric",
+					"error", err, "service", s.Service)
+				continue
+			}
+			me.observeMetric(reporter, s)
+		}
+	}
+}
+
+func (me *tcpHealthMetricsExporter) observeMetric(reporter *tcpHealthMetrics, s *tcphealth.Status) {
+	me.addCounter(reporter.retransmits, s, s.RetransmitsDelta)
+	me.addCounter(reporter.resets, s, s.ResetsSentDelta, netIODirTx)
+	me.addCounter(reporter.resets, s, s.ResetsReceivedDelta, netIODirRcv)
+	me.addCounter(reporter.failures, s, s.FailuresRefusedDelta, tcpFailureRefusedAttr)
+	me.addCounter(reporter.failures, s, s.FailuresTimeoutDelta, tcpFailureTimeoutAttr)
+
+	now := me.clock.Time()
+	if s.RTTDelta.Count() > 0 {
+		reporter.rtt.add(attributeSet(s, me.attrRTT),
+			s.RTTDelta.Counts[:], s.RTTDelta.SumNs, now)
+	}
+	if s.SetupDurationDelta.Count() > 0 {
+		reporter.setupDuration.add(attributeSet(s, me.attrSetupDuration),
+			s.SetupDurationDelta.Counts[:], s.SetupDurationDelta.SumNs, now)
+	}
+}
+
+// addCounter skips the zero deltas, to avoid reporting a series for each kind of event
+// of each peer, even if they never happened
+func (me *tcpHealthMetricsExporter) addCounter(
+	counter *Expirer[*tcphealth.Status, metric2.Int64Counter, int64],
+	s *tcphealth.Status, delta uint64, extraAttrs ...attribute.KeyValue,
+) {
+	if delta == 0 {
+		return
+	}
+	c, attrs := counter.ForRecord(s, extraAttrs...)
+	c.Add(me.ctx, int64(delta), metric2.WithAttributeSet(attrs))
+}
+
+func (r *tcpHealthMetrics) cleanupAllMetricsInstances() {
+	r.retransmits.RemoveAllMetrics(r.ctx)
+	r.resets.RemoveAllMetrics(r.ctx)
+	r.failures.RemoveAllMetrics(r.ctx)
+}
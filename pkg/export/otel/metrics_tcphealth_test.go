@@ -0,0 +1,91 @@
+package otel
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mariomac/guara/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/export/instrumentations"
+	"github.com/grafana/beyla/pkg/internal/infraolly/tcphealth"
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
+	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/test/collector"
+)
+
+func TestTCPHealthMetrics(t *testing.T) {
+	os.Setenv("OTEL_METRIC_EXPORT_INTERVAL", "100")
+	defer restoreEnvAfterExecution()()
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+
+	otlp, err := collector.Start(ctx)
+	require.NoError(t, err)
+
+	// GIVEN an OTEL TCP health metrics exporter
+	otelExporter, err := TCPHealthMetricsExporterProvider(
+		ctx, &global.ContextInfo{}, &TCPHealthMetricsConfig{
+			Metrics: &MetricsConfig{
+				ReportersCacheLen: 100,
+				CommonEndpoint:    otlp.ServerEndpoint,
+				MetricsProtocol:   ProtocolHTTPProtobuf,
+				Features:          []string{FeatureApplication, FeatureTCPConnection},
+				TTL:               3 * time.Minute,
+				Instrumentations: []string{
+					instrumentations.InstrumentationALL,
+				},
+			},
+		})()
+	require.NoError(t, err)
+
+	statuses := make(chan []*tcphealth.Status, 20)
+	go otelExporter(statuses)
+
+	// WHEN it receives the TCP health status of a service
+	statuses <- []*tcphealth.Status{{
+		Service:              &svc.ID{UID: "cart", Name: "cart", Namespace: "shop"},
+		Role:                 tcphealth.RoleClient,
+		PeerAddress:          "10.0.0.5",
+		ServerPort:           5432,
+		RetransmitsDelta:     3,
+		ResetsSentDelta:      1,
+		ResetsReceivedDelta:  2,
+		FailuresTimeoutDelta: 4,
+		SetupDurationDelta:   tcphealth.Histogram{Counts: [tcphealth.NumBuckets]uint64{2, 1}, SumNs: 400_000},
+		RTTDelta:             tcphealth.Histogram{Counts: [tcphealth.NumBuckets]uint64{0, 0, 4, 0, 0, 0, 0, 0, 0, 1}, SumNs: 2_002_000_000},
+	}}
+
+	// THEN the counters and histograms are exported for each kind of event that happened
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		records := map[string]collector.MetricRecord{}
+		for len(records) < 6 {
+			metric := readChan(t, otlp.Records(), timeout)
+			records[metric.Name+metric.Attributes["network.io.direction"]+metric.Attributes["error.type"]] = metric
+		}
+		for _, metric := range records {
+			assert.Equal(t, "cart", metric.ResourceAttributes["service.name"])
+			assert.Equal(t, "shop", metric.ResourceAttributes["service.namespace"])
+			assert.Equal(t, "client", metric.Attributes["tcp.role"])
+			assert.Equal(t, "10.0.0.5", metric.Attributes["network.peer.address"])
+			assert.Equal(t, "5432", metric.Attributes["server.port"])
+		}
+		assert.EqualValues(t, 3, records["tcp.connection.retransmits"].IntVal)
+		assert.EqualValues(t, 1, records["tcp.connection.resetstransmit"].IntVal)
+		assert.EqualValues(t, 2, records["tcp.connection.resetsreceive"].IntVal)
+		assert.EqualValues(t, 4, records["tcp.connection.failurestimeout"].IntVal)
+		assert.NotContains(t, records, "tcp.connection.failuresrefused")
+
+		setup := records["tcp.connection.setup.duration"]
+		assert.Equal(t, "s", setup.Unit)
+		assert.Equal(t, 3, setup.Count)
+		assert.InDelta(t, 0.0004, setup.FloatVal, 1e-12)
+
+		rtt := records["tcp.connection.rtt"]
+		assert.Equal(t, 5, rtt.Count)
+		assert.InDelta(t, 2.002, rtt.FloatVal, 1e-12)
+	})
+}
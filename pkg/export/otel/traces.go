@@ -82,6 +82,9 @@ type TracesConfig struct {
 
 	ReportersCacheLen int `yaml:"reporters_cache_len" env:"BEYLA_TRACES_REPORT_CACHE_LEN"`
 
+	// DiskQueue stores the traces that can't be exported while the OTLP endpoint is unavailable
+	DiskQueue DiskQueueConfig `yaml:"disk_queue" envPrefix:"BEYLA_OTEL_TRACES_DISK_QUEUE_"`
+
 	// SDKLogLevel works independently from the global LogLevel because it prints GBs of logs in Debug mode
 	// and the Info messages leak internal details that are not usually valuable for the final user.
 	SDKLogLevel string `yaml:"otel_sdk_log_level" env:"BEYLA_OTEL_SDK_LOG_LEVEL"`
@@ -138,12 +141,27 @@ func TracesReceiver(ctx context.Context, cfg TracesConfig, ctxInfo *global.Conte
 	return makeTracesReceiver(ctx, cfg, ctxInfo, userAttribSelection).provideLoop
 }
 
+// ReloadableTracesReceiver works as TracesReceiver, but it also replaces the user-provided attribute selection
+// each time a new attributes.Selection is received from the attrUpdates channel.
+func ReloadableTracesReceiver(
+	ctx context.Context,
+	cfg TracesConfig,
+	ctxInfo *global.ContextInfo,
+	userAttribSelection attributes.Selection,
+	attrUpdates <-chan attributes.Selection,
+) pipe.FinalProvider[[]request.Span] {
+	tr := makeTracesReceiver(ctx, cfg, ctxInfo, userAttribSelection)
+	tr.attrUpdates = attrUpdates
+	return tr.provideLoop
+}
+
 type tracesOTELReceiver struct {
-	ctx        context.Context
-	cfg        TracesConfig
-	ctxInfo    *global.ContextInfo
-	attributes attributes.Selection
-	is         instrumentations.InstrumentationSelection
+	ctx         context.Context
+	cfg         TracesConfig
+	ctxInfo     *global.ContextInfo
+	attributes  attributes.Selection
+	attrUpdates <-chan attributes.Selection
+	is          instrumentations.InstrumentationSelection
 }
 
 func GetUserSelectedAttributes(attrs attributes.Selection) (map[attr.Name]struct{}, error) {
@@ -190,22 +208,74 @@ func (tr *tracesOTELReceiver) provideLoop() (pipe.FinalFunc[[]request.Span], err
 			return
 		}
 
-		for spans := range in {
-			for i := range spans {
-				span := &spans[i]
-				if span.IgnoreSpan == request.IgnoreTraces || !tr.acceptSpan(span) {
+		export := exp.ConsumeTraces
+		if tr.cfg.DiskQueue.Enabled {
+			queue, err := newTracesExportQueue(&tr.cfg.DiskQueue, tr.ctxInfo.Metrics, exp.ConsumeTraces)
+			if err != nil {
+				slog.Error("error creating traces disk queue", "error", err)
+				return
+			}
+			ctx, cancel := context.WithCancel(tr.ctx)
+			defer cancel()
+			go queue.replayLoop(ctx)
+			export = queue.export
+		}
+
+		for {
+			select {
+			case selection := <-tr.attrUpdates:
+				newAttrs, err := GetUserSelectedAttributes(selection)
+				if err != nil {
+					slog.Error("can't reload user trace attributes. Keeping the previous ones", "error", err)
 					continue
 				}
-				traces := GenerateTraces(span, tr.ctxInfo.HostID, traceAttrs)
-				err := exp.ConsumeTraces(tr.ctx, traces)
-				if err != nil {
-					slog.Error("error sending trace to consumer", "error", err)
+				traceAttrs = newAttrs
+			case spans, ok := <-in:
+				if !ok {
+					return
+				}
+				if tr.cfg.DiskQueue.Enabled {
+					// all the spans of the batch are sent together, so the disk queue
+					// stores them in a single record
+					traces := tr.batchTraces(spans, traceAttrs)
+					if traces.ResourceSpans().Len() == 0 {
+						continue
+					}
+					if err := export(tr.ctx, traces); err != nil {
+						slog.Error("error sending trace to consumer", "error", err)
+					}
+					continue
+				}
+				for i := range spans {
+					span := &spans[i]
+					if span.IgnoreSpan == request.IgnoreTraces || !tr.acceptSpan(span) {
+						continue
+					}
+					traces := GenerateTraces(span, tr.ctxInfo.HostID, traceAttrs)
+					err := export(tr.ctx, traces)
+					if err != nil {
+						slog.Error("error sending trace to consumer", "error", err)
+					}
 				}
 			}
 		}
 	}, nil
 }
 
+// batchTraces merges the accepted spans of a batch into a single ptrace.Traces
+func (tr *tracesOTELReceiver) batchTraces(spans []request.Span, traceAttrs map[attr.Name]struct{}) ptrace.Traces {
+	traces := ptrace.NewTraces()
+	for i := range spans {
+		span := &spans[i]
+		if span.IgnoreSpan == request.IgnoreTraces || !tr.acceptSpan(span) {
+			continue
+		}
+		GenerateTraces(span, tr.ctxInfo.HostID, traceAttrs).ResourceSpans().
+			MoveAndAppendTo(traces.ResourceSpans())
+	}
+	return traces
+}
+
 func getTracesExporter(ctx context.Context, cfg TracesConfig, ctxInfo *global.ContextInfo) (exporter.Traces, error) {
 	switch proto := cfg.getProtocol(); proto {
 	case ProtocolHTTPJSON, ProtocolHTTPProtobuf, "": // zero value defaults to HTTP for backwards-compatibility
@@ -407,6 +477,9 @@ func GenerateTraces(span *request.Span, hostID string, userAttrs map[attr.Name]s
 	// Set status code
 	statusCode := codeToStatusCode(request.SpanStatusCode(span))
 	s.Status().SetCode(statusCode)
+	if statusCode == ptrace.StatusCodeError && span.DBError.Description != "" {
+		s.Status().SetMessage(span.DBError.Description)
+	}
 	s.SetEndTimestamp(pcommon.NewTimestampFromTime(t.End))
 	return traces
 }
@@ -503,8 +576,12 @@ func (tr *tracesOTELReceiver) acceptSpan(span *request.Span) bool {
 		return tr.is.SQLEnabled()
 	case request.EventTypeRedisClient, request.EventTypeRedisServer:
 		return tr.is.RedisEnabled()
+	case request.EventTypeMongoClient:
+		return tr.is.MongoEnabled()
 	case request.EventTypeKafkaClient, request.EventTypeKafkaServer:
 		return tr.is.KafkaEnabled()
+	case request.EventTypeDNSClient:
+		return tr.is.DNSEnabled()
 	}
 
 	return false
@@ -558,7 +635,10 @@ func traceAttributes(span *request.Span, optionalAttrs map[attr.Name]struct{}) [
 		attrs = []attribute.KeyValue{
 			request.ServerAddr(request.SpanHost(span)),
 			request.ServerPort(span.HostPort),
-			semconv.DBSystemOtherSQL, // We can distinguish in the future for MySQL, Postgres etc
+			semconv.DBSystemKey.String(request.DBSystemName(span)),
+		}
+		if span.DBError.ErrorCode != "" {
+			attrs = append(attrs, request.ErrorType(span.DBError.ErrorCode))
 		}
 		if _, ok := optionalAttrs[attr.DBQueryText]; ok {
 			attrs = append(attrs, request.DBQueryText(span.Statement))
@@ -587,6 +667,28 @@ func traceAttributes(span *request.Span, optionalAttrs map[attr.Name]struct{}) [
 				}
 			}
 		}
+		if span.Redis != nil {
+			if span.Redis.KeyPattern != "" {
+				attrs = append(attrs, request.DBRedisKeyPattern(span.Redis.KeyPattern))
+			}
+			if span.Redis.CacheResult != "" {
+				attrs = append(attrs, request.DBRedisCacheResult(span.Redis.CacheResult))
+			}
+		}
+	case request.EventTypeMongoClient:
+		attrs = []attribute.KeyValue{
+			request.ServerAddr(request.SpanHost(span)),
+			request.ServerPort(span.HostPort),
+			semconv.DBSystemMongoDB,
+		}
+		operation := span.Method
+		if operation != "" {
+			attrs = append(attrs, request.DBOperationName(operation))
+			collection := span.Path
+			if collection != "" {
+				attrs = append(attrs, request.DBCollectionName(collection))
+			}
+		}
 	case request.EventTypeKafkaServer, request.EventTypeKafkaClient:
 		operation := request.MessagingOperationType(span.Method)
 		attrs = []attribute.KeyValue{
@@ -597,6 +699,20 @@ func traceAttributes(span *request.Span, optionalAttrs map[attr.Name]struct{}) [
 			semconv.MessagingClientID(span.OtherNamespace),
 			operation,
 		}
+	case request.EventTypeDNSClient:
+		attrs = []attribute.KeyValue{
+			request.ServerAddr(request.SpanHost(span)),
+			request.ServerPort(span.HostPort),
+			request.DNSQuestionName(span.Path),
+			request.DNSQuestionType(span.Method),
+			request.DNSResponseCode(request.DNSResponseCodeName(span.Status)),
+		}
+		if span.Status != request.DNSResponseNoError {
+			attrs = append(attrs, request.ErrorType(request.DNSResponseCodeName(span.Status)))
+		}
+		if span.DNS != nil && len(span.DNS.Addresses) > 0 {
+			attrs = append(attrs, request.DNSAnswers(span.DNS.Addresses))
+		}
 	}
 
 	return attrs
@@ -606,7 +722,8 @@ func spanKind(span *request.Span) trace2.SpanKind {
 	switch span.Type {
 	case request.EventTypeHTTP, request.EventTypeGRPC, request.EventTypeRedisServer:
 		return trace2.SpanKindServer
-	case request.EventTypeHTTPClient, request.EventTypeGRPCClient, request.EventTypeSQLClient, request.EventTypeRedisClient:
+	case request.EventTypeHTTPClient, request.EventTypeGRPCClient, request.EventTypeSQLClient, request.EventTypeRedisClient,
+		request.EventTypeMongoClient, request.EventTypeDNSClient:
 		return trace2.SpanKindClient
 	case request.EventTypeKafkaClient, request.EventTypeKafkaServer:
 		switch span.Method {
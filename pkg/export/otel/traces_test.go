@@ -943,6 +943,11 @@ func TestTracesInstrumentations(t *testing.T) {
 				}
 				assert.True(t, found, tt.name+":"+tt.expected[i])
 			}
+
+			// when the disk queue is enabled, the same spans are exported in a single batch
+			traceAttrs, err := GetUserSelectedAttributes(tr.attributes)
+			require.NoError(t, err)
+			assert.Equal(t, len(tt.expected), tr.batchTraces(spans, traceAttrs).ResourceSpans().Len(), tt.name)
 		})
 	}
 }
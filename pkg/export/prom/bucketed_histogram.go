@@ -0,0 +1,69 @@
+package prom
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// newBucketedHistogramVec returns a MetricVec of histograms whose observations have been already
+// bucketed by the eBPF probes, so they are accumulated as they are received instead of observing
+// each individual value. The bounds are provided in seconds, without the +Inf bucket.
+func newBucketedHistogramVec(bounds []float64, desc *prometheus.Desc) *prometheus.MetricVec {
+	return prometheus.NewMetricVec(desc, func(lvs ...string) prometheus.Metric {
+		return &bucketedHistogram{
+			desc:        desc,
+			bounds:      bounds,
+			labelValues: lvs,
+			counts:      make([]uint64, len(bounds)+1),
+		}
+	})
+}
+
+type bucketedHistogram struct {
+	desc        *prometheus.Desc
+	bounds      []float64
+	labelValues []string
+
+	mt sync.Mutex
+	// counts of each bucket, including the +Inf bucket. They are not cumulative.
+	counts []uint64
+	sumNs  uint64
+}
+
+// add the non-cumulative counts of each bucket, and the sum of the observations in nanoseconds
+func (h *bucketedHistogram) add(counts []uint64, sumNs uint64) {
+	h.mt.Lock()
+	for i := range h.counts {
+		h.counts[i] += counts[i]
+	}
+	h.sumNs += sumNs
+	h.mt.Unlock()
+}
+
+// Desc implements prometheus.Metric
+func (h *bucketedHistogram) Desc() *prometheus.Desc {
+	return h.desc
+}
+
+// Write implements prometheus.Metric
+func (h *bucketedHistogram) Write(out *dto.Metric) error {
+	h.mt.Lock()
+	// Prometheus buckets are cumulative and don't include the +Inf bucket
+	buckets := make(map[float64]uint64, len(h.bounds))
+	cumulative := uint64(0)
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		buckets[bound] = cumulative
+	}
+	count := cumulative + h.counts[len(h.bounds)]
+	sum := float64(h.sumNs) / 1e9
+	h.mt.Unlock()
+
+	m, err := prometheus.NewConstHistogram(h.desc, count, sum, buckets, h.labelValues...)
+	if err != nil {
+		return err
+	}
+	return m.Write(out)
+}
@@ -2,11 +2,14 @@ package prom
 
 import (
 	"log/slog"
+	"slices"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
 	"github.com/grafana/beyla/pkg/export/expire"
+	"github.com/grafana/beyla/pkg/internal/svc"
 )
 
 func plog() *slog.Logger {
@@ -17,6 +20,9 @@ func plog() *slog.Logger {
 type Expirer[T prometheus.Metric] struct {
 	entries *expire.ExpiryMap[*MetricEntry[T]]
 	wrapped *prometheus.MetricVec
+	// overflowLabels are the label values of the overflow series, or nil if the
+	// number of series is not limited
+	overflowLabels []string
 }
 
 type MetricEntry[T prometheus.Metric] struct {
@@ -33,26 +39,70 @@ func NewExpirer[T prometheus.Metric](wrapped *prometheus.MetricVec, clock func()
 	}
 }
 
+// NewLimitedExpirer works as NewExpirer, but limiting the number of series that each service can create
+// through the ForService method. If the limit is not nil, the wrapped MetricVec must have been created with the
+// label names returned by the limitedLabelNames function.
+func NewLimitedExpirer[T prometheus.Metric](wrapped *prometheus.MetricVec, clock func() time.Time, expireTime time.Duration, limit *expire.SeriesLimit) *Expirer[T] {
+	if limit == nil {
+		return NewExpirer[T](wrapped, clock, expireTime)
+	}
+	// all the labels of the overflow series are empty, except the overflow label.
+	overflowLabels := make([]string, len(limit.LabelNames)+1)
+	overflowLabels[len(limit.LabelNames)] = "true"
+	return &Expirer[T]{
+		wrapped:        wrapped,
+		entries:        expire.NewLimitedExpiryMap[*MetricEntry[T]](clock, expireTime, limit),
+		overflowLabels: overflowLabels,
+	}
+}
+
+// limitedLabelNames returns the label names of a metric whose number of series might be limited
+func limitedLabelNames(limit *expire.SeriesLimit, names []string) []string {
+	if limit == nil {
+		return names
+	}
+	return append(slices.Clip(names), attr.OTELMetricOverflow.Prom())
+}
+
+// ForService works as WithLabelValues, but accounting the new label sets for the given service.
+// If the service reached its limit of series, the overflow series is returned.
+func (ex *Expirer[T]) ForService(service *svc.ID, lbls ...string) *MetricEntry[T] {
+	if ex.overflowLabels == nil {
+		return ex.WithLabelValues(lbls...)
+	}
+	// the overflow label is empty in the normal series, so Prometheus does not report it
+	lbls = append(slices.Clip(lbls), "")
+	return ex.entries.GetOrCreateLimited(service, lbls, func() *MetricEntry[T] {
+		return ex.newEntry(lbls)
+	}, func() *MetricEntry[T] {
+		return ex.newEntry(ex.overflowLabels)
+	})
+}
+
 // WithLabelValues returns the Counter for the given slice of label
 // values (same order as the variable labels in Desc). If that combination of
 // label values is accessed for the first time, a new Counter is created.
 // If not, a cached copy is returned and the "last access" cache time is updated.
 func (ex *Expirer[T]) WithLabelValues(lbls ...string) *MetricEntry[T] {
 	return ex.entries.GetOrCreate(lbls, func() *MetricEntry[T] {
-		plog().With("labelValues", lbls).Debug("storing new metric label set")
-		c, err := ex.wrapped.GetMetricWithLabelValues(lbls...)
-		// same behavior as specific WithLabelValues implementations
-		// no need to return the error
-		if err != nil {
-			panic(err)
-		}
-		return &MetricEntry[T]{
-			metric:    c.(T),
-			labelVals: lbls,
-		}
+		return ex.newEntry(lbls)
 	})
 }
 
+func (ex *Expirer[T]) newEntry(lbls []string) *MetricEntry[T] {
+	plog().With("labelValues", lbls).Debug("storing new metric label set")
+	c, err := ex.wrapped.GetMetricWithLabelValues(lbls...)
+	// same behavior as specific WithLabelValues implementations
+	// no need to return the error
+	if err != nil {
+		panic(err)
+	}
+	return &MetricEntry[T]{
+		metric:    c.(T),
+		labelVals: lbls,
+	}
+}
+
 // Describe wraps prometheus.Collector Describe method
 func (ex *Expirer[T]) Describe(descs chan<- *prometheus.Desc) {
 	ex.wrapped.Describe(descs)
@@ -18,8 +18,10 @@ import (
 	attr "github.com/grafana/beyla/pkg/export/attributes/names"
 	"github.com/grafana/beyla/pkg/export/expire"
 	"github.com/grafana/beyla/pkg/export/instrumentations"
+	"github.com/grafana/beyla/pkg/export/msggraph"
 	"github.com/grafana/beyla/pkg/export/otel"
 	"github.com/grafana/beyla/pkg/internal/connector"
+	"github.com/grafana/beyla/pkg/internal/imetrics"
 	"github.com/grafana/beyla/pkg/internal/pipe/global"
 	"github.com/grafana/beyla/pkg/internal/request"
 	"github.com/grafana/beyla/pkg/internal/svc"
@@ -42,6 +44,9 @@ const (
 	ServiceGraphFailed = "traces_service_graph_request_failed_total"
 	ServiceGraphTotal  = "traces_service_graph_request_total"
 
+	// ServiceGraphMessaging measures the time between the publication of a message and its processing
+	ServiceGraphMessaging = "traces_service_graph_request_messaging_system_seconds"
+
 	serviceKey          = "service"
 	serviceNamespaceKey = "service_namespace"
 
@@ -67,6 +72,11 @@ const (
 	sourceKey            = "source"
 	telemetryLanguageKey = "telemetry_sdk_language"
 	telemetrySDKKey      = "telemetry_sdk_name"
+	runtimeNameKey       = "process_runtime_name"
+	runtimeVersionKey    = "process_runtime_version"
+	serviceVersionKey    = "service_version"
+	vcsRevisionKey       = "vcs_revision"
+	goModulePathKey      = "go_module_path"
 
 	clientKey          = "client"
 	clientNamespaceKey = "client_service_namespace"
@@ -115,6 +125,13 @@ type PrometheusConfig struct {
 	TTL                         time.Duration `yaml:"ttl" env:"BEYLA_PROMETHEUS_TTL"`
 	SpanMetricsServiceCacheSize int           `yaml:"service_cache_size"`
 
+	// CardinalityLimits caps the number of series of the application metrics, for each service
+	CardinalityLimits expire.CardinalityLimits `yaml:"cardinality_limits" envPrefix:"BEYLA_PROMETHEUS_CARDINALITY_"`
+
+	// RemoteWrite pushes the metrics to a remote-write endpoint, in addition to (or instead of)
+	// exposing them through the scrape endpoint
+	RemoteWrite RemoteWriteConfig `yaml:"remote_write" envPrefix:"BEYLA_PROMETHEUS_REMOTE_WRITE_"`
+
 	// Registry is only used for embedding Beyla within the Grafana Agent.
 	// It must be nil when Beyla runs as standalone
 	Registry *prometheus.Registry `yaml:"-"`
@@ -129,15 +146,31 @@ func (p *PrometheusConfig) OTelMetricsEnabled() bool {
 }
 
 func (p *PrometheusConfig) ServiceGraphMetricsEnabled() bool {
-	return slices.Contains(p.Features, otel.FeatureGraph)
+	return slices.Contains(p.Features, otel.FeatureGraph) || p.ServiceGraphMessagingEnabled()
+}
+
+func (p *PrometheusConfig) ServiceGraphMessagingEnabled() bool {
+	return slices.Contains(p.Features, otel.FeatureGraphMessaging)
 }
 
 func (p *PrometheusConfig) NetworkMetricsEnabled() bool {
 	return slices.Contains(p.Features, otel.FeatureNetwork)
 }
 
+func (p *PrometheusConfig) GoRuntimeMetricsEnabled() bool {
+	return slices.Contains(p.Features, otel.FeatureGoRuntime)
+}
+
+func (p *PrometheusConfig) GoSchedulerMetricsEnabled() bool {
+	return slices.Contains(p.Features, otel.FeatureGoScheduler)
+}
+
+func (p *PrometheusConfig) TCPConnectionMetricsEnabled() bool {
+	return slices.Contains(p.Features, otel.FeatureTCPConnection)
+}
+
 func (p *PrometheusConfig) EndpointEnabled() bool {
-	return p.Port != 0 || p.Registry != nil
+	return p.Port != 0 || p.Registry != nil || p.RemoteWrite.Enabled()
 }
 
 // nolint:gocritic
@@ -158,6 +191,13 @@ type metricsReporter struct {
 	msgProcessDuration    *Expirer[prometheus.Histogram]
 	httpRequestSize       *Expirer[prometheus.Histogram]
 	httpClientRequestSize *Expirer[prometheus.Histogram]
+	kafkaMessages         *Expirer[prometheus.Counter]
+	kafkaMessagesSize     *Expirer[prometheus.Counter]
+	kafkaOffset           *Expirer[prometheus.Gauge]
+	kafkaConsumerLag      *Expirer[prometheus.Gauge]
+	dnsLookupDuration     *Expirer[prometheus.Histogram]
+	redisCacheLookups     *Expirer[prometheus.Counter]
+	redisPipelineSize     *Expirer[prometheus.Histogram]
 	targetInfo            *Expirer[prometheus.Gauge]
 
 	// user-selected attributes for the application-level metrics
@@ -170,6 +210,13 @@ type metricsReporter struct {
 	attrMsgProcessDuration    []attributes.Field[*request.Span, string]
 	attrHTTPRequestSize       []attributes.Field[*request.Span, string]
 	attrHTTPClientRequestSize []attributes.Field[*request.Span, string]
+	attrKafkaMessages         []attributes.Field[*request.Span, string]
+	attrKafkaMessagesSize     []attributes.Field[*request.Span, string]
+	attrKafkaOffset           []attributes.Field[*request.Span, string]
+	attrKafkaConsumerLag      []attributes.Field[*request.Span, string]
+	attrDNSLookupDuration     []attributes.Field[*request.Span, string]
+	attrRedisCacheLookups     []attributes.Field[*request.Span, string]
+	attrRedisPipelineSize     []attributes.Field[*request.Span, string]
 
 	// trace span metrics
 	spanMetricsLatency    *Expirer[prometheus.Histogram]
@@ -183,6 +230,10 @@ type metricsReporter struct {
 	serviceGraphFailed *Expirer[prometheus.Counter]
 	serviceGraphTotal  *Expirer[prometheus.Counter]
 
+	// messaging service graph
+	serviceGraphMessaging *Expirer[prometheus.Histogram]
+	msgJoiner             *msggraph.Joiner
+
 	promConnect *connector.PrometheusManager
 
 	clock   *expire.CachedClock
@@ -264,13 +315,38 @@ func newReporter(
 			attrsProvider.For(attributes.DBClientDuration))
 	}
 
+	var attrRedisCacheLookups, attrRedisPipelineSize []attributes.Field[*request.Span, string]
+
+	if is.RedisEnabled() {
+		attrRedisCacheLookups = attributes.PrometheusGetters(request.SpanPromGetters,
+			attrsProvider.For(attributes.RedisCacheLookups))
+		attrRedisPipelineSize = attributes.PrometheusGetters(request.SpanPromGetters,
+			attrsProvider.For(attributes.RedisPipelineSize))
+	}
+
 	var attrMessagingProcessDuration, attrMessagingPublishDuration []attributes.Field[*request.Span, string]
+	var attrKafkaMessages, attrKafkaMessagesSize, attrKafkaOffset, attrKafkaConsumerLag []attributes.Field[*request.Span, string]
 
 	if is.MQEnabled() {
 		attrMessagingPublishDuration = attributes.PrometheusGetters(request.SpanPromGetters,
 			attrsProvider.For(attributes.MessagingPublishDuration))
 		attrMessagingProcessDuration = attributes.PrometheusGetters(request.SpanPromGetters,
 			attrsProvider.For(attributes.MessagingProcessDuration))
+		attrKafkaMessages = attributes.PrometheusGetters(request.SpanPromGetters,
+			attrsProvider.For(attributes.MessagingKafkaMessages))
+		attrKafkaMessagesSize = attributes.PrometheusGetters(request.SpanPromGetters,
+			attrsProvider.For(attributes.MessagingKafkaMessagesSize))
+		attrKafkaOffset = attributes.PrometheusGetters(request.SpanPromGetters,
+			attrsProvider.For(attributes.MessagingKafkaOffset))
+		attrKafkaConsumerLag = attributes.PrometheusGetters(request.SpanPromGetters,
+			attrsProvider.For(attributes.MessagingKafkaConsumerLag))
+	}
+
+	var attrDNSLookupDuration []attributes.Field[*request.Span, string]
+
+	if is.DNSEnabled() {
+		attrDNSLookupDuration = attributes.PrometheusGetters(request.SpanPromGetters,
+			attrsProvider.For(attributes.DNSLookupDuration))
 	}
 
 	clock := expire.NewCachedClock(timeNow)
@@ -295,6 +371,13 @@ func newReporter(
 		attrMsgProcessDuration:    attrMessagingProcessDuration,
 		attrHTTPRequestSize:       attrHTTPRequestSize,
 		attrHTTPClientRequestSize: attrHTTPClientRequestSize,
+		attrKafkaMessages:         attrKafkaMessages,
+		attrKafkaMessagesSize:     attrKafkaMessagesSize,
+		attrKafkaOffset:           attrKafkaOffset,
+		attrKafkaConsumerLag:      attrKafkaConsumerLag,
+		attrDNSLookupDuration:     attrDNSLookupDuration,
+		attrRedisCacheLookups:     attrRedisCacheLookups,
+		attrRedisPipelineSize:     attrRedisPipelineSize,
 		beylaInfo: NewExpirer[prometheus.Gauge](prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: BeylaBuildInfo,
 			Help: "A metric with a constant '1' value labeled by version, revision, branch, " +
@@ -309,94 +392,160 @@ func newReporter(
 			},
 		}, beylaInfoLabelNames).MetricVec, clock.Time, cfg.TTL),
 		httpDuration: optionalHistogramProvider(is.HTTPEnabled(), func() *Expirer[prometheus.Histogram] {
-			return NewExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			limit := seriesLimit(cfg, ctxInfo.Metrics, attributes.HTTPServerDuration, attrHTTPDuration)
+			return NewLimitedExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
 				Name:                            attributes.HTTPServerDuration.Prom,
 				Help:                            "duration of HTTP service calls from the server side, in seconds",
 				Buckets:                         cfg.Buckets.DurationHistogram,
 				NativeHistogramBucketFactor:     defaultHistogramBucketFactor,
 				NativeHistogramMaxBucketNumber:  defaultHistogramMaxBucketNumber,
 				NativeHistogramMinResetDuration: defaultHistogramMinResetDuration,
-			}, labelNames(attrHTTPDuration)).MetricVec, clock.Time, cfg.TTL)
+			}, limitedLabelNames(limit, labelNames(attrHTTPDuration))).MetricVec, clock.Time, cfg.TTL, limit)
 		}),
 		httpClientDuration: optionalHistogramProvider(is.HTTPEnabled(), func() *Expirer[prometheus.Histogram] {
-			return NewExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			limit := seriesLimit(cfg, ctxInfo.Metrics, attributes.HTTPClientDuration, attrHTTPClientDuration)
+			return NewLimitedExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
 				Name:                            attributes.HTTPClientDuration.Prom,
 				Help:                            "duration of HTTP service calls from the client side, in seconds",
 				Buckets:                         cfg.Buckets.DurationHistogram,
 				NativeHistogramBucketFactor:     defaultHistogramBucketFactor,
 				NativeHistogramMaxBucketNumber:  defaultHistogramMaxBucketNumber,
 				NativeHistogramMinResetDuration: defaultHistogramMinResetDuration,
-			}, labelNames(attrHTTPClientDuration)).MetricVec, clock.Time, cfg.TTL)
+			}, limitedLabelNames(limit, labelNames(attrHTTPClientDuration))).MetricVec, clock.Time, cfg.TTL, limit)
 		}),
 		grpcDuration: optionalHistogramProvider(is.GRPCEnabled(), func() *Expirer[prometheus.Histogram] {
-			return NewExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			limit := seriesLimit(cfg, ctxInfo.Metrics, attributes.RPCServerDuration, attrGRPCDuration)
+			return NewLimitedExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
 				Name:                            attributes.RPCServerDuration.Prom,
 				Help:                            "duration of RCP service calls from the server side, in seconds",
 				Buckets:                         cfg.Buckets.DurationHistogram,
 				NativeHistogramBucketFactor:     defaultHistogramBucketFactor,
 				NativeHistogramMaxBucketNumber:  defaultHistogramMaxBucketNumber,
 				NativeHistogramMinResetDuration: defaultHistogramMinResetDuration,
-			}, labelNames(attrGRPCDuration)).MetricVec, clock.Time, cfg.TTL)
+			}, limitedLabelNames(limit, labelNames(attrGRPCDuration))).MetricVec, clock.Time, cfg.TTL, limit)
 		}),
 		grpcClientDuration: optionalHistogramProvider(is.GRPCEnabled(), func() *Expirer[prometheus.Histogram] {
-			return NewExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			limit := seriesLimit(cfg, ctxInfo.Metrics, attributes.RPCClientDuration, attrGRPCClientDuration)
+			return NewLimitedExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
 				Name:                            attributes.RPCClientDuration.Prom,
 				Help:                            "duration of GRPC service calls from the client side, in seconds",
 				Buckets:                         cfg.Buckets.DurationHistogram,
 				NativeHistogramBucketFactor:     defaultHistogramBucketFactor,
 				NativeHistogramMaxBucketNumber:  defaultHistogramMaxBucketNumber,
 				NativeHistogramMinResetDuration: defaultHistogramMinResetDuration,
-			}, labelNames(attrGRPCClientDuration)).MetricVec, clock.Time, cfg.TTL)
+			}, limitedLabelNames(limit, labelNames(attrGRPCClientDuration))).MetricVec, clock.Time, cfg.TTL, limit)
 		}),
 		dbClientDuration: optionalHistogramProvider(is.DBEnabled(), func() *Expirer[prometheus.Histogram] {
-			return NewExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			limit := seriesLimit(cfg, ctxInfo.Metrics, attributes.DBClientDuration, attrDBClientDuration)
+			return NewLimitedExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
 				Name:                            attributes.DBClientDuration.Prom,
 				Help:                            "duration of db client operations, in seconds",
 				Buckets:                         cfg.Buckets.DurationHistogram,
 				NativeHistogramBucketFactor:     defaultHistogramBucketFactor,
 				NativeHistogramMaxBucketNumber:  defaultHistogramMaxBucketNumber,
 				NativeHistogramMinResetDuration: defaultHistogramMinResetDuration,
-			}, labelNames(attrDBClientDuration)).MetricVec, clock.Time, cfg.TTL)
+			}, limitedLabelNames(limit, labelNames(attrDBClientDuration))).MetricVec, clock.Time, cfg.TTL, limit)
+		}),
+		redisCacheLookups: optionalCounterProvider(is.RedisEnabled(), func() *Expirer[prometheus.Counter] {
+			limit := seriesLimit(cfg, ctxInfo.Metrics, attributes.RedisCacheLookups, attrRedisCacheLookups)
+			return NewLimitedExpirer[prometheus.Counter](prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: attributes.RedisCacheLookups.Prom,
+				Help: "number of Redis GET-family commands, by hit or miss result",
+			}, limitedLabelNames(limit, labelNames(attrRedisCacheLookups))).MetricVec, clock.Time, cfg.TTL, limit)
+		}),
+		redisPipelineSize: optionalHistogramProvider(is.RedisEnabled(), func() *Expirer[prometheus.Histogram] {
+			limit := seriesLimit(cfg, ctxInfo.Metrics, attributes.RedisPipelineSize, attrRedisPipelineSize)
+			return NewLimitedExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Name:                            attributes.RedisPipelineSize.Prom,
+				Help:                            "number of commands that are sent together in a Redis request",
+				Buckets:                         cfg.Buckets.RedisPipelineSizeHistogram,
+				NativeHistogramBucketFactor:     defaultHistogramBucketFactor,
+				NativeHistogramMaxBucketNumber:  defaultHistogramMaxBucketNumber,
+				NativeHistogramMinResetDuration: defaultHistogramMinResetDuration,
+			}, limitedLabelNames(limit, labelNames(attrRedisPipelineSize))).MetricVec, clock.Time, cfg.TTL, limit)
 		}),
 		msgPublishDuration: optionalHistogramProvider(is.MQEnabled(), func() *Expirer[prometheus.Histogram] {
-			return NewExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			limit := seriesLimit(cfg, ctxInfo.Metrics, attributes.MessagingPublishDuration, attrMessagingPublishDuration)
+			return NewLimitedExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
 				Name:                            attributes.MessagingPublishDuration.Prom,
 				Help:                            "duration of messaging client publish operations, in seconds",
 				Buckets:                         cfg.Buckets.DurationHistogram,
 				NativeHistogramBucketFactor:     defaultHistogramBucketFactor,
 				NativeHistogramMaxBucketNumber:  defaultHistogramMaxBucketNumber,
 				NativeHistogramMinResetDuration: defaultHistogramMinResetDuration,
-			}, labelNames(attrMessagingPublishDuration)).MetricVec, clock.Time, cfg.TTL)
+			}, limitedLabelNames(limit, labelNames(attrMessagingPublishDuration))).MetricVec, clock.Time, cfg.TTL, limit)
 		}),
 		msgProcessDuration: optionalHistogramProvider(is.MQEnabled(), func() *Expirer[prometheus.Histogram] {
-			return NewExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			limit := seriesLimit(cfg, ctxInfo.Metrics, attributes.MessagingProcessDuration, attrMessagingProcessDuration)
+			return NewLimitedExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
 				Name:                            attributes.MessagingProcessDuration.Prom,
 				Help:                            "duration of messaging client process operations, in seconds",
 				Buckets:                         cfg.Buckets.DurationHistogram,
 				NativeHistogramBucketFactor:     defaultHistogramBucketFactor,
 				NativeHistogramMaxBucketNumber:  defaultHistogramMaxBucketNumber,
 				NativeHistogramMinResetDuration: defaultHistogramMinResetDuration,
-			}, labelNames(attrMessagingProcessDuration)).MetricVec, clock.Time, cfg.TTL)
+			}, limitedLabelNames(limit, labelNames(attrMessagingProcessDuration))).MetricVec, clock.Time, cfg.TTL, limit)
 		}),
 		httpRequestSize: optionalHistogramProvider(is.HTTPEnabled(), func() *Expirer[prometheus.Histogram] {
-			return NewExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			limit := seriesLimit(cfg, ctxInfo.Metrics, attributes.HTTPServerRequestSize, attrHTTPRequestSize)
+			return NewLimitedExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
 				Name:                            attributes.HTTPServerRequestSize.Prom,
 				Help:                            "size, in bytes, of the HTTP request body as received at the server side",
 				Buckets:                         cfg.Buckets.RequestSizeHistogram,
 				NativeHistogramBucketFactor:     defaultHistogramBucketFactor,
 				NativeHistogramMaxBucketNumber:  defaultHistogramMaxBucketNumber,
 				NativeHistogramMinResetDuration: defaultHistogramMinResetDuration,
-			}, labelNames(attrHTTPRequestSize)).MetricVec, clock.Time, cfg.TTL)
+			}, limitedLabelNames(limit, labelNames(attrHTTPRequestSize))).MetricVec, clock.Time, cfg.TTL, limit)
 		}),
 		httpClientRequestSize: optionalHistogramProvider(is.HTTPEnabled(), func() *Expirer[prometheus.Histogram] {
-			return NewExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			limit := seriesLimit(cfg, ctxInfo.Metrics, attributes.HTTPClientRequestSize, attrHTTPClientRequestSize)
+			return NewLimitedExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
 				Name:                            attributes.HTTPClientRequestSize.Prom,
 				Help:                            "size, in bytes, of the HTTP request body as sent from the client side",
 				Buckets:                         cfg.Buckets.RequestSizeHistogram,
 				NativeHistogramBucketFactor:     defaultHistogramBucketFactor,
 				NativeHistogramMaxBucketNumber:  defaultHistogramMaxBucketNumber,
 				NativeHistogramMinResetDuration: defaultHistogramMinResetDuration,
-			}, labelNames(attrHTTPClientRequestSize)).MetricVec, clock.Time, cfg.TTL)
+			}, limitedLabelNames(limit, labelNames(attrHTTPClientRequestSize))).MetricVec, clock.Time, cfg.TTL, limit)
+		}),
+		kafkaMessages: optionalCounterProvider(is.MQEnabled(), func() *Expirer[prometheus.Counter] {
+			limit := seriesLimit(cfg, ctxInfo.Metrics, attributes.MessagingKafkaMessages, attrKafkaMessages)
+			return NewLimitedExpirer[prometheus.Counter](prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: attributes.MessagingKafkaMessages.Prom,
+				Help: "number of messages published to or fetched from a Kafka partition",
+			}, limitedLabelNames(limit, labelNames(attrKafkaMessages))).MetricVec, clock.Time, cfg.TTL, limit)
+		}),
+		kafkaMessagesSize: optionalCounterProvider(is.MQEnabled(), func() *Expirer[prometheus.Counter] {
+			limit := seriesLimit(cfg, ctxInfo.Metrics, attributes.MessagingKafkaMessagesSize, attrKafkaMessagesSize)
+			return NewLimitedExpirer[prometheus.Counter](prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: attributes.MessagingKafkaMessagesSize.Prom,
+				Help: "size, in bytes, of the record batches published to or fetched from a Kafka partition",
+			}, limitedLabelNames(limit, labelNames(attrKafkaMessagesSize))).MetricVec, clock.Time, cfg.TTL, limit)
+		}),
+		kafkaOffset: optionalGaugeProvider(is.MQEnabled(), func() *Expirer[prometheus.Gauge] {
+			limit := seriesLimit(cfg, ctxInfo.Metrics, attributes.MessagingKafkaOffset, attrKafkaOffset)
+			return NewLimitedExpirer[prometheus.Gauge](prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Name: attributes.MessagingKafkaOffset.Prom,
+				Help: "offset of the last message produced to or consumed from a Kafka partition",
+			}, limitedLabelNames(limit, labelNames(attrKafkaOffset))).MetricVec, clock.Time, cfg.TTL, limit)
+		}),
+		kafkaConsumerLag: optionalGaugeProvider(is.MQEnabled(), func() *Expirer[prometheus.Gauge] {
+			limit := seriesLimit(cfg, ctxInfo.Metrics, attributes.MessagingKafkaConsumerLag, attrKafkaConsumerLag)
+			return NewLimitedExpirer[prometheus.Gauge](prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Name: attributes.MessagingKafkaConsumerLag.Prom,
+				Help: "approximate number of messages pending to be consumed from a Kafka partition",
+			}, limitedLabelNames(limit, labelNames(attrKafkaConsumerLag))).MetricVec, clock.Time, cfg.TTL, limit)
+		}),
+		dnsLookupDuration: optionalHistogramProvider(is.DNSEnabled(), func() *Expirer[prometheus.Histogram] {
+			limit := seriesLimit(cfg, ctxInfo.Metrics, attributes.DNSLookupDuration, attrDNSLookupDuration)
+			return NewLimitedExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Name:                            attributes.DNSLookupDuration.Prom,
+				Help:                            "duration of DNS queries from the client side, in seconds",
+				Buckets:                         cfg.Buckets.DurationHistogram,
+				NativeHistogramBucketFactor:     defaultHistogramBucketFactor,
+				NativeHistogramMaxBucketNumber:  defaultHistogramMaxBucketNumber,
+				NativeHistogramMinResetDuration: defaultHistogramMinResetDuration,
+			}, limitedLabelNames(limit, labelNames(attrDNSLookupDuration))).MetricVec, clock.Time, cfg.TTL, limit)
 		}),
 		spanMetricsLatency: optionalHistogramProvider(cfg.SpanMetricsEnabled(), func() *Expirer[prometheus.Histogram] {
 			return NewExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
@@ -458,6 +607,16 @@ func newReporter(
 				Help: "number of service calls in trace service graph metrics format",
 			}, labelNamesServiceGraph()).MetricVec, clock.Time, cfg.TTL)
 		}),
+		serviceGraphMessaging: optionalHistogramProvider(cfg.ServiceGraphMessagingEnabled(), func() *Expirer[prometheus.Histogram] {
+			return NewExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Name:                            ServiceGraphMessaging,
+				Help:                            "time between the publication and the processing of messages, in seconds, in trace service graph metrics format",
+				Buckets:                         cfg.Buckets.DurationHistogram,
+				NativeHistogramBucketFactor:     defaultHistogramBucketFactor,
+				NativeHistogramMaxBucketNumber:  defaultHistogramMaxBucketNumber,
+				NativeHistogramMinResetDuration: defaultHistogramMinResetDuration,
+			}, labelNamesServiceGraph()).MetricVec, clock.Time, cfg.TTL)
+		}),
 		targetInfo: NewExpirer[prometheus.Gauge](prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Name: TargetInfo,
 			Help: "attributes associated to a given monitored entity",
@@ -500,10 +659,27 @@ func newReporter(
 			)
 		}
 
+		if is.RedisEnabled() {
+			registeredMetrics = append(registeredMetrics,
+				mr.redisCacheLookups,
+				mr.redisPipelineSize,
+			)
+		}
+
 		if is.MQEnabled() {
 			registeredMetrics = append(registeredMetrics,
 				mr.msgProcessDuration,
 				mr.msgPublishDuration,
+				mr.kafkaMessages,
+				mr.kafkaMessagesSize,
+				mr.kafkaOffset,
+				mr.kafkaConsumerLag,
+			)
+		}
+
+		if is.DNSEnabled() {
+			registeredMetrics = append(registeredMetrics,
+				mr.dnsLookupDuration,
 			)
 		}
 	}
@@ -526,6 +702,11 @@ func newReporter(
 		)
 	}
 
+	if cfg.ServiceGraphMessagingEnabled() {
+		mr.msgJoiner = msggraph.NewJoiner(clock.Time, cfg.TTL)
+		registeredMetrics = append(registeredMetrics, mr.serviceGraphMessaging)
+	}
+
 	if mr.cfg.Registry != nil {
 		mr.cfg.Registry.MustRegister(registeredMetrics...)
 	} else {
@@ -535,6 +716,18 @@ func newReporter(
 	return mr, nil
 }
 
+// seriesLimit returns the cardinality limit of an application metric, or nil if it is not limited
+func seriesLimit[T any](
+	cfg *PrometheusConfig, internal imetrics.Reporter, metric attributes.Name, getters []attributes.Field[T, string],
+) *expire.SeriesLimit {
+	if internal == nil {
+		internal = imetrics.NoopReporter{}
+	}
+	return cfg.CardinalityLimits.SeriesLimit(metric, labelNames(getters), func(service *svc.ID, label string) {
+		internal.MetricSeriesOverflow(metric.Prom, service.String(), label)
+	})
+}
+
 func optionalHistogramProvider(enable bool, provider func() *Expirer[prometheus.Histogram]) *Expirer[prometheus.Histogram] {
 	if !enable {
 		return nil
@@ -588,52 +781,44 @@ func (r *metricsReporter) observe(span *request.Span) {
 		switch span.Type {
 		case request.EventTypeHTTP:
 			if r.is.HTTPEnabled() {
-				r.httpDuration.WithLabelValues(
-					labelValues(span, r.attrHTTPDuration)...,
-				).metric.Observe(duration)
-				r.httpRequestSize.WithLabelValues(
-					labelValues(span, r.attrHTTPRequestSize)...,
-				).metric.Observe(float64(span.RequestLength()))
+				r.httpDuration.ForService(&span.ServiceID, labelValues(span, r.attrHTTPDuration)...).metric.Observe(duration)
+				r.httpRequestSize.ForService(&span.ServiceID, labelValues(span, r.attrHTTPRequestSize)...).metric.Observe(float64(span.RequestLength()))
 			}
 		case request.EventTypeHTTPClient:
 			if r.is.HTTPEnabled() {
-				r.httpClientDuration.WithLabelValues(
-					labelValues(span, r.attrHTTPClientDuration)...,
-				).metric.Observe(duration)
-				r.httpClientRequestSize.WithLabelValues(
-					labelValues(span, r.attrHTTPClientRequestSize)...,
-				).metric.Observe(float64(span.RequestLength()))
+				r.httpClientDuration.ForService(&span.ServiceID, labelValues(span, r.attrHTTPClientDuration)...).metric.Observe(duration)
+				r.httpClientRequestSize.ForService(&span.ServiceID, labelValues(span, r.attrHTTPClientRequestSize)...).metric.Observe(float64(span.RequestLength()))
 			}
 		case request.EventTypeGRPC:
 			if r.is.GRPCEnabled() {
-				r.grpcDuration.WithLabelValues(
-					labelValues(span, r.attrGRPCDuration)...,
-				).metric.Observe(duration)
+				r.grpcDuration.ForService(&span.ServiceID, labelValues(span, r.attrGRPCDuration)...).metric.Observe(duration)
 			}
 		case request.EventTypeGRPCClient:
 			if r.is.GRPCEnabled() {
-				r.grpcClientDuration.WithLabelValues(
-					labelValues(span, r.attrGRPCClientDuration)...,
-				).metric.Observe(duration)
+				r.grpcClientDuration.ForService(&span.ServiceID, labelValues(span, r.attrGRPCClientDuration)...).metric.Observe(duration)
 			}
-		case request.EventTypeRedisClient, request.EventTypeSQLClient, request.EventTypeRedisServer:
+		case request.EventTypeRedisClient, request.EventTypeSQLClient, request.EventTypeRedisServer, request.EventTypeMongoClient:
 			if r.is.DBEnabled() {
-				r.dbClientDuration.WithLabelValues(
-					labelValues(span, r.attrDBClientDuration)...,
-				).metric.Observe(duration)
+				r.dbClientDuration.ForService(&span.ServiceID, labelValues(span, r.attrDBClientDuration)...).metric.Observe(duration)
+			}
+			if r.is.RedisEnabled() && span.Redis != nil {
+				r.observeRedis(span)
 			}
 		case request.EventTypeKafkaClient, request.EventTypeKafkaServer:
 			if r.is.MQEnabled() {
 				switch span.Method {
 				case request.MessagingPublish:
-					r.msgPublishDuration.WithLabelValues(
-						labelValues(span, r.attrMsgPublishDuration)...,
-					).metric.Observe(duration)
+					r.msgPublishDuration.ForService(&span.ServiceID, labelValues(span, r.attrMsgPublishDuration)...).metric.Observe(duration)
 				case request.MessagingProcess:
-					r.msgProcessDuration.WithLabelValues(
-						labelValues(span, r.attrMsgProcessDuration)...,
-					).metric.Observe(duration)
+					r.msgProcessDuration.ForService(&span.ServiceID, labelValues(span, r.attrMsgProcessDuration)...).metric.Observe(duration)
 				}
+				if span.Messaging != nil {
+					r.observeKafkaPartition(span)
+				}
+			}
+		case request.EventTypeDNSClient:
+			if r.is.DNSEnabled() {
+				r.dnsLookupDuration.ForService(&span.ServiceID, labelValues(span, r.attrDNSLookupDuration)...).metric.Observe(duration)
 			}
 		}
 	}
@@ -652,7 +837,7 @@ func (r *metricsReporter) observe(span *request.Span) {
 
 	if r.cfg.ServiceGraphMetricsEnabled() {
 		lvg := r.labelValuesServiceGraph(span)
-		if span.IsClientSpan() {
+		if r.graphClientSide(span) {
 			r.serviceGraphClient.WithLabelValues(lvg...).metric.Observe(duration)
 		} else {
 			r.serviceGraphServer.WithLabelValues(lvg...).metric.Observe(duration)
@@ -661,6 +846,48 @@ func (r *metricsReporter) observe(span *request.Span) {
 		if request.SpanStatusCode(span) == codes.Error {
 			r.serviceGraphFailed.WithLabelValues(lvg...).metric.Add(1)
 		}
+		if r.msgJoiner != nil {
+			if latency, ok := r.msgJoiner.Join(span); ok {
+				r.serviceGraphMessaging.WithLabelValues(lvg...).metric.Observe(latency.Seconds())
+			}
+		}
+	}
+}
+
+// graphClientSide returns whether the span is reported as the client side of its service graph edge
+func (r *metricsReporter) graphClientSide(span *request.Span) bool {
+	if r.cfg.ServiceGraphMessagingEnabled() {
+		return msggraph.IsClientSide(span)
+	}
+	return span.IsClientSpan()
+}
+
+// observeRedis records the metrics that are only available when the Redis
+// commands could be parsed from the request
+func (r *metricsReporter) observeRedis(span *request.Span) {
+	if span.Redis.CacheResult != "" {
+		r.redisCacheLookups.ForService(&span.ServiceID, labelValues(span, r.attrRedisCacheLookups)...).metric.Add(1)
+	}
+	if span.Redis.PipelineSize > 0 {
+		r.redisPipelineSize.ForService(&span.ServiceID, labelValues(span, r.attrRedisPipelineSize)...).metric.Observe(float64(span.Redis.PipelineSize))
+	}
+}
+
+// observeKafkaPartition records the metrics that are only available when the
+// partition information could be parsed from the Kafka messages
+func (r *metricsReporter) observeKafkaPartition(span *request.Span) {
+	info := span.Messaging
+	if info.Messages > 0 {
+		r.kafkaMessages.ForService(&span.ServiceID, labelValues(span, r.attrKafkaMessages)...).metric.Add(float64(info.Messages))
+	}
+	if info.Bytes > 0 {
+		r.kafkaMessagesSize.ForService(&span.ServiceID, labelValues(span, r.attrKafkaMessagesSize)...).metric.Add(float64(info.Bytes))
+	}
+	if info.Offset >= 0 {
+		r.kafkaOffset.ForService(&span.ServiceID, labelValues(span, r.attrKafkaOffset)...).metric.Set(float64(info.Offset))
+	}
+	if lag, ok := info.ConsumerLag(); ok {
+		r.kafkaConsumerLag.ForService(&span.ServiceID, labelValues(span, r.attrKafkaConsumerLag)...).metric.Set(float64(lag))
 	}
 }
 
@@ -709,7 +936,8 @@ func (r *metricsReporter) labelValuesSpans(span *request.Span) []string {
 }
 
 func labelNamesTargetInfo(kubeEnabled bool) []string {
-	names := []string{hostIDKey, hostNameKey, serviceKey, serviceNamespaceKey, serviceInstanceKey, serviceJobKey, telemetryLanguageKey, telemetrySDKKey, sourceKey}
+	names := []string{hostIDKey, hostNameKey, serviceKey, serviceNamespaceKey, serviceInstanceKey, serviceJobKey, telemetryLanguageKey, telemetrySDKKey, sourceKey,
+		runtimeNameKey, runtimeVersionKey, serviceVersionKey, vcsRevisionKey, goModulePathKey}
 
 	if kubeEnabled {
 		names = appendK8sLabelNames(names)
@@ -733,6 +961,11 @@ func (r *metricsReporter) labelValuesTargetInfo(service svc.ID) []string {
 		service.SDKLanguage.String(),
 		"beyla",
 		"beyla",
+		service.RuntimeName,
+		service.RuntimeVersion,
+		service.Metadata[attr.ServiceVersion],
+		service.Metadata[attr.VCSRevision],
+		service.Metadata[attr.GoModulePath],
 	}
 
 	if r.kubeEnabled {
@@ -747,6 +980,18 @@ func labelNamesServiceGraph() []string {
 }
 
 func (r *metricsReporter) labelValuesServiceGraph(span *request.Span) []string {
+	if r.cfg.ServiceGraphMessagingEnabled() {
+		// producers and consumers are connected to the virtual node of their messaging destination
+		if edge, ok := msggraph.EdgeOf(span); ok {
+			return []string{
+				edge.Client,
+				edge.ClientNamespace,
+				edge.Server,
+				edge.ServerNamespace,
+				"beyla",
+			}
+		}
+	}
 	if span.IsClientSpan() {
 		return []string{
 			request.SpanPeer(span),
@@ -0,0 +1,164 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mariomac/pipes/pipe"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/beyla/pkg/export/attributes"
+	"github.com/grafana/beyla/pkg/export/expire"
+	"github.com/grafana/beyla/pkg/internal/connector"
+	"github.com/grafana/beyla/pkg/internal/infraolly/goruntime"
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
+)
+
+// GoRuntimePrometheusConfig for the Go runtime metrics just wraps the global prom.PrometheusConfig as provided by the user
+type GoRuntimePrometheusConfig struct {
+	Metrics            *PrometheusConfig
+	AttributeSelectors attributes.Selection
+}
+
+// nolint:gocritic
+func (p GoRuntimePrometheusConfig) Enabled() bool {
+	return p.Metrics != nil && p.Metrics.EndpointEnabled() && p.Metrics.OTelMetricsEnabled() &&
+		p.Metrics.GoRuntimeMetricsEnabled()
+}
+
+// GoRuntimePrometheusEndpoint provides a pipeline node that exports the Go runtime
+// status of the instrumented services as Prometheus metrics
+func GoRuntimePrometheusEndpoint(
+	ctx context.Context, ctxInfo *global.ContextInfo, cfg *GoRuntimePrometheusConfig,
+) pipe.FinalProvider[[]*goruntime.Status] {
+	return func() (pipe.FinalFunc[[]*goruntime.Status], error) {
+		if !cfg.Enabled() {
+			// This node is not going to be instantiated. Let the pipes library just ignore it.
+			return pipe.IgnoreFinal[[]*goruntime.Status](), nil
+		}
+		reporter, err := newGoRuntimeReporter(ctx, ctxInfo, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Metrics.Registry != nil {
+			return reporter.collectMetrics, nil
+		}
+		return reporter.reportMetrics, nil
+	}
+}
+
+type goRuntimeMetricsReporter struct {
+	promConnect *connector.PrometheusManager
+
+	clock *expire.CachedClock
+	bgCtx context.Context
+
+	goroutinesCreatedAttrs []attributes.Field[*goruntime.Status, string]
+	goroutinesCreated      *Expirer[prometheus.Counter]
+
+	goroutinesExitedAttrs []attributes.Field[*goruntime.Status, string]
+	goroutinesExited      *Expirer[prometheus.Counter]
+
+	gcCyclesAttrs []attributes.Field[*goruntime.Status, string]
+	gcCycles      *Expirer[prometheus.Counter]
+
+	stwPausesAttrs []attributes.Field[*goruntime.Status, string]
+	stwPauses      *Expirer[*bucketedHistogram]
+
+	// nil unless the application_go_scheduler feature is enabled
+	schedLatencyAttrs []attributes.Field[*goruntime.Status, string]
+	schedLatency      *Expirer[*bucketedHistogram]
+}
+
+func newGoRuntimeReporter(
+	ctx context.Context,
+	ctxInfo *global.ContextInfo,
+	cfg *GoRuntimePrometheusConfig,
+) (*goRuntimeMetricsReporter, error) {
+	group := ctxInfo.MetricAttributeGroups
+	// this property can't be set inside the ConfiguredGroups function, otherwise the
+	// OTEL exporter would report also some prometheus-exclusive attributes
+	group.Add(attributes.GroupPrometheus)
+
+	provider, err := attributes.NewAttrSelector(group, cfg.AttributeSelectors)
+	if err != nil {
+		return nil, fmt.Errorf("go runtime Prometheus exporter attributes enable: %w", err)
+	}
+
+	attrGoroutinesCreated := attributes.PrometheusGetters(goruntime.PromGetters, provider.For(attributes.GoRuntimeGoroutinesCreated))
+	attrGoroutinesExited := attributes.PrometheusGetters(goruntime.PromGetters, provider.For(attributes.GoRuntimeGoroutinesExited))
+	attrGCCycles := attributes.PrometheusGetters(goruntime.PromGetters, provider.For(attributes.GoRuntimeGCCycles))
+	attrSTWPauses := attributes.PrometheusGetters(goruntime.PromGetters, provider.For(attributes.GoRuntimeSTWPauseDuration))
+	attrSchedLatency := attributes.PrometheusGetters(goruntime.PromGetters, provider.For(attributes.GoRuntimeSchedLatency))
+
+	clock := expire.NewCachedClock(timeNow)
+	mr := &goRuntimeMetricsReporter{
+		bgCtx:                  ctx,
+		promConnect:            ctxInfo.Prometheus,
+		clock:                  clock,
+		goroutinesCreatedAttrs: attrGoroutinesCreated,
+		goroutinesCreated: NewExpirer[prometheus.Counter](prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: attributes.GoRuntimeGoroutinesCreated.Prom,
+			Help: "Goroutines that have been created since the process was instrumented",
+		}, labelNames(attrGoroutinesCreated)).MetricVec, clock.Time, cfg.Metrics.TTL),
+		goroutinesExitedAttrs: attrGoroutinesExited,
+		goroutinesExited: NewExpirer[prometheus.Counter](prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: attributes.GoRuntimeGoroutinesExited.Prom,
+			Help: "Goroutines that have exited since the process was instrumented",
+		}, labelNames(attrGoroutinesExited)).MetricVec, clock.Time, cfg.Metrics.TTL),
+		gcCyclesAttrs: attrGCCycles,
+		gcCycles: NewExpirer[prometheus.Counter](prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: attributes.GoRuntimeGCCycles.Prom,
+			Help: "Completed garbage collection cycles",
+		}, labelNames(attrGCCycles)).MetricVec, clock.Time, cfg.Metrics.TTL),
+		stwPausesAttrs: attrSTWPauses,
+		stwPauses: NewExpirer[*bucketedHistogram](newBucketedHistogramVec(goruntime.BucketBounds(), prometheus.NewDesc(
+			attributes.GoRuntimeSTWPauseDuration.Prom,
+			"Duration of the stop-the-world pauses of the Go runtime, caused by the garbage collector or other runtime operations",
+			labelNames(attrSTWPauses), nil,
+		)), clock.Time, cfg.Metrics.TTL),
+	}
+	collectors := []prometheus.Collector{mr.goroutinesCreated, mr.goroutinesExited, mr.gcCycles, mr.stwPauses}
+	if cfg.Metrics.GoSchedulerMetricsEnabled() {
+		mr.schedLatencyAttrs = attrSchedLatency
+		mr.schedLatency = NewExpirer[*bucketedHistogram](newBucketedHistogramVec(goruntime.BucketBounds(), prometheus.NewDesc(
+			attributes.GoRuntimeSchedLatency.Prom,
+			"Time that the goroutines spend in the scheduler run queues before running",
+			labelNames(attrSchedLatency), nil,
+		)), clock.Time, cfg.Metrics.TTL)
+		collectors = append(collectors, mr.schedLatency)
+	}
+
+	if cfg.Metrics.Registry != nil {
+		cfg.Metrics.Registry.MustRegister(collectors...)
+	} else {
+		mr.promConnect.Register(cfg.Metrics.Port, cfg.Metrics.Path, collectors...)
+	}
+	return mr, nil
+}
+
+func (r *goRuntimeMetricsReporter) reportMetrics(input <-chan []*goruntime.Status) {
+	go r.promConnect.StartHTTP(r.bgCtx)
+	r.collectMetrics(input)
+}
+
+func (r *goRuntimeMetricsReporter) collectMetrics(input <-chan []*goruntime.Status) {
+	for statuses := range input {
+		// clock needs to be updated to let the expirer
+		// remove the old metrics
+		r.clock.Update()
+		for _, st := range statuses {
+			r.observe(st)
+		}
+	}
+}
+
+func (r *goRuntimeMetricsReporter) observe(st *goruntime.Status) {
+	r.goroutinesCreated.WithLabelValues(labelValues(st, r.goroutinesCreatedAttrs)...).metric.Add(float64(st.GoroutinesCreatedDelta))
+	r.goroutinesExited.WithLabelValues(labelValues(st, r.goroutinesExitedAttrs)...).metric.Add(float64(st.GoroutinesExitedDelta))
+	r.gcCycles.WithLabelValues(labelValues(st, r.gcCyclesAttrs)...).metric.Add(float64(st.GCCyclesDelta))
+	r.stwPauses.WithLabelValues(labelValues(st, r.stwPausesAttrs)...).metric.add(st.STWPausesDelta.Counts[:], st.STWPausesDelta.SumNs)
+	if r.schedLatency != nil {
+		r.schedLatency.WithLabelValues(labelValues(st, r.schedLatencyAttrs)...).metric.add(st.SchedLatencyDelta.Counts[:], st.SchedLatencyDelta.SumNs)
+	}
+}
@@ -0,0 +1,131 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mariomac/guara/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/export/otel"
+	"github.com/grafana/beyla/pkg/internal/connector"
+	"github.com/grafana/beyla/pkg/internal/infraolly/goruntime"
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func TestGoRuntimePrometheusEndpoint(t *testing.T) {
+	now := syncedClock{now: time.Now()}
+	timeNow = now.Now
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+	openPort, err := test.FreeTCPPort()
+	require.NoError(t, err)
+	promURL := fmt.Sprintf("http://127.0.0.1:%d/metrics", openPort)
+
+	// GIVEN a Prometheus Go runtime metrics exporter
+	exporter, err := GoRuntimePrometheusEndpoint(
+		ctx, &global.ContextInfo{Prometheus: &connector.PrometheusManager{}},
+		&GoRuntimePrometheusConfig{Metrics: &PrometheusConfig{
+			Port:     openPort,
+			Path:     "/metrics",
+			TTL:      3 * time.Minute,
+			Features: []string{otel.FeatureApplication, otel.FeatureGoRuntime, otel.FeatureGoScheduler},
+		}},
+	)()
+	require.NoError(t, err)
+
+	statuses := make(chan []*goruntime.Status, 20)
+	go exporter(statuses)
+
+	service := &svc.ID{UID: "cart", Name: "cart", Namespace: "shop"}
+	// WHEN it receives the Go runtime status of a service
+	statuses <- []*goruntime.Status{{
+		Service:                service,
+		GoroutinesCreatedDelta: 12,
+		GoroutinesExitedDelta:  4,
+		GCCyclesDelta:          3,
+		STWPausesDelta:         goruntime.Histogram{Counts: [goruntime.NumBuckets]uint64{2, 1}, SumNs: 60_000},
+		SchedLatencyDelta:      goruntime.Histogram{Counts: [goruntime.NumBuckets]uint64{4, 0, 0, 0, 0, 0, 0, 0, 0, 1}, SumNs: 200_020_000},
+	}}
+
+	// THEN the goroutines, GC cycles and latency histograms are exported
+	labels := `service_name="cart",service_namespace="shop",target_instance=""`
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		exported := getMetrics(t, promURL)
+		assert.Contains(t, exported, `process_runtime_go_goroutines_created_total{`+labels+`} 12`)
+		assert.Contains(t, exported, `process_runtime_go_goroutines_exited_total{`+labels+`} 4`)
+		assert.Contains(t, exported, `process_runtime_go_gc_cycles_total{`+labels+`} 3`)
+		assert.Contains(t, exported, `process_runtime_go_stw_pause_duration_seconds_bucket{`+labels+`,le="1e-05"} 2`)
+		assert.Contains(t, exported, `process_runtime_go_stw_pause_duration_seconds_bucket{`+labels+`,le="5e-05"} 3`)
+		assert.Contains(t, exported, `process_runtime_go_stw_pause_duration_seconds_bucket{`+labels+`,le="+Inf"} 3`)
+		assert.Contains(t, exported, `process_runtime_go_stw_pause_duration_seconds_count{`+labels+`} 3`)
+		assert.Contains(t, exported, `process_runtime_go_stw_pause_duration_seconds_sum{`+labels+`} 6e-05`)
+		assert.Contains(t, exported, `process_runtime_go_sched_latency_seconds_bucket{`+labels+`,le="0.1"} 4`)
+		assert.Contains(t, exported, `process_runtime_go_sched_latency_seconds_bucket{`+labels+`,le="+Inf"} 5`)
+	})
+
+	// AND WHEN it receives the next deltas
+	statuses <- []*goruntime.Status{{
+		Service:                service,
+		GoroutinesCreatedDelta: 7,
+		GoroutinesExitedDelta:  9,
+		GCCyclesDelta:          2,
+		STWPausesDelta:         goruntime.Histogram{Counts: [goruntime.NumBuckets]uint64{1}, SumNs: 5_000},
+	}}
+
+	// THEN the counters and histograms are accumulated
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		exported := getMetrics(t, promURL)
+		assert.Contains(t, exported, `process_runtime_go_goroutines_created_total{`+labels+`} 19`)
+		assert.Contains(t, exported, `process_runtime_go_goroutines_exited_total{`+labels+`} 13`)
+		assert.Contains(t, exported, `process_runtime_go_gc_cycles_total{`+labels+`} 5`)
+		assert.Contains(t, exported, `process_runtime_go_stw_pause_duration_seconds_bucket{`+labels+`,le="1e-05"} 3`)
+		assert.Contains(t, exported, `process_runtime_go_stw_pause_duration_seconds_count{`+labels+`} 4`)
+		assert.Contains(t, exported, `process_runtime_go_stw_pause_duration_seconds_sum{`+labels+`} 6.5e-05`)
+	})
+}
+
+func TestGoRuntimePrometheusEndpoint_SchedulerDisabled(t *testing.T) {
+	now := syncedClock{now: time.Now()}
+	timeNow = now.Now
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+	openPort, err := test.FreeTCPPort()
+	require.NoError(t, err)
+	promURL := fmt.Sprintf("http://127.0.0.1:%d/metrics", openPort)
+
+	// GIVEN a Prometheus Go runtime metrics exporter without the application_go_scheduler feature
+	exporter, err := GoRuntimePrometheusEndpoint(
+		ctx, &global.ContextInfo{Prometheus: &connector.PrometheusManager{}},
+		&GoRuntimePrometheusConfig{Metrics: &PrometheusConfig{
+			Port:     openPort,
+			Path:     "/metrics",
+			TTL:      3 * time.Minute,
+			Features: []string{otel.FeatureApplication, otel.FeatureGoRuntime},
+		}},
+	)()
+	require.NoError(t, err)
+
+	statuses := make(chan []*goruntime.Status, 20)
+	go exporter(statuses)
+
+	// WHEN it receives the Go runtime status of a service
+	statuses <- []*goruntime.Status{{
+		Service:                &svc.ID{UID: "cart", Name: "cart", Namespace: "shop"},
+		GoroutinesCreatedDelta: 12,
+		SchedLatencyDelta:      goruntime.Histogram{Counts: [goruntime.NumBuckets]uint64{4}, SumNs: 20_000},
+	}}
+
+	// THEN the scheduler latency is not exported
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		exported := getMetrics(t, promURL)
+		assert.Contains(t, exported, `process_runtime_go_goroutines_created_total{`)
+		assert.NotContains(t, exported, `process_runtime_go_sched_latency_seconds`)
+	})
+}
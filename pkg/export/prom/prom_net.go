@@ -8,6 +8,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/grafana/beyla/pkg/export/attributes"
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
 	"github.com/grafana/beyla/pkg/export/expire"
 	"github.com/grafana/beyla/pkg/internal/connector"
 	"github.com/grafana/beyla/pkg/internal/netolly/ebpf"
@@ -25,13 +26,17 @@ type NetPrometheusConfig struct {
 
 // nolint:gocritic
 func (p NetPrometheusConfig) Enabled() bool {
-	return p.Config != nil && p.Config.Port != 0 && (p.Config.NetworkMetricsEnabled() || p.GloballyEnabled)
+	return p.Config != nil && (p.Config.Port != 0 || p.Config.RemoteWrite.Enabled()) && (p.Config.NetworkMetricsEnabled() || p.GloballyEnabled)
 }
 
 type netMetricsReporter struct {
 	cfg *PrometheusConfig
 
-	flowBytes *Expirer[prometheus.Counter]
+	flowBytes    *Expirer[prometheus.Counter]
+	flowRTT      *Expirer[prometheus.Histogram]
+	flowResets   *Expirer[prometheus.Counter]
+	flowHalfOpen *Expirer[prometheus.Counter]
+	flowDrops    *Expirer[prometheus.Counter]
 
 	promConnect *connector.PrometheusManager
 
@@ -97,11 +102,31 @@ func newNetReporter(
 			Name: attributes.BeylaNetworkFlow.Prom,
 			Help: "bytes submitted from a source network endpoint to a destination network endpoint",
 		}, labelNames).MetricVec, clock.Time, cfg.Config.TTL),
+		flowRTT: NewExpirer[prometheus.Histogram](prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:                            attributes.BeylaNetworkFlowRTT.Prom,
+			Help:                            "approximate time between the SYN and the SYN-ACK of the TCP connections opened by the network flows, measured from the start of the flows in both directions, in seconds",
+			Buckets:                         cfg.Config.Buckets.DurationHistogram,
+			NativeHistogramBucketFactor:     defaultHistogramBucketFactor,
+			NativeHistogramMaxBucketNumber:  defaultHistogramMaxBucketNumber,
+			NativeHistogramMinResetDuration: defaultHistogramMinResetDuration,
+		}, labelNames).MetricVec, clock.Time, cfg.Config.TTL),
+		flowResets: NewExpirer[prometheus.Counter](prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: attributes.BeylaNetworkFlowResets.Prom,
+			Help: "network flows that contain a TCP packet with the RST flag",
+		}, labelNames).MetricVec, clock.Time, cfg.Config.TTL),
+		flowHalfOpen: NewExpirer[prometheus.Counter](prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: attributes.BeylaNetworkFlowHalfOpen.Prom,
+			Help: "TCP connections whose SYN has not been answered",
+		}, labelNames).MetricVec, clock.Time, cfg.Config.TTL),
+		flowDrops: NewExpirer[prometheus.Counter](prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: attributes.BeylaNetworkFlowDrops.Prom,
+			Help: "packets from a source network endpoint to a destination network endpoint that were dropped by the kernel",
+		}, append([]string{attr.DropReason.Prom()}, labelNames...)).MetricVec, clock.Time, cfg.Config.TTL),
 	}
 	if cfg.Config.Registry != nil {
-		cfg.Config.Registry.MustRegister(mr.flowBytes)
+		cfg.Config.Registry.MustRegister(mr.flowBytes, mr.flowRTT, mr.flowResets, mr.flowHalfOpen, mr.flowDrops)
 	} else {
-		mr.promConnect.Register(cfg.Config.Port, cfg.Config.Path, mr.flowBytes)
+		mr.promConnect.Register(cfg.Config.Port, cfg.Config.Path, mr.flowBytes, mr.flowRTT, mr.flowResets, mr.flowHalfOpen, mr.flowDrops)
 	}
 
 	return mr, nil
@@ -128,5 +153,21 @@ func (r *netMetricsReporter) observe(flow *ebpf.Record) {
 	for _, attr := range r.attrs {
 		labelValues = append(labelValues, attr.Get(flow))
 	}
-	r.flowBytes.WithLabelValues(labelValues...).metric.Add(float64(flow.Metrics.Bytes))
+	if flow.DropReason != "" {
+		r.flowDrops.WithLabelValues(append([]string{flow.DropReason}, labelValues...)...).metric.Add(float64(flow.Metrics.Packets))
+		return
+	}
+	// records that only report handshake information must not account bytes
+	if !flow.IsHandshakeReport() {
+		r.flowBytes.WithLabelValues(labelValues...).metric.Add(float64(flow.Metrics.Bytes))
+	}
+	if flow.HandshakeRTT > 0 {
+		r.flowRTT.WithLabelValues(labelValues...).metric.Observe(flow.HandshakeRTT.Seconds())
+	}
+	if flow.Metrics.IsReset() {
+		r.flowResets.WithLabelValues(labelValues...).metric.Inc()
+	}
+	if flow.HalfOpen {
+		r.flowHalfOpen.WithLabelValues(labelValues...).metric.Inc()
+	}
 }
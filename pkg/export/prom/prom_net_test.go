@@ -96,3 +96,95 @@ func TestMetricsExpiration(t *testing.T) {
 	})
 	assert.NotContains(t, exported, `beyla_network_flow_bytes_total{dst_name="bar",src_name="foo"}`)
 }
+
+func TestNetHandshakeMetrics(t *testing.T) {
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+	openPort, err := test.FreeTCPPort()
+	require.NoError(t, err)
+	promURL := fmt.Sprintf("http://127.0.0.1:%d/metrics", openPort)
+
+	exporter, err := NetPrometheusEndpoint(
+		ctx, &global.ContextInfo{Prometheus: &connector.PrometheusManager{}},
+		&NetPrometheusConfig{Config: &PrometheusConfig{
+			Port:     openPort,
+			Path:     "/metrics",
+			TTL:      time.Hour,
+			Features: []string{otel.FeatureNetwork},
+			Buckets:  otel.Buckets{DurationHistogram: []float64{0.001, 0.01}},
+		}, AttributeSelectors: attributes.Selection{
+			attributes.BeylaNetworkFlow.Section: attributes.InclusionLists{
+				Include: []string{"src_name", "dst_name"},
+			},
+		}},
+	)
+	require.NoError(t, err)
+
+	metrics := make(chan []*ebpf.Record, 20)
+	go exporter(metrics)
+
+	metrics <- []*ebpf.Record{
+		{Attrs: ebpf.RecordAttrs{SrcName: "foo", DstName: "bar"}, HandshakeRTT: 5 * time.Millisecond,
+			NetFlowRecordT: ebpf.NetFlowRecordT{Metrics: ebpf.NetFlowMetrics{Packets: 2, Bytes: 123}}},
+		{Attrs: ebpf.RecordAttrs{SrcName: "bar", DstName: "foo"},
+			NetFlowRecordT: ebpf.NetFlowRecordT{Metrics: ebpf.NetFlowMetrics{Packets: 1, Bytes: 40, Flags: ebpf.FlagRSTACK}}},
+		// records that only report handshake information do not account bytes
+		{Attrs: ebpf.RecordAttrs{SrcName: "foo", DstName: "baz"}, HalfOpen: true},
+	}
+
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		exported := getMetrics(t, promURL)
+		assert.Contains(t, exported, `beyla_network_flow_bytes_total{dst_name="bar",src_name="foo"} 123`)
+		assert.Contains(t, exported, `beyla_network_flow_bytes_total{dst_name="foo",src_name="bar"} 40`)
+		assert.NotContains(t, exported, `beyla_network_flow_bytes_total{dst_name="baz",src_name="foo"}`)
+		assert.Contains(t, exported, `beyla_network_flow_rtt_seconds_bucket{dst_name="bar",src_name="foo",le="0.001"} 0`)
+		assert.Contains(t, exported, `beyla_network_flow_rtt_seconds_bucket{dst_name="bar",src_name="foo",le="0.01"} 1`)
+		assert.Contains(t, exported, `beyla_network_flow_rtt_seconds_count{dst_name="bar",src_name="foo"} 1`)
+		assert.NotContains(t, exported, `beyla_network_flow_rtt_seconds_count{dst_name="foo",src_name="bar"}`)
+		assert.Contains(t, exported, `beyla_network_flow_resets_total{dst_name="foo",src_name="bar"} 1`)
+		assert.NotContains(t, exported, `beyla_network_flow_resets_total{dst_name="bar",src_name="foo"}`)
+		assert.Contains(t, exported, `beyla_network_flow_half_open_total{dst_name="baz",src_name="foo"} 1`)
+	})
+}
+
+func TestNetDropMetrics(t *testing.T) {
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+	openPort, err := test.FreeTCPPort()
+	require.NoError(t, err)
+	promURL := fmt.Sprintf("http://127.0.0.1:%d/metrics", openPort)
+
+	exporter, err := NetPrometheusEndpoint(
+		ctx, &global.ContextInfo{Prometheus: &connector.PrometheusManager{}},
+		&NetPrometheusConfig{Config: &PrometheusConfig{
+			Port:     openPort,
+			Path:     "/metrics",
+			TTL:      time.Hour,
+			Features: []string{otel.FeatureNetwork},
+		}, AttributeSelectors: attributes.Selection{
+			attributes.BeylaNetworkFlow.Section: attributes.InclusionLists{
+				Include: []string{"src_name", "dst_name", "dst_port"},
+			},
+		}},
+	)
+	require.NoError(t, err)
+
+	metrics := make(chan []*ebpf.Record, 20)
+	go exporter(metrics)
+
+	metrics <- []*ebpf.Record{
+		{Attrs: ebpf.RecordAttrs{SrcName: "foo", DstName: "bar"},
+			NetFlowRecordT: ebpf.NetFlowRecordT{Id: ebpf.NetFlowId{DstPort: 8080}, Metrics: ebpf.NetFlowMetrics{Packets: 2, Bytes: 123}}},
+		// dropped packets are not accounted as transmitted bytes
+		{Attrs: ebpf.RecordAttrs{SrcName: "foo", DstName: "baz"}, DropReason: "NETFILTER_DROP",
+			NetFlowRecordT: ebpf.NetFlowRecordT{Id: ebpf.NetFlowId{DstPort: 5432}, Metrics: ebpf.NetFlowMetrics{Packets: 3, Bytes: 180}}},
+	}
+
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		exported := getMetrics(t, promURL)
+		assert.Contains(t, exported, `beyla_network_flow_bytes_total{dst_name="bar",dst_port="8080",src_name="foo"} 123`)
+		assert.NotContains(t, exported, `beyla_network_flow_bytes_total{dst_name="baz"`)
+		assert.Contains(t, exported, `beyla_network_flow_drops_total{drop_reason="NETFILTER_DROP",dst_name="baz",dst_port="5432",src_name="foo"} 3`)
+		assert.NotContains(t, exported, `beyla_network_flow_drops_total{drop_reason="",`)
+	})
+}
@@ -0,0 +1,114 @@
+package prom
+
+import (
+	"context"
+
+	"github.com/mariomac/pipes/pipe"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/beyla/pkg/export/attributes"
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+	"github.com/grafana/beyla/pkg/export/expire"
+	"github.com/grafana/beyla/pkg/internal/connector"
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
+	"github.com/grafana/beyla/pkg/internal/slo"
+)
+
+// SLOPrometheusConfig for the Service Level Objectives metrics
+type SLOPrometheusConfig struct {
+	Metrics *PrometheusConfig
+	SLO     *slo.Config
+}
+
+// nolint:gocritic
+func (p SLOPrometheusConfig) Enabled() bool {
+	return p.Metrics != nil && p.Metrics.EndpointEnabled() &&
+		p.SLO != nil && p.SLO.Enabled()
+}
+
+// SLOPrometheusEndpoint provides a pipeline node that exports the status of the
+// Service Level Objectives as Prometheus metrics
+func SLOPrometheusEndpoint(
+	ctx context.Context, ctxInfo *global.ContextInfo, cfg *SLOPrometheusConfig,
+) pipe.FinalProvider[[]*slo.Status] {
+	return func() (pipe.FinalFunc[[]*slo.Status], error) {
+		if !cfg.Enabled() {
+			// This node is not going to be instantiated. Let the pipes library just ignore it.
+			return pipe.IgnoreFinal[[]*slo.Status](), nil
+		}
+		reporter := newSLOReporter(ctx, ctxInfo, cfg)
+		if cfg.Metrics.Registry != nil {
+			return reporter.collectMetrics, nil
+		}
+		return reporter.reportMetrics, nil
+	}
+}
+
+type sloMetricsReporter struct {
+	promConnect *connector.PrometheusManager
+
+	clock *expire.CachedClock
+	bgCtx context.Context
+
+	attrs []attributes.Field[*slo.Status, string]
+
+	target               *Expirer[prometheus.Gauge]
+	errorBudgetRemaining *Expirer[prometheus.Gauge]
+	burnRate             *Expirer[prometheus.Gauge]
+}
+
+func newSLOReporter(ctx context.Context, ctxInfo *global.ContextInfo, cfg *SLOPrometheusConfig) *sloMetricsReporter {
+	attrs := attributes.PrometheusGetters(slo.PromGetters, slo.AttributeNames)
+	lblNames := labelNames(attrs)
+
+	clock := expire.NewCachedClock(timeNow)
+	mr := &sloMetricsReporter{
+		bgCtx:       ctx,
+		promConnect: ctxInfo.Prometheus,
+		clock:       clock,
+		attrs:       attrs,
+		target: NewExpirer[prometheus.Gauge](prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: attributes.SLOTarget.Prom,
+			Help: "Target ratio of good requests of a Service Level Objective",
+		}, lblNames).MetricVec, clock.Time, cfg.Metrics.TTL),
+		errorBudgetRemaining: NewExpirer[prometheus.Gauge](prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: attributes.SLOErrorBudgetRemaining.Prom,
+			Help: "Ratio of the error budget that remains available during the Service Level Objective window",
+		}, lblNames).MetricVec, clock.Time, cfg.Metrics.TTL),
+		burnRate: NewExpirer[prometheus.Gauge](prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: attributes.SLOBurnRate.Prom,
+			Help: "Rate at which the error budget of a Service Level Objective is consumed during the last time window",
+		}, append([]string{attr.SLOWindow.Prom()}, lblNames...)).MetricVec, clock.Time, cfg.Metrics.TTL),
+	}
+	if cfg.Metrics.Registry != nil {
+		cfg.Metrics.Registry.MustRegister(mr.target, mr.errorBudgetRemaining, mr.burnRate)
+	} else {
+		mr.promConnect.Register(cfg.Metrics.Port, cfg.Metrics.Path, mr.target, mr.errorBudgetRemaining, mr.burnRate)
+	}
+	return mr
+}
+
+func (r *sloMetricsReporter) reportMetrics(input <-chan []*slo.Status) {
+	go r.promConnect.StartHTTP(r.bgCtx)
+	r.collectMetrics(input)
+}
+
+func (r *sloMetricsReporter) collectMetrics(input <-chan []*slo.Status) {
+	for statuses := range input {
+		// clock needs to be updated to let the expirer
+		// remove the old metrics
+		r.clock.Update()
+		for _, st := range statuses {
+			r.observe(st)
+		}
+	}
+}
+
+func (r *sloMetricsReporter) observe(st *slo.Status) {
+	lv := labelValues(st, r.attrs)
+	r.target.WithLabelValues(lv...).metric.Set(st.Target)
+	r.errorBudgetRemaining.WithLabelValues(lv...).metric.Set(st.ErrorBudgetRemaining)
+	for _, br := range st.BurnRates {
+		r.burnRate.WithLabelValues(append([]string{slo.WindowLabel(br.Window)}, lv...)...).metric.Set(br.Rate)
+	}
+}
@@ -0,0 +1,64 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mariomac/guara/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/connector"
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
+	"github.com/grafana/beyla/pkg/internal/slo"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func TestSLOPrometheusEndpoint(t *testing.T) {
+	now := syncedClock{now: time.Now()}
+	timeNow = now.Now
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+	openPort, err := test.FreeTCPPort()
+	require.NoError(t, err)
+	promURL := fmt.Sprintf("http://127.0.0.1:%d/metrics", openPort)
+
+	// GIVEN a Prometheus SLO metrics exporter
+	exporter, err := SLOPrometheusEndpoint(
+		ctx, &global.ContextInfo{Prometheus: &connector.PrometheusManager{}},
+		&SLOPrometheusConfig{
+			Metrics: &PrometheusConfig{Port: openPort, Path: "/metrics", TTL: 3 * time.Minute},
+			SLO:     &slo.Config{Objectives: []slo.Objective{{Name: "checkout", Availability: 0.99}}},
+		},
+	)()
+	require.NoError(t, err)
+
+	statuses := make(chan []*slo.Status, 20)
+	go exporter(statuses)
+
+	// WHEN it receives the status of an objective
+	statuses <- []*slo.Status{{
+		Objective:            "checkout",
+		SLI:                  slo.SLIAvailability,
+		Service:              &svc.ID{Name: "cart", Namespace: "shop"},
+		Target:               0.99,
+		ErrorBudgetRemaining: 0.75,
+		BurnRates: []slo.BurnRate{
+			{Window: 5 * time.Minute, Rate: 2},
+			{Window: 72 * time.Hour, Rate: 0.5},
+		},
+	}}
+
+	// THEN the target, error budget and burn rates are exported
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		exported := getMetrics(t, promURL)
+		labels := `service_name="cart",service_namespace="shop",slo_name="checkout",slo_sli="availability"`
+		assert.Contains(t, exported, `slo_target{`+labels+`} 0.99`)
+		assert.Contains(t, exported, `slo_error_budget_remaining{`+labels+`} 0.75`)
+		assert.Contains(t, exported, `slo_burn_rate{`+labels+`,slo_window="5m"} 2`)
+		assert.Contains(t, exported, `slo_burn_rate{`+labels+`,slo_window="3d"} 0.5`)
+	})
+}
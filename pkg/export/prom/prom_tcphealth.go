@@ -0,0 +1,181 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mariomac/pipes/pipe"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/beyla/pkg/export/attributes"
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+	"github.com/grafana/beyla/pkg/export/expire"
+	"github.com/grafana/beyla/pkg/internal/connector"
+	"github.com/grafana/beyla/pkg/internal/infraolly/tcphealth"
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
+)
+
+// TCPHealthPrometheusConfig for the TCP connection health metrics just wraps the global
+// prom.PrometheusConfig as provided by the user
+type TCPHealthPrometheusConfig struct {
+	Metrics            *PrometheusConfig
+	AttributeSelectors attributes.Selection
+}
+
+// nolint:gocritic
+func (p TCPHealthPrometheusConfig) Enabled() bool {
+	return p.Metrics != nil && p.Metrics.EndpointEnabled() && p.Metrics.OTelMetricsEnabled() &&
+		p.Metrics.TCPConnectionMetricsEnabled()
+}
+
+// TCPHealthPrometheusEndpoint provides a pipeline node that exports the health of the
+// TCP connections of the instrumented services as Prometheus metrics
+func TCPHealthPrometheusEndpoint(
+	ctx context.Context, ctxInfo *global.ContextInfo, cfg *TCPHealthPrometheusConfig,
+) pipe.FinalProvider[[]*tcphealth.Status] {
+	return func() (pipe.FinalFunc[[]*tcphealth.Status], error) {
+		if !cfg.Enabled() {
+			// This node is not going to be instantiated. Let the pipes library just ignore it.
+			return pipe.IgnoreFinal[[]*tcphealth.Status](), nil
+		}
+		reporter, err := newTCPHealthReporter(ctx, ctxInfo, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Metrics.Registry != nil {
+			return reporter.collectMetrics, nil
+		}
+		return reporter.reportMetrics, nil
+	}
+}
+
+type tcpHealthMetricsReporter struct {
+	promConnect *connector.PrometheusManager
+
+	clock *expire.CachedClock
+	bgCtx context.Context
+
+	rttAttrs []attributes.Field[*tcphealth.Status, string]
+	rtt      *Expirer[*bucketedHistogram]
+
+	setupDurationAttrs []attributes.Field[*tcphealth.Status, string]
+	setupDuration      *Expirer[*bucketedHistogram]
+
+	retransmitsAttrs []attributes.Field[*tcphealth.Status, string]
+	retransmits      *Expirer[prometheus.Counter]
+
+	// resets are labeled by network_io_direction (transmit or receive)
+	resetsAttrs []attributes.Field[*tcphealth.Status, string]
+	resets      *Expirer[prometheus.Counter]
+
+	// failures are labeled by error_type (refused or timeout)
+	failuresAttrs []attributes.Field[*tcphealth.Status, string]
+	failures      *Expirer[prometheus.Counter]
+}
+
+func newTCPHealthReporter(
+	ctx context.Context,
+	ctxInfo *global.ContextInfo,
+	cfg *TCPHealthPrometheusConfig,
+) (*tcpHealthMetricsReporter, error) {
+	group := ctxInfo.MetricAttributeGroups
+	// this property can't be set inside the ConfiguredGroups function, otherwise the
+	// OTEL exporter would report also some prometheus-exclusive attributes
+	group.Add(attributes.GroupPrometheus)
+
+	provider, err := attributes.NewAttrSelector(group, cfg.AttributeSelectors)
+	if err != nil {
+		return nil, fmt.Errorf("TCP health Prometheus exporter attributes enable: %w", err)
+	}
+
+	attrRTT := attributes.PrometheusGetters(tcphealth.PromGetters, provider.For(attributes.TCPConnectionRTT))
+	attrSetupDuration := attributes.PrometheusGetters(tcphealth.PromGetters, provider.For(attributes.TCPConnectionSetupDuration))
+	attrRetransmits := attributes.PrometheusGetters(tcphealth.PromGetters, provider.For(attributes.TCPConnectionRetransmits))
+	attrResets := attributes.PrometheusGetters(tcphealth.PromGetters, provider.For(attributes.TCPConnectionResets))
+	attrFailures := attributes.PrometheusGetters(tcphealth.PromGetters, provider.For(attributes.TCPConnectionFailures))
+
+	clock := expire.NewCachedClock(timeNow)
+	mr := &tcpHealthMetricsReporter{
+		bgCtx:       ctx,
+		promConnect: ctxInfo.Prometheus,
+		clock:       clock,
+		rttAttrs:    attrRTT,
+		rtt: NewExpirer[*bucketedHistogram](newBucketedHistogramVec(tcphealth.BucketBounds(), prometheus.NewDesc(
+			attributes.TCPConnectionRTT.Prom,
+			"Smoothed round-trip time of the TCP connections, sampled each time that data is sent",
+			labelNames(attrRTT), nil,
+		)), clock.Time, cfg.Metrics.TTL),
+		setupDurationAttrs: attrSetupDuration,
+		setupDuration: NewExpirer[*bucketedHistogram](newBucketedHistogramVec(tcphealth.BucketBounds(), prometheus.NewDesc(
+			attributes.TCPConnectionSetupDuration.Prom,
+			"Time between sending the SYN of a TCP connection and its establishment",
+			labelNames(attrSetupDuration), nil,
+		)), clock.Time, cfg.Metrics.TTL),
+		retransmitsAttrs: attrRetransmits,
+		retransmits: NewExpirer[prometheus.Counter](prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: attributes.TCPConnectionRetransmits.Prom,
+			Help: "Retransmitted TCP segments",
+		}, labelNames(attrRetransmits)).MetricVec, clock.Time, cfg.Metrics.TTL),
+		resetsAttrs: attrResets,
+		resets: NewExpirer[prometheus.Counter](prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: attributes.TCPConnectionResets.Prom,
+			Help: "TCP resets that have been sent or received",
+		}, append([]string{attr.ProcNetIODir.Prom()}, labelNames(attrResets)...)).MetricVec, clock.Time, cfg.Metrics.TTL),
+		failuresAttrs: attrFailures,
+		failures: NewExpirer[prometheus.Counter](prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: attributes.TCPConnectionFailures.Prom,
+			Help: "TCP connection attempts that were refused or timed out",
+		}, append([]string{attr.ErrorType.Prom()}, labelNames(attrFailures)...)).MetricVec, clock.Time, cfg.Metrics.TTL),
+	}
+
+	if cfg.Metrics.Registry != nil {
+		cfg.Metrics.Registry.MustRegister(mr.rtt, mr.setupDuration, mr.retransmits, mr.resets, mr.failures)
+	} else {
+		mr.promConnect.Register(cfg.Metrics.Port, cfg.Metrics.Path,
+			mr.rtt, mr.setupDuration, mr.retransmits, mr.resets, mr.failures)
+	}
+	return mr, nil
+}
+
+func (r *tcpHealthMetricsReporter) reportMetrics(input <-chan []*tcphealth.Status) {
+	go r.promConnect.StartHTTP(r.bgCtx)
+	r.collectMetrics(input)
+}
+
+func (r *tcpHealthMetricsReporter) collectMetrics(input <-chan []*tcphealth.Status) {
+	for statuses := range input {
+		// clock needs to be updated to let the expirer
+		// remove the old metrics
+		r.clock.Update()
+		for _, st := range statuses {
+			r.observe(st)
+		}
+	}
+}
+
+// observe skips the zero deltas, to avoid reporting a series for each kind of event
+// of each peer, even if they never happened
+func (r *tcpHealthMetricsReporter) observe(st *tcphealth.Status) {
+	if st.RTTDelta.Count() > 0 {
+		r.rtt.WithLabelValues(labelValues(st, r.rttAttrs)...).metric.add(st.RTTDelta.Counts[:], st.RTTDelta.SumNs)
+	}
+	if st.SetupDurationDelta.Count() > 0 {
+		r.setupDuration.WithLabelValues(labelValues(st, r.setupDurationAttrs)...).
+			metric.add(st.SetupDurationDelta.Counts[:], st.SetupDurationDelta.SumNs)
+	}
+	if st.RetransmitsDelta > 0 {
+		r.retransmits.WithLabelValues(labelValues(st, r.retransmitsAttrs)...).metric.Add(float64(st.RetransmitsDelta))
+	}
+	addLabeled(r.resets, "transmit", labelValues(st, r.resetsAttrs), st.ResetsSentDelta)
+	addLabeled(r.resets, "receive", labelValues(st, r.resetsAttrs), st.ResetsReceivedDelta)
+	addLabeled(r.failures, "refused", labelValues(st, r.failuresAttrs), st.FailuresRefusedDelta)
+	addLabeled(r.failures, "timeout", labelValues(st, r.failuresAttrs), st.FailuresTimeoutDelta)
+}
+
+// addLabeled adds the delta to the counter whose first label has the provided value
+func addLabeled(counter *Expirer[prometheus.Counter], label string, lv []string, delta uint64) {
+	if delta == 0 {
+		return
+	}
+	counter.WithLabelValues(append([]string{label}, lv...)...).metric.Add(float64(delta))
+}
@@ -0,0 +1,101 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mariomac/guara/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/export/otel"
+	"github.com/grafana/beyla/pkg/internal/connector"
+	"github.com/grafana/beyla/pkg/internal/infraolly/tcphealth"
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func TestTCPHealthPrometheusEndpoint(t *testing.T) {
+	now := syncedClock{now: time.Now()}
+	timeNow = now.Now
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+	openPort, err := test.FreeTCPPort()
+	require.NoError(t, err)
+	promURL := fmt.Sprintf("http://127.0.0.1:%d/metrics", openPort)
+
+	// GIVEN a Prometheus TCP health metrics exporter
+	exporter, err := TCPHealthPrometheusEndpoint(
+		ctx, &global.ContextInfo{Prometheus: &connector.PrometheusManager{}},
+		&TCPHealthPrometheusConfig{Metrics: &PrometheusConfig{
+			Port:     openPort,
+			Path:     "/metrics",
+			TTL:      3 * time.Minute,
+			Features: []string{otel.FeatureApplication, otel.FeatureTCPConnection},
+		}},
+	)()
+	require.NoError(t, err)
+
+	statuses := make(chan []*tcphealth.Status, 20)
+	go exporter(statuses)
+
+	service := &svc.ID{UID: "cart", Name: "cart", Namespace: "shop"}
+	// WHEN it receives the TCP health status of a service
+	statuses <- []*tcphealth.Status{{
+		Service:              service,
+		Role:                 tcphealth.RoleClient,
+		PeerAddress:          "10.0.0.5",
+		ServerPort:           5432,
+		RetransmitsDelta:     3,
+		ResetsReceivedDelta:  1,
+		FailuresTimeoutDelta: 2,
+		SetupDurationDelta:   tcphealth.Histogram{Counts: [tcphealth.NumBuckets]uint64{2, 1}, SumNs: 400_000},
+		RTTDelta:             tcphealth.Histogram{Counts: [tcphealth.NumBuckets]uint64{0, 0, 4, 0, 0, 0, 0, 0, 0, 1}, SumNs: 2_002_000_000},
+	}}
+
+	// THEN the counters and histograms are exported
+	labels := `network_peer_address="10.0.0.5",server_port="5432",service_name="cart",service_namespace="shop",target_instance="",tcp_role="client"`
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		exported := getMetrics(t, promURL)
+		assert.Contains(t, exported, `tcp_connection_retransmits_total{`+labels+`} 3`)
+		assert.Contains(t, exported, `tcp_connection_resets_total{network_io_direction="receive",`+labels+`} 1`)
+		assert.Contains(t, exported, `tcp_connection_failures_total{error_type="timeout",`+labels+`} 2`)
+		assert.Contains(t, exported, `tcp_connection_setup_duration_seconds_bucket{`+labels+`,le="0.0001"} 2`)
+		assert.Contains(t, exported, `tcp_connection_setup_duration_seconds_bucket{`+labels+`,le="0.0005"} 3`)
+		assert.Contains(t, exported, `tcp_connection_setup_duration_seconds_sum{`+labels+`} 0.0004`)
+		assert.Contains(t, exported, `tcp_connection_rtt_seconds_bucket{`+labels+`,le="0.001"} 4`)
+		assert.Contains(t, exported, `tcp_connection_rtt_seconds_bucket{`+labels+`,le="1"} 4`)
+		assert.Contains(t, exported, `tcp_connection_rtt_seconds_count{`+labels+`} 5`)
+	})
+	// AND the events that didn't happen are not reported
+	exported := getMetrics(t, promURL)
+	assert.NotContains(t, exported, `network_io_direction="transmit"`)
+	assert.NotContains(t, exported, `error_type="refused"`)
+
+	// AND WHEN it receives the next deltas
+	statuses <- []*tcphealth.Status{{
+		Service:              service,
+		Role:                 tcphealth.RoleClient,
+		PeerAddress:          "10.0.0.5",
+		ServerPort:           5432,
+		RetransmitsDelta:     2,
+		ResetsSentDelta:      1,
+		FailuresRefusedDelta: 1,
+		SetupDurationDelta:   tcphealth.Histogram{Counts: [tcphealth.NumBuckets]uint64{1}, SumNs: 50_000},
+	}}
+
+	// THEN the counters and histograms are accumulated
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		exported := getMetrics(t, promURL)
+		assert.Contains(t, exported, `tcp_connection_retransmits_total{`+labels+`} 5`)
+		assert.Contains(t, exported, `tcp_connection_resets_total{network_io_direction="transmit",`+labels+`} 1`)
+		assert.Contains(t, exported, `tcp_connection_resets_total{network_io_direction="receive",`+labels+`} 1`)
+		assert.Contains(t, exported, `tcp_connection_failures_total{error_type="refused",`+labels+`} 1`)
+		assert.Contains(t, exported, `tcp_connection_setup_duration_seconds_bucket{`+labels+`,le="0.0001"} 3`)
+		assert.Contains(t, exported, `tcp_connection_setup_duration_seconds_count{`+labels+`} 4`)
+		assert.Contains(t, exported, `tcp_connection_setup_duration_seconds_sum{`+labels+`} 0.00045`)
+	})
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/beyla/pkg/export/attributes"
+	"github.com/grafana/beyla/pkg/export/expire"
 	"github.com/grafana/beyla/pkg/export/instrumentations"
 	"github.com/grafana/beyla/pkg/export/otel"
 	"github.com/grafana/beyla/pkg/internal/connector"
@@ -59,12 +60,14 @@ func TestAppMetricsExpiration(t *testing.T) {
 	go exporter(metrics)
 
 	// WHEN it receives metrics
+	runtimeSvc := svc.ID{RuntimeName: "go", RuntimeVersion: "1.22.1"}
 	metrics <- []request.Span{
-		{Type: request.EventTypeHTTP, Path: "/foo", End: 123 * time.Second.Nanoseconds()},
-		{Type: request.EventTypeHTTP, Path: "/baz", End: 456 * time.Second.Nanoseconds()},
+		{Type: request.EventTypeHTTP, Path: "/foo", End: 123 * time.Second.Nanoseconds(), ServiceID: runtimeSvc},
+		{Type: request.EventTypeHTTP, Path: "/baz", End: 456 * time.Second.Nanoseconds(), ServiceID: runtimeSvc},
 	}
 
 	containsTargetInfo := regexp.MustCompile(`\ntarget_info\{.*host_id="my-host"`)
+	containsRuntime := regexp.MustCompile(`\ntarget_info\{.*process_runtime_name="go",process_runtime_version="1.22.1"`)
 
 	// THEN the metrics are exported
 	test.Eventually(t, timeout, func(t require.TestingT) {
@@ -72,6 +75,7 @@ func TestAppMetricsExpiration(t *testing.T) {
 		assert.Contains(t, exported, `http_server_request_duration_seconds_sum{url_path="/foo"} 123`)
 		assert.Contains(t, exported, `http_server_request_duration_seconds_sum{url_path="/baz"} 456`)
 		assert.Regexp(t, containsTargetInfo, exported)
+		assert.Regexp(t, containsRuntime, exported)
 	})
 
 	// AND WHEN it keeps receiving a subset of the initial metrics during the timeout
@@ -296,8 +300,122 @@ func TestAppMetrics_ByInstrumentation(t *testing.T) {
 	}
 }
 
+func TestServiceGraphMessaging(t *testing.T) {
+	now := syncedClock{now: time.Now()}
+	timeNow = now.Now
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+	openPort, err := test.FreeTCPPort()
+	require.NoError(t, err)
+	promURL := fmt.Sprintf("http://127.0.0.1:%d/metrics", openPort)
+
+	exporter, err := PrometheusEndpoint(
+		ctx, &global.ContextInfo{Prometheus: &connector.PrometheusManager{}},
+		&PrometheusConfig{
+			Port:                        openPort,
+			Path:                        "/metrics",
+			TTL:                         300 * time.Minute,
+			SpanMetricsServiceCacheSize: 10,
+			Features:                    []string{otel.FeatureGraphMessaging},
+			Instrumentations:            []string{instrumentations.InstrumentationALL},
+		}, attributes.Selection{},
+	)()
+	require.NoError(t, err)
+
+	metrics := make(chan []request.Span, 20)
+	go exporter(metrics)
+
+	metrics <- []request.Span{
+		{ServiceID: svc.ID{Name: "orders-api"}, PeerName: "orders-api", Type: request.EventTypeKafkaClient,
+			Method: request.MessagingPublish, Path: "orders", RequestStart: 100, End: 200,
+			Messaging: &request.MessagingInfo{Partition: 0, Messages: 1, Offset: 33, HighWatermark: -1}},
+		{ServiceID: svc.ID{Name: "billing-worker"}, PeerName: "billing-worker", Type: request.EventTypeKafkaClient,
+			Method: request.MessagingProcess, Path: "orders", RequestStart: 300, End: 400,
+			Messaging: &request.MessagingInfo{Partition: 0, Messages: 1, Offset: 33, HighWatermark: 34}},
+		{ServiceID: svc.ID{Name: "billing-worker"}, PeerName: "billing-worker", HostName: "users", Type: request.EventTypeHTTPClient,
+			Method: "GET", Path: "/users", RequestStart: 300, End: 400},
+	}
+
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		exported := getMetrics(t, promURL)
+		assert.Contains(t, exported,
+			`traces_service_graph_request_total{client="orders-api",client_service_namespace="",server="kafka:orders",server_service_namespace="",source="beyla"} 1`)
+		assert.Contains(t, exported,
+			`traces_service_graph_request_total{client="kafka:orders",client_service_namespace="",server="billing-worker",server_service_namespace="",source="beyla"} 1`)
+		assert.Contains(t, exported,
+			`traces_service_graph_request_total{client="billing-worker",client_service_namespace="",server="users",server_service_namespace="",source="beyla"} 1`)
+		assert.Contains(t, exported,
+			`traces_service_graph_request_client_seconds_count{client="orders-api",client_service_namespace="",server="kafka:orders",server_service_namespace="",source="beyla"} 1`)
+		assert.Contains(t, exported,
+			`traces_service_graph_request_server_seconds_count{client="kafka:orders",client_service_namespace="",server="billing-worker",server_service_namespace="",source="beyla"} 1`)
+		assert.Contains(t, exported,
+			`traces_service_graph_request_messaging_system_seconds_count{client="kafka:orders",client_service_namespace="",server="billing-worker",server_service_namespace="",source="beyla"} 1`)
+	})
+}
+
 var mmux = sync.Mutex{}
 
+func TestAppMetrics_CardinalityLimit(t *testing.T) {
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	defer cancelCtx()
+	openPort, err := test.FreeTCPPort()
+	require.NoError(t, err)
+	promURL := fmt.Sprintf("http://127.0.0.1:%d/metrics", openPort)
+
+	// GIVEN a Prometheus Metrics Exporter that limits the HTTP server metrics to 2 series per service
+	exporter, err := PrometheusEndpoint(
+		ctx, &global.ContextInfo{Prometheus: &connector.PrometheusManager{}},
+		&PrometheusConfig{
+			Port:             openPort,
+			Path:             "/metrics",
+			TTL:              time.Minute,
+			Features:         []string{otel.FeatureApplication},
+			Instrumentations: []string{instrumentations.InstrumentationALL},
+			CardinalityLimits: expire.CardinalityLimits{
+				Metrics: map[string]int{"http.server.request.duration": 2},
+			},
+		},
+		attributes.Selection{
+			attributes.HTTPServerDuration.Section: attributes.InclusionLists{
+				Include: []string{"url_path"},
+			},
+			attributes.HTTPClientDuration.Section: attributes.InclusionLists{
+				Include: []string{"url_path"},
+			},
+		},
+	)()
+	require.NoError(t, err)
+
+	metrics := make(chan []request.Span, 20)
+	go exporter(metrics)
+
+	// WHEN a service reports more distinct label sets than allowed
+	metrics <- []request.Span{
+		{Type: request.EventTypeHTTP, Path: "/a", End: 1 * time.Second.Nanoseconds()},
+		{Type: request.EventTypeHTTP, Path: "/b", End: 2 * time.Second.Nanoseconds()},
+		{Type: request.EventTypeHTTP, Path: "/c", End: 3 * time.Second.Nanoseconds()},
+		{Type: request.EventTypeHTTP, Path: "/d", End: 4 * time.Second.Nanoseconds()},
+		{Type: request.EventTypeHTTPClient, Path: "/a", End: 1 * time.Second.Nanoseconds()},
+		{Type: request.EventTypeHTTPClient, Path: "/b", End: 1 * time.Second.Nanoseconds()},
+		{Type: request.EventTypeHTTPClient, Path: "/c", End: 1 * time.Second.Nanoseconds()},
+	}
+
+	// THEN the exceeding label sets are folded into the overflow series
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		exported := getMetrics(t, promURL)
+		// the overflow label is empty in the normal series, and the rest of labels are empty in the overflow series
+		assert.Contains(t, exported, `http_server_request_duration_seconds_sum{otel_metric_overflow="",url_path="/a"} 1`)
+		assert.Contains(t, exported, `http_server_request_duration_seconds_sum{otel_metric_overflow="",url_path="/b"} 2`)
+		assert.Contains(t, exported, `http_server_request_duration_seconds_sum{otel_metric_overflow="true",url_path=""} 7`)
+		assert.Contains(t, exported, `http_server_request_duration_seconds_count{otel_metric_overflow="true",url_path=""} 2`)
+		assert.NotContains(t, exported, `url_path="/c"} 3`)
+		// AND the metrics without limits are not affected
+		assert.Contains(t, exported, `http_client_request_duration_seconds_count{url_path="/c"} 1`)
+		assert.NotContains(t, exported, `http_client_request_duration_seconds_count{otel_metric_overflow`)
+	})
+}
+
 func getMetrics(t require.TestingT, promURL string) string {
 	mmux.Lock()
 	defer mmux.Unlock()
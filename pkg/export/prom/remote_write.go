@@ -0,0 +1,382 @@
+package prom
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// RemoteWriteConfig enables pushing the metrics of the Prometheus exporter to a
+// remote-write compatible endpoint (e.g. Prometheus, Mimir, Thanos...). The metric and
+// label names are the same as in the scrape endpoint.
+type RemoteWriteConfig struct {
+	// URL of the remote-write endpoint. If empty, the metrics are not pushed.
+	URL string `yaml:"url" env:"URL"`
+	// Interval between each submission of the metrics.
+	Interval time.Duration `yaml:"interval" env:"INTERVAL"`
+	// Timeout of each HTTP request. Zero means no timeout.
+	Timeout time.Duration `yaml:"timeout" env:"TIMEOUT"`
+	// MaxSamplesPerSend limits the size of each request. The metrics are split in multiple
+	// requests if they contain more samples.
+	MaxSamplesPerSend int `yaml:"max_samples_per_send" env:"MAX_SAMPLES_PER_SEND"`
+	// MaxRetries of a failed request, for recoverable errors (network errors, HTTP 5xx and 429)
+	MaxRetries int `yaml:"max_retries" env:"MAX_RETRIES"`
+	// RetryBackoff is the time to wait before the first retry. It is doubled after each retry.
+	RetryBackoff time.Duration `yaml:"retry_backoff" env:"RETRY_BACKOFF"`
+
+	BasicAuth   RemoteWriteBasicAuth `yaml:"basic_auth" envPrefix:"BASIC_AUTH_"`
+	BearerToken string               `yaml:"bearer_token" env:"BEARER_TOKEN"`
+	// Headers are added to each request
+	Headers map[string]string `yaml:"headers" env:"HEADERS"`
+	// ExternalLabels are added to all the submitted series
+	ExternalLabels map[string]string `yaml:"external_labels" env:"EXTERNAL_LABELS"`
+}
+
+type RemoteWriteBasicAuth struct {
+	Username string `yaml:"username" env:"USERNAME"`
+	Password string `yaml:"password" env:"PASSWORD"`
+}
+
+func (rw *RemoteWriteConfig) Enabled() bool {
+	return rw.URL != ""
+}
+
+// Validate returns error if the remote-write endpoint is enabled with durations that would
+// make Beyla panic or retry the failed requests without pause
+func (rw *RemoteWriteConfig) Validate() error {
+	if !rw.Enabled() {
+		return nil
+	}
+	if rw.Interval <= 0 {
+		return fmt.Errorf("remote_write.interval must be positive. Got: %s", rw.Interval)
+	}
+	if rw.Timeout < 0 {
+		return fmt.Errorf("remote_write.timeout can't be negative. Got: %s", rw.Timeout)
+	}
+	if rw.MaxRetries > 0 && rw.RetryBackoff <= 0 {
+		return fmt.Errorf("remote_write.retry_backoff must be positive when max_retries is set. Got: %s",
+			rw.RetryBackoff)
+	}
+	return nil
+}
+
+var DefaultRemoteWriteConfig = RemoteWriteConfig{
+	Interval:          15 * time.Second,
+	Timeout:           30 * time.Second,
+	MaxSamplesPerSend: 2000,
+	MaxRetries:        3,
+	RetryBackoff:      time.Second,
+}
+
+// remote-write protocol headers
+const (
+	remoteWriteVersion     = "0.1.0"
+	remoteWriteContentType = "application/x-protobuf"
+)
+
+// metric types, as defined in the MetricMetadata message of the remote-write protocol
+const (
+	rwMetricTypeUnknown   = 0
+	rwMetricTypeCounter   = 1
+	rwMetricTypeGauge     = 2
+	rwMetricTypeHistogram = 3
+	rwMetricTypeSummary   = 5
+)
+
+// errUnrecoverable wraps the errors that must not be retried
+var errUnrecoverable = errors.New("unrecoverable remote-write error")
+
+// RemoteWriter periodically gathers the metrics of a Prometheus registry and pushes
+// them to a remote-write endpoint.
+type RemoteWriter struct {
+	log      *slog.Logger
+	cfg      *RemoteWriteConfig
+	gatherer prometheus.Gatherer
+	client   *http.Client
+	clock    func() time.Time
+	// external labels, sorted by name
+	externalLabels []rwLabel
+}
+
+type rwLabel struct {
+	name, value string
+}
+
+type rwSeries struct {
+	labels    []rwLabel
+	value     float64
+	timestamp int64
+}
+
+type rwMetadata struct {
+	metricType int
+	family     string
+	help       string
+}
+
+func NewRemoteWriter(cfg *RemoteWriteConfig, gatherer prometheus.Gatherer) *RemoteWriter {
+	rw := &RemoteWriter{
+		log:      slog.With("component", "prom.RemoteWriter", "url", cfg.URL),
+		cfg:      cfg,
+		gatherer: gatherer,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		clock:    timeNow,
+	}
+	for name, value := range cfg.ExternalLabels {
+		rw.externalLabels = append(rw.externalLabels, rwLabel{name: name, value: value})
+	}
+	sort.Slice(rw.externalLabels, func(i, j int) bool {
+		return rw.externalLabels[i].name < rw.externalLabels[j].name
+	})
+	return rw
+}
+
+// Run pushes the metrics periodically until the context is cancelled
+func (rw *RemoteWriter) Run(ctx context.Context) {
+	rw.log.Info("pushing Prometheus metrics to remote-write endpoint", "interval", rw.cfg.Interval)
+	ticker := time.NewTicker(rw.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			rw.log.Debug("context done. Stopping remote writer")
+			return
+		case <-ticker.C:
+			if err := rw.Write(ctx); err != nil {
+				rw.log.Warn("can't push metrics to the remote-write endpoint", "error", err)
+			}
+		}
+	}
+}
+
+// Write gathers the metrics and submits them, in batches of at most MaxSamplesPerSend samples
+func (rw *RemoteWriter) Write(ctx context.Context) error {
+	families, err := rw.gatherer.Gather()
+	if err != nil {
+		// Gather might return partial results together with the error
+		rw.log.Debug("error gathering metrics", "error", err)
+	}
+	series, metadata := rw.convert(families, rw.clock().UnixMilli())
+	batchSize := rw.cfg.MaxSamplesPerSend
+	if batchSize <= 0 {
+		batchSize = len(series)
+	}
+	for start := 0; start < len(series); start += batchSize {
+		end := min(start+batchSize, len(series))
+		req := encodeWriteRequest(series[start:end], metadata)
+		// metadata is only submitted within the first batch
+		metadata = nil
+		if err := rw.send(ctx, snappy.Encode(nil, req)); err != nil {
+			return fmt.Errorf("submitting %d samples: %w", end-start, err)
+		}
+	}
+	return nil
+}
+
+func (rw *RemoteWriter) send(ctx context.Context, body []byte) error {
+	backoff := rw.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		err := rw.post(ctx, body)
+		if err == nil || errors.Is(err, errUnrecoverable) || attempt >= rw.cfg.MaxRetries {
+			return err
+		}
+		rw.log.Debug("remote-write request failed. Retrying", "error", err, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (rw *RemoteWriter) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rw.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %w", errUnrecoverable, err)
+	}
+	for name, value := range rw.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", remoteWriteContentType)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", remoteWriteVersion)
+	switch {
+	case rw.cfg.BasicAuth.Username != "":
+		req.SetBasicAuth(rw.cfg.BasicAuth.Username, rw.cfg.BasicAuth.Password)
+	case rw.cfg.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+rw.cfg.BearerToken)
+	}
+	resp, err := rw.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("HTTP status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	if resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests {
+		return err
+	}
+	return fmt.Errorf("%w: %w", errUnrecoverable, err)
+}
+
+// convert the gathered metric families to remote-write series, with the same names and labels
+// as in the Prometheus exposition format. Native histograms are submitted as classic histograms.
+func (rw *RemoteWriter) convert(families []*dto.MetricFamily, now int64) ([]rwSeries, []rwMetadata) {
+	var series []rwSeries
+	metadata := make([]rwMetadata, 0, len(families))
+	for _, mf := range families {
+		name := mf.GetName()
+		metricType := rwMetricTypeUnknown
+		for _, m := range mf.Metric {
+			ts := now
+			if m.TimestampMs != nil {
+				ts = m.GetTimestampMs()
+			}
+			add := func(suffix string, value float64, extra ...rwLabel) {
+				series = append(series, rwSeries{
+					labels:    rw.labels(name+suffix, m.Label, extra...),
+					value:     value,
+					timestamp: ts,
+				})
+			}
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				metricType = rwMetricTypeCounter
+				add("", m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				metricType = rwMetricTypeGauge
+				add("", m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add("", m.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+				metricType = rwMetricTypeHistogram
+				h := m.GetHistogram()
+				infSeen := false
+				for _, b := range h.Bucket {
+					infSeen = infSeen || math.IsInf(b.GetUpperBound(), +1)
+					add("_bucket", float64(b.GetCumulativeCount()), rwLabel{name: "le", value: formatFloat(b.GetUpperBound())})
+				}
+				if !infSeen {
+					add("_bucket", float64(h.GetSampleCount()), rwLabel{name: "le", value: "+Inf"})
+				}
+				add("_sum", h.GetSampleSum())
+				add("_count", float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				metricType = rwMetricTypeSummary
+				s := m.GetSummary()
+				for _, q := range s.Quantile {
+					add("", q.GetValue(), rwLabel{name: "quantile", value: formatFloat(q.GetQuantile())})
+				}
+				add("_sum", s.GetSampleSum())
+				add("_count", float64(s.GetSampleCount()))
+			}
+		}
+		metadata = append(metadata, rwMetadata{metricType: metricType, family: name, help: mf.GetHelp()})
+	}
+	return series, metadata
+}
+
+// labels returns the sorted labels of a series, including the metric name and the external labels.
+// The labels of the metric take precedence over the external labels with the same name.
+func (rw *RemoteWriter) labels(name string, pairs []*dto.LabelPair, extra ...rwLabel) []rwLabel {
+	labels := make([]rwLabel, 0, 1+len(pairs)+len(extra)+len(rw.externalLabels))
+	labels = append(labels, rwLabel{name: "__name__", value: name})
+	for _, p := range pairs {
+		// empty labels are equivalent to missing labels in Prometheus
+		if p.GetValue() != "" {
+			labels = append(labels, rwLabel{name: p.GetName(), value: p.GetValue()})
+		}
+	}
+	labels = append(labels, extra...)
+	for _, el := range rw.externalLabels {
+		if !hasLabel(labels, el.name) {
+			labels = append(labels, el)
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].name < labels[j].name
+	})
+	return labels
+}
+
+func hasLabel(labels []rwLabel, name string) bool {
+	for i := range labels {
+		if labels[i].name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, +1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// encodeWriteRequest serializes the series and metadata as a remote-write WriteRequest protobuf message:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; repeated MetricMetadata metadata = 3; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+//	message MetricMetadata { MetricType type = 1; string metric_family_name = 2; string help = 4; }
+func encodeWriteRequest(series []rwSeries, metadata []rwMetadata) []byte {
+	var buf, ts, msg []byte
+	for i := range series {
+		s := &series[i]
+		ts = ts[:0]
+		for _, l := range s.labels {
+			msg = msg[:0]
+			msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+			msg = protowire.AppendString(msg, l.name)
+			msg = protowire.AppendTag(msg, 2, protowire.BytesType)
+			msg = protowire.AppendString(msg, l.value)
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, msg)
+		}
+		msg = msg[:0]
+		msg = protowire.AppendTag(msg, 1, protowire.Fixed64Type)
+		msg = protowire.AppendFixed64(msg, math.Float64bits(s.value))
+		msg = protowire.AppendTag(msg, 2, protowire.VarintType)
+		msg = protowire.AppendVarint(msg, uint64(s.timestamp))
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, msg)
+
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, ts)
+	}
+	for _, md := range metadata {
+		msg = msg[:0]
+		msg = protowire.AppendTag(msg, 1, protowire.VarintType)
+		msg = protowire.AppendVarint(msg, uint64(md.metricType))
+		msg = protowire.AppendTag(msg, 2, protowire.BytesType)
+		msg = protowire.AppendString(msg, md.family)
+		if md.help != "" {
+			msg = protowire.AppendTag(msg, 4, protowire.BytesType)
+			msg = protowire.AppendString(msg, md.help)
+		}
+		buf = protowire.AppendTag(buf, 3, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, msg)
+	}
+	return buf
+}
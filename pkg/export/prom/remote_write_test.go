@@ -0,0 +1,229 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestRemoteWrite(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	calls := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_server_calls_total", Help: "calls",
+	}, []string{"service", "url_path"})
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "http_server_request_duration_seconds", Buckets: []float64{0.1, 1},
+	}, []string{"service"})
+	reg.MustRegister(calls, duration)
+	calls.WithLabelValues("foo", "/bar").Add(3)
+	calls.WithLabelValues("foo", "").Add(1)
+	duration.WithLabelValues("foo").Observe(0.5)
+
+	var mt sync.Mutex
+	var requests []string
+	attempt := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		mt.Lock()
+		defer mt.Unlock()
+		attempt++
+		// the first request fails with a recoverable error
+		if attempt == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "snappy", req.Header.Get("Content-Encoding"))
+		assert.Equal(t, "application/x-protobuf", req.Header.Get("Content-Type"))
+		assert.Equal(t, "0.1.0", req.Header.Get("X-Prometheus-Remote-Write-Version"))
+		assert.Equal(t, "tenant-1", req.Header.Get("X-Scope-OrgID"))
+		user, pass, ok := req.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", user)
+		assert.Equal(t, "pass", pass)
+		compressed, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		body, err := snappy.Decode(nil, compressed)
+		require.NoError(t, err)
+		requests = append(requests, decodeWriteRequest(t, body)...)
+	}))
+	defer server.Close()
+
+	rw := NewRemoteWriter(&RemoteWriteConfig{
+		URL:               server.URL,
+		MaxSamplesPerSend: 4,
+		MaxRetries:        2,
+		RetryBackoff:      time.Millisecond,
+		BasicAuth:         RemoteWriteBasicAuth{Username: "user", Password: "pass"},
+		Headers:           map[string]string{"X-Scope-OrgID": "tenant-1"},
+		ExternalLabels:    map[string]string{"cluster": "prod", "service": "overridden"},
+	}, reg)
+	rw.clock = func() time.Time { return time.UnixMilli(1234) }
+
+	require.NoError(t, rw.Write(context.Background()))
+
+	mt.Lock()
+	defer mt.Unlock()
+	// 7 samples in batches of 4, plus the failed request
+	assert.Equal(t, 3, attempt)
+	sort.Strings(requests)
+	assert.Equal(t, []string{
+		`http_server_calls_total{cluster="prod",service="foo",url_path="/bar"} 3 @1234`,
+		`http_server_calls_total{cluster="prod",service="foo"} 1 @1234`,
+		`http_server_request_duration_seconds_bucket{cluster="prod",le="+Inf",service="foo"} 1 @1234`,
+		`http_server_request_duration_seconds_bucket{cluster="prod",le="0.1",service="foo"} 0 @1234`,
+		`http_server_request_duration_seconds_bucket{cluster="prod",le="1",service="foo"} 1 @1234`,
+		`http_server_request_duration_seconds_count{cluster="prod",service="foo"} 1 @1234`,
+		`http_server_request_duration_seconds_sum{cluster="prod",service="foo"} 0.5 @1234`,
+		`metadata counter http_server_calls_total "calls"`,
+		`metadata histogram http_server_request_duration_seconds ""`,
+	}, requests)
+}
+
+func TestRemoteWrite_UnrecoverableError(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "some_gauge"})
+	reg.MustRegister(gauge)
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		attempts++
+		assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+		rw.WriteHeader(http.StatusBadRequest)
+		_, _ = rw.Write([]byte("out of order sample"))
+	}))
+	defer server.Close()
+
+	rw := NewRemoteWriter(&RemoteWriteConfig{
+		URL:          server.URL,
+		MaxRetries:   3,
+		RetryBackoff: time.Millisecond,
+		BearerToken:  "token",
+	}, reg)
+	err := rw.Write(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of order sample")
+	// 4xx errors are not retried
+	assert.Equal(t, 1, attempts)
+}
+
+// decodeWriteRequest returns a textual representation of the series and metadata of a WriteRequest
+func decodeWriteRequest(t *testing.T, buf []byte) []string {
+	var out []string
+	forEachField(t, buf, func(num protowire.Number, field []byte, _ uint64) {
+		switch num {
+		case 1: // TimeSeries
+			var name, sample string
+			var labels []string
+			forEachField(t, field, func(num protowire.Number, field []byte, _ uint64) {
+				switch num {
+				case 1: // Label
+					var lbl [3]string
+					forEachField(t, field, func(num protowire.Number, field []byte, _ uint64) {
+						lbl[num] = string(field)
+					})
+					if lbl[1] == "__name__" {
+						name = lbl[2]
+					} else {
+						labels = append(labels, lbl[1]+`="`+lbl[2]+`"`)
+					}
+				case 2: // Sample
+					var value float64
+					var ts int64
+					forEachField(t, field, func(num protowire.Number, _ []byte, v uint64) {
+						if num == 1 {
+							value = math.Float64frombits(v)
+						} else {
+							ts = int64(v)
+						}
+					})
+					sample = fmt.Sprintf("%v @%d", value, ts)
+				}
+			})
+			out = append(out, name+"{"+strings.Join(labels, ",")+"} "+sample)
+		case 3: // MetricMetadata
+			var fields [5]string
+			forEachField(t, field, func(num protowire.Number, field []byte, v uint64) {
+				if num == 1 {
+					fields[1] = rwMetricTypeNames[v]
+				} else {
+					fields[num] = string(field)
+				}
+			})
+			out = append(out, "metadata "+fields[1]+" "+fields[2]+` "`+fields[4]+`"`)
+		}
+	})
+	return out
+}
+
+var rwMetricTypeNames = map[uint64]string{
+	rwMetricTypeUnknown: "unknown", rwMetricTypeCounter: "counter", rwMetricTypeGauge: "gauge",
+	rwMetricTypeHistogram: "histogram", rwMetricTypeSummary: "summary",
+}
+
+// forEachField invokes the provided function for each field of a protobuf message, with
+// the contents of the length-delimited fields or the value of the numeric fields
+func forEachField(t *testing.T, buf []byte, fn func(num protowire.Number, field []byte, value uint64)) {
+	for len(buf) > 0 {
+		num, typ, n := protowire.ConsumeTag(buf)
+		require.Positive(t, n)
+		buf = buf[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(buf)
+			require.Positive(t, n)
+			fn(num, v, 0)
+			buf = buf[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(buf)
+			require.Positive(t, n)
+			fn(num, nil, v)
+			buf = buf[n:]
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(buf)
+			require.Positive(t, n)
+			fn(num, nil, v)
+			buf = buf[n:]
+		default:
+			require.Failf(t, "unexpected wire type", "%v", typ)
+		}
+	}
+}
+
+func TestRemoteWriteConfig_Validate(t *testing.T) {
+	valid := DefaultRemoteWriteConfig
+	valid.URL = "http://mimir:9009/api/v1/push"
+	require.NoError(t, valid.Validate())
+
+	// the configuration is not validated if the endpoint is disabled
+	disabled := RemoteWriteConfig{}
+	require.NoError(t, disabled.Validate())
+
+	for _, modify := range []func(c *RemoteWriteConfig){
+		func(c *RemoteWriteConfig) { c.Interval = 0 },
+		func(c *RemoteWriteConfig) { c.Interval = -time.Second },
+		func(c *RemoteWriteConfig) { c.Timeout = -time.Second },
+		func(c *RemoteWriteConfig) { c.RetryBackoff = 0 },
+	} {
+		cfg := valid
+		modify(&cfg)
+		assert.Error(t, cfg.Validate())
+	}
+
+	// without retries, the backoff is not used
+	noRetries := valid
+	noRetries.MaxRetries, noRetries.RetryBackoff = 0, 0
+	assert.NoError(t, noRetries.Validate())
+}
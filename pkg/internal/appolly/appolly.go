@@ -5,12 +5,15 @@ package appolly
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 
 	"github.com/grafana/beyla/pkg/beyla"
 	"github.com/grafana/beyla/pkg/internal/discover"
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
 	"github.com/grafana/beyla/pkg/internal/pipe"
 	"github.com/grafana/beyla/pkg/internal/pipe/global"
+	"github.com/grafana/beyla/pkg/internal/profile"
 	"github.com/grafana/beyla/pkg/internal/request"
 	"github.com/grafana/beyla/pkg/internal/transform/kube"
 )
@@ -52,6 +55,9 @@ func (i *Instrumenter) FindAndInstrument() error {
 	if err != nil {
 		return fmt.Errorf("couldn't start Process Finder: %w", err)
 	}
+	if i.ctxInfo.AppO11y.Profiler != nil {
+		go i.ctxInfo.AppO11y.Profiler.Run(i.ctx)
+	}
 	// In background, listen indefinitely for each new process and run its
 	// associated ebpf.ProcessTracer once it is found.
 	go func() {
@@ -111,9 +117,43 @@ func (i *Instrumenter) ReadAndForward() error {
 	return nil
 }
 
+// Replay forwards to the instrumentation pipeline the spans from a capture of raw eBPF events,
+// instead of instrumenting the processes of the host. It returns when the capture has been
+// completely processed by the pipeline, or when the context is cancelled.
+func (i *Instrumenter) Replay(capture io.Reader) error {
+	log := log()
+	log.Debug("creating instrumentation pipeline")
+
+	bp, err := pipe.Build(i.ctx, i.config, i.ctxInfo, i.tracesInput)
+	if err != nil {
+		return fmt.Errorf("can't instantiate instrumentation pipeline: %w", err)
+	}
+	pipelineDone := make(chan struct{})
+	go func() {
+		bp.Run(i.ctx)
+		close(pipelineDone)
+	}()
+
+	err = ebpfcommon.ReplayCapture(i.ctx, &i.config.EBPF, capture, i.tracesInput)
+	// closing the input lets the pipeline end after processing all the replayed spans
+	close(i.tracesInput)
+	if err != nil {
+		return fmt.Errorf("replaying capture: %w", err)
+	}
+	log.Info("capture replayed. Waiting for the pipeline to end")
+	select {
+	case <-pipelineDone:
+	case <-i.ctx.Done():
+	}
+	return nil
+}
+
 func setupFeatureContextInfo(ctx context.Context, ctxInfo *global.ContextInfo, config *beyla.Config) {
 	ctxInfo.AppO11y.ReportRoutes = config.Routes != nil
 	setupKubernetes(ctx, ctxInfo)
+	if config.Profiling.Enabled {
+		ctxInfo.AppO11y.Profiler = profile.NewProfiler(&config.Profiling, ctxInfo.HostID, config.Discovery.SystemWide)
+	}
 }
 
 // setupKubernetes sets up common Kubernetes database and API clients that need to be accessed
@@ -42,6 +42,14 @@ func (pm *PrometheusManager) InstrumentWith(ii internalIntrumenter) {
 func (pm *PrometheusManager) Register(port int, path string, collectors ...prometheus.Collector) {
 	log().Debug("registering Prometheus metrics collectors",
 		"len", len(collectors), "port", port, "path", path)
+	pm.Registry(port, path).MustRegister(collectors...)
+}
+
+// Registry returns the registry of the collectors that are exposed through the provided port and path,
+// creating it if it didn't exist. Port 0 can be used for collectors that must not be exposed
+// through HTTP (e.g. because they are only pushed to a remote endpoint).
+// This method is not thread-safe
+func (pm *PrometheusManager) Registry(port int, path string) *prometheus.Registry {
 	if pm.registries == nil {
 		pm.registries = map[int]map[string]*prometheus.Registry{}
 	}
@@ -55,7 +63,7 @@ func (pm *PrometheusManager) Register(port int, path string, collectors ...prome
 		reg = prometheus.NewRegistry()
 		paths[path] = reg
 	}
-	reg.MustRegister(collectors...)
+	return reg
 }
 
 // StartHTTP serves metrics in background. Its invocation won't have effect if it has been invoked previously,
@@ -67,6 +75,9 @@ func (pm *PrometheusManager) StartHTTP(ctx context.Context) {
 	log := log()
 	// Creating a serve mux for each port
 	for port, paths := range pm.registries {
+		if port == 0 {
+			continue
+		}
 		mux := http.NewServeMux()
 		for path, registry := range paths {
 			log.With("port", port, "path", path).Info("opening prometheus scrape endpoint")
@@ -11,9 +11,11 @@ import (
 
 	"github.com/grafana/beyla/pkg/beyla"
 	"github.com/grafana/beyla/pkg/internal/ebpf"
+	"github.com/grafana/beyla/pkg/internal/ebpf/tcphealth"
 	"github.com/grafana/beyla/pkg/internal/goexec"
 	"github.com/grafana/beyla/pkg/internal/helpers/maps"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
+	"github.com/grafana/beyla/pkg/internal/profile"
 	"github.com/grafana/beyla/pkg/internal/svc"
 )
 
@@ -27,9 +29,14 @@ type TraceAttacher struct {
 	DiscoveredTracers chan *ebpf.ProcessTracer
 	DeleteTracers     chan *Instrumentable
 	Metrics           imetrics.Reporter
+	Profiler          *profile.Profiler // optional. Nil if profiling is disabled
 	pinPath           string
 	beylaPID          int
 
+	// tcpHealth accounts the health of the TCP connections of the instrumented processes.
+	// Nil if the TCP connection metrics are disabled or the tracer couldn't be loaded.
+	tcpHealth *tcphealth.Tracer
+
 	// processInstances keeps track of the instances of each process. This will help making sure
 	// that we don't remove the BPF resources of an executable until all their instances are removed
 	// are stopped
@@ -96,6 +103,7 @@ func (ta *TraceAttacher) getTracer(ie *Instrumentable) (*ebpf.ProcessTracer, boo
 		ie.FileInfo.Service.SDKLanguage = ie.Type
 		// allowing the tracer to forward traces from the new PID and its children processes
 		monitorPIDs(tracer, ie)
+		ta.profilePIDs(ie)
 		ta.Metrics.InstrumentProcess(ie.FileInfo.ExecutableName())
 		if tracer.Type == ebpf.Generic {
 			monitorPIDs(ta.reusableTracer, ie)
@@ -171,6 +179,7 @@ func (ta *TraceAttacher) getTracer(ie *Instrumentable) (*ebpf.ProcessTracer, boo
 		"exec", ie.FileInfo.CmdExePath)
 	// allowing the tracer to forward traces from the discovered PID and its children processes
 	monitorPIDs(tracer, ie)
+	ta.profilePIDs(ie)
 	ta.existingTracers[ie.FileInfo.Ino] = tracer
 	if tracer.Type == ebpf.Generic {
 		if ta.reusableTracer != nil {
@@ -210,6 +219,35 @@ func monitorPIDs(tracer *ebpf.ProcessTracer, ie *Instrumentable) {
 	}
 }
 
+// pidFilter is implemented by the host-wide components that only account the
+// instrumented processes
+type pidFilter interface {
+	AllowPID(pid, ns uint32, id svc.ID)
+	BlockPID(pid, ns uint32)
+}
+
+// hostTracers returns the enabled host-wide components that need to know which
+// processes are instrumented
+func (ta *TraceAttacher) hostTracers() []pidFilter {
+	var filters []pidFilter
+	if ta.Profiler != nil {
+		filters = append(filters, ta.Profiler)
+	}
+	if ta.tcpHealth != nil {
+		filters = append(filters, ta.tcpHealth)
+	}
+	return filters
+}
+
+func (ta *TraceAttacher) profilePIDs(ie *Instrumentable) {
+	for _, t := range ta.hostTracers() {
+		t.AllowPID(uint32(ie.FileInfo.Pid), ie.FileInfo.Ns, ie.FileInfo.Service)
+		for _, pid := range ie.ChildPids {
+			t.AllowPID(pid, ie.FileInfo.Ns, ie.FileInfo.Service)
+		}
+	}
+}
+
 // BuildPinPath pinpath must be unique for a given executable group
 // it will be:
 //   - current beyla PID
@@ -227,6 +265,9 @@ func (ta *TraceAttacher) notifyProcessDeletion(ie *Instrumentable) {
 		// unless explicitly allowed
 		ta.Metrics.UninstrumentProcess(ie.FileInfo.ExecutableName())
 		tracer.BlockPID(uint32(ie.FileInfo.Pid), ie.FileInfo.Ns)
+		for _, t := range ta.hostTracers() {
+			t.BlockPID(uint32(ie.FileInfo.Pid), ie.FileInfo.Ns)
+		}
 
 		// if there are no more trace instances for a Go program, we need to notify that
 		// the tracer needs to be stopped and deleted.
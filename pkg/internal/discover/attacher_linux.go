@@ -7,6 +7,8 @@ import (
 	"github.com/cilium/ebpf/rlimit"
 	"golang.org/x/sys/unix"
 
+	"github.com/grafana/beyla/pkg/internal/ebpf"
+	"github.com/grafana/beyla/pkg/internal/ebpf/tcphealth"
 	"github.com/grafana/beyla/pkg/internal/helpers"
 )
 
@@ -71,5 +73,20 @@ func (ta *TraceAttacher) init() error {
 	if err := ta.mountBpfPinPath(); err != nil {
 		return fmt.Errorf("can't mount BPF filesystem: %w", err)
 	}
+	if ta.Cfg.TCPConnectionMetricsEnabled() {
+		ta.loadTCPHealthTracer()
+	}
 	return nil
 }
+
+// loadTCPHealthTracer loads the host-wide tracer of the TCP connection metrics. If it can't be
+// loaded, only the TCP connection metrics are disabled.
+func (ta *TraceAttacher) loadTCPHealthTracer() {
+	tracer := tcphealth.New(ta.Cfg.Discovery.SystemWide)
+	if err := ebpf.RunUtilityTracer(tracer, ta.pinPath); err != nil {
+		ta.log.Warn("can't load the TCP connection health tracer. TCP connection metrics won't be reported",
+			"error", err)
+		return
+	}
+	ta.tcpHealth = tracer
+}
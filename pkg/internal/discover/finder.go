@@ -84,6 +84,7 @@ func (pf *ProcessFinder) Start() (<-chan *ebpf.ProcessTracer, <-chan *Instrument
 		DiscoveredTracers: discoveredTracers,
 		DeleteTracers:     deleteTracers,
 		Metrics:           pf.ctxInfo.Metrics,
+		Profiler:          pf.ctxInfo.AppO11y.Profiler,
 	}))
 	pipeline, err := gb.Build()
 	if err != nil {
@@ -98,7 +99,7 @@ func (pf *ProcessFinder) Start() (<-chan *ebpf.ProcessTracer, <-chan *Instrument
 
 func newGoTracersGroup(cfg *beyla.Config, metrics imetrics.Reporter) []ebpf.Tracer {
 	// Each program is an eBPF source: net/http, grpc...
-	return []ebpf.Tracer{
+	tracers := []ebpf.Tracer{
 		nethttp.New(cfg, metrics),
 		grpc.New(cfg, metrics),
 		goruntime.New(cfg, metrics),
@@ -107,6 +108,10 @@ func newGoTracersGroup(cfg *beyla.Config, metrics imetrics.Reporter) []ebpf.Trac
 		goredis.New(cfg, metrics),
 		kafkago.New(cfg, metrics),
 	}
+	if cfg.GoRuntimeMetricsEnabled() {
+		tracers = append(tracers, goruntime.NewMetricsTracer(cfg.GoSchedulerMetricsEnabled()))
+	}
+	return tracers
 }
 
 func newNonGoTracersGroup(cfg *beyla.Config, metrics imetrics.Reporter) []ebpf.Tracer {
@@ -16,6 +16,10 @@ import (
 )
 
 // CriteriaMatcherProvider filters the processes that match the discovery criteria.
+// If the configuration hot reload is enabled, the discovery criteria is replaced each time
+// the configuration changes, the already matched processes that don't match the
+// new criteria are notified as deleted, and the previously rejected processes that match
+// the new criteria are notified as created.
 func CriteriaMatcherProvider(cfg *beyla.Config) pipe.MiddleProvider[[]Event[processAttrs], []Event[ProcessMatch]] {
 	return func() (pipe.MiddleFunc[[]Event[processAttrs], []Event[ProcessMatch]], error) {
 		m := &matcher{
@@ -23,6 +27,11 @@ func CriteriaMatcherProvider(cfg *beyla.Config) pipe.MiddleProvider[[]Event[proc
 			criteria:        FindingCriteria(cfg),
 			excludeCriteria: cfg.Discovery.ExcludeServices,
 			processHistory:  map[PID]*services.ProcessInfo{},
+			attrsHistory:    map[PID]processAttrs{},
+			updates:         beyla.SubscribeReloads(cfg.Reloads, func(c *beyla.Config) *beyla.Config { return c }),
+		}
+		if m.updates != nil {
+			m.rejected = map[PID]processAttrs{}
 		}
 		return m.run, nil
 	}
@@ -36,6 +45,15 @@ type matcher struct {
 	// instrumentation.
 	// This avoids keep inspecting again and again client processes each time they open a new connection port
 	processHistory map[PID]*services.ProcessInfo
+	// attrsHistory stores the attributes of the processes in processHistory, to check them again
+	// when the discovery criteria is reloaded
+	attrsHistory map[PID]processAttrs
+	// rejected stores the attributes of the processes that did not match the discovery criteria,
+	// to check them again when the criteria is reloaded. The process watcher also notifies again
+	// all the processes after a reload, but they could arrive before the new criteria is received.
+	// It is nil if the configuration hot reload is disabled.
+	rejected map[PID]processAttrs
+	updates  <-chan *beyla.Config
 }
 
 // ProcessMatch matches a found process with the first selection criteria it fulfilled.
@@ -46,16 +64,94 @@ type ProcessMatch struct {
 
 func (m *matcher) run(in <-chan []Event[processAttrs], out chan<- []Event[ProcessMatch]) {
 	m.log.Debug("starting criteria matcher node")
-	for i := range in {
-		m.log.Debug("filtering processes", "len", len(i))
-		o := m.filter(i)
-		m.log.Debug("processes matching selection criteria", "len", len(o))
-		if len(o) > 0 {
-			out <- o
+	for {
+		select {
+		case cfg := <-m.updates:
+			m.log.Debug("reloading discovery criteria")
+			if o := m.reload(cfg); len(o) > 0 {
+				m.log.Debug("processes not matching the new selection criteria", "len", len(o))
+				out <- o
+			}
+		case i, ok := <-in:
+			if !ok {
+				return
+			}
+			m.log.Debug("filtering processes", "len", len(i))
+			o := m.filter(i)
+			m.log.Debug("processes matching selection criteria", "len", len(o))
+			if len(o) > 0 {
+				out <- o
+			}
 		}
 	}
 }
 
+// reload replaces the discovery criteria and returns deletion events for the already matched processes
+// that do not match the new criteria, as well as creation events for the previously rejected processes
+// that match the new criteria. Processes that were matched because their parent was matched
+// are kept as long as their parent is kept.
+func (m *matcher) reload(cfg *beyla.Config) []Event[ProcessMatch] {
+	m.criteria = FindingCriteria(cfg)
+	m.excludeCriteria = cfg.Discovery.ExcludeServices
+	var unmatched []PID
+	for pid, proc := range m.processHistory {
+		attrs := m.attrsHistory[pid]
+		if !m.isExcluded(&attrs, proc) && m.matchesAny(&attrs, proc) {
+			continue
+		}
+		unmatched = append(unmatched, pid)
+	}
+	// removing first all the unmatched processes, so we can check below
+	// which children processes lost their parents
+	removed := map[PID]*services.ProcessInfo{}
+	for _, pid := range unmatched {
+		removed[pid] = m.processHistory[pid]
+		delete(m.processHistory, pid)
+	}
+	for pid, proc := range m.processHistory {
+		attrs := m.attrsHistory[pid]
+		if _, parentRemoved := removed[PID(proc.PPid)]; parentRemoved && !m.matchesAny(&attrs, proc) {
+			removed[pid] = proc
+			delete(m.processHistory, pid)
+		}
+	}
+	var events []Event[ProcessMatch]
+	// checking the rejected processes in PID order, as parents usually have lower PIDs than their children
+	rejected := make([]PID, 0, len(m.rejected))
+	for pid := range m.rejected {
+		rejected = append(rejected, pid)
+	}
+	slices.Sort(rejected)
+	for _, pid := range rejected {
+		attrs := m.rejected[pid]
+		delete(m.rejected, pid)
+		if ev, ok := m.filterCreated(attrs); ok {
+			events = append(events, ev)
+		}
+	}
+	for pid, proc := range removed {
+		if m.rejected != nil {
+			m.rejected[pid] = m.attrsHistory[pid]
+		}
+		delete(m.attrsHistory, pid)
+		m.log.Debug("process does not match the new discovery criteria", "pid", pid, "comm", proc.ExePath)
+		events = append(events, Event[ProcessMatch]{
+			Type: EventDeleted,
+			Obj:  ProcessMatch{Process: proc},
+		})
+	}
+	return events
+}
+
+func (m *matcher) matchesAny(obj *processAttrs, proc *services.ProcessInfo) bool {
+	for i := range m.criteria {
+		if m.matchProcess(obj, proc, &m.criteria[i]) {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *matcher) filter(events []Event[processAttrs]) []Event[ProcessMatch] {
 	var matches []Event[ProcessMatch]
 	for _, ev := range events {
@@ -86,6 +182,8 @@ func (m *matcher) filterCreated(obj processAttrs) (Event[ProcessMatch], bool) {
 		if m.matchProcess(&obj, proc, &m.criteria[i]) && !m.isExcluded(&obj, proc) {
 			m.log.Debug("found process", "pid", proc.Pid, "comm", proc.ExePath, "metadata", obj.metadata, "podLabels", obj.podLabels)
 			m.processHistory[obj.pid] = proc
+			m.attrsHistory[obj.pid] = obj
+			delete(m.rejected, obj.pid)
 			return Event[ProcessMatch]{
 				Type: EventCreated,
 				Obj:  ProcessMatch{Criteria: &m.criteria[i], Process: proc},
@@ -94,25 +192,32 @@ func (m *matcher) filterCreated(obj processAttrs) (Event[ProcessMatch], bool) {
 	}
 
 	// We didn't match the process, but let's see if the parent PID is tracked, it might be the child hasn't opened the port yet
-	if _, ok := m.processHistory[PID(proc.PPid)]; ok {
+	if _, ok := m.processHistory[PID(proc.PPid)]; ok && len(m.criteria) > 0 {
 		m.log.Debug("found process by matching the process parent id", "pid", proc.Pid, "ppid", proc.PPid, "comm", proc.ExePath, "metadata", obj.metadata)
 		m.processHistory[obj.pid] = proc
+		m.attrsHistory[obj.pid] = obj
+		delete(m.rejected, obj.pid)
 		return Event[ProcessMatch]{
 			Type: EventCreated,
 			Obj:  ProcessMatch{Criteria: &m.criteria[0], Process: proc},
 		}, true
 	}
 
+	if m.rejected != nil {
+		m.rejected[obj.pid] = obj
+	}
 	return Event[ProcessMatch]{}, false
 }
 
 func (m *matcher) filterDeleted(obj processAttrs) (Event[ProcessMatch], bool) {
+	delete(m.rejected, obj.pid)
 	proc, ok := m.processHistory[obj.pid]
 	if !ok {
 		m.log.Debug("deleted untracked process. Ignoring", "pid", obj.pid)
 		return Event[ProcessMatch]{}, false
 	}
 	delete(m.processHistory, obj.pid)
+	delete(m.attrsHistory, obj.pid)
 	m.log.Debug("stopped process", "pid", proc.Pid, "comm", proc.ExePath)
 	return Event[ProcessMatch]{
 		Type: EventDeleted,
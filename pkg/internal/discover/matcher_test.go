@@ -1,6 +1,7 @@
 package discover
 
 import (
+	"log/slog"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -275,3 +276,138 @@ func TestCriteriaMatcherMissingPort(t *testing.T) {
 	assert.Equal(t, "foo", m.Obj.Criteria.Namespace)
 	assert.Equal(t, services.ProcessInfo{Pid: 3, ExePath: "/bin/weird33", OpenPorts: []uint32{}, PPid: 1}, *m.Obj.Process)
 }
+
+func TestCriteriaMatcher_Reload(t *testing.T) {
+	pipeConfig := beyla.Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`discovery:
+  services:
+  - name: port-only
+    open_ports: 80
+  - name: exec-only
+    exe_path: weird
+`), &pipeConfig))
+
+	updates := make(chan *beyla.Config, 1)
+	m := &matcher{
+		log:            slog.With("component", "discover.CriteriaMatcher"),
+		criteria:       FindingCriteria(&pipeConfig),
+		processHistory: map[PID]*services.ProcessInfo{},
+		attrsHistory:   map[PID]processAttrs{},
+		updates:        updates,
+	}
+	discoveredProcesses := make(chan []Event[processAttrs], 10)
+	filteredProcesses := make(chan []Event[ProcessMatch], 10)
+	go m.run(discoveredProcesses, filteredProcesses)
+	defer close(discoveredProcesses)
+
+	processInfo = func(pp processAttrs) (*services.ProcessInfo, error) {
+		proc := map[PID]struct {
+			Exe  string
+			PPid int32
+		}{
+			1: {Exe: "/bin/server"}, 2: {Exe: "/bin/server", PPid: 1}, 3: {Exe: "/bin/weird"}}[pp.pid]
+		return &services.ProcessInfo{Pid: int32(pp.pid), ExePath: proc.Exe, PPid: proc.PPid, OpenPorts: pp.openPorts}, nil
+	}
+	discoveredProcesses <- []Event[processAttrs]{
+		{Type: EventCreated, Obj: processAttrs{pid: 1, openPorts: []uint32{80}}}, // matches by port
+		{Type: EventCreated, Obj: processAttrs{pid: 2}},                          // matches by parent
+		{Type: EventCreated, Obj: processAttrs{pid: 3}},                          // matches by executable
+	}
+	matches := testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	require.Len(t, matches, 3)
+
+	// after reloading, the processes that don't match the new criteria are notified as deleted
+	newConfig := beyla.Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`discovery:
+  services:
+  - name: exec-only
+    exe_path: weird
+`), &newConfig))
+	updates <- &newConfig
+
+	matches = testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	require.Len(t, matches, 2)
+	deleted := map[int32]WatchEventType{}
+	for _, m := range matches {
+		deleted[m.Obj.Process.Pid] = m.Type
+	}
+	assert.Equal(t, map[int32]WatchEventType{1: EventDeleted, 2: EventDeleted}, deleted)
+
+	// new processes are matched according to the new criteria
+	discoveredProcesses <- []Event[processAttrs]{
+		{Type: EventCreated, Obj: processAttrs{pid: 1, openPorts: []uint32{80}}},
+	}
+	discoveredProcesses <- []Event[processAttrs]{
+		{Type: EventDeleted, Obj: processAttrs{pid: 3}},
+	}
+	matches = testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	require.Len(t, matches, 1)
+	assert.Equal(t, EventDeleted, matches[0].Type)
+	assert.Equal(t, int32(3), matches[0].Obj.Process.Pid)
+}
+
+func TestCriteriaMatcher_Reload_ProcessesBeforeCriteria(t *testing.T) {
+	pipeConfig := beyla.Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`discovery:
+  services:
+  - name: port-only
+    open_ports: 80
+`), &pipeConfig))
+
+	updates := make(chan *beyla.Config, 1)
+	m := &matcher{
+		log:            slog.With("component", "discover.CriteriaMatcher"),
+		criteria:       FindingCriteria(&pipeConfig),
+		processHistory: map[PID]*services.ProcessInfo{},
+		attrsHistory:   map[PID]processAttrs{},
+		rejected:       map[PID]processAttrs{},
+		updates:        updates,
+	}
+	discoveredProcesses := make(chan []Event[processAttrs], 10)
+	filteredProcesses := make(chan []Event[ProcessMatch], 10)
+	go m.run(discoveredProcesses, filteredProcesses)
+	defer close(discoveredProcesses)
+
+	processInfo = func(pp processAttrs) (*services.ProcessInfo, error) {
+		exe := map[PID]string{1: "/bin/server", 2: "/bin/weird", 3: "/bin/weird"}[pp.pid]
+		return &services.ProcessInfo{Pid: int32(pp.pid), ExePath: exe, OpenPorts: pp.openPorts}, nil
+	}
+	// the process watcher re-lists all the processes after a reload, but the matcher
+	// receives them before the new criteria, so they are rejected
+	discoveredProcesses <- []Event[processAttrs]{
+		{Type: EventCreated, Obj: processAttrs{pid: 1, openPorts: []uint32{80}}},
+		{Type: EventCreated, Obj: processAttrs{pid: 2}},
+		{Type: EventCreated, Obj: processAttrs{pid: 3}},
+		{Type: EventDeleted, Obj: processAttrs{pid: 3}},
+	}
+	matches := testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	require.Len(t, matches, 1)
+	assert.Equal(t, int32(1), matches[0].Obj.Process.Pid)
+
+	newConfig := beyla.Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`discovery:
+  services:
+  - name: exec-only
+    exe_path: weird
+`), &newConfig))
+	updates <- &newConfig
+
+	// the previously rejected processes that are still running are checked against the new criteria
+	matches = testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	require.Len(t, matches, 2)
+	events := map[int32]WatchEventType{}
+	for _, m := range matches {
+		events[m.Obj.Process.Pid] = m.Type
+	}
+	assert.Equal(t, map[int32]WatchEventType{1: EventDeleted, 2: EventCreated}, events)
+
+	// the process that was matched before, and now is rejected, is matched again if the
+	// criteria is reverted
+	updates <- &pipeConfig
+	matches = testutil.ReadChannel(t, filteredProcesses, testTimeout)
+	events = map[int32]WatchEventType{}
+	for _, m := range matches {
+		events[m.Obj.Process.Pid] = m.Type
+	}
+	assert.Equal(t, map[int32]WatchEventType{1: EventCreated, 2: EventDeleted}, events)
+}
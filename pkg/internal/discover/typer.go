@@ -2,12 +2,14 @@ package discover
 
 import (
 	"log/slog"
+	"maps"
 	"strings"
 
 	lru "github.com/hashicorp/golang-lru/v2"
 	"github.com/mariomac/pipes/pipe"
 
 	"github.com/grafana/beyla/pkg/beyla"
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
 	"github.com/grafana/beyla/pkg/internal/exec"
 	"github.com/grafana/beyla/pkg/internal/goexec"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
@@ -32,6 +34,8 @@ type InstrumentedExecutable struct {
 	Type                 svc.InstrumentableType
 	Offsets              *goexec.Offsets
 	InstrumentationError error
+	RuntimeName          string
+	RuntimeVersion       string
 }
 
 // ExecTyperProvider classifies the discovered executables according to the
@@ -117,6 +121,8 @@ func (t *typer) asInstrumentable(execElf *exec.FileInfo) Instrumentable {
 	log := t.log.With("pid", execElf.Pid, "comm", execElf.CmdExePath)
 	if ic, ok := instrumentableCache.Get(execElf.Ino); ok {
 		log.Debug("new instance of existing executable", "type", ic.Type)
+		execElf.Service.RuntimeName, execElf.Service.RuntimeVersion = ic.RuntimeName, ic.RuntimeVersion
+		t.setBuildInfo(execElf, ic.Offsets)
 		return Instrumentable{Type: ic.Type, FileInfo: execElf, Offsets: ic.Offsets, InstrumentationError: ic.InstrumentationError}
 	}
 
@@ -127,7 +133,10 @@ func (t *typer) asInstrumentable(execElf *exec.FileInfo) Instrumentable {
 		// we found go offsets, let's see if this application is not a proxy
 		if !isGoProxy(offsets) {
 			log.Debug("identified as a Go service or client")
-			instrumentableCache.Add(execElf.Ino, InstrumentedExecutable{Type: svc.InstrumentableGolang, Offsets: offsets})
+			t.setRuntime(execElf, svc.InstrumentableGolang, offsets)
+			t.setBuildInfo(execElf, offsets)
+			instrumentableCache.Add(execElf.Ino, InstrumentedExecutable{Type: svc.InstrumentableGolang, Offsets: offsets,
+				RuntimeName: execElf.Service.RuntimeName, RuntimeVersion: execElf.Service.RuntimeVersion})
 			return Instrumentable{Type: svc.InstrumentableGolang, FileInfo: execElf, Offsets: offsets}
 		}
 		log.Debug("identified as a Go proxy")
@@ -152,14 +161,49 @@ func (t *typer) asInstrumentable(execElf *exec.FileInfo) Instrumentable {
 
 	detectedType := exec.FindProcLanguage(execElf.Pid, execElf.ELF, execElf.CmdExePath)
 
+	t.setRuntime(execElf, detectedType, offsets)
+	t.setBuildInfo(execElf, offsets)
+
 	log.Debug("instrumented", "comm", execElf.CmdExePath, "pid", execElf.Pid,
 		"child", child, "language", detectedType.String())
 	// Return the instrumentable without offsets, as it is identified as a generic
 	// (or non-instrumentable Go proxy) executable
-	instrumentableCache.Add(execElf.Ino, InstrumentedExecutable{Type: detectedType, Offsets: offsets, InstrumentationError: err})
+	instrumentableCache.Add(execElf.Ino, InstrumentedExecutable{Type: detectedType, Offsets: offsets, InstrumentationError: err,
+		RuntimeName: execElf.Service.RuntimeName, RuntimeVersion: execElf.Service.RuntimeVersion})
 	return Instrumentable{Type: detectedType, FileInfo: execElf, ChildPids: child, InstrumentationError: err}
 }
 
+// setRuntime identifies the name and version of the runtime of the executable, to
+// report them as resource attributes of the service. For Go executables, the version
+// is taken from the already inspected build info, to avoid parsing the executable again.
+func (t *typer) setRuntime(execElf *exec.FileInfo, lang svc.InstrumentableType, offsets *goexec.Offsets) {
+	if lang == svc.InstrumentableGolang && offsets != nil && offsets.BuildInfo != nil {
+		execElf.Service.RuntimeName, execElf.Service.RuntimeVersion = exec.GoRuntime(offsets.BuildInfo.GoVersion)
+	} else {
+		execElf.Service.RuntimeName, execElf.Service.RuntimeVersion = exec.FindProcRuntime(execElf.Pid, lang)
+	}
+	t.log.Debug("identified runtime", "pid", execElf.Pid,
+		"runtime", execElf.Service.RuntimeName, "version", execElf.Service.RuntimeVersion)
+}
+
+// setBuildInfo adds the build information of Go executables (module path and version,
+// VCS revision) to the service metadata, and optionally names the service after its module
+func (t *typer) setBuildInfo(execElf *exec.FileInfo, offsets *goexec.Offsets) {
+	if offsets == nil || offsets.BuildInfo == nil {
+		return
+	}
+	// the metadata map is shared with other components, so it's replaced instead of modified
+	md := maps.Clone(execElf.Service.Metadata)
+	if md == nil {
+		md = map[attr.Name]string{}
+	}
+	maps.Copy(md, goexec.BuildInfoMetadata(offsets.BuildInfo))
+	execElf.Service.Metadata = md
+	if t.cfg.Discovery.ServiceNameFromGoModule && execElf.Service.Name == "" {
+		execElf.Service.Name = goexec.ModuleServiceName(offsets.BuildInfo)
+	}
+}
+
 func (t *typer) inspectOffsets(execElf *exec.FileInfo) (*goexec.Offsets, bool, error) {
 	if !t.cfg.Discovery.SystemWide {
 		if t.cfg.Discovery.SkipGoSpecificTracers {
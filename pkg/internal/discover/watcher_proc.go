@@ -67,6 +67,7 @@ func ProcessWatcherFunc(ctx context.Context, cfg *beyla.Config) pipe.StartFunc[[
 		bpfWatcherEnabled: false, // async set by listening on the bpfWatchEvents channel
 		stateMux:          sync.Mutex{},
 		findingCriteria:   FindingCriteria(cfg),
+		updates:           beyla.SubscribeReloads(cfg.Reloads, FindingCriteria),
 	}
 	if acc.interval == 0 {
 		acc.interval = defaultPollInterval
@@ -103,6 +104,10 @@ type pollAccounter struct {
 	bpfWatcherEnabled bool
 	fetchPorts        bool
 	findingCriteria   services.DefinitionCriteria
+	// updates of the finding criteria, when the configuration is reloaded
+	updates <-chan services.DefinitionCriteria
+	// processes from the snapshot that was discarded after the last reload
+	forgottenPids map[PID]processAttrs
 }
 
 func (pa *pollAccounter) Run(out chan<- []Event[processAttrs]) {
@@ -135,12 +140,38 @@ func (pa *pollAccounter) Run(out chan<- []Event[processAttrs]) {
 		case <-pa.ctx.Done():
 			log.Debug("context canceled. Exiting")
 			return
+		case criteria := <-pa.updates:
+			log.Debug("discovery criteria reloaded. Checking again all the processes")
+			pa.reload(criteria)
 		case <-time.After(pa.interval):
 			// poll event starting again
 		}
 	}
 }
 
+// reload replaces the finding criteria and forgets the previous snapshot, so all the
+// running processes are notified again to be checked against the new criteria
+func (pa *pollAccounter) reload(criteria services.DefinitionCriteria) {
+	pa.stateMux.Lock()
+	defer pa.stateMux.Unlock()
+	pa.findingCriteria = criteria
+	pa.fetchPorts = true
+	// keeping the forgotten processes to notify their deletion if they ended before the next poll
+	if pa.forgottenPids == nil {
+		pa.forgottenPids = pa.pids
+	} else {
+		maps.Copy(pa.forgottenPids, pa.pids)
+	}
+	pa.pids = map[PID]processAttrs{}
+	pa.pidPorts = map[pidPort]processAttrs{}
+}
+
+func (pa *pollAccounter) portOfInterest(port int) bool {
+	pa.stateMux.Lock()
+	defer pa.stateMux.Unlock()
+	return pa.cfg.Port.Matches(port) || pa.findingCriteria.PortOfInterest(port)
+}
+
 func (pa *pollAccounter) bpfWatcherIsReady() {
 	pa.stateMux.Lock()
 	defer pa.stateMux.Unlock()
@@ -174,7 +205,7 @@ func (pa *pollAccounter) watchForProcessEvents(log *slog.Logger, events <-chan w
 			pa.bpfWatcherIsReady()
 		case watcher.NewPort:
 			port := int(e.Payload)
-			if pa.cfg.Port.Matches(port) || pa.findingCriteria.PortOfInterest(port) {
+			if pa.portOfInterest(port) {
 				pa.refetchPorts()
 			}
 		default:
@@ -214,6 +245,12 @@ func (pa *pollAccounter) snapshot(fetchedProcs map[PID]processAttrs) []Event[pro
 			events = append(events, Event[processAttrs]{Type: EventDeleted, Obj: proc})
 		}
 	}
+	for pid, proc := range pa.forgottenPids {
+		if _, ok := fetchedProcs[pid]; !ok {
+			events = append(events, Event[processAttrs]{Type: EventDeleted, Obj: proc})
+		}
+	}
+	pa.forgottenPids = nil
 
 	currentProcs := maps.Clone(fetchedProcs)
 
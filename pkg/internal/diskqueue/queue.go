@@ -0,0 +1,254 @@
+// Package diskqueue provides a FIFO queue of binary records that are persisted in a local directory,
+// so they can survive temporary outages of the remote endpoints as well as restarts of Beyla.
+package diskqueue
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func qlog() *slog.Logger {
+	return slog.With("component", "diskqueue.Queue")
+}
+
+const (
+	recordSuffix = ".rec"
+	tmpSuffix    = ".tmp"
+)
+
+// Config for the disk queue
+type Config struct {
+	// Dir where the records are stored. It is created if it does not exist.
+	Dir string
+	// MaxSize of the queue, in bytes. When it is exceeded, the oldest records are dropped.
+	// Zero means no limit.
+	MaxSize int64
+	// MaxAge of the records. Older records are dropped without being returned.
+	// Zero means no limit.
+	MaxAge time.Duration
+	// DepthChanged, if set, is invoked each time the number of queued records changes
+	DepthChanged func(depth int)
+	// Dropped, if set, is invoked each time some records are discarded because the
+	// size or age limits were exceeded, or because they couldn't be read.
+	Dropped func(bytes int)
+}
+
+// Queue stores each record in a different file, whose name contains a sequence number
+// and the creation timestamp of the record. This way the queue can be reconstructed
+// after a restart by just listing the directory.
+type Queue struct {
+	cfg Config
+	log *slog.Logger
+
+	mt      sync.Mutex
+	entries []entry
+	size    int64
+	nextSeq uint64
+
+	timeNow func() time.Time
+}
+
+type entry struct {
+	seq     uint64
+	created time.Time
+	size    int64
+}
+
+func (e *entry) fileName() string {
+	return fmt.Sprintf("%020d-%d%s", e.seq, e.created.UnixNano(), recordSuffix)
+}
+
+// Open a queue in the configured directory, loading any record that was previously stored there.
+func Open(cfg Config) (*Queue, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("disk queue directory can't be empty")
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating disk queue directory: %w", err)
+	}
+	q := &Queue{cfg: cfg, log: qlog().With("dir", cfg.Dir), timeNow: time.Now}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	q.mt.Lock()
+	defer q.mt.Unlock()
+	q.evict()
+	q.depthChanged()
+	return q, nil
+}
+
+func (q *Queue) load() error {
+	files, err := os.ReadDir(q.cfg.Dir)
+	if err != nil {
+		return fmt.Errorf("reading disk queue directory: %w", err)
+	}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() {
+			continue
+		}
+		// leftovers from a record that couldn't be completely written
+		if strings.HasSuffix(name, tmpSuffix) {
+			_ = os.Remove(filepath.Join(q.cfg.Dir, name))
+			continue
+		}
+		e, ok := parseFileName(name)
+		if !ok {
+			q.log.Debug("ignoring unknown file", "name", name)
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			q.log.Debug("can't get file info. Ignoring it", "name", name, "error", err)
+			continue
+		}
+		e.size = info.Size()
+		q.entries = append(q.entries, e)
+		q.size += e.size
+	}
+	sort.Slice(q.entries, func(i, j int) bool {
+		return q.entries[i].seq < q.entries[j].seq
+	})
+	if len(q.entries) > 0 {
+		q.nextSeq = q.entries[len(q.entries)-1].seq + 1
+		q.log.Info("loaded stored records", "records", len(q.entries), "bytes", q.size)
+	}
+	return nil
+}
+
+func parseFileName(name string) (entry, bool) {
+	seq, created, ok := strings.Cut(strings.TrimSuffix(name, recordSuffix), "-")
+	if !ok || !strings.HasSuffix(name, recordSuffix) {
+		return entry{}, false
+	}
+	s, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return entry{}, false
+	}
+	c, err := strconv.ParseInt(created, 10, 64)
+	if err != nil {
+		return entry{}, false
+	}
+	return entry{seq: s, created: time.Unix(0, c)}, true
+}
+
+// Len returns the number of queued records
+func (q *Queue) Len() int {
+	q.mt.Lock()
+	defer q.mt.Unlock()
+	return len(q.entries)
+}
+
+// Size returns the total size, in bytes, of the queued records
+func (q *Queue) Size() int64 {
+	q.mt.Lock()
+	defer q.mt.Unlock()
+	return q.size
+}
+
+// Push appends a record to the tail of the queue. If the maximum size is exceeded,
+// the oldest records are dropped.
+func (q *Queue) Push(record []byte) error {
+	q.mt.Lock()
+	defer q.mt.Unlock()
+	e := entry{seq: q.nextSeq, created: q.timeNow(), size: int64(len(record))}
+	if q.cfg.MaxSize > 0 && e.size > q.cfg.MaxSize {
+		q.dropped(e.size)
+		return fmt.Errorf("record of %d bytes exceeds the disk queue max size", e.size)
+	}
+	path := filepath.Join(q.cfg.Dir, e.fileName())
+	// write first into a temporary file, so a crash never leaves a partial record
+	if err := os.WriteFile(path+tmpSuffix, record, 0o600); err != nil {
+		_ = os.Remove(path + tmpSuffix)
+		return fmt.Errorf("writing disk queue record: %w", err)
+	}
+	if err := os.Rename(path+tmpSuffix, path); err != nil {
+		_ = os.Remove(path + tmpSuffix)
+		return fmt.Errorf("writing disk queue record: %w", err)
+	}
+	q.nextSeq++
+	q.entries = append(q.entries, e)
+	q.size += e.size
+	q.evict()
+	q.depthChanged()
+	return nil
+}
+
+// Peek returns the oldest record in the queue without removing it, as well as its sequence
+// number. Expired or unreadable records are dropped. It returns false if the queue is empty.
+func (q *Queue) Peek() ([]byte, uint64, bool) {
+	q.mt.Lock()
+	defer q.mt.Unlock()
+	q.evict()
+	for len(q.entries) > 0 {
+		record, err := os.ReadFile(filepath.Join(q.cfg.Dir, q.entries[0].fileName()))
+		if err == nil {
+			return record, q.entries[0].seq, true
+		}
+		q.log.Warn("can't read record. Dropping it", "error", err)
+		q.dropped(q.entries[0].size)
+		q.removeHead()
+		q.depthChanged()
+	}
+	return nil, 0, false
+}
+
+// Pop removes the oldest record from the queue, if its sequence number is the provided one.
+// Otherwise, the record returned by Peek was already dropped to make room for newer records.
+func (q *Queue) Pop(seq uint64) {
+	q.mt.Lock()
+	defer q.mt.Unlock()
+	if len(q.entries) == 0 || q.entries[0].seq != seq {
+		return
+	}
+	q.removeHead()
+	q.depthChanged()
+}
+
+// evict drops the oldest records until the queue fits into the size and age limits.
+// It must be invoked with the mutex locked.
+func (q *Queue) evict() {
+	expiry := time.Time{}
+	if q.cfg.MaxAge > 0 {
+		expiry = q.timeNow().Add(-q.cfg.MaxAge)
+	}
+	droppedBytes := int64(0)
+	for len(q.entries) > 0 &&
+		(q.cfg.MaxSize > 0 && q.size > q.cfg.MaxSize || q.entries[0].created.Before(expiry)) {
+		droppedBytes += q.entries[0].size
+		q.removeHead()
+	}
+	if droppedBytes > 0 {
+		q.log.Debug("disk queue limits exceeded. Dropped oldest records", "bytes", droppedBytes)
+		q.dropped(droppedBytes)
+	}
+}
+
+func (q *Queue) removeHead() {
+	head := q.entries[0]
+	if err := os.Remove(filepath.Join(q.cfg.Dir, head.fileName())); err != nil && !os.IsNotExist(err) {
+		q.log.Warn("can't remove record file", "error", err)
+	}
+	q.entries = q.entries[1:]
+	q.size -= head.size
+}
+
+func (q *Queue) depthChanged() {
+	if q.cfg.DepthChanged != nil {
+		q.cfg.DepthChanged(len(q.entries))
+	}
+}
+
+func (q *Queue) dropped(bytes int64) {
+	if q.cfg.Dropped != nil {
+		q.cfg.Dropped(int(bytes))
+	}
+}
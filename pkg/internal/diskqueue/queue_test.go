@@ -0,0 +1,118 @@
+package diskqueue
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue_FIFO(t *testing.T) {
+	dir := t.TempDir()
+	depth := 0
+	q, err := Open(Config{Dir: dir, DepthChanged: func(d int) { depth = d }})
+	require.NoError(t, err)
+
+	for _, r := range []string{"one", "two", "three"} {
+		require.NoError(t, q.Push([]byte(r)))
+	}
+	assert.Equal(t, 3, q.Len())
+	assert.Equal(t, 3, depth)
+	assert.EqualValues(t, 11, q.Size())
+
+	rec, seq, ok := q.Peek()
+	require.True(t, ok)
+	assert.Equal(t, "one", string(rec))
+	// peek does not remove
+	rec, seq, ok = q.Peek()
+	require.True(t, ok)
+	assert.Equal(t, "one", string(rec))
+
+	q.Pop(seq)
+	rec, _, ok = q.Peek()
+	require.True(t, ok)
+	assert.Equal(t, "two", string(rec))
+	assert.Equal(t, 2, depth)
+
+	// records survive a restart, and new records are appended after them
+	q, err = Open(Config{Dir: dir})
+	require.NoError(t, err)
+	require.NoError(t, q.Push([]byte("four")))
+	var got []string
+	for rec, seq, ok := q.Peek(); ok; rec, seq, ok = q.Peek() {
+		got = append(got, string(rec))
+		q.Pop(seq)
+	}
+	assert.Equal(t, []string{"two", "three", "four"}, got)
+	assert.Zero(t, q.Len())
+	assert.Zero(t, q.Size())
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestQueue_MaxSize(t *testing.T) {
+	dropped := 0
+	q, err := Open(Config{Dir: t.TempDir(), MaxSize: 10, Dropped: func(b int) { dropped += b }})
+	require.NoError(t, err)
+
+	require.NoError(t, q.Push([]byte("1234")))
+	require.NoError(t, q.Push([]byte("5678")))
+	assert.Zero(t, dropped)
+	// the oldest record is dropped to make room for the new one
+	require.NoError(t, q.Push([]byte("abcd")))
+	assert.Equal(t, 4, dropped)
+	assert.Equal(t, 2, q.Len())
+	rec, _, ok := q.Peek()
+	require.True(t, ok)
+	assert.Equal(t, "5678", string(rec))
+
+	// records bigger than the whole queue are rejected
+	require.Error(t, q.Push([]byte("this is too long")))
+	assert.Equal(t, 20, dropped)
+	assert.Equal(t, 2, q.Len())
+}
+
+func TestQueue_PopEvicted(t *testing.T) {
+	q, err := Open(Config{Dir: t.TempDir(), MaxSize: 8})
+	require.NoError(t, err)
+	require.NoError(t, q.Push([]byte("1234")))
+	_, seq, ok := q.Peek()
+	require.True(t, ok)
+
+	// the peeked record is evicted while it is being processed
+	require.NoError(t, q.Push([]byte("5678")))
+	require.NoError(t, q.Push([]byte("abcd")))
+	q.Pop(seq)
+	// popping the evicted record does not remove the newer records
+	assert.Equal(t, 2, q.Len())
+	rec, _, ok := q.Peek()
+	require.True(t, ok)
+	assert.Equal(t, "5678", string(rec))
+}
+
+func TestQueue_MaxAge(t *testing.T) {
+	now := time.Now()
+	dropped := 0
+	q, err := Open(Config{Dir: t.TempDir(), MaxAge: time.Minute, Dropped: func(b int) { dropped += b }})
+	require.NoError(t, err)
+	q.timeNow = func() time.Time { return now }
+
+	require.NoError(t, q.Push([]byte("old")))
+	now = now.Add(30 * time.Second)
+	require.NoError(t, q.Push([]byte("new")))
+
+	now = now.Add(45 * time.Second)
+	rec, _, ok := q.Peek()
+	require.True(t, ok)
+	assert.Equal(t, "new", string(rec))
+	assert.Equal(t, 3, dropped)
+
+	now = now.Add(time.Minute)
+	_, _, ok = q.Peek()
+	assert.False(t, ok)
+	assert.Equal(t, 6, dropped)
+}
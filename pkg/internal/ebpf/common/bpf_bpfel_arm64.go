@@ -19,6 +19,33 @@ type bpfConnectionInfoT struct {
 	D_port uint16
 }
 
+type bpfDnsReqT struct {
+	Flags           uint8
+	_               [1]byte
+	ConnInfo        bpfConnectionInfoT
+	_               [2]byte
+	StartMonotimeNs uint64
+	EndMonotimeNs   uint64
+	Buf             [128]uint8
+	Rbuf            [256]uint8
+	Len             uint32
+	RespLen         uint32
+	Pid             struct {
+		HostPid uint32
+		UserPid uint32
+		Ns      uint32
+	}
+	_  [4]byte
+	Tp struct {
+		TraceId  [16]uint8
+		SpanId   [8]uint8
+		ParentId [8]uint8
+		Ts       uint64
+		Flags    uint8
+		_        [7]byte
+	}
+}
+
 type bpfHttp2GrpcRequestT struct {
 	Flags           uint8
 	_               [1]byte
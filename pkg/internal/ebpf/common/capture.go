@@ -0,0 +1,326 @@
+package ebpfcommon
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+// Captures are stored in a binary file with the following format:
+//   - captureMagic
+//   - captureVersion (1 byte)
+//   - a sequence of frames, each one with:
+//     frame kind (1 byte), payload length (uvarint), payload
+//
+// The first frame is always a header frame. Record frames contain the nanoseconds elapsed
+// since the capture start (uvarint) followed by the raw ring buffer sample. PID frames contain
+// the JSON representation of the PIDs that were allowed by the service filter at that moment,
+// and are written only when they change.
+const (
+	captureMagic   = "BEYLACAP"
+	captureVersion = 1
+
+	captureFrameHeader = 1
+	captureFrameRecord = 2
+	captureFramePIDs   = 3
+
+	// maximum frequency at which the PIDs of the service filter are checked for changes
+	capturePIDsCheckPeriod = time.Second
+	// upper bound for the size of a frame, to prevent allocating huge buffers from corrupt files
+	maxFrameSize = 16 * 1024 * 1024
+)
+
+var errCaptureFull = errors.New("capture file reached its maximum size")
+
+// CaptureConfig enables the capture of the raw eBPF events into a file, which can be
+// later replayed without eBPF nor root privileges through the "beyla replay" command.
+type CaptureConfig struct {
+	// Path of the capture file. If empty, the capture is disabled.
+	Path string `yaml:"path" env:"BEYLA_BPF_CAPTURE_PATH"`
+	// MaxSize of the capture file, in bytes. When reached, Beyla stops capturing events.
+	MaxSize int64 `yaml:"max_size" env:"BEYLA_BPF_CAPTURE_MAX_SIZE"`
+}
+
+func (c *CaptureConfig) Enabled() bool {
+	return c.Path != ""
+}
+
+// CaptureHeader provides information about the environment where the capture was taken
+type CaptureHeader struct {
+	Start time.Time `json:"start"`
+	Arch  string    `json:"arch"`
+	// SystemWide is true if the capture was taken in system-wide mode, so no PIDs
+	// information is stored
+	SystemWide bool `json:"systemWide,omitempty"`
+}
+
+// CapturedPID is an entry of the PIDs map of the service filter
+type CapturedPID struct {
+	Namespace uint32  `json:"ns"`
+	PID       uint32  `json:"pid"`
+	Type      PIDType `json:"type"`
+	Service   svc.ID  `json:"service"`
+}
+
+// captureWriter stores the raw ring buffer records, as well as the PIDs of the
+// service filter. It is not safe for concurrent use.
+type captureWriter struct {
+	log     *slog.Logger
+	file    io.WriteCloser
+	out     *bufio.Writer
+	maxSize int64
+	written int64
+	start   time.Time
+	filter  ServiceFilter
+	// buffer for the frame length and record timestamp varints
+	varint [2 * binary.MaxVarintLen64]byte
+
+	lastPIDsCheck time.Time
+	lastPIDs      []byte
+}
+
+func newCaptureWriter(cfg *CaptureConfig, filter ServiceFilter) (*captureWriter, error) {
+	file, err := os.Create(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("creating capture file: %w", err)
+	}
+	cw, err := startCapture(file, cfg.MaxSize, filter, time.Now())
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	cw.log.Info("capturing raw eBPF events", "path", cfg.Path, "maxSize", cfg.MaxSize)
+	return cw, nil
+}
+
+func startCapture(file io.WriteCloser, maxSize int64, filter ServiceFilter, now time.Time) (*captureWriter, error) {
+	_, systemWide := filter.(*IdentityPidsFilter)
+	cw := &captureWriter{
+		log:     slog.With("component", "ebpf.CaptureWriter"),
+		file:    file,
+		out:     bufio.NewWriter(file),
+		maxSize: maxSize,
+		start:   now,
+		filter:  filter,
+	}
+	if _, err := cw.out.WriteString(captureMagic); err != nil {
+		return nil, fmt.Errorf("writing capture header: %w", err)
+	}
+	if err := cw.out.WriteByte(captureVersion); err != nil {
+		return nil, fmt.Errorf("writing capture header: %w", err)
+	}
+	cw.written = int64(len(captureMagic) + 1)
+	header, _ := json.Marshal(CaptureHeader{Start: now, Arch: runtime.GOARCH, SystemWide: systemWide})
+	if err := cw.writeFrame(captureFrameHeader, header); err != nil {
+		return nil, fmt.Errorf("writing capture header: %w", err)
+	}
+	if !systemWide {
+		cw.writePIDsIfChanged(now)
+	}
+	return cw, cw.out.Flush()
+}
+
+// writeRecord stores the raw sample of a ring buffer record. It returns false if the
+// capture is finished because of an error or because the maximum size has been reached.
+func (cw *captureWriter) writeRecord(now time.Time, rawSample []byte) bool {
+	if !cw.lastPIDsCheck.IsZero() && now.Sub(cw.lastPIDsCheck) >= capturePIDsCheckPeriod {
+		if !cw.writePIDsIfChanged(now) {
+			return false
+		}
+	}
+	elapsed := now.Sub(cw.start)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	tsLen := binary.PutUvarint(cw.varint[binary.MaxVarintLen64:], uint64(elapsed))
+	ts := cw.varint[binary.MaxVarintLen64 : binary.MaxVarintLen64+tsLen]
+	return cw.finishOnError(cw.writeFrame(captureFrameRecord, ts, rawSample))
+}
+
+func (cw *captureWriter) writePIDsIfChanged(now time.Time) bool {
+	cw.lastPIDsCheck = now
+	pids, _ := json.Marshal(snapshotPIDs(cw.filter))
+	if bytes.Equal(pids, cw.lastPIDs) {
+		return true
+	}
+	cw.lastPIDs = pids
+	return cw.finishOnError(cw.writeFrame(captureFramePIDs, pids))
+}
+
+func (cw *captureWriter) writeFrame(kind byte, payload ...[]byte) error {
+	payloadLen := 0
+	for _, p := range payload {
+		payloadLen += len(p)
+	}
+	lenLen := binary.PutUvarint(cw.varint[:binary.MaxVarintLen64], uint64(payloadLen))
+	frameLen := int64(1 + lenLen + payloadLen)
+	if cw.maxSize > 0 && cw.written+frameLen > cw.maxSize {
+		return errCaptureFull
+	}
+	if err := cw.out.WriteByte(kind); err != nil {
+		return err
+	}
+	if _, err := cw.out.Write(cw.varint[:lenLen]); err != nil {
+		return err
+	}
+	for _, p := range payload {
+		if _, err := cw.out.Write(p); err != nil {
+			return err
+		}
+	}
+	cw.written += frameLen
+	return nil
+}
+
+func (cw *captureWriter) finishOnError(err error) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, errCaptureFull) {
+		cw.log.Info("capture file reached its maximum size. Stopping capture", "size", cw.written)
+	} else {
+		cw.log.Warn("can't write into capture file. Stopping capture", "error", err)
+	}
+	cw.close()
+	return false
+}
+
+// flush the buffered frames, so the capture file is readable even if Beyla does not end gracefully
+func (cw *captureWriter) flush() {
+	if err := cw.out.Flush(); err != nil {
+		cw.log.Warn("can't flush capture file", "error", err)
+	}
+}
+
+func (cw *captureWriter) close() {
+	cw.flush()
+	if err := cw.file.Close(); err != nil {
+		cw.log.Warn("can't close capture file", "error", err)
+	}
+}
+
+func snapshotPIDs(filter ServiceFilter) []CapturedPID {
+	var pids []CapturedPID
+	for _, pidType := range []PIDType{PIDTypeKProbes, PIDTypeGo} {
+		for ns, nsPids := range filter.CurrentPIDs(pidType) {
+			for pid, service := range nsPids {
+				pids = append(pids, CapturedPID{Namespace: ns, PID: pid, Type: pidType, Service: service})
+			}
+		}
+	}
+	// sorting allows comparing the serialized snapshots
+	sort.Slice(pids, func(i, j int) bool {
+		if pids[i].Namespace != pids[j].Namespace {
+			return pids[i].Namespace < pids[j].Namespace
+		}
+		if pids[i].PID != pids[j].PID {
+			return pids[i].PID < pids[j].PID
+		}
+		return pids[i].Type < pids[j].Type
+	})
+	return pids
+}
+
+// CaptureEntry is an element of a capture file. Only one of RawSample or PIDs is set.
+type CaptureEntry struct {
+	// Elapsed time since the start of the capture, for RawSample entries
+	Elapsed   time.Duration
+	RawSample []byte
+	PIDs      []CapturedPID
+}
+
+// CaptureReader sequentially reads the entries of a capture file. It can be used to load
+// captured eBPF events as fixtures for unit tests.
+type CaptureReader struct {
+	in     *bufio.Reader
+	Header CaptureHeader
+}
+
+func NewCaptureReader(in io.Reader) (*CaptureReader, error) {
+	cr := &CaptureReader{in: bufio.NewReader(in)}
+	magic := make([]byte, len(captureMagic)+1)
+	if _, err := io.ReadFull(cr.in, magic); err != nil {
+		return nil, fmt.Errorf("reading capture header: %w", err)
+	}
+	if string(magic[:len(captureMagic)]) != captureMagic {
+		return nil, errors.New("not a Beyla capture file")
+	}
+	if magic[len(captureMagic)] != captureVersion {
+		return nil, fmt.Errorf("unsupported capture version: %d", magic[len(captureMagic)])
+	}
+	kind, payload, err := cr.readFrame()
+	if err != nil {
+		return nil, fmt.Errorf("reading capture header: %w", err)
+	}
+	if kind != captureFrameHeader {
+		return nil, fmt.Errorf("expected capture header. Got frame kind %d", kind)
+	}
+	if err := json.Unmarshal(payload, &cr.Header); err != nil {
+		return nil, fmt.Errorf("decoding capture header: %w", err)
+	}
+	return cr, nil
+}
+
+// Next returns the next entry of the capture, or io.EOF if there are no more entries.
+// A capture file that has been truncated (e.g. because Beyla was killed) ends
+// with io.ErrUnexpectedEOF.
+func (cr *CaptureReader) Next() (CaptureEntry, error) {
+	for {
+		kind, payload, err := cr.readFrame()
+		if err != nil {
+			return CaptureEntry{}, err
+		}
+		switch kind {
+		case captureFrameRecord:
+			elapsed, n := binary.Uvarint(payload)
+			if n <= 0 {
+				return CaptureEntry{}, errors.New("corrupt record frame")
+			}
+			return CaptureEntry{Elapsed: time.Duration(elapsed), RawSample: payload[n:]}, nil
+		case captureFramePIDs:
+			entry := CaptureEntry{PIDs: []CapturedPID{}}
+			if err := json.Unmarshal(payload, &entry.PIDs); err != nil {
+				return CaptureEntry{}, fmt.Errorf("decoding PIDs frame: %w", err)
+			}
+			return entry, nil
+		}
+		// ignoring unknown frame kinds, for forward compatibility
+	}
+}
+
+func (cr *CaptureReader) readFrame() (byte, []byte, error) {
+	kind, err := cr.in.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	size, err := binary.ReadUvarint(cr.in)
+	if err != nil {
+		return 0, nil, unexpectedEOF(err)
+	}
+	if size > maxFrameSize {
+		return 0, nil, fmt.Errorf("frame too large: %d bytes", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(cr.in, payload); err != nil {
+		return 0, nil, unexpectedEOF(err)
+	}
+	return kind, payload, nil
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
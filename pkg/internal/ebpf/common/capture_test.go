@@ -0,0 +1,169 @@
+package ebpfcommon
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/pkg/internal/testutil"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func httpTraceSample(t *testing.T, userPID, ns uint32, contentLength int64) []byte {
+	trace := HTTPRequestTrace{Type: 1, Method: [7]byte{'G', 'E', 'T'}, ContentLength: contentLength}
+	trace.Pid.HostPid = userPID + 1000
+	trace.Pid.UserPid = userPID
+	trace.Pid.Ns = ns
+	raw := bytes.Buffer{}
+	require.NoError(t, binary.Write(&raw, binary.LittleEndian, trace))
+	return raw.Bytes()
+}
+
+func TestCaptureAndReplay(t *testing.T) {
+	readNamespacePIDs = func(pid int32) ([]uint32, error) {
+		return []uint32{uint32(pid)}, nil
+	}
+	// GIVEN a capture of the eBPF events of two processes
+	filter := NewPIDsFilter(slog.Default())
+	filter.AllowPID(1, 33, svc.ID{Name: "foo", UID: "foo"}, PIDTypeGo)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	file := bytes.Buffer{}
+	cw, err := startCapture(nopWriteCloser{&file}, 0, filter, start)
+	require.NoError(t, err)
+	require.True(t, cw.writeRecord(start.Add(time.Millisecond), httpTraceSample(t, 1, 33, 10)))
+	// the second process is discovered later
+	filter.AllowPID(2, 33, svc.ID{Name: "bar", UID: "bar"}, PIDTypeKProbes)
+	require.True(t, cw.writeRecord(start.Add(2*time.Second), httpTraceSample(t, 2, 33, 20)))
+	require.True(t, cw.writeRecord(start.Add(3*time.Second), httpTraceSample(t, 1, 33, 30)))
+	// events from processes that are not instrumented are filtered in the replay
+	require.True(t, cw.writeRecord(start.Add(4*time.Second), httpTraceSample(t, 3, 33, 40)))
+	cw.close()
+
+	// WHEN the capture is read
+	reader, err := NewCaptureReader(bytes.NewReader(file.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, start, reader.Header.Start.UTC())
+	assert.False(t, reader.Header.SystemWide)
+	var entries []CaptureEntry
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		entries = append(entries, entry)
+	}
+	// THEN it contains the records along with the PIDs changes
+	require.Len(t, entries, 6)
+	require.Len(t, entries[0].PIDs, 1)
+	assert.Equal(t, time.Millisecond, entries[1].Elapsed)
+	require.Len(t, entries[2].PIDs, 2)
+	assert.Equal(t, CapturedPID{Namespace: 33, PID: 2, Type: PIDTypeKProbes,
+		Service: svc.ID{Name: "bar", UID: "bar"}}, entries[2].PIDs[1])
+	assert.Equal(t, 2*time.Second, entries[3].Elapsed)
+
+	// AND WHEN the capture is replayed
+	spans := make(chan []request.Span, 10)
+	require.NoError(t, ReplayCapture(context.Background(), &TracerConfig{BatchLength: 10},
+		bytes.NewReader(file.Bytes()), spans))
+
+	// THEN the spans are forwarded with the captured services
+	batch := testutil.ReadChannel(t, spans, testTimeout)
+	require.Len(t, batch, 3)
+	assert.Equal(t, "foo", batch[0].ServiceID.Name)
+	assert.EqualValues(t, 10, batch[0].ContentLength)
+	assert.Equal(t, "bar", batch[1].ServiceID.Name)
+	assert.EqualValues(t, 20, batch[1].ContentLength)
+	assert.Equal(t, "foo", batch[2].ServiceID.Name)
+	assert.EqualValues(t, 30, batch[2].ContentLength)
+}
+
+func TestReplay_MisclassifiedHTTP2(t *testing.T) {
+	// GIVEN a capture with a generic TCP event that contains HTTP/2 frames,
+	// followed by an HTTP event
+	filter := &IdentityPidsFilter{}
+	start := time.Now()
+	file := bytes.Buffer{}
+	cw, err := startCapture(nopWriteCloser{&file}, 0, filter, start)
+	require.NoError(t, err)
+
+	frames := []byte{0, 0, 70, 1, 4, 0, 0, 0, 19, 204, 131, 4, 147, 96, 233, 45, 18, 22, 147, 175, 12, 155, 139, 103, 115, 16, 172, 98, 42, 97, 145, 31, 134, 126, 167, 0, 22, 16, 7, 36, 140, 179, 27, 50, 202, 25, 101, 105, 182, 93, 33, 66, 211, 97, 41, 64, 0, 182, 66, 44, 219, 242, 186, 217, 2, 203, 196, 3, 143, 182, 209, 86, 0, 127, 203, 202, 201, 200, 199, 0, 0, 5, 0, 0, 0, 0, 0, 19, 0, 0, 0, 0, 0}
+	tcp := makeTCPReq(string(frames), tcpSend, 343534, 8080, 2000)
+	tcp.Flags = EventTypeTCP
+	tcp.Len = 10000
+	raw := bytes.Buffer{}
+	require.NoError(t, binary.Write(&raw, binary.LittleEndian, tcp))
+	require.True(t, cw.writeRecord(start, raw.Bytes()))
+	require.True(t, cw.writeRecord(start, httpTraceSample(t, 1, 33, 10)))
+	cw.close()
+
+	// WHEN the capture is replayed
+	spans := make(chan []request.Span, 10)
+	replayed := make(chan error)
+	go func() {
+		replayed <- ReplayCapture(context.Background(), &TracerConfig{BatchLength: 10},
+			bytes.NewReader(file.Bytes()), spans)
+	}()
+
+	// THEN the replay does not block waiting for the HTTP/2 tracer
+	// AND the HTTP/2 event is not reported as a span
+	require.NoError(t, testutil.ReadChannel(t, replayed, testTimeout))
+	batch := testutil.ReadChannel(t, spans, testTimeout)
+	require.Len(t, batch, 1)
+	assert.EqualValues(t, 10, batch[0].ContentLength)
+}
+
+func TestCapture_MaxSize(t *testing.T) {
+	filter := &IdentityPidsFilter{}
+	start := time.Now()
+	file := bytes.Buffer{}
+	sample := httpTraceSample(t, 1, 1, 0)
+	cw, err := startCapture(nopWriteCloser{&file}, 1000, filter, start)
+	require.NoError(t, err)
+	records := 0
+	for cw.writeRecord(start, sample) {
+		records++
+	}
+	assert.LessOrEqual(t, file.Len(), 1000)
+	assert.Positive(t, records)
+
+	reader, err := NewCaptureReader(bytes.NewReader(file.Bytes()))
+	require.NoError(t, err)
+	assert.True(t, reader.Header.SystemWide)
+	for i := 0; i < records; i++ {
+		entry, err := reader.Next()
+		require.NoError(t, err)
+		assert.Equal(t, sample, entry.RawSample)
+	}
+	_, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+
+	// truncated captures are reported
+	reader, err = NewCaptureReader(bytes.NewReader(file.Bytes()[:file.Len()-1]))
+	require.NoError(t, err)
+	for i := 0; i < records-1; i++ {
+		_, err := reader.Next()
+		require.NoError(t, err)
+	}
+	_, err = reader.Next()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestCaptureReader_Invalid(t *testing.T) {
+	_, err := NewCaptureReader(bytes.NewReader([]byte("not a capture file")))
+	assert.Error(t, err)
+}
@@ -20,7 +20,7 @@ import (
 	"github.com/grafana/beyla/pkg/internal/request"
 )
 
-//go:generate $BPF2GO -cc $BPF_CLANG -cflags $BPF_CFLAGS -target amd64,arm64 -type http_request_trace -type sql_request_trace -type http_info_t -type connection_info_t -type http2_grpc_request_t -type tcp_req_t -type kafka_client_req_t -type kafka_go_req_t  -type redis_client_req_t bpf ../../../../bpf/http_trace.c -- -I../../../../bpf/headers
+//go:generate $BPF2GO -cc $BPF_CLANG -cflags $BPF_CFLAGS -target amd64,arm64 -type http_request_trace -type sql_request_trace -type http_info_t -type connection_info_t -type http2_grpc_request_t -type tcp_req_t -type kafka_client_req_t -type kafka_go_req_t  -type redis_client_req_t -type dns_req_t bpf ../../../../bpf/http_trace.c -- -I../../../../bpf/headers
 
 // HTTPRequestTrace contains information from an HTTP request as directly received from the
 // eBPF layer. This contains low-level C structures for accurate binary read from ring buffer.
@@ -32,6 +32,7 @@ type TCPRequestInfo bpfTcpReqT
 type GoSaramaClientInfo bpfKafkaClientReqT
 type GoRedisClientInfo bpfRedisClientReqT
 type GoKafkaGoClientInfo bpfKafkaGoReqT
+type DNSRequestInfo bpfDnsReqT
 
 const EventTypeSQL = 5        // EVENT_SQL_CLIENT
 const EventTypeKHTTP = 6      // HTTP Events generated by kprobes
@@ -40,6 +41,7 @@ const EventTypeTCP = 8        // Unknown TCP protocol to be classified by user s
 const EventTypeGoSarama = 9   // Kafka client for Go (Shopify/IBM Sarama)
 const EventTypeGoRedis = 10   // Redis client for Go
 const EventTypeGoKafkaGo = 11 // Kafka-Go client from Segment-io
+const EventTypeDNS = 12       // DNS queries over UDP
 
 var IntegrityModeOverride = false
 
@@ -75,6 +77,14 @@ type TracerConfig struct {
 	TrackRequestHeaders bool `yaml:"track_request_headers" env:"BEYLA_BPF_TRACK_REQUEST_HEADERS"`
 
 	HTTPRequestTimeout time.Duration `yaml:"http_request_timeout" env:"BEYLA_BPF_HTTP_REQUEST_TIMEOUT"`
+
+	// DisabledProtocolDetectors lists the names of the protocol detectors that won't be used
+	// to classify the payload of generic TCP requests (postgres, mysql, sql, redis, mongo, http2,
+	// kafka, or any custom registered ProtocolDetector).
+	DisabledProtocolDetectors []string `yaml:"disabled_protocol_detectors" env:"BEYLA_BPF_DISABLED_PROTOCOL_DETECTORS" envSeparator:","`
+
+	// Capture optionally stores the raw eBPF events into a file, for offline debugging
+	Capture CaptureConfig `yaml:"capture"`
 }
 
 // Probe holds the information of the instrumentation points of a given function: its start and end offsets and
@@ -88,6 +98,9 @@ type FunctionPrograms struct {
 	// Required, if true, will cancel the execution of the eBPF Tracer
 	// if the function has not been found in the executable
 	Required bool
+	// Optional, if true, won't cancel the execution of the eBPF Tracer if the
+	// kprobe can't be attached, e.g. because the kernel function does not exist
+	Optional bool
 	Start    *ebpf.Program
 	End      *ebpf.Program
 }
@@ -130,6 +143,8 @@ func ReadBPFTraceAsSpan(record *ringbuf.Record, filter ServiceFilter) (request.S
 		return ReadGoRedisRequestIntoSpan(record)
 	case EventTypeGoKafkaGo:
 		return ReadGoKafkaGoRequestIntoSpan(record)
+	case EventTypeDNS:
+		return ReadDNSRequestIntoSpan(record, filter)
 	}
 
 	var event HTTPRequestTrace
@@ -0,0 +1,223 @@
+package ebpfcommon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/cilium/ebpf/ringbuf"
+	trace2 "go.opentelemetry.io/otel/trace"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+const (
+	dnsHeaderLen = 12
+	// maximum number of compression pointers to follow while reading a name, to avoid loops
+	dnsMaxPointers = 16
+	dnsClassIN     = 1
+
+	dnsTypeA    = 1
+	dnsTypeAAAA = 28
+)
+
+var dnsTypes = map[uint16]string{
+	1: "A", 2: "NS", 5: "CNAME", 6: "SOA", 12: "PTR", 15: "MX", 16: "TXT",
+	28: "AAAA", 33: "SRV", 35: "NAPTR", 64: "SVCB", 65: "HTTPS", 255: "ANY",
+}
+
+var errDNSMalformed = errors.New("malformed DNS message")
+
+// dnsMessage contains the parts of a DNS message that Beyla reports
+type dnsMessage struct {
+	id        uint16
+	response  bool
+	rcode     int
+	qName     string
+	qType     string
+	addresses []string
+}
+
+func dnsTypeName(t uint16) string {
+	if name, ok := dnsTypes[t]; ok {
+		return name
+	}
+	return "TYPE" + strconv.Itoa(int(t))
+}
+
+// parseDNSMessage parses the header, the first question and the A/AAAA answers of a DNS message.
+// The message might be truncated by the eBPF buffer size, so the answers are parsed on a best-effort
+// basis and any answer that goes beyond the buffer is ignored.
+func parseDNSMessage(buf []byte) (*dnsMessage, error) {
+	if len(buf) < dnsHeaderLen {
+		return nil, errDNSMalformed
+	}
+	flags := binary.BigEndian.Uint16(buf[2:4])
+	msg := &dnsMessage{
+		id:       binary.BigEndian.Uint16(buf[0:2]),
+		response: flags&0x8000 != 0,
+		rcode:    int(flags & 0x000f),
+	}
+	// only standard queries are reported
+	if (flags>>11)&0x0f != 0 {
+		return nil, errDNSMalformed
+	}
+	qdCount := binary.BigEndian.Uint16(buf[4:6])
+	anCount := binary.BigEndian.Uint16(buf[6:8])
+	if qdCount == 0 {
+		return nil, errDNSMalformed
+	}
+
+	name, off, err := readDNSName(buf, dnsHeaderLen)
+	if err != nil || off+4 > len(buf) {
+		return nil, errDNSMalformed
+	}
+	msg.qName = name
+	msg.qType = dnsTypeName(binary.BigEndian.Uint16(buf[off : off+2]))
+	off += 4
+
+	// skip any other question
+	for i := 1; i < int(qdCount); i++ {
+		if _, off, err = readDNSName(buf, off); err != nil || off+4 > len(buf) {
+			return msg, nil
+		}
+		off += 4
+	}
+
+	if !msg.response {
+		return msg, nil
+	}
+	for i := 0; i < int(anCount); i++ {
+		if _, off, err = readDNSName(buf, off); err != nil || off+10 > len(buf) {
+			break
+		}
+		rType := binary.BigEndian.Uint16(buf[off : off+2])
+		rClass := binary.BigEndian.Uint16(buf[off+2 : off+4])
+		rdLen := int(binary.BigEndian.Uint16(buf[off+8 : off+10]))
+		off += 10
+		if off+rdLen > len(buf) {
+			break
+		}
+		if rClass == dnsClassIN &&
+			((rType == dnsTypeA && rdLen == net.IPv4len) || (rType == dnsTypeAAAA && rdLen == net.IPv6len)) {
+			msg.addresses = append(msg.addresses, net.IP(buf[off:off+rdLen]).String())
+		}
+		off += rdLen
+	}
+	return msg, nil
+}
+
+// readDNSName reads a (possibly compressed) domain name starting at the given offset. It returns
+// the name and the offset right after the name in the original position.
+func readDNSName(buf []byte, off int) (string, int, error) {
+	sb := strings.Builder{}
+	end := -1
+	pointers := 0
+	for {
+		if off >= len(buf) {
+			return "", 0, errDNSMalformed
+		}
+		l := int(buf[off])
+		switch {
+		case l == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return sb.String(), end, nil
+		case l&0xc0 == 0xc0:
+			if off+1 >= len(buf) || pointers >= dnsMaxPointers {
+				return "", 0, errDNSMalformed
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			pointers++
+			off = int(binary.BigEndian.Uint16(buf[off:off+2]) & 0x3fff)
+		case l&0xc0 != 0:
+			return "", 0, errDNSMalformed
+		default:
+			off++
+			if off+l > len(buf) {
+				return "", 0, errDNSMalformed
+			}
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+			sb.Write(buf[off : off+l])
+			off += l
+		}
+	}
+}
+
+func ReadDNSRequestIntoSpan(record *ringbuf.Record, filter ServiceFilter) (request.Span, bool, error) {
+	var event DNSRequestInfo
+
+	err := binary.Read(bytes.NewBuffer(record.RawSample), binary.LittleEndian, &event)
+	if err != nil {
+		return request.Span{}, true, err
+	}
+
+	if !filter.ValidPID(event.Pid.UserPid, event.Pid.Ns, PIDTypeKProbes) {
+		return request.Span{}, true, nil
+	}
+
+	l := int(event.Len)
+	if l < 0 || len(event.Buf) < l {
+		l = len(event.Buf)
+	}
+	rl := int(event.RespLen)
+	if rl < 0 || len(event.Rbuf) < rl {
+		rl = len(event.Rbuf)
+	}
+
+	query, err := parseDNSMessage(event.Buf[:l])
+	if err != nil || query.response {
+		return request.Span{}, true, nil
+	}
+	response, err := parseDNSMessage(event.Rbuf[:rl])
+	if err != nil || !response.response || response.id != query.id {
+		return request.Span{}, true, nil
+	}
+
+	return dnsToSpan(&event, query, response), false, nil
+}
+
+func dnsToSpan(trace *DNSRequestInfo, query, response *dnsMessage) request.Span {
+	peer := ""
+	hostname := ""
+	hostPort := 0
+
+	if trace.ConnInfo.S_port != 0 || trace.ConnInfo.D_port != 0 {
+		peer, hostname = (*BPFConnInfo)(unsafe.Pointer(&trace.ConnInfo)).reqHostInfo()
+		hostPort = int(trace.ConnInfo.D_port)
+	}
+
+	return request.Span{
+		Type:          request.EventTypeDNSClient,
+		Method:        query.qType,
+		Path:          query.qName,
+		Peer:          peer,
+		PeerPort:      int(trace.ConnInfo.S_port),
+		Host:          hostname,
+		HostPort:      hostPort,
+		ContentLength: int64(trace.Len),
+		RequestStart:  int64(trace.StartMonotimeNs),
+		Start:         int64(trace.StartMonotimeNs),
+		End:           int64(trace.EndMonotimeNs),
+		Status:        response.rcode,
+		TraceID:       trace2.TraceID(trace.Tp.TraceId),
+		SpanID:        trace2.SpanID(trace.Tp.SpanId),
+		ParentSpanID:  trace2.SpanID(trace.Tp.ParentId),
+		Flags:         trace.Tp.Flags,
+		Pid: request.PidInfo{
+			HostPID:   trace.Pid.HostPid,
+			UserPID:   trace.Pid.UserPid,
+			Namespace: trace.Pid.Ns,
+		},
+		DNS: &request.DNSInfo{Addresses: response.addresses},
+	}
+}
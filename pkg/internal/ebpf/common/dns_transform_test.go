@@ -0,0 +1,122 @@
+package ebpfcommon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/cilium/ebpf/ringbuf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+var (
+	// query for www.example.com, type A, id 0x1234
+	dnsQuery = []byte{
+		0x12, 0x34, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		3, 'w', 'w', 'w', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+		0x00, 0x01, 0x00, 0x01,
+	}
+	// response with a CNAME answer and two compressed A answers
+	dnsResponse = append(append([]byte{
+		0x12, 0x34, 0x81, 0x80, 0x00, 0x01, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00,
+	}, dnsQuery[12:]...),
+		// www.example.com CNAME edge.example.com
+		0xc0, 0x0c, 0x00, 0x05, 0x00, 0x01, 0x00, 0x00, 0x00, 0x3c, 0x00, 0x07,
+		4, 'e', 'd', 'g', 'e', 0xc0, 0x10,
+		// edge.example.com A 10.0.0.1
+		0xc0, 0x2d, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x3c, 0x00, 0x04,
+		10, 0, 0, 1,
+		// edge.example.com A 10.0.0.2
+		0xc0, 0x2d, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x3c, 0x00, 0x04,
+		10, 0, 0, 2,
+	)
+)
+
+func TestParseDNSMessage(t *testing.T) {
+	query, err := parseDNSMessage(dnsQuery)
+	require.NoError(t, err)
+	assert.Equal(t, &dnsMessage{id: 0x1234, qName: "www.example.com", qType: "A"}, query)
+
+	response, err := parseDNSMessage(dnsResponse)
+	require.NoError(t, err)
+	assert.Equal(t, &dnsMessage{
+		id: 0x1234, response: true, qName: "www.example.com", qType: "A",
+		addresses: []string{"10.0.0.1", "10.0.0.2"},
+	}, response)
+
+	// truncated answers are ignored
+	response, err = parseDNSMessage(dnsResponse[:len(dnsResponse)-2])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1"}, response.addresses)
+}
+
+func TestParseDNSMessage_NXDomain(t *testing.T) {
+	nx := append([]byte{0x12, 0x34, 0x81, 0x83, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		dnsQuery[12:]...)
+	nx[len(nx)-3] = 28 // AAAA
+	response, err := parseDNSMessage(nx)
+	require.NoError(t, err)
+	assert.True(t, response.response)
+	assert.Equal(t, request.DNSResponseNXDomain, response.rcode)
+	assert.Equal(t, "AAAA", response.qType)
+	assert.Empty(t, response.addresses)
+}
+
+func TestParseDNSMessage_Malformed(t *testing.T) {
+	for _, buf := range [][]byte{
+		nil,
+		dnsQuery[:10],
+		// no questions
+		{0x12, 0x34, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		// truncated name
+		dnsQuery[:20],
+		// compression pointer loop
+		{0x12, 0x34, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc0, 0x0c, 0x00, 0x01, 0x00, 0x01},
+	} {
+		_, err := parseDNSMessage(buf)
+		assert.Error(t, err)
+	}
+}
+
+func TestReadDNSRequestIntoSpan(t *testing.T) {
+	fltr := TestPidsFilter{services: map[uint32]svc.ID{}}
+
+	event := DNSRequestInfo{
+		Flags:           EventTypeDNS,
+		StartMonotimeNs: 100,
+		EndMonotimeNs:   300,
+		Len:             uint32(len(dnsQuery)),
+		RespLen:         uint32(len(dnsResponse)),
+	}
+	event.ConnInfo.S_port = 41234
+	event.ConnInfo.D_port = 53
+	copy(event.Buf[:], dnsQuery)
+	copy(event.Rbuf[:], dnsResponse)
+
+	binaryRecord := bytes.Buffer{}
+	require.NoError(t, binary.Write(&binaryRecord, binary.LittleEndian, event))
+	span, ignore, err := ReadBPFTraceAsSpan(&ringbuf.Record{RawSample: binaryRecord.Bytes()}, &fltr)
+	require.NoError(t, err)
+	require.False(t, ignore)
+
+	assert.Equal(t, request.EventTypeDNSClient, span.Type)
+	assert.Equal(t, "A", span.Method)
+	assert.Equal(t, "www.example.com", span.Path)
+	assert.Equal(t, request.DNSResponseNoError, span.Status)
+	assert.Equal(t, 53, span.HostPort)
+	assert.Equal(t, int64(100), span.Start)
+	assert.Equal(t, int64(300), span.End)
+	assert.Equal(t, &request.DNSInfo{Addresses: []string{"10.0.0.1", "10.0.0.2"}}, span.DNS)
+
+	// responses that don't match the query are ignored
+	event.Rbuf[1] = 0x35
+	binaryRecord.Reset()
+	require.NoError(t, binary.Write(&binaryRecord, binary.LittleEndian, event))
+	_, ignore, err = ReadBPFTraceAsSpan(&ringbuf.Record{RawSample: binaryRecord.Bytes()}, &fltr)
+	require.NoError(t, err)
+	assert.True(t, ignore)
+}
@@ -22,6 +22,8 @@ func ReadGoSaramaRequestIntoSpan(record *ringbuf.Record) (request.Span, bool, er
 	info, err := ProcessKafkaRequest(event.Buf[:])
 
 	if err == nil {
+		// the response is not captured, so only the request side of the partition information is available
+		info.Messaging = kafkaPartitionInfo(info.Operation, event.Buf[:], nil)
 		return GoKafkaSaramaToSpan(&event, info), false, nil
 	}
 
@@ -43,6 +45,7 @@ func GoKafkaSaramaToSpan(event *GoSaramaClientInfo, data *KafkaInfo) request.Spa
 		Method:         data.Operation.String(),
 		OtherNamespace: data.ClientID,
 		Path:           data.Topic,
+		Messaging:      data.Messaging,
 		Peer:           peer,
 		PeerPort:       int(event.Conn.S_port),
 		Host:           hostname,
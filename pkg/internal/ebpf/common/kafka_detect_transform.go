@@ -31,6 +31,8 @@ type KafkaInfo struct {
 	Topic       string
 	ClientID    string
 	TopicOffset int
+	// Messaging details of the first partition, if they could be parsed
+	Messaging *request.MessagingInfo
 }
 
 func (k Operation) String() string {
@@ -59,9 +61,12 @@ func ProcessPossibleKafkaEvent(event *TCPRequestInfo, pkt []byte, rpkt []byte) (
 		k, err = ProcessKafkaRequest(rpkt)
 		if err == nil {
 			reverseTCPEvent(event)
+			k.Messaging = kafkaPartitionInfo(k.Operation, rpkt, pkt)
 		}
+		return k, err
 	}
-	return k, err
+	k.Messaging = kafkaPartitionInfo(k.Operation, pkt, rpkt)
+	return k, nil
 }
 
 // https://kafka.apache.org/protocol.html
@@ -362,6 +367,7 @@ func TCPToKafkaToSpan(trace *TCPRequestInfo, data *KafkaInfo) request.Span {
 		Method:         data.Operation.String(),
 		OtherNamespace: data.ClientID,
 		Path:           data.Topic,
+		Messaging:      data.Messaging,
 		Peer:           peer,
 		PeerPort:       int(trace.ConnInfo.S_port),
 		Host:           hostname,
@@ -0,0 +1,323 @@
+package ebpfcommon
+
+import (
+	"encoding/binary"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// Kafka record batch header, as defined in https://kafka.apache.org/documentation/#recordbatch
+const (
+	kafkaBatchMagicPos        = 16
+	kafkaBatchLastOffsetDelta = 23
+	kafkaBatchRecordsCount    = 57
+	kafkaBatchHeaderLen       = 61
+	kafkaBatchMagic           = 2
+)
+
+// first API versions using the flexible (compact) encoding of strings, arrays and tagged fields
+const (
+	kafkaProduceFlexibleVersion = 9
+	kafkaFetchFlexibleVersion   = 12
+)
+
+// kafkaReader reads the Kafka protocol primitive types from a buffer that might be truncated.
+// After any read fails, the rest of reads will fail.
+type kafkaReader struct {
+	buf      []byte
+	pos      int
+	flexible bool
+	ok       bool
+}
+
+func newKafkaReader(buf []byte, flexible bool) *kafkaReader {
+	return &kafkaReader{buf: buf, flexible: flexible, ok: true}
+}
+
+func (r *kafkaReader) skip(n int) {
+	if !r.ok || n < 0 || r.pos+n > len(r.buf) {
+		r.ok = false
+		return
+	}
+	r.pos += n
+}
+
+func (r *kafkaReader) int16() int16 {
+	if r.skip(2); !r.ok {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(r.buf[r.pos-2:]))
+}
+
+func (r *kafkaReader) int32() int32 {
+	if r.skip(4); !r.ok {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(r.buf[r.pos-4:]))
+}
+
+func (r *kafkaReader) int64() int64 {
+	if r.skip(8); !r.ok {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(r.buf[r.pos-8:]))
+}
+
+func (r *kafkaReader) uvarint() int {
+	if !r.ok || r.pos >= len(r.buf) {
+		r.ok = false
+		return 0
+	}
+	v, err := readUnsignedVarint(r.buf[r.pos:])
+	if err != nil {
+		r.ok = false
+		return 0
+	}
+	// unsigned varints are encoded in groups of 7 bits
+	for n := v; ; n >>= 7 {
+		r.pos++
+		if n < 0x80 {
+			break
+		}
+	}
+	return v
+}
+
+// length of an array, string or byte sequence. Nullable values return -1.
+func (r *kafkaReader) length(compact bool) int {
+	if compact {
+		return r.uvarint() - 1
+	}
+	return int(r.int32())
+}
+
+func (r *kafkaReader) arrayLen() int {
+	return r.length(r.flexible)
+}
+
+// skipString skips a (nullable) string, either compact or with int16 length
+func (r *kafkaReader) skipString() {
+	if r.flexible {
+		r.skip(max(r.uvarint()-1, 0))
+	} else {
+		r.skip(max(int(r.int16()), 0))
+	}
+}
+
+func (r *kafkaReader) skipTaggedFields() {
+	if !r.flexible {
+		return
+	}
+	for fields := r.uvarint(); fields > 0 && r.ok; fields-- {
+		r.uvarint() // tag
+		r.skip(r.uvarint())
+	}
+}
+
+// skipRequestHeader skips the header of a request, which is always followed by the client ID
+// as a non-compact string. The API version is read first, so the reader switches to the
+// flexible encoding before skipping the tagged fields that end the header of the flexible
+// versions (starting at flexibleVersion).
+func (r *kafkaReader) skipRequestHeader(flexibleVersion int16) int16 {
+	r.skip(6) // size and API key
+	version := r.int16()
+	r.flexible = version >= flexibleVersion
+	r.skip(4) // correlation ID
+	r.skip(max(int(r.int16()), 0))
+	r.skipTaggedFields()
+	return version
+}
+
+// skipResponseHeader skips the header of a response: size, correlation ID and tagged fields
+func (r *kafkaReader) skipResponseHeader() {
+	r.skip(8)
+	r.skipTaggedFields()
+}
+
+// skipTopic skips the topic name, or the topic ID in Fetch versions that use it
+func (r *kafkaReader) skipTopic(usesID bool) {
+	if usesID {
+		r.skip(16)
+	} else {
+		r.skipString()
+	}
+}
+
+// recordBatch reads the header of the first record batch in the records of a partition,
+// and returns the offset of its first and last record, and the number of records.
+// It returns false if the header is not available or it is not a valid batch.
+func (r *kafkaReader) recordBatch() (firstOffset, lastOffset int64, count int, ok bool) {
+	if !r.ok || r.pos+kafkaBatchLastOffsetDelta+4 > len(r.buf) {
+		return 0, 0, 0, false
+	}
+	batch := r.buf[r.pos:]
+	if batch[kafkaBatchMagicPos] != kafkaBatchMagic {
+		return 0, 0, 0, false
+	}
+	firstOffset = int64(binary.BigEndian.Uint64(batch))
+	lastOffset = firstOffset + int64(int32(binary.BigEndian.Uint32(batch[kafkaBatchLastOffsetDelta:])))
+	count = int(lastOffset-firstOffset) + 1
+	if len(batch) >= kafkaBatchHeaderLen {
+		count = int(int32(binary.BigEndian.Uint32(batch[kafkaBatchRecordsCount:])))
+	}
+	return firstOffset, lastOffset, count, firstOffset >= 0 && lastOffset >= firstOffset
+}
+
+// kafkaPartitionInfo extracts, from the captured request and response buffers, the details about the
+// first partition of the first topic in a Produce or Fetch operation. The response might be nil.
+// Since Beyla only captures the beginning of the messages, the information is approximate: only the
+// first record batch of each partition can be inspected.
+// It returns nil if the partition could not be parsed.
+func kafkaPartitionInfo(op Operation, req, resp []byte) *request.MessagingInfo {
+	switch op {
+	case Produce:
+		return kafkaProduceInfo(req, resp)
+	case Fetch:
+		return kafkaFetchInfo(req, resp)
+	}
+	return nil
+}
+
+func kafkaProduceInfo(req, resp []byte) *request.MessagingInfo {
+	r := newKafkaReader(req, false)
+	version := r.skipRequestHeader(kafkaProduceFlexibleVersion)
+	if version >= 3 {
+		r.skipString() // transactional ID
+	}
+	r.skip(6) // acks and timeout
+	if r.arrayLen() <= 0 {
+		return nil
+	}
+	r.skipString()
+	if r.arrayLen() <= 0 {
+		return nil
+	}
+	info := &request.MessagingInfo{Partition: int(r.int32()), Offset: -1, HighWatermark: -1}
+	info.Bytes = max(r.length(r.flexible), 0)
+	if !r.ok {
+		return nil
+	}
+	if _, _, count, ok := r.recordBatch(); ok {
+		info.Messages = count
+	}
+
+	// the response contains the offset that the broker assigned to the first message
+	if len(resp) == 0 || info.Messages == 0 {
+		return info
+	}
+	r = newKafkaReader(resp, version >= kafkaProduceFlexibleVersion)
+	r.skipResponseHeader()
+	if r.arrayLen() <= 0 {
+		return info
+	}
+	r.skipString()
+	if r.arrayLen() <= 0 {
+		return info
+	}
+	partition := r.int32()
+	errorCode := r.int16()
+	baseOffset := r.int64()
+	if r.ok && int(partition) == info.Partition && errorCode == 0 && baseOffset >= 0 {
+		info.Offset = baseOffset + int64(info.Messages) - 1
+	}
+	return info
+}
+
+func kafkaFetchInfo(req, resp []byte) *request.MessagingInfo {
+	// the request contains the offset that the consumer is going to fetch from
+	r := newKafkaReader(req, false)
+	version := r.skipRequestHeader(kafkaFetchFlexibleVersion)
+	usesTopicID := version >= 13
+	if version < 15 {
+		r.skip(4) // replica ID
+	}
+	r.skip(8) // max wait and min bytes
+	if version >= 3 {
+		r.skip(4) // max bytes
+	}
+	if version >= 4 {
+		r.skip(1) // isolation level
+	}
+	if version >= 7 {
+		r.skip(8) // session ID and epoch
+	}
+	reqPartition, fetchOffset := int32(-1), int64(-1)
+	if r.arrayLen() > 0 {
+		r.skipTopic(usesTopicID)
+		if r.arrayLen() > 0 {
+			reqPartition = r.int32()
+			if version >= 9 {
+				r.skip(4) // current leader epoch
+			}
+			if fetchOffset = r.int64(); !r.ok {
+				reqPartition, fetchOffset = -1, -1
+			}
+		}
+	}
+
+	info := &request.MessagingInfo{Partition: int(reqPartition), Offset: -1, HighWatermark: -1}
+	if fetchOffset > 0 {
+		info.Offset = fetchOffset - 1
+	}
+	if len(resp) > 0 {
+		kafkaFetchResponseInfo(resp, version, info)
+	}
+	if info.Partition < 0 {
+		return nil
+	}
+	return info
+}
+
+// kafkaFetchResponseInfo completes the fetch information with the high watermark and the
+// fetched records in the response
+func kafkaFetchResponseInfo(resp []byte, version int16, info *request.MessagingInfo) {
+	r := newKafkaReader(resp, version >= kafkaFetchFlexibleVersion)
+	r.skipResponseHeader()
+	r.skip(4) // throttle time
+	if version >= 7 {
+		r.skip(6) // error code and session ID
+	}
+	if r.arrayLen() <= 0 {
+		return
+	}
+	r.skipTopic(version >= 13)
+	if r.arrayLen() <= 0 {
+		return
+	}
+	partition := int(r.int32())
+	errorCode := r.int16()
+	highWatermark := r.int64()
+	if !r.ok || errorCode != 0 {
+		return
+	}
+	if partition != info.Partition {
+		// in incremental fetch sessions, the response might not follow the order of the request
+		info.Partition, info.Offset = partition, -1
+	}
+	info.HighWatermark = highWatermark
+	if version >= 4 {
+		r.skip(8) // last stable offset
+	}
+	if version >= 5 {
+		r.skip(8) // log start offset
+	}
+	if version >= 4 {
+		// aborted transactions: producer ID and first offset
+		abortedLen := 16
+		if r.flexible {
+			abortedLen++ // tagged fields, assuming there are none
+		}
+		r.skip(max(r.arrayLen(), 0) * abortedLen)
+	}
+	if version >= 11 {
+		r.skip(4) // preferred read replica
+	}
+	info.Bytes = max(r.length(r.flexible), 0)
+	if !r.ok {
+		return
+	}
+	if _, lastOffset, count, ok := r.recordBatch(); ok {
+		info.Messages = count
+		info.Offset = lastOffset
+	}
+}
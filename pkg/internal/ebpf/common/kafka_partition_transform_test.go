@@ -0,0 +1,172 @@
+package ebpfcommon
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// captured from a sarama client
+var (
+	kafkaProduceV7Req = []byte{0, 0, 0, 123, 0, 0, 0, 7, 0, 0, 0, 2, 0, 6, 115, 97, 114, 97, 109, 97, 255, 255, 255, 255, 0, 0, 39, 16, 0, 0, 0, 1, 0, 9, 105, 109, 112, 111, 114, 116, 97, 110, 116, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 72, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 60, 0, 0, 0, 0, 2, 249, 236, 167, 144, 0, 0, 0, 0, 0, 0, 0, 0, 1, 143, 191, 130, 165, 117, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 0, 0, 0, 0, 0, 0, 0, 1, 20, 0, 0, 0, 1, 8, 100, 97, 116, 97, 0}
+	kafkaFetchV11Req  = []byte{0, 0, 0, 94, 0, 1, 0, 11, 0, 0, 0, 224, 0, 6, 115, 97, 114, 97, 109, 97, 255, 255, 255, 255, 0, 0, 1, 244, 0, 0, 0, 1, 6, 64, 0, 0, 0, 0, 0, 0, 0, 255, 255, 255, 255, 0, 0, 0, 1, 0, 9, 105, 109, 112, 111, 114, 116, 97, 110, 116, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 19, 0, 0, 0, 0, 0, 0, 0, 0, 0, 16, 0, 0, 0, 0, 0, 0, 0, 0}
+)
+
+type kafkaBuf []byte
+
+func (b kafkaBuf) append(v ...byte) kafkaBuf { return append(b, v...) }
+func (b kafkaBuf) i16(v int16) kafkaBuf      { return binary.BigEndian.AppendUint16(b, uint16(v)) }
+func (b kafkaBuf) i32(v int32) kafkaBuf      { return binary.BigEndian.AppendUint32(b, uint32(v)) }
+func (b kafkaBuf) i64(v int64) kafkaBuf      { return binary.BigEndian.AppendUint64(b, uint64(v)) }
+func (b kafkaBuf) str(s string) kafkaBuf {
+	return append(b.i16(int16(len(s))), s...)
+}
+
+// flexible versions use unsigned varints for the lengths and tagged fields
+func (b kafkaBuf) uvar(v int) kafkaBuf { return binary.AppendUvarint(b, uint64(v)) }
+func (b kafkaBuf) cstr(s string) kafkaBuf {
+	return append(b.uvar(len(s)+1), s...)
+}
+
+// sized prepends the message size
+func (b kafkaBuf) sized() kafkaBuf {
+	return append(kafkaBuf(nil).i32(int32(len(b))), b...)
+}
+
+// recordBatch returns the header of a record batch, without records
+func kafkaRecordBatch(baseOffset int64, count int32) kafkaBuf {
+	b := kafkaBuf(nil).i64(baseOffset).i32(49).i32(0)
+	b = append(b, kafkaBatchMagic)
+	b = b.i32(0).i16(0).i32(count - 1).i64(0).i64(0).i64(-1).i16(-1).i32(-1).i32(count)
+	return b
+}
+
+func TestKafkaProduceInfo(t *testing.T) {
+	resp := kafkaBuf(nil).i32(50).i32(2).i32(1).str("important").i32(1).
+		i32(0).i16(0).i64(41)
+	info := kafkaPartitionInfo(Produce, kafkaProduceV7Req, resp)
+	require.NotNil(t, info)
+	assert.Equal(t, request.MessagingInfo{
+		Partition: 0, Messages: 1, Bytes: 72, Offset: 41, HighWatermark: -1,
+	}, *info)
+	_, ok := info.ConsumerLag()
+	assert.False(t, ok)
+
+	// without response, the offset is unknown
+	info = kafkaPartitionInfo(Produce, kafkaProduceV7Req, nil)
+	require.NotNil(t, info)
+	assert.Equal(t, int64(-1), info.Offset)
+	assert.Equal(t, 1, info.Messages)
+
+	// failed produce
+	resp = kafkaBuf(nil).i32(50).i32(2).i32(1).str("important").i32(1).
+		i32(0).i16(6).i64(-1)
+	info = kafkaPartitionInfo(Produce, kafkaProduceV7Req, resp)
+	require.NotNil(t, info)
+	assert.Equal(t, int64(-1), info.Offset)
+}
+
+func TestKafkaProduceInfo_Flexible(t *testing.T) {
+	records := kafkaRecordBatch(0, 4)
+	// header, ending with tagged fields
+	req := kafkaBuf(nil).i16(0).i16(9).i32(2).str("sarama").uvar(0)
+	// null transactional ID, acks and timeout
+	req = req.uvar(0).i16(-1).i32(10000)
+	req = req.uvar(2).cstr("important").uvar(2).i32(3).uvar(len(records) + 1)
+	req = append(req, records...).uvar(0).uvar(0).uvar(0).sized()
+	resp := kafkaBuf(nil).i32(2).uvar(0).
+		uvar(2).cstr("important").uvar(2).
+		i32(3).i16(0).i64(100).sized()
+
+	info := kafkaPartitionInfo(Produce, req, resp)
+	require.NotNil(t, info)
+	assert.Equal(t, request.MessagingInfo{
+		Partition: 3, Messages: 4, Bytes: len(records), Offset: 103, HighWatermark: -1,
+	}, *info)
+}
+
+func TestKafkaFetchInfo(t *testing.T) {
+	records := kafkaRecordBatch(19, 5)
+	resp := kafkaBuf(nil).i32(200).i32(224).
+		i32(0).i16(0).i32(0). // throttle time, error code, session ID
+		i32(1).str("important").i32(1).
+		i32(0).i16(0).i64(30).          // partition, error code, high watermark
+		i64(30).i64(0).i32(-1).i32(-1). // last stable offset, log start offset, aborted txs, preferred replica
+		i32(100)
+	resp = append(resp, records...)
+
+	info := kafkaPartitionInfo(Fetch, kafkaFetchV11Req, resp[:min(len(resp), 128)])
+	require.NotNil(t, info)
+	assert.Equal(t, request.MessagingInfo{
+		Partition: 0, Messages: 5, Bytes: 100, Offset: 23, HighWatermark: 30,
+	}, *info)
+	lag, ok := info.ConsumerLag()
+	require.True(t, ok)
+	assert.Equal(t, int64(6), lag)
+
+	// if the response does not contain records, the consumed offset is taken from the request
+	resp = kafkaBuf(nil).i32(200).i32(224).i32(0).i16(0).i32(0).
+		i32(1).str("important").i32(1).
+		i32(0).i16(0).i64(30).i64(30).i64(0).i32(-1).i32(-1).i32(0)
+	info = kafkaPartitionInfo(Fetch, kafkaFetchV11Req, resp)
+	require.NotNil(t, info)
+	assert.Equal(t, request.MessagingInfo{
+		Partition: 0, Messages: 0, Bytes: 0, Offset: 18, HighWatermark: 30,
+	}, *info)
+	lag, ok = info.ConsumerLag()
+	require.True(t, ok)
+	assert.Equal(t, int64(11), lag)
+
+	// without response, only the request side is known
+	info = kafkaPartitionInfo(Fetch, kafkaFetchV11Req, nil)
+	require.NotNil(t, info)
+	assert.Equal(t, int64(18), info.Offset)
+	assert.Equal(t, int64(-1), info.HighWatermark)
+}
+
+func TestKafkaFetchInfo_Flexible(t *testing.T) {
+	// header, ending with tagged fields
+	req := kafkaBuf(nil).i16(1).i16(12).i32(224).str("sarama").uvar(0)
+	// replica ID, max wait, min and max bytes, isolation level, session ID and epoch
+	req = req.i32(-1).i32(500).i32(1).i32(1024).append(0).i32(0).i32(0)
+	// partition, leader epoch, fetch offset, last fetched epoch, log start offset and max bytes
+	req = req.uvar(2).cstr("important").uvar(2).
+		i32(2).i32(-1).i64(19).i32(-1).i64(0).i32(1024).uvar(0).uvar(0)
+	// forgotten topics, rack ID and tagged fields
+	req = req.uvar(1).cstr("").uvar(0).sized()
+
+	records := kafkaRecordBatch(19, 5)
+	// header, throttle time, error code and session ID
+	resp := kafkaBuf(nil).i32(224).uvar(0).i32(0).i16(0).i32(0)
+	// partition, error code, high watermark, last stable offset, log start offset,
+	// aborted transactions and preferred replica
+	resp = resp.uvar(2).cstr("important").uvar(2).
+		i32(2).i16(0).i64(30).i64(30).i64(0).uvar(0).i32(-1).
+		uvar(len(records) + 1)
+	resp = append(resp, records...).uvar(0).uvar(0).uvar(0).sized()
+
+	info := kafkaPartitionInfo(Fetch, req, resp)
+	require.NotNil(t, info)
+	assert.Equal(t, request.MessagingInfo{
+		Partition: 2, Messages: 5, Bytes: len(records), Offset: 23, HighWatermark: 30,
+	}, *info)
+
+	// without response, the consumed offset is taken from the request
+	info = kafkaPartitionInfo(Fetch, req, nil)
+	require.NotNil(t, info)
+	assert.Equal(t, 2, info.Partition)
+	assert.Equal(t, int64(18), info.Offset)
+}
+
+func TestKafkaPartitionInfo_Truncated(t *testing.T) {
+	for i := 0; i < len(kafkaFetchV11Req); i++ {
+		// must not panic
+		kafkaPartitionInfo(Fetch, kafkaFetchV11Req[:i], nil)
+		kafkaPartitionInfo(Produce, kafkaProduceV7Req[:min(i, len(kafkaProduceV7Req))], kafkaFetchV11Req[:i])
+	}
+	assert.Nil(t, kafkaPartitionInfo(Produce, kafkaProduceV7Req[:40], nil))
+}
@@ -0,0 +1,372 @@
+package ebpfcommon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"unsafe"
+
+	trace2 "go.opentelemetry.io/otel/trace"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// https://www.mongodb.com/docs/manual/reference/mongodb-wire-protocol/
+const (
+	mongoHeaderLen     = 16
+	mongoMaxMessageLen = 48 * 1024 * 1024 // maxMessageSizeBytes for MongoDB servers
+
+	mongoOpQuery = 2004
+	mongoOpMsg   = 2013
+
+	// OP_MSG section kinds
+	mongoSectionBody     = 0
+	mongoSectionSequence = 1
+)
+
+// BSON element types
+const (
+	bsonDouble     = 0x01
+	bsonString     = 0x02
+	bsonDocument   = 0x03
+	bsonArray      = 0x04
+	bsonBinary     = 0x05
+	bsonUndefined  = 0x06
+	bsonObjectID   = 0x07
+	bsonBoolean    = 0x08
+	bsonDateTime   = 0x09
+	bsonNull       = 0x0A
+	bsonRegex      = 0x0B
+	bsonInt32      = 0x10
+	bsonTimestamp  = 0x11
+	bsonInt64      = 0x12
+	bsonDecimal128 = 0x13
+	bsonMinKey     = 0xFF
+	bsonMaxKey     = 0x7F
+)
+
+var errTruncatedBSON = errors.New("truncated BSON document")
+
+type mongoHeader struct {
+	MessageLength int32
+	RequestID     int32
+	ResponseTo    int32
+	OpCode        int32
+}
+
+type mongoCommand struct {
+	operation  string
+	collection string
+}
+
+func parseMongoHeader(buf []byte) (mongoHeader, bool) {
+	if len(buf) < mongoHeaderLen {
+		return mongoHeader{}, false
+	}
+	h := mongoHeader{
+		MessageLength: int32(binary.LittleEndian.Uint32(buf[0:4])),
+		RequestID:     int32(binary.LittleEndian.Uint32(buf[4:8])),
+		ResponseTo:    int32(binary.LittleEndian.Uint32(buf[8:12])),
+		OpCode:        int32(binary.LittleEndian.Uint32(buf[12:16])),
+	}
+	if h.MessageLength <= mongoHeaderLen || h.MessageLength > mongoMaxMessageLen {
+		return h, false
+	}
+	return h, h.OpCode == mongoOpMsg || h.OpCode == mongoOpQuery
+}
+
+// isMongoRequest returns true if the buffer starts with the header of a MongoDB request.
+// Responses are discarded, as they always refer to a previous request.
+func isMongoRequest(buf []byte) bool {
+	h, ok := parseMongoHeader(buf)
+	return ok && h.ResponseTo == 0
+}
+
+// parseMongoRequest extracts the command name and the target collection
+// from an OP_MSG or OP_QUERY request.
+func parseMongoRequest(buf []byte) (mongoCommand, error) {
+	h, ok := parseMongoHeader(buf)
+	if !ok || h.ResponseTo != 0 {
+		return mongoCommand{}, errors.New("not a MongoDB request")
+	}
+	body := buf[mongoHeaderLen:]
+	switch h.OpCode {
+	case mongoOpMsg:
+		doc, err := opMsgBody(body)
+		if err != nil {
+			return mongoCommand{}, err
+		}
+		return commandFromDocument(doc)
+	default: // OP_QUERY
+		return parseOpQuery(body)
+	}
+}
+
+// opMsgBody returns the body document of an OP_MSG message, skipping
+// any document sequence section that could precede it
+func opMsgBody(body []byte) ([]byte, error) {
+	// skip flagBits
+	if len(body) < 5 {
+		return nil, errTruncatedBSON
+	}
+	body = body[4:]
+	for len(body) > 0 {
+		kind := body[0]
+		body = body[1:]
+		switch kind {
+		case mongoSectionBody:
+			return body, nil
+		case mongoSectionSequence:
+			if len(body) < 4 {
+				return nil, errTruncatedBSON
+			}
+			size := int(int32(binary.LittleEndian.Uint32(body)))
+			if size < 4 || size > len(body) {
+				return nil, errTruncatedBSON
+			}
+			body = body[size:]
+		default:
+			return nil, errors.New("unknown OP_MSG section kind")
+		}
+	}
+	return nil, errTruncatedBSON
+}
+
+// parseOpQuery handles the legacy OP_QUERY messages. Commands are sent to the
+// special "<db>.$cmd" collection, while the rest of the messages are queries.
+func parseOpQuery(body []byte) (mongoCommand, error) {
+	// skip flags
+	if len(body) < 4 {
+		return mongoCommand{}, errTruncatedBSON
+	}
+	fullName, rest, ok := readCString(body[4:])
+	if !ok {
+		return mongoCommand{}, errTruncatedBSON
+	}
+	_, collection, found := bytes.Cut([]byte(fullName), []byte{'.'})
+	if !found || len(collection) == 0 {
+		return mongoCommand{}, errors.New("invalid collection name")
+	}
+	if string(collection) != "$cmd" {
+		return mongoCommand{operation: "find", collection: string(collection)}, nil
+	}
+	// skip numberToSkip and numberToReturn
+	if len(rest) < 8 {
+		return mongoCommand{}, errTruncatedBSON
+	}
+	return commandFromDocument(rest[8:])
+}
+
+// commandFromDocument returns the command of a MongoDB command document, which
+// is the name of its first element. The value of the first element is usually the collection name.
+func commandFromDocument(doc []byte) (mongoCommand, error) {
+	var cmd mongoCommand
+	first := true
+	err := walkBSON(doc, func(eType byte, key string, value []byte) bool {
+		if first {
+			first = false
+			if !validMongoName(key) {
+				return false
+			}
+			cmd.operation = key
+			if eType == bsonString {
+				cmd.collection = bsonStringValue(value)
+				return false
+			}
+			// some commands (e.g. getMore) specify the collection in another field
+			return true
+		}
+		if key == "collection" && eType == bsonString {
+			cmd.collection = bsonStringValue(value)
+			return false
+		}
+		return true
+	})
+	if cmd.operation == "" {
+		if err == nil {
+			err = errors.New("missing MongoDB command")
+		}
+		return cmd, err
+	}
+	// truncated documents are expected, as the eBPF buffers have a limited size
+	return cmd, nil
+}
+
+// mongoStatus returns 1 if the provided response reports that the command failed.
+// Since the "ok" field of successful responses is usually after the returned documents,
+// it might be missing if the response was truncated, so the response is then considered successful.
+func mongoStatus(buf []byte) int {
+	h, ok := parseMongoHeader(buf)
+	if !ok || h.OpCode != mongoOpMsg || h.ResponseTo == 0 {
+		return 0
+	}
+	doc, err := opMsgBody(buf[mongoHeaderLen:])
+	if err != nil {
+		return 0
+	}
+	status := 0
+	_ = walkBSON(doc, func(eType byte, key string, value []byte) bool {
+		if key != "ok" {
+			return true
+		}
+		switch eType {
+		case bsonDouble:
+			if math.Float64frombits(binary.LittleEndian.Uint64(value)) == 0 {
+				status = 1
+			}
+		case bsonInt32:
+			if binary.LittleEndian.Uint32(value) == 0 {
+				status = 1
+			}
+		case bsonInt64:
+			if binary.LittleEndian.Uint64(value) == 0 {
+				status = 1
+			}
+		case bsonBoolean:
+			if value[0] == 0 {
+				status = 1
+			}
+		}
+		return false
+	})
+	return status
+}
+
+// walkBSON invokes the visitor function for each element of the provided BSON document,
+// until the document ends, it is truncated, or the visitor returns false.
+func walkBSON(doc []byte, visit func(eType byte, key string, value []byte) bool) error {
+	if len(doc) < 5 {
+		return errTruncatedBSON
+	}
+	buf := doc[4:]
+	for len(buf) > 0 {
+		eType := buf[0]
+		if eType == 0 {
+			return nil // end of document
+		}
+		key, rest, ok := readCString(buf[1:])
+		if !ok {
+			return errTruncatedBSON
+		}
+		size, ok := bsonValueSize(eType, rest)
+		if !ok {
+			return errTruncatedBSON
+		}
+		if size < 0 || size > len(rest) {
+			return errTruncatedBSON
+		}
+		if !visit(eType, key, rest[:size]) {
+			return nil
+		}
+		buf = rest[size:]
+	}
+	return errTruncatedBSON
+}
+
+// nolint:cyclop
+func bsonValueSize(eType byte, buf []byte) (int, bool) {
+	switch eType {
+	case bsonUndefined, bsonNull, bsonMinKey, bsonMaxKey:
+		return 0, true
+	case bsonBoolean:
+		return 1, true
+	case bsonInt32:
+		return 4, true
+	case bsonDouble, bsonDateTime, bsonTimestamp, bsonInt64:
+		return 8, true
+	case bsonObjectID:
+		return 12, true
+	case bsonDecimal128:
+		return 16, true
+	case bsonString:
+		if len(buf) < 4 {
+			return 0, false
+		}
+		return 4 + int(int32(binary.LittleEndian.Uint32(buf))), true
+	case bsonDocument, bsonArray:
+		if len(buf) < 4 {
+			return 0, false
+		}
+		return int(int32(binary.LittleEndian.Uint32(buf))), true
+	case bsonBinary:
+		if len(buf) < 4 {
+			return 0, false
+		}
+		return 5 + int(int32(binary.LittleEndian.Uint32(buf))), true
+	case bsonRegex:
+		_, rest, ok := readCString(buf)
+		if !ok {
+			return 0, false
+		}
+		_, rest, ok = readCString(rest)
+		if !ok {
+			return 0, false
+		}
+		return len(buf) - len(rest), true
+	}
+	return 0, false
+}
+
+func bsonStringValue(value []byte) string {
+	// value contains the length, the string and the null terminator
+	if len(value) < 5 {
+		return ""
+	}
+	return string(value[4 : len(value)-1])
+}
+
+func readCString(buf []byte) (string, []byte, bool) {
+	end := bytes.IndexByte(buf, 0)
+	if end < 0 {
+		return "", nil, false
+	}
+	return string(buf[:end]), buf[end+1:], true
+}
+
+func validMongoName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range []byte(name) {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '$') {
+			return false
+		}
+	}
+	return true
+}
+
+func TCPToMongoToSpan(trace *TCPRequestInfo, cmd mongoCommand, status int) request.Span {
+	peer := ""
+	hostname := ""
+	hostPort := 0
+
+	if trace.ConnInfo.S_port != 0 || trace.ConnInfo.D_port != 0 {
+		peer, hostname = (*BPFConnInfo)(unsafe.Pointer(&trace.ConnInfo)).reqHostInfo()
+		hostPort = int(trace.ConnInfo.D_port)
+	}
+
+	return request.Span{
+		Type:          request.EventTypeMongoClient,
+		Method:        cmd.operation,
+		Path:          cmd.collection,
+		Peer:          peer,
+		PeerPort:      int(trace.ConnInfo.S_port),
+		Host:          hostname,
+		HostPort:      hostPort,
+		ContentLength: 0,
+		RequestStart:  int64(trace.StartMonotimeNs),
+		Start:         int64(trace.StartMonotimeNs),
+		End:           int64(trace.EndMonotimeNs),
+		Status:        status,
+		TraceID:       trace2.TraceID(trace.Tp.TraceId),
+		SpanID:        trace2.SpanID(trace.Tp.SpanId),
+		ParentSpanID:  trace2.SpanID(trace.Tp.ParentId),
+		Flags:         trace.Tp.Flags,
+		Pid: request.PidInfo{
+			HostPID:   trace.Pid.HostPid,
+			UserPID:   trace.Pid.UserPid,
+			Namespace: trace.Pid.Ns,
+		},
+	}
+}
@@ -0,0 +1,158 @@
+package ebpfcommon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// bsonElement builds a BSON element of the given type, key and raw value
+func bsonElement(eType byte, key string, value []byte) []byte {
+	e := append([]byte{eType}, key...)
+	e = append(e, 0)
+	return append(e, value...)
+}
+
+func bsonStr(key, value string) []byte {
+	v := binary.LittleEndian.AppendUint32(nil, uint32(len(value)+1))
+	v = append(v, value...)
+	return bsonElement(bsonString, key, append(v, 0))
+}
+
+func bsonDoc(elements ...[]byte) []byte {
+	body := bytes.Join(elements, nil)
+	doc := binary.LittleEndian.AppendUint32(nil, uint32(len(body)+5))
+	doc = append(doc, body...)
+	return append(doc, 0)
+}
+
+func mongoMessage(opCode, responseTo int32, body []byte) []byte {
+	msg := binary.LittleEndian.AppendUint32(nil, uint32(mongoHeaderLen+len(body)))
+	msg = binary.LittleEndian.AppendUint32(msg, 1234)
+	msg = binary.LittleEndian.AppendUint32(msg, uint32(responseTo))
+	msg = binary.LittleEndian.AppendUint32(msg, uint32(opCode))
+	return append(msg, body...)
+}
+
+func opMsg(responseTo int32, doc []byte) []byte {
+	return mongoMessage(mongoOpMsg, responseTo, append([]byte{0, 0, 0, 0, mongoSectionBody}, doc...))
+}
+
+func okDoc(ok float64) []byte {
+	return bsonDoc(bsonElement(bsonDouble, "ok", binary.LittleEndian.AppendUint64(nil, math.Float64bits(ok))))
+}
+
+func TestParseMongoRequest(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		msg  []byte
+		cmd  mongoCommand
+	}{{
+		name: "OP_MSG find",
+		msg: opMsg(0, bsonDoc(
+			bsonStr("find", "users"),
+			bsonDoc(),
+			bsonStr("$db", "shop"),
+		)),
+		cmd: mongoCommand{operation: "find", collection: "users"},
+	}, {
+		name: "OP_MSG insert with document sequence",
+		msg: mongoMessage(mongoOpMsg, 0, bytes.Join([][]byte{
+			{0, 0, 0, 0},
+			{mongoSectionSequence}, append(binary.LittleEndian.AppendUint32(nil, 15), "documents\x00\x00\x00"...)[:15],
+			{mongoSectionBody}, bsonDoc(bsonStr("insert", "orders")),
+		}, nil)),
+		cmd: mongoCommand{operation: "insert", collection: "orders"},
+	}, {
+		name: "OP_MSG getMore",
+		msg: opMsg(0, bsonDoc(
+			bsonElement(bsonInt64, "getMore", make([]byte, 8)),
+			bsonStr("collection", "products"),
+		)),
+		cmd: mongoCommand{operation: "getMore", collection: "products"},
+	}, {
+		name: "OP_QUERY command",
+		msg: mongoMessage(mongoOpQuery, 0, bytes.Join([][]byte{
+			{0, 0, 0, 0}, []byte("shop.$cmd\x00"), make([]byte, 8),
+			bsonDoc(bsonStr("aggregate", "sales")),
+		}, nil)),
+		cmd: mongoCommand{operation: "aggregate", collection: "sales"},
+	}, {
+		name: "OP_QUERY legacy query",
+		msg: mongoMessage(mongoOpQuery, 0, bytes.Join([][]byte{
+			{0, 0, 0, 0}, []byte("shop.users\x00"), make([]byte, 8), bsonDoc(),
+		}, nil)),
+		cmd: mongoCommand{operation: "find", collection: "users"},
+	}, {
+		name: "truncated document",
+		msg:  opMsg(0, bsonDoc(bsonStr("update", "users"), bsonStr("filter", "long filter value")))[:48],
+		cmd:  mongoCommand{operation: "update", collection: "users"},
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.True(t, isMongoRequest(tc.msg))
+			cmd, err := parseMongoRequest(tc.msg)
+			require.NoError(t, err)
+			assert.Equal(t, tc.cmd, cmd)
+		})
+	}
+}
+
+func TestParseMongoRequest_Invalid(t *testing.T) {
+	for _, msg := range [][]byte{
+		nil,
+		[]byte("SELECT * FROM users"),
+		// response
+		opMsg(33, bsonDoc(bsonStr("find", "users"))),
+		// unsupported opcode
+		mongoMessage(2012, 0, make([]byte, 20)),
+		// invalid command name
+		opMsg(0, bsonDoc(bsonStr("fi nd", "users"))),
+	} {
+		_, err := parseMongoRequest(msg)
+		assert.Error(t, err)
+	}
+}
+
+func TestMongoStatus(t *testing.T) {
+	assert.Equal(t, 0, mongoStatus(opMsg(1, okDoc(1))))
+	assert.Equal(t, 1, mongoStatus(opMsg(1, okDoc(0))))
+	// truncated response
+	assert.Equal(t, 0, mongoStatus(opMsg(1, okDoc(0))[:30]))
+	// not a response
+	assert.Equal(t, 0, mongoStatus(opMsg(0, okDoc(0))))
+}
+
+func TestMongoDetector(t *testing.T) {
+	req := opMsg(0, bsonDoc(bsonStr("delete", "sessions")))
+	resp := opMsg(1234, okDoc(0))
+
+	event := makeTCPReq(string(req), tcpSend, 343534, 27017, 2000)
+	span, ignore, err := detectProtocol(&event, req, resp)
+	require.NoError(t, err)
+	require.False(t, ignore)
+	assert.Equal(t, request.EventTypeMongoClient, span.Type)
+	assert.Equal(t, "delete", span.Method)
+	assert.Equal(t, "sessions", span.Path)
+	assert.Equal(t, 1, span.Status)
+	assert.Equal(t, 27017, span.HostPort)
+
+	// event captured in reverse order
+	event = makeTCPReq(string(resp), tcpRecv, 27017, 343534, 2000)
+	span, ignore, err = detectProtocol(&event, resp, req)
+	require.NoError(t, err)
+	require.False(t, ignore)
+	assert.Equal(t, "delete", span.Method)
+	assert.Equal(t, 27017, span.HostPort)
+
+	// server-side events are ignored
+	event = makeTCPReq(string(req), tcpRecv, 343534, 27017, 2000)
+	_, ignore, err = detectProtocol(&event, req, resp)
+	require.NoError(t, err)
+	assert.True(t, ignore)
+}
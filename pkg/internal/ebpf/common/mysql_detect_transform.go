@@ -0,0 +1,129 @@
+package ebpfcommon
+
+import (
+	"encoding/binary"
+	"strconv"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// https://dev.mysql.com/doc/dev/mysql-server/latest/page_protocol_command_phase.html
+const (
+	mysqlHeaderLen     = 4
+	mysqlMaxPayloadLen = 1<<24 - 1
+
+	mysqlComQuery       = 0x03
+	mysqlComStmtPrepare = 0x16
+	mysqlComStmtExecute = 0x17
+	mysqlComStmtClose   = 0x19
+
+	mysqlOK  = 0x00
+	mysqlERR = 0xFF
+
+	// minimum length of a COM_STMT_EXECUTE payload: command, statement ID, flags and iteration count
+	mysqlStmtExecuteMinLen = 10
+)
+
+// mysqlPacket returns the payload of the first MySQL packet in the buffer, which might be truncated,
+// along with its sequence ID
+func mysqlPacket(buf []byte) ([]byte, uint8, bool) {
+	if len(buf) <= mysqlHeaderLen {
+		return nil, 0, false
+	}
+	size := int(buf[0]) | int(buf[1])<<8 | int(buf[2])<<16
+	if size == 0 || size > mysqlMaxPayloadLen {
+		return nil, 0, false
+	}
+	end := mysqlHeaderLen + size
+	if end > len(buf) {
+		end = len(buf)
+	}
+	return buf[mysqlHeaderLen:end], buf[3], true
+}
+
+// isMySQLRequest returns whether the buffer contains a single MySQL command packet
+// of a type that we can handle
+func isMySQLRequest(buf []byte) bool {
+	payload, seq, ok := mysqlPacket(buf)
+	// commands always start a new sequence, and they can't be followed by other packets
+	if !ok || seq != 0 || mysqlHeaderLen+len(payload) != len(buf) {
+		return false
+	}
+	switch payload[0] {
+	case mysqlComQuery, mysqlComStmtPrepare:
+		return len(payload) > 1 && isMySQLQueryText(mysqlQueryText(payload))
+	case mysqlComStmtExecute:
+		return len(payload) >= mysqlStmtExecuteMinLen
+	case mysqlComStmtClose:
+		return len(payload) == 5
+	}
+	return false
+}
+
+// mysqlQueryText returns the query of a COM_QUERY or COM_STMT_PREPARE payload
+func mysqlQueryText(payload []byte) []byte {
+	text := payload[1:]
+	// when the client supports query attributes, COM_QUERY prepends the number of parameters
+	// and parameter sets. Beyla only handles the most common case: no parameters
+	if payload[0] == mysqlComQuery && len(text) > 2 && text[0] == 0 && text[1] == 1 {
+		text = text[2:]
+	}
+	return text
+}
+
+func isMySQLQueryText(text []byte) bool {
+	if len(text) == 0 {
+		return false
+	}
+	for _, c := range text[:min(len(text), 16)] {
+		if c < ' ' && c != '\t' && c != '\n' && c != '\r' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseMySQLRequest returns the SQL statement of a MySQL command. The text of the prepared
+// statements is remembered from the response to COM_STMT_PREPARE, so later executions
+// can be linked to it by means of the statement ID.
+// It returns false if the request does not need to be reported.
+func parseMySQLRequest(conn *BPFConnInfo, req, resp []byte) (sqlRequest, bool) {
+	payload, _, _ := mysqlPacket(req)
+	switch payload[0] {
+	case mysqlComQuery:
+		return sqlRequest{query: string(mysqlQueryText(payload))}, true
+	case mysqlComStmtPrepare:
+		query := string(mysqlQueryText(payload))
+		if respPayload, _, ok := mysqlPacket(resp); ok && respPayload[0] == mysqlOK && len(respPayload) >= 5 {
+			rememberStatement(conn, mysqlStmtID(respPayload[1:5]), query)
+		}
+		return sqlRequest{query: query, prepare: true}, true
+	case mysqlComStmtExecute:
+		query, _ := preparedStatement(conn, mysqlStmtID(payload[1:5]))
+		return sqlRequest{query: query}, true
+	default: // COM_STMT_CLOSE
+		forgetStatement(conn, mysqlStmtID(payload[1:5]))
+		// there is no response for this command
+		return sqlRequest{}, false
+	}
+}
+
+func mysqlStmtID(b []byte) string {
+	return strconv.FormatUint(uint64(binary.LittleEndian.Uint32(b)), 10)
+}
+
+// mysqlError returns the error reported by the server in the response buffer, if any.
+// The error code is the MySQL error number.
+func mysqlError(buf []byte) (request.DBError, bool) {
+	payload, _, ok := mysqlPacket(buf)
+	if !ok || payload[0] != mysqlERR || len(payload) < 3 {
+		return request.DBError{}, false
+	}
+	code := int(binary.LittleEndian.Uint16(payload[1:3]))
+	msg := payload[3:]
+	// skip the SQL state marker and the SQL state
+	if len(msg) >= 6 && msg[0] == '#' {
+		msg = msg[6:]
+	}
+	return request.DBError{ErrorCode: strconv.Itoa(code), Description: string(msg)}, true
+}
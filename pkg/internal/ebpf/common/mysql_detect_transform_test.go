@@ -0,0 +1,92 @@
+package ebpfcommon
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+func mysqlPkt(seq uint8, payload ...byte) []byte {
+	return append([]byte{byte(len(payload)), byte(len(payload) >> 8), byte(len(payload) >> 16), seq}, payload...)
+}
+
+func mysqlCmd(cmd byte, data string) []byte {
+	return mysqlPkt(0, append([]byte{cmd}, data...)...)
+}
+
+func mysqlStmtCmd(cmd byte, stmtID uint32, extra ...byte) []byte {
+	payload := binary.LittleEndian.AppendUint32([]byte{cmd}, stmtID)
+	return mysqlPkt(0, append(payload, extra...)...)
+}
+
+func TestMySQLDetector(t *testing.T) {
+	okPkt := mysqlPkt(1, 0x00, 0x01, 0x00, 0x02, 0x00, 0x00, 0x00)
+
+	req := mysqlCmd(mysqlComQuery, "SELECT * FROM accounts")
+	event := makeTCPReq(string(req), tcpSend, 343534, 3306, 2000)
+	span, ignore, err := detectProtocol(&event, req, okPkt)
+	require.NoError(t, err)
+	require.False(t, ignore)
+	assert.Equal(t, request.EventTypeSQLClient, span.Type)
+	assert.Equal(t, request.DBMySQL, span.SubType)
+	assert.Equal(t, "SELECT", span.Method)
+	assert.Equal(t, "accounts", span.Path)
+	assert.Zero(t, span.Status)
+
+	// error response
+	errPkt := mysqlPkt(1, append([]byte{0xFF, 0x7a, 0x04, '#', '4', '2', 'S', '0', '2'}, "Table 'foo' doesn't exist"...)...)
+	req = mysqlCmd(mysqlComQuery, "SELECT * FROM foo")
+	event = makeTCPReq(string(req), tcpSend, 343534, 3306, 2000)
+	span, _, err = detectProtocol(&event, req, errPkt)
+	require.NoError(t, err)
+	assert.Equal(t, 1, span.Status)
+	assert.Equal(t, request.DBError{ErrorCode: "1146", Description: "Table 'foo' doesn't exist"}, span.DBError)
+}
+
+func TestMySQLDetector_PreparedStatements(t *testing.T) {
+	// COM_STMT_PREPARE_OK with statement ID 7
+	prepareOK := mysqlPkt(1, 0x00, 0x07, 0x00, 0x00, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00)
+	req := mysqlCmd(mysqlComStmtPrepare, "UPDATE users SET name = ? WHERE id = ?")
+	event := makeTCPReq(string(req), tcpSend, 343534, 3306, 2000)
+	span, ignore, err := detectProtocol(&event, req, prepareOK)
+	require.NoError(t, err)
+	require.False(t, ignore)
+	assert.Equal(t, "PREPARE", span.Method)
+	assert.Equal(t, "users", span.Path)
+
+	req = mysqlStmtCmd(mysqlComStmtExecute, 7, 0x00, 0x01, 0x00, 0x00, 0x00)
+	event = makeTCPReq(string(req), tcpSend, 343534, 3306, 2000)
+	span, ignore, err = detectProtocol(&event, req, mysqlPkt(1, 0x00, 0x01, 0x00))
+	require.NoError(t, err)
+	require.False(t, ignore)
+	assert.Equal(t, "UPDATE", span.Method)
+	assert.Equal(t, "users", span.Path)
+	assert.Equal(t, "UPDATE users SET name = ? WHERE id = ?", span.Statement)
+
+	// closing the statement is not reported, but it is forgotten
+	req = mysqlStmtCmd(mysqlComStmtClose, 7)
+	event = makeTCPReq(string(req), tcpSend, 343534, 3306, 2000)
+	_, ignore, err = detectProtocol(&event, req, nil)
+	require.NoError(t, err)
+	assert.True(t, ignore)
+	_, found := preparedStatement((*BPFConnInfo)(&event.ConnInfo), "7")
+	assert.False(t, found)
+}
+
+func TestIsMySQLRequest(t *testing.T) {
+	assert.True(t, isMySQLRequest(mysqlCmd(mysqlComQuery, "select 1")))
+	// query attributes
+	assert.True(t, isMySQLRequest(mysqlCmd(mysqlComQuery, "\x00\x01select 1")))
+	assert.False(t, isMySQLRequest(nil))
+	assert.False(t, isMySQLRequest([]byte("SELECT * FROM accounts")))
+	// responses start with a sequence ID higher than zero
+	assert.False(t, isMySQLRequest(mysqlPkt(1, 0x03, 's', 'e', 'l')))
+	// binary garbage
+	assert.False(t, isMySQLRequest(mysqlCmd(mysqlComQuery, "\x01\x02\x03\x04")))
+	// extra data after the packet
+	assert.False(t, isMySQLRequest(append(mysqlCmd(mysqlComQuery, "select 1"), 1, 2, 3)))
+}
@@ -215,6 +215,7 @@ func serviceInfo(pid uint32) svc.ID {
 	name := commName(pid)
 	lang := exec.FindProcLanguage(int32(pid), nil, name)
 	result := svc.ID{Name: name, SDKLanguage: lang, ProcPID: int32(pid)}
+	result.RuntimeName, result.RuntimeVersion = exec.FindProcRuntime(int32(pid), lang)
 
 	activePids.Add(pid, result)
 
@@ -0,0 +1,166 @@
+package ebpfcommon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// https://www.postgresql.org/docs/current/protocol-message-formats.html
+const (
+	pgHeaderLen     = 5
+	pgMaxMessageLen = 16 * 1024 * 1024
+
+	// frontend messages
+	pgQuery    = 'Q'
+	pgParse    = 'P'
+	pgBind     = 'B'
+	pgExecute  = 'E'
+	pgDescribe = 'D'
+	pgSync     = 'S'
+	pgClose    = 'C'
+	pgFlush    = 'H'
+
+	// backend messages
+	pgErrorResponse = 'E'
+
+	// ErrorResponse fields
+	pgFieldSQLState = 'C'
+	pgFieldMessage  = 'M'
+)
+
+type pgMessage struct {
+	kind byte
+	// payload of the message, which might be truncated
+	payload []byte
+}
+
+// pgMessages splits the buffer into Postgres protocol messages. The last message might be truncated.
+// It returns false if the buffer doesn't look like a sequence of Postgres messages.
+func pgMessages(buf []byte, validKind func(byte) bool) ([]pgMessage, bool) {
+	var msgs []pgMessage
+	for len(buf) >= pgHeaderLen {
+		size := int(int32(binary.BigEndian.Uint32(buf[1:pgHeaderLen])))
+		if !validKind(buf[0]) || size < 4 || size > pgMaxMessageLen {
+			return nil, false
+		}
+		end := 1 + size
+		if end > len(buf) {
+			end = len(buf)
+		}
+		msgs = append(msgs, pgMessage{kind: buf[0], payload: buf[pgHeaderLen:end]})
+		buf = buf[end:]
+	}
+	return msgs, len(msgs) > 0
+}
+
+func isPGFrontendKind(kind byte) bool {
+	switch kind {
+	case pgQuery, pgParse, pgBind, pgExecute, pgDescribe, pgSync, pgClose, pgFlush:
+		return true
+	}
+	return false
+}
+
+func isPGBackendKind(kind byte) bool {
+	switch kind {
+	case '1', '2', '3', 'C', 'D', 'E', 'I', 'K', 'n', 'N', 's', 'S', 't', 'T', 'Z':
+		return true
+	}
+	return false
+}
+
+// isPostgresRequest returns whether the buffer starts with a Postgres query, or with the
+// first message of the extended query protocol.
+func isPostgresRequest(buf []byte) bool {
+	msgs, ok := pgMessages(buf, isPGFrontendKind)
+	if !ok {
+		return false
+	}
+	switch msgs[0].kind {
+	case pgQuery, pgParse, pgBind:
+		return true
+	}
+	return false
+}
+
+// parsePostgresRequest extracts the executed SQL statement from a simple query, or from the
+// Parse/Bind/Execute messages of the extended query protocol. Statements are remembered
+// at Parse time, so later Bind messages can be linked to their text.
+func parsePostgresRequest(conn *BPFConnInfo, buf []byte) (sqlRequest, error) {
+	msgs, ok := pgMessages(buf, isPGFrontendKind)
+	if !ok {
+		return sqlRequest{}, errors.New("not a Postgres request")
+	}
+	var req sqlRequest
+	executed := false
+	for _, msg := range msgs {
+		switch msg.kind {
+		case pgQuery:
+			req.query, _ = readPGString(msg.payload)
+			executed = true
+		case pgParse:
+			name, rest := readPGString(msg.payload)
+			req.query, _ = readPGString(rest)
+			rememberStatement(conn, name, req.query)
+		case pgBind:
+			// the portal name goes before the statement name
+			_, rest := readPGString(msg.payload)
+			name, _ := readPGString(rest)
+			if query, ok := preparedStatement(conn, name); ok {
+				req.query = query
+			}
+			executed = true
+		case pgExecute:
+			executed = true
+		case pgClose:
+			// closing a prepared statement (as opposed to a portal)
+			if len(msg.payload) > 0 && msg.payload[0] == 'S' {
+				name, _ := readPGString(msg.payload[1:])
+				forgetStatement(conn, name)
+			}
+		}
+	}
+	req.prepare = !executed
+	return req, nil
+}
+
+// postgresError returns the error reported by the backend in the response buffer, if any
+func postgresError(buf []byte) (request.DBError, bool) {
+	msgs, ok := pgMessages(buf, isPGBackendKind)
+	if !ok {
+		return request.DBError{}, false
+	}
+	for _, msg := range msgs {
+		if msg.kind != pgErrorResponse {
+			continue
+		}
+		dbErr := request.DBError{ErrorCode: "error"}
+		fields := msg.payload
+		for len(fields) > 0 && fields[0] != 0 {
+			code := fields[0]
+			var value string
+			value, fields = readPGString(fields[1:])
+			switch code {
+			case pgFieldSQLState:
+				dbErr.ErrorCode = value
+			case pgFieldMessage:
+				dbErr.Description = value
+			}
+		}
+		return dbErr, true
+	}
+	return request.DBError{}, false
+}
+
+// readPGString reads a null-terminated string, and returns it along with the rest of the buffer.
+// If the string is not terminated, it is considered to be truncated.
+func readPGString(buf []byte) (string, []byte) {
+	end := bytes.IndexByte(buf, 0)
+	if end < 0 {
+		return string(buf), nil
+	}
+	return string(buf[:end]), buf[end+1:]
+}
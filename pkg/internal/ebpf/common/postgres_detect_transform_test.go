@@ -0,0 +1,128 @@
+package ebpfcommon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+func pgMsg(kind byte, fields ...string) []byte {
+	payload := []byte(nil)
+	for _, f := range fields {
+		payload = append(payload, f...)
+	}
+	msg := binary.BigEndian.AppendUint32([]byte{kind}, uint32(len(payload)+4))
+	return append(msg, payload...)
+}
+
+func pgMsgs(msgs ...[]byte) []byte {
+	return bytes.Join(msgs, nil)
+}
+
+func TestPostgresDetector(t *testing.T) {
+	ok := pgMsgs(pgMsg('C', "SELECT 1\x00"), pgMsg('Z', "I"))
+
+	for _, tc := range []struct {
+		name      string
+		req       []byte
+		resp      []byte
+		method    string
+		table     string
+		statement string
+		status    int
+		errorCode string
+	}{{
+		name:      "simple query",
+		req:       pgMsg('Q', "SELECT * FROM accounts\x00"),
+		resp:      ok,
+		method:    "SELECT",
+		table:     "accounts",
+		statement: "SELECT * FROM accounts",
+	}, {
+		name: "unnamed statement in extended protocol",
+		req: pgMsgs(
+			pgMsg('P', "\x00", "UPDATE users SET name = $1\x00", "\x00\x00"),
+			pgMsg('B', "\x00", "\x00", "\x00\x00\x00\x00\x00\x00"),
+			pgMsg('E', "\x00", "\x00\x00\x00\x00"),
+			pgMsg('S'),
+		),
+		resp:      ok,
+		method:    "UPDATE",
+		table:     "users",
+		statement: "UPDATE users SET name = $1",
+	}, {
+		name: "failed query",
+		req:  pgMsg('Q', "INSERT INTO users VALUES (1)\x00"),
+		resp: pgMsgs(pgMsg('E', "SERROR\x00", "C23505\x00",
+			"Mduplicate key value violates unique constraint\x00", "\x00"), pgMsg('Z', "I")),
+		method:    "INSERT",
+		table:     "users",
+		statement: "INSERT INTO users VALUES (1)",
+		status:    1,
+		errorCode: "23505",
+	}} {
+		t.Run(tc.name, func(t *testing.T) {
+			event := makeTCPReq(string(tc.req), tcpSend, 343534, 5432, 2000)
+			span, ignore, err := detectProtocol(&event, tc.req, tc.resp)
+			require.NoError(t, err)
+			require.False(t, ignore)
+			assert.Equal(t, request.EventTypeSQLClient, span.Type)
+			assert.Equal(t, request.DBPostgres, span.SubType)
+			assert.Equal(t, tc.method, span.Method)
+			assert.Equal(t, tc.table, span.Path)
+			assert.Equal(t, tc.statement, span.Statement)
+			assert.Equal(t, tc.status, span.Status)
+			assert.Equal(t, tc.errorCode, span.DBError.ErrorCode)
+		})
+	}
+}
+
+func TestPostgresDetector_PreparedStatements(t *testing.T) {
+	ok := pgMsgs(pgMsg('1'), pgMsg('Z', "I"))
+	prepare := pgMsgs(pgMsg('P', "stmt1\x00", "DELETE FROM sessions WHERE id = $1\x00", "\x00\x00"), pgMsg('S'))
+	event := makeTCPReq(string(prepare), tcpSend, 343534, 5432, 2000)
+	span, ignore, err := detectProtocol(&event, prepare, ok)
+	require.NoError(t, err)
+	require.False(t, ignore)
+	assert.Equal(t, "PREPARE", span.Method)
+	assert.Equal(t, "sessions", span.Path)
+
+	// the execution is linked to the prepared statement, even if captured in reverse order
+	execute := pgMsgs(
+		pgMsg('B', "\x00", "stmt1\x00", "\x00\x00\x00\x00\x00\x00"),
+		pgMsg('E', "\x00", "\x00\x00\x00\x00"),
+		pgMsg('S'),
+	)
+	event = makeTCPReq(string(ok), tcpRecv, 5432, 343534, 2000)
+	span, ignore, err = detectProtocol(&event, ok, execute)
+	require.NoError(t, err)
+	require.False(t, ignore)
+	assert.Equal(t, "DELETE", span.Method)
+	assert.Equal(t, "sessions", span.Path)
+	assert.Equal(t, "DELETE FROM sessions WHERE id = $1", span.Statement)
+
+	// closed statements are forgotten
+	closeStmt := pgMsgs(pgMsg('C', "S", "stmt1\x00"), pgMsg('S'))
+	event = makeTCPReq(string(closeStmt), tcpSend, 343534, 5432, 2000)
+	_, err = parsePostgresRequest((*BPFConnInfo)(&event.ConnInfo), closeStmt)
+	require.NoError(t, err)
+	_, found := preparedStatement((*BPFConnInfo)(&event.ConnInfo), "stmt1")
+	assert.False(t, found)
+}
+
+func TestIsPostgresRequest(t *testing.T) {
+	assert.True(t, isPostgresRequest(pgMsg('Q', "SELECT 1\x00")))
+	// truncated message
+	assert.True(t, isPostgresRequest(pgMsg('Q', "SELECT * FROM foo\x00")[:12]))
+	assert.False(t, isPostgresRequest(nil))
+	assert.False(t, isPostgresRequest([]byte("SELECT * FROM accounts")))
+	// responses are not requests
+	assert.False(t, isPostgresRequest(pgMsgs(pgMsg('C', "SELECT 1\x00"), pgMsg('Z', "I"))))
+	// invalid length
+	assert.False(t, isPostgresRequest([]byte{'Q', 0xFF, 0xFF, 0xFF, 0xFF, 'S'}))
+}
@@ -0,0 +1,449 @@
+package ebpfcommon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// ProtocolDetector classifies the payload of the generic TCP events captured by the kprobes
+// tracer, and converts them into spans of a given protocol.
+// Custom detectors can be added by means of RegisterProtocolDetector.
+type ProtocolDetector interface {
+	// Name of the detector, used to enable or disable it from the configuration.
+	Name() string
+	// Detect returns a value between 0 and 1 specifying how likely is that the provided request
+	// and response buffers belong to the protocol of the detector. Zero means that the buffers
+	// definitely do not belong to the protocol. The enabled detectors are checked in descending
+	// order of the returned confidence.
+	// It also returns any intermediate result of the detection (e.g. which buffer contains the
+	// request), which is later passed to Span to avoid parsing the buffers twice.
+	// This method should be cheap, as it is invoked for all the enabled detectors.
+	Detect(event *TCPRequestInfo, req, resp []byte) (detection any, confidence float64)
+	// Span parses the request and response buffers and returns the resulting span. The
+	// detection argument is the value that was returned by Detect.
+	// The returned boolean specifies whether the event needs to be ignored.
+	// Returning an error means that the buffers couldn't be parsed, so the event is passed to the
+	// next detector in order of confidence. The detector must not modify the event when it
+	// returns an error.
+	Span(event *TCPRequestInfo, req, resp []byte, detection any) (request.Span, bool, error)
+}
+
+var (
+	detectorsMt sync.RWMutex
+	// ordered by registration, which is the tie-break order for detectors with the same confidence
+	protocolDetectors = []ProtocolDetector{
+		postgresDetector{}, mysqlDetector{}, sqlDetector{}, redisDetector{}, mongoDetector{},
+		http2Detector{}, kafkaDetector{},
+	}
+	disabledDetectors = map[string]struct{}{}
+)
+
+// RegisterProtocolDetector adds a ProtocolDetector to the TCP payload classifier. It returns error if
+// there is already a detector with the same name. It is intended to be invoked during initialization
+// (e.g. from an init function), before Beyla starts processing events.
+func RegisterProtocolDetector(d ProtocolDetector) error {
+	detectorsMt.Lock()
+	defer detectorsMt.Unlock()
+	for _, pd := range protocolDetectors {
+		if pd.Name() == d.Name() {
+			return fmt.Errorf("protocol detector %q already registered", d.Name())
+		}
+	}
+	protocolDetectors = append(protocolDetectors, d)
+	return nil
+}
+
+// CheckProtocolDetectors returns error if any of the provided names does not belong
+// to a registered ProtocolDetector.
+func CheckProtocolDetectors(names []string) error {
+	detectorsMt.RLock()
+	defer detectorsMt.RUnlock()
+	var unknown []string
+	for _, name := range names {
+		if findDetector(name) == nil {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown protocol detectors: %s. Valid values: %s",
+			strings.Join(unknown, ", "), strings.Join(detectorNames(), ", "))
+	}
+	return nil
+}
+
+// DisableProtocolDetectors disables the detectors with the provided names. Any previously
+// disabled detector whose name is not in the list is enabled again.
+func DisableProtocolDetectors(names []string) error {
+	if err := CheckProtocolDetectors(names); err != nil {
+		return err
+	}
+	detectorsMt.Lock()
+	defer detectorsMt.Unlock()
+	disabledDetectors = make(map[string]struct{}, len(names))
+	for _, name := range names {
+		disabledDetectors[name] = struct{}{}
+	}
+	return nil
+}
+
+func findDetector(name string) ProtocolDetector {
+	for _, pd := range protocolDetectors {
+		if pd.Name() == name {
+			return pd
+		}
+	}
+	return nil
+}
+
+func detectorNames() []string {
+	names := make([]string, 0, len(protocolDetectors))
+	for _, pd := range protocolDetectors {
+		names = append(names, pd.Name())
+	}
+	return names
+}
+
+// enabledDetectors returns a copy of the enabled detectors, so they can be invoked
+// without holding the lock (e.g. the http2Detector might block while forwarding events)
+func enabledDetectors() []ProtocolDetector {
+	detectorsMt.RLock()
+	defer detectorsMt.RUnlock()
+	enabled := make([]ProtocolDetector, 0, len(protocolDetectors))
+	for _, pd := range protocolDetectors {
+		if _, ok := disabledDetectors[pd.Name()]; !ok {
+			enabled = append(enabled, pd)
+		}
+	}
+	return enabled
+}
+
+type detectorCandidate struct {
+	detector   ProtocolDetector
+	detection  any
+	confidence float64
+}
+
+// rankDetectors returns the enabled detectors that detect the request and response buffers,
+// sorted by descending confidence
+func rankDetectors(event *TCPRequestInfo, req, resp []byte) []detectorCandidate {
+	var candidates []detectorCandidate
+	for _, pd := range enabledDetectors() {
+		if detection, c := pd.Detect(event, req, resp); c > 0 {
+			candidates = append(candidates, detectorCandidate{detector: pd, detection: detection, confidence: c})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].confidence > candidates[j].confidence
+	})
+	return candidates
+}
+
+var errNoProtocolDetected = errors.New("no protocol detected")
+
+// detectProtocol returns the span provided by the enabled detector with the highest
+// confidence that is able to parse the request and response buffers.
+func detectProtocol(event *TCPRequestInfo, req, resp []byte) (request.Span, bool, error) {
+	for _, c := range rankDetectors(event, req, resp) {
+		if span, ignore, err := c.detector.Span(event, req, resp, c.detection); err == nil {
+			return span, ignore, nil
+		}
+	}
+	return request.Span{}, true, errNoProtocolDetected
+}
+
+// Application protocols that are recognized by ClassifyPayload, in addition to the
+// names of the protocol detectors
+const (
+	PayloadProtocolTLS   = "tls"
+	PayloadProtocolHTTP  = "http"
+	PayloadProtocolHTTP2 = "http2"
+)
+
+var (
+	http2Preface  = []byte("PRI * HTTP/2.0")
+	http1Prefixes = [][]byte{
+		[]byte("GET "), []byte("POST "), []byte("PUT "), []byte("DELETE "), []byte("HEAD "),
+		[]byte("OPTIONS "), []byte("PATCH "), []byte("CONNECT "), []byte("TRACE "), []byte("HTTP/1."),
+	}
+)
+
+// ClassifyPayload returns the application protocol of the first payload bytes that are sent
+// in one direction of a TCP connection, or an empty string if the protocol is unknown.
+// TLS, HTTP/1.x and the HTTP/2 connection preface are checked first. Other payloads are
+// classified with the enabled detector that provides the highest confidence, and the
+// protocol is reported with the name of the detector.
+func ClassifyPayload(payload []byte) string {
+	switch {
+	case isTLSHandshake(payload):
+		return PayloadProtocolTLS
+	case bytes.HasPrefix(payload, http2Preface):
+		return PayloadProtocolHTTP2
+	case isHTTP1(payload):
+		return PayloadProtocolHTTP
+	}
+	// as the connection direction is unknown, the payload is checked as both request and response
+	event := &TCPRequestInfo{Len: uint32(len(payload)), RespLen: uint32(len(payload))}
+	if candidates := rankDetectors(event, payload, payload); len(candidates) > 0 {
+		return candidates[0].detector.Name()
+	}
+	return ""
+}
+
+// isTLSHandshake returns whether the payload starts with a TLS ClientHello or ServerHello record
+func isTLSHandshake(payload []byte) bool {
+	const (
+		recordTypeHandshake = 0x16
+		clientHello         = 0x01
+		serverHello         = 0x02
+	)
+	return len(payload) > 5 &&
+		payload[0] == recordTypeHandshake &&
+		payload[1] == 0x03 && payload[2] <= 0x04 &&
+		(payload[5] == clientHello || payload[5] == serverHello)
+}
+
+func isHTTP1(payload []byte) bool {
+	for _, prefix := range http1Prefixes {
+		if bytes.HasPrefix(payload, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type sqlDetector struct{}
+
+func (sqlDetector) Name() string { return "sql" }
+
+// sqlDetection is the SQL statement that is found by the sqlDetector
+type sqlDetection struct {
+	op, table, sql string
+}
+
+// Detect is less confident than the wire protocol detectors, as the SQL statements
+// are sniffed from the text of the request
+func (sqlDetector) Detect(_ *TCPRequestInfo, req, _ []byte) (any, float64) {
+	op, table, sql := detectSQLBytes(req)
+	if !validSQL(op, table) {
+		return nil, 0
+	}
+	return sqlDetection{op: op, table: table, sql: sql}, 0.9
+}
+
+func (sqlDetector) Span(event *TCPRequestInfo, _, _ []byte, detection any) (request.Span, bool, error) {
+	d := detection.(sqlDetection)
+	return TCPToSQLToSpan(event, d.op, d.table, d.sql), false, nil
+}
+
+type postgresDetector struct{}
+
+func (postgresDetector) Name() string { return "postgres" }
+
+// Detect returns whether the request was found in the response buffer. Finding it there
+// is less reliable, as it only happens when the event was captured reversed.
+func (postgresDetector) Detect(_ *TCPRequestInfo, req, resp []byte) (any, float64) {
+	switch {
+	case isPostgresRequest(req):
+		return false, 0.95
+	case isPostgresRequest(resp):
+		return true, 0.9
+	}
+	return nil, 0
+}
+
+func (postgresDetector) Span(event *TCPRequestInfo, req, resp []byte, reversed any) (request.Span, bool, error) {
+	// the connection info is only used as key for the prepared statements, which is
+	// the same in both directions
+	conn := (*BPFConnInfo)(&event.ConnInfo)
+	if reversed.(bool) {
+		req, resp = resp, req
+	}
+	sqlReq, err := parsePostgresRequest(conn, req)
+	if err != nil {
+		return request.Span{}, true, err
+	}
+	if reversed.(bool) {
+		// We've caught the event reversed in the middle of communication
+		reverseTCPEvent(event)
+	}
+	span := sqlReq.toSpan(event, request.DBPostgres)
+	if dbErr, ok := postgresError(resp); ok {
+		span.Status = 1
+		span.DBError = dbErr
+	}
+	return span, false, nil
+}
+
+type mysqlDetector struct{}
+
+func (mysqlDetector) Name() string { return "mysql" }
+
+// Detect returns whether the request was found in the response buffer. Finding it there
+// is less reliable, as it only happens when the event was captured reversed.
+func (mysqlDetector) Detect(_ *TCPRequestInfo, req, resp []byte) (any, float64) {
+	switch {
+	case isMySQLRequest(req):
+		return false, 0.95
+	case isMySQLRequest(resp):
+		return true, 0.9
+	}
+	return nil, 0
+}
+
+func (mysqlDetector) Span(event *TCPRequestInfo, req, resp []byte, reversed any) (request.Span, bool, error) {
+	// the connection info is only used as key for the prepared statements, which is
+	// the same in both directions
+	conn := (*BPFConnInfo)(&event.ConnInfo)
+	if reversed.(bool) {
+		// We've caught the event reversed in the middle of communication
+		reverseTCPEvent(event)
+		req, resp = resp, req
+	}
+	sqlReq, report := parseMySQLRequest(conn, req, resp)
+	if !report {
+		return request.Span{}, true, nil
+	}
+	span := sqlReq.toSpan(event, request.DBMySQL)
+	if dbErr, ok := mysqlError(resp); ok {
+		span.Status = 1
+		span.DBError = dbErr
+	}
+	return span, false, nil
+}
+
+type redisDetector struct{}
+
+func (redisDetector) Name() string { return "redis" }
+
+func (redisDetector) Detect(_ *TCPRequestInfo, req, resp []byte) (any, float64) {
+	if isRedis(req) && isRedis(resp) {
+		return nil, 0.8
+	}
+	return nil, 0
+}
+
+// Span ignores the events that look like Redis but can't be parsed, instead of passing
+// them to other detectors.
+func (redisDetector) Span(event *TCPRequestInfo, req, resp []byte, _ any) (request.Span, bool, error) {
+	op, text, ok := parseRedisRequest(string(req))
+	if !ok {
+		return request.Span{}, true, nil
+	}
+	var status int
+	if op == "" {
+		op, text, ok = parseRedisRequest(string(resp))
+		if !ok || op == "" {
+			return request.Span{}, true, nil
+		}
+		// We've caught the event reversed in the middle of communication, let's
+		// reverse the event
+		reverseTCPEvent(event)
+		status = redisStatus(req)
+		req, resp = resp, req
+	} else {
+		status = redisStatus(resp)
+	}
+	span := TCPToRedisToSpan(event, op, text, status)
+	span.Redis = redisRequestInfo(req, resp)
+	return span, false, nil
+}
+
+type mongoDetector struct{}
+
+func (mongoDetector) Name() string { return "mongo" }
+
+// Detect returns whether the request was found in the response buffer. Finding it there
+// is less reliable, as it only happens when the event was captured reversed.
+func (mongoDetector) Detect(_ *TCPRequestInfo, req, resp []byte) (any, float64) {
+	switch {
+	case isMongoRequest(req):
+		return false, 0.7
+	case isMongoRequest(resp):
+		return true, 0.65
+	}
+	return nil, 0
+}
+
+func (mongoDetector) Span(event *TCPRequestInfo, req, resp []byte, detection any) (request.Span, bool, error) {
+	reversed := detection.(bool)
+	if reversed {
+		// We've caught the event reversed in the middle of communication
+		req, resp = resp, req
+	}
+	cmd, err := parseMongoRequest(req)
+	if err != nil {
+		return request.Span{}, true, err
+	}
+	// Only the client side of the MongoDB connections is reported. The event direction
+	// is checked before reversing it, to not modify the event when returning an error.
+	if (event.Direction == 0) != reversed {
+		return request.Span{}, true, nil
+	}
+	if reversed {
+		reverseTCPEvent(event)
+	}
+	return TCPToMongoToSpan(event, cmd, mongoStatus(resp)), false, nil
+}
+
+// http2Detector forwards the HTTP/2 and gRPC events that were misclassified as generic TCP events
+// to the HTTP/2 tracer, so it does not directly produce spans.
+type http2Detector struct{}
+
+func (http2Detector) Name() string { return "http2" }
+
+// Kafka and gRPC can look very similar in terms of bytes. We can mistake one for another.
+// We try gRPC first because it's more reliable in detecting false gRPC sequences.
+// Detect is more confident when both buffers contain HTTP/2 frames.
+func (http2Detector) Detect(event *TCPRequestInfo, req, resp []byte) (any, float64) {
+	reqFrames, respFrames := isHTTP2(req, int(event.Len)), isHTTP2(resp, int(event.RespLen))
+	switch {
+	case reqFrames && respFrames:
+		return nil, 0.65
+	case reqFrames || respFrames:
+		return nil, 0.6
+	}
+	return nil, 0
+}
+
+func (http2Detector) Span(event *TCPRequestInfo, _, _ []byte, _ any) (request.Span, bool, error) {
+	MisclassifiedEvents <- MisclassifiedEvent{EventType: EventTypeKHTTP2, TCPInfo: event}
+	return request.Span{}, true, nil
+}
+
+type kafkaDetector struct{}
+
+func (kafkaDetector) Name() string { return "kafka" }
+
+// Detect is less confident when the Kafka header is only found in the response buffer,
+// as it only happens when the event was captured reversed.
+func (kafkaDetector) Detect(_ *TCPRequestInfo, req, resp []byte) (any, float64) {
+	switch {
+	case isKafkaHeader(req):
+		return nil, 0.5
+	case isKafkaHeader(resp):
+		return nil, 0.45
+	}
+	return nil, 0
+}
+
+func isKafkaHeader(pkt []byte) bool {
+	if len(pkt) < KafkaMinLength {
+		return false
+	}
+	_, err := parseKafkaHeader(pkt)
+	return err == nil
+}
+
+func (kafkaDetector) Span(event *TCPRequestInfo, req, resp []byte, _ any) (request.Span, bool, error) {
+	k, err := ProcessPossibleKafkaEvent(event, req, resp)
+	if err != nil {
+		return request.Span{}, true, err
+	}
+	return TCPToKafkaToSpan(event, k), false, nil
+}
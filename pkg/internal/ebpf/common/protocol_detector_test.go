@@ -0,0 +1,199 @@
+package ebpfcommon
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+type fakeDetector struct {
+	name       string
+	prefix     string
+	confidence float64
+}
+
+func (f *fakeDetector) Name() string { return f.name }
+
+func (f *fakeDetector) Detect(_ *TCPRequestInfo, req, _ []byte) (any, float64) {
+	if bytes.HasPrefix(req, []byte(f.prefix)) {
+		return nil, f.confidence
+	}
+	return nil, 0
+}
+
+func (f *fakeDetector) Span(_ *TCPRequestInfo, _, _ []byte, _ any) (request.Span, bool, error) {
+	return request.Span{Type: request.EventTypeHTTPClient, Method: f.name}, false, nil
+}
+
+// restoreDetectors returns the detectors registry to its original state after the test
+func restoreDetectors(t *testing.T) {
+	detectors := protocolDetectors
+	t.Cleanup(func() {
+		protocolDetectors = detectors
+		disabledDetectors = map[string]struct{}{}
+	})
+}
+
+func TestProtocolDetectors_Register(t *testing.T) {
+	restoreDetectors(t)
+	require.NoError(t, RegisterProtocolDetector(&fakeDetector{name: "myproto"}))
+	require.Error(t, RegisterProtocolDetector(&fakeDetector{name: "myproto"}))
+	require.Error(t, RegisterProtocolDetector(&fakeDetector{name: "sql"}))
+
+	assert.NoError(t, CheckProtocolDetectors([]string{"sql", "kafka", "myproto"}))
+	assert.Error(t, CheckProtocolDetectors([]string{"sql", "foo"}))
+	assert.Error(t, DisableProtocolDetectors([]string{"foo"}))
+}
+
+func TestProtocolDetectors_OrderedByConfidence(t *testing.T) {
+	restoreDetectors(t)
+	sql := []byte("SELECT * FROM accounts")
+	event := makeTCPReq(string(sql), tcpSend, 343534, 8080, 2000)
+
+	// built-in SQL detector wins over less confident detectors, despite the registration order
+	require.NoError(t, RegisterProtocolDetector(&fakeDetector{name: "unsure", prefix: "SELECT", confidence: 0.1}))
+	span, ignore, err := detectProtocol(&event, sql, nil)
+	require.NoError(t, err)
+	assert.False(t, ignore)
+	assert.Equal(t, request.EventTypeSQLClient, span.Type)
+
+	// more confident detectors are prioritized
+	require.NoError(t, RegisterProtocolDetector(&fakeDetector{name: "sure", prefix: "SELECT", confidence: 1}))
+	span, _, err = detectProtocol(&event, sql, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "sure", span.Method)
+
+	// disabled detectors are not used
+	require.NoError(t, DisableProtocolDetectors([]string{"sure", "sql"}))
+	span, _, err = detectProtocol(&event, sql, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "unsure", span.Method)
+
+	// when no detector is able to parse the buffer, an error is returned
+	require.NoError(t, DisableProtocolDetectors([]string{"sure", "sql", "unsure"}))
+	_, _, err = detectProtocol(&event, sql, nil)
+	assert.Error(t, err)
+}
+
+// echoDetector is only confident about the buffers when the response echoes the request
+type echoDetector struct{}
+
+func (echoDetector) Name() string { return "echo" }
+
+func (echoDetector) Detect(_ *TCPRequestInfo, req, resp []byte) (any, float64) {
+	if bytes.Equal(req, resp) {
+		return nil, 1
+	}
+	return nil, 0.1
+}
+
+func (echoDetector) Span(_ *TCPRequestInfo, _, _ []byte, _ any) (request.Span, bool, error) {
+	return request.Span{Type: request.EventTypeHTTPClient, Method: "echo"}, false, nil
+}
+
+func TestProtocolDetectors_RankedByDetection(t *testing.T) {
+	restoreDetectors(t)
+	require.NoError(t, RegisterProtocolDetector(echoDetector{}))
+	sql := []byte("SELECT * FROM accounts")
+	event := makeTCPReq(string(sql), tcpSend, 343534, 8080, 2000)
+
+	// the confidence is provided for each detection, not for each detector
+	span, _, err := detectProtocol(&event, sql, []byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, request.EventTypeSQLClient, span.Type)
+
+	span, _, err = detectProtocol(&event, sql, sql)
+	require.NoError(t, err)
+	assert.Equal(t, "echo", span.Method)
+}
+
+// blockingDetector blocks the creation of spans until the unblock channel is closed
+type blockingDetector struct {
+	entered, unblock chan struct{}
+}
+
+func (blockingDetector) Name() string { return "blocking" }
+
+func (blockingDetector) Detect(_ *TCPRequestInfo, _, _ []byte) (any, float64) { return nil, 1 }
+
+func (b blockingDetector) Span(_ *TCPRequestInfo, _, _ []byte, _ any) (request.Span, bool, error) {
+	close(b.entered)
+	<-b.unblock
+	return request.Span{}, true, nil
+}
+
+func TestProtocolDetectors_SpanDoesNotBlockRegistry(t *testing.T) {
+	restoreDetectors(t)
+	bd := blockingDetector{entered: make(chan struct{}), unblock: make(chan struct{})}
+	require.NoError(t, RegisterProtocolDetector(bd))
+	defer close(bd.unblock)
+
+	event := makeTCPReq("foo", tcpSend, 343534, 8080, 2000)
+	go func() { _, _, _ = detectProtocol(&event, []byte("foo"), nil) }()
+	<-bd.entered
+
+	// the detectors can be reconfigured and other events classified while a detector is creating a span
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = DisableProtocolDetectors([]string{"blocking"})
+		_, _, _ = detectProtocol(&event, []byte("foo"), nil)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the detectors registry is blocked while a detector creates a span")
+	}
+}
+
+func TestProtocolDetectors_UnparseableRedisIsIgnored(t *testing.T) {
+	restoreDetectors(t)
+	require.NoError(t, RegisterProtocolDetector(&fakeDetector{name: "unsure", prefix: "+OK", confidence: 0.1}))
+	// the event looks like Redis, but there is no command in any buffer. It is ignored
+	// instead of being passed to the next detectors
+	buf := []byte("+OK\r\n")
+	event := makeTCPReq(string(buf), tcpSend, 343534, 6379, 2000)
+	_, ignore, err := detectProtocol(&event, buf, buf)
+	require.NoError(t, err)
+	assert.True(t, ignore)
+}
+
+func TestClassifyPayload(t *testing.T) {
+	restoreDetectors(t)
+	kafka := []byte{0, 0, 0, 94, 0, 1, 0, 11, 0, 0, 0, 224, 0, 6, 115, 97, 114, 97, 109, 97, 255, 255, 255, 255, 0, 0, 1, 244, 0, 0, 0, 1, 6, 64, 0, 0, 0, 0, 0, 0, 0, 255, 255, 255, 255, 0, 0, 0, 1, 0, 9, 105, 109, 112, 111, 114, 116, 97, 110, 116, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 19, 0, 0, 0, 0, 0, 0, 0, 0, 0, 16, 0, 0, 0, 0, 0, 0, 0, 0}
+	for _, tc := range []struct {
+		name     string
+		payload  []byte
+		expected string
+	}{
+		{name: "tls client hello", payload: []byte{0x16, 0x03, 0x01, 0x02, 0x00, 0x01, 0x00, 0x01, 0xfc}, expected: "tls"},
+		{name: "tls server hello", payload: []byte{0x16, 0x03, 0x03, 0x00, 0x7a, 0x02, 0x00, 0x00, 0x76}, expected: "tls"},
+		{name: "http request", payload: []byte("GET /foo HTTP/1.1\r\nHost: bar\r\n\r\n"), expected: "http"},
+		{name: "http response", payload: []byte("HTTP/1.1 200 OK\r\n"), expected: "http"},
+		{name: "http2 preface", payload: []byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"), expected: "http2"},
+		{name: "redis", payload: []byte("*2\r\n$3\r\nGET\r\n$3\r\nfoo\r\n"), expected: "redis"},
+		{name: "postgres", payload: pgMsg('Q', "SELECT 1\x00"), expected: "postgres"},
+		{name: "mysql", payload: mysqlCmd(mysqlComQuery, "select 1"), expected: "mysql"},
+		{name: "sql", payload: []byte("SELECT * FROM accounts"), expected: "sql"},
+		{name: "kafka", payload: kafka, expected: "kafka"},
+		{name: "unknown", payload: []byte("hello world"), expected: ""},
+		{name: "empty", payload: nil, expected: ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ClassifyPayload(tc.payload))
+		})
+	}
+
+	// disabled detectors are not used
+	require.NoError(t, DisableProtocolDetectors([]string{"postgres"}))
+	assert.NotEqual(t, "postgres", ClassifyPayload(pgMsg('Q', "SELECT 1\x00")))
+
+	// the most confident detection wins, regardless of the registration order
+	require.NoError(t, RegisterProtocolDetector(&fakeDetector{name: "sure", prefix: "SELECT", confidence: 1}))
+	assert.Equal(t, "sure", ClassifyPayload([]byte("SELECT * FROM accounts")))
+}
@@ -3,6 +3,7 @@ package ebpfcommon
 import (
 	"bytes"
 	"encoding/binary"
+	"strconv"
 	"strings"
 	"unsafe"
 
@@ -140,6 +141,90 @@ func redisStatus(buf []byte) int {
 	return status
 }
 
+// redisReadCommands are the GET-family commands whose nil or empty replies are reported as cache misses
+var redisReadCommands = map[string]struct{}{
+	"GET": {}, "GETEX": {}, "GETDEL": {}, "HGET": {}, "HGETALL": {}, "LINDEX": {}, "ZSCORE": {}, "JSON.GET": {},
+}
+
+// parseRedisCommands parses the RESP arrays of a request buffer, which might be truncated. It returns
+// the arguments of the first command and the number of commands that were sent in the buffer.
+func parseRedisCommands(buf []byte) ([]string, int) {
+	var first []string
+	count := 0
+	for len(buf) > 0 && buf[0] == '*' {
+		n, rest, ok := readRedisInt(buf[1:])
+		if !ok || n <= 0 {
+			break
+		}
+		count++
+		buf = rest
+		for i := 0; i < n; i++ {
+			if len(buf) == 0 || buf[0] != '$' {
+				return first, count
+			}
+			l, rest, ok := readRedisInt(buf[1:])
+			if !ok || l < 0 {
+				return first, count
+			}
+			if len(rest) < l+2 {
+				// truncated argument. We still can use it if it is the key of the first command
+				if count == 1 && len(first) == 1 {
+					first = append(first, string(rest[:min(l, len(rest))]))
+				}
+				return first, count
+			}
+			if count == 1 {
+				first = append(first, string(rest[:l]))
+			}
+			buf = rest[l+2:]
+		}
+	}
+	return first, count
+}
+
+// readRedisInt reads a CRLF-terminated integer, and returns the rest of the buffer
+func readRedisInt(buf []byte) (int, []byte, bool) {
+	end := bytes.Index(buf, []byte("\r\n"))
+	if end <= 0 {
+		return 0, nil, false
+	}
+	n, err := strconv.Atoi(string(buf[:end]))
+	if err != nil {
+		return 0, nil, false
+	}
+	return n, buf[end+2:], true
+}
+
+// redisCacheResult returns whether the reply of a GET-family command is a hit or a miss (nil or empty
+// replies), or an empty string if the command is not a read command or the reply is an error.
+func redisCacheResult(cmd string, resp []byte) string {
+	if _, ok := redisReadCommands[strings.ToUpper(cmd)]; !ok || len(resp) == 0 || resp[0] == '-' {
+		return ""
+	}
+	for _, miss := range []string{"$-1\r\n", "*-1\r\n", "_\r\n", "*0\r\n", "%0\r\n"} {
+		if bytes.HasPrefix(resp, []byte(miss)) {
+			return request.CacheMiss
+		}
+	}
+	return request.CacheHit
+}
+
+// redisRequestInfo extracts the key, the pipeline size and the cache result of a Redis request.
+// The response buffer is optional.
+func redisRequestInfo(req, resp []byte) *request.RedisInfo {
+	args, count := parseRedisCommands(req)
+	if count == 0 || len(args) == 0 {
+		return nil
+	}
+	info := &request.RedisInfo{PipelineSize: count}
+	if len(args) > 1 {
+		info.Key = args[1]
+	}
+	// the response of a pipeline starts with the reply of the first command
+	info.CacheResult = redisCacheResult(args[0], resp)
+	return info
+}
+
 func TCPToRedisToSpan(trace *TCPRequestInfo, op, text string, status int) request.Span {
 	peer := ""
 	hostname := ""
@@ -198,6 +283,7 @@ func ReadGoRedisRequestIntoSpan(record *ringbuf.Record) (request.Span, bool, err
 	}
 
 	op, text, ok := parseRedisRequest(string(event.Buf[:]))
+	redisInfo := redisRequestInfo(event.Buf[:], nil)
 
 	if !ok {
 		// We know it's redis request here, it just didn't complete correctly
@@ -225,5 +311,6 @@ func ReadGoRedisRequestIntoSpan(record *ringbuf.Record) (request.Span, bool, err
 			UserPID:   event.Pid.UserPid,
 			Namespace: event.Pid.Ns,
 		},
+		Redis: redisInfo,
 	}, false, nil
 }
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/beyla/pkg/internal/request"
 )
 
 type crlfTest struct {
@@ -74,3 +76,33 @@ func TestIsRedis(t *testing.T) {
 	assert.True(t, isRedis(buf))
 	assert.True(t, isRedis(rbuf))
 }
+
+func TestRedisRequestInfo(t *testing.T) {
+	get := []byte("*2\r\n$3\r\nGET\r\n$13\r\nuser:123:cart\r\n")
+	assert.Equal(t, &request.RedisInfo{Key: "user:123:cart", PipelineSize: 1, CacheResult: request.CacheMiss},
+		redisRequestInfo(get, []byte("$-1\r\n")))
+	assert.Equal(t, &request.RedisInfo{Key: "user:123:cart", PipelineSize: 1, CacheResult: request.CacheHit},
+		redisRequestInfo(get, []byte("$5\r\nbeyla\r\n")))
+	// errors are neither hits nor misses
+	assert.Equal(t, &request.RedisInfo{Key: "user:123:cart", PipelineSize: 1},
+		redisRequestInfo(get, []byte("-ERR wrong\r\n")))
+	// unknown response
+	assert.Equal(t, &request.RedisInfo{Key: "user:123:cart", PipelineSize: 1},
+		redisRequestInfo(get, nil))
+
+	// pipelines, with the last command truncated
+	pipeline := []byte("*3\r\n$3\r\nSET\r\n$5\r\nk:1:a\r\n$1\r\nv\r\n*2\r\n$4\r\nINCR\r\n$3\r\nctr\r\n*2\r\n$3\r\nGET\r\n$10\r\nk:")
+	assert.Equal(t, &request.RedisInfo{Key: "k:1:a", PipelineSize: 3},
+		redisRequestInfo(pipeline, []byte("+OK\r\n:1\r\n")))
+
+	// truncated key of the first command
+	assert.Equal(t, &request.RedisInfo{Key: "user:1", PipelineSize: 1},
+		redisRequestInfo([]byte("*2\r\n$7\r\nHGETALL\r\n$13\r\nuser:1"), nil))
+
+	// empty HGETALL replies are misses
+	assert.Equal(t, request.CacheMiss, redisCacheResult("hgetall", []byte("*0\r\n")))
+	assert.Equal(t, "", redisCacheResult("SET", []byte("+OK\r\n")))
+
+	assert.Nil(t, redisRequestInfo([]byte("PING\r\n"), nil))
+	assert.Nil(t, redisRequestInfo(make([]byte, 64), nil))
+}
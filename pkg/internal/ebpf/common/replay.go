@@ -0,0 +1,129 @@
+package ebpfcommon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"strconv"
+
+	"github.com/cilium/ebpf/ringbuf"
+
+	"github.com/grafana/beyla/pkg/internal/imetrics"
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+// ReplayCapture reads the raw eBPF events from a capture file and forwards them as spans, through
+// the same readers as the ring buffer forwarder. The PIDs filter is replaced by the PIDs stored
+// in the capture, so the replay does not require the original processes to be running.
+// The function returns when all the captured events have been forwarded.
+func ReplayCapture(ctx context.Context, cfg *TracerConfig, capture io.Reader, spansChan chan<- []request.Span) error {
+	log := slog.With("component", "ebpf.ReplayCapture")
+	reader, err := NewCaptureReader(capture)
+	if err != nil {
+		return err
+	}
+	log.Info("replaying capture", "start", reader.Header.Start, "arch", reader.Header.Arch,
+		"systemWide", reader.Header.SystemWide)
+	if reader.Header.Arch != runtime.GOARCH {
+		log.Warn("the capture was taken in a different architecture. Events might be wrongly decoded",
+			"captureArch", reader.Header.Arch, "arch", runtime.GOARCH)
+	}
+	if err := DisableProtocolDetectors(cfg.DisabledProtocolDetectors); err != nil {
+		log.Warn("can't disable protocol detectors", "error", err)
+	}
+
+	stopDiscarding := discardMisclassifiedEvents()
+	defer stopDiscarding()
+
+	rbf := ringBufForwarder{
+		cfg: cfg, logger: log, reader: ReadBPFTraceAsSpan,
+		filter: &replayPIDsFilter{PIDsFilter: *NewPIDsFilter(log)}, metrics: imetrics.NoopReporter{},
+		spans: make([]request.Span, cfg.BatchLength),
+	}
+	if reader.Header.SystemWide {
+		rbf.filter = &replaySystemWideFilter{}
+	}
+	records := 0
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		entry, err := reader.Next()
+		if err != nil {
+			if rbf.spansLen > 0 {
+				rbf.flushEvents(spansChan)
+			}
+			log.Info("capture replayed", "records", records)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("reading capture after %d records: %w", records, err)
+		}
+		if entry.PIDs != nil {
+			if f, ok := rbf.filter.(*replayPIDsFilter); ok {
+				f.load(entry.PIDs)
+			}
+			continue
+		}
+		records++
+		rbf.processAndForward(ringbuf.Record{RawSample: entry.RawSample}, spansChan)
+	}
+}
+
+// discardMisclassifiedEvents consumes the HTTP/2 events that the protocol detectors found in the
+// generic TCP events. They are usually forwarded to the HTTP/2 tracer, which does not run during
+// the replay, so the replay would block. The capture already contains the events that the
+// tracer received after reclassifying the connection. It returns a function to stop discarding.
+func discardMisclassifiedEvents() func() {
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-MisclassifiedEvents:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// replayPIDsFilter accepts the PIDs stored in the capture file without
+// looking for them in the /proc filesystem
+type replayPIDsFilter struct {
+	PIDsFilter
+}
+
+func (pf *replayPIDsFilter) load(pids []CapturedPID) {
+	pf.mux.Lock()
+	defer pf.mux.Unlock()
+	pf.current = map[uint32]map[uint32]PIDInfo{}
+	for i := range pids {
+		p := &pids[i]
+		ns, ok := pf.current[p.Namespace]
+		if !ok {
+			ns = map[uint32]PIDInfo{}
+			pf.current[p.Namespace] = ns
+		}
+		ns[p.PID] = PIDInfo{service: p.Service, pidType: p.Type}
+	}
+}
+
+// replaySystemWideFilter accepts all the spans. As the original processes are not available,
+// the services are named after their host PID.
+type replaySystemWideFilter struct {
+	IdentityPidsFilter
+}
+
+func (pf *replaySystemWideFilter) Filter(inputSpans []request.Span) []request.Span {
+	for i := range inputSpans {
+		s := &inputSpans[i]
+		pid := strconv.FormatUint(uint64(s.Pid.HostPID), 10)
+		s.ServiceID = svc.ID{Name: "pid-" + pid, UID: svc.UID(pid), ProcPID: int32(s.Pid.HostPID)}
+	}
+	return inputSpans
+}
@@ -42,6 +42,8 @@ type ringBufForwarder struct {
 	// belong to a process that does not match the discovery policies
 	filter  ServiceFilter
 	metrics imetrics.Reporter
+	// capture is optional. If not nil, the raw ring buffer records are stored for later replay
+	capture *captureWriter
 }
 
 var singleRbf *ringBufForwarder
@@ -64,11 +66,20 @@ func SharedRingbuf(
 	}
 
 	log := slog.With("component", "ringbuf.Tracer")
+	if err := DisableProtocolDetectors(cfg.DisabledProtocolDetectors); err != nil {
+		log.Warn("can't disable protocol detectors", "error", err)
+	}
 	rbf := ringBufForwarder{
 		cfg: cfg, logger: log, ringbuffer: ringbuffer,
 		closers: nil, reader: ReadBPFTraceAsSpan,
 		filter: filter, metrics: metrics,
 	}
+	if cfg.Capture.Enabled() {
+		var err error
+		if rbf.capture, err = newCaptureWriter(&cfg.Capture, filter); err != nil {
+			log.Warn("can't capture raw eBPF events", "error", err)
+		}
+	}
 	singleRbf = &rbf
 	return singleRbf.sharedReadAndForward
 }
@@ -170,6 +181,9 @@ func (rbf *ringBufForwarder) alreadyForwarded(ctx context.Context, _ []io.Closer
 func (rbf *ringBufForwarder) processAndForward(record ringbuf.Record, spansChan chan<- []request.Span) {
 	rbf.access.Lock()
 	defer rbf.access.Unlock()
+	if rbf.capture != nil && !rbf.capture.writeRecord(time.Now(), record.RawSample) {
+		rbf.capture = nil
+	}
 	s, ignore, err := rbf.reader(&record, rbf.filter)
 	if err != nil {
 		rbf.logger.Error("error parsing perf event", err)
@@ -200,6 +214,9 @@ func (rbf *ringBufForwarder) flushEvents(spansChan chan<- []request.Span) {
 	spansChan <- rbf.filter.Filter(rbf.spans[:rbf.spansLen])
 	rbf.spans = make([]request.Span, rbf.cfg.BatchLength)
 	rbf.spansLen = 0
+	if rbf.capture != nil {
+		rbf.capture.flush()
+	}
 }
 
 func (rbf *ringBufForwarder) bgFlushOnTimeout(spansChan chan<- []request.Span) {
@@ -209,6 +226,8 @@ func (rbf *ringBufForwarder) bgFlushOnTimeout(spansChan chan<- []request.Span) {
 		if rbf.spansLen > 0 {
 			rbf.logger.Debug("submitting traces on timeout", "len", rbf.spansLen)
 			rbf.flushEvents(spansChan)
+		} else if rbf.capture != nil {
+			rbf.capture.flush()
 		}
 		rbf.access.Unlock()
 	}
@@ -226,6 +245,12 @@ func (rbf *ringBufForwarder) bgListenSharedContextCancelation(ctx context.Contex
 	for _, c := range closers {
 		_ = c.Close()
 	}
+	rbf.access.Lock()
+	if rbf.capture != nil {
+		rbf.capture.close()
+		rbf.capture = nil
+	}
+	rbf.access.Unlock()
 }
 
 func (rbf *ringBufForwarder) closeAllResources() {
@@ -1,18 +1,72 @@
 package ebpfcommon
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"strings"
 	"unsafe"
 
+	lru "github.com/hashicorp/golang-lru/v2"
 	trace2 "go.opentelemetry.io/otel/trace"
 
 	"github.com/grafana/beyla/pkg/internal/request"
 	"github.com/grafana/beyla/pkg/internal/sqlprune"
 )
 
+type preparedStmtKey struct {
+	conn BPFConnInfo
+	id   string
+}
+
+// text of the prepared statements, to link the later executions with the statement
+// provided at prepare time
+var preparedStatements, _ = lru.New[preparedStmtKey, string](1024 * 10)
+
+// stmtKey returns the same key for both directions of a connection, as the events might
+// have been captured in reverse order
+func stmtKey(conn *BPFConnInfo, id string) preparedStmtKey {
+	key := preparedStmtKey{conn: *conn, id: id}
+	if bytes.Compare(conn.S_addr[:], conn.D_addr[:]) > 0 ||
+		(conn.S_addr == conn.D_addr && conn.S_port > conn.D_port) {
+		key.conn.S_addr, key.conn.D_addr = conn.D_addr, conn.S_addr
+		key.conn.S_port, key.conn.D_port = conn.D_port, conn.S_port
+	}
+	return key
+}
+
+func rememberStatement(conn *BPFConnInfo, id, query string) {
+	preparedStatements.Add(stmtKey(conn, id), query)
+}
+
+func preparedStatement(conn *BPFConnInfo, id string) (string, bool) {
+	return preparedStatements.Get(stmtKey(conn, id))
+}
+
+func forgetStatement(conn *BPFConnInfo, id string) {
+	preparedStatements.Remove(stmtKey(conn, id))
+}
+
+// sqlRequest contains the information of a request to a SQL database
+type sqlRequest struct {
+	query string
+	// prepare is true if the statement was prepared but not executed
+	prepare bool
+}
+
+func (r *sqlRequest) toSpan(event *TCPRequestInfo, subType int) request.Span {
+	op, table := sqlprune.SQLParseOperationAndTable(r.query)
+	if r.prepare {
+		op = "PREPARE"
+	} else if op == "" {
+		op = "EXECUTE"
+	}
+	span := TCPToSQLToSpan(event, op, table, r.query)
+	span.SubType = subType
+	return span
+}
+
 func validSQL(op, table string) bool {
 	return op != "" && table != ""
 }
@@ -9,7 +9,6 @@ import (
 	"github.com/grafana/beyla/pkg/internal/request"
 )
 
-// nolint:cyclop
 func ReadTCPRequestIntoSpan(record *ringbuf.Record, filter ServiceFilter) (request.Span, bool, error) {
 	var event TCPRequestInfo
 
@@ -32,47 +31,13 @@ func ReadTCPRequestIntoSpan(record *ringbuf.Record, filter ServiceFilter) (reque
 		rl = len(event.Rbuf)
 	}
 
-	b := event.Buf[:l]
-
-	// Check if we have a SQL statement
-	op, table, sql := detectSQLBytes(b)
-	switch {
-	case validSQL(op, table):
-		return TCPToSQLToSpan(&event, op, table, sql), false, nil
-	case isRedis(b) && isRedis(event.Rbuf[:rl]):
-		op, text, ok := parseRedisRequest(string(b))
-
-		if ok {
-			var status int
-			if op == "" {
-				op, text, ok = parseRedisRequest(string(event.Rbuf[:rl]))
-				if !ok || op == "" {
-					return request.Span{}, true, nil // ignore if we couldn't parse it
-				}
-				// We've caught the event reversed in the middle of communication, let's
-				// reverse the event
-				reverseTCPEvent(&event)
-				status = redisStatus(b)
-			} else {
-				status = redisStatus(event.Rbuf[:rl])
-			}
-
-			return TCPToRedisToSpan(&event, op, text, status), false, nil
-		}
-	default:
-		// Kafka and gRPC can look very similar in terms of bytes. We can mistake one for another.
-		// We try gRPC first because it's more reliable in detecting false gRPC sequences.
-		if isHTTP2(b, int(event.Len)) || isHTTP2(event.Rbuf[:rl], int(event.RespLen)) {
-			MisclassifiedEvents <- MisclassifiedEvent{EventType: EventTypeKHTTP2, TCPInfo: &event}
-		} else {
-			k, err := ProcessPossibleKafkaEvent(&event, b, event.Rbuf[:rl])
-			if err == nil {
-				return TCPToKafkaToSpan(&event, k), false, nil
-			}
-		}
+	// the detectors are sorted by confidence, so the first one that is able to parse the buffers
+	// provides the span
+	span, ignore, err := detectProtocol(&event, event.Buf[:l], event.Rbuf[:rl])
+	if err != nil {
+		return request.Span{}, true, nil // ignore if we couldn't parse it
 	}
-
-	return request.Span{}, true, nil // ignore if we couldn't parse it
+	return span, ignore, nil
 }
 
 func reverseTCPEvent(trace *TCPRequestInfo) {
@@ -0,0 +1,160 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build 386 || amd64
+
+package goruntime
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+
+	"github.com/cilium/ebpf"
+)
+
+type bpf_metricsGoRtStartKeyT struct {
+	Goroutine uint64
+	Pid       uint32
+	Pad       uint32
+}
+
+type bpf_metricsGoRtStatsT struct {
+	GoroutinesCreated uint64
+	GoroutinesExited  uint64
+	GcCycles          uint64
+	StwPauses         struct {
+		Counts [10]uint64
+		SumNs  uint64
+	}
+	SchedLatency struct {
+		Counts [10]uint64
+		SumNs  uint64
+	}
+}
+
+// loadBpf_metrics returns the embedded CollectionSpec for bpf_metrics.
+func loadBpf_metrics() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_Bpf_metricsBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load bpf_metrics: %w", err)
+	}
+
+	return spec, err
+}
+
+// loadBpf_metricsObjects loads bpf_metrics and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*bpf_metricsObjects
+//	*bpf_metricsPrograms
+//	*bpf_metricsMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func loadBpf_metricsObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := loadBpf_metrics()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// bpf_metricsSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpf_metricsSpecs struct {
+	bpf_metricsProgramSpecs
+	bpf_metricsMapSpecs
+}
+
+// bpf_metricsSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpf_metricsProgramSpecs struct {
+	UprobeRtExecute    *ebpf.ProgramSpec `ebpf:"uprobe_rt_execute"`
+	UprobeRtGcMarkTerm *ebpf.ProgramSpec `ebpf:"uprobe_rt_gc_mark_term"`
+	UprobeRtGoexit     *ebpf.ProgramSpec `ebpf:"uprobe_rt_goexit"`
+	UprobeRtNewproc    *ebpf.ProgramSpec `ebpf:"uprobe_rt_newproc"`
+	UprobeRtRunqput    *ebpf.ProgramSpec `ebpf:"uprobe_rt_runqput"`
+	UprobeRtStwEnd     *ebpf.ProgramSpec `ebpf:"uprobe_rt_stw_end"`
+	UprobeRtStwStart   *ebpf.ProgramSpec `ebpf:"uprobe_rt_stw_start"`
+}
+
+// bpf_metricsMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpf_metricsMapSpecs struct {
+	GoRtStarts *ebpf.MapSpec `ebpf:"go_rt_starts"`
+	GoRtStats  *ebpf.MapSpec `ebpf:"go_rt_stats"`
+}
+
+// bpf_metricsObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to loadBpf_metricsObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpf_metricsObjects struct {
+	bpf_metricsPrograms
+	bpf_metricsMaps
+}
+
+func (o *bpf_metricsObjects) Close() error {
+	return _Bpf_metricsClose(
+		&o.bpf_metricsPrograms,
+		&o.bpf_metricsMaps,
+	)
+}
+
+// bpf_metricsMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to loadBpf_metricsObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpf_metricsMaps struct {
+	GoRtStarts *ebpf.Map `ebpf:"go_rt_starts"`
+	GoRtStats  *ebpf.Map `ebpf:"go_rt_stats"`
+}
+
+func (m *bpf_metricsMaps) Close() error {
+	return _Bpf_metricsClose(
+		m.GoRtStarts,
+		m.GoRtStats,
+	)
+}
+
+// bpf_metricsPrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to loadBpf_metricsObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpf_metricsPrograms struct {
+	UprobeRtExecute    *ebpf.Program `ebpf:"uprobe_rt_execute"`
+	UprobeRtGcMarkTerm *ebpf.Program `ebpf:"uprobe_rt_gc_mark_term"`
+	UprobeRtGoexit     *ebpf.Program `ebpf:"uprobe_rt_goexit"`
+	UprobeRtNewproc    *ebpf.Program `ebpf:"uprobe_rt_newproc"`
+	UprobeRtRunqput    *ebpf.Program `ebpf:"uprobe_rt_runqput"`
+	UprobeRtStwEnd     *ebpf.Program `ebpf:"uprobe_rt_stw_end"`
+	UprobeRtStwStart   *ebpf.Program `ebpf:"uprobe_rt_stw_start"`
+}
+
+func (p *bpf_metricsPrograms) Close() error {
+	return _Bpf_metricsClose(
+		p.UprobeRtExecute,
+		p.UprobeRtGcMarkTerm,
+		p.UprobeRtGoexit,
+		p.UprobeRtNewproc,
+		p.UprobeRtRunqput,
+		p.UprobeRtStwEnd,
+		p.UprobeRtStwStart,
+	)
+}
+
+func _Bpf_metricsClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed bpf_metrics_bpfel_x86.o
+var _Bpf_metricsBytes []byte
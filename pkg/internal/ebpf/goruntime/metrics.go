@@ -0,0 +1,120 @@
+package goruntime
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/cilium/ebpf"
+
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/exec"
+	"github.com/grafana/beyla/pkg/internal/goexec"
+	rtstats "github.com/grafana/beyla/pkg/internal/infraolly/goruntime"
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+//go:generate $BPF2GO -cc $BPF_CLANG -cflags $BPF_CFLAGS -target amd64,arm64 -type go_rt_stats_t bpf_metrics ../../../../bpf/go_runtime_metrics.c -- -I../../../../bpf/headers
+
+// MetricsTracer accounts the runtime statistics of the Go processes: goroutines, garbage
+// collection cycles, stop-the-world pauses and, optionally, the latency of the goroutines in
+// the scheduler run queues. The statistics of each process are stored in a pinned map that is
+// periodically read by the goruntime Collector of the infraolly package.
+type MetricsTracer struct {
+	log        *slog.Logger
+	bpfObjects bpf_metricsObjects
+	closers    []io.Closer
+	// schedLatency enables the probes of the scheduler run queues. They are invoked
+	// each time that a goroutine is scheduled, so they have a noticeable overhead.
+	schedLatency bool
+}
+
+func NewMetricsTracer(schedLatency bool) *MetricsTracer {
+	return &MetricsTracer{
+		log:          slog.With("component", "goruntime.MetricsTracer"),
+		schedLatency: schedLatency,
+	}
+}
+
+// AllowPID does nothing, as the uprobes only account the processes of the
+// instrumented executable, and the Collector filters them by the PIDs of the
+// reported spans
+func (p *MetricsTracer) AllowPID(_, _ uint32, _ svc.ID) {}
+
+func (p *MetricsTracer) BlockPID(pid, _ uint32) {
+	if p.bpfObjects.GoRtStats == nil {
+		return
+	}
+	if err := p.bpfObjects.GoRtStats.Delete(pid); err != nil {
+		p.log.Debug("can't remove Go runtime stats", "pid", pid, "error", err)
+	}
+}
+
+func (p *MetricsTracer) Load() (*ebpf.CollectionSpec, error) {
+	return loadBpf_metrics()
+}
+
+func (p *MetricsTracer) SetupTailCalls() {}
+
+func (p *MetricsTracer) Constants(_ *exec.FileInfo, _ *goexec.Offsets) map[string]any {
+	var bounds [len(rtstats.BucketBoundsNs)]uint64
+	for i, b := range rtstats.BucketBoundsNs {
+		bounds[i] = uint64(b)
+	}
+	return map[string]any{"bucket_bounds_ns": bounds}
+}
+
+func (p *MetricsTracer) BpfObjects() any {
+	return &p.bpfObjects
+}
+
+func (p *MetricsTracer) AddCloser(c ...io.Closer) {
+	p.closers = append(p.closers, c...)
+}
+
+// GoProbes of the runtime metrics. GC cycles are accounted at mark termination, as
+// runtime.gcStart might return without starting a new cycle.
+func (p *MetricsTracer) GoProbes() map[string]ebpfcommon.FunctionPrograms {
+	probes := map[string]ebpfcommon.FunctionPrograms{
+		"runtime.newproc1":              {Start: p.bpfObjects.UprobeRtNewproc},
+		"runtime.goexit1":               {Start: p.bpfObjects.UprobeRtGoexit},
+		"runtime.gcMarkTermination":     {Start: p.bpfObjects.UprobeRtGcMarkTerm},
+		"runtime.stopTheWorldWithSema":  {Start: p.bpfObjects.UprobeRtStwStart},
+		"runtime.startTheWorldWithSema": {Start: p.bpfObjects.UprobeRtStwEnd},
+	}
+	if p.schedLatency {
+		probes["runtime.runqput"] = ebpfcommon.FunctionPrograms{Start: p.bpfObjects.UprobeRtRunqput}
+		probes["runtime.execute"] = ebpfcommon.FunctionPrograms{Start: p.bpfObjects.UprobeRtExecute}
+	}
+	return probes
+}
+
+func (p *MetricsTracer) KProbes() map[string]ebpfcommon.FunctionPrograms {
+	return nil
+}
+
+func (p *MetricsTracer) UProbes() map[string]map[string]ebpfcommon.FunctionPrograms {
+	return nil
+}
+
+func (p *MetricsTracer) Tracepoints() map[string]ebpfcommon.FunctionPrograms {
+	return nil
+}
+
+func (p *MetricsTracer) SocketFilters() []*ebpf.Program {
+	return nil
+}
+
+func (p *MetricsTracer) RecordInstrumentedLib(_ uint64) {}
+
+func (p *MetricsTracer) AlreadyInstrumentedLib(_ uint64) bool {
+	return false
+}
+
+func (p *MetricsTracer) Run(ctx context.Context, _ chan<- []request.Span) {
+	<-ctx.Done()
+	for _, c := range p.closers {
+		_ = c.Close()
+	}
+}
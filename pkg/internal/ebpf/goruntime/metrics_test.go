@@ -0,0 +1,55 @@
+package goruntime
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/rlimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	rtstats "github.com/grafana/beyla/pkg/internal/infraolly/goruntime"
+)
+
+func TestStatsLayout(t *testing.T) {
+	// the Collector reads the stats map with the offsets of the infraolly package
+	var st bpf_metricsGoRtStatsT
+	assert.Equal(t, rtstats.StatsSize, binary.Size(st))
+	assert.EqualValues(t, rtstats.OffGoroutinesCreated, unsafe.Offsetof(st.GoroutinesCreated))
+	assert.EqualValues(t, rtstats.OffGoroutinesExited, unsafe.Offsetof(st.GoroutinesExited))
+	assert.EqualValues(t, rtstats.OffGCCycles, unsafe.Offsetof(st.GcCycles))
+	assert.EqualValues(t, rtstats.OffSTWPauses, unsafe.Offsetof(st.StwPauses))
+	assert.EqualValues(t, rtstats.OffSchedLatency, unsafe.Offsetof(st.SchedLatency))
+	assert.EqualValues(t, rtstats.HistogramSumOff, unsafe.Offsetof(st.StwPauses.SumNs))
+}
+
+func TestMetricsTracer_Load(t *testing.T) {
+	_ = rlimit.RemoveMemlock()
+	tracer := NewMetricsTracer(true)
+	spec, err := tracer.Load()
+	require.NoError(t, err)
+	// avoid pinning the maps of the test
+	spec.Maps[rtstats.StatsMapName].Pinning = ebpf.PinNone
+	require.NoError(t, spec.RewriteConstants(tracer.Constants(nil, nil)))
+
+	err = spec.LoadAndAssign(tracer.BpfObjects(), nil)
+	if errors.Is(err, os.ErrPermission) || errors.Is(err, ebpf.ErrNotSupported) {
+		t.Skip("can't load eBPF programs in this environment:", err)
+	}
+	require.NoError(t, err)
+	defer tracer.bpfObjects.Close()
+	probes := tracer.GoProbes()
+	assert.Contains(t, probes, "runtime.runqput")
+	assert.Contains(t, probes, "runtime.execute")
+	for name, programs := range probes {
+		require.NotNil(t, programs.Start, name)
+	}
+
+	// the scheduler probes are only instrumented on demand
+	assert.NotContains(t, NewMetricsTracer(false).GoProbes(), "runtime.runqput")
+	assert.NotContains(t, NewMetricsTracer(false).GoProbes(), "runtime.execute")
+}
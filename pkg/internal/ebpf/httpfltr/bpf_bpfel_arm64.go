@@ -31,6 +31,40 @@ type bpfConnectionInfoT struct {
 	D_port uint16
 }
 
+type bpfDnsReqKeyT struct {
+	SockPtr uint64
+	Pid     uint32
+	Id      uint16
+	Pad     [2]uint8
+}
+
+type bpfDnsReqT struct {
+	Flags           uint8
+	_               [1]byte
+	ConnInfo        bpfConnectionInfoT
+	_               [2]byte
+	StartMonotimeNs uint64
+	EndMonotimeNs   uint64
+	Buf             [128]uint8
+	Rbuf            [256]uint8
+	Len             uint32
+	RespLen         uint32
+	Pid             struct {
+		HostPid uint32
+		UserPid uint32
+		Ns      uint32
+	}
+	_  [4]byte
+	Tp struct {
+		TraceId  [16]uint8
+		SpanId   [8]uint8
+		ParentId [8]uint8
+		Ts       uint64
+		Flags    uint8
+		_        [7]byte
+	}
+}
+
 type bpfHttp2ConnStreamT struct {
 	PidConn  bpfPidConnectionInfoT
 	StreamId uint32
@@ -244,12 +278,15 @@ type bpfProgramSpecs struct {
 	KprobeTcpRcvEstablished *ebpf.ProgramSpec `ebpf:"kprobe_tcp_rcv_established"`
 	KprobeTcpRecvmsg        *ebpf.ProgramSpec `ebpf:"kprobe_tcp_recvmsg"`
 	KprobeTcpSendmsg        *ebpf.ProgramSpec `ebpf:"kprobe_tcp_sendmsg"`
+	KprobeUdpRecvmsg        *ebpf.ProgramSpec `ebpf:"kprobe_udp_recvmsg"`
+	KprobeUdpSendmsg        *ebpf.ProgramSpec `ebpf:"kprobe_udp_sendmsg"`
 	KretprobeSockAlloc      *ebpf.ProgramSpec `ebpf:"kretprobe_sock_alloc"`
 	KretprobeSysAccept4     *ebpf.ProgramSpec `ebpf:"kretprobe_sys_accept4"`
 	KretprobeSysClone       *ebpf.ProgramSpec `ebpf:"kretprobe_sys_clone"`
 	KretprobeSysConnect     *ebpf.ProgramSpec `ebpf:"kretprobe_sys_connect"`
 	KretprobeTcpRecvmsg     *ebpf.ProgramSpec `ebpf:"kretprobe_tcp_recvmsg"`
 	KretprobeTcpSendmsg     *ebpf.ProgramSpec `ebpf:"kretprobe_tcp_sendmsg"`
+	KretprobeUdpRecvmsg     *ebpf.ProgramSpec `ebpf:"kretprobe_udp_recvmsg"`
 	ProtocolHttp            *ebpf.ProgramSpec `ebpf:"protocol_http"`
 	ProtocolHttp2           *ebpf.ProgramSpec `ebpf:"protocol_http2"`
 	ProtocolTcp             *ebpf.ProgramSpec `ebpf:"protocol_tcp"`
@@ -270,14 +307,17 @@ type bpfMapSpecs struct {
 	ActiveSslHandshakes     *ebpf.MapSpec `ebpf:"active_ssl_handshakes"`
 	ActiveSslReadArgs       *ebpf.MapSpec `ebpf:"active_ssl_read_args"`
 	ActiveSslWriteArgs      *ebpf.MapSpec `ebpf:"active_ssl_write_args"`
+	ActiveUdpRecvArgs       *ebpf.MapSpec `ebpf:"active_udp_recv_args"`
 	CloneMap                *ebpf.MapSpec `ebpf:"clone_map"`
 	ConnectionMetaMem       *ebpf.MapSpec `ebpf:"connection_meta_mem"`
+	DnsReqMem               *ebpf.MapSpec `ebpf:"dns_req_mem"`
 	Events                  *ebpf.MapSpec `ebpf:"events"`
 	Http2InfoMem            *ebpf.MapSpec `ebpf:"http2_info_mem"`
 	HttpInfoMem             *ebpf.MapSpec `ebpf:"http_info_mem"`
 	IovecMem                *ebpf.MapSpec `ebpf:"iovec_mem"`
 	JumpTable               *ebpf.MapSpec `ebpf:"jump_table"`
 	NodejsParentMap         *ebpf.MapSpec `ebpf:"nodejs_parent_map"`
+	OngoingDnsReq           *ebpf.MapSpec `ebpf:"ongoing_dns_req"`
 	OngoingHttp             *ebpf.MapSpec `ebpf:"ongoing_http"`
 	OngoingHttp2Connections *ebpf.MapSpec `ebpf:"ongoing_http2_connections"`
 	OngoingHttp2Grpc        *ebpf.MapSpec `ebpf:"ongoing_http2_grpc"`
@@ -326,14 +366,17 @@ type bpfMaps struct {
 	ActiveSslHandshakes     *ebpf.Map `ebpf:"active_ssl_handshakes"`
 	ActiveSslReadArgs       *ebpf.Map `ebpf:"active_ssl_read_args"`
 	ActiveSslWriteArgs      *ebpf.Map `ebpf:"active_ssl_write_args"`
+	ActiveUdpRecvArgs       *ebpf.Map `ebpf:"active_udp_recv_args"`
 	CloneMap                *ebpf.Map `ebpf:"clone_map"`
 	ConnectionMetaMem       *ebpf.Map `ebpf:"connection_meta_mem"`
+	DnsReqMem               *ebpf.Map `ebpf:"dns_req_mem"`
 	Events                  *ebpf.Map `ebpf:"events"`
 	Http2InfoMem            *ebpf.Map `ebpf:"http2_info_mem"`
 	HttpInfoMem             *ebpf.Map `ebpf:"http_info_mem"`
 	IovecMem                *ebpf.Map `ebpf:"iovec_mem"`
 	JumpTable               *ebpf.Map `ebpf:"jump_table"`
 	NodejsParentMap         *ebpf.Map `ebpf:"nodejs_parent_map"`
+	OngoingDnsReq           *ebpf.Map `ebpf:"ongoing_dns_req"`
 	OngoingHttp             *ebpf.Map `ebpf:"ongoing_http"`
 	OngoingHttp2Connections *ebpf.Map `ebpf:"ongoing_http2_connections"`
 	OngoingHttp2Grpc        *ebpf.Map `ebpf:"ongoing_http2_grpc"`
@@ -365,14 +408,17 @@ func (m *bpfMaps) Close() error {
 		m.ActiveSslHandshakes,
 		m.ActiveSslReadArgs,
 		m.ActiveSslWriteArgs,
+		m.ActiveUdpRecvArgs,
 		m.CloneMap,
 		m.ConnectionMetaMem,
+		m.DnsReqMem,
 		m.Events,
 		m.Http2InfoMem,
 		m.HttpInfoMem,
 		m.IovecMem,
 		m.JumpTable,
 		m.NodejsParentMap,
+		m.OngoingDnsReq,
 		m.OngoingHttp,
 		m.OngoingHttp2Connections,
 		m.OngoingHttp2Grpc,
@@ -403,12 +449,15 @@ type bpfPrograms struct {
 	KprobeTcpRcvEstablished *ebpf.Program `ebpf:"kprobe_tcp_rcv_established"`
 	KprobeTcpRecvmsg        *ebpf.Program `ebpf:"kprobe_tcp_recvmsg"`
 	KprobeTcpSendmsg        *ebpf.Program `ebpf:"kprobe_tcp_sendmsg"`
+	KprobeUdpRecvmsg        *ebpf.Program `ebpf:"kprobe_udp_recvmsg"`
+	KprobeUdpSendmsg        *ebpf.Program `ebpf:"kprobe_udp_sendmsg"`
 	KretprobeSockAlloc      *ebpf.Program `ebpf:"kretprobe_sock_alloc"`
 	KretprobeSysAccept4     *ebpf.Program `ebpf:"kretprobe_sys_accept4"`
 	KretprobeSysClone       *ebpf.Program `ebpf:"kretprobe_sys_clone"`
 	KretprobeSysConnect     *ebpf.Program `ebpf:"kretprobe_sys_connect"`
 	KretprobeTcpRecvmsg     *ebpf.Program `ebpf:"kretprobe_tcp_recvmsg"`
 	KretprobeTcpSendmsg     *ebpf.Program `ebpf:"kretprobe_tcp_sendmsg"`
+	KretprobeUdpRecvmsg     *ebpf.Program `ebpf:"kretprobe_udp_recvmsg"`
 	ProtocolHttp            *ebpf.Program `ebpf:"protocol_http"`
 	ProtocolHttp2           *ebpf.Program `ebpf:"protocol_http2"`
 	ProtocolTcp             *ebpf.Program `ebpf:"protocol_tcp"`
@@ -423,12 +472,15 @@ func (p *bpfPrograms) Close() error {
 		p.KprobeTcpRcvEstablished,
 		p.KprobeTcpRecvmsg,
 		p.KprobeTcpSendmsg,
+		p.KprobeUdpRecvmsg,
+		p.KprobeUdpSendmsg,
 		p.KretprobeSockAlloc,
 		p.KretprobeSysAccept4,
 		p.KretprobeSysClone,
 		p.KretprobeSysConnect,
 		p.KretprobeTcpRecvmsg,
 		p.KretprobeTcpSendmsg,
+		p.KretprobeUdpRecvmsg,
 		p.ProtocolHttp,
 		p.ProtocolHttp2,
 		p.ProtocolTcp,
@@ -240,6 +240,26 @@ func (p *Tracer) KProbes() map[string]ebpfcommon.FunctionPrograms {
 			Required: true,
 			Start:    p.bpfObjects.KprobeSysExit,
 		},
+		// Tracking of DNS queries. IPv4 and IPv6 functions share the same programs.
+		// The IPv6 functions are not available if the kernel has been built without IPv6 support.
+		"udp_sendmsg": {
+			Optional: true,
+			Start:    p.bpfObjects.KprobeUdpSendmsg,
+		},
+		"udpv6_sendmsg": {
+			Optional: true,
+			Start:    p.bpfObjects.KprobeUdpSendmsg,
+		},
+		"udp_recvmsg": {
+			Optional: true,
+			Start:    p.bpfObjects.KprobeUdpRecvmsg,
+			End:      p.bpfObjects.KretprobeUdpRecvmsg,
+		},
+		"udpv6_recvmsg": {
+			Optional: true,
+			Start:    p.bpfObjects.KprobeUdpRecvmsg,
+			End:      p.bpfObjects.KretprobeUdpRecvmsg,
+		},
 	}
 }
 
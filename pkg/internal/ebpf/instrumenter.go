@@ -90,7 +90,10 @@ func (i *instrumenter) kprobes(p KprobesTracer) error {
 		log.Debug("going to add kprobe to function", "function", kfunc, "probes", kprobes)
 
 		if err := i.kprobe(kfunc, kprobes); err != nil {
-			return fmt.Errorf("instrumenting function %q: %w", kfunc, err)
+			if !kprobes.Optional {
+				return fmt.Errorf("instrumenting function %q: %w", kfunc, err)
+			}
+			log.Debug("can't instrument optional function. Ignoring", "function", kfunc, "error", err)
 		}
 		p.AddCloser(i.closables...)
 	}
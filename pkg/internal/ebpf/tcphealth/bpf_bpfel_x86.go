@@ -0,0 +1,170 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build 386 || amd64
+
+package tcphealth
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+
+	"github.com/cilium/ebpf"
+)
+
+type bpfTcpHealthKeyT struct {
+	Pid  uint32
+	Port uint16
+	Role uint8
+	Pad  uint8
+	Addr [16]uint8
+}
+
+type bpfTcpHealthStatsT struct {
+	Retransmits     uint64
+	ResetsReceived  uint64
+	ResetsSent      uint64
+	FailuresRefused uint64
+	FailuresTimeout uint64
+	SetupDuration   struct {
+		Counts [10]uint64
+		SumNs  uint64
+	}
+	Rtt struct {
+		Counts [10]uint64
+		SumNs  uint64
+	}
+}
+
+// loadBpf returns the embedded CollectionSpec for bpf.
+func loadBpf() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_BpfBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load bpf: %w", err)
+	}
+
+	return spec, err
+}
+
+// loadBpfObjects loads bpf and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*bpfObjects
+//	*bpfPrograms
+//	*bpfMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func loadBpfObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := loadBpf()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// bpfSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfSpecs struct {
+	bpfProgramSpecs
+	bpfMapSpecs
+}
+
+// bpfSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfProgramSpecs struct {
+	KprobeTcpSendmsg   *ebpf.ProgramSpec `ebpf:"kprobe_tcp_sendmsg"`
+	KretprobeTcpAccept *ebpf.ProgramSpec `ebpf:"kretprobe_tcp_accept"`
+	TpTcpReceiveReset  *ebpf.ProgramSpec `ebpf:"tp_tcp_receive_reset"`
+	TpTcpRetransmit    *ebpf.ProgramSpec `ebpf:"tp_tcp_retransmit"`
+	TpTcpSendReset     *ebpf.ProgramSpec `ebpf:"tp_tcp_send_reset"`
+	TpTcpSetState      *ebpf.ProgramSpec `ebpf:"tp_tcp_set_state"`
+}
+
+// bpfMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfMapSpecs struct {
+	TcpHealthConnects *ebpf.MapSpec `ebpf:"tcp_health_connects"`
+	TcpHealthPending  *ebpf.MapSpec `ebpf:"tcp_health_pending"`
+	TcpHealthPids     *ebpf.MapSpec `ebpf:"tcp_health_pids"`
+	TcpHealthSocks    *ebpf.MapSpec `ebpf:"tcp_health_socks"`
+	TcpHealthStats    *ebpf.MapSpec `ebpf:"tcp_health_stats"`
+}
+
+// bpfObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfObjects struct {
+	bpfPrograms
+	bpfMaps
+}
+
+func (o *bpfObjects) Close() error {
+	return _BpfClose(
+		&o.bpfPrograms,
+		&o.bpfMaps,
+	)
+}
+
+// bpfMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfMaps struct {
+	TcpHealthConnects *ebpf.Map `ebpf:"tcp_health_connects"`
+	TcpHealthPending  *ebpf.Map `ebpf:"tcp_health_pending"`
+	TcpHealthPids     *ebpf.Map `ebpf:"tcp_health_pids"`
+	TcpHealthSocks    *ebpf.Map `ebpf:"tcp_health_socks"`
+	TcpHealthStats    *ebpf.Map `ebpf:"tcp_health_stats"`
+}
+
+func (m *bpfMaps) Close() error {
+	return _BpfClose(
+		m.TcpHealthConnects,
+		m.TcpHealthPending,
+		m.TcpHealthPids,
+		m.TcpHealthSocks,
+		m.TcpHealthStats,
+	)
+}
+
+// bpfPrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfPrograms struct {
+	KprobeTcpSendmsg   *ebpf.Program `ebpf:"kprobe_tcp_sendmsg"`
+	KretprobeTcpAccept *ebpf.Program `ebpf:"kretprobe_tcp_accept"`
+	TpTcpReceiveReset  *ebpf.Program `ebpf:"tp_tcp_receive_reset"`
+	TpTcpRetransmit    *ebpf.Program `ebpf:"tp_tcp_retransmit"`
+	TpTcpSendReset     *ebpf.Program `ebpf:"tp_tcp_send_reset"`
+	TpTcpSetState      *ebpf.Program `ebpf:"tp_tcp_set_state"`
+}
+
+func (p *bpfPrograms) Close() error {
+	return _BpfClose(
+		p.KprobeTcpSendmsg,
+		p.KretprobeTcpAccept,
+		p.TpTcpReceiveReset,
+		p.TpTcpRetransmit,
+		p.TpTcpSendReset,
+		p.TpTcpSetState,
+	)
+}
+
+func _BpfClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed bpf_bpfel_x86.o
+var _BpfBytes []byte
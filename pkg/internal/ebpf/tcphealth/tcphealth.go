@@ -0,0 +1,107 @@
+// Package tcphealth provides the eBPF tracer that accounts the health of the TCP connections
+// of the instrumented processes: smoothed round-trip time, retransmitted segments, resets,
+// connection establishment latency and failed connection attempts.
+package tcphealth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/cilium/ebpf"
+
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	stats "github.com/grafana/beyla/pkg/internal/infraolly/tcphealth"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+//go:generate $BPF2GO -cc $BPF_CLANG -cflags $BPF_CFLAGS -target amd64,arm64 -type tcp_health_key_t -type tcp_health_stats_t bpf ../../../../bpf/tcp_health.c -- -I../../../../bpf/headers
+
+// Tracer accounts the health of the TCP connections that are initiated or accepted by the
+// instrumented processes. The statistics are grouped by process, role and peer, and stored
+// in a pinned map that is periodically read by the tcphealth Collector of the infraolly package.
+// It is loaded once for the whole host.
+type Tracer struct {
+	log        *slog.Logger
+	systemWide bool
+	bpfObjects bpfObjects
+	closers    []io.Closer
+}
+
+// New TCP health tracer. If systemWide is true, the connections of all the processes
+// are accounted, regardless of the allowed PIDs.
+func New(systemWide bool) *Tracer {
+	return &Tracer{log: slog.With("component", "tcphealth.Tracer"), systemWide: systemWide}
+}
+
+func (p *Tracer) AllowPID(pid, _ uint32, _ svc.ID) {
+	if p.bpfObjects.TcpHealthPids == nil {
+		return
+	}
+	if err := p.bpfObjects.TcpHealthPids.Put(pid, uint32(1)); err != nil {
+		p.log.Warn("can't account TCP connections of process", "pid", pid, "error", err)
+	}
+}
+
+func (p *Tracer) BlockPID(pid, _ uint32) {
+	if p.bpfObjects.TcpHealthPids == nil {
+		return
+	}
+	if err := p.bpfObjects.TcpHealthPids.Delete(pid); err != nil {
+		p.log.Debug("can't stop accounting TCP connections of process", "pid", pid, "error", err)
+	}
+}
+
+// Load the eBPF programs. The fields of the tracepoints and the kernel socket are
+// relocated from the kernel BTF.
+func (p *Tracer) Load() (*ebpf.CollectionSpec, error) {
+	spec, err := loadBpf()
+	if err != nil {
+		return nil, err
+	}
+	var bounds [len(stats.BucketBoundsNs)]uint64
+	for i, b := range stats.BucketBoundsNs {
+		bounds[i] = uint64(b)
+	}
+	if err := spec.RewriteConstants(map[string]any{
+		"system_wide":      p.systemWide,
+		"bucket_bounds_ns": bounds,
+	}); err != nil {
+		return nil, fmt.Errorf("rewriting constants: %w", err)
+	}
+	return spec, nil
+}
+
+func (p *Tracer) SetupTailCalls() {}
+
+func (p *Tracer) BpfObjects() any {
+	return &p.bpfObjects
+}
+
+func (p *Tracer) AddCloser(c ...io.Closer) {
+	p.closers = append(p.closers, c...)
+}
+
+func (p *Tracer) KProbes() map[string]ebpfcommon.FunctionPrograms {
+	return map[string]ebpfcommon.FunctionPrograms{
+		"inet_csk_accept": {Required: true, End: p.bpfObjects.KretprobeTcpAccept},
+		"tcp_sendmsg":     {Start: p.bpfObjects.KprobeTcpSendmsg},
+	}
+}
+
+func (p *Tracer) Tracepoints() map[string]ebpfcommon.FunctionPrograms {
+	return map[string]ebpfcommon.FunctionPrograms{
+		"sock/inet_sock_set_state": {Start: p.bpfObjects.TpTcpSetState},
+		"tcp/tcp_retransmit_skb":   {Start: p.bpfObjects.TpTcpRetransmit},
+		"tcp/tcp_receive_reset":    {Start: p.bpfObjects.TpTcpReceiveReset},
+		"tcp/tcp_send_reset":       {Start: p.bpfObjects.TpTcpSendReset},
+	}
+}
+
+func (p *Tracer) Run(ctx context.Context) {
+	<-ctx.Done()
+	for _, c := range p.closers {
+		_ = c.Close()
+	}
+}
@@ -0,0 +1,61 @@
+package tcphealth
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/rlimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	stats "github.com/grafana/beyla/pkg/internal/infraolly/tcphealth"
+)
+
+func TestLayout(t *testing.T) {
+	// the Collector reads the stats map with the offsets of the infraolly package
+	var k bpfTcpHealthKeyT
+	assert.Equal(t, stats.KeySize, binary.Size(k))
+	assert.EqualValues(t, stats.KeyOffPID, unsafe.Offsetof(k.Pid))
+	assert.EqualValues(t, stats.KeyOffPort, unsafe.Offsetof(k.Port))
+	assert.EqualValues(t, stats.KeyOffRole, unsafe.Offsetof(k.Role))
+	assert.EqualValues(t, stats.KeyOffAddr, unsafe.Offsetof(k.Addr))
+
+	var s bpfTcpHealthStatsT
+	assert.Equal(t, stats.StatsSize, binary.Size(s))
+	assert.EqualValues(t, stats.OffRetransmits, unsafe.Offsetof(s.Retransmits))
+	assert.EqualValues(t, stats.OffResetsReceived, unsafe.Offsetof(s.ResetsReceived))
+	assert.EqualValues(t, stats.OffResetsSent, unsafe.Offsetof(s.ResetsSent))
+	assert.EqualValues(t, stats.OffFailuresRefused, unsafe.Offsetof(s.FailuresRefused))
+	assert.EqualValues(t, stats.OffFailuresTimeout, unsafe.Offsetof(s.FailuresTimeout))
+	assert.EqualValues(t, stats.OffSetupDuration, unsafe.Offsetof(s.SetupDuration))
+	assert.EqualValues(t, stats.OffRTT, unsafe.Offsetof(s.Rtt))
+	assert.EqualValues(t, stats.HistogramSumOff, unsafe.Offsetof(s.Rtt.SumNs))
+}
+
+func TestTracer_Load(t *testing.T) {
+	_ = rlimit.RemoveMemlock()
+	for _, systemWide := range []bool{false, true} {
+		tracer := New(systemWide)
+		spec, err := tracer.Load()
+		require.NoError(t, err)
+		// avoid pinning the maps of the test
+		spec.Maps[stats.StatsMapName].Pinning = ebpf.PinNone
+
+		err = spec.LoadAndAssign(tracer.BpfObjects(), nil)
+		if errors.Is(err, os.ErrPermission) || errors.Is(err, ebpf.ErrNotSupported) {
+			t.Skip("can't load eBPF programs in this environment:", err)
+		}
+		require.NoError(t, err)
+		for name, programs := range tracer.KProbes() {
+			require.True(t, programs.Start != nil || programs.End != nil, name)
+		}
+		for name, programs := range tracer.Tracepoints() {
+			require.NotNil(t, programs.Start, name)
+		}
+		require.NoError(t, tracer.bpfObjects.Close())
+	}
+}
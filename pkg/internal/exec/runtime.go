@@ -0,0 +1,114 @@
+package exec
+
+import (
+	"debug/elf"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+var (
+	javaReleaseVersion = regexp.MustCompile(`(?m)^JAVA_VERSION="([^"]+)"`)
+	javaPathVersion    = regexp.MustCompile(`/(?:java|jdk|jre|openjdk)-?(\d+(?:\.\d+)*)`)
+	nodeReleaseVersion = regexp.MustCompile(`nodejs\.org/download/release/v(\d+\.\d+\.\d+)/`)
+	pythonLibVersion   = regexp.MustCompile(`(?:^|/)(?:libpython|python)(\d+\.\d+)`)
+	rubyLibVersion     = regexp.MustCompile(`(?:^|/)(?:libruby|ruby)[-.]?(?:so\.)?(\d+\.\d+)`)
+	dotnetPathVersion  = regexp.MustCompile(`/Microsoft\.NETCore\.App/(\d+\.\d+\.\d+[^/]*)/`)
+)
+
+// runtimeName returns the name of the runtime for the languages whose runtime can be identified
+func runtimeName(lang svc.InstrumentableType) string {
+	switch lang {
+	case svc.InstrumentableGolang, svc.InstrumentableJava, svc.InstrumentableNodejs,
+		svc.InstrumentablePython, svc.InstrumentableRuby, svc.InstrumentableDotnet:
+		return lang.String()
+	default:
+		return ""
+	}
+}
+
+// javaVersionFromRelease returns the JAVA_VERSION entry from the contents of
+// the release file that is placed in the root of any JDK/JRE installation.
+func javaVersionFromRelease(release string) string {
+	if m := javaReleaseVersion.FindStringSubmatch(release); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// javaVersionFromPath guesses the Java version from the path of the libjvm.so library,
+// for installations without release file (e.g. /usr/lib/jvm/java-17-openjdk-amd64/lib/server/libjvm.so)
+func javaVersionFromPath(libPath string) string {
+	if m := javaPathVersion.FindStringSubmatch(libPath); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func pythonVersionFromModule(module string) string {
+	if m := pythonLibVersion.FindStringSubmatch(module); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func rubyVersionFromModule(module string) string {
+	if m := rubyLibVersion.FindStringSubmatch(module); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// dotnetVersionFromModule returns the .NET runtime version from the path of the
+// libcoreclr.so library (e.g. /usr/share/dotnet/shared/Microsoft.NETCore.App/8.0.1/libcoreclr.so)
+func dotnetVersionFromModule(module string) string {
+	if m := dotnetPathVersion.FindStringSubmatch(module); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// GoRuntime returns the runtime name and version of a Go executable whose build
+// information has been already read, from its Go version (e.g. go1.22.1)
+func GoRuntime(buildVersion string) (name, version string) {
+	return runtimeName(svc.InstrumentableGolang), goVersion(buildVersion)
+}
+
+// goVersion removes the "go" prefix from the runtime.buildVersion value (e.g. go1.22.1)
+func goVersion(buildVersion string) string {
+	version, _, _ := strings.Cut(strings.TrimPrefix(buildVersion, "go"), " ")
+	return version
+}
+
+// nodeVersionFromELF looks for the release URL that is embedded in the read-only data
+// of the Node.js executable (or libnode library)
+func nodeVersionFromELF(f *elf.File) string {
+	section := f.Section(".rodata")
+	if section == nil {
+		return ""
+	}
+	return findInReader(section.Open(), nodeReleaseVersion)
+}
+
+// findInReader returns the first submatch of the regular expression in the
+// provided reader. The data is read in chunks that overlap to find the matches
+// that are split between chunks.
+func findInReader(r io.Reader, re *regexp.Regexp) string {
+	const chunkSize = 1 << 20
+	const overlap = 256
+	buf := make([]byte, chunkSize+overlap)
+	kept := 0
+	for {
+		n, err := io.ReadFull(r, buf[kept:])
+		data := buf[:kept+n]
+		if m := re.FindSubmatch(data); m != nil {
+			return string(m[1])
+		}
+		if err != nil {
+			return ""
+		}
+		kept = copy(buf, data[len(data)-overlap:])
+	}
+}
@@ -0,0 +1,9 @@
+package exec
+
+import (
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func FindProcRuntime(_ int32, _ svc.InstrumentableType) (string, string) {
+	return "", ""
+}
@@ -0,0 +1,99 @@
+package exec
+
+import (
+	"debug/buildinfo"
+	"debug/elf"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/prometheus/procfs"
+
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+// maximum number of parent directories of libjvm.so where the release file is looked for
+const maxJavaHomeDepth = 5
+
+// FindProcRuntime returns the name and version of the runtime of a process whose language
+// has been already identified. The version is empty if it can't be found.
+func FindProcRuntime(pid int32, lang svc.InstrumentableType) (name, version string) {
+	name = runtimeName(lang)
+	if name == "" {
+		return "", ""
+	}
+	if lang == svc.InstrumentableGolang {
+		bi, err := buildinfo.ReadFile(fmt.Sprintf("/proc/%d/exe", pid))
+		if err != nil {
+			return name, ""
+		}
+		return name, goVersion(bi.GoVersion)
+	}
+	maps, err := FindLibMaps(pid)
+	if err != nil {
+		return name, ""
+	}
+	switch lang {
+	case svc.InstrumentableJava:
+		version = javaVersion(pid, maps)
+	case svc.InstrumentableNodejs:
+		version = nodeVersion(pid, maps)
+	case svc.InstrumentablePython:
+		version = versionFromModules(maps, pythonVersionFromModule)
+	case svc.InstrumentableRuby:
+		version = versionFromModules(maps, rubyVersionFromModule)
+	case svc.InstrumentableDotnet:
+		version = versionFromModules(maps, dotnetVersionFromModule)
+	}
+	return name, version
+}
+
+func versionFromModules(maps []*procfs.ProcMap, versionFromModule func(string) string) string {
+	for _, m := range maps {
+		if v := versionFromModule(m.Pathname); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// javaVersion looks for the release file in the Java home folder, which is one of
+// the parent folders of the libjvm.so library. If not found, it tries to guess the
+// version from the library path.
+func javaVersion(pid int32, maps []*procfs.ProcMap) string {
+	lib := LibPath("libjvm.so", maps)
+	if lib == nil {
+		return ""
+	}
+	// the paths in the maps file are relative to the root of the process, which might be in a container
+	root := fmt.Sprintf("/proc/%d/root", pid)
+	dir := path.Dir(lib.Pathname)
+	for i := 0; i < maxJavaHomeDepth && dir != "/" && dir != "."; i++ {
+		if release, err := os.ReadFile(path.Join(root, dir, "release")); err == nil {
+			if v := javaVersionFromRelease(string(release)); v != "" {
+				return v
+			}
+		}
+		dir = path.Dir(dir)
+	}
+	return javaVersionFromPath(lib.Pathname)
+}
+
+// nodeVersion reads the version from the Node.js executable or, if Node.js is
+// dynamically linked, from the libnode.so library.
+func nodeVersion(pid int32, maps []*procfs.ProcMap) string {
+	file := fmt.Sprintf("/proc/%d/exe", pid)
+	for _, m := range maps {
+		if strings.Contains(path.Base(m.Pathname), "libnode.so") {
+			file = path.Join(fmt.Sprintf("/proc/%d/root", pid), m.Pathname)
+			break
+		}
+	}
+	f, err := elf.Open(file)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	return nodeVersionFromELF(f)
+}
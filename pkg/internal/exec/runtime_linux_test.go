@@ -0,0 +1,18 @@
+package exec
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func TestFindProcRuntime_Go(t *testing.T) {
+	name, version := FindProcRuntime(int32(os.Getpid()), svc.InstrumentableGolang)
+	assert.Equal(t, "go", name)
+	assert.Equal(t, strings.TrimPrefix(runtime.Version(), "go"), version)
+}
@@ -0,0 +1,69 @@
+package exec
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func TestRuntimeName(t *testing.T) {
+	assert.Equal(t, "go", runtimeName(svc.InstrumentableGolang))
+	assert.Equal(t, "java", runtimeName(svc.InstrumentableJava))
+	assert.Equal(t, "nodejs", runtimeName(svc.InstrumentableNodejs))
+	assert.Equal(t, "python", runtimeName(svc.InstrumentablePython))
+	assert.Equal(t, "ruby", runtimeName(svc.InstrumentableRuby))
+	assert.Equal(t, "dotnet", runtimeName(svc.InstrumentableDotnet))
+	assert.Empty(t, runtimeName(svc.InstrumentableRust))
+	assert.Empty(t, runtimeName(svc.InstrumentableGeneric))
+}
+
+func TestJavaVersion(t *testing.T) {
+	assert.Equal(t, "21.0.2", javaVersionFromRelease(
+		"IMPLEMENTOR=\"Eclipse Adoptium\"\nJAVA_RUNTIME_VERSION=\"21.0.2+13-LTS\"\nJAVA_VERSION=\"21.0.2\"\nOS_ARCH=\"x86_64\"\n"))
+	assert.Equal(t, "1.8.0_402", javaVersionFromRelease("JAVA_VERSION=\"1.8.0_402\"\nOS_NAME=\"Linux\"\n"))
+	assert.Empty(t, javaVersionFromRelease("IMPLEMENTOR=\"Eclipse Adoptium\"\n"))
+
+	assert.Equal(t, "17", javaVersionFromPath("/usr/lib/jvm/java-17-openjdk-amd64/lib/server/libjvm.so"))
+	assert.Equal(t, "1.8.0", javaVersionFromPath("/usr/lib/jvm/java-1.8.0-openjdk/jre/lib/amd64/server/libjvm.so"))
+	assert.Equal(t, "21.0.2", javaVersionFromPath("/opt/jdk-21.0.2/lib/server/libjvm.so"))
+	assert.Empty(t, javaVersionFromPath("/opt/java/openjdk/lib/server/libjvm.so"))
+}
+
+func TestModuleVersions(t *testing.T) {
+	assert.Equal(t, "3.11", pythonVersionFromModule("/usr/lib/x86_64-linux-gnu/libpython3.11.so.1.0"))
+	assert.Equal(t, "3.9", pythonVersionFromModule("/usr/local/bin/python3.9"))
+	assert.Empty(t, pythonVersionFromModule("/usr/bin/python3"))
+	assert.Empty(t, pythonVersionFromModule("/usr/lib/libc.so.6"))
+
+	assert.Equal(t, "3.2", rubyVersionFromModule("/usr/lib/libruby.so.3.2"))
+	assert.Equal(t, "3.1", rubyVersionFromModule("/usr/local/lib/libruby-3.1.so"))
+	assert.Equal(t, "2.7", rubyVersionFromModule("/usr/bin/ruby2.7"))
+	assert.Empty(t, rubyVersionFromModule("/usr/bin/ruby"))
+
+	assert.Equal(t, "8.0.1", dotnetVersionFromModule("/usr/share/dotnet/shared/Microsoft.NETCore.App/8.0.1/libcoreclr.so"))
+	assert.Equal(t, "9.0.0-preview.1", dotnetVersionFromModule("/root/.dotnet/shared/Microsoft.NETCore.App/9.0.0-preview.1/libcoreclr.so"))
+	assert.Empty(t, dotnetVersionFromModule("/app/libcoreclr.so"))
+
+	assert.Equal(t, "1.22.1", goVersion("go1.22.1"))
+	assert.Equal(t, "1.23rc1", goVersion("go1.23rc1 X:nocoverageredesign"))
+
+	name, version := GoRuntime("go1.22.1")
+	assert.Equal(t, "go", name)
+	assert.Equal(t, "1.22.1", version)
+}
+
+func TestFindInReader(t *testing.T) {
+	const url = "https://nodejs.org/download/release/v20.11.1/node-v20.11.1.tar.gz"
+	// the version is placed across the limit of the first chunk
+	data := bytes.Repeat([]byte{0}, 1<<20-40)
+	data = append(data, []byte(url)...)
+	data = append(data, bytes.Repeat([]byte{0}, 1000)...)
+	assert.Equal(t, "20.11.1", findInReader(bytes.NewReader(data), nodeReleaseVersion))
+
+	assert.Equal(t, "20.11.1", findInReader(bytes.NewReader([]byte(url)), nodeReleaseVersion))
+	assert.Empty(t, findInReader(bytes.NewReader(data), regexp.MustCompile(`release/v(\d+)-not-found`)))
+}
@@ -2,6 +2,7 @@ package filter
 
 import (
 	"fmt"
+	"log/slog"
 
 	"github.com/gobwas/glob"
 	"github.com/mariomac/pipes/pipe"
@@ -23,8 +24,18 @@ type AttributeFamilyConfig map[string]MatchDefinition
 // ByAttribute provides a pipeline node that drops all the records of type T (*ebpf.Record, or *request.Span)
 // that do not match the provided AttributeFamilyConfig.
 func ByAttribute[T any](config AttributeFamilyConfig, getters attributes.NamedGetters[T, string]) pipe.MiddleProvider[[]T, []T] {
+	return ByReloadableAttribute(config, nil, getters)
+}
+
+// ByReloadableAttribute works as ByAttribute, but it also replaces the filter configuration
+// each time a new AttributeFamilyConfig is received from the updates channel.
+func ByReloadableAttribute[T any](
+	config AttributeFamilyConfig,
+	updates <-chan AttributeFamilyConfig,
+	getters attributes.NamedGetters[T, string],
+) pipe.MiddleProvider[[]T, []T] {
 	return func() (pipe.MiddleFunc[[]T, []T], error) {
-		if len(config) == 0 {
+		if len(config) == 0 && updates == nil {
 			// No filter configuration provided. The node will be ignored
 			// and bypassed by the Pipes library
 			return pipe.Bypass[[]T](), nil
@@ -33,7 +44,30 @@ func ByAttribute[T any](config AttributeFamilyConfig, getters attributes.NamedGe
 		if err != nil {
 			return nil, err
 		}
-		return f.doFilter, nil
+		if updates == nil {
+			return f.doFilter, nil
+		}
+		return func(in <-chan []T, out chan<- []T) {
+			for {
+				select {
+				case cfg := <-updates:
+					nf, err := newFilter(cfg, getters)
+					if err != nil {
+						slog.With("component", "filter.Attribute").
+							Error("can't reload filter configuration. Keeping the previous one", "error", err)
+						continue
+					}
+					f = nf
+				case i, ok := <-in:
+					if !ok {
+						return
+					}
+					if i = f.filterBatch(i); len(i) > 0 {
+						out <- i
+					}
+				}
+			}
+		}, nil
 	}
 }
 
@@ -98,3 +98,36 @@ func TestAttributeFilter_VerificationError(t *testing.T) {
 		})
 	}
 }
+
+func TestAttributeFilter_Reload(t *testing.T) {
+	// unbuffered channel, so the updates are processed before the next input batch
+	updates := make(chan AttributeFamilyConfig)
+	filterFunc, err := ByReloadableAttribute[*ebpf.Record](AttributeFamilyConfig{
+		"beyla.ip": MatchDefinition{Match: "148.*"},
+	}, updates, ebpf.RecordStringGetters)()
+	require.NoError(t, err)
+
+	in := make(chan []*ebpf.Record, 10)
+	out := make(chan []*ebpf.Record, 10)
+	go filterFunc(in, out)
+	defer close(in)
+
+	in <- []*ebpf.Record{
+		{Attrs: ebpf.RecordAttrs{BeylaIP: "148.132.1.1"}},
+		{Attrs: ebpf.RecordAttrs{BeylaIP: "141.132.1.1"}},
+	}
+	assert.Equal(t, []*ebpf.Record{
+		{Attrs: ebpf.RecordAttrs{BeylaIP: "148.132.1.1"}},
+	}, testutil.ReadChannel(t, out, timeout))
+
+	// invalid configurations are ignored
+	updates <- AttributeFamilyConfig{"super-attribute": MatchDefinition{Match: "foo"}}
+	updates <- AttributeFamilyConfig{"beyla.ip": MatchDefinition{Match: "141.*"}}
+	in <- []*ebpf.Record{
+		{Attrs: ebpf.RecordAttrs{BeylaIP: "148.132.1.1"}},
+		{Attrs: ebpf.RecordAttrs{BeylaIP: "141.132.1.1"}},
+	}
+	assert.Equal(t, []*ebpf.Record{
+		{Attrs: ebpf.RecordAttrs{BeylaIP: "141.132.1.1"}},
+	}, testutil.ReadChannel(t, out, timeout))
+}
@@ -0,0 +1,55 @@
+package goexec
+
+import (
+	"path"
+	"regexp"
+	"runtime/debug"
+	"strings"
+
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+)
+
+// develVersion is the version of the main module when it has been built
+// from a local source tree, without version control information
+const develVersion = "(devel)"
+
+var majorVersionSuffix = regexp.MustCompile(`^v\d+$`)
+
+// BuildInfoMetadata returns the service metadata that is extracted from the
+// build information: main module path and version, and VCS revision.
+func BuildInfoMetadata(bi *debug.BuildInfo) map[attr.Name]string {
+	if bi == nil {
+		return nil
+	}
+	md := map[attr.Name]string{}
+	if bi.Main.Path != "" {
+		md[attr.GoModulePath] = bi.Main.Path
+	}
+	if bi.Main.Version != "" && bi.Main.Version != develVersion {
+		md[attr.ServiceVersion] = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		if s.Key == "vcs.revision" && s.Value != "" {
+			md[attr.VCSRevision] = s.Value
+		}
+	}
+	return md
+}
+
+// ModuleServiceName returns a service name from the path of the main module of an executable
+// (e.g. "checkout" for github.com/acme/checkout/v2). It returns an empty string if the module
+// path is unknown.
+func ModuleServiceName(bi *debug.BuildInfo) string {
+	if bi == nil {
+		return ""
+	}
+	modPath := strings.TrimSuffix(bi.Main.Path, "/")
+	if modPath == "" {
+		return ""
+	}
+	name := path.Base(modPath)
+	if majorVersionSuffix.MatchString(name) && path.Dir(modPath) != "." {
+		name = path.Base(path.Dir(modPath))
+	}
+	return name
+}
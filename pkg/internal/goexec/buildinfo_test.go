@@ -0,0 +1,64 @@
+package goexec
+
+import (
+	"debug/elf"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+)
+
+func TestBuildInfoMetadata(t *testing.T) {
+	assert.Equal(t, map[attr.Name]string{
+		attr.GoModulePath:   "github.com/acme/checkout/v2",
+		attr.ServiceVersion: "v2.3.1",
+		attr.VCSRevision:    "0123456789abcdef",
+	}, BuildInfoMetadata(&debug.BuildInfo{
+		Main: debug.Module{Path: "github.com/acme/checkout/v2", Version: "v2.3.1"},
+		Settings: []debug.BuildSetting{
+			{Key: "-trimpath", Value: "true"},
+			{Key: "vcs.revision", Value: "0123456789abcdef"},
+		},
+	}))
+
+	// local builds don't have version
+	assert.Equal(t, map[attr.Name]string{attr.GoModulePath: "example.com/foo"},
+		BuildInfoMetadata(&debug.BuildInfo{Main: debug.Module{Path: "example.com/foo", Version: "(devel)"}}))
+
+	assert.Nil(t, BuildInfoMetadata(nil))
+}
+
+func TestModuleServiceName(t *testing.T) {
+	name := func(path string) string {
+		return ModuleServiceName(&debug.BuildInfo{Main: debug.Module{Path: path}})
+	}
+	assert.Equal(t, "checkout", name("github.com/acme/checkout"))
+	assert.Equal(t, "checkout", name("github.com/acme/checkout/v2"))
+	assert.Equal(t, "v2", name("v2"))
+	assert.Equal(t, "server", name("server"))
+	assert.Empty(t, name(""))
+	assert.Empty(t, ModuleServiceName(nil))
+}
+
+func TestFindBuildInfo(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	elfFile, err := elf.Open(exe)
+	require.NoError(t, err)
+	defer elfFile.Close()
+
+	bi, err := findBuildInfo(elfFile)
+	require.NoError(t, err)
+
+	// compare with the build information of the running test executable
+	own, ok := debug.ReadBuildInfo()
+	require.True(t, ok)
+	assert.Equal(t, runtime.Version(), bi.GoVersion)
+	assert.Equal(t, own.Main.Path, bi.Main.Path)
+	assert.Equal(t, own.Path, bi.Path)
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"runtime/debug"
 	"strings"
 
 	"golang.org/x/mod/semver"
@@ -46,6 +47,21 @@ func findLibraryVersions(elfFile *elf.File) (map[string]string, error) {
 	return modsMap, nil
 }
 
+// findBuildInfo returns the build information that is embedded in the Go executable:
+// main module, dependencies and build settings (e.g. VCS revision)
+func findBuildInfo(elfFile *elf.File) (*debug.BuildInfo, error) {
+	goVersion, modules, err := getGoDetails(elfFile)
+	if err != nil {
+		return nil, fmt.Errorf("getting Go details: %w", err)
+	}
+	bi, err := debug.ParseBuildInfo(modules)
+	if err != nil {
+		return nil, fmt.Errorf("parsing build info: %w", err)
+	}
+	bi.GoVersion = goVersion
+	return bi, nil
+}
+
 // The build info blob left by the linker is identified by
 // a 16-byte header, consisting of buildInfoMagic (14 bytes),
 // the binary's pointer size (1 byte),
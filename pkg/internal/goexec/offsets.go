@@ -3,6 +3,7 @@ package goexec
 
 import (
 	"fmt"
+	"runtime/debug"
 
 	"github.com/grafana/beyla/pkg/internal/exec"
 )
@@ -11,6 +12,8 @@ type Offsets struct {
 	// Funcs key: function name
 	Funcs map[string]FuncOffsets
 	Field FieldOffsets
+	// BuildInfo embedded in the executable. It might be nil if it can't be read.
+	BuildInfo *debug.BuildInfo
 }
 
 type FuncOffsets struct {
@@ -42,8 +45,14 @@ func InspectOffsets(execElf *exec.FileInfo, funcs []string) (*Offsets, error) {
 		return nil, fmt.Errorf("checking struct members in file %s: %w", execElf.ProExeLinkPath, err)
 	}
 
+	buildInfo, err := findBuildInfo(execElf.ELF)
+	if err != nil {
+		log().Debug("can't read Go build info", "file", execElf.CmdExePath, "error", err)
+	}
+
 	return &Offsets{
-		Funcs: found,
-		Field: structFieldOffsets,
+		Funcs:     found,
+		Field:     structFieldOffsets,
+		BuildInfo: buildInfo,
 	}, nil
 }
@@ -26,6 +26,16 @@ type Reporter interface {
 	OTELTraceExport(i int)
 	// OTELTraceExportError is invoked every time the OpenTelemetry Traces export fails with an error
 	OTELTraceExportError(err error)
+	// OTELExportQueueDepth is invoked every time the number of records in the OTEL exporters' disk queue
+	// changes, for a given signal (traces, metrics...)
+	OTELExportQueueDepth(signal string, depth int)
+	// OTELExportQueueDroppedBytes is invoked every time data is discarded from the OTEL exporters' disk queue,
+	// because it exceeded its size or age limits
+	OTELExportQueueDroppedBytes(signal string, bytes int)
+	// MetricSeriesOverflow is invoked every time that a new label set of a metric is folded into the overflow
+	// series, because the service reached the cardinality limit of the metric. The label argument is the
+	// metric label with the highest number of distinct values for the service.
+	MetricSeriesOverflow(metric, service, label string)
 	// PrometheusRequest is invoked every time the Prometheus exporter is invoked, for a given port and path
 	PrometheusRequest(port, path string)
 	// InstrumentProcess is invoked every time a new process is instrumented
@@ -46,3 +56,7 @@ func (n NoopReporter) OTELTraceExportError(_ error)  {}
 func (n NoopReporter) PrometheusRequest(_, _ string) {}
 func (n NoopReporter) InstrumentProcess(_ string)    {}
 func (n NoopReporter) UninstrumentProcess(_ string)  {}
+
+func (n NoopReporter) OTELExportQueueDepth(_ string, _ int)        {}
+func (n NoopReporter) OTELExportQueueDroppedBytes(_ string, _ int) {}
+func (n NoopReporter) MetricSeriesOverflow(_, _, _ string)         {}
@@ -29,6 +29,9 @@ type PrometheusReporter struct {
 	otelMetricExportErrs  *prometheus.CounterVec
 	otelTraceExports      prometheus.Counter
 	otelTraceExportErrs   *prometheus.CounterVec
+	otelExportQueueDepth  *prometheus.GaugeVec
+	otelExportQueueDrops  *prometheus.CounterVec
+	metricSeriesOverflows *prometheus.CounterVec
 	prometheusRequests    *prometheus.CounterVec
 	instrumentedProcesses *prometheus.GaugeVec
 	beylaInfo             prometheus.Gauge
@@ -61,6 +64,18 @@ func NewPrometheusReporter(cfg *PrometheusConfig, manager *connector.PrometheusM
 			Name: "beyla_otel_trace_export_errors_total",
 			Help: "Error count on each failed OTEL trace export",
 		}, []string{"error"}),
+		otelExportQueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "beyla_otel_export_queue_depth",
+			Help: "Number of records waiting in the disk queue to be exported to the remote OTEL collector",
+		}, []string{"signal"}),
+		otelExportQueueDrops: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "beyla_otel_export_queue_dropped_bytes_total",
+			Help: "Bytes discarded from the disk queue because of exceeding its size or age limits",
+		}, []string{"signal"}),
+		metricSeriesOverflows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "beyla_metric_series_overflows_total",
+			Help: "Label sets folded into the overflow series of a metric, because a service exceeded its cardinality limit",
+		}, []string{"metric", "service", "label"}),
 		prometheusRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Name: "beyla_prometheus_http_requests_total",
 			Help: "Requests towards the Prometheus Scrape endpoint",
@@ -88,6 +103,9 @@ func NewPrometheusReporter(cfg *PrometheusConfig, manager *connector.PrometheusM
 			pr.otelMetricExportErrs,
 			pr.otelTraceExports,
 			pr.otelTraceExportErrs,
+			pr.otelExportQueueDepth,
+			pr.otelExportQueueDrops,
+			pr.metricSeriesOverflows,
 			pr.prometheusRequests,
 			pr.instrumentedProcesses)
 		// Using the registry here means that the metrics will be registered with the global prometheus registry
@@ -100,6 +118,9 @@ func NewPrometheusReporter(cfg *PrometheusConfig, manager *connector.PrometheusM
 			pr.otelMetricExportErrs,
 			pr.otelTraceExports,
 			pr.otelTraceExportErrs,
+			pr.otelExportQueueDepth,
+			pr.otelExportQueueDrops,
+			pr.metricSeriesOverflows,
 			pr.prometheusRequests,
 			pr.instrumentedProcesses,
 			pr.beylaInfo)
@@ -137,6 +158,18 @@ func (p *PrometheusReporter) OTELTraceExportError(err error) {
 	p.otelTraceExportErrs.WithLabelValues(err.Error()).Inc()
 }
 
+func (p *PrometheusReporter) OTELExportQueueDepth(signal string, depth int) {
+	p.otelExportQueueDepth.WithLabelValues(signal).Set(float64(depth))
+}
+
+func (p *PrometheusReporter) OTELExportQueueDroppedBytes(signal string, bytes int) {
+	p.otelExportQueueDrops.WithLabelValues(signal).Add(float64(bytes))
+}
+
+func (p *PrometheusReporter) MetricSeriesOverflow(metric, service, label string) {
+	p.metricSeriesOverflows.WithLabelValues(metric, service, label).Inc()
+}
+
 func (p *PrometheusReporter) PrometheusRequest(port, path string) {
 	p.prometheusRequests.WithLabelValues(port, path).Inc()
 }
@@ -0,0 +1,146 @@
+package goruntime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/mariomac/pipes/pipe"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+type CollectConfig struct {
+	// Interval between collections
+	Interval time.Duration
+	// PinPath of the eBPF maps of the Go runtime tracer
+	PinPath string
+}
+
+// statsReader returns the runtime Stats of a process. It returns false if there
+// are no stats for the process (e.g. it is not a Go process, or it has exited).
+type statsReader interface {
+	read(pid uint32) (Stats, bool, error)
+}
+
+// Collector periodically reads the runtime statistics of the instrumented Go processes.
+// The collector receives each application trace from the newPids internal channel,
+// to know which PIDs are active and which service they belong to.
+type Collector struct {
+	newPids *<-chan []request.Span
+	ctx     context.Context
+	cfg     *CollectConfig
+	reader  statsReader
+	log     *slog.Logger
+
+	// last read stats of each process, to calculate the deltas
+	last map[uint32]Stats
+}
+
+// NewCollectorProvider creates and returns a new Go runtime Collector
+func NewCollectorProvider(ctx context.Context, input *<-chan []request.Span, cfg *CollectConfig) pipe.StartProvider[[]*Status] {
+	return func() (pipe.StartFunc[[]*Status], error) {
+		return newCollector(ctx, input, cfg, &pinnedMapReader{path: path.Join(cfg.PinPath, StatsMapName)}).Run, nil
+	}
+}
+
+func newCollector(ctx context.Context, input *<-chan []request.Span, cfg *CollectConfig, reader statsReader) *Collector {
+	return &Collector{
+		ctx:     ctx,
+		cfg:     cfg,
+		reader:  reader,
+		log:     glog(),
+		newPids: input,
+		last:    map[uint32]Stats{},
+	}
+}
+
+func (c *Collector) Run(out chan<- []*Status) {
+	pids := map[uint32]*svc.ID{}
+	collectTicker := time.NewTicker(c.cfg.Interval)
+	defer collectTicker.Stop()
+	newPids := *c.newPids
+	for {
+		select {
+		case <-c.ctx.Done():
+			c.log.Debug("exiting")
+			return
+		case spans := <-newPids:
+			// updating PIDs map with spans information
+			for i := range spans {
+				pids[spans[i].Pid.HostPID] = &spans[i].ServiceID
+			}
+		case <-collectTicker.C:
+			if statuses := c.Collect(pids); len(statuses) > 0 {
+				out <- statuses
+			}
+		}
+	}
+}
+
+// Collect returns the Go runtime status of each service. The processes without runtime
+// statistics are removed from the pids map.
+func (c *Collector) Collect(pids map[uint32]*svc.ID) []*Status {
+	services := map[svc.UID]*Status{}
+	var results []*Status
+	for pid, svcID := range pids {
+		stats, ok, err := c.reader.read(pid)
+		if err != nil {
+			c.log.Debug("can't read Go runtime stats", "pid", pid, "error", err)
+			continue
+		}
+		if !ok {
+			delete(pids, pid)
+			delete(c.last, pid)
+			continue
+		}
+		prev := c.last[pid]
+		c.last[pid] = stats
+
+		status, ok := services[svcID.UID]
+		if !ok {
+			status = &Status{Service: svcID}
+			services[svcID.UID] = status
+			results = append(results, status)
+		}
+		status.GoroutinesCreatedDelta += stats.GoroutinesCreated - prev.GoroutinesCreated
+		status.GoroutinesExitedDelta += stats.GoroutinesExited - prev.GoroutinesExited
+		status.GCCyclesDelta += stats.GCCycles - prev.GCCycles
+		pauses := stats.STWPauses.sub(&prev.STWPauses)
+		status.STWPausesDelta.Add(&pauses)
+		latency := stats.SchedLatency.sub(&prev.SchedLatency)
+		status.SchedLatencyDelta.Add(&latency)
+	}
+	return results
+}
+
+// pinnedMapReader reads the stats from the map that is pinned by the Go runtime tracer.
+// The map is opened lazily, as it does not exist until the first Go process is instrumented.
+type pinnedMapReader struct {
+	path  string
+	stats *ebpf.Map
+}
+
+func (r *pinnedMapReader) read(pid uint32) (Stats, bool, error) {
+	if r.stats == nil {
+		m, err := ebpf.LoadPinnedMap(r.path, nil)
+		if err != nil {
+			return Stats{}, false, fmt.Errorf("opening pinned map %s: %w", r.path, err)
+		}
+		r.stats = m
+	}
+	raw := make([]byte, StatsSize)
+	if err := r.stats.Lookup(pid, raw); err != nil {
+		if errors.Is(err, ebpf.ErrKeyNotExist) {
+			return Stats{}, false, nil
+		}
+		return Stats{}, false, err
+	}
+	stats, ok := DecodeStats(raw)
+	return stats, ok, nil
+}
@@ -0,0 +1,113 @@
+package goruntime
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func TestDecodeStats(t *testing.T) {
+	raw := make([]byte, StatsSize)
+	ne := binary.NativeEndian
+	ne.PutUint64(raw[OffGoroutinesCreated:], 30)
+	ne.PutUint64(raw[OffGoroutinesExited:], 12)
+	ne.PutUint64(raw[OffGCCycles:], 4)
+	ne.PutUint64(raw[OffSTWPauses:], 1)
+	ne.PutUint64(raw[OffSTWPauses+8*(NumBuckets-1):], 2)
+	ne.PutUint64(raw[OffSTWPauses+HistogramSumOff:], 300_005_000)
+	ne.PutUint64(raw[OffSchedLatency+8:], 3)
+	ne.PutUint64(raw[OffSchedLatency+HistogramSumOff:], 90_000)
+
+	stats, ok := DecodeStats(raw)
+	require.True(t, ok)
+	assert.Equal(t, Stats{
+		GoroutinesCreated: 30,
+		GoroutinesExited:  12,
+		GCCycles:          4,
+		STWPauses: Histogram{
+			Counts: [NumBuckets]uint64{1, 0, 0, 0, 0, 0, 0, 0, 0, 2},
+			SumNs:  300_005_000,
+		},
+		SchedLatency: Histogram{
+			Counts: [NumBuckets]uint64{0, 3},
+			SumNs:  90_000,
+		},
+	}, stats)
+	assert.EqualValues(t, 3, stats.STWPauses.Count())
+
+	_, ok = DecodeStats(raw[:StatsSize-1])
+	assert.False(t, ok)
+}
+
+func TestBucketBounds(t *testing.T) {
+	assert.Equal(t,
+		[]float64{0.00001, 0.00005, 0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1},
+		BucketBounds())
+}
+
+type fakeReader map[uint32]Stats
+
+func (f fakeReader) read(pid uint32) (Stats, bool, error) {
+	s, ok := f[pid]
+	return s, ok, nil
+}
+
+func TestCollect(t *testing.T) {
+	reader := fakeReader{
+		1: {GoroutinesCreated: 10, GoroutinesExited: 4, GCCycles: 3,
+			STWPauses:    Histogram{Counts: [NumBuckets]uint64{2, 1}, SumNs: 40_000},
+			SchedLatency: Histogram{Counts: [NumBuckets]uint64{5}, SumNs: 20_000}},
+		2: {GoroutinesCreated: 5, GoroutinesExited: 1, GCCycles: 1,
+			STWPauses: Histogram{Counts: [NumBuckets]uint64{1}, SumNs: 8_000}},
+		// goroutines that were created before the instrumentation exited afterwards
+		3: {GoroutinesCreated: 1, GoroutinesExited: 6},
+	}
+	collector := newCollector(context.Background(), nil, &CollectConfig{Interval: time.Second}, reader)
+
+	svcA := &svc.ID{UID: "a", Name: "a"}
+	svcB := &svc.ID{UID: "b", Name: "b"}
+	pids := map[uint32]*svc.ID{1: svcA, 2: svcA, 3: svcB, 4: svcB}
+
+	// WHEN collecting the stats for the first time
+	statuses := statusesByService(collector.Collect(pids))
+
+	// THEN the stats of the processes of the same service are aggregated
+	require.Len(t, statuses, 2)
+	assert.Equal(t, &Status{
+		Service: svcA, GoroutinesCreatedDelta: 15, GoroutinesExitedDelta: 5, GCCyclesDelta: 4,
+		STWPausesDelta:    Histogram{Counts: [NumBuckets]uint64{3, 1}, SumNs: 48_000},
+		SchedLatencyDelta: Histogram{Counts: [NumBuckets]uint64{5}, SumNs: 20_000},
+	}, statuses["a"])
+	assert.Equal(t, &Status{Service: svcB, GoroutinesCreatedDelta: 1, GoroutinesExitedDelta: 6}, statuses["b"])
+	// AND the processes without runtime stats are forgotten
+	assert.NotContains(t, pids, uint32(4))
+
+	// WHEN the stats are updated and a process exits
+	reader[1] = Stats{GoroutinesCreated: 12, GoroutinesExited: 9, GCCycles: 5,
+		STWPauses:    Histogram{Counts: [NumBuckets]uint64{2, 3}, SumNs: 100_000},
+		SchedLatency: Histogram{Counts: [NumBuckets]uint64{6}, SumNs: 21_000}}
+	delete(reader, 2)
+	statuses = statusesByService(collector.Collect(pids))
+
+	// THEN only the deltas since the last collection are reported
+	assert.Equal(t, &Status{
+		Service: svcA, GoroutinesCreatedDelta: 2, GoroutinesExitedDelta: 5, GCCyclesDelta: 2,
+		STWPausesDelta:    Histogram{Counts: [NumBuckets]uint64{0, 2}, SumNs: 60_000},
+		SchedLatencyDelta: Histogram{Counts: [NumBuckets]uint64{1}, SumNs: 1_000},
+	}, statuses["a"])
+	assert.NotContains(t, pids, uint32(2))
+}
+
+func statusesByService(statuses []*Status) map[svc.UID]*Status {
+	m := map[svc.UID]*Status{}
+	for _, s := range statuses {
+		m[s.Service.UID] = s
+	}
+	return m
+}
@@ -0,0 +1,120 @@
+// Package goruntime collects the runtime statistics (goroutines, garbage collection, stop-the-world
+// pauses and scheduler latency) of the instrumented Go processes, as they are accounted by the eBPF probes of the
+// Go runtime tracer, and forwards them to the metrics exporters.
+package goruntime
+
+import (
+	"encoding/binary"
+)
+
+// Layout of the eBPF maps that are shared between the Go runtime tracer, which writes
+// the statistics of each process, and the Collector, which periodically reads them.
+// The stats layout mirrors the go_rt_stats_t struct in bpf/go_runtime_metrics.c.
+const (
+	// StatsMapName is the name of the pinned map that stores the Stats of each Go process, indexed by PID
+	StatsMapName = "go_rt_stats"
+	// StartsMapName is the name of the map that stores the start time of the stop-the-world
+	// pauses and the goroutines that are waiting in the run queue
+	StartsMapName = "go_rt_starts"
+
+	// NumBuckets of the latency histograms, including the +Inf bucket
+	NumBuckets = 10
+
+	// each histogram contains the count of each bucket followed by the sum of all the observations
+	histogramSize = (NumBuckets + 1) * 8
+
+	OffGoroutinesCreated = 0
+	OffGoroutinesExited  = 8
+	OffGCCycles          = 16
+	OffSTWPauses         = 24
+	OffSchedLatency      = OffSTWPauses + histogramSize
+	StatsSize            = OffSchedLatency + histogramSize
+
+	// HistogramSumOff is the offset of the sum of the observations, from the start of a histogram
+	HistogramSumOff = NumBuckets * 8
+)
+
+// BucketBoundsNs are the upper bounds of the latency histograms, in nanoseconds.
+// The histograms have an extra +Inf bucket for the observations above the last bound.
+var BucketBoundsNs = [NumBuckets - 1]int32{
+	10_000, 50_000, 100_000, 500_000,
+	1_000_000, 5_000_000, 10_000_000, 50_000_000, 100_000_000,
+}
+
+// BucketBounds returns the upper bounds of the latency histograms in seconds, as
+// reported by the metrics exporters
+func BucketBounds() []float64 {
+	bounds := make([]float64, 0, len(BucketBoundsNs))
+	for _, b := range BucketBoundsNs {
+		bounds = append(bounds, float64(b)/1e9)
+	}
+	return bounds
+}
+
+// Stats of a Go process, as accounted by the eBPF probes since the process was instrumented.
+type Stats struct {
+	GoroutinesCreated uint64
+	GoroutinesExited  uint64
+	GCCycles          uint64
+	// STWPauses accounts the duration of the stop-the-world pauses
+	STWPauses Histogram
+	// SchedLatency accounts the time that the goroutines wait in the scheduler run queues
+	SchedLatency Histogram
+}
+
+// Histogram of latencies
+type Histogram struct {
+	// Counts of observations in each bucket. They are not cumulative: each observation is
+	// only accounted in the first bucket whose upper bound is higher or equal to it.
+	Counts [NumBuckets]uint64
+	// SumNs of all the observations, in nanoseconds
+	SumNs uint64
+}
+
+// Count of all the observations in the histogram
+func (h *Histogram) Count() uint64 {
+	c := uint64(0)
+	for _, b := range h.Counts {
+		c += b
+	}
+	return c
+}
+
+// Add the observations of another histogram
+func (h *Histogram) Add(o *Histogram) {
+	for i := range h.Counts {
+		h.Counts[i] += o.Counts[i]
+	}
+	h.SumNs += o.SumNs
+}
+
+func (h *Histogram) sub(o *Histogram) Histogram {
+	d := Histogram{SumNs: h.SumNs - o.SumNs}
+	for i := range h.Counts {
+		d.Counts[i] = h.Counts[i] - o.Counts[i]
+	}
+	return d
+}
+
+// DecodeStats from the raw value of the StatsMapName map
+func DecodeStats(raw []byte) (Stats, bool) {
+	if len(raw) < StatsSize {
+		return Stats{}, false
+	}
+	ne := binary.NativeEndian
+	return Stats{
+		GoroutinesCreated: ne.Uint64(raw[OffGoroutinesCreated:]),
+		GoroutinesExited:  ne.Uint64(raw[OffGoroutinesExited:]),
+		GCCycles:          ne.Uint64(raw[OffGCCycles:]),
+		STWPauses:         decodeHistogram(raw[OffSTWPauses:]),
+		SchedLatency:      decodeHistogram(raw[OffSchedLatency:]),
+	}, true
+}
+
+func decodeHistogram(raw []byte) Histogram {
+	h := Histogram{SumNs: binary.NativeEndian.Uint64(raw[HistogramSumOff:])}
+	for i := range h.Counts {
+		h.Counts[i] = binary.NativeEndian.Uint64(raw[i*8:])
+	}
+	return h
+}
@@ -0,0 +1,51 @@
+package goruntime
+
+import (
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/grafana/beyla/pkg/export/attributes"
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func glog() *slog.Logger {
+	return slog.With("component", "goruntime.Collector")
+}
+
+// Status of the Go runtime of a service since the last collection
+type Status struct {
+	Service *svc.ID
+
+	// Despite the eBPF values are absolute counters, the OTEL and Prometheus APIs require that
+	// they are specified as deltas.
+	// The goroutines are only accounted since the process was instrumented, so the difference
+	// between the created and exited goroutines is not the number of live goroutines.
+
+	GoroutinesCreatedDelta uint64
+	GoroutinesExitedDelta  uint64
+	GCCyclesDelta          uint64
+	STWPausesDelta         Histogram
+	SchedLatencyDelta      Histogram
+}
+
+func PromGetters(name attr.Name) (attributes.Getter[*Status, string], bool) {
+	var g attributes.Getter[*Status, string]
+	switch name {
+	case attr.ServiceName:
+		g = func(s *Status) string { return s.Service.Name }
+	case attr.ServiceNamespace:
+		g = func(s *Status) string { return s.Service.Namespace }
+	default:
+		g = func(s *Status) string { return s.Service.Metadata[name] }
+	}
+	return g, g != nil
+}
+
+func OTELGetters(name attr.Name) (attributes.Getter[*Status, attribute.KeyValue], bool) {
+	if g, ok := PromGetters(name); ok {
+		return func(s *Status) attribute.KeyValue { return name.OTEL().String(g(s)) }, true
+	}
+	return nil, false
+}
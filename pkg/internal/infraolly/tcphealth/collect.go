@@ -0,0 +1,188 @@
+package tcphealth
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/mariomac/pipes/pipe"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+type CollectConfig struct {
+	// Interval between collections
+	Interval time.Duration
+	// PinPath of the eBPF maps of the TCP health tracer
+	PinPath string
+}
+
+// statsReader returns the Stats of all the connection groups that are accounted in the
+// eBPF map, and allows removing the groups of the processes that have exited.
+type statsReader interface {
+	readAll() (map[Key]Stats, error)
+	remove(k *Key) error
+}
+
+// Collector periodically reads the TCP health statistics of the instrumented processes.
+// The collector receives each application trace from the newPids internal channel,
+// to know which PIDs are active and which service they belong to.
+type Collector struct {
+	newPids *<-chan []request.Span
+	ctx     context.Context
+	cfg     *CollectConfig
+	reader  statsReader
+	log     *slog.Logger
+
+	processExists func(pid uint32) bool
+
+	// last read stats of each connection group, to calculate the deltas
+	last map[Key]Stats
+}
+
+// NewCollectorProvider creates and returns a new TCP health Collector
+func NewCollectorProvider(ctx context.Context, input *<-chan []request.Span, cfg *CollectConfig) pipe.StartProvider[[]*Status] {
+	return func() (pipe.StartFunc[[]*Status], error) {
+		return newCollector(ctx, input, cfg, &pinnedMapReader{path: path.Join(cfg.PinPath, StatsMapName)}).Run, nil
+	}
+}
+
+func newCollector(ctx context.Context, input *<-chan []request.Span, cfg *CollectConfig, reader statsReader) *Collector {
+	return &Collector{
+		ctx:           ctx,
+		cfg:           cfg,
+		reader:        reader,
+		log:           tlog(),
+		newPids:       input,
+		processExists: procExists,
+		last:          map[Key]Stats{},
+	}
+}
+
+func (c *Collector) Run(out chan<- []*Status) {
+	pids := map[uint32]*svc.ID{}
+	collectTicker := time.NewTicker(c.cfg.Interval)
+	defer collectTicker.Stop()
+	newPids := *c.newPids
+	for {
+		select {
+		case <-c.ctx.Done():
+			c.log.Debug("exiting")
+			return
+		case spans := <-newPids:
+			// updating PIDs map with spans information
+			for i := range spans {
+				pids[spans[i].Pid.HostPID] = &spans[i].ServiceID
+			}
+		case <-collectTicker.C:
+			if statuses := c.Collect(pids); len(statuses) > 0 {
+				out <- statuses
+			}
+		}
+	}
+}
+
+type statusKey struct {
+	service svc.UID
+	role    Role
+	port    uint16
+	addr    [16]byte
+}
+
+// Collect returns the TCP health status of each service and peer. The connection groups
+// of the processes that have exited are removed from the eBPF map, and their PIDs
+// are removed from the pids map.
+func (c *Collector) Collect(pids map[uint32]*svc.ID) []*Status {
+	all, err := c.reader.readAll()
+	if err != nil {
+		c.log.Debug("can't read TCP health stats", "error", err)
+		return nil
+	}
+	for pid := range pids {
+		if !c.processExists(pid) {
+			delete(pids, pid)
+		}
+	}
+	for k := range c.last {
+		if _, ok := all[k]; !ok {
+			delete(c.last, k)
+		}
+	}
+	statuses := map[statusKey]*Status{}
+	var results []*Status
+	for k, stats := range all {
+		svcID, ok := pids[k.PID]
+		if !ok {
+			// connections of processes that are not instrumented anymore, or that haven't
+			// reported any span yet
+			if !c.processExists(k.PID) {
+				if err := c.reader.remove(&k); err != nil {
+					c.log.Debug("can't remove TCP health stats", "pid", k.PID, "error", err)
+				}
+				delete(c.last, k)
+			}
+			continue
+		}
+		prev := c.last[k]
+		c.last[k] = stats
+		delta := stats.sub(&prev)
+
+		sk := statusKey{service: svcID.UID, role: k.Role, port: k.Port, addr: k.Addr}
+		status, ok := statuses[sk]
+		if !ok {
+			status = &Status{Service: svcID, Role: k.Role, PeerAddress: k.PeerAddress(), ServerPort: k.Port}
+			statuses[sk] = status
+			results = append(results, status)
+		}
+		status.add(&delta)
+	}
+	return results
+}
+
+func procExists(pid uint32) bool {
+	_, err := os.Stat("/proc/" + strconv.Itoa(int(pid)))
+	return err == nil
+}
+
+// pinnedMapReader reads the stats from the map that is pinned by the TCP health tracer.
+// The map is opened lazily, as the tracer might not have been loaded yet.
+type pinnedMapReader struct {
+	path  string
+	stats *ebpf.Map
+}
+
+func (r *pinnedMapReader) readAll() (map[Key]Stats, error) {
+	if r.stats == nil {
+		m, err := ebpf.LoadPinnedMap(r.path, nil)
+		if err != nil {
+			return nil, fmt.Errorf("opening pinned map %s: %w", r.path, err)
+		}
+		r.stats = m
+	}
+	all := map[Key]Stats{}
+	rawKey, rawStats := make([]byte, KeySize), make([]byte, StatsSize)
+	entries := r.stats.Iterate()
+	for entries.Next(&rawKey, &rawStats) {
+		key, ok := DecodeKey(rawKey)
+		if !ok {
+			continue
+		}
+		if stats, ok := DecodeStats(rawStats); ok {
+			all[key] = stats
+		}
+	}
+	if err := entries.Err(); err != nil {
+		return nil, fmt.Errorf("iterating TCP health stats: %w", err)
+	}
+	return all, nil
+}
+
+func (r *pinnedMapReader) remove(k *Key) error {
+	return r.stats.Delete(k.Encode())
+}
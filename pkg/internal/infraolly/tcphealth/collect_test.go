@@ -0,0 +1,155 @@
+package tcphealth
+
+import (
+	"context"
+	"encoding/binary"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func TestDecodeKey(t *testing.T) {
+	key := Key{PID: 123, Port: 8080, Role: RoleServer, Addr: netip.MustParseAddr("10.0.0.3").As16()}
+	decoded, ok := DecodeKey(key.Encode())
+	require.True(t, ok)
+	assert.Equal(t, key, decoded)
+	assert.Equal(t, "10.0.0.3", decoded.PeerAddress())
+
+	key.Addr = netip.MustParseAddr("fe80::1").As16()
+	assert.Equal(t, "fe80::1", key.PeerAddress())
+
+	_, ok = DecodeKey(make([]byte, KeySize-1))
+	assert.False(t, ok)
+}
+
+func TestDecodeStats(t *testing.T) {
+	raw := make([]byte, StatsSize)
+	ne := binary.NativeEndian
+	ne.PutUint64(raw[OffRetransmits:], 7)
+	ne.PutUint64(raw[OffResetsReceived:], 2)
+	ne.PutUint64(raw[OffResetsSent:], 1)
+	ne.PutUint64(raw[OffFailuresRefused:], 3)
+	ne.PutUint64(raw[OffFailuresTimeout:], 4)
+	ne.PutUint64(raw[OffSetupDuration:], 5)
+	ne.PutUint64(raw[OffSetupDuration+HistogramSumOff:], 250_000)
+	ne.PutUint64(raw[OffRTT+8*(NumBuckets-1):], 1)
+	ne.PutUint64(raw[OffRTT+HistogramSumOff:], 2_000_000_000)
+
+	stats, ok := DecodeStats(raw)
+	require.True(t, ok)
+	assert.Equal(t, Stats{
+		Retransmits:     7,
+		ResetsReceived:  2,
+		ResetsSent:      1,
+		FailuresRefused: 3,
+		FailuresTimeout: 4,
+		SetupDuration:   Histogram{Counts: [NumBuckets]uint64{5}, SumNs: 250_000},
+		RTT: Histogram{
+			Counts: [NumBuckets]uint64{0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+			SumNs:  2_000_000_000,
+		},
+	}, stats)
+
+	_, ok = DecodeStats(raw[:StatsSize-1])
+	assert.False(t, ok)
+}
+
+func TestBucketBounds(t *testing.T) {
+	assert.Equal(t,
+		[]float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1},
+		BucketBounds())
+}
+
+type fakeReader map[Key]Stats
+
+func (f fakeReader) readAll() (map[Key]Stats, error) {
+	all := make(map[Key]Stats, len(f))
+	for k, v := range f {
+		all[k] = v
+	}
+	return all, nil
+}
+
+func (f fakeReader) remove(k *Key) error {
+	delete(f, *k)
+	return nil
+}
+
+func TestCollect(t *testing.T) {
+	db := netip.MustParseAddr("10.0.0.5").As16()
+	client := netip.MustParseAddr("10.0.0.9").As16()
+	toDBFromPid1 := Key{PID: 1, Port: 5432, Role: RoleClient, Addr: db}
+	toDBFromPid2 := Key{PID: 2, Port: 5432, Role: RoleClient, Addr: db}
+	fromClient := Key{PID: 2, Port: 8080, Role: RoleServer, Addr: client}
+	uninstrumented := Key{PID: 3, Port: 80, Role: RoleClient, Addr: db}
+	exited := Key{PID: 4, Port: 80, Role: RoleClient, Addr: db}
+	reader := fakeReader{
+		toDBFromPid1: {Retransmits: 2, FailuresRefused: 1,
+			SetupDuration: Histogram{Counts: [NumBuckets]uint64{1}, SumNs: 50_000},
+			RTT:           Histogram{Counts: [NumBuckets]uint64{0, 3}, SumNs: 900_000}},
+		toDBFromPid2: {Retransmits: 1, FailuresTimeout: 1,
+			SetupDuration: Histogram{Counts: [NumBuckets]uint64{2}, SumNs: 60_000}},
+		fromClient:     {ResetsSent: 1, RTT: Histogram{Counts: [NumBuckets]uint64{1}, SumNs: 80_000}},
+		uninstrumented: {Retransmits: 10},
+		exited:         {Retransmits: 20},
+	}
+	collector := newCollector(context.Background(), nil, &CollectConfig{Interval: time.Second}, reader)
+	collector.processExists = func(pid uint32) bool { return pid != 4 }
+
+	svcA := &svc.ID{UID: "a", Name: "a"}
+	pids := map[uint32]*svc.ID{1: svcA, 2: svcA}
+
+	// WHEN collecting the stats for the first time
+	statuses := statusesByPeer(collector.Collect(pids))
+
+	// THEN the stats of the processes of the same service and peer are aggregated
+	require.Len(t, statuses, 2)
+	assert.Equal(t, &Status{
+		Service: svcA, Role: RoleClient, PeerAddress: "10.0.0.5", ServerPort: 5432,
+		RetransmitsDelta: 3, FailuresRefusedDelta: 1, FailuresTimeoutDelta: 1,
+		SetupDurationDelta: Histogram{Counts: [NumBuckets]uint64{3}, SumNs: 110_000},
+		RTTDelta:           Histogram{Counts: [NumBuckets]uint64{0, 3}, SumNs: 900_000},
+	}, statuses["client/10.0.0.5"])
+	assert.Equal(t, &Status{
+		Service: svcA, Role: RoleServer, PeerAddress: "10.0.0.9", ServerPort: 8080,
+		ResetsSentDelta: 1,
+		RTTDelta:        Histogram{Counts: [NumBuckets]uint64{1}, SumNs: 80_000},
+	}, statuses["server/10.0.0.9"])
+	// AND the stats of the processes that have exited are removed
+	assert.NotContains(t, reader, exited)
+	assert.Contains(t, reader, uninstrumented)
+
+	// WHEN the stats are updated and a process exits
+	reader[toDBFromPid1] = Stats{Retransmits: 5, FailuresRefused: 1,
+		SetupDuration: Histogram{Counts: [NumBuckets]uint64{1, 1}, SumNs: 1_050_000},
+		RTT:           Histogram{Counts: [NumBuckets]uint64{0, 4}, SumNs: 1_200_000}}
+	delete(reader, toDBFromPid2)
+	delete(reader, fromClient)
+	collector.processExists = func(pid uint32) bool { return pid == 1 || pid == 3 }
+	statuses = statusesByPeer(collector.Collect(pids))
+
+	// THEN only the deltas since the last collection are reported
+	require.Len(t, statuses, 1)
+	assert.Equal(t, &Status{
+		Service: svcA, Role: RoleClient, PeerAddress: "10.0.0.5", ServerPort: 5432,
+		RetransmitsDelta:   3,
+		SetupDurationDelta: Histogram{Counts: [NumBuckets]uint64{0, 1}, SumNs: 1_000_000},
+		RTTDelta:           Histogram{Counts: [NumBuckets]uint64{0, 1}, SumNs: 300_000},
+	}, statuses["client/10.0.0.5"])
+	// AND the exited processes are forgotten
+	assert.NotContains(t, pids, uint32(2))
+	assert.NotContains(t, collector.last, toDBFromPid2)
+}
+
+func statusesByPeer(statuses []*Status) map[string]*Status {
+	m := map[string]*Status{}
+	for _, s := range statuses {
+		m[s.Role.String()+"/"+s.PeerAddress] = s
+	}
+	return m
+}
@@ -0,0 +1,202 @@
+// Package tcphealth collects the health statistics of the TCP connections of the instrumented
+// processes (round-trip time, retransmissions, resets and connection establishment), as they are
+// accounted by the eBPF probes of the TCP health tracer, and forwards them to the metrics exporters.
+package tcphealth
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+// Layout of the eBPF map that is shared between the TCP health tracer, which writes
+// the statistics of each connection group, and the Collector, which periodically reads them.
+const (
+	// StatsMapName is the name of the pinned map that stores the Stats of each connection group, indexed by Key
+	StatsMapName = "tcp_health_stats"
+
+	// KeySize of the StatsMapName map: {u32 pid, u16 server port, u8 role, u8 padding, u8 peer address[16]}
+	KeySize       = 24
+	KeyOffPID     = 0
+	KeyOffPort    = 4
+	KeyOffRole    = 6
+	KeyOffPadding = 7
+	KeyOffAddr    = 8
+
+	// NumBuckets of the duration histograms, including the +Inf bucket
+	NumBuckets = 10
+
+	// each histogram contains the count of each bucket followed by the sum of all the observations
+	histogramSize = (NumBuckets + 1) * 8
+
+	OffRetransmits     = 0
+	OffResetsReceived  = 8
+	OffResetsSent      = 16
+	OffFailuresRefused = 24
+	OffFailuresTimeout = 32
+	OffSetupDuration   = 40
+	OffRTT             = OffSetupDuration + histogramSize
+	StatsSize          = OffRTT + histogramSize
+
+	// HistogramSumOff is the offset of the sum of the observations, from the start of a histogram
+	HistogramSumOff = NumBuckets * 8
+)
+
+// BucketBoundsNs are the upper bounds of the duration histograms, in nanoseconds.
+// The histograms have an extra +Inf bucket for the observations above the last bound.
+var BucketBoundsNs = [NumBuckets - 1]int32{
+	100_000, 500_000, 1_000_000, 5_000_000,
+	10_000_000, 50_000_000, 100_000_000, 500_000_000, 1_000_000_000,
+}
+
+// BucketBounds returns the upper bounds of the duration histograms in seconds, as
+// reported by the metrics exporters
+func BucketBounds() []float64 {
+	bounds := make([]float64, 0, len(BucketBoundsNs))
+	for _, b := range BucketBoundsNs {
+		bounds = append(bounds, float64(b)/1e9)
+	}
+	return bounds
+}
+
+// Role of the instrumented process in a TCP connection
+type Role uint8
+
+const (
+	// RoleClient connections are initiated by the instrumented process
+	RoleClient = Role(0)
+	// RoleServer connections are accepted by the instrumented process
+	RoleServer = Role(1)
+)
+
+func (r Role) String() string {
+	if r == RoleServer {
+		return "server"
+	}
+	return "client"
+}
+
+// Key groups the connections of a process by role and peer. The port is always the port of
+// the server side (the remote port for clients, the local port for servers), to avoid
+// creating a group for each ephemeral port.
+type Key struct {
+	PID  uint32
+	Port uint16
+	Role Role
+	// Addr of the peer. IPv4 addresses are mapped into IPv6
+	Addr [16]byte
+}
+
+// PeerAddress returns the textual representation of the peer address
+func (k *Key) PeerAddress() string {
+	return netip.AddrFrom16(k.Addr).Unmap().String()
+}
+
+// DecodeKey from the raw key of the StatsMapName map
+func DecodeKey(raw []byte) (Key, bool) {
+	if len(raw) < KeySize {
+		return Key{}, false
+	}
+	k := Key{
+		PID:  binary.NativeEndian.Uint32(raw[KeyOffPID:]),
+		Port: binary.NativeEndian.Uint16(raw[KeyOffPort:]),
+		Role: Role(raw[KeyOffRole]),
+	}
+	copy(k.Addr[:], raw[KeyOffAddr:KeyOffAddr+16])
+	return k, true
+}
+
+// Encode the key in the format of the StatsMapName map
+func (k *Key) Encode() []byte {
+	raw := make([]byte, KeySize)
+	binary.NativeEndian.PutUint32(raw[KeyOffPID:], k.PID)
+	binary.NativeEndian.PutUint16(raw[KeyOffPort:], k.Port)
+	raw[KeyOffRole] = byte(k.Role)
+	copy(raw[KeyOffAddr:], k.Addr[:])
+	return raw
+}
+
+// Stats of a group of connections, as accounted by the eBPF probes since the process was instrumented.
+type Stats struct {
+	Retransmits    uint64
+	ResetsReceived uint64
+	ResetsSent     uint64
+	// FailuresRefused accounts the connection attempts that were answered with a reset
+	FailuresRefused uint64
+	// FailuresTimeout accounts the connection attempts that were closed without response
+	FailuresTimeout uint64
+	// SetupDuration accounts the time between sending the SYN and establishing the connection
+	SetupDuration Histogram
+	// RTT accounts the smoothed round-trip time of the connections, sampled on each send
+	RTT Histogram
+}
+
+func (s *Stats) sub(o *Stats) Stats {
+	return Stats{
+		Retransmits:     s.Retransmits - o.Retransmits,
+		ResetsReceived:  s.ResetsReceived - o.ResetsReceived,
+		ResetsSent:      s.ResetsSent - o.ResetsSent,
+		FailuresRefused: s.FailuresRefused - o.FailuresRefused,
+		FailuresTimeout: s.FailuresTimeout - o.FailuresTimeout,
+		SetupDuration:   s.SetupDuration.sub(&o.SetupDuration),
+		RTT:             s.RTT.sub(&o.RTT),
+	}
+}
+
+// Histogram of durations
+type Histogram struct {
+	// Counts of observations in each bucket. They are not cumulative: each observation is
+	// only accounted in the first bucket whose upper bound is higher or equal to it.
+	Counts [NumBuckets]uint64
+	// SumNs of all the observations, in nanoseconds
+	SumNs uint64
+}
+
+// Count of all the observations in the histogram
+func (h *Histogram) Count() uint64 {
+	c := uint64(0)
+	for _, b := range h.Counts {
+		c += b
+	}
+	return c
+}
+
+// Add the observations of another histogram
+func (h *Histogram) Add(o *Histogram) {
+	for i := range h.Counts {
+		h.Counts[i] += o.Counts[i]
+	}
+	h.SumNs += o.SumNs
+}
+
+func (h *Histogram) sub(o *Histogram) Histogram {
+	d := Histogram{SumNs: h.SumNs - o.SumNs}
+	for i := range h.Counts {
+		d.Counts[i] = h.Counts[i] - o.Counts[i]
+	}
+	return d
+}
+
+// DecodeStats from the raw value of the StatsMapName map
+func DecodeStats(raw []byte) (Stats, bool) {
+	if len(raw) < StatsSize {
+		return Stats{}, false
+	}
+	ne := binary.NativeEndian
+	return Stats{
+		Retransmits:     ne.Uint64(raw[OffRetransmits:]),
+		ResetsReceived:  ne.Uint64(raw[OffResetsReceived:]),
+		ResetsSent:      ne.Uint64(raw[OffResetsSent:]),
+		FailuresRefused: ne.Uint64(raw[OffFailuresRefused:]),
+		FailuresTimeout: ne.Uint64(raw[OffFailuresTimeout:]),
+		SetupDuration:   decodeHistogram(raw[OffSetupDuration:]),
+		RTT:             decodeHistogram(raw[OffRTT:]),
+	}, true
+}
+
+func decodeHistogram(raw []byte) Histogram {
+	h := Histogram{SumNs: binary.NativeEndian.Uint64(raw[HistogramSumOff:])}
+	for i := range h.Counts {
+		h.Counts[i] = binary.NativeEndian.Uint64(raw[i*8:])
+	}
+	return h
+}
@@ -0,0 +1,75 @@
+package tcphealth
+
+import (
+	"log/slog"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/grafana/beyla/pkg/export/attributes"
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+func tlog() *slog.Logger {
+	return slog.With("component", "tcphealth.Collector")
+}
+
+// Status of the TCP connections of a service with a given peer since the last collection
+type Status struct {
+	Service *svc.ID
+
+	Role        Role
+	PeerAddress string
+	ServerPort  uint16
+
+	// Despite the eBPF values are absolute counters, the OTEL and Prometheus APIs require that
+	// they are specified as deltas
+
+	RetransmitsDelta     uint64
+	ResetsReceivedDelta  uint64
+	ResetsSentDelta      uint64
+	FailuresRefusedDelta uint64
+	FailuresTimeoutDelta uint64
+	SetupDurationDelta   Histogram
+	RTTDelta             Histogram
+}
+
+func (s *Status) add(d *Stats) {
+	s.RetransmitsDelta += d.Retransmits
+	s.ResetsReceivedDelta += d.ResetsReceived
+	s.ResetsSentDelta += d.ResetsSent
+	s.FailuresRefusedDelta += d.FailuresRefused
+	s.FailuresTimeoutDelta += d.FailuresTimeout
+	s.SetupDurationDelta.Add(&d.SetupDuration)
+	s.RTTDelta.Add(&d.RTT)
+}
+
+func PromGetters(name attr.Name) (attributes.Getter[*Status, string], bool) {
+	var g attributes.Getter[*Status, string]
+	switch name {
+	case attr.ServiceName:
+		g = func(s *Status) string { return s.Service.Name }
+	case attr.ServiceNamespace:
+		g = func(s *Status) string { return s.Service.Namespace }
+	case attr.TCPRole:
+		g = func(s *Status) string { return s.Role.String() }
+	case attr.NetworkPeerAddress:
+		g = func(s *Status) string { return s.PeerAddress }
+	case attr.ServerPort:
+		g = func(s *Status) string { return strconv.Itoa(int(s.ServerPort)) }
+	default:
+		g = func(s *Status) string { return s.Service.Metadata[name] }
+	}
+	return g, g != nil
+}
+
+func OTELGetters(name attr.Name) (attributes.Getter[*Status, attribute.KeyValue], bool) {
+	if name == attr.ServerPort {
+		return func(s *Status) attribute.KeyValue { return name.OTEL().Int(int(s.ServerPort)) }, true
+	}
+	if g, ok := PromGetters(name); ok {
+		return func(s *Status) attribute.KeyValue { return name.OTEL().String(g(s)) }, true
+	}
+	return nil, false
+}
@@ -28,9 +28,11 @@ import (
 	"github.com/cilium/ebpf/ringbuf"
 
 	"github.com/grafana/beyla/pkg/beyla"
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
 	"github.com/grafana/beyla/pkg/internal/netolly/ebpf"
 	"github.com/grafana/beyla/pkg/internal/netolly/flow"
 	"github.com/grafana/beyla/pkg/internal/netolly/ifaces"
+	"github.com/grafana/beyla/pkg/internal/netolly/transform/l7proto"
 	"github.com/grafana/beyla/pkg/internal/pipe/global"
 )
 
@@ -96,6 +98,12 @@ type Flows struct {
 	// processing nodes to be wired in the buildPipeline method
 	mapTracer *flow.MapTracer
 	rbTracer  *flow.RingBufTracer
+	// dropTracer is nil if the tracing of dropped packets is disabled
+	dropTracer *flow.DropTracer
+	drops      *ebpf.DropFetcher
+	// l7Tagger is nil if the detection of the application protocols is disabled
+	l7Tagger *l7proto.Tagger
+	payloads *ebpf.PayloadSampler
 
 	// elements used to decorate flows with extra information
 	interfaceNamer flow.InterfaceNamer
@@ -161,7 +169,29 @@ func FlowsAgent(ctxInfo *global.ContextInfo, cfg *beyla.Config) (*Flows, error)
 		return nil, fmt.Errorf("unknown network configuration eBPF source specified, allowed options are [tc, socket_filter]")
 	}
 
-	return flowsAgent(ctxInfo, cfg, informer, fetcher, agentIP)
+	f, err := flowsAgent(ctxInfo, cfg, informer, fetcher, agentIP)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.NetworkFlows.Drops {
+		if f.drops, err = ebpf.NewDropFetcher(cfg.NetworkFlows.CacheMaxFlows); err != nil {
+			alog.Warn("can't trace the dropped packets. They won't be reported", "error", err)
+		} else {
+			f.dropTracer = flow.NewDropTracer(f.drops, cfg.NetworkFlows.CacheActiveTimeout)
+		}
+	}
+	if cfg.NetworkFlows.L7Protocol {
+		// the application tracer might not be running, so the detectors need to be disabled also here
+		if err := ebpfcommon.DisableProtocolDetectors(cfg.EBPF.DisabledProtocolDetectors); err != nil {
+			return nil, fmt.Errorf("configuring L7 protocol detectors: %w", err)
+		}
+		if f.payloads, err = ebpf.NewPayloadSampler(cfg.NetworkFlows.CacheMaxFlows); err != nil {
+			alog.Warn("can't sample the TCP payloads. The application protocols won't be reported", "error", err)
+		} else if f.l7Tagger, err = l7proto.NewTagger(f.payloads, cfg.NetworkFlows.CacheMaxFlows); err != nil {
+			return nil, fmt.Errorf("creating L7 protocol tagger: %w", err)
+		}
+	}
+	return f, nil
 }
 
 // flowsAgent is a private constructor with injectable dependencies, usable for tests
@@ -240,6 +270,16 @@ func (f *Flows) Run(ctx context.Context) error {
 	if err := f.ebpf.Close(); err != nil {
 		alog.Warn("eBPF resources not correctly closed", "error", err)
 	}
+	if f.drops != nil {
+		if err := f.drops.Close(); err != nil {
+			alog.Warn("drops tracer resources not correctly closed", "error", err)
+		}
+	}
+	if f.payloads != nil {
+		if err := f.payloads.Close(); err != nil {
+			alog.Warn("payload sampler resources not correctly closed", "error", err)
+		}
+	}
 
 	alog.Debug("waiting for all nodes to finish their pending work")
 	<-graph.Done()
@@ -10,9 +10,11 @@ import (
 	"github.com/grafana/beyla/pkg/internal/filter"
 	"github.com/grafana/beyla/pkg/internal/netolly/ebpf"
 	"github.com/grafana/beyla/pkg/internal/netolly/export"
+	"github.com/grafana/beyla/pkg/internal/netolly/export/ipfix"
 	"github.com/grafana/beyla/pkg/internal/netolly/flow"
 	"github.com/grafana/beyla/pkg/internal/netolly/transform/cidr"
 	"github.com/grafana/beyla/pkg/internal/netolly/transform/k8s"
+	"github.com/grafana/beyla/pkg/internal/netolly/transform/l7proto"
 )
 
 // FlowsPipeline defines the different nodes in the Beyla's NetO11y module,
@@ -20,17 +22,21 @@ import (
 type FlowsPipeline struct {
 	MapTracer     pipe.Start[[]*ebpf.Record]
 	RingBufTracer pipe.Start[[]*ebpf.Record]
+	DropTracer    pipe.Start[[]*ebpf.Record]
 
 	ProtoFilter     pipe.Middle[[]*ebpf.Record, []*ebpf.Record]
 	Deduper         pipe.Middle[[]*ebpf.Record, []*ebpf.Record]
+	Handshakes      pipe.Middle[[]*ebpf.Record, []*ebpf.Record]
 	Kubernetes      pipe.Middle[[]*ebpf.Record, []*ebpf.Record]
 	ReverseDNS      pipe.Middle[[]*ebpf.Record, []*ebpf.Record]
 	CIDRs           pipe.Middle[[]*ebpf.Record, []*ebpf.Record]
+	L7Protocols     pipe.Middle[[]*ebpf.Record, []*ebpf.Record]
 	Decorator       pipe.Middle[[]*ebpf.Record, []*ebpf.Record]
 	AttributeFilter pipe.Middle[[]*ebpf.Record, []*ebpf.Record]
 
 	OTEL    pipe.Final[[]*ebpf.Record]
 	Prom    pipe.Final[[]*ebpf.Record]
+	IPFIX   pipe.Final[[]*ebpf.Record]
 	Printer pipe.Final[[]*ebpf.Record]
 }
 
@@ -38,32 +44,39 @@ type FlowsPipeline struct {
 func (fp *FlowsPipeline) Connect() {
 	fp.MapTracer.SendTo(fp.ProtoFilter)
 	fp.RingBufTracer.SendTo(fp.ProtoFilter)
+	fp.DropTracer.SendTo(fp.ProtoFilter)
 
 	fp.ProtoFilter.SendTo(fp.Deduper)
-	fp.Deduper.SendTo(fp.Kubernetes)
+	fp.Deduper.SendTo(fp.Handshakes)
+	fp.Handshakes.SendTo(fp.Kubernetes)
 	fp.Kubernetes.SendTo(fp.ReverseDNS)
 	fp.ReverseDNS.SendTo(fp.CIDRs)
-	fp.CIDRs.SendTo(fp.Decorator)
+	fp.CIDRs.SendTo(fp.L7Protocols)
+	fp.L7Protocols.SendTo(fp.Decorator)
 	fp.Decorator.SendTo(fp.AttributeFilter)
 
-	fp.AttributeFilter.SendTo(fp.OTEL, fp.Prom, fp.Printer)
+	fp.AttributeFilter.SendTo(fp.OTEL, fp.Prom, fp.IPFIX, fp.Printer)
 }
 
 // Accessory field pointer getters to later tell to the node providers where to store each pipeline Node
 func mapTracer(fp *FlowsPipeline) *pipe.Start[[]*ebpf.Record]     { return &fp.MapTracer }
 func ringBufTracer(fp *FlowsPipeline) *pipe.Start[[]*ebpf.Record] { return &fp.RingBufTracer }
+func dropTracer(fp *FlowsPipeline) *pipe.Start[[]*ebpf.Record]    { return &fp.DropTracer }
 
 func prtFltr(fp *FlowsPipeline) *pipe.Middle[[]*ebpf.Record, []*ebpf.Record]   { return &fp.ProtoFilter }
 func deduper(fp *FlowsPipeline) *pipe.Middle[[]*ebpf.Record, []*ebpf.Record]   { return &fp.Deduper }
+func hshakes(fp *FlowsPipeline) *pipe.Middle[[]*ebpf.Record, []*ebpf.Record]   { return &fp.Handshakes }
 func kube(fp *FlowsPipeline) *pipe.Middle[[]*ebpf.Record, []*ebpf.Record]      { return &fp.Kubernetes }
 func rdns(fp *FlowsPipeline) *pipe.Middle[[]*ebpf.Record, []*ebpf.Record]      { return &fp.ReverseDNS }
 func cidrs(fp *FlowsPipeline) *pipe.Middle[[]*ebpf.Record, []*ebpf.Record]     { return &fp.CIDRs }
+func l7protos(fp *FlowsPipeline) *pipe.Middle[[]*ebpf.Record, []*ebpf.Record]  { return &fp.L7Protocols }
 func decorator(fp *FlowsPipeline) *pipe.Middle[[]*ebpf.Record, []*ebpf.Record] { return &fp.Decorator }
 func fltr(fp *FlowsPipeline) *pipe.Middle[[]*ebpf.Record, []*ebpf.Record]      { return &fp.AttributeFilter }
 
-func otelExport(fp *FlowsPipeline) *pipe.Final[[]*ebpf.Record] { return &fp.OTEL }
-func promExport(fp *FlowsPipeline) *pipe.Final[[]*ebpf.Record] { return &fp.Prom }
-func printer(fp *FlowsPipeline) *pipe.Final[[]*ebpf.Record]    { return &fp.Printer }
+func otelExport(fp *FlowsPipeline) *pipe.Final[[]*ebpf.Record]  { return &fp.OTEL }
+func promExport(fp *FlowsPipeline) *pipe.Final[[]*ebpf.Record]  { return &fp.Prom }
+func ipfixExport(fp *FlowsPipeline) *pipe.Final[[]*ebpf.Record] { return &fp.IPFIX }
+func printer(fp *FlowsPipeline) *pipe.Final[[]*ebpf.Record]     { return &fp.Printer }
 
 // buildPipeline creates the ETL flow processing graph.
 // For a more visual view, check the docs/architecture.md document.
@@ -83,12 +96,29 @@ func (f *Flows) pipelineBuilder(ctx context.Context) (*pipe.Builder[*FlowsPipeli
 		return nil, err
 	}
 
+	f.cfg.Attributes.Select.Normalize()
+	otelCfg := &otel.NetMetricsConfig{
+		Metrics:            &f.cfg.Metrics,
+		AttributeSelectors: f.cfg.Attributes.Select,
+		GloballyEnabled:    f.cfg.NetworkFlows.Enable,
+	}
+	promCfg := &prom.NetPrometheusConfig{
+		Config:             &f.cfg.Prometheus,
+		AttributeSelectors: f.cfg.Attributes.Select,
+		GloballyEnabled:    f.cfg.NetworkFlows.Enable,
+	}
+
 	alog.Debug("creating flows' processing graph")
 	pb := pipe.NewBuilder(&FlowsPipeline{}, pipe.ChannelBufferLen(f.cfg.ChannelBufferLen))
 
 	// Start nodes: those generating flow records (reading them from eBPF)
 	pipe.AddStart(pb, mapTracer, f.mapTracer.TraceLoop(ctx))
 	pipe.AddStart(pb, ringBufTracer, f.rbTracer.TraceLoop(ctx))
+	if f.dropTracer != nil {
+		pipe.AddStart(pb, dropTracer, f.dropTracer.TraceLoop(ctx))
+	} else {
+		pipe.AddStart(pb, dropTracer, func(_ chan<- []*ebpf.Record) {})
+	}
 
 	// Middle nodes: transforming flow records and passing them to the next stage in the pipeline.
 	// Many of the nodes here are not mandatory. It's decision of each Provider function to decide
@@ -106,6 +136,17 @@ func (f *Flows) pipelineBuilder(ctx context.Context) (*pipe.Builder[*FlowsPipeli
 			ExpireTime: deduperExpireTime,
 		})
 	})
+	pipe.AddMiddleProvider(pb, hshakes, func() (pipe.MiddleFunc[[]*ebpf.Record, []*ebpf.Record], error) {
+		var handshakeTimeout = f.cfg.NetworkFlows.HandshakeTimeout
+		if handshakeTimeout <= 0 {
+			handshakeTimeout = 2 * f.cfg.NetworkFlows.CacheActiveTimeout
+		}
+		return flow.HandshakeTrackerProvider(&flow.HandshakeTracker{
+			// the handshake information is only useful for the metrics exporters
+			Enabled: otelCfg.Enabled() || promCfg.Enabled(),
+			Timeout: handshakeTimeout,
+		})
+	})
 	pipe.AddMiddleProvider(pb, decorator, func() (pipe.MiddleFunc[[]*ebpf.Record, []*ebpf.Record], error) {
 		// If deduper is enabled, we know that interfaces are unset.
 		// As an optimization, we just pass here an empty-string interface namer
@@ -120,6 +161,12 @@ func (f *Flows) pipelineBuilder(ctx context.Context) (*pipe.Builder[*FlowsPipeli
 	pipe.AddMiddleProvider(pb, cidrs, func() (pipe.MiddleFunc[[]*ebpf.Record, []*ebpf.Record], error) {
 		return cidr.DecoratorProvider(f.cfg.NetworkFlows.CIDRs)
 	})
+	pipe.AddMiddleProvider(pb, l7protos, func() (pipe.MiddleFunc[[]*ebpf.Record, []*ebpf.Record], error) {
+		if f.l7Tagger != nil {
+			go f.l7Tagger.ClassifyLoop(ctx)
+		}
+		return l7proto.DecoratorProvider(f.l7Tagger)
+	})
 	pipe.AddMiddleProvider(pb, kube, func() (pipe.MiddleFunc[[]*ebpf.Record, []*ebpf.Record], error) {
 		return k8s.MetadataDecoratorProvider(ctx, &f.cfg.Attributes.Kubernetes, f.ctxInfo.K8sInformer)
 	})
@@ -128,23 +175,17 @@ func (f *Flows) pipelineBuilder(ctx context.Context) (*pipe.Builder[*FlowsPipeli
 	})
 	pipe.AddMiddleProvider(pb, fltr, filter.ByAttribute(f.cfg.Filters.Network, ebpf.RecordStringGetters))
 
-	// Terminal nodes export the flow record information out of the pipeline: OTEL, Prom and printer.
+	// Terminal nodes export the flow record information out of the pipeline: OTEL, Prom, IPFIX and printer.
 	// Not all the nodes are mandatory here. Is the responsibility of each Provider function to decide
 	// whether each node is going to be instantiated or just ignored.
-	f.cfg.Attributes.Select.Normalize()
 	pipe.AddFinalProvider(pb, otelExport, func() (pipe.FinalFunc[[]*ebpf.Record], error) {
-		return otel.NetMetricsExporterProvider(ctx, f.ctxInfo, &otel.NetMetricsConfig{
-			Metrics:            &f.cfg.Metrics,
-			AttributeSelectors: f.cfg.Attributes.Select,
-			GloballyEnabled:    f.cfg.NetworkFlows.Enable,
-		})
+		return otel.NetMetricsExporterProvider(ctx, f.ctxInfo, otelCfg)
 	})
 	pipe.AddFinalProvider(pb, promExport, func() (pipe.FinalFunc[[]*ebpf.Record], error) {
-		return prom.NetPrometheusEndpoint(ctx, f.ctxInfo, &prom.NetPrometheusConfig{
-			Config:             &f.cfg.Prometheus,
-			AttributeSelectors: f.cfg.Attributes.Select,
-			GloballyEnabled:    f.cfg.NetworkFlows.Enable,
-		})
+		return prom.NetPrometheusEndpoint(ctx, f.ctxInfo, promCfg)
+	})
+	pipe.AddFinalProvider(pb, ipfixExport, func() (pipe.FinalFunc[[]*ebpf.Record], error) {
+		return ipfix.ExporterProvider(&f.cfg.NetworkFlows.IPFIX)
 	})
 	pipe.AddFinalProvider(pb, printer, func() (pipe.FinalFunc[[]*ebpf.Record], error) {
 		return export.FlowPrinterProvider(f.cfg.NetworkFlows.Print)
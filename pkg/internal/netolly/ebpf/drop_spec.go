@@ -0,0 +1,142 @@
+package ebpf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf"
+)
+
+//go:generate $BPF2GO -cc $BPF_CLANG -cflags $BPF_CFLAGS -type drop_key_t -type drop_value_t -target amd64,arm64 NetDrops ../../../../bpf/net_drops.c -- -I../../../../bpf/headers
+
+const (
+	dropsMapName = "net_drops"
+	// constant defined in net_drops.c as "volatile const"
+	constMinReason = "min_reason"
+
+	// DropKeySize of the dropsMapName map, as the drop_key_t struct in net_drops.c:
+	// {u8 src ip[16], u8 dst ip[16], u16 src port, u16 dst port, u8 protocol, u8 padding[3], u32 reason}
+	// IPv4 addresses are mapped into IPv6. Ports are stored in network byte order.
+	DropKeySize       = 44
+	dropKeyOffSrcIP   = 0
+	dropKeyOffDstIP   = 16
+	dropKeyOffSrcPort = 32
+	dropKeyOffDstPort = 34
+	dropKeyOffProto   = 36
+	dropKeyOffReason  = 40
+
+	// DropValueSize of the dropsMapName map: {u64 packets, u64 bytes}
+	DropValueSize     = 16
+	dropValOffPackets = 0
+	dropValOffBytes   = 8
+)
+
+// Values and sizes of the packet headers
+const (
+	ethPIPv4      = 0x0800
+	ethPIPv6      = 0x86DD
+	ipv4HeaderLen = 20
+	ipv6HeaderLen = 40
+	ipProtoTCP    = 6
+	ipProtoUDP    = 17
+)
+
+// KfreeSkbFormat describes the drop reasons of the skb/kfree_skb tracepoint, as they are
+// described in /sys/kernel/tracing/events/skb/kfree_skb/format. The drop reason codes change
+// between kernel versions. The offsets of the tracepoint fields are relocated from the kernel BTF.
+type KfreeSkbFormat struct {
+	// Reasons maps each drop reason code to its name. Codes that are not in this
+	// map (e.g. SKB_CONSUMED) don't represent dropped packets.
+	Reasons map[uint32]string
+}
+
+var (
+	formatFieldRegex  = regexp.MustCompile(`field:.*[ *](\w+);\s*offset:\d+;`)
+	formatReasonRegex = regexp.MustCompile(`\{\s*(\d+),\s*"(\w+)"\s*}`)
+)
+
+// ParseKfreeSkbFormat parses the format of the skb/kfree_skb tracepoint. It fails if the
+// kernel does not report the drop reason (kernels older than 5.17).
+func ParseKfreeSkbFormat(format io.Reader) (*KfreeSkbFormat, error) {
+	f := KfreeSkbFormat{Reasons: map[uint32]string{}}
+	fields := map[string]bool{}
+	scanner := bufio.NewScanner(format)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "print fmt:") {
+			for _, m := range formatReasonRegex.FindAllStringSubmatch(line, -1) {
+				code, _ := strconv.ParseUint(m[1], 10, 32)
+				f.Reasons[uint32(code)] = m[2]
+			}
+			continue
+		}
+		m := formatFieldRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		fields[m[1]] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading kfree_skb tracepoint format: %w", err)
+	}
+	if !fields["skbaddr"] || !fields["protocol"] {
+		return nil, errors.New("kfree_skb tracepoint does not provide the socket buffer and protocol")
+	}
+	if !fields["reason"] || len(f.Reasons) == 0 {
+		return nil, errors.New("kfree_skb tracepoint does not provide the drop reason. Kernel 5.17 or higher is required")
+	}
+	return &f, nil
+}
+
+// minReason returns the lowest drop reason code. Lower codes don't represent dropped packets.
+func (f *KfreeSkbFormat) minReason() uint32 {
+	minCode := ^uint32(0)
+	for code := range f.Reasons {
+		minCode = min(minCode, code)
+	}
+	return minCode
+}
+
+// dropsSpec returns the specification of the skb/kfree_skb tracepoint program, which accounts
+// the dropped packets and bytes in a hash map that is indexed by flow and drop reason.
+func dropsSpec(format *KfreeSkbFormat, maxEntries int) (*ebpf.CollectionSpec, error) {
+	spec, err := LoadNetDrops()
+	if err != nil {
+		return nil, err
+	}
+	spec.Maps[dropsMapName].MaxEntries = uint32(maxEntries)
+	if err := spec.RewriteConstants(map[string]any{constMinReason: format.minReason()}); err != nil {
+		return nil, fmt.Errorf("rewriting constants: %w", err)
+	}
+	return spec, nil
+}
+
+// decodeDrop returns the Record of the packets that are accounted in an entry of the dropsMapName map
+func decodeDrop(key, value []byte, reasons map[uint32]string) *Record {
+	r := &Record{}
+	copy(r.Id.SrcIp.In6U.U6Addr8[:], key[dropKeyOffSrcIP:dropKeyOffSrcIP+16])
+	copy(r.Id.DstIp.In6U.U6Addr8[:], key[dropKeyOffDstIP:dropKeyOffDstIP+16])
+	r.Id.SrcPort = binary.BigEndian.Uint16(key[dropKeyOffSrcPort:])
+	r.Id.DstPort = binary.BigEndian.Uint16(key[dropKeyOffDstPort:])
+	r.Id.TransportProtocol = key[dropKeyOffProto]
+	r.Id.IfIndex = InterfaceUnset
+	r.Id.EthProtocol = ethPIPv6
+	if r.Id.SrcIP().IP().To4() != nil {
+		r.Id.EthProtocol = ethPIPv4
+	}
+	r.Metrics.IfaceDirection = DirectionUnset
+	r.Metrics.Packets = uint32(binary.NativeEndian.Uint64(value[dropValOffPackets:]))
+	r.Metrics.Bytes = binary.NativeEndian.Uint64(value[dropValOffBytes:])
+	code := binary.NativeEndian.Uint32(key[dropKeyOffReason:])
+	if r.DropReason = reasons[code]; r.DropReason == "" {
+		r.DropReason = strconv.Itoa(int(code))
+	}
+	return r
+}
@@ -0,0 +1,112 @@
+package ebpf
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/rlimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const kfreeSkbFormat = `name: kfree_skb
+ID: 2210
+format:
+	field:unsigned short common_type;	offset:0;	size:2;	signed:0;
+	field:unsigned char common_flags;	offset:2;	size:1;	signed:0;
+	field:unsigned char common_preempt_count;	offset:3;	size:1;	signed:0;
+	field:int common_pid;	offset:4;	size:4;	signed:1;
+
+	field:void * skbaddr;	offset:8;	size:8;	signed:0;
+	field:void * location;	offset:16;	size:8;	signed:0;
+	field:void * rx_sk;	offset:24;	size:8;	signed:0;
+	field:unsigned short protocol;	offset:32;	size:2;	signed:0;
+	field:enum skb_drop_reason reason;	offset:36;	size:4;	signed:0;
+
+print fmt: "skbaddr=%p rx_sk=%p protocol=%u location=%pS reason: %s", REC->skbaddr, REC->rx_sk, REC->protocol, REC->location, __print_symbolic(REC->reason, { 2, "NOT_SPECIFIED" }, { 3, "NO_SOCKET" }, { 12, "NETFILTER_DROP" }, { 128, "MAX" })
+`
+
+func TestParseKfreeSkbFormat(t *testing.T) {
+	format, err := ParseKfreeSkbFormat(strings.NewReader(kfreeSkbFormat))
+	require.NoError(t, err)
+	assert.Equal(t, &KfreeSkbFormat{
+		Reasons: map[uint32]string{2: "NOT_SPECIFIED", 3: "NO_SOCKET", 12: "NETFILTER_DROP", 128: "MAX"},
+	}, format)
+	assert.Equal(t, uint32(2), format.minReason())
+}
+
+func TestParseKfreeSkbFormat_NoReason(t *testing.T) {
+	// kernels older than 5.17 don't report the drop reason
+	_, err := ParseKfreeSkbFormat(strings.NewReader(`name: kfree_skb
+format:
+	field:void * skbaddr;	offset:8;	size:8;	signed:0;
+	field:void * location;	offset:16;	size:8;	signed:0;
+	field:unsigned short protocol;	offset:24;	size:2;	signed:0;
+
+print fmt: "skbaddr=%p protocol=%u location=%p", REC->skbaddr, REC->protocol, REC->location
+`))
+	require.Error(t, err)
+}
+
+func TestDecodeDrop(t *testing.T) {
+	key, value := make([]byte, DropKeySize), make([]byte, DropValueSize)
+	copy(key[dropKeyOffSrcIP:], []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 10, 0, 0, 1})
+	copy(key[dropKeyOffDstIP:], []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 10, 0, 0, 2})
+	binary.BigEndian.PutUint16(key[dropKeyOffSrcPort:], 34567)
+	binary.BigEndian.PutUint16(key[dropKeyOffDstPort:], 8080)
+	key[dropKeyOffProto] = ipProtoTCP
+	binary.NativeEndian.PutUint32(key[dropKeyOffReason:], 12)
+	binary.NativeEndian.PutUint64(value[dropValOffPackets:], 3)
+	binary.NativeEndian.PutUint64(value[dropValOffBytes:], 180)
+
+	r := decodeDrop(key, value, map[uint32]string{12: "NETFILTER_DROP"})
+	assert.Equal(t, "NETFILTER_DROP", r.DropReason)
+	assert.Equal(t, "10.0.0.1", r.Id.SrcIP().IP().String())
+	assert.Equal(t, "10.0.0.2", r.Id.DstIP().IP().String())
+	assert.Equal(t, uint16(34567), r.Id.SrcPort)
+	assert.Equal(t, uint16(8080), r.Id.DstPort)
+	assert.Equal(t, uint8(ipProtoTCP), r.Id.TransportProtocol)
+	assert.Equal(t, uint16(ethPIPv4), r.Id.EthProtocol)
+	assert.Equal(t, uint32(3), r.Metrics.Packets)
+	assert.Equal(t, uint64(180), r.Metrics.Bytes)
+
+	// unknown reasons are reported by their code
+	r = decodeDrop(key, value, map[uint32]string{})
+	assert.Equal(t, "12", r.DropReason)
+}
+
+func TestDropsLayout(t *testing.T) {
+	var k NetDropsDropKeyT
+	assert.Equal(t, DropKeySize, binary.Size(k))
+	assert.EqualValues(t, dropKeyOffSrcIP, unsafe.Offsetof(k.SrcIp))
+	assert.EqualValues(t, dropKeyOffDstIP, unsafe.Offsetof(k.DstIp))
+	assert.EqualValues(t, dropKeyOffSrcPort, unsafe.Offsetof(k.SrcPort))
+	assert.EqualValues(t, dropKeyOffDstPort, unsafe.Offsetof(k.DstPort))
+	assert.EqualValues(t, dropKeyOffProto, unsafe.Offsetof(k.Protocol))
+	assert.EqualValues(t, dropKeyOffReason, unsafe.Offsetof(k.Reason))
+
+	var v NetDropsDropValueT
+	assert.Equal(t, DropValueSize, binary.Size(v))
+	assert.EqualValues(t, dropValOffPackets, unsafe.Offsetof(v.Packets))
+	assert.EqualValues(t, dropValOffBytes, unsafe.Offsetof(v.Bytes))
+}
+
+func TestDropsSpec_Load(t *testing.T) {
+	_ = rlimit.RemoveMemlock()
+	format, err := ParseKfreeSkbFormat(strings.NewReader(kfreeSkbFormat))
+	require.NoError(t, err)
+	spec, err := dropsSpec(format, 100)
+	require.NoError(t, err)
+	coll, err := ebpf.NewCollection(spec)
+	if errors.Is(err, os.ErrPermission) || errors.Is(err, ebpf.ErrNotSupported) {
+		t.Skip("can't load eBPF programs in this environment:", err)
+	}
+	require.NoError(t, err)
+	assert.EqualValues(t, 100, spec.Maps[dropsMapName].MaxEntries)
+	coll.Close()
+}
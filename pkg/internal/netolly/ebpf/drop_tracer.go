@@ -0,0 +1,94 @@
+//go:build linux
+
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/cilium/ebpf/link"
+)
+
+// tracefs mount points where the format of the kfree_skb tracepoint is looked up
+var kfreeSkbFormatPaths = []string{
+	"/sys/kernel/tracing/events/skb/kfree_skb/format",
+	"/sys/kernel/debug/tracing/events/skb/kfree_skb/format",
+}
+
+func dtlog() *slog.Logger {
+	return slog.With("component", "ebpf.DropFetcher")
+}
+
+// DropFetcher accounts the network packets that are dropped by the kernel (e.g. by a
+// Kubernetes NetworkPolicy or an iptables rule), from the skb/kfree_skb tracepoint.
+type DropFetcher struct {
+	objects NetDropsObjects
+	link    link.Link
+	reasons map[uint32]string
+}
+
+// NewDropFetcher loads and attaches the drops tracer. It requires a kernel that reports
+// the drop reason in the kfree_skb tracepoint (5.17 or higher).
+func NewDropFetcher(cacheMaxSize int) (*DropFetcher, error) {
+	format, err := loadKfreeSkbFormat()
+	if err != nil {
+		return nil, err
+	}
+	spec, err := dropsSpec(format, cacheMaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("loading drops tracer spec: %w", err)
+	}
+	df := &DropFetcher{reasons: format.Reasons}
+	if err := spec.LoadAndAssign(&df.objects, nil); err != nil {
+		return nil, fmt.Errorf("loading drops tracer: %w", err)
+	}
+	if df.link, err = link.Tracepoint("skb", "kfree_skb", df.objects.TpKfreeSkb, nil); err != nil {
+		_ = df.Close()
+		return nil, fmt.Errorf("attaching drops tracer: %w", err)
+	}
+	return df, nil
+}
+
+func loadKfreeSkbFormat() (*KfreeSkbFormat, error) {
+	var errs []error
+	for _, path := range kfreeSkbFormatPaths {
+		file, err := os.Open(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		format, err := ParseKfreeSkbFormat(file)
+		_ = file.Close()
+		return format, err
+	}
+	return nil, fmt.Errorf("can't read the kfree_skb tracepoint format: %w", errors.Join(errs...))
+}
+
+// LookupAndDeleteDrops returns the dropped packets that have been accounted since the last
+// invocation, grouped by flow and drop reason
+func (d *DropFetcher) LookupAndDeleteDrops() []*Record {
+	var records []*Record
+	key, value := make([]byte, DropKeySize), make([]byte, DropValueSize)
+	iterator := d.objects.NetDrops.Iterate()
+	for iterator.Next(&key, &value) {
+		if err := d.objects.NetDrops.Delete(key); err != nil {
+			dtlog().Debug("couldn't delete drops entry", "error", err)
+		}
+		records = append(records, decodeDrop(key, value, d.reasons))
+	}
+	if err := iterator.Err(); err != nil {
+		dtlog().Debug("iterating drops map", "error", err)
+	}
+	return records
+}
+
+func (d *DropFetcher) Close() error {
+	var errs []error
+	if d.link != nil {
+		errs = append(errs, d.link.Close())
+	}
+	errs = append(errs, d.objects.Close())
+	return errors.Join(errs...)
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package ebpf
+
+type DropFetcher struct {
+}
+
+func NewDropFetcher(_ int) (*DropFetcher, error) {
+	return nil, nil
+}
+
+func (d *DropFetcher) LookupAndDeleteDrops() []*Record {
+	return nil
+}
+
+func (d *DropFetcher) Close() error {
+	return nil
+}
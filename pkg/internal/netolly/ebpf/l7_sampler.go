@@ -0,0 +1,85 @@
+//go:build linux
+
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// readTimeout lets the readers of the PayloadSampler periodically check whether they need to stop
+const readTimeout = time.Second
+
+// PayloadSampler captures the first payload bytes that are sent in each direction of the
+// TCP connections, from all the network interfaces, by means of a socket filter.
+type PayloadSampler struct {
+	objects NetL7Objects
+	fd      int
+	buf     []byte
+}
+
+// NewPayloadSampler loads the sampler socket filter and attaches it to a packet socket.
+// The cacheMaxSize is the number of connection directions that are remembered as sampled.
+func NewPayloadSampler(cacheMaxSize int) (*PayloadSampler, error) {
+	spec, err := l7SampleSpec(cacheMaxSize)
+	if err != nil {
+		return nil, fmt.Errorf("loading payload sampler spec: %w", err)
+	}
+	ps := &PayloadSampler{
+		fd:  -1,
+		buf: make([]byte, ethHeaderLen+ipv6HeaderLen+60+L7SamplePayloadLen),
+	}
+	if err := spec.LoadAndAssign(&ps.objects, nil); err != nil {
+		return nil, fmt.Errorf("loading payload sampler: %w", err)
+	}
+	// the socket does not receive packets until it is bound, so the filter is attached before
+	if ps.fd, err = unix.Socket(unix.AF_PACKET, unix.SOCK_RAW, 0); err != nil {
+		_ = ps.Close()
+		return nil, fmt.Errorf("creating payload sampler socket: %w", err)
+	}
+	if err := unix.SetsockoptInt(ps.fd, unix.SOL_SOCKET, unix.SO_ATTACH_BPF, ps.objects.SfL7Sample.FD()); err != nil {
+		_ = ps.Close()
+		return nil, fmt.Errorf("attaching payload sampler: %w", err)
+	}
+	tv := unix.NsecToTimeval(readTimeout.Nanoseconds())
+	if err := unix.SetsockoptTimeval(ps.fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		_ = ps.Close()
+		return nil, fmt.Errorf("setting payload sampler read timeout: %w", err)
+	}
+	if err := unix.Bind(ps.fd, &unix.SockaddrLinklayer{Protocol: htons(unix.ETH_P_ALL)}); err != nil {
+		_ = ps.Close()
+		return nil, fmt.Errorf("binding payload sampler socket: %w", err)
+	}
+	return ps, nil
+}
+
+// ReadSample blocks until a payload is sampled, or the read timeout expires. In the latter
+// case, it returns a nil sample and no error. The returned sample is only valid until the
+// next invocation.
+func (ps *PayloadSampler) ReadSample() (*PayloadSample, error) {
+	for {
+		n, _, err := unix.Recvfrom(ps.fd, ps.buf, 0)
+		if err != nil {
+			if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EINTR) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		if sample, ok := ParsePayloadSample(ps.buf[:n]); ok {
+			return sample, nil
+		}
+	}
+}
+
+func (ps *PayloadSampler) Close() error {
+	var errs []error
+	if ps.fd >= 0 {
+		errs = append(errs, unix.Close(ps.fd))
+		ps.fd = -1
+	}
+	errs = append(errs, ps.objects.Close())
+	return errors.Join(errs...)
+}
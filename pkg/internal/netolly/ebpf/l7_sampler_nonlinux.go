@@ -0,0 +1,18 @@
+//go:build !linux
+
+package ebpf
+
+type PayloadSampler struct {
+}
+
+func NewPayloadSampler(_ int) (*PayloadSampler, error) {
+	return nil, nil
+}
+
+func (ps *PayloadSampler) ReadSample() (*PayloadSample, error) {
+	return nil, nil
+}
+
+func (ps *PayloadSampler) Close() error {
+	return nil
+}
@@ -0,0 +1,96 @@
+package ebpf
+
+import (
+	"encoding/binary"
+
+	"github.com/cilium/ebpf"
+)
+
+//go:generate $BPF2GO -cc $BPF_CLANG -cflags $BPF_CFLAGS -type l7_key_t -target amd64,arm64 NetL7 ../../../../bpf/net_l7.c -- -I../../../../bpf/headers
+
+const (
+	l7SeenMapName     = "net_l7_seen"
+	l7SampleValueSize = 1
+
+	// L7SamplePayloadLen is the maximum number of payload bytes that are captured from the
+	// first packet of each connection direction. It must be kept in sync with
+	// L7_SAMPLE_PAYLOAD_LEN in net_l7.c
+	L7SamplePayloadLen = 1024
+
+	// l7KeySize of the l7SeenMapName map, as the l7_key_t struct in net_l7.c:
+	// {u8 src ip[16], u8 dst ip[16], u16 src port, u16 dst port}
+	// IPv4 addresses are mapped into IPv6. Ports are stored in network byte order.
+	l7KeySize = 36
+
+	ethHeaderLen = 14
+	// minimum length of the TCP header, until the data offset field
+	tcpHeaderMinLen = 13
+)
+
+// l7SampleSpec returns the specification of the sf_l7_sample socket filter, which captures the
+// Ethernet, IP and TCP headers, plus the first L7SamplePayloadLen payload bytes, of the first
+// TCP packet with payload that is sent in each direction of a connection.
+func l7SampleSpec(maxEntries int) (*ebpf.CollectionSpec, error) {
+	spec, err := LoadNetL7()
+	if err != nil {
+		return nil, err
+	}
+	spec.Maps[l7SeenMapName].MaxEntries = uint32(maxEntries)
+	return spec, nil
+}
+
+// PayloadSample is the first payload of a TCP connection direction
+type PayloadSample struct {
+	SrcIP   IPAddr
+	DstIP   IPAddr
+	SrcPort uint16
+	DstPort uint16
+	Payload []byte
+}
+
+// ParsePayloadSample extracts the addresses, ports and payload of a TCP packet, starting from
+// its Ethernet header. It returns false if the packet can't be parsed or it has no payload.
+func ParsePayloadSample(pkt []byte) (*PayloadSample, bool) {
+	if len(pkt) < ethHeaderLen+ipv4HeaderLen {
+		return nil, false
+	}
+	s := &PayloadSample{}
+	var tcpStart, ipEnd int
+	ip := pkt[ethHeaderLen:]
+	switch binary.BigEndian.Uint16(pkt[12:]) {
+	case ethPIPv4:
+		if ip[9] != ipProtoTCP || binary.BigEndian.Uint16(ip[6:])&0x1FFF != 0 {
+			return nil, false
+		}
+		s.SrcIP[10], s.SrcIP[11] = 0xFF, 0xFF
+		copy(s.SrcIP[12:], ip[12:16])
+		s.DstIP[10], s.DstIP[11] = 0xFF, 0xFF
+		copy(s.DstIP[12:], ip[16:20])
+		tcpStart = ethHeaderLen + int(ip[0]&0x0F)*4
+		ipEnd = ethHeaderLen + int(binary.BigEndian.Uint16(ip[2:]))
+	case ethPIPv6:
+		if len(ip) < ipv6HeaderLen || ip[6] != ipProtoTCP {
+			return nil, false
+		}
+		copy(s.SrcIP[:], ip[8:24])
+		copy(s.DstIP[:], ip[24:40])
+		tcpStart = ethHeaderLen + ipv6HeaderLen
+		ipEnd = tcpStart + int(binary.BigEndian.Uint16(ip[4:]))
+	default:
+		return nil, false
+	}
+	if len(pkt) < tcpStart+tcpHeaderMinLen {
+		return nil, false
+	}
+	tcp := pkt[tcpStart:]
+	s.SrcPort = binary.BigEndian.Uint16(tcp[0:])
+	s.DstPort = binary.BigEndian.Uint16(tcp[2:])
+	payloadStart := tcpStart + int(tcp[12]>>4)*4
+	// the packet might have been truncated by the socket filter
+	payloadEnd := min(ipEnd, len(pkt))
+	if payloadStart >= payloadEnd {
+		return nil, false
+	}
+	s.Payload = pkt[payloadStart:payloadEnd]
+	return s, true
+}
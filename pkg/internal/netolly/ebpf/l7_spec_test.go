@@ -0,0 +1,136 @@
+package ebpf
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/rlimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tcpPacket builds an Ethernet frame containing a TCP segment
+func tcpPacket(src, dst string, srcPort, dstPort uint16, payload []byte) []byte {
+	srcIP, dstIP := net.ParseIP(src), net.ParseIP(dst)
+	tcp := make([]byte, 20, 20+len(payload))
+	binary.BigEndian.PutUint16(tcp[0:], srcPort)
+	binary.BigEndian.PutUint16(tcp[2:], dstPort)
+	tcp[12] = 5 << 4
+	tcp = append(tcp, payload...)
+
+	pkt := make([]byte, ethHeaderLen)
+	if srcIP.To4() != nil {
+		binary.BigEndian.PutUint16(pkt[12:], ethPIPv4)
+		ip := make([]byte, ipv4HeaderLen)
+		ip[0] = 0x45
+		binary.BigEndian.PutUint16(ip[2:], uint16(ipv4HeaderLen+len(tcp)))
+		ip[9] = ipProtoTCP
+		copy(ip[12:], srcIP.To4())
+		copy(ip[16:], dstIP.To4())
+		pkt = append(pkt, ip...)
+	} else {
+		binary.BigEndian.PutUint16(pkt[12:], ethPIPv6)
+		ip := make([]byte, ipv6HeaderLen)
+		ip[0] = 0x60
+		binary.BigEndian.PutUint16(ip[4:], uint16(len(tcp)))
+		ip[6] = ipProtoTCP
+		copy(ip[8:], srcIP.To16())
+		copy(ip[24:], dstIP.To16())
+		pkt = append(pkt, ip...)
+	}
+	return append(pkt, tcp...)
+}
+
+func TestParsePayloadSample(t *testing.T) {
+	pkt := tcpPacket("10.0.0.1", "10.0.0.2", 34567, 80, []byte("GET / HTTP/1.1\r\n"))
+	// Ethernet frames might be padded
+	s, ok := ParsePayloadSample(append(pkt, 0, 0, 0, 0))
+	require.True(t, ok)
+	assert.Equal(t, "10.0.0.1", s.SrcIP.IP().String())
+	assert.Equal(t, "10.0.0.2", s.DstIP.IP().String())
+	assert.EqualValues(t, 34567, s.SrcPort)
+	assert.EqualValues(t, 80, s.DstPort)
+	assert.Equal(t, "GET / HTTP/1.1\r\n", string(s.Payload))
+
+	// truncated payloads
+	s, ok = ParsePayloadSample(pkt[:len(pkt)-5])
+	require.True(t, ok)
+	assert.Equal(t, "GET / HTTP/", string(s.Payload))
+
+	s, ok = ParsePayloadSample(tcpPacket("fd00::1", "fd00::2", 34567, 6379, []byte("*1\r\n")))
+	require.True(t, ok)
+	assert.Equal(t, "fd00::1", s.SrcIP.IP().String())
+	assert.Equal(t, "fd00::2", s.DstIP.IP().String())
+	assert.EqualValues(t, 6379, s.DstPort)
+	assert.Equal(t, "*1\r\n", string(s.Payload))
+
+	// packets without payload
+	_, ok = ParsePayloadSample(tcpPacket("10.0.0.1", "10.0.0.2", 34567, 80, nil))
+	assert.False(t, ok)
+	// non-TCP packets
+	udp := tcpPacket("10.0.0.1", "10.0.0.2", 34567, 53, []byte("foo"))
+	udp[ethHeaderLen+9] = ipProtoUDP
+	_, ok = ParsePayloadSample(udp)
+	assert.False(t, ok)
+	_, ok = ParsePayloadSample([]byte{1, 2, 3})
+	assert.False(t, ok)
+}
+
+func TestL7KeyLayout(t *testing.T) {
+	var k NetL7L7KeyT
+	assert.Equal(t, l7KeySize, binary.Size(k))
+}
+
+func TestL7SampleSpec_Run(t *testing.T) {
+	_ = rlimit.RemoveMemlock()
+	spec, err := l7SampleSpec(100)
+	require.NoError(t, err)
+	var objs NetL7Objects
+	err = spec.LoadAndAssign(&objs, nil)
+	if errors.Is(err, os.ErrPermission) || errors.Is(err, ebpf.ErrNotSupported) {
+		t.Skip("can't load eBPF programs in this environment:", err)
+	}
+	require.NoError(t, err)
+	defer objs.Close()
+	prog := objs.SfL7Sample
+
+	run := func(pkt []byte) uint32 {
+		// the test runner strips the first Ethernet header, while the packet sockets
+		// provide it to the filter, so the frame is prepended with a dummy header
+		ret, err := prog.Run(&ebpf.RunOptions{Data: append(make([]byte, ethHeaderLen), pkt...)})
+		if errors.Is(err, ebpf.ErrNotSupported) || errors.Is(err, os.ErrPermission) {
+			t.Skip("can't run eBPF programs in this environment:", err)
+		}
+		require.NoError(t, err)
+		return ret
+	}
+	headers := uint32(ethHeaderLen + ipv4HeaderLen + 20)
+
+	// packets without payload are discarded
+	assert.Zero(t, run(tcpPacket("10.0.0.1", "10.0.0.2", 34567, 80, nil)))
+	// the first packet with payload is sampled, truncated to the headers plus the payload sample
+	assert.Equal(t, headers+L7SamplePayloadLen, run(tcpPacket("10.0.0.1", "10.0.0.2", 34567, 80, []byte("GET /"))))
+	// next packets in the same direction are discarded
+	assert.Zero(t, run(tcpPacket("10.0.0.1", "10.0.0.2", 34567, 80, []byte("GET /"))))
+	// the first packet in the opposite direction is sampled
+	assert.Equal(t, headers+L7SamplePayloadLen, run(tcpPacket("10.0.0.2", "10.0.0.1", 80, 34567, []byte("HTTP/1.1"))))
+	// IPv6
+	assert.Equal(t, uint32(ethHeaderLen+ipv6HeaderLen+20)+L7SamplePayloadLen,
+		run(tcpPacket("fd00::1", "fd00::2", 34567, 80, []byte("GET /"))))
+	// non-TCP packets are discarded
+	udp := tcpPacket("10.0.0.1", "10.0.0.2", 34568, 53, []byte("foo"))
+	udp[ethHeaderLen+9] = ipProtoUDP
+	assert.Zero(t, run(udp))
+
+	var key [l7KeySize]byte
+	var value [l7SampleValueSize]byte
+	var keys int
+	for it := objs.NetL7Seen.Iterate(); it.Next(&key, &value); {
+		keys++
+	}
+	assert.Equal(t, 3, keys)
+}
@@ -0,0 +1,134 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build arm64
+
+package ebpf
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+
+	"github.com/cilium/ebpf"
+)
+
+type NetDropsDropKeyT struct {
+	SrcIp    [16]uint8
+	DstIp    [16]uint8
+	SrcPort  uint16
+	DstPort  uint16
+	Protocol uint8
+	Pad      [3]uint8
+	Reason   uint32
+}
+
+type NetDropsDropValueT struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// LoadNetDrops returns the embedded CollectionSpec for NetDrops.
+func LoadNetDrops() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_NetDropsBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load NetDrops: %w", err)
+	}
+
+	return spec, err
+}
+
+// LoadNetDropsObjects loads NetDrops and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*NetDropsObjects
+//	*NetDropsPrograms
+//	*NetDropsMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func LoadNetDropsObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := LoadNetDrops()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// NetDropsSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type NetDropsSpecs struct {
+	NetDropsProgramSpecs
+	NetDropsMapSpecs
+}
+
+// NetDropsSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type NetDropsProgramSpecs struct {
+	TpKfreeSkb *ebpf.ProgramSpec `ebpf:"tp_kfree_skb"`
+}
+
+// NetDropsMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type NetDropsMapSpecs struct {
+	NetDrops *ebpf.MapSpec `ebpf:"net_drops"`
+}
+
+// NetDropsObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to LoadNetDropsObjects or ebpf.CollectionSpec.LoadAndAssign.
+type NetDropsObjects struct {
+	NetDropsPrograms
+	NetDropsMaps
+}
+
+func (o *NetDropsObjects) Close() error {
+	return _NetDropsClose(
+		&o.NetDropsPrograms,
+		&o.NetDropsMaps,
+	)
+}
+
+// NetDropsMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to LoadNetDropsObjects or ebpf.CollectionSpec.LoadAndAssign.
+type NetDropsMaps struct {
+	NetDrops *ebpf.Map `ebpf:"net_drops"`
+}
+
+func (m *NetDropsMaps) Close() error {
+	return _NetDropsClose(
+		m.NetDrops,
+	)
+}
+
+// NetDropsPrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to LoadNetDropsObjects or ebpf.CollectionSpec.LoadAndAssign.
+type NetDropsPrograms struct {
+	TpKfreeSkb *ebpf.Program `ebpf:"tp_kfree_skb"`
+}
+
+func (p *NetDropsPrograms) Close() error {
+	return _NetDropsClose(
+		p.TpKfreeSkb,
+	)
+}
+
+func _NetDropsClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed netdrops_bpfel_arm64.o
+var _NetDropsBytes []byte
@@ -0,0 +1,126 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build arm64
+
+package ebpf
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+
+	"github.com/cilium/ebpf"
+)
+
+type NetL7L7KeyT struct {
+	SrcIp   [16]uint8
+	DstIp   [16]uint8
+	SrcPort uint16
+	DstPort uint16
+}
+
+// LoadNetL7 returns the embedded CollectionSpec for NetL7.
+func LoadNetL7() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_NetL7Bytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load NetL7: %w", err)
+	}
+
+	return spec, err
+}
+
+// LoadNetL7Objects loads NetL7 and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*NetL7Objects
+//	*NetL7Programs
+//	*NetL7Maps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func LoadNetL7Objects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := LoadNetL7()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// NetL7Specs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type NetL7Specs struct {
+	NetL7ProgramSpecs
+	NetL7MapSpecs
+}
+
+// NetL7Specs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type NetL7ProgramSpecs struct {
+	SfL7Sample *ebpf.ProgramSpec `ebpf:"sf_l7_sample"`
+}
+
+// NetL7MapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type NetL7MapSpecs struct {
+	NetL7Seen *ebpf.MapSpec `ebpf:"net_l7_seen"`
+}
+
+// NetL7Objects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to LoadNetL7Objects or ebpf.CollectionSpec.LoadAndAssign.
+type NetL7Objects struct {
+	NetL7Programs
+	NetL7Maps
+}
+
+func (o *NetL7Objects) Close() error {
+	return _NetL7Close(
+		&o.NetL7Programs,
+		&o.NetL7Maps,
+	)
+}
+
+// NetL7Maps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to LoadNetL7Objects or ebpf.CollectionSpec.LoadAndAssign.
+type NetL7Maps struct {
+	NetL7Seen *ebpf.Map `ebpf:"net_l7_seen"`
+}
+
+func (m *NetL7Maps) Close() error {
+	return _NetL7Close(
+		m.NetL7Seen,
+	)
+}
+
+// NetL7Programs contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to LoadNetL7Objects or ebpf.CollectionSpec.LoadAndAssign.
+type NetL7Programs struct {
+	SfL7Sample *ebpf.Program `ebpf:"sf_l7_sample"`
+}
+
+func (p *NetL7Programs) Close() error {
+	return _NetL7Close(
+		p.SfL7Sample,
+	)
+}
+
+func _NetL7Close(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed netl7_bpfel_arm64.o
+var _NetL7Bytes []byte
@@ -22,6 +22,7 @@ import (
 	"encoding/binary"
 	"io"
 	"net"
+	"time"
 
 	attr "github.com/grafana/beyla/pkg/export/attributes/names"
 )
@@ -41,6 +42,16 @@ type Record struct {
 
 	// Attrs of the flow record: source/destination, Interface, Beyla IP, etc...
 	Attrs RecordAttrs
+
+	// HandshakeRTT is the approximate time between the SYN and the SYN-ACK of the TCP connection
+	// that is opened by this flow, calculated from the start times of the flows in both
+	// directions. Zero if the handshake hasn't been observed.
+	HandshakeRTT time.Duration
+	// HalfOpen is true if the flow sent a SYN that was never answered
+	HalfOpen bool
+	// DropReason is set if the record accounts packets that were dropped by the kernel,
+	// instead of delivered packets
+	DropReason string
 }
 
 type RecordAttrs struct {
@@ -57,6 +68,17 @@ type RecordAttrs struct {
 	Metadata map[attr.Name]string
 }
 
+// TCP flags, as defined in bpf/flows_common.h
+const (
+	FlagFIN    = 0x01
+	FlagSYN    = 0x02
+	FlagRST    = 0x04
+	FlagACK    = 0x10
+	FlagSYNACK = 0x100
+	FlagFINACK = 0x200
+	FlagRSTACK = 0x400
+)
+
 func NewRecord(
 	key NetFlowId,
 	metrics NetFlowMetrics,
@@ -85,6 +107,33 @@ func (fm *NetFlowMetrics) Accumulate(src *NetFlowMetrics) {
 	fm.Flags |= src.Flags
 }
 
+// IsHandshakeReport returns whether the record does not come from the eBPF tracers, and has been
+// generated in the user space only to report the handshake information of an earlier flow
+func (r *Record) IsHandshakeReport() bool {
+	return r.Metrics.Packets == 0 && (r.HandshakeRTT > 0 || r.HalfOpen)
+}
+
+// IsReset returns whether the flow contains a TCP packet with the RST flag
+func (fm *NetFlowMetrics) IsReset() bool {
+	return fm.Flags&(FlagRST|FlagRSTACK) != 0
+}
+
+// TCPFlags returns the standard TCP control bits of the flow, expanding the custom
+// SYN-ACK, FIN-ACK and RST-ACK flags into their respective bits.
+func (fm *NetFlowMetrics) TCPFlags() uint8 {
+	flags := uint8(fm.Flags)
+	if fm.Flags&FlagSYNACK != 0 {
+		flags |= FlagSYN | FlagACK
+	}
+	if fm.Flags&FlagFINACK != 0 {
+		flags |= FlagFIN | FlagACK
+	}
+	if fm.Flags&FlagRSTACK != 0 {
+		flags |= FlagRST | FlagACK
+	}
+	return flags
+}
+
 // SrcIP is never null. Returned as pointer for efficiency.
 func (fi *NetFlowId) SrcIP() *IPAddr {
 	return (*IPAddr)(&fi.SrcIp.In6U.U6Addr8)
@@ -93,6 +93,8 @@ func RecordGetters(name attr.Name) (attributes.Getter[*Record, attribute.KeyValu
 			}
 			return attribute.Int(string(attr.ServerPort), int(serverPort))
 		}
+	case attr.DropReason:
+		getter = func(r *Record) attribute.KeyValue { return attribute.String(string(attr.DropReason), r.DropReason) }
 	default:
 		getter = func(r *Record) attribute.KeyValue { return attribute.String(string(name), r.Attrs.Metadata[name]) }
 	}
@@ -0,0 +1,58 @@
+// Package ipfix exports the network flows to IPFIX (RFC 7011) or NetFlow v9 (RFC 3954) collectors.
+package ipfix
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	TransportUDP = "udp"
+	TransportTCP = "tcp"
+
+	VersionIPFIX     = "ipfix"
+	VersionNetFlowV9 = "netflow_v9"
+)
+
+// DefaultEnterpriseID is the Private Enterprise Number that is reserved for documentation
+// purposes (RFC 5612). Users that need to avoid collisions with other exporters should
+// override it with their own number.
+const DefaultEnterpriseID = 32473
+
+type Config struct {
+	// Endpoint of the collector, in host:port format. If empty, the exporter is disabled.
+	Endpoint string `yaml:"endpoint" env:"BEYLA_NETWORK_IPFIX_ENDPOINT"`
+	// Transport protocol to send the messages to the collector. Accepted values: "udp" (default) or "tcp".
+	Transport string `yaml:"transport" env:"BEYLA_NETWORK_IPFIX_TRANSPORT"`
+	// Version of the protocol. Accepted values: "ipfix" (default) or "netflow_v9".
+	Version string `yaml:"version" env:"BEYLA_NETWORK_IPFIX_VERSION"`
+	// ObservationDomainID is sent in the header of each message (the Source ID, in NetFlow v9).
+	ObservationDomainID uint32 `yaml:"observation_domain_id" env:"BEYLA_NETWORK_IPFIX_OBSERVATION_DOMAIN_ID"`
+	// EnterpriseID is the Private Enterprise Number of the enterprise-specific information
+	// elements that carry the Kubernetes metadata. Only used by IPFIX.
+	EnterpriseID uint32 `yaml:"enterprise_id" env:"BEYLA_NETWORK_IPFIX_ENTERPRISE_ID"`
+	// TemplateRefresh is the period to resend the templates over UDP. Over TCP, the templates
+	// are sent only once for each connection.
+	TemplateRefresh time.Duration `yaml:"template_refresh" env:"BEYLA_NETWORK_IPFIX_TEMPLATE_REFRESH"`
+}
+
+func (c *Config) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+func (c *Config) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	switch c.Transport {
+	case TransportUDP, TransportTCP:
+	default:
+		return fmt.Errorf("invalid transport %q. Accepted values: %s, %s", c.Transport, TransportUDP, TransportTCP)
+	}
+	switch c.Version {
+	case VersionIPFIX, VersionNetFlowV9:
+	default:
+		return fmt.Errorf("invalid version %q. Accepted values: %s, %s", c.Version, VersionIPFIX, VersionNetFlowV9)
+	}
+	return nil
+}
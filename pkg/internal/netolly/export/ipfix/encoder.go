@@ -0,0 +1,348 @@
+package ipfix
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+	"github.com/grafana/beyla/pkg/internal/netolly/ebpf"
+)
+
+const (
+	ipfixVersion = 10
+	nfv9Version  = 9
+
+	ipfixHeaderLen = 16
+	nfv9HeaderLen  = 20
+	setHeaderLen   = 4
+
+	ipfixTemplateSetID = 2
+	nfv9TemplateSetID  = 0
+
+	templateIPv4 = 256
+	templateIPv6 = 257
+
+	// enterpriseBit marks the enterprise-specific information elements in the IPFIX templates
+	enterpriseBit = 0x8000
+	// variableLength marks the variable-length information elements in the IPFIX templates
+	variableLength = 0xFFFF
+)
+
+// IANA information element identifiers (which match the NetFlow v9 field types)
+const (
+	ieOctetDeltaCount          = 1
+	iePacketDeltaCount         = 2
+	ieProtocolIdentifier       = 4
+	ieTCPControlBits           = 6
+	ieSourceTransportPort      = 7
+	ieSourceIPv4Address        = 8
+	ieIngressInterface         = 10
+	ieDestinationTransportPort = 11
+	ieDestinationIPv4Address   = 12
+	ieEgressInterface          = 14
+	ieLastSwitched             = 21
+	ieFirstSwitched            = 22
+	ieSourceIPv6Address        = 27
+	ieDestinationIPv6Address   = 28
+	ieFlowDirection            = 61
+	ieFlowStartMilliseconds    = 152
+	ieFlowEndMilliseconds      = 153
+)
+
+// enterpriseElements are the Kubernetes attributes that are exported as IPFIX
+// enterprise-specific information elements. The element ID is the position in
+// the slice, plus one.
+var enterpriseElements = []attr.Name{
+	attr.K8sSrcNamespace,
+	attr.K8sSrcName,
+	attr.K8sSrcType,
+	attr.K8sSrcOwnerName,
+	attr.K8sSrcOwnerType,
+	attr.K8sSrcNodeName,
+	attr.K8sDstNamespace,
+	attr.K8sDstName,
+	attr.K8sDstType,
+	attr.K8sDstOwnerName,
+	attr.K8sDstOwnerType,
+	attr.K8sDstNodeName,
+	attr.K8sClusterName,
+}
+
+type field struct {
+	id     uint16
+	length uint16
+	// enterprise is true for the enterprise-specific information elements
+	enterprise bool
+}
+
+// encoder builds the IPFIX or NetFlow v9 messages from the flow records
+type encoder struct {
+	netflowV9    bool
+	domainID     uint32
+	enterpriseID uint32
+	// maxLen of each message. A record that does not fit in an empty message is sent alone.
+	maxLen int
+
+	// sequence is the number of data records sent (IPFIX) or the number of messages sent (NetFlow v9)
+	sequence uint32
+
+	ipv4Fields []field
+	ipv6Fields []field
+
+	// allows replacing the clocks in unit tests
+	wallNow func() time.Time
+	monoNow func() time.Duration
+}
+
+func newEncoder(cfg *Config, maxLen int) *encoder {
+	e := &encoder{
+		netflowV9:    cfg.Version == VersionNetFlowV9,
+		domainID:     cfg.ObservationDomainID,
+		enterpriseID: cfg.EnterpriseID,
+		maxLen:       maxLen,
+		wallNow:      timeNow,
+		monoNow:      monoNow,
+	}
+	e.ipv4Fields = e.fields(ieSourceIPv4Address, ieDestinationIPv4Address, net.IPv4len)
+	e.ipv6Fields = e.fields(ieSourceIPv6Address, ieDestinationIPv6Address, net.IPv6len)
+	return e
+}
+
+func (e *encoder) fields(srcAddr, dstAddr, addrLen uint16) []field {
+	fields := []field{
+		{id: srcAddr, length: addrLen},
+		{id: dstAddr, length: addrLen},
+		{id: ieSourceTransportPort, length: 2},
+		{id: ieDestinationTransportPort, length: 2},
+		{id: ieProtocolIdentifier, length: 1},
+		{id: ieOctetDeltaCount, length: 8},
+		{id: iePacketDeltaCount, length: 8},
+		{id: ieIngressInterface, length: 4},
+		{id: ieEgressInterface, length: 4},
+		{id: ieFlowDirection, length: 1},
+	}
+	if e.netflowV9 {
+		// NetFlow v9 timestamps are the milliseconds since the system boot, as the header SysUptime
+		return append(fields,
+			field{id: ieTCPControlBits, length: 1},
+			field{id: ieFirstSwitched, length: 4},
+			field{id: ieLastSwitched, length: 4},
+		)
+	}
+	fields = append(fields,
+		field{id: ieTCPControlBits, length: 2},
+		field{id: ieFlowStartMilliseconds, length: 8},
+		field{id: ieFlowEndMilliseconds, length: 8},
+	)
+	for i := range enterpriseElements {
+		fields = append(fields, field{id: uint16(i + 1), length: variableLength, enterprise: true})
+	}
+	return fields
+}
+
+func (e *encoder) headerLen() int {
+	if e.netflowV9 {
+		return nfv9HeaderLen
+	}
+	return ipfixHeaderLen
+}
+
+// templates returns a message containing the IPv4 and IPv6 templates
+func (e *encoder) templates() []byte {
+	msg := make([]byte, e.headerLen(), e.maxLen)
+	setStart := len(msg)
+	setID := uint16(ipfixTemplateSetID)
+	if e.netflowV9 {
+		setID = nfv9TemplateSetID
+	}
+	msg = binary.BigEndian.AppendUint16(msg, setID)
+	msg = binary.BigEndian.AppendUint16(msg, 0) // set length, overridden later
+	for _, t := range []struct {
+		id     uint16
+		fields []field
+	}{{id: templateIPv4, fields: e.ipv4Fields}, {id: templateIPv6, fields: e.ipv6Fields}} {
+		msg = binary.BigEndian.AppendUint16(msg, t.id)
+		msg = binary.BigEndian.AppendUint16(msg, uint16(len(t.fields)))
+		for _, f := range t.fields {
+			if f.enterprise {
+				msg = binary.BigEndian.AppendUint16(msg, f.id|enterpriseBit)
+				msg = binary.BigEndian.AppendUint16(msg, f.length)
+				msg = binary.BigEndian.AppendUint32(msg, e.enterpriseID)
+			} else {
+				msg = binary.BigEndian.AppendUint16(msg, f.id)
+				msg = binary.BigEndian.AppendUint16(msg, f.length)
+			}
+		}
+	}
+	msg = e.closeSet(msg, setStart)
+	// the templates are not accounted in the IPFIX sequence number
+	e.writeHeader(msg, 2, 0)
+	return msg
+}
+
+// data returns the messages that encode the passed records, grouped in data sets by
+// their template, and split to not exceed the maximum message length
+func (e *encoder) data(records []*ebpf.Record) [][]byte {
+	if len(records) == 0 {
+		return nil
+	}
+	wallNow, monoNow := e.wallNow(), e.monoNow()
+	var msgs [][]byte
+	var msg, rec []byte
+	var setStart, count int
+	var setTemplate uint16
+	flush := func() {
+		if setStart > 0 {
+			msg = e.closeSet(msg, setStart)
+		}
+		e.writeHeader(msg, count, count)
+		msgs = append(msgs, msg)
+		msg, setStart, setTemplate, count = nil, 0, 0, 0
+	}
+	for _, r := range records {
+		template, fields := uint16(templateIPv6), e.ipv6Fields
+		if r.Id.SrcIP().IP().To4() != nil && r.Id.DstIP().IP().To4() != nil {
+			template, fields = templateIPv4, e.ipv4Fields
+		}
+		rec = e.appendRecord(rec[:0], r, fields, wallNow, monoNow)
+
+		// the current set might need up to 3 bytes of padding
+		needed := len(rec) + 3
+		if template != setTemplate {
+			needed += setHeaderLen
+		}
+		if msg != nil && len(msg)+needed > e.maxLen {
+			flush()
+		}
+		if msg == nil {
+			msg = make([]byte, e.headerLen(), e.maxLen)
+		}
+		if template != setTemplate {
+			if setStart > 0 {
+				msg = e.closeSet(msg, setStart)
+			}
+			setStart, setTemplate = len(msg), template
+			msg = binary.BigEndian.AppendUint16(msg, template)
+			msg = binary.BigEndian.AppendUint16(msg, 0) // set length, overridden later
+		}
+		msg = append(msg, rec...)
+		count++
+	}
+	flush()
+	return msgs
+}
+
+func (e *encoder) appendRecord(
+	dst []byte, r *ebpf.Record, fields []field, wallNow time.Time, monoNow time.Duration,
+) []byte {
+	var ingress, egress uint32
+	if r.Id.IfIndex != ebpf.InterfaceUnset {
+		if r.Metrics.IfaceDirection == ebpf.DirectionEgress {
+			egress = r.Id.IfIndex
+		} else {
+			ingress = r.Id.IfIndex
+		}
+	}
+	for _, f := range fields {
+		if f.enterprise {
+			dst = appendString(dst, r.Attrs.Metadata[enterpriseElements[f.id-1]])
+			continue
+		}
+		switch f.id {
+		case ieSourceIPv4Address:
+			dst = append(dst, r.Id.SrcIP().IP().To4()...)
+		case ieDestinationIPv4Address:
+			dst = append(dst, r.Id.DstIP().IP().To4()...)
+		case ieSourceIPv6Address:
+			dst = append(dst, r.Id.SrcIP()[:]...)
+		case ieDestinationIPv6Address:
+			dst = append(dst, r.Id.DstIP()[:]...)
+		case ieSourceTransportPort:
+			dst = binary.BigEndian.AppendUint16(dst, r.Id.SrcPort)
+		case ieDestinationTransportPort:
+			dst = binary.BigEndian.AppendUint16(dst, r.Id.DstPort)
+		case ieProtocolIdentifier:
+			dst = append(dst, r.Id.TransportProtocol)
+		case ieOctetDeltaCount:
+			dst = binary.BigEndian.AppendUint64(dst, r.Metrics.Bytes)
+		case iePacketDeltaCount:
+			dst = binary.BigEndian.AppendUint64(dst, uint64(r.Metrics.Packets))
+		case ieIngressInterface:
+			dst = binary.BigEndian.AppendUint32(dst, ingress)
+		case ieEgressInterface:
+			dst = binary.BigEndian.AppendUint32(dst, egress)
+		case ieFlowDirection:
+			dst = append(dst, r.Metrics.IfaceDirection)
+		case ieTCPControlBits:
+			if f.length == 1 {
+				dst = append(dst, r.Metrics.TCPFlags())
+			} else {
+				dst = binary.BigEndian.AppendUint16(dst, uint16(r.Metrics.TCPFlags()))
+			}
+		case ieFirstSwitched:
+			dst = binary.BigEndian.AppendUint32(dst, uint32(r.Metrics.StartMonoTimeNs/uint64(time.Millisecond)))
+		case ieLastSwitched:
+			dst = binary.BigEndian.AppendUint32(dst, uint32(r.Metrics.EndMonoTimeNs/uint64(time.Millisecond)))
+		case ieFlowStartMilliseconds:
+			dst = binary.BigEndian.AppendUint64(dst, uint64(wallTime(r.Metrics.StartMonoTimeNs, wallNow, monoNow).UnixMilli()))
+		case ieFlowEndMilliseconds:
+			dst = binary.BigEndian.AppendUint64(dst, uint64(wallTime(r.Metrics.EndMonoTimeNs, wallNow, monoNow).UnixMilli()))
+		}
+	}
+	return dst
+}
+
+// wallTime converts a monotonic timestamp, from the eBPF programs, to wall clock time
+func wallTime(monoNs uint64, wallNow time.Time, monoNow time.Duration) time.Time {
+	return wallNow.Add(-(monoNow - time.Duration(monoNs)))
+}
+
+// appendString encodes a variable-length information element, as described in
+// https://datatracker.ietf.org/doc/html/rfc7011#section-7
+func appendString(dst []byte, s string) []byte {
+	if len(s) > 0xFFFF {
+		s = s[:0xFFFF]
+	}
+	if len(s) < 0xFF {
+		dst = append(dst, uint8(len(s)))
+	} else {
+		dst = append(dst, 0xFF)
+		dst = binary.BigEndian.AppendUint16(dst, uint16(len(s)))
+	}
+	return append(dst, s...)
+}
+
+// closeSet sets the length of the set that starts at the given position. NetFlow v9
+// flowsets are padded to a 4-byte boundary.
+func (e *encoder) closeSet(msg []byte, setStart int) []byte {
+	if e.netflowV9 {
+		for (len(msg)-setStart)%4 != 0 {
+			msg = append(msg, 0)
+		}
+	}
+	binary.BigEndian.PutUint16(msg[setStart+2:], uint16(len(msg)-setStart))
+	return msg
+}
+
+// writeHeader writes the message header, given the number of records in the message and
+// the number of data records, which are accounted in the IPFIX sequence number.
+func (e *encoder) writeHeader(msg []byte, records, dataRecords int) {
+	now := e.wallNow()
+	if e.netflowV9 {
+		binary.BigEndian.PutUint16(msg[0:], nfv9Version)
+		binary.BigEndian.PutUint16(msg[2:], uint16(records))
+		binary.BigEndian.PutUint32(msg[4:], uint32(e.monoNow()/time.Millisecond))
+		binary.BigEndian.PutUint32(msg[8:], uint32(now.Unix()))
+		binary.BigEndian.PutUint32(msg[12:], e.sequence)
+		binary.BigEndian.PutUint32(msg[16:], e.domainID)
+		e.sequence++
+		return
+	}
+	binary.BigEndian.PutUint16(msg[0:], ipfixVersion)
+	binary.BigEndian.PutUint16(msg[2:], uint16(len(msg)))
+	binary.BigEndian.PutUint32(msg[4:], uint32(now.Unix()))
+	binary.BigEndian.PutUint32(msg[8:], e.sequence)
+	binary.BigEndian.PutUint32(msg[12:], e.domainID)
+	e.sequence += uint32(dataRecords)
+}
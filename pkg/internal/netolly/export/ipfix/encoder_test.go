@@ -0,0 +1,277 @@
+package ipfix
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+	"github.com/grafana/beyla/pkg/internal/netolly/ebpf"
+)
+
+var (
+	testWallNow = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	testMonoNow = 100 * time.Second
+)
+
+func testEncoder(version string, maxLen int) *encoder {
+	e := newEncoder(&Config{Version: version, ObservationDomainID: 33, EnterpriseID: DefaultEnterpriseID}, maxLen)
+	e.wallNow = func() time.Time { return testWallNow }
+	e.monoNow = func() time.Duration { return testMonoNow }
+	return e
+}
+
+func testRecord(src, dst string, srcPort uint16) *ebpf.Record {
+	r := &ebpf.Record{NetFlowRecordT: ebpf.NetFlowRecordT{
+		Id: ebpf.NetFlowId{
+			SrcPort: srcPort, DstPort: 80, TransportProtocol: 6, IfIndex: 3,
+		},
+		Metrics: ebpf.NetFlowMetrics{
+			Packets: 7, Bytes: 1234,
+			StartMonoTimeNs: uint64(90 * time.Second), EndMonoTimeNs: uint64(95 * time.Second),
+			Flags:          ebpf.FlagSYNACK,
+			IfaceDirection: ebpf.DirectionEgress,
+		},
+	}}
+	copy(r.Id.SrcIP()[:], net.ParseIP(src).To16())
+	copy(r.Id.DstIP()[:], net.ParseIP(dst).To16())
+	return r
+}
+
+// reader helps decoding the encoded messages
+type reader struct {
+	t   *testing.T
+	buf []byte
+}
+
+func (r *reader) u8() uint8 {
+	require.NotEmpty(r.t, r.buf)
+	v := r.buf[0]
+	r.buf = r.buf[1:]
+	return v
+}
+
+func (r *reader) u16() uint16 {
+	require.GreaterOrEqual(r.t, len(r.buf), 2)
+	v := binary.BigEndian.Uint16(r.buf)
+	r.buf = r.buf[2:]
+	return v
+}
+
+func (r *reader) u32() uint32 {
+	require.GreaterOrEqual(r.t, len(r.buf), 4)
+	v := binary.BigEndian.Uint32(r.buf)
+	r.buf = r.buf[4:]
+	return v
+}
+
+func (r *reader) u64() uint64 {
+	require.GreaterOrEqual(r.t, len(r.buf), 8)
+	v := binary.BigEndian.Uint64(r.buf)
+	r.buf = r.buf[8:]
+	return v
+}
+
+func (r *reader) bytes(n int) []byte {
+	require.GreaterOrEqual(r.t, len(r.buf), n)
+	v := r.buf[:n]
+	r.buf = r.buf[n:]
+	return v
+}
+
+// assertSetLen checks that the set, whose ID has been already read, spans the rest of the message
+func assertSetLen(t *testing.T, r *reader) {
+	t.Helper()
+	remaining := len(r.buf)
+	assert.EqualValues(t, remaining+setHeaderLen-2, r.u16())
+}
+
+func (r *reader) str() string {
+	l := int(r.u8())
+	if l == 0xFF {
+		l = int(r.u16())
+	}
+	return string(r.bytes(l))
+}
+
+func TestIPFIX_Templates(t *testing.T) {
+	e := testEncoder(VersionIPFIX, maxUDPMessageLen)
+	msg := e.templates()
+
+	r := reader{t: t, buf: msg}
+	assert.EqualValues(t, 10, r.u16())
+	assert.EqualValues(t, len(msg), r.u16())
+	assert.EqualValues(t, testWallNow.Unix(), r.u32())
+	assert.EqualValues(t, 0, r.u32())
+	assert.EqualValues(t, 33, r.u32())
+
+	assert.EqualValues(t, 2, r.u16()) // template set
+	assert.EqualValues(t, len(msg)-ipfixHeaderLen, r.u16())
+	for _, tpl := range []struct {
+		id      uint16
+		addrs   [2]uint16
+		addrLen uint16
+	}{{id: 256, addrs: [2]uint16{8, 12}, addrLen: 4}, {id: 257, addrs: [2]uint16{27, 28}, addrLen: 16}} {
+		assert.Equal(t, tpl.id, r.u16())
+		assert.EqualValues(t, 13+len(enterpriseElements), r.u16())
+		assert.Equal(t, tpl.addrs[0], r.u16())
+		assert.Equal(t, tpl.addrLen, r.u16())
+		assert.Equal(t, tpl.addrs[1], r.u16())
+		assert.Equal(t, tpl.addrLen, r.u16())
+		for _, f := range []struct{ id, length uint16 }{
+			{7, 2}, {11, 2}, {4, 1}, {1, 8}, {2, 8}, {10, 4}, {14, 4}, {61, 1}, {6, 2}, {152, 8}, {153, 8},
+		} {
+			assert.Equal(t, f.id, r.u16())
+			assert.Equal(t, f.length, r.u16())
+		}
+		for i := range enterpriseElements {
+			assert.Equal(t, uint16(i+1)|0x8000, r.u16())
+			assert.EqualValues(t, 0xFFFF, r.u16())
+			assert.EqualValues(t, DefaultEnterpriseID, r.u32())
+		}
+	}
+	assert.Empty(t, r.buf)
+}
+
+func TestIPFIX_Data(t *testing.T) {
+	e := testEncoder(VersionIPFIX, maxUDPMessageLen)
+	v4 := testRecord("10.0.0.1", "10.0.0.2", 34567)
+	v4.Attrs.Metadata = map[attr.Name]string{
+		attr.K8sSrcNamespace: "default",
+		attr.K8sSrcName:      "frontend-abcde",
+		attr.K8sDstName:      "backend",
+		attr.K8sClusterName:  "prod",
+	}
+	v6 := testRecord("fd00::1", "fd00::2", 34568)
+	msgs := e.data([]*ebpf.Record{v4, v6})
+	require.Len(t, msgs, 1)
+
+	r := reader{t: t, buf: msgs[0]}
+	assert.EqualValues(t, 10, r.u16())
+	assert.EqualValues(t, len(msgs[0]), r.u16())
+	assert.EqualValues(t, testWallNow.Unix(), r.u32())
+	assert.EqualValues(t, 0, r.u32()) // sequence
+	assert.EqualValues(t, 33, r.u32())
+
+	// IPv4 data set
+	assert.EqualValues(t, 256, r.u16())
+	setLen := int(r.u16())
+	setEnd := len(r.buf) - setLen + setHeaderLen
+	assert.Equal(t, net.IPv4(10, 0, 0, 1).To4(), net.IP(r.bytes(4)))
+	assert.Equal(t, net.IPv4(10, 0, 0, 2).To4(), net.IP(r.bytes(4)))
+	assertCommonFields(t, &r, 34567)
+	assert.Equal(t, []string{
+		"default", "frontend-abcde", "", "", "", "", "", "backend", "", "", "", "", "prod",
+	}, readStrings(&r))
+	assert.Len(t, r.buf, setEnd)
+
+	// IPv6 data set
+	assert.EqualValues(t, 257, r.u16())
+	assertSetLen(t, &r)
+	assert.Equal(t, net.ParseIP("fd00::1"), net.IP(r.bytes(16)))
+	assert.Equal(t, net.ParseIP("fd00::2"), net.IP(r.bytes(16)))
+	assertCommonFields(t, &r, 34568)
+	assert.Equal(t, make([]string, len(enterpriseElements)), readStrings(&r))
+	assert.Empty(t, r.buf)
+
+	// the sequence number accounts the data records that were sent before
+	msgs = e.data([]*ebpf.Record{v4})
+	require.Len(t, msgs, 1)
+	assert.EqualValues(t, 2, binary.BigEndian.Uint32(msgs[0][8:]))
+}
+
+func assertCommonFields(t *testing.T, r *reader, srcPort uint16) {
+	t.Helper()
+	assert.Equal(t, srcPort, r.u16())
+	assert.EqualValues(t, 80, r.u16())
+	assert.EqualValues(t, 6, r.u8())
+	assert.EqualValues(t, 1234, r.u64())
+	assert.EqualValues(t, 7, r.u64())
+	assert.EqualValues(t, 0, r.u32()) // ingress interface
+	assert.EqualValues(t, 3, r.u32()) // egress interface
+	assert.EqualValues(t, ebpf.DirectionEgress, r.u8())
+	assert.EqualValues(t, ebpf.FlagSYN|ebpf.FlagACK, r.u16())
+	assert.EqualValues(t, testWallNow.Add(-10*time.Second).UnixMilli(), r.u64())
+	assert.EqualValues(t, testWallNow.Add(-5*time.Second).UnixMilli(), r.u64())
+}
+
+func readStrings(r *reader) []string {
+	strs := make([]string, 0, len(enterpriseElements))
+	for range enterpriseElements {
+		strs = append(strs, r.str())
+	}
+	return strs
+}
+
+func TestIPFIX_SplitMessages(t *testing.T) {
+	e := testEncoder(VersionIPFIX, 200)
+	var records []*ebpf.Record
+	for i := 0; i < 10; i++ {
+		records = append(records, testRecord("10.0.0.1", "10.0.0.2", uint16(30000+i)))
+	}
+	msgs := e.data(records)
+	require.Greater(t, len(msgs), 1)
+
+	var seq uint32
+	var srcPorts []uint16
+	for _, msg := range msgs {
+		assert.LessOrEqual(t, len(msg), 200)
+		r := reader{t: t, buf: msg}
+		r.bytes(8)
+		assert.Equal(t, seq, r.u32())
+		r.bytes(4)
+		assert.EqualValues(t, 256, r.u16())
+		assertSetLen(t, &r)
+		for len(r.buf) > 0 {
+			r.bytes(8)
+			srcPorts = append(srcPorts, r.u16())
+			r.bytes(2 + 1 + 8 + 8 + 4 + 4 + 1 + 2 + 8 + 8)
+			readStrings(&r)
+			seq++
+		}
+	}
+	assert.Equal(t, []uint16{30000, 30001, 30002, 30003, 30004, 30005, 30006, 30007, 30008, 30009}, srcPorts)
+}
+
+func TestNetFlowV9(t *testing.T) {
+	e := testEncoder(VersionNetFlowV9, maxUDPMessageLen)
+
+	tpl := reader{t: t, buf: e.templates()}
+	assert.EqualValues(t, 9, tpl.u16())
+	assert.EqualValues(t, 2, tpl.u16()) // count
+	assert.EqualValues(t, testMonoNow.Milliseconds(), tpl.u32())
+	assert.EqualValues(t, testWallNow.Unix(), tpl.u32())
+	assert.EqualValues(t, 0, tpl.u32()) // sequence
+	assert.EqualValues(t, 33, tpl.u32())
+	assert.EqualValues(t, 0, tpl.u16()) // template flowset
+	assertSetLen(t, &tpl)
+	assert.EqualValues(t, 256, tpl.u16())
+	assert.EqualValues(t, 13, tpl.u16()) // no enterprise fields
+	tpl.bytes(10 * 4)
+	for _, f := range []struct{ id, length uint16 }{{6, 1}, {22, 4}, {21, 4}} {
+		assert.Equal(t, f.id, tpl.u16())
+		assert.Equal(t, f.length, tpl.u16())
+	}
+
+	msgs := e.data([]*ebpf.Record{testRecord("10.0.0.1", "10.0.0.2", 34567)})
+	require.Len(t, msgs, 1)
+	r := reader{t: t, buf: msgs[0]}
+	assert.EqualValues(t, 9, r.u16())
+	assert.EqualValues(t, 1, r.u16())
+	r.bytes(8)
+	assert.EqualValues(t, 1, r.u32()) // sequence counts the messages
+	r.bytes(4)
+	assert.EqualValues(t, 256, r.u16())
+	// flowsets are padded to 4 bytes
+	assert.EqualValues(t, 4+4+4+2+2+1+8+8+4+4+1+1+4+4+1, r.u16())
+	assert.Len(t, r.buf, 4+4+2+2+1+8+8+4+4+1+1+4+4+1)
+	r.bytes(4 + 4 + 2 + 2 + 1 + 8 + 8 + 4 + 4 + 1)
+	assert.EqualValues(t, ebpf.FlagSYN|ebpf.FlagACK, r.u8())
+	assert.EqualValues(t, 90_000, r.u32())
+	assert.EqualValues(t, 95_000, r.u32())
+	assert.EqualValues(t, 0, r.u8()) // padding
+}
@@ -0,0 +1,129 @@
+package ipfix
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/gavv/monotime"
+	"github.com/mariomac/pipes/pipe"
+
+	"github.com/grafana/beyla/pkg/internal/netolly/ebpf"
+)
+
+const (
+	// maxUDPMessageLen keeps the UDP messages below the usual path MTU, to avoid IP fragmentation
+	maxUDPMessageLen = 1400
+	// maxTCPMessageLen is limited by the 16-bit length of the IPFIX header
+	maxTCPMessageLen = 65535
+
+	dialTimeout = 5 * time.Second
+)
+
+// allows replacing the clocks and timeouts in unit tests
+var (
+	timeNow = time.Now
+	monoNow = monotime.Now
+	// writeTimeout prevents a TCP collector that doesn't read from blocking the exporter forever
+	writeTimeout = 5 * time.Second
+)
+
+func elog() *slog.Logger {
+	return slog.With("component", "ipfix.Exporter")
+}
+
+type exporter struct {
+	cfg     *Config
+	log     *slog.Logger
+	encoder *encoder
+	conn    net.Conn
+	// nextTemplates is the time after which the templates are sent again over UDP
+	nextTemplates time.Time
+}
+
+// ExporterProvider sends the flow records to an IPFIX or NetFlow v9 collector. The records
+// that do not account packets from the eBPF tracers (dropped packets or handshake reports)
+// are not exported.
+func ExporterProvider(cfg *Config) (pipe.FinalFunc[[]*ebpf.Record], error) {
+	if !cfg.Enabled() {
+		// This node is not going to be instantiated. Let the pipes library just ignore it.
+		return pipe.IgnoreFinal[[]*ebpf.Record](), nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid IPFIX configuration: %w", err)
+	}
+	maxLen := maxUDPMessageLen
+	if cfg.Transport == TransportTCP {
+		maxLen = maxTCPMessageLen
+	}
+	e := &exporter{
+		cfg:     cfg,
+		log:     elog().With("endpoint", cfg.Endpoint, "transport", cfg.Transport, "version", cfg.Version),
+		encoder: newEncoder(cfg, maxLen),
+	}
+	return e.run, nil
+}
+
+func (e *exporter) run(in <-chan []*ebpf.Record) {
+	defer e.closeConn()
+	for records := range in {
+		if err := e.export(records); err != nil {
+			e.log.Warn("can't export flows. Reconnecting on the next batch", "error", err)
+			e.closeConn()
+		}
+	}
+}
+
+func (e *exporter) export(records []*ebpf.Record) error {
+	flows := make([]*ebpf.Record, 0, len(records))
+	for _, r := range records {
+		if r.DropReason == "" && !r.IsHandshakeReport() {
+			flows = append(flows, r)
+		}
+	}
+	if len(flows) == 0 {
+		return nil
+	}
+	if e.conn == nil {
+		conn, err := net.DialTimeout(e.cfg.Transport, e.cfg.Endpoint, dialTimeout)
+		if err != nil {
+			return fmt.Errorf("connecting to collector: %w", err)
+		}
+		e.log.Debug("connected to collector")
+		e.conn = conn
+		e.nextTemplates = time.Time{}
+	}
+	// over TCP, the templates are sent once per connection
+	if now := timeNow(); e.nextTemplates.IsZero() ||
+		(e.cfg.Transport == TransportUDP && e.cfg.TemplateRefresh > 0 && now.After(e.nextTemplates)) {
+		if err := e.write(e.encoder.templates()); err != nil {
+			return fmt.Errorf("sending templates: %w", err)
+		}
+		e.nextTemplates = now.Add(e.cfg.TemplateRefresh)
+	}
+	for _, msg := range e.encoder.data(flows) {
+		if err := e.write(msg); err != nil {
+			return fmt.Errorf("sending flows: %w", err)
+		}
+	}
+	return nil
+}
+
+func (e *exporter) write(msg []byte) error {
+	if err := e.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return fmt.Errorf("setting write deadline: %w", err)
+	}
+	_, err := e.conn.Write(msg)
+	return err
+}
+
+func (e *exporter) closeConn() {
+	if e.conn == nil {
+		return
+	}
+	if err := e.conn.Close(); err != nil {
+		e.log.Debug("closing connection", "error", err)
+	}
+	e.conn = nil
+}
@@ -0,0 +1,119 @@
+package ipfix
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/netolly/ebpf"
+)
+
+func TestExporter_UDP(t *testing.T) {
+	collector, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer collector.Close()
+
+	export, err := ExporterProvider(&Config{
+		Endpoint:        collector.LocalAddr().String(),
+		Transport:       TransportUDP,
+		Version:         VersionIPFIX,
+		EnterpriseID:    DefaultEnterpriseID,
+		TemplateRefresh: time.Hour,
+	})
+	require.NoError(t, err)
+	in := make(chan []*ebpf.Record, 10)
+	go export(in)
+	defer close(in)
+
+	in <- []*ebpf.Record{
+		testRecord("10.0.0.1", "10.0.0.2", 34567),
+		// records that are not exported
+		{NetFlowRecordT: testRecord("10.0.0.1", "10.0.0.2", 34568).NetFlowRecordT, DropReason: "NO_SOCKET"},
+		{NetFlowRecordT: ebpf.NetFlowRecordT{Id: testRecord("10.0.0.1", "10.0.0.2", 34569).Id}, HalfOpen: true},
+	}
+	in <- []*ebpf.Record{testRecord("10.0.0.1", "10.0.0.2", 34570)}
+
+	// the templates are sent only before the first batch
+	assert.Equal(t, []uint16{2, 256, 256}, readSetIDs(t, collector, 3))
+	// and the dropped and half-open records are ignored
+	buf := make([]byte, 2000)
+	require.NoError(t, collector.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, _, err = collector.ReadFrom(buf)
+	require.Error(t, err)
+}
+
+func TestExporter_TCPWriteTimeout(t *testing.T) {
+	defer func(orig time.Duration) { writeTimeout = orig }(writeTimeout)
+	writeTimeout = 50 * time.Millisecond
+
+	// GIVEN a TCP collector that accepts connections but never reads from them
+	collector, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer collector.Close()
+	go func() {
+		for {
+			conn, err := collector.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	cfg := &Config{
+		Endpoint:     collector.Addr().String(),
+		Transport:    TransportTCP,
+		Version:      VersionIPFIX,
+		EnterpriseID: DefaultEnterpriseID,
+	}
+	e := &exporter{cfg: cfg, log: elog(), encoder: newEncoder(cfg, maxTCPMessageLen)}
+	defer e.closeConn()
+	records := make([]*ebpf.Record, 0, 2000)
+	for i := 0; i < cap(records); i++ {
+		records = append(records, testRecord("10.0.0.1", "10.0.0.2", uint16(10000+i)))
+	}
+
+	// WHEN the socket buffers are full
+	// THEN the exporter doesn't block forever but returns an error
+	done := make(chan error, 1)
+	go func() {
+		for {
+			if err := e.export(records); err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+	select {
+	case err := <-done:
+		require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	case <-time.After(30 * time.Second):
+		require.Fail(t, "the exporter is blocked writing to the collector")
+	}
+}
+
+func TestExporter_InvalidConfig(t *testing.T) {
+	_, err := ExporterProvider(&Config{Endpoint: "localhost:4739", Transport: "sctp", Version: VersionIPFIX})
+	require.Error(t, err)
+}
+
+// readSetIDs returns the ID of the first set of each received message
+func readSetIDs(t *testing.T, conn net.PacketConn, messages int) []uint16 {
+	t.Helper()
+	var ids []uint16
+	buf := make([]byte, 2000)
+	for i := 0; i < messages; i++ {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		require.Greater(t, n, ipfixHeaderLen+setHeaderLen)
+		require.EqualValues(t, n, binary.BigEndian.Uint16(buf[2:]))
+		ids = append(ids, binary.BigEndian.Uint16(buf[ipfixHeaderLen:]))
+	}
+	return ids
+}
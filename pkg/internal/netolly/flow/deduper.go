@@ -85,7 +85,8 @@ func DeduperProvider(dd *Deduper) (pipe.MiddleFunc[[]*ebpf.Record, []*ebpf.Recor
 			cache.removeExpired()
 			fwd := make([]*ebpf.Record, 0, len(records))
 			for _, record := range records {
-				if cache.isDupe(&record.Id) {
+				// dropped packets are not captured from the interfaces, so they can't be duplicate
+				if record.DropReason == "" && cache.isDupe(&record.Id) {
 					continue
 				}
 				// Before forwarding, unset the non-common fields of deduplicate flows.
@@ -86,6 +86,23 @@ func TestDedupe(t *testing.T) {
 	assert.Equal(t, []*ebpf.Record{unset(oneIf2)}, deduped)
 }
 
+func TestDedupe_Drops(t *testing.T) {
+	input := make(chan []*ebpf.Record, 100)
+	output := make(chan []*ebpf.Record, 100)
+
+	dedupe, err := DeduperProvider(&Deduper{Type: DeduperFirstCome, ExpireTime: time.Minute})
+	require.NoError(t, err)
+	go dedupe(input, output)
+
+	// dropped packets of an already accounted flow are not considered duplicate
+	drop := clone(oneIf1)
+	drop.Id.IfIndex = ebpf.InterfaceUnset
+	drop.DropReason = "NETFILTER_DROP"
+	input <- []*ebpf.Record{clone(oneIf2), drop}
+	assert.Equal(t, []*ebpf.Record{unset(oneIf2), drop},
+		testutil.ReadChannel(t, output, timeout))
+}
+
 func TestDedupe_EvictFlows(t *testing.T) {
 	tm := &timerMock{now: time.Now()}
 	timeNow = tm.Now
@@ -0,0 +1,183 @@
+package flow
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/mariomac/pipes/pipe"
+
+	"github.com/grafana/beyla/pkg/internal/netolly/ebpf"
+)
+
+const tcpProtocol = 6
+
+// maxPendingSYNs limits the memory used to remember the unanswered SYNs, e.g. during a SYN flood.
+// When the limit is reached, the new SYNs are forwarded without tracking their handshake.
+const maxPendingSYNs = 1 << 16
+
+func hslog() *slog.Logger {
+	return slog.With("component", "flow/HandshakeTracker")
+}
+
+// HandshakeTracker pairs the flows that open a TCP connection (their first packet is a SYN)
+// with the flows in the opposite direction that answer them (their first packet is a SYN-ACK),
+// to annotate the handshake round-trip time in the ebpf.Record.
+// The eBPF tracers don't timestamp the SYN and SYN-ACK packets, so the round-trip time is
+// approximated as the difference between the start times of both flows. It is accurate as long
+// as the SYN and the SYN-ACK are the first packets that are aggregated in each flow, but it
+// also accounts the retransmission delay if the first SYN was lost, and it might account
+// other packets if a flow entry was evicted and recreated before the handshake finished.
+type HandshakeTracker struct {
+	// Enabled is false if none of the exporters report handshake metrics
+	Enabled bool
+	// Timeout after which a SYN that hasn't been answered is reported as a half-open connection
+	Timeout time.Duration
+}
+
+// handshakeKey identifies a TCP connection from the point of view of the client,
+// ignoring the interface where it was captured
+type handshakeKey struct {
+	client, server         ebpf.IPAddr
+	clientPort, serverPort uint16
+}
+
+func clientKey(id *ebpf.NetFlowId) handshakeKey {
+	return handshakeKey{
+		client: *id.SrcIP(), server: *id.DstIP(),
+		clientPort: id.SrcPort, serverPort: id.DstPort,
+	}
+}
+
+func serverKey(id *ebpf.NetFlowId) handshakeKey {
+	return handshakeKey{
+		client: *id.DstIP(), server: *id.SrcIP(),
+		clientPort: id.DstPort, serverPort: id.SrcPort,
+	}
+}
+
+// pendingSYN is a SYN flow whose SYN-ACK hasn't been received yet
+type pendingSYN struct {
+	// flow is kept to report the SYN-ACK, or the half-open connection, in later batches.
+	// Its metrics are reset so they are not accounted twice.
+	flow       ebpf.NetFlowRecordT
+	startNs    uint64
+	expiryTime time.Time
+}
+
+type handshakeTracker struct {
+	timeout    time.Duration
+	maxPending int
+	pending    map[handshakeKey]*pendingSYN
+}
+
+// HandshakeTrackerProvider measures the SYN to SYN-ACK latency of the TCP flows. The latency
+// is annotated in the client-to-server flow. If the SYN and the SYN-ACK are received in different
+// batches, an extra client-to-server record, without packets nor bytes, is forwarded to report
+// the latency. SYN flows that aren't answered before the timeout are also forwarded as empty
+// records that are marked as half-open.
+func HandshakeTrackerProvider(ht *HandshakeTracker) (pipe.MiddleFunc[[]*ebpf.Record, []*ebpf.Record], error) {
+	if !ht.Enabled {
+		return pipe.Bypass[[]*ebpf.Record](), nil
+	}
+	tracker := &handshakeTracker{
+		timeout:    ht.Timeout,
+		maxPending: maxPendingSYNs,
+		pending:    map[handshakeKey]*pendingSYN{},
+	}
+	return func(in <-chan []*ebpf.Record, out chan<- []*ebpf.Record) {
+		for records := range in {
+			out <- tracker.track(records)
+		}
+	}, nil
+}
+
+func (t *handshakeTracker) track(records []*ebpf.Record) []*ebpf.Record {
+	now := timeNow()
+	// SYN flows from this batch, which are annotated in place if they are answered in this batch
+	syns := map[handshakeKey]*ebpf.Record{}
+	for _, r := range records {
+		if isSYN(r) {
+			key := clientKey(&r.Id)
+			if _, ok := syns[key]; !ok {
+				syns[key] = r
+			}
+		}
+	}
+	for _, r := range records {
+		if r.Id.TransportProtocol != tcpProtocol || r.Metrics.Initiator != ebpf.InitiatorDst {
+			continue
+		}
+		key := serverKey(&r.Id)
+		if syn, ok := syns[key]; ok {
+			if isSYNACK(r) {
+				syn.HandshakeRTT = rtt(syn.Metrics.StartMonoTimeNs, r.Metrics.StartMonoTimeNs)
+			}
+			delete(syns, key)
+			delete(t.pending, key)
+		} else if p, ok := t.pending[key]; ok {
+			// any answer from the server (e.g. a reset) means that the connection is not half-open
+			if isSYNACK(r) {
+				records = append(records, &ebpf.Record{
+					NetFlowRecordT: p.flow,
+					HandshakeRTT:   rtt(p.startNs, r.Metrics.StartMonoTimeNs),
+				})
+			}
+			delete(t.pending, key)
+		}
+	}
+	// the expired SYNs are removed before storing the new ones, to make room for them
+	expired := 0
+	for key, p := range t.pending {
+		if now.After(p.expiryTime) {
+			records = append(records, &ebpf.Record{NetFlowRecordT: p.flow, HalfOpen: true})
+			delete(t.pending, key)
+			expired++
+		}
+	}
+	if expired > 0 {
+		hslog().Debug("unanswered SYNs reported as half-open", "count", expired, "pending", len(t.pending))
+	}
+	untracked := 0
+	for key, syn := range syns {
+		// retransmitted SYNs keep the time of the first SYN
+		if _, ok := t.pending[key]; ok {
+			continue
+		}
+		if len(t.pending) >= t.maxPending {
+			untracked++
+			continue
+		}
+		p := &pendingSYN{
+			flow:       syn.NetFlowRecordT,
+			startNs:    syn.Metrics.StartMonoTimeNs,
+			expiryTime: now.Add(t.timeout),
+		}
+		p.flow.Metrics.Packets, p.flow.Metrics.Bytes, p.flow.Metrics.Flags = 0, 0, 0
+		t.pending[key] = p
+	}
+	if untracked > 0 {
+		hslog().Debug("too many unanswered SYNs. Not tracking the handshake of the new ones",
+			"count", untracked, "pending", len(t.pending))
+	}
+	return records
+}
+
+// isSYN returns whether the flow record starts with a TCP SYN that opens a connection
+func isSYN(r *ebpf.Record) bool {
+	return r.Id.TransportProtocol == tcpProtocol &&
+		r.Metrics.Initiator == ebpf.InitiatorSrc &&
+		r.Metrics.Flags&ebpf.FlagSYN != 0
+}
+
+// isSYNACK returns whether the flow record answers a TCP SYN. Depending on the flows' source,
+// the SYN-ACK packets are either marked with the SYN flag, or with the SYN-ACK custom flag.
+func isSYNACK(r *ebpf.Record) bool {
+	return r.Metrics.Flags&(ebpf.FlagSYN|ebpf.FlagSYNACK) != 0
+}
+
+func rtt(synNs, synAckNs uint64) time.Duration {
+	if synAckNs <= synNs {
+		return 0
+	}
+	return time.Duration(synAckNs - synNs)
+}
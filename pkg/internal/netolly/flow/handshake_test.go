@@ -0,0 +1,115 @@
+package flow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/netolly/ebpf"
+	"github.com/grafana/beyla/pkg/internal/testutil"
+)
+
+func tcpFlow(srcPort, dstPort uint16, initiator uint8, flags uint16, startNs uint64) *ebpf.Record {
+	r := &ebpf.Record{NetFlowRecordT: ebpf.NetFlowRecordT{Id: ebpf.NetFlowId{
+		TransportProtocol: tcpProtocol, SrcPort: srcPort, DstPort: dstPort,
+	}, Metrics: ebpf.NetFlowMetrics{
+		Packets: 3, Bytes: 300, Flags: flags, Initiator: initiator, StartMonoTimeNs: startNs,
+	}}}
+	return r
+}
+
+func TestHandshakeTracker_SameBatch(t *testing.T) {
+	input := make(chan []*ebpf.Record, 10)
+	output := make(chan []*ebpf.Record, 10)
+	tracker, err := HandshakeTrackerProvider(&HandshakeTracker{Enabled: true, Timeout: time.Minute})
+	require.NoError(t, err)
+	go tracker(input, output)
+
+	syn := tcpFlow(34567, 80, ebpf.InitiatorSrc, ebpf.FlagSYN, 1_000_000)
+	// socket filter flows mark the SYN-ACK with the SYN flag
+	synAck := tcpFlow(80, 34567, ebpf.InitiatorDst, ebpf.FlagSYN, 4_000_000)
+	// flows of connections that were opened before are ignored
+	established := tcpFlow(34568, 80, ebpf.InitiatorSrc, 0, 1_000_000)
+	input <- []*ebpf.Record{syn, synAck, established}
+
+	out := testutil.ReadChannel(t, output, timeout)
+	require.Len(t, out, 3)
+	assert.Equal(t, 3*time.Millisecond, out[0].HandshakeRTT)
+	assert.Zero(t, out[1].HandshakeRTT)
+	assert.Zero(t, out[2].HandshakeRTT)
+}
+
+func TestHandshakeTracker_CrossBatchAndHalfOpen(t *testing.T) {
+	tm := &timerMock{now: time.Now()}
+	timeNow = tm.Now
+	tracker := &handshakeTracker{timeout: 10 * time.Second, maxPending: maxPendingSYNs, pending: map[handshakeKey]*pendingSYN{}}
+
+	answered := tcpFlow(34567, 80, ebpf.InitiatorSrc, ebpf.FlagSYN, 1_000_000)
+	unanswered := tcpFlow(34568, 80, ebpf.InitiatorSrc, ebpf.FlagSYN, 2_000_000)
+	refused := tcpFlow(34569, 80, ebpf.InitiatorSrc, ebpf.FlagSYN, 2_000_000)
+	out := tracker.track([]*ebpf.Record{answered, unanswered, refused})
+	require.Len(t, out, 3)
+	for _, r := range out {
+		assert.Zero(t, r.HandshakeRTT)
+	}
+
+	// WHEN the SYN-ACK arrives in a later batch
+	tm.Add(5 * time.Second)
+	// TC flows mark the SYN-ACK with the SYN-ACK custom flag
+	synAck := tcpFlow(80, 34567, ebpf.InitiatorDst, ebpf.FlagSYNACK, 3_000_000)
+	reset := tcpFlow(80, 34569, ebpf.InitiatorDst, ebpf.FlagRSTACK, 2_500_000)
+	// a retransmitted SYN does not restart the handshake
+	retransmit := tcpFlow(34568, 80, ebpf.InitiatorSrc, ebpf.FlagSYN, 2_500_000_000)
+	out = tracker.track([]*ebpf.Record{synAck, reset, retransmit})
+
+	// THEN an empty record is forwarded to report the handshake RTT
+	require.Len(t, out, 4)
+	assert.Equal(t, uint16(34567), out[3].Id.SrcPort)
+	assert.Equal(t, 2*time.Millisecond, out[3].HandshakeRTT)
+	assert.True(t, out[3].IsHandshakeReport())
+	assert.Zero(t, out[3].Metrics.Packets)
+	assert.True(t, out[1].Metrics.IsReset())
+
+	// WHEN the SYN isn't answered before the timeout
+	tm.Add(6 * time.Second)
+	out = tracker.track([]*ebpf.Record{tcpFlow(34570, 443, ebpf.InitiatorSrc, 0, 1)})
+
+	// THEN an empty record is forwarded to report the half-open connection
+	require.Len(t, out, 2)
+	assert.True(t, out[1].HalfOpen)
+	assert.Equal(t, uint16(34568), out[1].Id.SrcPort)
+	assert.True(t, out[1].IsHandshakeReport())
+	assert.Empty(t, tracker.pending)
+}
+
+func TestHandshakeTracker_MaxPending(t *testing.T) {
+	tm := &timerMock{now: time.Now()}
+	timeNow = tm.Now
+	tracker := &handshakeTracker{timeout: 10 * time.Second, maxPending: 2, pending: map[handshakeKey]*pendingSYN{}}
+
+	// WHEN there are more unanswered SYNs than the pending limit
+	out := tracker.track([]*ebpf.Record{
+		tcpFlow(34567, 80, ebpf.InitiatorSrc, ebpf.FlagSYN, 1_000_000),
+		tcpFlow(34568, 80, ebpf.InitiatorSrc, ebpf.FlagSYN, 1_000_000),
+		tcpFlow(34569, 80, ebpf.InitiatorSrc, ebpf.FlagSYN, 1_000_000),
+	})
+
+	// THEN all the flows are forwarded but only the first SYNs are tracked
+	require.Len(t, out, 3)
+	assert.Len(t, tracker.pending, 2)
+
+	// AND WHEN the pending SYNs expire
+	tm.Add(11 * time.Second)
+	out = tracker.track([]*ebpf.Record{
+		tcpFlow(34570, 80, ebpf.InitiatorSrc, ebpf.FlagSYN, 12_000_000_000),
+	})
+
+	// THEN they are reported as half-open and the new SYNs can be tracked again
+	require.Len(t, out, 3)
+	assert.True(t, out[1].HalfOpen)
+	assert.True(t, out[2].HalfOpen)
+	require.Len(t, tracker.pending, 1)
+	assert.Contains(t, tracker.pending, clientKey(&out[0].Id))
+}
@@ -0,0 +1,53 @@
+package flow
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/mariomac/pipes/pipe"
+
+	"github.com/grafana/beyla/pkg/internal/netolly/ebpf"
+)
+
+func dtlog() *slog.Logger {
+	return slog.With("component", "flow.DropTracer")
+}
+
+type dropsFetcher interface {
+	LookupAndDeleteDrops() []*ebpf.Record
+}
+
+// DropTracer periodically reads the packets that have been dropped by the kernel, and forwards
+// them as flow records whose DropReason is set.
+type DropTracer struct {
+	fetcher         dropsFetcher
+	evictionTimeout time.Duration
+}
+
+func NewDropTracer(fetcher dropsFetcher, evictionTimeout time.Duration) *DropTracer {
+	return &DropTracer{
+		fetcher:         fetcher,
+		evictionTimeout: evictionTimeout,
+	}
+}
+
+func (d *DropTracer) TraceLoop(ctx context.Context) pipe.StartFunc[[]*ebpf.Record] {
+	return func(out chan<- []*ebpf.Record) {
+		evictionTicker := time.NewTicker(d.evictionTimeout)
+		defer evictionTicker.Stop()
+		log := dtlog()
+		for {
+			select {
+			case <-ctx.Done():
+				log.Debug("exiting trace loop due to context cancellation")
+				return
+			case <-evictionTicker.C:
+				if drops := d.fetcher.LookupAndDeleteDrops(); len(drops) > 0 {
+					log.Debug("dropped packets evicted", "len", len(drops))
+					out <- drops
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package flow
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/beyla/pkg/internal/netolly/ebpf"
+	"github.com/grafana/beyla/pkg/internal/testutil"
+)
+
+type fakeDropsFetcher struct {
+	mt    sync.Mutex
+	drops []*ebpf.Record
+}
+
+func (f *fakeDropsFetcher) LookupAndDeleteDrops() []*ebpf.Record {
+	f.mt.Lock()
+	defer f.mt.Unlock()
+	drops := f.drops
+	f.drops = nil
+	return drops
+}
+
+func TestDropTracer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	drop := &ebpf.Record{DropReason: "NETFILTER_DROP"}
+	fetcher := &fakeDropsFetcher{drops: []*ebpf.Record{drop}}
+	out := make(chan []*ebpf.Record, 10)
+	go NewDropTracer(fetcher, 10*time.Millisecond).TraceLoop(ctx)(out)
+
+	assert.Equal(t, []*ebpf.Record{drop}, testutil.ReadChannel(t, out, timeout))
+
+	// empty evictions are not forwarded
+	time.Sleep(50 * time.Millisecond)
+	requireNoEviction(t, out)
+}
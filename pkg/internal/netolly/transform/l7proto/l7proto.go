@@ -0,0 +1,126 @@
+// Package l7proto decorates the network flows with the application protocol of their
+// TCP connections, as detected from the first payload bytes of each connection.
+package l7proto
+
+import (
+	"context"
+	"log/slog"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/mariomac/pipes/pipe"
+
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+	ebpfcommon "github.com/grafana/beyla/pkg/internal/ebpf/common"
+	"github.com/grafana/beyla/pkg/internal/netolly/ebpf"
+)
+
+const tcpProtocol = 6
+
+func plog() *slog.Logger {
+	return slog.With("component", "l7proto.Tagger")
+}
+
+// Sampler provides the first payload of each TCP connection direction
+type Sampler interface {
+	// ReadSample blocks until a payload is sampled. It can periodically return a nil
+	// sample, to let the caller check whether it needs to stop.
+	ReadSample() (*ebpf.PayloadSample, error)
+}
+
+// connKey identifies a direction of a TCP connection
+type connKey struct {
+	src, dst         ebpf.IPAddr
+	srcPort, dstPort uint16
+}
+
+// Tagger classifies the payload samples of the TCP connections, and remembers the
+// application protocol of each connection.
+type Tagger struct {
+	sampler Sampler
+	// protocols of the connections, stored for both directions. The cache is accessed
+	// concurrently from the classification loop and the pipeline node.
+	protocols *lru.Cache[connKey, string]
+	classify  func(payload []byte) string
+}
+
+// NewTagger creates a Tagger that remembers the protocol of up to cacheLen connection directions
+func NewTagger(sampler Sampler, cacheLen int) (*Tagger, error) {
+	protocols, err := lru.New[connKey, string](cacheLen)
+	if err != nil {
+		return nil, err
+	}
+	return &Tagger{sampler: sampler, protocols: protocols, classify: ebpfcommon.ClassifyPayload}, nil
+}
+
+// ClassifyLoop reads and classifies the payload samples until the context is cancelled
+func (t *Tagger) ClassifyLoop(ctx context.Context) {
+	log := plog()
+	log.Debug("starting classification loop")
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debug("stopping classification loop")
+			return
+		default:
+		}
+		sample, err := t.sampler.ReadSample()
+		if err != nil {
+			// the sampler is closed when the context is cancelled
+			if ctx.Err() == nil {
+				log.Warn("can't read payload samples. Stopping classification", "error", err)
+			}
+			return
+		}
+		if sample != nil {
+			t.tagConnection(sample)
+		}
+	}
+}
+
+// tagConnection remembers the protocol of the connection for both directions. The first
+// detected protocol prevails, as the sample from the other direction (e.g. a server
+// greeting) might be classified less accurately.
+func (t *Tagger) tagConnection(s *ebpf.PayloadSample) {
+	protocol := t.classify(s.Payload)
+	if protocol == "" {
+		return
+	}
+	t.protocols.PeekOrAdd(connKey{src: s.SrcIP, dst: s.DstIP, srcPort: s.SrcPort, dstPort: s.DstPort}, protocol)
+	t.protocols.PeekOrAdd(connKey{src: s.DstIP, dst: s.SrcIP, srcPort: s.DstPort, dstPort: s.SrcPort}, protocol)
+}
+
+func (t *Tagger) decorate(flow *ebpf.Record) {
+	if flow.Id.TransportProtocol != tcpProtocol {
+		return
+	}
+	protocol, ok := t.protocols.Get(connKey{
+		src: *flow.Id.SrcIP(), dst: *flow.Id.DstIP(),
+		srcPort: flow.Id.SrcPort, dstPort: flow.Id.DstPort,
+	})
+	if !ok {
+		return
+	}
+	if flow.Attrs.Metadata == nil {
+		flow.Attrs.Metadata = map[attr.Name]string{}
+	}
+	flow.Attrs.Metadata[attr.L7Protocol] = protocol
+}
+
+// DecoratorProvider sets the "l7.protocol" attribute of the TCP flows whose protocol has
+// been detected. The node is bypassed if the tagger is nil.
+func DecoratorProvider(t *Tagger) (pipe.MiddleFunc[[]*ebpf.Record, []*ebpf.Record], error) {
+	if t == nil {
+		// This node is not going to be instantiated. Let the pipes library just bypassing it.
+		return pipe.Bypass[[]*ebpf.Record](), nil
+	}
+	return func(in <-chan []*ebpf.Record, out chan<- []*ebpf.Record) {
+		plog().Debug("starting node")
+		for flows := range in {
+			for _, flow := range flows {
+				t.decorate(flow)
+			}
+			out <- flows
+		}
+		plog().Debug("stopping node")
+	}, nil
+}
@@ -0,0 +1,98 @@
+package l7proto
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mariomac/guara/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+	"github.com/grafana/beyla/pkg/internal/netolly/ebpf"
+	"github.com/grafana/beyla/pkg/internal/testutil"
+)
+
+const timeout = 5 * time.Second
+
+type fakeSampler struct {
+	samples chan *ebpf.PayloadSample
+}
+
+func (f *fakeSampler) ReadSample() (*ebpf.PayloadSample, error) {
+	select {
+	case s := <-f.samples:
+		return s, nil
+	case <-time.After(10 * time.Millisecond):
+		return nil, nil
+	}
+}
+
+func ipAddr(ip string) ebpf.IPAddr {
+	var addr ebpf.IPAddr
+	copy(addr[:], net.ParseIP(ip).To16())
+	return addr
+}
+
+func flow(src, dst string, srcPort, dstPort uint16, protocol uint8) *ebpf.Record {
+	r := &ebpf.Record{NetFlowRecordT: ebpf.NetFlowRecordT{Id: ebpf.NetFlowId{
+		SrcPort: srcPort, DstPort: dstPort, TransportProtocol: protocol,
+	}}}
+	*r.Id.SrcIP() = ipAddr(src)
+	*r.Id.DstIP() = ipAddr(dst)
+	return r
+}
+
+func TestTagger(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sampler := &fakeSampler{samples: make(chan *ebpf.PayloadSample, 10)}
+	tagger, err := NewTagger(sampler, 100)
+	require.NoError(t, err)
+	go tagger.ClassifyLoop(ctx)
+
+	// server greeting, whose protocol is unknown
+	sampler.samples <- &ebpf.PayloadSample{
+		SrcIP: ipAddr("10.0.0.2"), DstIP: ipAddr("10.0.0.1"), SrcPort: 80, DstPort: 34567,
+		Payload: []byte("hello"),
+	}
+	sampler.samples <- &ebpf.PayloadSample{
+		SrcIP: ipAddr("10.0.0.1"), DstIP: ipAddr("10.0.0.2"), SrcPort: 34567, DstPort: 80,
+		Payload: []byte("GET / HTTP/1.1\r\n"),
+	}
+	sampler.samples <- &ebpf.PayloadSample{
+		SrcIP: ipAddr("10.0.0.1"), DstIP: ipAddr("10.0.0.3"), SrcPort: 34568, DstPort: 443,
+		Payload: []byte{0x16, 0x03, 0x01, 0x02, 0x00, 0x01, 0x00, 0x01, 0xfc},
+	}
+	// the first detected protocol prevails
+	sampler.samples <- &ebpf.PayloadSample{
+		SrcIP: ipAddr("10.0.0.3"), DstIP: ipAddr("10.0.0.1"), SrcPort: 443, DstPort: 34568,
+		Payload: []byte("HTTP/1.1 200 OK\r\n"),
+	}
+	test.Eventually(t, timeout, func(t require.TestingT) {
+		require.Equal(t, 4, tagger.protocols.Len())
+	})
+
+	in := make(chan []*ebpf.Record, 10)
+	out := make(chan []*ebpf.Record, 10)
+	decorator, err := DecoratorProvider(tagger)
+	require.NoError(t, err)
+	go decorator(in, out)
+	in <- []*ebpf.Record{
+		flow("10.0.0.1", "10.0.0.2", 34567, 80, 6),
+		flow("10.0.0.2", "10.0.0.1", 80, 34567, 6),
+		flow("10.0.0.3", "10.0.0.1", 443, 34568, 6),
+		flow("10.0.0.1", "10.0.0.4", 34569, 80, 6),
+		flow("10.0.0.1", "10.0.0.2", 34567, 80, 17),
+	}
+	decorated := testutil.ReadChannel(t, out, timeout)
+	require.Len(t, decorated, 5)
+	assert.Equal(t, "http", decorated[0].Attrs.Metadata[attr.L7Protocol])
+	assert.Equal(t, "http", decorated[1].Attrs.Metadata[attr.L7Protocol])
+	assert.Equal(t, "tls", decorated[2].Attrs.Metadata[attr.L7Protocol])
+	// unknown connections and non-TCP flows are not decorated
+	assert.Empty(t, decorated[3].Attrs.Metadata)
+	assert.Empty(t, decorated[4].Attrs.Metadata)
+}
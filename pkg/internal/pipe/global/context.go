@@ -5,6 +5,7 @@ import (
 	"github.com/grafana/beyla/pkg/internal/connector"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
 	kube2 "github.com/grafana/beyla/pkg/internal/kube"
+	"github.com/grafana/beyla/pkg/internal/profile"
 	"github.com/grafana/beyla/pkg/internal/transform/kube"
 )
 
@@ -35,4 +36,6 @@ type AppO11y struct {
 	ReportRoutes bool
 	// K8sDatabase provides access to shared kubernetes metadata
 	K8sDatabase *kube.Database
+	// Profiler samples the CPU of the instrumented processes. It is nil if profiling is disabled.
+	Profiler *profile.Profiler
 }
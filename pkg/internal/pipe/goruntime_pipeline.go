@@ -0,0 +1,79 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mariomac/pipes/pipe"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/export/otel"
+	"github.com/grafana/beyla/pkg/export/prom"
+	"github.com/grafana/beyla/pkg/internal/discover"
+	"github.com/grafana/beyla/pkg/internal/infraolly/goruntime"
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// goRuntimeSubPipeline is part of the Application Observability pipeline. It periodically
+// reads the Go runtime statistics that are accounted by the eBPF probes for each instrumented
+// Go process, and exports them as metrics.
+type goRuntimeSubPipeline struct {
+	Collector  pipe.Start[[]*goruntime.Status]
+	OtelExport pipe.Final[[]*goruntime.Status]
+	PromExport pipe.Final[[]*goruntime.Status]
+}
+
+func goRuntimeCollect(sp *goRuntimeSubPipeline) *pipe.Start[[]*goruntime.Status] {
+	return &sp.Collector
+}
+func goRuntimeOtelExport(sp *goRuntimeSubPipeline) *pipe.Final[[]*goruntime.Status] {
+	return &sp.OtelExport
+}
+func goRuntimePromExport(sp *goRuntimeSubPipeline) *pipe.Final[[]*goruntime.Status] {
+	return &sp.PromExport
+}
+
+func (sp *goRuntimeSubPipeline) Connect() {
+	sp.Collector.SendTo(sp.OtelExport, sp.PromExport)
+}
+
+// GoRuntimeSubPipelineProvider returns a Final node that collects and exports the Go runtime
+// metrics in an internal pipeline. It is manually connected through a channel
+func GoRuntimeSubPipelineProvider(ctx context.Context, ctxInfo *global.ContextInfo, cfg *beyla.Config) pipe.FinalProvider[[]request.Span] {
+	return func() (pipe.FinalFunc[[]request.Span], error) {
+		if !cfg.GoRuntimeMetricsEnabled() {
+			return pipe.IgnoreFinal[[]request.Span](), nil
+		}
+		connectorChan := make(chan []request.Span, cfg.ChannelBufferLen)
+		var connector <-chan []request.Span = connectorChan
+		nb := pipe.NewBuilder(&goRuntimeSubPipeline{}, pipe.ChannelBufferLen(cfg.ChannelBufferLen))
+		pipe.AddStartProvider(nb, goRuntimeCollect, goruntime.NewCollectorProvider(ctx, &connector,
+			&goruntime.CollectConfig{
+				Interval: cfg.Processes.Interval,
+				PinPath:  discover.BuildPinPath(cfg),
+			}))
+		pipe.AddFinalProvider(nb, goRuntimeOtelExport, otel.GoRuntimeMetricsExporterProvider(ctx, ctxInfo,
+			&otel.GoRuntimeMetricsConfig{
+				Metrics:            &cfg.Metrics,
+				AttributeSelectors: cfg.Attributes.Select,
+			}))
+		pipe.AddFinalProvider(nb, goRuntimePromExport, prom.GoRuntimePrometheusEndpoint(ctx, ctxInfo,
+			&prom.GoRuntimePrometheusConfig{
+				Metrics:            &cfg.Prometheus,
+				AttributeSelectors: cfg.Attributes.Select,
+			}))
+
+		runner, err := nb.Build()
+		if err != nil {
+			return nil, fmt.Errorf("creating Go runtime subpipeline: %w", err)
+		}
+		return func(in <-chan []request.Span) {
+			// connect the input channel of this final node to the input of the
+			// Go runtime collector
+			connector = in
+			runner.Start()
+			<-ctx.Done()
+		}, nil
+	}
+}
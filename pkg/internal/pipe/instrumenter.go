@@ -15,6 +15,7 @@ import (
 	"github.com/grafana/beyla/pkg/internal/filter"
 	"github.com/grafana/beyla/pkg/internal/imetrics"
 	"github.com/grafana/beyla/pkg/internal/pipe/global"
+	"github.com/grafana/beyla/pkg/internal/profile"
 	"github.com/grafana/beyla/pkg/internal/request"
 	"github.com/grafana/beyla/pkg/internal/traces"
 	"github.com/grafana/beyla/pkg/transform"
@@ -27,6 +28,8 @@ type nodesMap struct {
 
 	// Routes is an optional pipe. If not enabled, data will be bypassed to the next stage in the pipeline.
 	Routes pipe.Middle[[]request.Span, []request.Span]
+	// RedisKeys is an optional pipe that decorates the Redis spans with the pattern of their keys.
+	RedisKeys pipe.Middle[[]request.Span, []request.Span]
 
 	// Kubernetes is an optional pipe. If not enabled, data will be bypassed to the exporters.
 	Kubernetes pipe.Middle[[]request.Span, []request.Span]
@@ -35,13 +38,24 @@ type nodesMap struct {
 
 	AttributeFilter pipe.Middle[[]request.Span, []request.Span]
 
+	// TailSampler is an optional pipe. If not enabled, data will be bypassed to the exporters.
+	TailSampler pipe.Middle[[]request.Span, []request.Span]
+
 	AlloyTraces pipe.Final[[]request.Span]
 	Metrics     pipe.Final[[]request.Span]
 	Traces      pipe.Final[[]request.Span]
 	Prometheus  pipe.Final[[]request.Span]
 	Printer     pipe.Final[[]request.Span]
+	SpanStream  pipe.Final[[]request.Span]
+
+	ProcessReport   pipe.Final[[]request.Span]
+	SLOReport       pipe.Final[[]request.Span]
+	GoRuntimeReport pipe.Final[[]request.Span]
+	TCPHealthReport pipe.Final[[]request.Span]
 
-	ProcessReport pipe.Final[[]request.Span]
+	// Profiler links the CPU profile samples to the spans. It receives the spans before
+	// the tail sampling, whose decision delay would otherwise arrive too late for the linking.
+	Profiler pipe.Final[[]request.Span]
 }
 
 // Connect must specify how the above nodes are connected. Nodes that are disabled
@@ -49,24 +63,33 @@ type nodesMap struct {
 // will directly connect TracesReader to Kubernetes node).
 func (n *nodesMap) Connect() {
 	n.TracesReader.SendTo(n.Routes)
-	n.Routes.SendTo(n.Kubernetes)
+	n.Routes.SendTo(n.RedisKeys)
+	n.RedisKeys.SendTo(n.Kubernetes)
 	n.Kubernetes.SendTo(n.NameResolver)
 	n.NameResolver.SendTo(n.AttributeFilter)
-	n.AttributeFilter.SendTo(n.AlloyTraces, n.Metrics, n.Traces, n.Prometheus, n.Printer, n.ProcessReport)
+	n.AttributeFilter.SendTo(n.TailSampler, n.Profiler)
+	n.TailSampler.SendTo(n.AlloyTraces, n.Metrics, n.Traces, n.Prometheus, n.Printer, n.SpanStream, n.ProcessReport, n.SLOReport, n.GoRuntimeReport, n.TCPHealthReport)
 }
 
 // accessor functions to each field. Grouped here for code brevity during the pipeline build
 func tracesReader(n *nodesMap) *pipe.Start[[]request.Span]                  { return &n.TracesReader }
 func router(n *nodesMap) *pipe.Middle[[]request.Span, []request.Span]       { return &n.Routes }
+func redisKeys(n *nodesMap) *pipe.Middle[[]request.Span, []request.Span]    { return &n.RedisKeys }
 func kubernetes(n *nodesMap) *pipe.Middle[[]request.Span, []request.Span]   { return &n.Kubernetes }
 func nameResolver(n *nodesMap) *pipe.Middle[[]request.Span, []request.Span] { return &n.NameResolver }
 func attrFilter(n *nodesMap) *pipe.Middle[[]request.Span, []request.Span]   { return &n.AttributeFilter }
+func tailSampler(n *nodesMap) *pipe.Middle[[]request.Span, []request.Span]  { return &n.TailSampler }
 func alloyTraces(n *nodesMap) *pipe.Final[[]request.Span]                   { return &n.AlloyTraces }
 func otelMetrics(n *nodesMap) *pipe.Final[[]request.Span]                   { return &n.Metrics }
 func otelTraces(n *nodesMap) *pipe.Final[[]request.Span]                    { return &n.Traces }
 func printer(n *nodesMap) *pipe.Final[[]request.Span]                       { return &n.Printer }
+func spanStream(n *nodesMap) *pipe.Final[[]request.Span]                    { return &n.SpanStream }
+func profiler(n *nodesMap) *pipe.Final[[]request.Span]                      { return &n.Profiler }
 func prometheus(n *nodesMap) *pipe.Final[[]request.Span]                    { return &n.Prometheus }
 func processReport(n *nodesMap) *pipe.Final[[]request.Span]                 { return &n.ProcessReport }
+func sloReport(n *nodesMap) *pipe.Final[[]request.Span]                     { return &n.SLOReport }
+func goRuntimeReport(n *nodesMap) *pipe.Final[[]request.Span]               { return &n.GoRuntimeReport }
+func tcpHealthReport(n *nodesMap) *pipe.Final[[]request.Span]               { return &n.TCPHealthReport }
 
 // builder with injectable instantiators for unit testing
 type graphFunctions struct {
@@ -106,23 +129,42 @@ func newGraphBuilder(ctx context.Context, config *beyla.Config, ctxInfo *global.
 		TracesInput: gb.tracesCh,
 	}))
 
-	pipe.AddMiddleProvider(gnb, router, transform.RoutesProvider(config.Routes))
+	pipe.AddMiddleProvider(gnb, router, transform.ReloadableRoutesProvider(ctx, config.Routes,
+		beyla.SubscribeReloads(config.Reloads, func(c *beyla.Config) *transform.RoutesConfig { return c.Routes })))
+	pipe.AddMiddleProvider(gnb, redisKeys, transform.RedisKeysProvider(&config.RedisKeys))
 	pipe.AddMiddleProvider(gnb, kubernetes, transform.KubeDecoratorProvider(ctx, &config.Attributes.Kubernetes, ctxInfo))
 	pipe.AddMiddleProvider(gnb, nameResolver, transform.NameResolutionProvider(gb.ctxInfo, config.NameResolver))
-	pipe.AddMiddleProvider(gnb, attrFilter, filter.ByAttribute(config.Filters.Application, spanPtrPromGetters))
+	pipe.AddMiddleProvider(gnb, attrFilter, filter.ByReloadableAttribute(config.Filters.Application,
+		beyla.SubscribeReloads(config.Reloads, func(c *beyla.Config) filter.AttributeFamilyConfig { return c.Filters.Application }),
+		spanPtrPromGetters))
+	pipe.AddMiddleProvider(gnb, tailSampler, transform.TailSamplerProvider(&config.TailSampling))
 	config.Metrics.Grafana = &gb.config.Grafana.OTLP
 	pipe.AddFinalProvider(gnb, otelMetrics, otel.ReportMetrics(ctx, gb.ctxInfo, &config.Metrics, config.Attributes.Select))
 	config.Traces.Grafana = &gb.config.Grafana.OTLP
-	pipe.AddFinalProvider(gnb, otelTraces, otel.TracesReceiver(ctx, config.Traces, gb.ctxInfo, config.Attributes.Select))
+	pipe.AddFinalProvider(gnb, otelTraces, otel.ReloadableTracesReceiver(ctx, config.Traces, gb.ctxInfo, config.Attributes.Select,
+		beyla.SubscribeReloads(config.Reloads, attributesSelection)))
 	pipe.AddFinalProvider(gnb, prometheus, prom.PrometheusEndpoint(ctx, gb.ctxInfo, &config.Prometheus, config.Attributes.Select))
-	pipe.AddFinalProvider(gnb, alloyTraces, alloy.TracesReceiver(ctx, gb.ctxInfo, &config.TracesReceiver, config.Attributes.Select))
+	pipe.AddFinalProvider(gnb, alloyTraces, alloy.ReloadableTracesReceiver(ctx, gb.ctxInfo, &config.TracesReceiver, config.Attributes.Select,
+		beyla.SubscribeReloads(config.Reloads, attributesSelection)))
 
 	pipe.AddFinalProvider(gnb, printer, debug.PrinterNode(config.TracePrinter))
+	pipe.AddFinalProvider(gnb, spanStream, debug.SpanStreamNode(ctx, &config.SpanStream))
 
 	// process subpipeline will start another pipeline only to collect and export data
 	// about the processes of an instrumented application
 	pipe.AddFinalProvider(gnb, processReport, SubPipelineProvider(ctx, ctxInfo, config))
 
+	// SLO subpipeline evaluates the error budget of the Service Level Objectives, if any
+	pipe.AddFinalProvider(gnb, sloReport, SLOSubPipelineProvider(ctx, ctxInfo, config))
+
+	// Go runtime subpipeline reads the runtime statistics of the instrumented Go processes
+	pipe.AddFinalProvider(gnb, goRuntimeReport, GoRuntimeSubPipelineProvider(ctx, ctxInfo, config))
+
+	// TCP health subpipeline reads the TCP connection statistics of the instrumented processes
+	pipe.AddFinalProvider(gnb, tcpHealthReport, TCPHealthSubPipelineProvider(ctx, ctxInfo, config))
+
+	pipe.AddFinalProvider(gnb, profiler, profile.SpansLinker(ctxInfo.AppO11y.Profiler))
+
 	// The returned builder later invokes its "Build" function that, given
 	// the contents of the nodesMap struct, will instantiate
 	// and interconnect each node according to the SendTo invocations in the
@@ -155,6 +197,12 @@ func (i *Instrumenter) Run(ctx context.Context) {
 	<-i.graph.Done()
 }
 
+// attributesSelection returns the section of the configuration that is notified to the
+// traces exporters when the configuration is reloaded
+func attributesSelection(c *beyla.Config) attributes.Selection {
+	return c.Attributes.Select
+}
+
 // spanPtrPromGetters adapts the invocation of SpanPromGetters to work with a request.Span value
 // instead of a *request.Span pointer. This is a convenience method created to avoid having to
 // rewrite the pipeline types from []request.Span types to []*request.Span
@@ -0,0 +1,72 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mariomac/pipes/pipe"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/export/otel"
+	"github.com/grafana/beyla/pkg/export/prom"
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/slo"
+)
+
+// sloSubPipeline is part of the Application Observability pipeline. It is only
+// activated if any Service Level Objective is defined.
+type sloSubPipeline struct {
+	Evaluator  pipe.Start[[]*slo.Status]
+	OtelExport pipe.Final[[]*slo.Status]
+	PromExport pipe.Final[[]*slo.Status]
+}
+
+func sloEvaluator(sp *sloSubPipeline) *pipe.Start[[]*slo.Status]  { return &sp.Evaluator }
+func sloOtelExport(sp *sloSubPipeline) *pipe.Final[[]*slo.Status] { return &sp.OtelExport }
+func sloPromExport(sp *sloSubPipeline) *pipe.Final[[]*slo.Status] { return &sp.PromExport }
+
+func (sp *sloSubPipeline) Connect() {
+	sp.Evaluator.SendTo(sp.OtelExport, sp.PromExport)
+}
+
+// the sub-pipe is enabled only if there are SLOs defined and a metrics exporter enabled
+func isSLOSubPipeEnabled(cfg *beyla.Config) bool {
+	return cfg.SLO.Enabled() && (cfg.Metrics.EndpointEnabled() || cfg.Prometheus.EndpointEnabled())
+}
+
+// SLOSubPipelineProvider returns a Final node that evaluates the Service Level Objectives
+// in an internal pipeline. It is manually connected through a channel
+func SLOSubPipelineProvider(ctx context.Context, ctxInfo *global.ContextInfo, cfg *beyla.Config) pipe.FinalProvider[[]request.Span] {
+	return func() (pipe.FinalFunc[[]request.Span], error) {
+		if !isSLOSubPipeEnabled(cfg) {
+			return pipe.IgnoreFinal[[]request.Span](), nil
+		}
+		connectorChan := make(chan []request.Span, cfg.ChannelBufferLen)
+		var connector <-chan []request.Span = connectorChan
+		nb := pipe.NewBuilder(&sloSubPipeline{}, pipe.ChannelBufferLen(cfg.ChannelBufferLen))
+		pipe.AddStartProvider(nb, sloEvaluator, slo.EvaluatorProvider(ctx, &connector, &cfg.SLO))
+		pipe.AddFinalProvider(nb, sloOtelExport, otel.SLOMetricsExporterProvider(ctx, ctxInfo,
+			&otel.SLOMetricsConfig{
+				Metrics: &cfg.Metrics,
+				SLO:     &cfg.SLO,
+			}))
+		pipe.AddFinalProvider(nb, sloPromExport, prom.SLOPrometheusEndpoint(ctx, ctxInfo,
+			&prom.SLOPrometheusConfig{
+				Metrics: &cfg.Prometheus,
+				SLO:     &cfg.SLO,
+			}))
+
+		runner, err := nb.Build()
+		if err != nil {
+			return nil, fmt.Errorf("creating SLO subpipeline: %w", err)
+		}
+		return func(in <-chan []request.Span) {
+			// connect the input channel of this final node to the input of the
+			// SLO evaluator
+			connector = in
+			runner.Start()
+			<-ctx.Done()
+		}, nil
+	}
+}
@@ -0,0 +1,79 @@
+package pipe
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mariomac/pipes/pipe"
+
+	"github.com/grafana/beyla/pkg/beyla"
+	"github.com/grafana/beyla/pkg/export/otel"
+	"github.com/grafana/beyla/pkg/export/prom"
+	"github.com/grafana/beyla/pkg/internal/discover"
+	"github.com/grafana/beyla/pkg/internal/infraolly/tcphealth"
+	"github.com/grafana/beyla/pkg/internal/pipe/global"
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// tcpHealthSubPipeline is part of the Application Observability pipeline. It periodically
+// reads the TCP connection statistics that are accounted by the eBPF probes for each
+// instrumented process, and exports them as metrics.
+type tcpHealthSubPipeline struct {
+	Collector  pipe.Start[[]*tcphealth.Status]
+	OtelExport pipe.Final[[]*tcphealth.Status]
+	PromExport pipe.Final[[]*tcphealth.Status]
+}
+
+func tcpHealthCollect(sp *tcpHealthSubPipeline) *pipe.Start[[]*tcphealth.Status] {
+	return &sp.Collector
+}
+func tcpHealthOtelExport(sp *tcpHealthSubPipeline) *pipe.Final[[]*tcphealth.Status] {
+	return &sp.OtelExport
+}
+func tcpHealthPromExport(sp *tcpHealthSubPipeline) *pipe.Final[[]*tcphealth.Status] {
+	return &sp.PromExport
+}
+
+func (sp *tcpHealthSubPipeline) Connect() {
+	sp.Collector.SendTo(sp.OtelExport, sp.PromExport)
+}
+
+// TCPHealthSubPipelineProvider returns a Final node that collects and exports the TCP connection
+// health metrics in an internal pipeline. It is manually connected through a channel
+func TCPHealthSubPipelineProvider(ctx context.Context, ctxInfo *global.ContextInfo, cfg *beyla.Config) pipe.FinalProvider[[]request.Span] {
+	return func() (pipe.FinalFunc[[]request.Span], error) {
+		if !cfg.TCPConnectionMetricsEnabled() {
+			return pipe.IgnoreFinal[[]request.Span](), nil
+		}
+		connectorChan := make(chan []request.Span, cfg.ChannelBufferLen)
+		var connector <-chan []request.Span = connectorChan
+		nb := pipe.NewBuilder(&tcpHealthSubPipeline{}, pipe.ChannelBufferLen(cfg.ChannelBufferLen))
+		pipe.AddStartProvider(nb, tcpHealthCollect, tcphealth.NewCollectorProvider(ctx, &connector,
+			&tcphealth.CollectConfig{
+				Interval: cfg.Processes.Interval,
+				PinPath:  discover.BuildPinPath(cfg),
+			}))
+		pipe.AddFinalProvider(nb, tcpHealthOtelExport, otel.TCPHealthMetricsExporterProvider(ctx, ctxInfo,
+			&otel.TCPHealthMetricsConfig{
+				Metrics:            &cfg.Metrics,
+				AttributeSelectors: cfg.Attributes.Select,
+			}))
+		pipe.AddFinalProvider(nb, tcpHealthPromExport, prom.TCPHealthPrometheusEndpoint(ctx, ctxInfo,
+			&prom.TCPHealthPrometheusConfig{
+				Metrics:            &cfg.Prometheus,
+				AttributeSelectors: cfg.Attributes.Select,
+			}))
+
+		runner, err := nb.Build()
+		if err != nil {
+			return nil, fmt.Errorf("creating TCP health subpipeline: %w", err)
+		}
+		return func(in <-chan []request.Span) {
+			// connect the input channel of this final node to the input of the
+			// TCP health collector
+			connector = in
+			runner.Start()
+			<-ctx.Done()
+		}, nil
+	}
+}
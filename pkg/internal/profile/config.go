@@ -0,0 +1,43 @@
+// Package profile samples the on-CPU stacks of the instrumented processes and exports them
+// as pprof profiles, optionally linked to the traces that were active during each sample.
+package profile
+
+import (
+	"fmt"
+	"time"
+)
+
+// Config of the continuous CPU profiler.
+type Config struct {
+	// Enabled turns on the CPU profiling of the instrumented processes
+	Enabled bool `yaml:"enabled" env:"BEYLA_PROFILING_ENABLED"`
+	// SampleFrequency is the number of stack samples per second and CPU
+	SampleFrequency int `yaml:"sample_frequency" env:"BEYLA_PROFILING_SAMPLE_FREQUENCY"`
+	// Interval is the duration of each exported profile
+	Interval time.Duration `yaml:"interval" env:"BEYLA_PROFILING_INTERVAL"`
+	// Port of the HTTP server that serves the last profile of each service. Zero disables it.
+	Port int `yaml:"port" env:"BEYLA_PROFILING_PORT"`
+	// Path of the HTTP server that serves the profiles
+	Path string `yaml:"path" env:"BEYLA_PROFILING_PATH"`
+	// PyroscopeEndpoint is the URL of a Pyroscope-compatible server. If set, the profiles of
+	// each service are submitted to its ingestion API.
+	PyroscopeEndpoint string `yaml:"pyroscope_endpoint" env:"BEYLA_PROFILING_PYROSCOPE_ENDPOINT"`
+	// PyroscopeHeaders are added to the requests to the Pyroscope endpoint (e.g. for authentication)
+	PyroscopeHeaders map[string]string `yaml:"pyroscope_headers" env:"BEYLA_PROFILING_PYROSCOPE_HEADERS"`
+}
+
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.SampleFrequency <= 0 || c.SampleFrequency > 1000 {
+		return fmt.Errorf("sample_frequency must be between 1 and 1000. Got: %d", c.SampleFrequency)
+	}
+	if c.Interval < time.Second {
+		return fmt.Errorf("interval must be at least 1s. Got: %s", c.Interval)
+	}
+	if c.Port == 0 && c.PyroscopeEndpoint == "" {
+		return fmt.Errorf("profiling requires a port or a pyroscope_endpoint to export the profiles")
+	}
+	return nil
+}
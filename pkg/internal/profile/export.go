@@ -0,0 +1,187 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const pyroscopeTimeout = 30 * time.Second
+
+type serviceEntry struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Start     int64  `json:"start"`
+	End       int64  `json:"end"`
+}
+
+// serve the last profile of each service. Without query arguments, it returns the list of services
+// with an available profile. The ?service=<name>&namespace=<ns>[&instance=<id>] query returns the
+// gzipped pprof profile of the given service.
+func (p *Profiler) serve(ctx context.Context) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(p.cfg.Path, p.handle)
+	server := http.Server{Addr: fmt.Sprintf(":%d", p.cfg.Port), Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		if err := server.Close(); err != nil {
+			p.log.Warn("error closing HTTP server", "error", err)
+		}
+	}()
+	p.log.Info("serving CPU profiles", "port", p.cfg.Port, "path", p.cfg.Path)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		p.log.Error("profiles HTTP server ended unexpectedly", "error", err)
+	}
+}
+
+func (p *Profiler) handle(rw http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	if !query.Has("service") {
+		rw.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(rw).Encode(p.profiledServices()); err != nil {
+			p.log.Debug("can't write profiled services", "error", err)
+		}
+		return
+	}
+	sp := p.profileOf(query.Get("service"), query.Get("namespace"), query.Get("instance"))
+	if sp == nil {
+		http.Error(rw, "no profile for the given service", http.StatusNotFound)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/octet-stream")
+	rw.Header().Set("Content-Disposition", `attachment; filename="profile.pb.gz"`)
+	if _, err := rw.Write(sp.pprof); err != nil {
+		p.log.Debug("can't write profile", "error", err)
+	}
+}
+
+func (p *Profiler) profiledServices() []serviceEntry {
+	p.mt.Lock()
+	defer p.mt.Unlock()
+	entries := make([]serviceEntry, 0, len(p.latest))
+	for _, sp := range p.latest {
+		entries = append(entries, serviceEntry{
+			Name:      sp.service.Name,
+			Namespace: sp.service.Namespace,
+			Instance:  sp.service.Instance,
+			Start:     sp.start.Unix(),
+			End:       sp.end.Unix(),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Namespace != entries[j].Namespace {
+			return entries[i].Namespace < entries[j].Namespace
+		}
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].Instance < entries[j].Instance
+	})
+	return entries
+}
+
+// profileOf returns the last profile of a service. If the instance is not specified,
+// it returns the profile of any of the instances of the service.
+func (p *Profiler) profileOf(name, namespace, instance string) *serviceProfile {
+	p.mt.Lock()
+	defer p.mt.Unlock()
+	if instance != "" {
+		return p.latest[namespace+"/"+name+"/"+instance]
+	}
+	var found *serviceProfile
+	for _, sp := range p.latest {
+		if sp.service.Name == name && sp.service.Namespace == namespace &&
+			(found == nil || sp.end.After(found.end)) {
+			found = sp
+		}
+	}
+	return found
+}
+
+// pyroscopeClient submits the profiles to the ingestion API of a Pyroscope-compatible server
+type pyroscopeClient struct {
+	endpoint  string
+	headers   map[string]string
+	frequency int
+	client    *http.Client
+}
+
+func newPyroscopeClient(cfg *Config) *pyroscopeClient {
+	return &pyroscopeClient{
+		endpoint:  strings.TrimSuffix(cfg.PyroscopeEndpoint, "/") + "/ingest",
+		headers:   cfg.PyroscopeHeaders,
+		frequency: cfg.SampleFrequency,
+		client:    &http.Client{Timeout: pyroscopeTimeout},
+	}
+}
+
+func (pc *pyroscopeClient) ingest(ctx context.Context, sp *serviceProfile, labels []Label) error {
+	body := bytes.Buffer{}
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("profile", "profile.pprof")
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(sp.pprof); err != nil {
+		return err
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("name", pyroscopeAppName(labels))
+	query.Set("from", strconv.FormatInt(sp.start.Unix(), 10))
+	query.Set("until", strconv.FormatInt(sp.end.Unix(), 10))
+	query.Set("format", "pprof")
+	query.Set("sampleRate", strconv.Itoa(pc.frequency))
+	query.Set("spyName", "beyla")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pc.endpoint+"?"+query.Encode(), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	for k, v := range pc.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := pc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("unexpected response %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// pyroscopeAppName returns the application name in the format expected by Pyroscope:
+// the service name, followed by the rest of the labels between brackets.
+func pyroscopeAppName(labels []Label) string {
+	sb := strings.Builder{}
+	sb.WriteString(labels[0].Value)
+	sb.WriteByte('{')
+	for i, l := range labels[1:] {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(l.Key)
+		sb.WriteByte('=')
+		sb.WriteString(strings.NewReplacer(",", "_", "{", "_", "}", "_").Replace(l.Value))
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
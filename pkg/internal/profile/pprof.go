@@ -0,0 +1,294 @@
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// field numbers of the pprof protobuf format, as defined in
+// https://github.com/google/pprof/blob/main/proto/profile.proto
+const (
+	pbProfileSampleType    = 1
+	pbProfileSample        = 2
+	pbProfileMapping       = 3
+	pbProfileLocation      = 4
+	pbProfileFunction      = 5
+	pbProfileStringTable   = 6
+	pbProfileTimeNanos     = 9
+	pbProfileDurationNanos = 10
+	pbProfilePeriodType    = 11
+	pbProfilePeriod        = 12
+
+	pbValueTypeType = 1
+	pbValueTypeUnit = 2
+
+	pbSampleLocationID = 1
+	pbSampleValue      = 2
+	pbSampleLabel      = 3
+
+	pbLabelKey = 1
+	pbLabelStr = 2
+
+	pbMappingID          = 1
+	pbMappingMemoryStart = 2
+	pbMappingMemoryLimit = 3
+	pbMappingFileOffset  = 4
+	pbMappingFilename    = 5
+	pbMappingHasFuncs    = 7
+
+	pbLocationID        = 1
+	pbLocationMappingID = 2
+	pbLocationAddress   = 3
+	pbLocationLine      = 4
+
+	pbLineFunctionID = 1
+	pbLineLine       = 2
+
+	pbFunctionID         = 1
+	pbFunctionName       = 2
+	pbFunctionSystemName = 3
+	pbFunctionFilename   = 4
+)
+
+// Mapping of an executable or library into the memory of a process
+type Mapping struct {
+	Start  uint64
+	Limit  uint64
+	Offset uint64
+	File   string
+}
+
+// Frame of a stack. If the function name is empty, the frame couldn't be symbolized and
+// only the address is reported.
+type Frame struct {
+	Address  uint64
+	Function string
+	File     string
+	Line     int
+	Mapping  *Mapping
+}
+
+// Label of a profile sample
+type Label struct {
+	Key   string
+	Value string
+}
+
+type functionKey struct {
+	name string
+	file string
+}
+
+type locationKey struct {
+	address  uint64
+	function string
+	file     string
+	line     int
+	mapping  *Mapping
+}
+
+type pprofSample struct {
+	locations []uint64
+	labels    []Label
+	count     int64
+}
+
+// profileBuilder aggregates stack samples and encodes them in the pprof format
+type profileBuilder struct {
+	period int64
+
+	strings     map[string]int64
+	stringTable []string
+
+	functions map[functionKey]uint64
+	funcList  []functionKey
+
+	mappings    map[*Mapping]uint64
+	mappingList []*Mapping
+
+	locations map[locationKey]uint64
+	locList   []locationKey
+
+	samples    map[string]*pprofSample
+	sampleList []*pprofSample
+}
+
+// newProfileBuilder for CPU samples that are taken every period nanoseconds
+func newProfileBuilder(period int64) *profileBuilder {
+	return &profileBuilder{
+		period:      period,
+		strings:     map[string]int64{"": 0},
+		stringTable: []string{""},
+		functions:   map[functionKey]uint64{},
+		mappings:    map[*Mapping]uint64{},
+		locations:   map[locationKey]uint64{},
+		samples:     map[string]*pprofSample{},
+	}
+}
+
+func (b *profileBuilder) empty() bool {
+	return len(b.sampleList) == 0
+}
+
+func (b *profileBuilder) str(s string) int64 {
+	if id, ok := b.strings[s]; ok {
+		return id
+	}
+	id := int64(len(b.stringTable))
+	b.strings[s] = id
+	b.stringTable = append(b.stringTable, s)
+	return id
+}
+
+func (b *profileBuilder) location(f *Frame) uint64 {
+	key := locationKey{address: f.Address, function: f.Function, file: f.File, line: f.Line, mapping: f.Mapping}
+	if id, ok := b.locations[key]; ok {
+		return id
+	}
+	if f.Function != "" {
+		fk := functionKey{name: f.Function, file: f.File}
+		if _, ok := b.functions[fk]; !ok {
+			b.funcList = append(b.funcList, fk)
+			b.functions[fk] = uint64(len(b.funcList))
+		}
+	}
+	if f.Mapping != nil {
+		if _, ok := b.mappings[f.Mapping]; !ok {
+			b.mappingList = append(b.mappingList, f.Mapping)
+			b.mappings[f.Mapping] = uint64(len(b.mappingList))
+		}
+	}
+	b.locList = append(b.locList, key)
+	id := uint64(len(b.locList))
+	b.locations[key] = id
+	return id
+}
+
+// add a stack, whose frames are ordered from the leaf to the root, with the given labels.
+func (b *profileBuilder) add(frames []Frame, labels []Label) {
+	locations := make([]uint64, 0, len(frames))
+	sb := strings.Builder{}
+	for i := range frames {
+		id := b.location(&frames[i])
+		locations = append(locations, id)
+		sb.WriteString(strconv.FormatUint(id, 16))
+		sb.WriteByte(',')
+	}
+	for _, l := range labels {
+		sb.WriteString(l.Key)
+		sb.WriteByte('=')
+		sb.WriteString(l.Value)
+		sb.WriteByte(';')
+	}
+	key := sb.String()
+	if s, ok := b.samples[key]; ok {
+		s.count++
+		return
+	}
+	s := &pprofSample{locations: locations, labels: labels, count: 1}
+	b.samples[key] = s
+	b.sampleList = append(b.sampleList, s)
+}
+
+// write the gzipped pprof profile
+func (b *profileBuilder) write(w io.Writer, start time.Time, duration time.Duration) error {
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(b.encode(start, duration)); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func (b *profileBuilder) encode(start time.Time, duration time.Duration) []byte {
+	var out []byte
+	out = appendMessage(out, pbProfileSampleType, b.valueType("samples", "count"))
+	out = appendMessage(out, pbProfileSampleType, b.valueType("cpu", "nanoseconds"))
+
+	for _, s := range b.sampleList {
+		var msg, packed []byte
+		for _, l := range s.locations {
+			packed = protowire.AppendVarint(packed, l)
+		}
+		msg = appendMessage(msg, pbSampleLocationID, packed)
+		packed = protowire.AppendVarint(nil, uint64(s.count))
+		packed = protowire.AppendVarint(packed, uint64(s.count*b.period))
+		msg = appendMessage(msg, pbSampleValue, packed)
+		for _, l := range s.labels {
+			var label []byte
+			label = appendVarint(label, pbLabelKey, uint64(b.str(l.Key)))
+			label = appendVarint(label, pbLabelStr, uint64(b.str(l.Value)))
+			msg = appendMessage(msg, pbSampleLabel, label)
+		}
+		out = appendMessage(out, pbProfileSample, msg)
+	}
+
+	for i, m := range b.mappingList {
+		var msg []byte
+		msg = appendVarint(msg, pbMappingID, uint64(i+1))
+		msg = appendVarint(msg, pbMappingMemoryStart, m.Start)
+		msg = appendVarint(msg, pbMappingMemoryLimit, m.Limit)
+		msg = appendVarint(msg, pbMappingFileOffset, m.Offset)
+		msg = appendVarint(msg, pbMappingFilename, uint64(b.str(m.File)))
+		msg = appendVarint(msg, pbMappingHasFuncs, 1)
+		out = appendMessage(out, pbProfileMapping, msg)
+	}
+
+	for i, l := range b.locList {
+		var msg []byte
+		msg = appendVarint(msg, pbLocationID, uint64(i+1))
+		if l.mapping != nil {
+			msg = appendVarint(msg, pbLocationMappingID, b.mappings[l.mapping])
+		}
+		msg = appendVarint(msg, pbLocationAddress, l.address)
+		if l.function != "" {
+			var line []byte
+			line = appendVarint(line, pbLineFunctionID, b.functions[functionKey{name: l.function, file: l.file}])
+			line = appendVarint(line, pbLineLine, uint64(l.line))
+			msg = appendMessage(msg, pbLocationLine, line)
+		}
+		out = appendMessage(out, pbProfileLocation, msg)
+	}
+
+	for i, f := range b.funcList {
+		var msg []byte
+		msg = appendVarint(msg, pbFunctionID, uint64(i+1))
+		msg = appendVarint(msg, pbFunctionName, uint64(b.str(f.name)))
+		msg = appendVarint(msg, pbFunctionSystemName, uint64(b.str(f.name)))
+		msg = appendVarint(msg, pbFunctionFilename, uint64(b.str(f.file)))
+		out = appendMessage(out, pbProfileFunction, msg)
+	}
+
+	out = appendVarint(out, pbProfileTimeNanos, uint64(start.UnixNano()))
+	out = appendVarint(out, pbProfileDurationNanos, uint64(duration.Nanoseconds()))
+	out = appendMessage(out, pbProfilePeriodType, b.valueType("cpu", "nanoseconds"))
+	out = appendVarint(out, pbProfilePeriod, uint64(b.period))
+
+	// the string table goes last, as the previous messages might have added new strings
+	for _, s := range b.stringTable {
+		out = protowire.AppendTag(out, pbProfileStringTable, protowire.BytesType)
+		out = protowire.AppendString(out, s)
+	}
+	return out
+}
+
+func (b *profileBuilder) valueType(typ, unit string) []byte {
+	var msg []byte
+	msg = appendVarint(msg, pbValueTypeType, uint64(b.str(typ)))
+	msg = appendVarint(msg, pbValueTypeUnit, uint64(b.str(unit)))
+	return msg
+}
+
+func appendVarint(b []byte, field protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, field, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendMessage(b []byte, field protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, field, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
@@ -0,0 +1,134 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodedProfile contains the subset of the pprof fields that are verified by the tests
+type decodedProfile struct {
+	strings   []string
+	samples   [][]byte
+	locations int
+	functions int
+	mappings  int
+	period    uint64
+}
+
+func decodeProfile(t *testing.T, msg []byte) decodedProfile {
+	t.Helper()
+	p := decodedProfile{}
+	forEachField(t, msg, func(num protowire.Number, v uint64, b []byte) {
+		switch num {
+		case pbProfileStringTable:
+			p.strings = append(p.strings, string(b))
+		case pbProfileSample:
+			p.samples = append(p.samples, b)
+		case pbProfileLocation:
+			p.locations++
+		case pbProfileFunction:
+			p.functions++
+		case pbProfileMapping:
+			p.mappings++
+		case pbProfilePeriod:
+			p.period = v
+		}
+	})
+	return p
+}
+
+func forEachField(t *testing.T, msg []byte, fn func(num protowire.Number, v uint64, b []byte)) {
+	t.Helper()
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		require.Positive(t, n)
+		msg = msg[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(msg)
+			require.Positive(t, n)
+			fn(num, v, nil)
+			msg = msg[n:]
+		case protowire.BytesType:
+			b, n := protowire.ConsumeBytes(msg)
+			require.Positive(t, n)
+			fn(num, 0, b)
+			msg = msg[n:]
+		default:
+			require.Failf(t, "unexpected wire type", "%v", typ)
+		}
+	}
+}
+
+// sampleValues returns the count and cpu values of a sample, and its labels as key=value strings
+func sampleValues(t *testing.T, p *decodedProfile, sample []byte) (count, cpu uint64, labels []string) {
+	t.Helper()
+	forEachField(t, sample, func(num protowire.Number, _ uint64, b []byte) {
+		switch num {
+		case pbSampleValue:
+			c, n := protowire.ConsumeVarint(b)
+			count = c
+			cpu, _ = protowire.ConsumeVarint(b[n:])
+		case pbSampleLabel:
+			var key, str uint64
+			forEachField(t, b, func(num protowire.Number, v uint64, _ []byte) {
+				switch num {
+				case pbLabelKey:
+					key = v
+				case pbLabelStr:
+					str = v
+				}
+			})
+			labels = append(labels, p.strings[key]+"="+p.strings[str])
+		}
+	})
+	return count, cpu, labels
+}
+
+func TestProfileBuilder(t *testing.T) {
+	exe := &Mapping{Start: 0x400000, Limit: 0x500000, File: "/bin/server"}
+	handler := Frame{Address: 0x401000, Function: "main.handler", File: "main.go", Line: 10, Mapping: exe}
+	main := Frame{Address: 0x402000, Function: "main.main", File: "main.go", Line: 3, Mapping: exe}
+	unknown := Frame{Address: 0x7f0000001234}
+
+	b := newProfileBuilder(10_000_000)
+	assert.True(t, b.empty())
+	b.add([]Frame{handler, main}, nil)
+	b.add([]Frame{handler, main}, nil)
+	b.add([]Frame{handler, main}, []Label{{Key: "trace_id", Value: "abc"}})
+	b.add([]Frame{unknown, main}, nil)
+	assert.False(t, b.empty())
+
+	buf := bytes.Buffer{}
+	require.NoError(t, b.write(&buf, time.Unix(1000, 0), 15*time.Second))
+	gz, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	raw, err := io.ReadAll(gz)
+	require.NoError(t, err)
+
+	p := decodeProfile(t, raw)
+	assert.Equal(t, "", p.strings[0])
+	assert.Contains(t, p.strings, "main.handler")
+	assert.Contains(t, p.strings, "/bin/server")
+	assert.EqualValues(t, 10_000_000, p.period)
+	assert.Equal(t, 3, p.locations)
+	assert.Equal(t, 2, p.functions)
+	assert.Equal(t, 1, p.mappings)
+	require.Len(t, p.samples, 3)
+
+	count, cpu, labels := sampleValues(t, &p, p.samples[0])
+	assert.EqualValues(t, 2, count)
+	assert.EqualValues(t, 20_000_000, cpu)
+	assert.Empty(t, labels)
+
+	count, _, labels = sampleValues(t, &p, p.samples[1])
+	assert.EqualValues(t, 1, count)
+	assert.Equal(t, []string{"trace_id=abc"}, labels)
+}
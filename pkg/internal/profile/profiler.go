@@ -0,0 +1,380 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gavv/monotime"
+	"github.com/mariomac/pipes/pipe"
+	"go.opentelemetry.io/otel/trace"
+
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+const (
+	// samples are linked to the spans after this delay, to give time to the spans that were active
+	// during the sample to end and reach the profiler
+	linkDelay = 5 * time.Second
+
+	labelTraceID = "trace_id"
+	labelSpanID  = "span_id"
+)
+
+func monotonicNow() int64 {
+	return int64(monotime.Now())
+}
+
+func plog() *slog.Logger {
+	return slog.With("component", "profile.Profiler")
+}
+
+// Sample of the on-CPU stack of a thread
+type Sample struct {
+	PID uint32
+	TID uint32
+	// Time of the sample, in nanoseconds from the monotonic clock, as the start and end of the spans
+	Time int64
+	// User and Kernel stacks, from the leaf to the root
+	User   []uint64
+	Kernel []uint64
+}
+
+// sampler captures the on-CPU stacks of the profiled processes
+type sampler interface {
+	allow(pid uint32) error
+	block(pid uint32) error
+	// run forwards the samples until the context is cancelled
+	run(ctx context.Context, out func(*Sample)) error
+}
+
+type spanWindow struct {
+	start   int64
+	end     int64
+	traceID trace.TraceID
+	spanID  trace.SpanID
+}
+
+// serviceProfile is the last profile of a service
+type serviceProfile struct {
+	service svc.ID
+	start   time.Time
+	end     time.Time
+	pprof   []byte
+}
+
+// Profiler samples the on-CPU stacks of the instrumented processes and periodically
+// exports them as pprof profiles for each service. The samples are labeled with the trace
+// and span IDs of the server span that was active in the process when the sample was taken,
+// if there was only one.
+type Profiler struct {
+	cfg        *Config
+	hostID     string
+	systemWide bool
+	log        *slog.Logger
+
+	sampler  sampler
+	symbols  *symbolizer
+	monotime func() int64
+	pyro     *pyroscopeClient
+
+	mt          sync.Mutex
+	services    map[uint32]svc.ID
+	pending     []*Sample
+	spans       map[uint32][]spanWindow
+	latest      map[string]*serviceProfile
+	windowStart time.Time
+}
+
+// NewProfiler creates a Profiler, whose sampler must be started with the Run method.
+// The systemWide argument disables the PID filtering, so all the processes of the host are profiled.
+func NewProfiler(cfg *Config, hostID string, systemWide bool) *Profiler {
+	p := &Profiler{
+		cfg:        cfg,
+		hostID:     hostID,
+		systemWide: systemWide,
+		log:        plog(),
+		symbols:    newSymbolizer("/proc"),
+		monotime:   monotonicNow,
+		services:   map[uint32]svc.ID{},
+		spans:      map[uint32][]spanWindow{},
+		latest:     map[string]*serviceProfile{},
+	}
+	if cfg.PyroscopeEndpoint != "" {
+		p.pyro = newPyroscopeClient(cfg)
+	}
+	return p
+}
+
+// AllowPID starts profiling the process with the provided PID
+func (p *Profiler) AllowPID(pid, _ uint32, id svc.ID) {
+	p.mt.Lock()
+	p.services[pid] = id
+	s := p.sampler
+	p.mt.Unlock()
+	if s != nil {
+		if err := s.allow(pid); err != nil {
+			p.log.Warn("can't start profiling process", "pid", pid, "error", err)
+		}
+	}
+}
+
+// BlockPID stops profiling the process with the provided PID
+func (p *Profiler) BlockPID(pid, _ uint32) {
+	p.mt.Lock()
+	delete(p.services, pid)
+	delete(p.spans, pid)
+	s := p.sampler
+	p.mt.Unlock()
+	p.symbols.forget(pid)
+	if s != nil {
+		if err := s.block(pid); err != nil {
+			p.log.Debug("can't stop profiling process", "pid", pid, "error", err)
+		}
+	}
+}
+
+// Run the eBPF sampler and export the profiles until the context is cancelled
+func (p *Profiler) Run(ctx context.Context) {
+	s, err := newSampler(p.cfg.SampleFrequency, !p.systemWide)
+	if err != nil {
+		p.log.Error("can't start CPU profiler. Profiles won't be reported", "error", err)
+		return
+	}
+	p.runWith(ctx, s)
+}
+
+func (p *Profiler) runWith(ctx context.Context, s sampler) {
+	p.mt.Lock()
+	p.sampler = s
+	p.windowStart = time.Now()
+	// processes that were discovered before the sampler started
+	for pid := range p.services {
+		if err := s.allow(pid); err != nil {
+			p.log.Warn("can't start profiling process", "pid", pid, "error", err)
+		}
+	}
+	p.mt.Unlock()
+
+	if p.cfg.Port != 0 {
+		go p.serve(ctx)
+	}
+	go p.exportLoop(ctx)
+
+	p.log.Info("starting CPU profiler", "frequency", p.cfg.SampleFrequency, "interval", p.cfg.Interval)
+	if err := s.run(ctx, p.addSample); err != nil {
+		p.log.Error("CPU profiler stopped unexpectedly", "error", err)
+	}
+}
+
+func (p *Profiler) addSample(s *Sample) {
+	p.mt.Lock()
+	defer p.mt.Unlock()
+	if _, ok := p.services[s.PID]; ok || p.systemWide {
+		p.pending = append(p.pending, s)
+	}
+}
+
+// SpansLinker is a pipeline node that keeps track of the active server spans of each process, so the
+// profile samples can be linked to them. It also updates the service metadata of the profiled processes
+// with the decorations that are added in the pipeline (e.g. Kubernetes metadata).
+func SpansLinker(p *Profiler) pipe.FinalProvider[[]request.Span] {
+	return func() (pipe.FinalFunc[[]request.Span], error) {
+		if p == nil {
+			return pipe.IgnoreFinal[[]request.Span](), nil
+		}
+		return func(in <-chan []request.Span) {
+			for spans := range in {
+				p.addSpans(spans)
+			}
+		}, nil
+	}
+}
+
+func (p *Profiler) addSpans(spans []request.Span) {
+	p.mt.Lock()
+	defer p.mt.Unlock()
+	for i := range spans {
+		s := &spans[i]
+		pid := s.Pid.HostPID
+		if _, ok := p.services[pid]; ok {
+			p.services[pid] = s.ServiceID
+		} else if !p.systemWide {
+			continue
+		}
+		if s.IsClientSpan() || !s.TraceID.IsValid() {
+			continue
+		}
+		p.spans[pid] = append(p.spans[pid], spanWindow{
+			start:   s.RequestStart,
+			end:     s.End,
+			traceID: s.TraceID,
+			spanID:  s.SpanID,
+		})
+	}
+}
+
+func (p *Profiler) exportLoop(ctx context.Context) {
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			p.export(ctx, p.flush(now))
+		}
+	}
+}
+
+// flush builds the profiles of the samples that are older than the link delay
+func (p *Profiler) flush(now time.Time) []*serviceProfile {
+	p.mt.Lock()
+	cutoff := p.monotime() - linkDelay.Nanoseconds()
+	var ready []*Sample
+	keep := p.pending[:0]
+	for _, s := range p.pending {
+		if s.Time <= cutoff {
+			ready = append(ready, s)
+		} else {
+			keep = append(keep, s)
+		}
+	}
+	p.pending = keep
+
+	// the processes that belong to the same service are merged into the same profile
+	builders := map[string]*profileBuilder{}
+	services := map[string]svc.ID{}
+	for _, s := range ready {
+		service := p.serviceOf(s.PID)
+		key := profileKey(&service)
+		b, ok := builders[key]
+		if !ok {
+			b = newProfileBuilder(time.Second.Nanoseconds() / int64(p.cfg.SampleFrequency))
+			builders[key] = b
+			services[key] = service
+		}
+		var labels []Label
+		if sw, ok := activeSpan(p.spans[s.PID], s.Time); ok {
+			labels = []Label{
+				{Key: labelTraceID, Value: sw.traceID.String()},
+				{Key: labelSpanID, Value: sw.spanID.String()},
+			}
+		}
+		frames := append(p.symbols.kernelFrames(s.Kernel), p.symbols.userFrames(s.PID, s.User)...)
+		b.add(frames, labels)
+	}
+	// the remaining samples are newer than the cutoff, so they won't need the spans that ended before it
+	for pid, spans := range p.spans {
+		p.spans[pid] = pruneSpans(spans, cutoff)
+		if len(p.spans[pid]) == 0 {
+			delete(p.spans, pid)
+		}
+	}
+	start := p.windowStart
+	p.windowStart = now
+	p.mt.Unlock()
+
+	return p.buildProfiles(builders, services, start, now)
+}
+
+func (p *Profiler) buildProfiles(
+	builders map[string]*profileBuilder, services map[string]svc.ID, start, end time.Time,
+) []*serviceProfile {
+	keys := make([]string, 0, len(builders))
+	for key := range builders {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var profiles []*serviceProfile
+	for _, key := range keys {
+		b := builders[key]
+		if b.empty() {
+			continue
+		}
+		buf := bytes.Buffer{}
+		if err := b.write(&buf, start, end.Sub(start)); err != nil {
+			p.log.Warn("can't encode profile", "service", key, "error", err)
+			continue
+		}
+		profiles = append(profiles, &serviceProfile{service: services[key], start: start, end: end, pprof: buf.Bytes()})
+	}
+	return profiles
+}
+
+func (p *Profiler) serviceOf(pid uint32) svc.ID {
+	if id, ok := p.services[pid]; ok {
+		return id
+	}
+	return svc.ID{Name: "unknown", ProcPID: int32(pid)}
+}
+
+// activeSpan returns the server span that was active at the given time, if there is only one
+func activeSpan(spans []spanWindow, t int64) (spanWindow, bool) {
+	var found spanWindow
+	matches := 0
+	for _, sw := range spans {
+		if sw.start <= t && t <= sw.end {
+			found = sw
+			matches++
+		}
+	}
+	return found, matches == 1
+}
+
+func pruneSpans(spans []spanWindow, before int64) []spanWindow {
+	keep := spans[:0]
+	for _, sw := range spans {
+		if sw.end >= before {
+			keep = append(keep, sw)
+		}
+	}
+	return keep
+}
+
+func (p *Profiler) export(ctx context.Context, profiles []*serviceProfile) {
+	p.mt.Lock()
+	for _, sp := range profiles {
+		p.latest[profileKey(&sp.service)] = sp
+	}
+	p.mt.Unlock()
+	if p.pyro != nil {
+		for _, sp := range profiles {
+			if err := p.pyro.ingest(ctx, sp, p.resourceLabels(&sp.service)); err != nil {
+				p.log.Warn("can't submit profile to Pyroscope", "service", sp.service.String(), "error", err)
+			}
+		}
+	}
+}
+
+func profileKey(id *svc.ID) string {
+	return id.Namespace + "/" + id.Name + "/" + id.Instance
+}
+
+// resourceLabels of a service, as reported in the OpenTelemetry resource of its metrics and traces
+func (p *Profiler) resourceLabels(id *svc.ID) []Label {
+	labels := []Label{{Key: attr.ServiceName.Prom(), Value: id.Name}}
+	if id.Namespace != "" {
+		labels = append(labels, Label{Key: attr.ServiceNamespace.Prom(), Value: id.Namespace})
+	}
+	if id.Instance != "" {
+		labels = append(labels, Label{Key: attr.ServiceInstanceID.Prom(), Value: id.Instance})
+	}
+	if id.HostName != "" {
+		labels = append(labels, Label{Key: attr.HostName.Prom(), Value: id.HostName})
+	}
+	if p.hostID != "" {
+		labels = append(labels, Label{Key: attr.HostID.Prom(), Value: p.hostID})
+	}
+	for k, v := range id.Metadata {
+		labels = append(labels, Label{Key: k.Prom(), Value: v})
+	}
+	sort.Slice(labels[1:], func(i, j int) bool { return labels[i+1].Key < labels[j+1].Key })
+	return labels
+}
@@ -0,0 +1,229 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+type fakeSampler struct {
+	mt      sync.Mutex
+	allowed map[uint32]struct{}
+	samples chan *Sample
+}
+
+func newFakeSampler() *fakeSampler {
+	return &fakeSampler{allowed: map[uint32]struct{}{}, samples: make(chan *Sample, 10)}
+}
+
+func (f *fakeSampler) allow(pid uint32) error {
+	f.mt.Lock()
+	defer f.mt.Unlock()
+	f.allowed[pid] = struct{}{}
+	return nil
+}
+
+func (f *fakeSampler) block(pid uint32) error {
+	f.mt.Lock()
+	defer f.mt.Unlock()
+	delete(f.allowed, pid)
+	return nil
+}
+
+func (f *fakeSampler) isAllowed(pid uint32) bool {
+	f.mt.Lock()
+	defer f.mt.Unlock()
+	_, ok := f.allowed[pid]
+	return ok
+}
+
+func (f *fakeSampler) run(ctx context.Context, out func(*Sample)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case s := <-f.samples:
+			out(s)
+		}
+	}
+}
+
+func testProfiler(t *testing.T, now *int64) *Profiler {
+	t.Helper()
+	p := NewProfiler(&Config{Enabled: true, SampleFrequency: 100, Interval: time.Hour}, "the-host", false)
+	p.monotime = func() int64 { return *now }
+	return p
+}
+
+func serverSpan(pid uint32, start, end int64, traceID byte, spanID byte) request.Span {
+	return request.Span{
+		Type:         request.EventTypeHTTP,
+		Pid:          request.PidInfo{HostPID: pid},
+		RequestStart: start,
+		Start:        start,
+		End:          end,
+		TraceID:      trace.TraceID{traceID},
+		SpanID:       trace.SpanID{spanID},
+		ServiceID:    svc.ID{Name: "svc", Namespace: "ns", Instance: "svc-1", Metadata: map[attr.Name]string{"k8s.pod.name": "pod"}},
+	}
+}
+
+func TestProfiler_LinkSamplesToSpans(t *testing.T) {
+	now := int64(0)
+	p := testProfiler(t, &now)
+	p.AllowPID(123, 0, svc.ID{Name: "svc"})
+
+	p.addSpans([]request.Span{
+		serverSpan(123, 1000, 2000, 1, 1),
+		// overlapping spans can't be linked
+		serverSpan(123, 3000, 4000, 2, 2),
+		serverSpan(123, 3500, 4500, 3, 3),
+		// spans from non-profiled processes are ignored
+		serverSpan(456, 1000, 2000, 4, 4),
+	})
+
+	for _, t := range []int64{1500, 3700, 5000} {
+		p.addSample(&Sample{PID: 123, Time: t, User: []uint64{0x10}})
+	}
+	p.addSample(&Sample{PID: 456, Time: 1500, User: []uint64{0x10}})
+
+	// samples younger than the link delay are not exported yet
+	now = 1000
+	assert.Empty(t, p.flush(time.Now()))
+
+	now = linkDelay.Nanoseconds() + 4000
+	profiles := p.flush(time.Now())
+	require.Len(t, profiles, 1)
+	// service metadata is updated from the decorated spans
+	assert.Equal(t, "ns", profiles[0].service.Namespace)
+	assert.Equal(t, "svc-1", profiles[0].service.Instance)
+
+	prof := decodeGzipped(t, profiles[0].pprof)
+	var linked [][]string
+	for _, s := range prof.samples {
+		_, _, labels := sampleValues(t, &prof, s)
+		linked = append(linked, labels)
+	}
+	assert.Equal(t, [][]string{
+		{"trace_id=" + trace.TraceID{1}.String(), "span_id=" + trace.SpanID{1}.String()},
+		nil,
+	}, linked)
+
+	// the sample at 5000 is still pending, and the spans that ended before the cutoff are removed
+	assert.Len(t, p.pending, 1)
+	assert.Len(t, p.spans[123], 2)
+}
+
+func TestProfiler_AllowAndBlock(t *testing.T) {
+	now := int64(0)
+	p := testProfiler(t, &now)
+	p.AllowPID(1, 0, svc.ID{Name: "before"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := newFakeSampler()
+	go p.runWith(ctx, s)
+
+	// processes discovered before the sampler started are also profiled
+	require.Eventually(t, func() bool { return s.isAllowed(1) }, 5*time.Second, 10*time.Millisecond)
+	p.AllowPID(2, 0, svc.ID{Name: "after"})
+	assert.True(t, s.isAllowed(2))
+	p.BlockPID(1, 0)
+	assert.False(t, s.isAllowed(1))
+
+	s.samples <- &Sample{PID: 2, Time: 10, User: []uint64{0x10}}
+	s.samples <- &Sample{PID: 1, Time: 10, User: []uint64{0x10}}
+	require.Eventually(t, func() bool {
+		p.mt.Lock()
+		defer p.mt.Unlock()
+		return len(p.pending) == 1 && p.pending[0].PID == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestProfiler_Export(t *testing.T) {
+	type ingestion struct {
+		query   map[string]string
+		auth    string
+		profile []byte
+	}
+	ingested := make(chan ingestion, 10)
+	pyroscope := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/ingest", req.URL.Path)
+		file, _, err := req.FormFile("profile")
+		require.NoError(t, err)
+		content, err := io.ReadAll(file)
+		require.NoError(t, err)
+		query := map[string]string{}
+		for k := range req.URL.Query() {
+			query[k] = req.URL.Query().Get(k)
+		}
+		ingested <- ingestion{query: query, auth: req.Header.Get("Authorization"), profile: content}
+	}))
+	defer pyroscope.Close()
+
+	now := int64(0)
+	p := NewProfiler(&Config{
+		Enabled: true, SampleFrequency: 100, Interval: time.Hour,
+		PyroscopeEndpoint: pyroscope.URL, PyroscopeHeaders: map[string]string{"Authorization": "Bearer foo"},
+	}, "the-host", false)
+	p.monotime = func() int64 { return now }
+	p.AllowPID(123, 0, svc.ID{Name: "svc"})
+	p.addSpans([]request.Span{serverSpan(123, 1000, 2000, 1, 1)})
+	p.addSample(&Sample{PID: 123, Time: 1500, User: []uint64{0x10}})
+	now = linkDelay.Nanoseconds() + 2000
+	p.windowStart = time.Unix(1000, 0)
+	p.export(context.Background(), p.flush(time.Unix(1015, 0)))
+
+	select {
+	case in := <-ingested:
+		assert.Equal(t, "Bearer foo", in.auth)
+		assert.Equal(t, map[string]string{
+			"name":       "svc{host_id=the-host,k8s_pod_name=pod,service_instance_id=svc-1,service_namespace=ns}",
+			"from":       "1000",
+			"until":      "1015",
+			"format":     "pprof",
+			"sampleRate": "100",
+			"spyName":    "beyla",
+		}, in.query)
+		assert.NotEmpty(t, decodeGzipped(t, in.profile).samples)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "timeout waiting for the profile to be submitted")
+	}
+
+	// the last profile is also available through HTTP
+	rw := httptest.NewRecorder()
+	p.handle(rw, httptest.NewRequest(http.MethodGet, "/profiles", nil))
+	assert.JSONEq(t, `[{"name":"svc","namespace":"ns","instance":"svc-1","start":1000,"end":1015}]`, rw.Body.String())
+
+	rw = httptest.NewRecorder()
+	p.handle(rw, httptest.NewRequest(http.MethodGet, "/profiles?service=svc&namespace=ns", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.NotEmpty(t, decodeGzipped(t, rw.Body.Bytes()).samples)
+
+	rw = httptest.NewRecorder()
+	p.handle(rw, httptest.NewRequest(http.MethodGet, "/profiles?service=svc", nil))
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+}
+
+func decodeGzipped(t *testing.T, gzipped []byte) decodedProfile {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(gzipped))
+	require.NoError(t, err)
+	raw, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	return decodeProfile(t, raw)
+}
@@ -0,0 +1,170 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf/ringbuf"
+	"golang.org/x/sys/unix"
+)
+
+//go:generate $BPF2GO -cc $BPF_CLANG -cflags $BPF_CFLAGS -type profile_sample_t -target amd64,arm64 bpf ../../../bpf/profile.c -- -I../../../bpf/headers
+
+const constFilterPIDs = "filter_pids"
+
+// perfSampler runs an eBPF program that is triggered by a CPU clock perf event in each CPU
+// and submits the user and kernel stacks of the running thread.
+type perfSampler struct {
+	objects bpfObjects
+	events  []int
+}
+
+func newSampler(frequency int, filterPIDs bool) (sampler, error) {
+	spec, err := loadBpf()
+	if err != nil {
+		return nil, fmt.Errorf("loading profiler eBPF program: %w", err)
+	}
+	filter := uint32(0)
+	if filterPIDs {
+		filter = 1
+	}
+	if err := spec.RewriteConstants(map[string]any{constFilterPIDs: filter}); err != nil {
+		return nil, fmt.Errorf("rewriting profiler eBPF constants: %w", err)
+	}
+	s := &perfSampler{}
+	if err := spec.LoadAndAssign(&s.objects, nil); err != nil {
+		return nil, fmt.Errorf("loading and assigning profiler eBPF objects: %w", err)
+	}
+	if err := s.openEvents(frequency); err != nil {
+		s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// openEvents opens a sampling CPU clock perf event for each CPU, and attaches the program to them
+func (s *perfSampler) openEvents(frequency int) error {
+	cpus, err := onlineCPUs()
+	if err != nil {
+		return fmt.Errorf("reading online CPUs: %w", err)
+	}
+	attr := unix.PerfEventAttr{
+		Type:   unix.PERF_TYPE_SOFTWARE,
+		Config: unix.PERF_COUNT_SW_CPU_CLOCK,
+		Size:   uint32(binary.Size(unix.PerfEventAttr{})),
+		Sample: uint64(frequency),
+		Bits:   unix.PerfBitFreq,
+	}
+	for _, cpu := range cpus {
+		fd, err := unix.PerfEventOpen(&attr, -1, cpu, -1, unix.PERF_FLAG_FD_CLOEXEC)
+		if err != nil {
+			return fmt.Errorf("opening perf event for CPU %d: %w", cpu, err)
+		}
+		s.events = append(s.events, fd)
+		if err := unix.IoctlSetInt(fd, unix.PERF_EVENT_IOC_SET_BPF, s.objects.BeylaProfile.FD()); err != nil {
+			return fmt.Errorf("attaching profiler to CPU %d: %w", cpu, err)
+		}
+		if err := unix.IoctlSetInt(fd, unix.PERF_EVENT_IOC_ENABLE, 0); err != nil {
+			return fmt.Errorf("enabling perf event for CPU %d: %w", cpu, err)
+		}
+	}
+	return nil
+}
+
+func (s *perfSampler) allow(pid uint32) error {
+	return s.objects.ProfiledPids.Put(pid, uint32(1))
+}
+
+func (s *perfSampler) block(pid uint32) error {
+	return s.objects.ProfiledPids.Delete(pid)
+}
+
+func (s *perfSampler) run(ctx context.Context, out func(*Sample)) error {
+	defer s.close()
+	reader, err := ringbuf.NewReader(s.objects.Samples)
+	if err != nil {
+		return fmt.Errorf("reading samples ring buffer: %w", err)
+	}
+	go func() {
+		<-ctx.Done()
+		_ = reader.Close()
+	}()
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, ringbuf.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		if sample, ok := decodeSample(record.RawSample); ok {
+			out(sample)
+		}
+	}
+}
+
+func (s *perfSampler) close() {
+	for _, fd := range s.events {
+		_ = unix.Close(fd)
+	}
+	_ = s.objects.Close()
+}
+
+func decodeSample(raw []byte) (*Sample, bool) {
+	var event bpfProfileSampleT
+	if err := binary.Read(bytes.NewReader(raw), binary.NativeEndian, &event); err != nil {
+		return nil, false
+	}
+	return &Sample{
+		PID:    event.Pid,
+		TID:    event.Tid,
+		Time:   int64(event.Time),
+		User:   decodeStack(event.UserStack[:], event.UserLen),
+		Kernel: decodeStack(event.KernelStack[:], event.KernelLen),
+	}, true
+}
+
+// decodeStack of the given length in bytes. A negative length means that the stack couldn't be read.
+func decodeStack(stack []uint64, length int32) []uint64 {
+	if length <= 0 {
+		return nil
+	}
+	n := min(int(length)/8, len(stack))
+	return append(make([]uint64, 0, n), stack[:n]...)
+}
+
+// onlineCPUs parses the list of CPUs, in the format of /sys/devices/system/cpu/online (e.g. 0-3,6)
+func onlineCPUs() ([]int, error) {
+	content, err := os.ReadFile("/sys/devices/system/cpu/online")
+	if err != nil {
+		return nil, err
+	}
+	return parseCPUList(string(content))
+}
+
+func parseCPUList(list string) ([]int, error) {
+	var cpus []int
+	for _, r := range strings.Split(strings.TrimSpace(list), ",") {
+		from, to, isRange := strings.Cut(r, "-")
+		first, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU list %q: %w", list, err)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(to); err != nil {
+				return nil, fmt.Errorf("invalid CPU list %q: %w", list, err)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
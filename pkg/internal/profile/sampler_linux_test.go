@@ -0,0 +1,38 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCPUList(t *testing.T) {
+	cpus, err := parseCPUList("0-3,6,8-9\n")
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3, 6, 8, 9}, cpus)
+
+	_, err = parseCPUList("0-a")
+	assert.Error(t, err)
+}
+
+func TestDecodeStack(t *testing.T) {
+	event := bpfProfileSampleT{Pid: 123, Tid: 456, UserLen: 16, KernelLen: -14}
+	event.UserStack[0], event.UserStack[1], event.UserStack[2] = 0x10, 0x20, 0x30
+	// negative length: the kernel stack couldn't be read
+	event.KernelStack[0] = 0x40
+	raw := bytes.Buffer{}
+	require.NoError(t, binary.Write(&raw, binary.NativeEndian, &event))
+
+	s, ok := decodeSample(raw.Bytes())
+	require.True(t, ok)
+	assert.Equal(t, uint32(123), s.PID)
+	assert.Equal(t, uint32(456), s.TID)
+	assert.Equal(t, []uint64{0x10, 0x20}, s.User)
+	assert.Empty(t, s.Kernel)
+
+	_, ok = decodeSample(raw.Bytes()[:10])
+	assert.False(t, ok)
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package profile
+
+import "errors"
+
+func newSampler(_ int, _ bool) (sampler, error) {
+	return nil, errors.New("CPU profiling is only supported on Linux")
+}
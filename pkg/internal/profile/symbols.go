@@ -0,0 +1,313 @@
+package profile
+
+import (
+	"bufio"
+	"debug/elf"
+	"debug/gosym"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/prometheus/procfs"
+
+	"github.com/grafana/beyla/pkg/internal/exec"
+)
+
+const (
+	// minimum time between two reloads of the memory mappings of a process
+	mapsRefreshInterval = 10 * time.Second
+	// maximum number of executables and libraries whose symbols are cached
+	filesCacheLen = 256
+
+	kernelMappingName = "[kernel.kallsyms]"
+)
+
+// symbolizer translates the addresses of the sampled stacks into function names. Go executables
+// are symbolized from their .gopclntab section, which also provides the file and line of each
+// frame, and other executables and libraries from their ELF symbols tables.
+type symbolizer struct {
+	procRoot string
+
+	mt        sync.Mutex
+	processes map[uint32]*processSymbols
+	files     *lru.Cache[fileKey, *fileSymbols]
+	kernel    *kernelSymbols
+}
+
+type processSymbols struct {
+	mappings  []*procMapping
+	refreshed time.Time
+}
+
+type procMapping struct {
+	Mapping
+	symbols *fileSymbols
+}
+
+type fileKey struct {
+	dev   uint64
+	inode uint64
+	path  string
+}
+
+type elfSymbol struct {
+	addr uint64
+	size uint64
+	name string
+}
+
+// fileSymbols of an executable or library
+type fileSymbols struct {
+	loads []elf.ProgHeader
+	// Go symbols table, if the file is a Go executable
+	gotab *gosym.Table
+	// ELF function symbols, sorted by address
+	syms []elfSymbol
+}
+
+type kernelSymbols struct {
+	syms []elfSymbol
+}
+
+func newSymbolizer(procRoot string) *symbolizer {
+	files, _ := lru.New[fileKey, *fileSymbols](filesCacheLen)
+	return &symbolizer{
+		procRoot:  procRoot,
+		processes: map[uint32]*processSymbols{},
+		files:     files,
+	}
+}
+
+// forget the memory mappings of a process that is not profiled anymore
+func (s *symbolizer) forget(pid uint32) {
+	s.mt.Lock()
+	defer s.mt.Unlock()
+	delete(s.processes, pid)
+}
+
+// userFrames symbolizes a stack of user-space addresses, from the leaf to the root
+func (s *symbolizer) userFrames(pid uint32, addrs []uint64) []Frame {
+	s.mt.Lock()
+	defer s.mt.Unlock()
+	frames := make([]Frame, 0, len(addrs))
+	for i, addr := range addrs {
+		frames = append(frames, s.userFrame(pid, addr, i == 0))
+	}
+	return frames
+}
+
+func (s *symbolizer) userFrame(pid uint32, addr uint64, leaf bool) Frame {
+	m := s.mappingFor(pid, addr)
+	if m == nil {
+		return Frame{Address: addr}
+	}
+	frame := Frame{Address: addr, Mapping: &m.Mapping}
+	if m.symbols != nil {
+		// the addresses of the non-leaf frames are return addresses, which point to the instruction
+		// after the call, and might belong to the next line or even to the next function
+		lookupAddr := addr
+		if !leaf {
+			lookupAddr--
+		}
+		frame.Function, frame.File, frame.Line = m.symbols.lookup(lookupAddr - m.Start + m.Offset)
+	}
+	return frame
+}
+
+// kernelFrames symbolizes a stack of kernel addresses, from the leaf to the root
+func (s *symbolizer) kernelFrames(addrs []uint64) []Frame {
+	s.mt.Lock()
+	defer s.mt.Unlock()
+	if s.kernel == nil {
+		s.kernel = s.loadKernelSymbols()
+	}
+	frames := make([]Frame, 0, len(addrs))
+	for _, addr := range addrs {
+		frames = append(frames, Frame{Address: addr, Function: s.kernel.lookup(addr)})
+	}
+	return frames
+}
+
+func (s *symbolizer) mappingFor(pid uint32, addr uint64) *procMapping {
+	ps, ok := s.processes[pid]
+	if !ok || (findMapping(ps.mappings, addr) == nil && time.Since(ps.refreshed) > mapsRefreshInterval) {
+		// the process might have loaded new libraries since the last time its mappings were read
+		ps = s.loadProcess(pid)
+		s.processes[pid] = ps
+	}
+	return findMapping(ps.mappings, addr)
+}
+
+func findMapping(mappings []*procMapping, addr uint64) *procMapping {
+	i := sort.Search(len(mappings), func(i int) bool { return mappings[i].Limit > addr })
+	if i < len(mappings) && mappings[i].Start <= addr {
+		return mappings[i]
+	}
+	return nil
+}
+
+func (s *symbolizer) loadProcess(pid uint32) *processSymbols {
+	ps := &processSymbols{refreshed: time.Now()}
+	maps, err := exec.FindLibMaps(int32(pid))
+	if err != nil {
+		plog().Debug("can't read process memory mappings", "pid", pid, "error", err)
+		return ps
+	}
+	for _, m := range maps {
+		if m.Perms == nil || !m.Perms.Execute || m.Pathname == "" || strings.HasPrefix(m.Pathname, "[") {
+			continue
+		}
+		ps.mappings = append(ps.mappings, &procMapping{
+			Mapping: Mapping{
+				Start:  uint64(m.StartAddr),
+				Limit:  uint64(m.EndAddr),
+				Offset: uint64(m.Offset),
+				File:   m.Pathname,
+			},
+			symbols: s.fileSymbols(pid, m),
+		})
+	}
+	sort.Slice(ps.mappings, func(i, j int) bool { return ps.mappings[i].Start < ps.mappings[j].Start })
+	return ps
+}
+
+func (s *symbolizer) fileSymbols(pid uint32, m *procfs.ProcMap) *fileSymbols {
+	key := fileKey{dev: m.Dev, inode: m.Inode, path: m.Pathname}
+	if fs, ok := s.files.Get(key); ok {
+		return fs
+	}
+	// the file is accessed from the root of the process, which might run in another container
+	path := fmt.Sprintf("%s/%d/root%s", s.procRoot, pid, m.Pathname)
+	fs, err := loadFileSymbols(path)
+	if err != nil {
+		plog().Debug("can't load symbols", "pid", pid, "path", m.Pathname, "error", err)
+	}
+	// caching also nil values, to avoid trying to load unreadable files again
+	s.files.Add(key, fs)
+	return fs
+}
+
+func loadFileSymbols(path string) (*fileSymbols, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fs := &fileSymbols{}
+	for _, p := range f.Progs {
+		if p.Type == elf.PT_LOAD {
+			fs.loads = append(fs.loads, p.ProgHeader)
+		}
+	}
+
+	if pclntab, text := f.Section(".gopclntab"), f.Section(".text"); pclntab != nil && text != nil {
+		if data, err := pclntab.Data(); err == nil {
+			if tab, err := gosym.NewTable(nil, gosym.NewLineTable(data, text.Addr)); err == nil {
+				fs.gotab = tab
+				return fs, nil
+			}
+		}
+	}
+
+	for _, load := range []func() ([]elf.Symbol, error){f.Symbols, f.DynamicSymbols} {
+		syms, err := load()
+		if err != nil && !errors.Is(err, elf.ErrNoSymbols) {
+			return nil, err
+		}
+		for _, sym := range syms {
+			if elf.ST_TYPE(sym.Info) == elf.STT_FUNC && sym.Value != 0 {
+				fs.syms = append(fs.syms, elfSymbol{addr: sym.Value, size: sym.Size, name: sym.Name})
+			}
+		}
+	}
+	sort.Slice(fs.syms, func(i, j int) bool { return fs.syms[i].addr < fs.syms[j].addr })
+	return fs, nil
+}
+
+// lookup the function containing the given file offset
+func (fs *fileSymbols) lookup(fileOffset uint64) (function, file string, line int) {
+	vaddr, ok := fs.vaddr(fileOffset)
+	if !ok {
+		return "", "", 0
+	}
+	if fs.gotab != nil {
+		file, line, fn := fs.gotab.PCToLine(vaddr)
+		if fn == nil {
+			return "", "", 0
+		}
+		return fn.Name, file, line
+	}
+	return lookupSymbol(fs.syms, vaddr), "", 0
+}
+
+// vaddr translates an offset in the file to the virtual address of the ELF symbols
+func (fs *fileSymbols) vaddr(fileOffset uint64) (uint64, bool) {
+	for i := range fs.loads {
+		p := &fs.loads[i]
+		if p.Off <= fileOffset && fileOffset < p.Off+p.Filesz {
+			return fileOffset - p.Off + p.Vaddr, true
+		}
+	}
+	return 0, false
+}
+
+func lookupSymbol(syms []elfSymbol, addr uint64) string {
+	i := sort.Search(len(syms), func(i int) bool { return syms[i].addr > addr }) - 1
+	if i < 0 {
+		return ""
+	}
+	if sym := &syms[i]; sym.size == 0 || addr < sym.addr+sym.size {
+		return sym.name
+	}
+	return ""
+}
+
+func (s *symbolizer) loadKernelSymbols() *kernelSymbols {
+	ks := &kernelSymbols{}
+	f, err := os.Open(s.procRoot + "/kallsyms")
+	if err != nil {
+		plog().Debug("can't read kernel symbols", "error", err)
+		return ks
+	}
+	defer f.Close()
+	ks.syms = parseKallsyms(bufio.NewScanner(f))
+	return ks
+}
+
+// parseKallsyms returns the text symbols of the kernel, sorted by address. If the addresses
+// are hidden to Beyla (all of them are zero), it returns no symbols.
+func parseKallsyms(scanner *bufio.Scanner) []elfSymbol {
+	var syms []elfSymbol
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		switch fields[1] {
+		case "T", "t", "W", "w":
+		default:
+			continue
+		}
+		addr, err := strconv.ParseUint(fields[0], 16, 64)
+		if err != nil || addr == 0 {
+			continue
+		}
+		syms = append(syms, elfSymbol{addr: addr, name: fields[2]})
+	}
+	sort.Slice(syms, func(i, j int) bool { return syms[i].addr < syms[j].addr })
+	return syms
+}
+
+func (ks *kernelSymbols) lookup(addr uint64) string {
+	if name := lookupSymbol(ks.syms, addr); name != "" {
+		return name
+	}
+	return kernelMappingName
+}
@@ -0,0 +1,52 @@
+package profile
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSymbolizer_GoExecutable(t *testing.T) {
+	// symbolizing a function of the test executable itself
+	pc := reflect.ValueOf(TestSymbolizer_GoExecutable).Pointer()
+	s := newSymbolizer("/proc")
+	frames := s.userFrames(uint32(os.Getpid()), []uint64{uint64(pc)})
+	require.Len(t, frames, 1)
+	assert.Equal(t, runtime.FuncForPC(pc).Name(), frames[0].Function)
+	assert.True(t, strings.HasSuffix(frames[0].File, "symbols_test.go"), frames[0].File)
+	require.NotNil(t, frames[0].Mapping)
+	assert.NotEmpty(t, frames[0].Mapping.File)
+}
+
+func TestSymbolizer_UnknownAddress(t *testing.T) {
+	s := newSymbolizer("/proc")
+	frames := s.userFrames(uint32(os.Getpid()), []uint64{0x10})
+	require.Len(t, frames, 1)
+	assert.Equal(t, Frame{Address: 0x10}, frames[0])
+}
+
+func TestParseKallsyms(t *testing.T) {
+	syms := parseKallsyms(bufio.NewScanner(strings.NewReader(`ffffffff81000200 T do_syscall_64
+ffffffff81000000 T _stext
+ffffffff81002000 D some_data
+ffffffff81001000 t tcp_sendmsg	[kernel]
+`)))
+	ks := kernelSymbols{syms: syms}
+	assert.Equal(t, "_stext", ks.lookup(0xffffffff81000100))
+	assert.Equal(t, "do_syscall_64", ks.lookup(0xffffffff81000300))
+	assert.Equal(t, "tcp_sendmsg", ks.lookup(0xffffffff81001010))
+	assert.Equal(t, kernelMappingName, ks.lookup(0x1000))
+}
+
+func TestParseKallsyms_HiddenAddresses(t *testing.T) {
+	syms := parseKallsyms(bufio.NewScanner(strings.NewReader(`0000000000000000 T _stext
+0000000000000000 T do_syscall_64
+`)))
+	assert.Empty(t, syms)
+}
@@ -103,6 +103,34 @@ func MessagingOperationType(val string) attribute.KeyValue {
 	return attribute.Key(attr.MessagingOpType).String(val)
 }
 
+func MessagingPartition(val string) attribute.KeyValue {
+	return attribute.Key(attr.MessagingPartition).String(val)
+}
+
+func DBRedisKeyPattern(val string) attribute.KeyValue {
+	return attribute.Key(attr.DBRedisKeyPattern).String(val)
+}
+
+func DBRedisCacheResult(val string) attribute.KeyValue {
+	return attribute.Key(attr.DBRedisCacheResult).String(val)
+}
+
+func DNSQuestionName(val string) attribute.KeyValue {
+	return attribute.Key(attr.DNSQuestionName).String(val)
+}
+
+func DNSQuestionType(val string) attribute.KeyValue {
+	return attribute.Key(attr.DNSQuestionType).String(val)
+}
+
+func DNSResponseCode(val string) attribute.KeyValue {
+	return attribute.Key(attr.DNSResponseCode).String(val)
+}
+
+func DNSAnswers(val []string) attribute.KeyValue {
+	return attribute.Key(attr.DNSAnswers).StringSlice(val)
+}
+
 func SpanHost(span *Span) string {
 	if span.HostName != "" {
 		return span.HostName
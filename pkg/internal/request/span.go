@@ -29,6 +29,8 @@ const (
 	EventTypeKafkaClient
 	EventTypeRedisServer
 	EventTypeKafkaServer
+	EventTypeMongoClient
+	EventTypeDNSClient
 )
 
 func (t EventType) String() string {
@@ -51,6 +53,10 @@ func (t EventType) String() string {
 		return "RedisServer"
 	case EventTypeKafkaServer:
 		return "KafkaServer"
+	case EventTypeMongoClient:
+		return "MongoClient"
+	case EventTypeDNSClient:
+		return "DNSClient"
 	default:
 		return fmt.Sprintf("UNKNOWN (%d)", t)
 	}
@@ -60,6 +66,100 @@ func (t EventType) MarshalText() ([]byte, error) {
 	return []byte(t.String()), nil
 }
 
+// SQL database engines, stored in the SubType field of the EventTypeSQLClient spans
+const (
+	DBGeneric = iota
+	DBPostgres
+	DBMySQL
+)
+
+// DBError stores the error returned by a database server
+type DBError struct {
+	// ErrorCode is the error code returned by the database (e.g. the SQLSTATE for PostgreSQL,
+	// or the error number for MySQL)
+	ErrorCode   string
+	Description string
+}
+
+// MessagingInfo stores the details about the partition involved in a message publish or fetch operation,
+// when they can be parsed from the captured messages.
+type MessagingInfo struct {
+	Partition int
+	// Messages and Bytes published or fetched
+	Messages int
+	Bytes    int
+	// Offset of the last produced or consumed message. -1 if unknown.
+	Offset int64
+	// HighWatermark of the partition as reported by the broker on fetch operations. -1 if unknown.
+	HighWatermark int64
+}
+
+// ConsumerLag returns the approximate number of messages that are still pending to be consumed
+// from the partition, and false if it can't be calculated.
+func (m *MessagingInfo) ConsumerLag() (int64, bool) {
+	if m.Offset < 0 || m.HighWatermark < 0 {
+		return 0, false
+	}
+	return max(m.HighWatermark-m.Offset-1, 0), true
+}
+
+// Cache lookup results, stored in the RedisInfo of the spans for read commands
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// RedisInfo stores the details about the keys and commands of a Redis request,
+// when they can be parsed from the captured messages.
+type RedisInfo struct {
+	// Key accessed by the first command of the request
+	Key string
+	// KeyPattern is the low-cardinality family of the Key (e.g. user:*:cart). It is
+	// set by the transform.RedisKeys decorator.
+	KeyPattern string
+	// CacheResult is CacheHit or CacheMiss for the GET-family commands, or empty for the rest
+	CacheResult string
+	// PipelineSize is the number of commands that were sent in the same request
+	PipelineSize int
+}
+
+// DNSInfo stores the details of a DNS response that are not part of the common span fields.
+// The question name, record type and response code are stored in the Path, Method and Status
+// fields of the span.
+type DNSInfo struct {
+	// Addresses returned by the A and AAAA answers of the response
+	Addresses []string
+}
+
+// DNS response codes, stored in the Status field of the EventTypeDNSClient spans
+const (
+	DNSResponseNoError  = 0
+	DNSResponseFormErr  = 1
+	DNSResponseServFail = 2
+	DNSResponseNXDomain = 3
+	DNSResponseNotImp   = 4
+	DNSResponseRefused  = 5
+)
+
+// DNSResponseCodeName returns the textual representation of a DNS response code, as defined in RFC 1035
+func DNSResponseCodeName(rcode int) string {
+	switch rcode {
+	case DNSResponseNoError:
+		return "NOERROR"
+	case DNSResponseFormErr:
+		return "FORMERR"
+	case DNSResponseServFail:
+		return "SERVFAIL"
+	case DNSResponseNXDomain:
+		return "NXDOMAIN"
+	case DNSResponseNotImp:
+		return "NOTIMP"
+	case DNSResponseRefused:
+		return "REFUSED"
+	}
+	return "RCODE" + strconv.Itoa(rcode)
+}
+
 type IgnoreMode uint8
 
 const (
@@ -136,6 +236,11 @@ type Span struct {
 	HostName       string         `json:"hostName"`
 	OtherNamespace string         `json:"-"`
 	Statement      string         `json:"-"`
+	SubType        int            `json:"-"`
+	DBError        DBError        `json:"-"`
+	Messaging      *MessagingInfo `json:"-"`
+	DNS            *DNSInfo       `json:"-"`
+	Redis          *RedisInfo     `json:"-"`
 }
 
 func (s *Span) Inside(parent *Span) bool {
@@ -205,6 +310,21 @@ func spanAttributes(s *Span) SpanAttributes {
 			"operation":  s.Method,
 			"clientId":   s.OtherNamespace,
 		}
+	case EventTypeMongoClient:
+		return SpanAttributes{
+			"serverAddr": SpanHost(s),
+			"serverPort": strconv.Itoa(s.HostPort),
+			"operation":  s.Method,
+			"collection": s.Path,
+		}
+	case EventTypeDNSClient:
+		return SpanAttributes{
+			"serverAddr":   SpanHost(s),
+			"serverPort":   strconv.Itoa(s.HostPort),
+			"questionName": s.Path,
+			"questionType": s.Method,
+			"responseCode": DNSResponseCodeName(s.Status),
+		}
 	}
 
 	return SpanAttributes{}
@@ -282,7 +402,8 @@ func (s *Span) IsValid() bool {
 
 func (s *Span) IsClientSpan() bool {
 	switch s.Type {
-	case EventTypeGRPCClient, EventTypeHTTPClient, EventTypeRedisClient, EventTypeKafkaClient, EventTypeSQLClient:
+	case EventTypeGRPCClient, EventTypeHTTPClient, EventTypeRedisClient, EventTypeKafkaClient, EventTypeSQLClient,
+		EventTypeMongoClient, EventTypeDNSClient:
 		return true
 	}
 
@@ -295,7 +416,7 @@ func SpanStatusCode(span *Span) codes.Code {
 		return HTTPSpanStatusCode(span)
 	case EventTypeGRPC, EventTypeGRPCClient:
 		return GrpcSpanStatusCode(span)
-	case EventTypeSQLClient, EventTypeRedisClient, EventTypeRedisServer:
+	case EventTypeSQLClient, EventTypeRedisClient, EventTypeRedisServer, EventTypeMongoClient, EventTypeDNSClient:
 		if span.Status != 0 {
 			return codes.Error
 		}
@@ -355,7 +476,8 @@ func (s *Span) ServiceGraphKind() string {
 	switch s.Type {
 	case EventTypeHTTP, EventTypeGRPC, EventTypeKafkaServer, EventTypeRedisServer:
 		return "SPAN_KIND_SERVER"
-	case EventTypeHTTPClient, EventTypeGRPCClient, EventTypeSQLClient, EventTypeRedisClient:
+	case EventTypeHTTPClient, EventTypeGRPCClient, EventTypeSQLClient, EventTypeRedisClient, EventTypeMongoClient,
+		EventTypeDNSClient:
 		return "SPAN_KIND_CLIENT"
 	case EventTypeKafkaClient:
 		switch s.Method {
@@ -395,6 +517,19 @@ func (s *Span) TraceName() string {
 			return "REDIS"
 		}
 		return s.Method
+	case EventTypeMongoClient:
+		if s.Method == "" {
+			return "MONGODB"
+		}
+		if s.Path == "" {
+			return s.Method
+		}
+		return s.Method + " " + s.Path
+	case EventTypeDNSClient:
+		if s.Method == "" {
+			return "DNS"
+		}
+		return "DNS " + s.Method
 	case EventTypeKafkaClient, EventTypeKafkaServer:
 		if s.Path == "" {
 			return s.Method
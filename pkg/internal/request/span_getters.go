@@ -75,21 +75,17 @@ func SpanOTELGetters(name attr.Name) (attributes.Getter[*Span, attribute.KeyValu
 		getter = func(span *Span) attribute.KeyValue { return DBOperationName(span.Method) }
 	case attr.DBSystem:
 		getter = func(span *Span) attribute.KeyValue {
-			switch span.Type {
-			case EventTypeSQLClient:
-				return DBSystem(semconv.DBSystemOtherSQL.Value.AsString())
-			case EventTypeRedisClient, EventTypeRedisServer:
-				return DBSystem(semconv.DBSystemRedis.Value.AsString())
-			}
-			return DBSystem("unknown")
+			return DBSystem(DBSystemName(span))
 		}
-	case attr.ErrorType:
+	case attr.DBCollectionName:
 		getter = func(span *Span) attribute.KeyValue {
-			if SpanStatusCode(span) == codes.Error {
-				return ErrorType("error")
+			if span.Type == EventTypeSQLClient || span.Type == EventTypeMongoClient {
+				return DBCollectionName(span.Path)
 			}
-			return ErrorType("")
+			return DBCollectionName("")
 		}
+	case attr.ErrorType:
+		getter = func(span *Span) attribute.KeyValue { return ErrorType(SpanErrorType(span)) }
 	case attr.MessagingSystem:
 		getter = func(span *Span) attribute.KeyValue {
 			if span.Type == EventTypeKafkaClient || span.Type == EventTypeKafkaServer {
@@ -104,6 +100,20 @@ func SpanOTELGetters(name attr.Name) (attributes.Getter[*Span, attribute.KeyValu
 			}
 			return semconv.MessagingDestinationName("")
 		}
+	case attr.MessagingOpType:
+		getter = func(span *Span) attribute.KeyValue { return MessagingOperationType(span.Method) }
+	case attr.MessagingPartition:
+		getter = func(span *Span) attribute.KeyValue { return MessagingPartition(SpanMessagingPartition(span)) }
+	case attr.DNSQuestionName:
+		getter = func(span *Span) attribute.KeyValue { return DNSQuestionName(span.Path) }
+	case attr.DNSQuestionType:
+		getter = func(span *Span) attribute.KeyValue { return DNSQuestionType(span.Method) }
+	case attr.DNSResponseCode:
+		getter = func(span *Span) attribute.KeyValue { return DNSResponseCode(DNSResponseCodeName(span.Status)) }
+	case attr.DBRedisKeyPattern:
+		getter = func(span *Span) attribute.KeyValue { return DBRedisKeyPattern(SpanRedisKeyPattern(span)) }
+	case attr.DBRedisCacheResult:
+		getter = func(span *Span) attribute.KeyValue { return DBRedisCacheResult(SpanRedisCacheResult(span)) }
 	}
 	// default: unlike the Prometheus getters, we don't check here for service name nor k8s metadata
 	// because they are already attributes of the Resource instead of the attributes.
@@ -139,26 +149,15 @@ func SpanPromGetters(attrName attr.Name) (attributes.Getter[*Span, string], bool
 	case attr.DBOperation:
 		getter = func(span *Span) string { return span.Method }
 	case attr.ErrorType:
-		getter = func(span *Span) string {
-			if SpanStatusCode(span) == codes.Error {
-				return "error"
-			}
-			return ""
-		}
+		getter = SpanErrorType
 	case attr.DBSystem:
 		getter = func(span *Span) string {
-			switch span.Type {
-			case EventTypeSQLClient:
-				return semconv.DBSystemOtherSQL.Value.AsString()
-			case EventTypeRedisClient, EventTypeRedisServer:
-				return semconv.DBSystemRedis.Value.AsString()
-			}
-			return "unknown"
+			return DBSystemName(span)
 		}
 	case attr.DBCollectionName:
 		getter = func(span *Span) string {
-			if span.Type == EventTypeSQLClient {
-				return semconv.DBSystemOtherSQL.Value.AsString()
+			if span.Type == EventTypeSQLClient || span.Type == EventTypeMongoClient {
+				return span.Path
 			}
 			return ""
 		}
@@ -176,6 +175,20 @@ func SpanPromGetters(attrName attr.Name) (attributes.Getter[*Span, string], bool
 			}
 			return ""
 		}
+	case attr.MessagingOpType:
+		getter = func(span *Span) string { return span.Method }
+	case attr.MessagingPartition:
+		getter = SpanMessagingPartition
+	case attr.DNSQuestionName:
+		getter = func(span *Span) string { return span.Path }
+	case attr.DNSQuestionType:
+		getter = func(span *Span) string { return span.Method }
+	case attr.DNSResponseCode:
+		getter = func(span *Span) string { return DNSResponseCodeName(span.Status) }
+	case attr.DBRedisKeyPattern:
+		getter = SpanRedisKeyPattern
+	case attr.DBRedisCacheResult:
+		getter = SpanRedisCacheResult
 	// resource metadata values below. Unlike OTEL, they are included here because they
 	// belong to the metric, instead of the Resource
 	case attr.ServiceName:
@@ -187,3 +200,64 @@ func SpanPromGetters(attrName attr.Name) (attributes.Getter[*Span, string], bool
 	}
 	return getter, getter != nil
 }
+
+// DBSystemName returns the value of the db.system attribute for the database client spans
+func DBSystemName(span *Span) string {
+	switch span.Type {
+	case EventTypeSQLClient:
+		switch span.SubType {
+		case DBPostgres:
+			return semconv.DBSystemPostgreSQL.Value.AsString()
+		case DBMySQL:
+			return semconv.DBSystemMySQL.Value.AsString()
+		}
+		return semconv.DBSystemOtherSQL.Value.AsString()
+	case EventTypeRedisClient, EventTypeRedisServer:
+		return semconv.DBSystemRedis.Value.AsString()
+	case EventTypeMongoClient:
+		return semconv.DBSystemMongoDB.Value.AsString()
+	}
+	return "unknown"
+}
+
+// SpanErrorType returns the value of the error.type attribute: empty if the span is not erroneous,
+// the error code returned by the database server (if any), the DNS response code for DNS
+// lookups, or "error" otherwise.
+func SpanErrorType(span *Span) string {
+	if SpanStatusCode(span) != codes.Error {
+		return ""
+	}
+	if span.DBError.ErrorCode != "" {
+		return span.DBError.ErrorCode
+	}
+	if span.Type == EventTypeDNSClient {
+		return DNSResponseCodeName(span.Status)
+	}
+	return "error"
+}
+
+// SpanMessagingPartition returns the partition of a messaging span, or an empty
+// string if it is unknown
+func SpanMessagingPartition(span *Span) string {
+	if span.Messaging == nil {
+		return ""
+	}
+	return strconv.Itoa(span.Messaging.Partition)
+}
+
+// SpanRedisKeyPattern returns the pattern of the key of a Redis span, or an empty string
+// if it is unknown or the Redis key patterns are not configured
+func SpanRedisKeyPattern(span *Span) string {
+	if span.Redis == nil {
+		return ""
+	}
+	return span.Redis.KeyPattern
+}
+
+// SpanRedisCacheResult returns whether a Redis read command was a cache hit or miss
+func SpanRedisCacheResult(span *Span) string {
+	if span.Redis == nil {
+		return ""
+	}
+	return span.Redis.CacheResult
+}
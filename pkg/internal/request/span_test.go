@@ -36,6 +36,7 @@ func TestEventTypeString(t *testing.T) {
 		EventTypeKafkaClient: "KafkaClient",
 		EventTypeRedisServer: "RedisServer",
 		EventTypeKafkaServer: "KafkaServer",
+		EventTypeDNSClient:   "DNSClient",
 		EventType(99):        "UNKNOWN (99)",
 	}
 
@@ -67,6 +68,7 @@ func TestKindString(t *testing.T) {
 		&Span{Type: EventTypeGRPCClient}:                            "SPAN_KIND_CLIENT",
 		&Span{Type: EventTypeSQLClient}:                             "SPAN_KIND_CLIENT",
 		&Span{Type: EventTypeRedisClient}:                           "SPAN_KIND_CLIENT",
+		&Span{Type: EventTypeDNSClient}:                             "SPAN_KIND_CLIENT",
 		&Span{Type: EventTypeKafkaClient, Method: MessagingPublish}: "SPAN_KIND_PRODUCER",
 		&Span{Type: EventTypeKafkaClient, Method: MessagingProcess}: "SPAN_KIND_CONSUMER",
 		&Span{}: "SPAN_KIND_INTERNAL",
@@ -238,3 +240,27 @@ func TestSerializeJSONSpans(t *testing.T) {
 		test(t, &tData[i])
 	}
 }
+
+func TestDBSystemAndErrorType(t *testing.T) {
+	assert.Equal(t, "other_sql", DBSystemName(&Span{Type: EventTypeSQLClient}))
+	assert.Equal(t, "postgresql", DBSystemName(&Span{Type: EventTypeSQLClient, SubType: DBPostgres}))
+	assert.Equal(t, "mysql", DBSystemName(&Span{Type: EventTypeSQLClient, SubType: DBMySQL}))
+	assert.Equal(t, "redis", DBSystemName(&Span{Type: EventTypeRedisClient}))
+
+	assert.Empty(t, SpanErrorType(&Span{Type: EventTypeSQLClient}))
+	assert.Equal(t, "error", SpanErrorType(&Span{Type: EventTypeSQLClient, Status: 1}))
+	assert.Equal(t, "23505", SpanErrorType(&Span{Type: EventTypeSQLClient, Status: 1,
+		SubType: DBPostgres, DBError: DBError{ErrorCode: "23505"}}))
+}
+
+func TestDNSSpan(t *testing.T) {
+	span := &Span{Type: EventTypeDNSClient, Method: "AAAA", Path: "example.com"}
+	assert.True(t, span.IsClientSpan())
+	assert.Equal(t, "DNS AAAA", span.TraceName())
+	assert.Empty(t, SpanErrorType(span))
+
+	span.Status = DNSResponseNXDomain
+	assert.Equal(t, "NXDOMAIN", SpanErrorType(span))
+	assert.Equal(t, "SERVFAIL", DNSResponseCodeName(DNSResponseServFail))
+	assert.Equal(t, "RCODE9", DNSResponseCodeName(9))
+}
@@ -0,0 +1,153 @@
+package slo
+
+import (
+	"fmt"
+	"time"
+
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/pkg/services"
+)
+
+// DefaultWindow of the objectives that don't explicitly define it
+const DefaultWindow = 30 * 24 * time.Hour
+
+// DefaultBurnRateWindows follow the multi-window, multi-burn-rate alerting recommendations
+// from the Google SRE workbook
+var DefaultBurnRateWindows = []time.Duration{
+	5 * time.Minute, 30 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour, 3 * 24 * time.Hour,
+}
+
+// metadata names, as used in discovery.services, that can be used to select the services of an objective
+var selectorMetadata = map[string]attr.Name{
+	services.AttrNamespace:       attr.K8sNamespaceName,
+	services.AttrPodName:         attr.K8sPodName,
+	services.AttrDeploymentName:  attr.K8sDeploymentName,
+	services.AttrReplicaSetName:  attr.K8sReplicaSetName,
+	services.AttrDaemonSetName:   attr.K8sDaemonSetName,
+	services.AttrStatefulSetName: attr.K8sStatefulSetName,
+}
+
+// Config of the Service Level Objectives that are evaluated from the application spans.
+type Config struct {
+	// EvaluationInterval between two consecutive reports of the objectives status
+	EvaluationInterval time.Duration `yaml:"evaluation_interval" env:"BEYLA_SLO_EVALUATION_INTERVAL"`
+	// BurnRateWindows for which the error budget burn rate is reported
+	BurnRateWindows []time.Duration `yaml:"burn_rate_windows" env:"BEYLA_SLO_BURN_RATE_WINDOWS" envSeparator:","`
+	Objectives      []Objective     `yaml:"objectives"`
+}
+
+func (c *Config) Enabled() bool {
+	return len(c.Objectives) > 0
+}
+
+func (c *Config) Validate() error {
+	names := map[string]struct{}{}
+	for i := range c.Objectives {
+		o := &c.Objectives[i]
+		if o.Name == "" {
+			return fmt.Errorf("slo.objectives[%d] must define a name", i)
+		}
+		if _, ok := names[o.Name]; ok {
+			return fmt.Errorf("slo.objectives[%d]: duplicate name %q", i, o.Name)
+		}
+		names[o.Name] = struct{}{}
+		if err := o.validate(); err != nil {
+			return fmt.Errorf("slo.objectives[%d] (%s): %w", i, o.Name, err)
+		}
+	}
+	for _, w := range c.BurnRateWindows {
+		if w <= 0 {
+			return fmt.Errorf("slo.burn_rate_windows must be positive durations. Got: %s", w)
+		}
+	}
+	return nil
+}
+
+// Objective defines the availability and/or latency targets of a group of services
+type Objective struct {
+	Name string `yaml:"name"`
+	// Services that are selected by this objective. If a service matches multiple selectors, it is
+	// only accounted once. If empty, the objective applies to all the instrumented services, which are
+	// evaluated separately.
+	Services []ServiceSelector `yaml:"services"`
+	// Availability is the target ratio of requests that must not fail (e.g. 0.999). Zero means no target.
+	Availability float64 `yaml:"availability"`
+	// Latency target, if defined
+	Latency LatencyObjective `yaml:"latency"`
+	// Window of time over which the objective is evaluated (e.g. 30 days)
+	Window time.Duration `yaml:"window"`
+}
+
+// LatencyObjective defines the ratio of requests that must be served below a time threshold
+type LatencyObjective struct {
+	Threshold time.Duration `yaml:"threshold"`
+	// Target ratio of requests that must be faster than Threshold (e.g. 0.99). Zero means no target.
+	Target float64 `yaml:"target"`
+}
+
+func (o *Objective) validate() error {
+	if o.Availability == 0 && o.Latency.Target == 0 {
+		return fmt.Errorf("must define an availability or latency target")
+	}
+	if o.Availability < 0 || o.Availability >= 1 {
+		return fmt.Errorf("availability must be a ratio between 0 and 1 (exclusive). Got %v", o.Availability)
+	}
+	if o.Latency.Target < 0 || o.Latency.Target >= 1 {
+		return fmt.Errorf("latency.target must be a ratio between 0 and 1 (exclusive). Got %v", o.Latency.Target)
+	}
+	if o.Latency.Target > 0 && o.Latency.Threshold <= 0 {
+		return fmt.Errorf("latency.threshold must be a positive duration")
+	}
+	if o.Window < 0 {
+		return fmt.Errorf("window must be a positive duration")
+	}
+	for i := range o.Services {
+		for k := range o.Services[i].Metadata {
+			if _, ok := selectorMetadata[k]; !ok {
+				return fmt.Errorf("unknown attribute in services[%d]: %s", i, k)
+			}
+		}
+	}
+	return nil
+}
+
+func (o *Objective) window() time.Duration {
+	if o.Window == 0 {
+		return DefaultWindow
+	}
+	return o.Window
+}
+
+func (o *Objective) selects(service *svc.ID) bool {
+	if len(o.Services) == 0 {
+		return true
+	}
+	for i := range o.Services {
+		if o.Services[i].matches(service) {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceSelector selects services by their name, namespace or Kubernetes metadata, using the same
+// attribute names as the discovery.services section. All the defined attributes must match.
+type ServiceSelector struct {
+	Name      services.RegexpAttr `yaml:"name"`
+	Namespace services.RegexpAttr `yaml:"namespace"`
+	// Metadata stores the Kubernetes attributes (k8s_namespace, k8s_deployment_name...)
+	Metadata map[string]*services.RegexpAttr `yaml:",inline"`
+}
+
+func (s *ServiceSelector) matches(service *svc.ID) bool {
+	if !s.Name.MatchString(service.Name) || !s.Namespace.MatchString(service.Namespace) {
+		return false
+	}
+	for k, re := range s.Metadata {
+		if !re.MatchString(service.Metadata[selectorMetadata[k]]) {
+			return false
+		}
+	}
+	return true
+}
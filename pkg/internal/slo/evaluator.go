@@ -0,0 +1,223 @@
+// Package slo evaluates Service Level Objectives from the application spans, and reports
+// periodically the remaining error budget and its burn rate for each objective and service.
+package slo
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/mariomac/pipes/pipe"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+)
+
+// Service Level Indicators
+const (
+	SLIAvailability = "availability"
+	SLILatency      = "latency"
+)
+
+const (
+	// burnRateResolution is the size of the time slots used to calculate the burn rates
+	burnRateResolution = time.Minute
+	// maxWindowSlots limits the memory used to calculate the error budget over the objective window
+	maxWindowSlots = 720
+)
+
+func slolog() *slog.Logger {
+	return slog.With("component", "slo.Evaluator")
+}
+
+// Status of a Service Level Indicator of an objective, for a given service
+type Status struct {
+	Objective string
+	SLI       string
+	Service   *svc.ID
+	// Target ratio of good requests
+	Target float64
+	// ErrorBudgetRemaining during the objective window, as a ratio: 1 means that the
+	// error budget is intact, and 0 (or less) that it has been exhausted.
+	ErrorBudgetRemaining float64
+	BurnRates            []BurnRate
+}
+
+// BurnRate is the ratio of bad requests during a time window, divided by the
+// ratio of bad requests that is allowed by the objective. A burn rate of 1 would
+// exhaust the error budget exactly at the end of the objective window.
+type BurnRate struct {
+	Window time.Duration
+	Rate   float64
+}
+
+// EvaluatorProvider returns a pipeline start node that accounts the spans from the input channel
+// and periodically forwards the status of each objective and service.
+func EvaluatorProvider(ctx context.Context, input *<-chan []request.Span, cfg *Config) pipe.StartProvider[[]*Status] {
+	return func() (pipe.StartFunc[[]*Status], error) {
+		ev := NewEvaluator(cfg, time.Now)
+		return func(out chan<- []*Status) {
+			ticker := time.NewTicker(cfg.EvaluationInterval)
+			defer ticker.Stop()
+			in := *input
+			for {
+				select {
+				case <-ctx.Done():
+					ev.log.Debug("exiting")
+					return
+				case spans, ok := <-in:
+					if !ok {
+						return
+					}
+					ev.Record(spans)
+				case <-ticker.C:
+					out <- ev.Evaluate()
+				}
+			}
+		}, nil
+	}
+}
+
+// Evaluator accounts the good and bad requests of each SLI, for each objective and service.
+// It is not safe for concurrent use.
+type Evaluator struct {
+	log             *slog.Logger
+	clock           func() time.Time
+	objectives      []Objective
+	burnRateWindows []time.Duration
+	series          map[seriesKey]*series
+}
+
+type seriesKey struct {
+	objective int
+	service   svc.UID
+}
+
+type series struct {
+	objective *Objective
+	service   svc.ID
+	lastSeen  time.Time
+	// any of them might be nil if the objective does not define it
+	availability *sliCounters
+	latency      *sliCounters
+}
+
+// sliCounters for the evaluation of burn rates and the error budget
+type sliCounters struct {
+	target   float64
+	burnRate *slidingCounter
+	window   *slidingCounter
+}
+
+func NewEvaluator(cfg *Config, clock func() time.Time) *Evaluator {
+	windows := cfg.BurnRateWindows
+	if len(windows) == 0 {
+		windows = DefaultBurnRateWindows
+	}
+	return &Evaluator{
+		log:             slolog(),
+		clock:           clock,
+		objectives:      cfg.Objectives,
+		burnRateWindows: windows,
+		series:          map[seriesKey]*series{},
+	}
+}
+
+// Record accounts the server-side spans of the selected services. Spans that
+// are ignored for metrics generation are also ignored here.
+func (e *Evaluator) Record(spans []request.Span) {
+	now := e.clock()
+	for i := range spans {
+		span := &spans[i]
+		if span.IgnoreSpan == request.IgnoreMetrics ||
+			(span.Type != request.EventTypeHTTP && span.Type != request.EventTypeGRPC) {
+			continue
+		}
+		for o := range e.objectives {
+			if !e.objectives[o].selects(&span.ServiceID) {
+				continue
+			}
+			s := e.seriesFor(o, &span.ServiceID)
+			s.lastSeen = now
+			if s.availability != nil {
+				s.availability.add(now, request.SpanStatusCode(span) == codes.Error)
+			}
+			if s.latency != nil {
+				duration := time.Duration(span.End - span.RequestStart)
+				s.latency.add(now, duration > s.objective.Latency.Threshold)
+			}
+		}
+	}
+}
+
+func (e *Evaluator) seriesFor(objective int, service *svc.ID) *series {
+	key := seriesKey{objective: objective, service: service.UID}
+	if s, ok := e.series[key]; ok {
+		return s
+	}
+	o := &e.objectives[objective]
+	s := &series{objective: o, service: *service}
+	longestBurnWindow := slices.Max(e.burnRateWindows)
+	if o.Availability > 0 {
+		s.availability = newSLICounters(o.Availability, o.window(), longestBurnWindow)
+	}
+	if o.Latency.Target > 0 {
+		s.latency = newSLICounters(o.Latency.Target, o.window(), longestBurnWindow)
+	}
+	e.series[key] = s
+	return s
+}
+
+func newSLICounters(target float64, window, longestBurnWindow time.Duration) *sliCounters {
+	return &sliCounters{
+		target:   target,
+		burnRate: newSlidingCounter(burnRateResolution, longestBurnWindow),
+		window:   newSlidingCounter(max(window/maxWindowSlots, burnRateResolution), window),
+	}
+}
+
+func (c *sliCounters) add(now time.Time, bad bool) {
+	c.burnRate.add(now, bad)
+	c.window.add(now, bad)
+}
+
+// Evaluate returns the current status of all the objectives, for each service. The services
+// that haven't reported any request during the whole objective window are forgotten.
+func (e *Evaluator) Evaluate() []*Status {
+	now := e.clock()
+	statuses := make([]*Status, 0, len(e.series)*2)
+	for key, s := range e.series {
+		if now.Sub(s.lastSeen) > s.objective.window() {
+			delete(e.series, key)
+			continue
+		}
+		if s.availability != nil {
+			statuses = append(statuses, e.status(now, s, SLIAvailability, s.availability))
+		}
+		if s.latency != nil {
+			statuses = append(statuses, e.status(now, s, SLILatency, s.latency))
+		}
+	}
+	return statuses
+}
+
+func (e *Evaluator) status(now time.Time, s *series, sli string, c *sliCounters) *Status {
+	allowedBadRatio := 1 - c.target
+	st := &Status{
+		Objective:            s.objective.Name,
+		SLI:                  sli,
+		Service:              &s.service,
+		Target:               c.target,
+		ErrorBudgetRemaining: 1 - c.window.sum(now, s.objective.window()).badRatio()/allowedBadRatio,
+		BurnRates:            make([]BurnRate, 0, len(e.burnRateWindows)),
+	}
+	for _, w := range e.burnRateWindows {
+		st.BurnRates = append(st.BurnRates, BurnRate{
+			Window: w,
+			Rate:   c.burnRate.sum(now, w).badRatio() / allowedBadRatio,
+		})
+	}
+	return st
+}
@@ -0,0 +1,43 @@
+package slo
+
+import (
+	"time"
+
+	"github.com/prometheus/common/model"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/grafana/beyla/pkg/export/attributes"
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+)
+
+// AttributeNames that decorate all the SLO metrics. The burn rate metric additionally
+// reports the attr.SLOWindow attribute.
+var AttributeNames = []attr.Name{attr.SLOName, attr.SLOIndicator, attr.ServiceName, attr.ServiceNamespace}
+
+func PromGetters(name attr.Name) (attributes.Getter[*Status, string], bool) {
+	var g attributes.Getter[*Status, string]
+	switch name {
+	case attr.SLOName:
+		g = func(s *Status) string { return s.Objective }
+	case attr.SLOIndicator:
+		g = func(s *Status) string { return s.SLI }
+	case attr.ServiceName:
+		g = func(s *Status) string { return s.Service.Name }
+	case attr.ServiceNamespace:
+		g = func(s *Status) string { return s.Service.Namespace }
+	}
+	return g, g != nil
+}
+
+func OTELGetters(name attr.Name) (attributes.Getter[*Status, attribute.KeyValue], bool) {
+	if g, ok := PromGetters(name); ok {
+		return func(s *Status) attribute.KeyValue { return name.OTEL().String(g(s)) }, true
+	}
+	return nil, false
+}
+
+// WindowLabel returns the value of the attr.SLOWindow attribute, in the
+// Prometheus duration format (e.g. 5m, 6h, 3d)
+func WindowLabel(w time.Duration) string {
+	return model.Duration(w).String()
+}
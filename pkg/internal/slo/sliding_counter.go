@@ -0,0 +1,77 @@
+package slo
+
+import "time"
+
+type counts struct {
+	total int64
+	bad   int64
+}
+
+func (c counts) badRatio() float64 {
+	if c.total == 0 {
+		return 0
+	}
+	return float64(c.bad) / float64(c.total)
+}
+
+// slidingCounter accounts requests in a ring of fixed-duration time slots, so the
+// requests of the last time window can be calculated with a granularity of one slot.
+type slidingCounter struct {
+	resolution time.Duration
+	slots      []counts
+	// head is the index of the slot that accounts the current time
+	head      int
+	headStart time.Time
+}
+
+func newSlidingCounter(resolution, length time.Duration) *slidingCounter {
+	n := int((length + resolution - 1) / resolution)
+	return &slidingCounter{
+		resolution: resolution,
+		slots:      make([]counts, max(n, 1)),
+	}
+}
+
+// advance moves the head to the slot of the provided time, resetting the slots in between
+func (c *slidingCounter) advance(now time.Time) {
+	if c.headStart.IsZero() {
+		c.headStart = now.Truncate(c.resolution)
+		return
+	}
+	elapsed := int(now.Sub(c.headStart) / c.resolution)
+	if elapsed <= 0 {
+		return
+	}
+	if elapsed >= len(c.slots) {
+		clear(c.slots)
+		c.head = 0
+		c.headStart = now.Truncate(c.resolution)
+		return
+	}
+	for i := 0; i < elapsed; i++ {
+		c.head = (c.head + 1) % len(c.slots)
+		c.slots[c.head] = counts{}
+	}
+	c.headStart = c.headStart.Add(time.Duration(elapsed) * c.resolution)
+}
+
+func (c *slidingCounter) add(now time.Time, bad bool) {
+	c.advance(now)
+	c.slots[c.head].total++
+	if bad {
+		c.slots[c.head].bad++
+	}
+}
+
+// sum of the requests during the last time window, including the current slot
+func (c *slidingCounter) sum(now time.Time, window time.Duration) counts {
+	c.advance(now)
+	n := min(int((window+c.resolution-1)/c.resolution), len(c.slots))
+	var sum counts
+	for i := 0; i < n; i++ {
+		s := c.slots[(c.head-i+len(c.slots))%len(c.slots)]
+		sum.total += s.total
+		sum.bad += s.bad
+	}
+	return sum
+}
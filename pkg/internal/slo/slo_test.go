@@ -0,0 +1,156 @@
+package slo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	attr "github.com/grafana/beyla/pkg/export/attributes/names"
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/pkg/services"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestConfig_YAML(t *testing.T) {
+	cfg := Config{}
+	require.NoError(t, yaml.Unmarshal([]byte(`
+objectives:
+  - name: checkout
+    services:
+      - name: ^checkout$
+        k8s_namespace: shop
+    availability: 0.999
+    latency:
+      threshold: 300ms
+      target: 0.99
+    window: 168h
+`), &cfg))
+	require.NoError(t, cfg.Validate())
+	require.Len(t, cfg.Objectives, 1)
+	o := &cfg.Objectives[0]
+	assert.Equal(t, 0.999, o.Availability)
+	assert.Equal(t, 300*time.Millisecond, o.Latency.Threshold)
+	assert.Equal(t, 7*24*time.Hour, o.window())
+
+	assert.True(t, o.selects(&svc.ID{Name: "checkout",
+		Metadata: map[attr.Name]string{attr.K8sNamespaceName: "shop"}}))
+	assert.False(t, o.selects(&svc.ID{Name: "checkout",
+		Metadata: map[attr.Name]string{attr.K8sNamespaceName: "staging"}}))
+	assert.False(t, o.selects(&svc.ID{Name: "checkout-db",
+		Metadata: map[attr.Name]string{attr.K8sNamespaceName: "shop"}}))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	for name, objectives := range map[string][]Objective{
+		"missing name":         {{Availability: 0.99}},
+		"duplicate name":       {{Name: "a", Availability: 0.99}, {Name: "a", Availability: 0.9}},
+		"no targets":           {{Name: "a"}},
+		"availability > 1":     {{Name: "a", Availability: 99.9}},
+		"missing threshold":    {{Name: "a", Latency: LatencyObjective{Target: 0.99}}},
+		"negative window":      {{Name: "a", Availability: 0.99, Window: -time.Hour}},
+		"unknown k8s selector": {{Name: "a", Availability: 0.99, Services: []ServiceSelector{{Metadata: map[string]*services.RegexpAttr{"k8s_foo": nil}}}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := Config{Objectives: objectives}
+			assert.Error(t, cfg.Validate())
+		})
+	}
+}
+
+func TestEvaluator(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	ev := NewEvaluator(&Config{
+		BurnRateWindows: []time.Duration{5 * time.Minute, time.Hour},
+		Objectives: []Objective{{
+			Name:         "api",
+			Availability: 0.9,
+			Latency:      LatencyObjective{Threshold: 100 * time.Millisecond, Target: 0.5},
+			Window:       24 * time.Hour,
+		}},
+	}, clock.Now)
+
+	api := svc.ID{Name: "api", UID: "api"}
+	ok := request.Span{Type: request.EventTypeHTTP, Status: 200, ServiceID: api,
+		RequestStart: 0, End: int64(10 * time.Millisecond)}
+	failed := request.Span{Type: request.EventTypeHTTP, Status: 500, ServiceID: api,
+		RequestStart: 0, End: int64(10 * time.Millisecond)}
+	slow := request.Span{Type: request.EventTypeHTTP, Status: 200, ServiceID: api,
+		RequestStart: 0, End: int64(time.Second)}
+	// client and ignored spans are not accounted
+	client := request.Span{Type: request.EventTypeHTTPClient, Status: 500, ServiceID: api}
+	ignored := failed
+	ignored.IgnoreSpan = request.IgnoreMetrics
+
+	// 1 of 10 requests fail
+	ev.Record([]request.Span{ok, ok, ok, ok, ok, ok, ok, ok, slow, failed, client, ignored})
+	clock.Advance(59 * time.Minute)
+	// during the last minutes, 1 of 2 requests fail
+	ev.Record([]request.Span{ok, failed})
+
+	statuses := ev.Evaluate()
+	require.Len(t, statuses, 2)
+	byIndicator := map[string]*Status{}
+	for _, s := range statuses {
+		assert.Equal(t, "api", s.Objective)
+		assert.Equal(t, "api", s.Service.Name)
+		byIndicator[s.SLI] = s
+	}
+
+	av := byIndicator[SLIAvailability]
+	require.NotNil(t, av)
+	assert.Equal(t, 0.9, av.Target)
+	// 2 errors out of 12 requests, with an allowed error ratio of 0.1
+	assert.InDelta(t, 1-(2.0/12)/0.1, av.ErrorBudgetRemaining, 1e-9)
+	require.Len(t, av.BurnRates, 2)
+	assert.Equal(t, 5*time.Minute, av.BurnRates[0].Window)
+	assert.InDelta(t, 0.5/0.1, av.BurnRates[0].Rate, 1e-9)
+	assert.Equal(t, time.Hour, av.BurnRates[1].Window)
+	assert.InDelta(t, (2.0/12)/0.1, av.BurnRates[1].Rate, 1e-9)
+
+	lat := byIndicator[SLILatency]
+	require.NotNil(t, lat)
+	// 1 slow request out of 12, with an allowed ratio of 0.5
+	assert.InDelta(t, 1-(1.0/12)/0.5, lat.ErrorBudgetRemaining, 1e-9)
+	assert.InDelta(t, 0, lat.BurnRates[0].Rate, 1e-9)
+
+	// after the burn rate windows, the burn rate is zero but the budget consumption is still accounted
+	clock.Advance(2 * time.Hour)
+	ev.Record([]request.Span{ok})
+	for _, s := range ev.Evaluate() {
+		if s.SLI == SLIAvailability {
+			assert.InDelta(t, 1-(2.0/13)/0.1, s.ErrorBudgetRemaining, 1e-9)
+			assert.InDelta(t, 0, s.BurnRates[0].Rate, 1e-9)
+			assert.InDelta(t, 0, s.BurnRates[1].Rate, 1e-9)
+		}
+	}
+
+	// services that didn't report during the whole window are forgotten
+	clock.Advance(25 * time.Hour)
+	assert.Empty(t, ev.Evaluate())
+}
+
+func TestSlidingCounter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newSlidingCounter(time.Minute, 10*time.Minute)
+	for i := 0; i < 15; i++ {
+		c.add(now.Add(time.Duration(i)*time.Minute), i%3 == 0)
+	}
+	last := now.Add(14 * time.Minute)
+	// minutes 5 to 14 are kept
+	assert.Equal(t, counts{total: 10, bad: 3}, c.sum(last, time.Hour))
+	// minutes 12, 13 and 14
+	assert.Equal(t, counts{total: 3, bad: 1}, c.sum(last, 3*time.Minute))
+	// all the slots have expired
+	assert.Equal(t, counts{}, c.sum(last.Add(time.Hour), time.Hour))
+}
@@ -66,6 +66,11 @@ type ID struct {
 	SDKLanguage InstrumentableType
 	Instance    string
 
+	// RuntimeName and RuntimeVersion of the process (e.g. "java" and "21.0.2"), if they
+	// could be identified at discovery time. They are empty otherwise.
+	RuntimeName    string
+	RuntimeVersion string
+
 	Metadata map[attr.Name]string
 
 	// ProcPID is the PID of the instrumented process as seen by Beyla's /proc filesystem.
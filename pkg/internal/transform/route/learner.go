@@ -0,0 +1,395 @@
+package route
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	learnedPlaceholder = "{id}"
+
+	defaultLearningWindow       = time.Hour
+	defaultLearningMaxValues    = 20
+	defaultLearningMaxSegments  = 10
+	defaultLearningSaveInterval = time.Minute
+	defaultLearningPath         = "/routes"
+)
+
+// LearnerConfig defines how the routes are learned from the observed HTTP paths, when
+// the "learned" unmatched mode is enabled
+type LearnerConfig struct {
+	// Window of time during which the distinct values of each path segment are accounted
+	Window time.Duration `yaml:"window"`
+	// MaxDistinctValues of a path segment during the Window. When this number is exceeded,
+	// the path segment is replaced by a placeholder.
+	MaxDistinctValues int `yaml:"max_distinct_values"`
+	// MaxSegments of the learned routes. Any further segment is grouped by a trailing '*'.
+	MaxSegments int `yaml:"max_segments"`
+	// StatePath is the file where the learned routes are stored, so they persist across restarts.
+	// If empty, the learned routes are kept only in memory.
+	StatePath string `yaml:"state_path"`
+	// SaveInterval for the learned routes in the StatePath file
+	SaveInterval time.Duration `yaml:"save_interval"`
+	// Port of the HTTP endpoint that lists the learned routes. If 0, the endpoint is disabled.
+	Port int `yaml:"port"`
+	// Path of the HTTP endpoint that lists the learned routes
+	Path string `yaml:"path"`
+}
+
+func (c *LearnerConfig) withDefaults() LearnerConfig {
+	cfg := *c
+	if cfg.Window <= 0 {
+		cfg.Window = defaultLearningWindow
+	}
+	if cfg.MaxDistinctValues <= 0 {
+		cfg.MaxDistinctValues = defaultLearningMaxValues
+	}
+	if cfg.MaxSegments <= 0 {
+		cfg.MaxSegments = defaultLearningMaxSegments
+	}
+	if cfg.SaveInterval <= 0 {
+		cfg.SaveInterval = defaultLearningSaveInterval
+	}
+	if cfg.Path == "" {
+		cfg.Path = defaultLearningPath
+	}
+	return cfg
+}
+
+// Learner infers low-cardinality routes from the HTTP paths observed for each service.
+// It builds a prefix tree of path segments, and replaces by a placeholder any segment
+// position whose number of distinct values exceeds a threshold during a sliding time window.
+// It is safe for concurrent use.
+type Learner struct {
+	log   *slog.Logger
+	cfg   LearnerConfig
+	clock func() time.Time
+
+	mt    sync.Mutex
+	trees map[LearnedKey]*learnNode
+}
+
+// LearnedKey identifies a tree of learned routes. Server and client paths are learned separately.
+type LearnedKey struct {
+	Service string
+	Client  bool
+}
+
+// learnNode is a path segment in the tree of learned routes. Only one of
+// Children or Placeholder is set for a given node.
+type learnNode struct {
+	Children    map[string]*learnNode `json:"children,omitempty"`
+	Placeholder *learnNode            `json:"placeholder,omitempty"`
+	// End is true if any observed path ends in this segment
+	End      bool      `json:"end,omitempty"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+func NewLearner(cfg *LearnerConfig, clock func() time.Time) *Learner {
+	return &Learner{
+		log:   slog.With("component", "route.Learner"),
+		cfg:   cfg.withDefaults(),
+		clock: clock,
+		trees: map[LearnedKey]*learnNode{},
+	}
+}
+
+// Learn accounts the provided path and returns its route, according to what has been learned so far.
+func (l *Learner) Learn(key LearnedKey, path string) string {
+	segments := tokenize(path)
+	now := l.clock()
+
+	l.mt.Lock()
+	defer l.mt.Unlock()
+
+	n, ok := l.trees[key]
+	if !ok {
+		n = &learnNode{}
+		l.trees[key] = n
+	}
+	n.LastSeen = now
+	route := strings.Builder{}
+	for i, segment := range segments {
+		if i >= l.cfg.MaxSegments {
+			route.WriteString("/*")
+			return route.String()
+		}
+		route.WriteByte('/')
+		var placeholder bool
+		n, placeholder = l.child(n, segment, now)
+		if placeholder {
+			route.WriteString(learnedPlaceholder)
+		} else {
+			route.WriteString(segment)
+		}
+		n.LastSeen = now
+	}
+	n.End = true
+	if route.Len() == 0 {
+		return "/"
+	}
+	return route.String()
+}
+
+// child returns the node for the given segment, and whether it is a placeholder node. If the number of
+// distinct segments exceeds the threshold, all the children of the parent node are collapsed into a placeholder.
+func (l *Learner) child(parent *learnNode, segment string, now time.Time) (*learnNode, bool) {
+	if parent.Placeholder != nil {
+		return parent.Placeholder, true
+	}
+	if c, ok := parent.Children[segment]; ok {
+		return c, false
+	}
+	if parent.Children == nil {
+		parent.Children = map[string]*learnNode{}
+	}
+	if len(parent.Children) >= l.cfg.MaxDistinctValues {
+		// forget the segments that haven't been seen during the window before deciding to collapse
+		for s, c := range parent.Children {
+			if now.Sub(c.LastSeen) > l.cfg.Window {
+				delete(parent.Children, s)
+			}
+		}
+	}
+	c := &learnNode{}
+	parent.Children[segment] = c
+	if len(parent.Children) <= l.cfg.MaxDistinctValues {
+		return c, false
+	}
+	merged := &learnNode{}
+	for _, child := range parent.Children {
+		mergeNodes(merged, child)
+	}
+	parent.Children = nil
+	parent.Placeholder = merged
+	return merged, true
+}
+
+// mergeNodes merges the src subtree into dst
+func mergeNodes(dst, src *learnNode) {
+	dst.End = dst.End || src.End
+	if src.LastSeen.After(dst.LastSeen) {
+		dst.LastSeen = src.LastSeen
+	}
+	if src.Placeholder != nil {
+		if dst.Placeholder == nil {
+			dst.Placeholder = &learnNode{}
+		}
+		mergeNodes(dst.Placeholder, src.Placeholder)
+	}
+	for segment, c := range src.Children {
+		if dst.Children == nil {
+			dst.Children = map[string]*learnNode{}
+		}
+		if dc, ok := dst.Children[segment]; ok {
+			mergeNodes(dc, c)
+		} else {
+			dst.Children[segment] = c
+		}
+	}
+	// a placeholder absorbs any sibling segment
+	if dst.Placeholder != nil {
+		for _, c := range dst.Children {
+			mergeNodes(dst.Placeholder, c)
+		}
+		dst.Children = nil
+	}
+}
+
+// LearnedRoutes of a service
+type LearnedRoutes struct {
+	Service string   `json:"service"`
+	Client  bool     `json:"client,omitempty"`
+	Routes  []string `json:"routes"`
+}
+
+// Routes returns the routes that have been learned for each service, sorted alphabetically.
+func (l *Learner) Routes() []LearnedRoutes {
+	l.mt.Lock()
+	defer l.mt.Unlock()
+	learned := make([]LearnedRoutes, 0, len(l.trees))
+	for key, root := range l.trees {
+		routes := []string{}
+		if root.End {
+			routes = append(routes, "/")
+		}
+		routes = root.appendRoutes("", routes)
+		sort.Strings(routes)
+		learned = append(learned, LearnedRoutes{Service: key.Service, Client: key.Client, Routes: routes})
+	}
+	sort.Slice(learned, func(i, j int) bool {
+		if learned[i].Service != learned[j].Service {
+			return learned[i].Service < learned[j].Service
+		}
+		return !learned[i].Client && learned[j].Client
+	})
+	return learned
+}
+
+func (n *learnNode) appendRoutes(prefix string, routes []string) []string {
+	if n.Placeholder != nil {
+		p := prefix + "/" + learnedPlaceholder
+		if n.Placeholder.End {
+			routes = append(routes, p)
+		}
+		return n.Placeholder.appendRoutes(p, routes)
+	}
+	for segment, c := range n.Children {
+		p := prefix + "/" + segment
+		if c.End {
+			routes = append(routes, p)
+		}
+		routes = c.appendRoutes(p, routes)
+	}
+	return routes
+}
+
+// learnedState is the format of the file where the learned routes are persisted
+type learnedState struct {
+	Trees []learnedTree `json:"trees"`
+}
+
+type learnedTree struct {
+	Service string     `json:"service"`
+	Client  bool       `json:"client,omitempty"`
+	Root    *learnNode `json:"root"`
+}
+
+// Start loads the previously learned routes from the state file, if any, and starts saving
+// them periodically and serving them through HTTP, if enabled. The learned routes are saved
+// one last time when the context is cancelled.
+func (l *Learner) Start(ctx context.Context) {
+	if l.cfg.StatePath != "" {
+		if err := l.Load(); err != nil {
+			l.log.Warn("can't load the previously learned routes. Starting from scratch",
+				"path", l.cfg.StatePath, "error", err)
+		}
+		go l.saveLoop(ctx)
+	}
+	if l.cfg.Port != 0 {
+		go l.serve(ctx)
+	}
+}
+
+// Load the learned routes from the state file. Missing files are ignored.
+func (l *Learner) Load() error {
+	content, err := os.ReadFile(l.cfg.StatePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	state := learnedState{}
+	if err := json.Unmarshal(content, &state); err != nil {
+		return fmt.Errorf("decoding learned routes: %w", err)
+	}
+	// the loaded segments are accounted as if they were seen now, so they are not
+	// prematurely forgotten after a long downtime
+	now := l.clock()
+	l.mt.Lock()
+	defer l.mt.Unlock()
+	for _, t := range state.Trees {
+		if t.Root == nil {
+			continue
+		}
+		t.Root.touch(now)
+		key := LearnedKey{Service: t.Service, Client: t.Client}
+		if current, ok := l.trees[key]; ok {
+			mergeNodes(current, t.Root)
+		} else {
+			l.trees[key] = t.Root
+		}
+	}
+	l.log.Debug("loaded learned routes", "path", l.cfg.StatePath, "trees", len(state.Trees))
+	return nil
+}
+
+func (n *learnNode) touch(now time.Time) {
+	n.LastSeen = now
+	if n.Placeholder != nil {
+		n.Placeholder.touch(now)
+	}
+	for _, c := range n.Children {
+		c.touch(now)
+	}
+}
+
+// Save the learned routes into the state file. The file is atomically replaced, so
+// it is not corrupted if Beyla is killed during the write.
+func (l *Learner) Save() error {
+	l.mt.Lock()
+	state := learnedState{Trees: make([]learnedTree, 0, len(l.trees))}
+	for key, root := range l.trees {
+		state.Trees = append(state.Trees, learnedTree{Service: key.Service, Client: key.Client, Root: root})
+	}
+	content, err := json.Marshal(state)
+	l.mt.Unlock()
+	if err != nil {
+		return fmt.Errorf("encoding learned routes: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.cfg.StatePath), filepath.Base(l.cfg.StatePath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), l.cfg.StatePath)
+}
+
+func (l *Learner) saveLoop(ctx context.Context) {
+	ticker := time.NewTicker(l.cfg.SaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := l.Save(); err != nil {
+				l.log.Warn("can't save learned routes", "path", l.cfg.StatePath, "error", err)
+			}
+			return
+		case <-ticker.C:
+			if err := l.Save(); err != nil {
+				l.log.Warn("can't save learned routes", "path", l.cfg.StatePath, "error", err)
+			}
+		}
+	}
+}
+
+func (l *Learner) serve(ctx context.Context) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(l.cfg.Path, l.handle)
+	server := http.Server{Addr: fmt.Sprintf(":%d", l.cfg.Port), Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		if err := server.Close(); err != nil {
+			l.log.Warn("error closing HTTP server", "error", err)
+		}
+	}()
+	l.log.Info("serving learned routes", "port", l.cfg.Port, "path", l.cfg.Path)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		l.log.Error("learned routes HTTP server ended unexpectedly", "error", err)
+	}
+}
+
+func (l *Learner) handle(rw http.ResponseWriter, _ *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(l.Routes()); err != nil {
+		l.log.Debug("can't write learned routes", "error", err)
+	}
+}
@@ -0,0 +1,90 @@
+package route
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestLearner(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	l := NewLearner(&LearnerConfig{MaxDistinctValues: 3, MaxSegments: 4}, clock.Now)
+	svc := LearnedKey{Service: "ns/svc"}
+
+	assert.Equal(t, "/", l.Learn(svc, "/"))
+	assert.Equal(t, "/users", l.Learn(svc, "/users/"))
+	for i := 0; i < 3; i++ {
+		path := fmt.Sprintf("/users/%d/orders/%d", i, i*10)
+		assert.Equal(t, path, l.Learn(svc, path))
+	}
+	// the fourth distinct user ID collapses the segment into a placeholder. The orders of all the
+	// users are merged, so the fourth distinct order ID also collapses its segment
+	assert.Equal(t, "/users/{id}/orders/{id}", l.Learn(svc, "/users/4/orders/40"))
+	assert.Equal(t, "/users/{id}", l.Learn(svc, "/users/5"))
+	assert.Equal(t, "/users/{id}/orders/{id}", l.Learn(svc, "/users/1/orders/0"))
+	// segments beyond the maximum are grouped
+	assert.Equal(t, "/users/{id}/orders/{id}/*", l.Learn(svc, "/users/1/orders/3/items/2"))
+
+	// client paths and other services are learned separately
+	assert.Equal(t, "/users/1", l.Learn(LearnedKey{Service: "ns/svc", Client: true}, "/users/1"))
+	assert.Equal(t, "/users/1", l.Learn(LearnedKey{Service: "ns/other"}, "/users/1"))
+
+	assert.Equal(t, []LearnedRoutes{
+		{Service: "ns/other", Routes: []string{"/users/1"}},
+		{Service: "ns/svc", Routes: []string{"/", "/users", "/users/{id}", "/users/{id}/orders/{id}"}},
+		{Service: "ns/svc", Client: true, Routes: []string{"/users/1"}},
+	}, l.Routes())
+}
+
+func TestLearner_Window(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	l := NewLearner(&LearnerConfig{MaxDistinctValues: 2, Window: time.Minute}, clock.Now)
+	svc := LearnedKey{Service: "svc"}
+
+	assert.Equal(t, "/items/1", l.Learn(svc, "/items/1"))
+	assert.Equal(t, "/items/2", l.Learn(svc, "/items/2"))
+	clock.Advance(2 * time.Minute)
+	// the previous values are out of the window, so they are forgotten instead of collapsed
+	assert.Equal(t, "/items/3", l.Learn(svc, "/items/3"))
+	assert.Equal(t, "/items/4", l.Learn(svc, "/items/4"))
+	assert.Equal(t, []LearnedRoutes{{Service: "svc", Routes: []string{"/items/3", "/items/4"}}}, l.Routes())
+	// too many distinct values during the window
+	assert.Equal(t, "/items/{id}", l.Learn(svc, "/items/5"))
+	assert.Equal(t, "/items/{id}", l.Learn(svc, "/items/3"))
+}
+
+func TestLearner_Persistence(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1_700_000_000, 0)}
+	cfg := LearnerConfig{MaxDistinctValues: 2, StatePath: filepath.Join(t.TempDir(), "routes.json")}
+	svc := LearnedKey{Service: "svc"}
+
+	l := NewLearner(&cfg, clock.Now)
+	// loading a missing state file is not an error
+	require.NoError(t, l.Load())
+	for i := 0; i < 3; i++ {
+		l.Learn(svc, fmt.Sprintf("/items/%d/details", i))
+	}
+	l.Learn(svc, "/about")
+	require.NoError(t, l.Save())
+
+	restarted := NewLearner(&cfg, clock.Now)
+	require.NoError(t, restarted.Load())
+	assert.Equal(t, l.Routes(), restarted.Routes())
+	assert.Equal(t, "/items/{id}/details", restarted.Learn(svc, "/items/33/details"))
+}
@@ -67,6 +67,10 @@ type DiscoveryConfig struct {
 
 	// Debugging only option. Make sure the kernel side doesn't filter any PIDs, force user space filtering.
 	BPFPidFilterOff bool `yaml:"bpf_pid_filter_off" env:"BEYLA_BPF_PID_FILTER_OFF"`
+
+	// ServiceNameFromGoModule sets the name of the Go services without an explicitly configured name
+	// from the path of their main module, instead of the executable name.
+	ServiceNameFromGoModule bool `yaml:"service_name_from_go_module" env:"BEYLA_SERVICE_NAME_FROM_GO_MODULE"`
 }
 
 // DefinitionCriteria allows defining a group of services to be instrumented according to a set
@@ -89,7 +89,7 @@ func (md *metadataDecorator) nodeLoop(in <-chan []request.Span, out chan<- []req
 func (md *metadataDecorator) do(span *request.Span) {
 	if podInfo, ok := md.db.OwnerPodInfo(span.Pid.Namespace); ok {
 		md.appendMetadata(span, podInfo)
-	} else {
+	} else if span.ServiceID.Metadata == nil {
 		// do not leave the service attributes map as nil
 		span.ServiceID.Metadata = map[attr.Name]string{}
 	}
@@ -114,9 +114,9 @@ func (md *metadataDecorator) appendMetadata(span *request.Span, info *kube.PodIn
 	}
 	span.ServiceID.UID = svc.UID(info.UID)
 
-	// if, in the future, other pipeline steps modify the service metadata, we should
-	// replace the map literal by individual entry insertions
-	span.ServiceID.Metadata = map[attr.Name]string{
+	// the metadata from previous stages (e.g. Go build information) is kept. The original map
+	// is shared by all the spans of the service, so a new map is created instead of modifying it
+	metadata := map[attr.Name]string{
 		attr.K8sNamespaceName: info.Namespace,
 		attr.K8sPodName:       info.Name,
 		attr.K8sNodeName:      info.NodeName,
@@ -124,6 +124,12 @@ func (md *metadataDecorator) appendMetadata(span *request.Span, info *kube.PodIn
 		attr.K8sPodStartTime:  info.StartTimeStr,
 		attr.K8sClusterName:   md.clusterName,
 	}
+	for k, v := range span.ServiceID.Metadata {
+		if _, ok := metadata[k]; !ok {
+			metadata[k] = v
+		}
+	}
+	span.ServiceID.Metadata = metadata
 	owner := info.Owner
 	for owner != nil {
 		span.ServiceID.Metadata[attr.Name(owner.LabelName)] = owner.Name
@@ -129,6 +129,29 @@ func TestDecoration(t *testing.T) {
 			"k8s.cluster.name":    "the-cluster",
 		}, deco[0].ServiceID.Metadata)
 	})
+	t.Run("metadata from previous stages is kept", func(t *testing.T) {
+		buildInfo := map[attr.Name]string{
+			"service.version": "v1.2.3",
+			"go.module.path":  "github.com/acme/checkout",
+		}
+		inputCh <- []request.Span{{
+			Pid: request.PidInfo{Namespace: 56}, ServiceID: svc.ID{AutoName: true, Metadata: buildInfo},
+		}}
+		deco := testutil.ReadChannel(t, outputhCh, timeout)
+		require.Len(t, deco, 1)
+		assert.Equal(t, map[attr.Name]string{
+			"k8s.node.name":      "the-node",
+			"k8s.namespace.name": "the-ns",
+			"k8s.pod.name":       "the-pod",
+			"k8s.pod.uid":        "uid-56",
+			"k8s.pod.start_time": "2020-01-02 12:56:56",
+			"k8s.cluster.name":   "the-cluster",
+			"service.version":    "v1.2.3",
+			"go.module.path":     "github.com/acme/checkout",
+		}, deco[0].ServiceID.Metadata)
+		// the original map is not modified
+		assert.Len(t, buildInfo, 2)
+	})
 }
 
 type fakeDatabase struct {
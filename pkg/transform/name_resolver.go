@@ -20,6 +20,7 @@ import (
 const (
 	ResolverDNS = maps.Bits(1 << iota)
 	ResolverK8s
+	ResolverObserved
 )
 
 func resolverSources(str []string) maps.Bits {
@@ -28,11 +29,14 @@ func resolverSources(str []string) maps.Bits {
 		"k8s":        ResolverK8s,
 		"kube":       ResolverK8s,
 		"kubernetes": ResolverK8s,
+		"observed":   ResolverObserved,
 	}, maps.WithTransform(strings.ToLower))
 }
 
 type NameResolverConfig struct {
-	// Sources for name resolving. Accepted values: dns, k8s
+	// Sources for name resolving. Accepted values: dns, k8s, observed.
+	// The observed source names the IPs after the DNS queries that the instrumented
+	// processes performed to get them. It requires the DNS instrumentation.
 	Sources []string `yaml:"sources" env:"BEYLA_NAME_RESOLVER_SOURCES" envSeparator:"," envDefault:"k8s"`
 	// CacheLen specifies the max size of the LRU cache that is checked before
 	// performing the name lookup. Default: 256
@@ -47,6 +51,8 @@ type NameResolver struct {
 	cache *expirable.LRU[string, string]
 	cfg   *NameResolverConfig
 	db    *kube2.Database
+	// IP->hostname entries learned from the answers of the traced DNS queries
+	observed *expirable.LRU[string, string]
 
 	sources maps.Bits
 }
@@ -67,9 +73,15 @@ func nameResolver(ctxInfo *global.ContextInfo, cfg *NameResolverConfig) (pipe.Mi
 		cache:   expirable.NewLRU[string, string](cfg.CacheLen, nil, cfg.CacheTTL),
 		sources: resolverSources(cfg.Sources),
 	}
+	if nr.sources.Has(ResolverObserved) {
+		nr.observed = expirable.NewLRU[string, string](cfg.CacheLen, nil, cfg.CacheTTL)
+	}
 
 	return func(in <-chan []request.Span, out chan<- []request.Span) {
 		for spans := range in {
+			for i := range spans {
+				nr.learnFromDNS(&spans[i])
+			}
 			for i := range spans {
 				s := &spans[i]
 				nr.resolveNames(s)
@@ -93,6 +105,17 @@ func trimPrefixIgnoreCase(s, prefix string) string {
 	return s
 }
 
+// learnFromDNS stores the addresses returned by a traced DNS query, so they
+// can be named after the queried host
+func (nr *NameResolver) learnFromDNS(span *request.Span) {
+	if nr.observed == nil || span.Type != request.EventTypeDNSClient || span.DNS == nil || span.Path == "" {
+		return
+	}
+	for _, ip := range span.DNS.Addresses {
+		nr.observed.Add(ip, span.Path)
+	}
+}
+
 func (nr *NameResolver) resolveNames(span *request.Span) {
 	var hn, pn string
 	if span.IsClientSpan() {
@@ -161,6 +184,12 @@ func (nr *NameResolver) dnsResolve(svc *svc.ID, ip string) (string, string) {
 		}
 	}
 
+	if nr.observed != nil {
+		if n, ok := nr.observed.Get(ip); ok {
+			return nr.cleanName(svc, ip, n), svc.Namespace
+		}
+	}
+
 	if nr.sources.Has(ResolverDNS) {
 		n := nr.resolveIP(ip)
 		if n == ip {
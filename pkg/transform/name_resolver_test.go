@@ -215,3 +215,30 @@ func TestCleanName(t *testing.T) {
 	assert.Equal(t, "service", nr.cleanName(&s, "127.0.0.1", "service.special.namespace.svc.cluster.local."))
 	assert.Equal(t, "service", nr.cleanName(&s, "127.0.0.1", "service.k8snamespace.svc.cluster.local."))
 }
+
+func TestResolveFromObservedDNS(t *testing.T) {
+	nr := NameResolver{
+		cache:    expirable.NewLRU[string, string](10, nil, 5*time.Hour),
+		observed: expirable.NewLRU[string, string](10, nil, 5*time.Hour),
+		sources:  resolverSources([]string{"observed"}),
+	}
+
+	nr.learnFromDNS(&request.Span{
+		Type: request.EventTypeDNSClient,
+		Path: "api.example.com",
+		DNS:  &request.DNSInfo{Addresses: []string{"10.0.0.1", "10.0.0.2"}},
+	})
+
+	clientSpan := request.Span{
+		Type:      request.EventTypeHTTPClient,
+		Peer:      "10.0.0.9",
+		Host:      "10.0.0.2",
+		ServiceID: svc.ID{Name: "client", Namespace: "ns"},
+	}
+	nr.resolveNames(&clientSpan)
+
+	assert.Equal(t, "api.example.com", clientSpan.HostName)
+	assert.Equal(t, "ns", clientSpan.OtherNamespace)
+	// unknown IPs are not resolved, as the dns source is not enabled
+	assert.Equal(t, "10.0.0.9", clientSpan.PeerName)
+}
@@ -0,0 +1,141 @@
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mariomac/pipes/pipe"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// RedisKeysUnmatch defines which key pattern is set to the Redis keys that are not matched by any rule
+type RedisKeysUnmatch string
+
+const (
+	// RedisKeysUnmatchUnset leaves the key pattern as empty
+	RedisKeysUnmatchUnset = RedisKeysUnmatch("unset")
+	// RedisKeysUnmatchWildcard sets the key pattern to a generic asterisk symbol
+	RedisKeysUnmatchWildcard = RedisKeysUnmatch("wildcard")
+	// RedisKeysUnmatchHeuristic replaces the key segments that look like identifiers by an asterisk
+	RedisKeysUnmatchHeuristic = RedisKeysUnmatch("heuristic")
+
+	RedisKeysUnmatchDefault = RedisKeysUnmatchHeuristic
+)
+
+// RedisKeysConfig allows grouping Redis keys sharing a given pattern (e.g. user:123:cart and user:456:cart
+// would be reported as user:*:cart), so they can be used as a low-cardinality metric attribute.
+type RedisKeysConfig struct {
+	// Patterns of glob-like keys, where * matches any sequence of characters and ? matches
+	// a single character. A matching key is reported with the pattern itself.
+	Patterns []string `yaml:"patterns" env:"BEYLA_REDIS_KEY_PATTERNS" envSeparator:","`
+	// Rules of regular expressions. A key matching the Regex is reported with the rule Pattern.
+	Rules []RedisKeyRule `yaml:"rules"`
+	// Unmatched specifies what to do when a key does not match any pattern or rule
+	Unmatched RedisKeysUnmatch `yaml:"unmatched" env:"BEYLA_REDIS_KEY_PATTERNS_UNMATCHED"`
+}
+
+type RedisKeyRule struct {
+	Regex   string `yaml:"regex"`
+	Pattern string `yaml:"pattern"`
+}
+
+func (c *RedisKeysConfig) Enabled() bool {
+	return c != nil && (len(c.Patterns) > 0 || len(c.Rules) > 0)
+}
+
+type redisKeyMatcher struct {
+	re      *regexp.Regexp
+	pattern string
+}
+
+// RedisKeysProvider decorates the Redis spans with the pattern of their keys. If no patterns or rules
+// are defined, the node is bypassed.
+func RedisKeysProvider(cfg *RedisKeysConfig) pipe.MiddleProvider[[]request.Span, []request.Span] {
+	return func() (pipe.MiddleFunc[[]request.Span, []request.Span], error) {
+		if !cfg.Enabled() {
+			return pipe.Bypass[[]request.Span](), nil
+		}
+		matchers, err := redisKeyMatchers(cfg)
+		if err != nil {
+			return nil, err
+		}
+		unmatch := cfg.Unmatched
+		if unmatch == "" {
+			unmatch = RedisKeysUnmatchDefault
+		}
+		return func(in <-chan []request.Span, out chan<- []request.Span) {
+			for spans := range in {
+				for i := range spans {
+					if r := spans[i].Redis; r != nil && r.Key != "" {
+						r.KeyPattern = redisKeyPattern(matchers, unmatch, r.Key)
+					}
+				}
+				out <- spans
+			}
+		}, nil
+	}
+}
+
+func redisKeyMatchers(cfg *RedisKeysConfig) ([]redisKeyMatcher, error) {
+	matchers := make([]redisKeyMatcher, 0, len(cfg.Patterns)+len(cfg.Rules))
+	for _, p := range cfg.Patterns {
+		matchers = append(matchers, redisKeyMatcher{re: globToRegexp(p), pattern: p})
+	}
+	for _, r := range cfg.Rules {
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Redis key regex %q: %w", r.Regex, err)
+		}
+		matchers = append(matchers, redisKeyMatcher{re: re, pattern: r.Pattern})
+	}
+	return matchers, nil
+}
+
+func globToRegexp(glob string) *regexp.Regexp {
+	sb := strings.Builder{}
+	sb.WriteByte('^')
+	for _, c := range glob {
+		switch c {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteByte('.')
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteByte('$')
+	return regexp.MustCompile(sb.String())
+}
+
+func redisKeyPattern(matchers []redisKeyMatcher, unmatch RedisKeysUnmatch, key string) string {
+	for i := range matchers {
+		if matchers[i].re.MatchString(key) {
+			return matchers[i].pattern
+		}
+	}
+	switch unmatch {
+	case RedisKeysUnmatchWildcard:
+		return "*"
+	case RedisKeysUnmatchHeuristic:
+		return redisKeyHeuristic(key)
+	default:
+		return ""
+	}
+}
+
+var redisKeyIDSegment = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{8,}|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$`)
+
+// redisKeyHeuristic replaces by an asterisk the colon-separated segments of a key that
+// look like identifiers: numbers, long hexadecimal strings and UUIDs
+func redisKeyHeuristic(key string) string {
+	segments := strings.Split(key, ":")
+	for i, s := range segments {
+		if redisKeyIDSegment.MatchString(s) {
+			segments[i] = "*"
+		}
+	}
+	return strings.Join(segments, ":")
+}
@@ -0,0 +1,59 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/testutil"
+)
+
+func TestRedisKeyPatterns(t *testing.T) {
+	node, err := RedisKeysProvider(&RedisKeysConfig{
+		Patterns: []string{"user:*:cart", "session:?"},
+		Rules:    []RedisKeyRule{{Regex: `^order-[0-9]+$`, Pattern: "order-*"}},
+	})()
+	require.NoError(t, err)
+	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
+	defer close(in)
+	go node(in, out)
+
+	in <- []request.Span{
+		{Type: request.EventTypeRedisClient, Redis: &request.RedisInfo{Key: "user:123:cart"}},
+		{Type: request.EventTypeRedisClient, Redis: &request.RedisInfo{Key: "session:a"}},
+		{Type: request.EventTypeRedisClient, Redis: &request.RedisInfo{Key: "order-4321"}},
+		// unmatched keys are reduced by the heuristic
+		{Type: request.EventTypeRedisClient, Redis: &request.RedisInfo{Key: "item:42:0c9fa8aa-281f-11ef-97b9-be9600ca0f27:tags"}},
+		{Type: request.EventTypeRedisClient, Redis: &request.RedisInfo{Key: "session:abc"}},
+		{Type: request.EventTypeHTTP, Path: "/user/123"},
+	}
+	spans := testutil.ReadChannel(t, out, testTimeout)
+	require.Len(t, spans, 6)
+	assert.Equal(t, "user:*:cart", spans[0].Redis.KeyPattern)
+	assert.Equal(t, "session:?", spans[1].Redis.KeyPattern)
+	assert.Equal(t, "order-*", spans[2].Redis.KeyPattern)
+	assert.Equal(t, "item:*:*:tags", spans[3].Redis.KeyPattern)
+	assert.Equal(t, "session:abc", spans[4].Redis.KeyPattern)
+	assert.Nil(t, spans[5].Redis)
+}
+
+func TestRedisKeyPatterns_Unmatched(t *testing.T) {
+	matchers, err := redisKeyMatchers(&RedisKeysConfig{Patterns: []string{"user:*", "a.b"}})
+	require.NoError(t, err)
+	assert.Equal(t, "*", redisKeyPattern(matchers, RedisKeysUnmatchWildcard, "cart:123"))
+	assert.Equal(t, "", redisKeyPattern(matchers, RedisKeysUnmatchUnset, "cart:123"))
+	assert.Equal(t, "cart:*", redisKeyPattern(matchers, RedisKeysUnmatchHeuristic, "cart:123"))
+	// glob special characters are not interpreted as regular expressions
+	assert.Equal(t, "a.b", redisKeyPattern(matchers, RedisKeysUnmatchUnset, "a.b"))
+	assert.Equal(t, "", redisKeyPattern(matchers, RedisKeysUnmatchUnset, "axb"))
+}
+
+func TestRedisKeyPatterns_Disabled(t *testing.T) {
+	assert.False(t, (&RedisKeysConfig{}).Enabled())
+	assert.False(t, (*RedisKeysConfig)(nil).Enabled())
+
+	_, err := RedisKeysProvider(&RedisKeysConfig{Rules: []RedisKeyRule{{Regex: "(", Pattern: "x"}}})()
+	assert.Error(t, err)
+}
@@ -2,7 +2,9 @@
 package transform
 
 import (
+	"context"
 	"log/slog"
+	"time"
 
 	"github.com/mariomac/pipes/pipe"
 
@@ -22,6 +24,8 @@ const (
 	UnmatchWildcard = UnmatchType("wildcard")
 	// UnmatchHeuristic detects the route field using a heuristic
 	UnmatchHeuristic = UnmatchType("heuristic")
+	// UnmatchLearned sets the route field from the routes that are learned from the observed paths
+	UnmatchLearned = UnmatchType("learned")
 
 	UnmatchDefault = UnmatchWildcard
 )
@@ -49,63 +53,126 @@ type RoutesConfig struct {
 	Patterns       []string   `yaml:"patterns"`
 	IgnorePatterns []string   `yaml:"ignored_patterns"`
 	IgnoredEvents  IgnoreMode `yaml:"ignore_mode"`
+	// Learning configures the "learned" Unmatch mode
+	Learning route.LearnerConfig `yaml:"learning"`
 }
 
 func RoutesProvider(rc *RoutesConfig) pipe.MiddleProvider[[]request.Span, []request.Span] {
-	return (&routerNode{config: rc}).provideRoutes
+	return ReloadableRoutesProvider(context.Background(), rc, nil)
+}
+
+// ReloadableRoutesProvider works as RoutesProvider, but it also replaces the routes configuration
+// each time a new RoutesConfig is received from the updates channel.
+func ReloadableRoutesProvider(ctx context.Context, rc *RoutesConfig, updates <-chan *RoutesConfig) pipe.MiddleProvider[[]request.Span, []request.Span] {
+	return (&routerNode{ctx: ctx, config: rc, updates: updates}).provideRoutes
 }
 
 type routerNode struct {
-	config *RoutesConfig
+	ctx     context.Context
+	config  *RoutesConfig
+	updates <-chan *RoutesConfig
+	// learner is created the first time that the "learned" unmatch mode is configured, and
+	// it is kept across configuration reloads, so the learned routes are not lost.
+	// Changes to the routes.learning section are rejected by the configuration reloader.
+	learner *route.Learner
+}
+
+// router stores the routes decoration logic for a given RoutesConfig
+type router struct {
+	unmatchAction func(span *request.Span)
+	matcher       route.Matcher
+	discarder     route.Matcher
+	routesEnabled bool
+	ignoreEnabled bool
+	ignoreMode    IgnoreMode
 }
 
 func (rn *routerNode) provideRoutes() (pipe.MiddleFunc[[]request.Span, []request.Span], error) {
-	rc := rn.config
-	if rc == nil {
+	if rn.config == nil && rn.updates == nil {
 		// if no configuration is provided, we just bypass the node
 		return pipe.Bypass[[]request.Span](), nil
 	}
 
+	r, err := rn.newRouter(rn.config)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(in <-chan []request.Span, out chan<- []request.Span) {
+		for {
+			select {
+			case rc := <-rn.updates:
+				nr, err := rn.newRouter(rc)
+				if err != nil {
+					slog.With("component", "RoutesProvider").
+						Error("can't reload routes configuration. Keeping the previous one", "error", err)
+					continue
+				}
+				r = nr
+			case spans, ok := <-in:
+				if !ok {
+					return
+				}
+				if filtered := r.decorate(spans); len(filtered) > 0 {
+					out <- filtered
+				}
+			}
+		}
+	}, nil
+}
+
+// newRouter returns a router for the provided configuration. A nil configuration
+// returns a router that forwards the spans without modifying them.
+func (rn *routerNode) newRouter(rc *RoutesConfig) (*router, error) {
+	if rc == nil {
+		return &router{unmatchAction: leaveUnmatchEmpty}, nil
+	}
 	// set default value for Unmatch action
 	unmatchAction, err := chooseUnmatchPolicy(rc)
 	if err != nil {
 		return nil, err
 	}
-	matcher := route.NewMatcher(rc.Patterns)
-	discarder := route.NewMatcher(rc.IgnorePatterns)
-	routesEnabled := len(rc.Patterns) > 0
-	ignoreEnabled := len(rc.IgnorePatterns) > 0
-
+	if rc.Unmatch == UnmatchLearned {
+		if rn.learner == nil {
+			rn.learner = route.NewLearner(&rc.Learning, time.Now)
+			rn.learner.Start(rn.ctx)
+		}
+		unmatchAction = learnFromPath(rn.learner)
+	}
 	ignoreMode := rc.IgnoredEvents
 	if ignoreMode == "" {
 		ignoreMode = IgnoreDefault
 	}
+	return &router{
+		unmatchAction: unmatchAction,
+		matcher:       route.NewMatcher(rc.Patterns),
+		discarder:     route.NewMatcher(rc.IgnorePatterns),
+		routesEnabled: len(rc.Patterns) > 0,
+		ignoreEnabled: len(rc.IgnorePatterns) > 0,
+		ignoreMode:    ignoreMode,
+	}, nil
+}
 
-	return func(in <-chan []request.Span, out chan<- []request.Span) {
-		for spans := range in {
-			filtered := make([]request.Span, 0, len(spans))
-			for i := range spans {
-				s := &spans[i]
-				if ignoreEnabled {
-					if discarder.Find(s.Path) != "" {
-						if ignoreMode == IgnoreAll {
-							continue
-						}
-						// we can't discard it here, ignoring is selective (metrics | traces)
-						setSpanIgnoreMode(ignoreMode, s)
-					}
-				}
-				if routesEnabled {
-					s.Route = matcher.Find(s.Path)
+func (r *router) decorate(spans []request.Span) []request.Span {
+	filtered := make([]request.Span, 0, len(spans))
+	for i := range spans {
+		s := &spans[i]
+		if r.ignoreEnabled {
+			if r.discarder.Find(s.Path) != "" {
+				if r.ignoreMode == IgnoreAll {
+					continue
 				}
-				unmatchAction(s)
-				filtered = append(filtered, *s)
-			}
-			if len(filtered) > 0 {
-				out <- filtered
+				// we can't discard it here, ignoring is selective (metrics | traces)
+				setSpanIgnoreMode(r.ignoreMode, s)
 			}
 		}
-	}, nil
+		if r.routesEnabled {
+			s.Route = r.matcher.Find(s.Path)
+		}
+		r.unmatchAction(s)
+		filtered = append(filtered, *s)
+	}
+	return filtered
 }
 
 func chooseUnmatchPolicy(rc *RoutesConfig) (func(span *request.Span), error) {
@@ -129,6 +196,9 @@ func chooseUnmatchPolicy(rc *RoutesConfig) (func(span *request.Span), error) {
 		unmatchAction = leaveUnmatchEmpty
 	case UnmatchPath:
 		unmatchAction = setUnmatchToPath
+	case UnmatchLearned:
+		// the action is provided by the router node, which owns the routes learner
+		unmatchAction = leaveUnmatchEmpty
 	case UnmatchHeuristic:
 		err := route.InitAutoClassifier()
 		if err != nil {
@@ -165,6 +235,17 @@ func classifyFromPath(s *request.Span) {
 	}
 }
 
+func learnFromPath(learner *route.Learner) func(s *request.Span) {
+	return func(s *request.Span) {
+		if s.Route == "" && (s.Type == request.EventTypeHTTP || s.Type == request.EventTypeHTTPClient) {
+			s.Route = learner.Learn(route.LearnedKey{
+				Service: s.ServiceID.String(),
+				Client:  s.Type == request.EventTypeHTTPClient,
+			}, s.Path)
+		}
+	}
+}
+
 func setSpanIgnoreMode(mode IgnoreMode, s *request.Span) {
 	switch mode {
 	case IgnoreMetrics:
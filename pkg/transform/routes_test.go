@@ -1,6 +1,8 @@
 package transform
 
 import (
+	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -9,6 +11,7 @@ import (
 
 	"github.com/grafana/beyla/pkg/internal/request"
 	"github.com/grafana/beyla/pkg/internal/testutil"
+	"github.com/grafana/beyla/pkg/internal/transform/route"
 )
 
 const testTimeout = 5 * time.Second
@@ -167,3 +170,58 @@ func benchProvider(b *testing.B, unmatch UnmatchType) {
 		<-outCh
 	}
 }
+
+func TestRoutesReload(t *testing.T) {
+	// unbuffered channel, so the updates are processed before the next input batch
+	updates := make(chan *RoutesConfig)
+	router, err := ReloadableRoutesProvider(context.Background(), &RoutesConfig{Unmatch: UnmatchUnset, Patterns: []string{"/user/:id"}}, updates)()
+	require.NoError(t, err)
+	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
+	defer close(in)
+	go router(in, out)
+	in <- []request.Span{{Path: "/user/1234"}}
+	assert.Equal(t, []request.Span{{
+		Path:  "/user/1234",
+		Route: "/user/:id",
+	}}, testutil.ReadChannel(t, out, testTimeout))
+
+	updates <- &RoutesConfig{Unmatch: UnmatchPath, Patterns: []string{"/customer/:id"}, IgnorePatterns: []string{"/health"}}
+	in <- []request.Span{{Path: "/health"}, {Path: "/customer/1234"}, {Path: "/user/1234"}}
+	assert.Equal(t, []request.Span{{
+		Path:  "/customer/1234",
+		Route: "/customer/:id",
+	}, {
+		Path:  "/user/1234",
+		Route: "/user/1234",
+	}}, testutil.ReadChannel(t, out, testTimeout))
+}
+
+func TestUnmatchedLearned(t *testing.T) {
+	router, err := RoutesProvider(&RoutesConfig{
+		Unmatch:  UnmatchLearned,
+		Patterns: []string{"/health"},
+		Learning: route.LearnerConfig{MaxDistinctValues: 3},
+	})()
+	require.NoError(t, err)
+	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
+	defer close(in)
+	go router(in, out)
+	var spans []request.Span
+	for i := 0; i < 4; i++ {
+		spans = append(spans, request.Span{Type: request.EventTypeHTTP, Path: fmt.Sprintf("/users/%d/cart", i)})
+	}
+	spans = append(spans,
+		request.Span{Type: request.EventTypeHTTP, Path: "/health"},
+		request.Span{Type: request.EventTypeGRPC, Path: "/grpc.Service/Method"})
+	in <- spans
+	routes := []string{}
+	for _, s := range testutil.ReadChannel(t, out, testTimeout) {
+		routes = append(routes, s.Route)
+	}
+	assert.Equal(t, []string{
+		"/users/0/cart", "/users/1/cart", "/users/2/cart", "/users/{id}/cart", "/health", "",
+	}, routes)
+
+	in <- []request.Span{{Type: request.EventTypeHTTP, Path: "/users/1/cart"}}
+	assert.Equal(t, "/users/{id}/cart", testutil.ReadChannel(t, out, testTimeout)[0].Route)
+}
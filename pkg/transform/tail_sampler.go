@@ -0,0 +1,286 @@
+package transform
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/gobwas/glob"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/mariomac/pipes/pipe"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace"
+	trace2 "go.opentelemetry.io/otel/trace"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+)
+
+// TailSamplingConfig configures the tail-based sampling of traces. Spans are buffered
+// by their TraceID during a decision window, then the whole trace is either kept
+// or dropped according to the configured policies.
+type TailSamplingConfig struct {
+	Enabled bool `yaml:"enabled" env:"BEYLA_TAIL_SAMPLING_ENABLED"`
+
+	// DecisionWait is the time that the spans of a trace are buffered, since the first
+	// span of the trace is received, before taking a sampling decision.
+	DecisionWait time.Duration `yaml:"decision_wait" env:"BEYLA_TAIL_SAMPLING_DECISION_WAIT"`
+
+	// MaxTraces limits the number of traces that are kept in memory waiting for a decision.
+	// When the limit is reached, the decision is taken in advance for the oldest traces.
+	// It also limits the number of remembered decisions, which are applied to the spans
+	// arriving after the decision for their trace was taken.
+	MaxTraces int `yaml:"max_traces" env:"BEYLA_TAIL_SAMPLING_MAX_TRACES"`
+
+	Policies TailSamplingPolicies `yaml:"policies"`
+}
+
+// TailSamplingPolicies define which traces are kept. A trace is kept if any of its spans
+// satisfies any of the policies. Traces that do not satisfy any policy are sampled
+// according to the Probabilistic ratio.
+type TailSamplingPolicies struct {
+	// Errors keeps the traces with any span whose status code is error
+	Errors bool `yaml:"errors" env:"BEYLA_TAIL_SAMPLING_ERRORS"`
+	// Latency keeps the traces with any span whose duration is equal or higher than this value.
+	// Zero disables this policy.
+	Latency time.Duration `yaml:"latency" env:"BEYLA_TAIL_SAMPLING_LATENCY"`
+	// Routes keeps the traces with any span whose route matches any of the provided glob patterns
+	Routes []string `yaml:"routes" env:"BEYLA_TAIL_SAMPLING_ROUTES" envSeparator:","`
+	// Services keeps the traces with any span whose service name matches any of the provided glob patterns
+	Services []string `yaml:"services" env:"BEYLA_TAIL_SAMPLING_SERVICES" envSeparator:","`
+	// Probabilistic is the ratio (from 0 to 1) of the traces that are kept when they do not
+	// satisfy any other policy.
+	Probabilistic float64 `yaml:"probabilistic" env:"BEYLA_TAIL_SAMPLING_PROBABILISTIC"`
+}
+
+func tslog() *slog.Logger {
+	return slog.With("component", "transform.TailSampler")
+}
+
+// TailSamplerProvider returns a pipeline node that applies tail-based sampling to the traces.
+// Since the metrics exporters also consume the spans, sampled-out spans are not removed from
+// the pipeline but marked to be ignored by the traces exporters. Spans that were already
+// ignored for metrics are removed.
+func TailSamplerProvider(cfg *TailSamplingConfig) pipe.MiddleProvider[[]request.Span, []request.Span] {
+	return func() (pipe.MiddleFunc[[]request.Span, []request.Span], error) {
+		if cfg == nil || !cfg.Enabled {
+			return pipe.Bypass[[]request.Span](), nil
+		}
+		ts, err := newTailSampler(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return ts.doSample, nil
+	}
+}
+
+type pendingTrace struct {
+	deadline time.Time
+	spans    []request.Span
+}
+
+type tailSampler struct {
+	cfg      *TailSamplingConfig
+	clock    func() time.Time
+	routes   []glob.Glob
+	services []glob.Glob
+	ratio    trace.Sampler
+	// random provides the probabilistic decision for the spans without trace ID
+	random func() float64
+
+	// pending traces, indexed by trace ID
+	pending map[trace2.TraceID]*pendingTrace
+	// arrival order of pending traces. Since the decision window is the same for all the traces,
+	// it is also the order of their deadlines.
+	order []trace2.TraceID
+	// decisions already taken, for the spans arriving after the decision window
+	decisions *lru.Cache[trace2.TraceID, bool]
+}
+
+// minDecisionWait avoids checking the pending traces in a busy loop
+const minDecisionWait = 10 * time.Millisecond
+
+func newTailSampler(cfg *TailSamplingConfig) (*tailSampler, error) {
+	if cfg.DecisionWait < minDecisionWait {
+		return nil, fmt.Errorf("tail sampling: decision_wait must be at least %s. Got: %s",
+			minDecisionWait, cfg.DecisionWait)
+	}
+	if cfg.MaxTraces <= 0 {
+		return nil, fmt.Errorf("tail sampling: max_traces must be positive. Got: %d", cfg.MaxTraces)
+	}
+	if cfg.Policies.Probabilistic < 0 || cfg.Policies.Probabilistic > 1 {
+		return nil, fmt.Errorf("tail sampling: probabilistic ratio must be between 0 and 1. Got: %f",
+			cfg.Policies.Probabilistic)
+	}
+	routes, err := compileGlobs(cfg.Policies.Routes)
+	if err != nil {
+		return nil, fmt.Errorf("tail sampling: invalid route pattern: %w", err)
+	}
+	services, err := compileGlobs(cfg.Policies.Services)
+	if err != nil {
+		return nil, fmt.Errorf("tail sampling: invalid service pattern: %w", err)
+	}
+	decisions, err := lru.New[trace2.TraceID, bool](cfg.MaxTraces)
+	if err != nil {
+		return nil, fmt.Errorf("tail sampling: can't instantiate decisions cache: %w", err)
+	}
+	return &tailSampler{
+		cfg:       cfg,
+		clock:     time.Now,
+		routes:    routes,
+		services:  services,
+		ratio:     trace.TraceIDRatioBased(cfg.Policies.Probabilistic),
+		random:    rand.Float64,
+		pending:   map[trace2.TraceID]*pendingTrace{},
+		decisions: decisions,
+	}, nil
+}
+
+func compileGlobs(patterns []string) ([]glob.Glob, error) {
+	globs := make([]glob.Glob, 0, len(patterns))
+	for _, p := range patterns {
+		g, err := glob.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", p, err)
+		}
+		globs = append(globs, g)
+	}
+	return globs, nil
+}
+
+func (ts *tailSampler) doSample(in <-chan []request.Span, out chan<- []request.Span) {
+	log := tslog()
+	log.Debug("starting tail sampling node",
+		"decisionWait", ts.cfg.DecisionWait, "maxTraces", ts.cfg.MaxTraces)
+	// checking the pending traces a few times per decision window, to not delay
+	// much the decisions
+	ticker := time.NewTicker(ts.cfg.DecisionWait / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case spans, ok := <-in:
+			if !ok {
+				log.Debug("input channel closed. Flushing pending traces")
+				ts.send(ts.flush(true), out)
+				return
+			}
+			ts.send(ts.receive(spans), out)
+			ts.send(ts.flush(false), out)
+		case <-ticker.C:
+			ts.send(ts.flush(false), out)
+		}
+	}
+}
+
+func (ts *tailSampler) send(spans []request.Span, out chan<- []request.Span) {
+	if len(spans) > 0 {
+		out <- spans
+	}
+}
+
+// receive buffers the spans of the traces that are pending of a decision, and returns
+// the spans that can be directly forwarded: spans without trace ID, or spans whose trace
+// already has a sampling decision.
+func (ts *tailSampler) receive(spans []request.Span) []request.Span {
+	var forward []request.Span
+	for i := range spans {
+		s := &spans[i]
+		if !s.TraceID.IsValid() {
+			// without trace ID, each span is considered a different trace
+			single := []request.Span{*s}
+			forward = ts.apply(ts.keep(single), single, forward)
+			continue
+		}
+		if keep, ok := ts.decisions.Get(s.TraceID); ok {
+			forward = ts.apply(keep, []request.Span{*s}, forward)
+			continue
+		}
+		pt, ok := ts.pending[s.TraceID]
+		if !ok {
+			pt = &pendingTrace{deadline: ts.clock().Add(ts.cfg.DecisionWait)}
+			ts.pending[s.TraceID] = pt
+			ts.order = append(ts.order, s.TraceID)
+		}
+		pt.spans = append(pt.spans, *s)
+	}
+	return forward
+}
+
+// flush takes the sampling decision for all the traces whose decision window expired
+// (or all of them, if the "all" argument is true) and returns their spans.
+func (ts *tailSampler) flush(all bool) []request.Span {
+	var forward []request.Span
+	now := ts.clock()
+	taken := 0
+	for _, traceID := range ts.order {
+		pt := ts.pending[traceID]
+		if !all && now.Before(pt.deadline) && len(ts.order)-taken <= ts.cfg.MaxTraces {
+			break
+		}
+		keep := ts.keep(pt.spans)
+		ts.decisions.Add(traceID, keep)
+		forward = ts.apply(keep, pt.spans, forward)
+		delete(ts.pending, traceID)
+		taken++
+	}
+	if taken > 0 {
+		ts.order = ts.order[taken:]
+	}
+	return forward
+}
+
+// apply the sampling decision to the provided spans and append the result to the dst slice
+func (ts *tailSampler) apply(keep bool, spans []request.Span, dst []request.Span) []request.Span {
+	if keep {
+		return append(dst, spans...)
+	}
+	for i := range spans {
+		if spans[i].IgnoreSpan == request.IgnoreMetrics {
+			// ignored both for metrics and traces. No need to forward it
+			continue
+		}
+		spans[i].IgnoreSpan = request.IgnoreTraces
+		dst = append(dst, spans[i])
+	}
+	return dst
+}
+
+// keep returns whether the trace composed by the provided spans is sampled
+func (ts *tailSampler) keep(spans []request.Span) bool {
+	for i := range spans {
+		if ts.matchesPolicy(&spans[i]) {
+			return true
+		}
+	}
+	traceID := spans[0].TraceID
+	if !traceID.IsValid() {
+		// the ratio-based sampler would always keep the zero trace ID
+		return ts.random() < ts.cfg.Policies.Probabilistic
+	}
+	return ts.ratio.ShouldSample(trace.SamplingParameters{TraceID: traceID}).Decision ==
+		trace.RecordAndSample
+}
+
+func (ts *tailSampler) matchesPolicy(s *request.Span) bool {
+	p := &ts.cfg.Policies
+	if p.Errors && request.SpanStatusCode(s) == codes.Error {
+		return true
+	}
+	if p.Latency > 0 {
+		t := s.Timings()
+		if t.End.Sub(t.RequestStart) >= p.Latency {
+			return true
+		}
+	}
+	for _, g := range ts.routes {
+		if g.Match(s.Route) {
+			return true
+		}
+	}
+	for _, g := range ts.services {
+		if g.Match(s.ServiceID.Name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package transform
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	trace2 "go.opentelemetry.io/otel/trace"
+
+	"github.com/grafana/beyla/pkg/internal/request"
+	"github.com/grafana/beyla/pkg/internal/svc"
+	"github.com/grafana/beyla/pkg/internal/testutil"
+)
+
+func traceID(id byte) trace2.TraceID {
+	return trace2.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, id}
+}
+
+func TestTailSampler_InvalidConfig(t *testing.T) {
+	for _, cfg := range []TailSamplingConfig{
+		{Enabled: true, MaxTraces: 10},
+		{Enabled: true, DecisionWait: time.Second},
+		{Enabled: true, DecisionWait: time.Nanosecond, MaxTraces: 10},
+		{Enabled: true, DecisionWait: time.Second, MaxTraces: 10, Policies: TailSamplingPolicies{Probabilistic: 2}},
+		{Enabled: true, DecisionWait: time.Second, MaxTraces: 10, Policies: TailSamplingPolicies{Routes: []string{"/foo/["}}},
+	} {
+		_, err := TailSamplerProvider(&cfg)()
+		assert.Error(t, err)
+	}
+}
+
+func TestTailSampler_Policies(t *testing.T) {
+	ts, err := newTailSampler(&TailSamplingConfig{
+		Enabled:      true,
+		DecisionWait: time.Minute,
+		MaxTraces:    100,
+		Policies: TailSamplingPolicies{
+			Errors:   true,
+			Latency:  time.Second,
+			Routes:   []string{"/admin/*"},
+			Services: []string{"payments-*"},
+		},
+	})
+	require.NoError(t, err)
+	now := time.Now()
+	ts.clock = func() time.Time { return now }
+
+	assert.Empty(t, ts.receive([]request.Span{
+		// healthy trace: sampled out
+		{TraceID: traceID(1), Type: request.EventTypeHTTP, Status: 200, Route: "/users"},
+		// trace with an error in a child span: kept
+		{TraceID: traceID(2), Type: request.EventTypeHTTP, Status: 200, Route: "/users"},
+		{TraceID: traceID(2), Type: request.EventTypeHTTPClient, Status: 503, Route: "/db"},
+		// slow trace: kept
+		{TraceID: traceID(3), Type: request.EventTypeHTTP, Status: 200, RequestStart: 0, End: int64(2 * time.Second)},
+		// trace matching route: kept
+		{TraceID: traceID(4), Type: request.EventTypeHTTP, Status: 200, Route: "/admin/users"},
+		// trace matching service: kept
+		{TraceID: traceID(5), Type: request.EventTypeHTTP, Status: 200, ServiceID: svc.ID{Name: "payments-api"}},
+	}))
+	// nothing is decided before the decision window expires
+	assert.Empty(t, ts.flush(false))
+
+	now = now.Add(2 * time.Minute)
+	decided := ts.flush(false)
+	require.Len(t, decided, 6)
+	assert.Equal(t, request.IgnoreTraces, decided[0].IgnoreSpan)
+	for _, s := range decided[1:] {
+		assert.Zero(t, s.IgnoreSpan, "%+v", s)
+	}
+	assert.Empty(t, ts.pending)
+	assert.Empty(t, ts.order)
+
+	// late spans get the same decision as the rest of the trace
+	late := ts.receive([]request.Span{
+		{TraceID: traceID(1), Type: request.EventTypeHTTP, Status: 500},
+		{TraceID: traceID(2), Type: request.EventTypeHTTP, Status: 200},
+	})
+	require.Len(t, late, 2)
+	assert.Equal(t, request.IgnoreTraces, late[0].IgnoreSpan)
+	assert.Zero(t, late[1].IgnoreSpan)
+}
+
+func TestTailSampler_Probabilistic(t *testing.T) {
+	ts, err := newTailSampler(&TailSamplingConfig{
+		Enabled:      true,
+		DecisionWait: time.Minute,
+		MaxTraces:    100,
+		Policies:     TailSamplingPolicies{Probabilistic: 1},
+	})
+	require.NoError(t, err)
+	ts.receive([]request.Span{{TraceID: traceID(1), Type: request.EventTypeHTTP, Status: 200}})
+	kept := ts.flush(true)
+	require.Len(t, kept, 1)
+	assert.Zero(t, kept[0].IgnoreSpan)
+}
+
+func TestTailSampler_Probabilistic_NoTraceID(t *testing.T) {
+	ts, err := newTailSampler(&TailSamplingConfig{
+		Enabled:      true,
+		DecisionWait: time.Minute,
+		MaxTraces:    100,
+		Policies:     TailSamplingPolicies{Probabilistic: 0.5},
+	})
+	require.NoError(t, err)
+	randoms := []float64{0.2, 0.7, 0.4, 0.9}
+	ts.random = func() float64 {
+		r := randoms[0]
+		randoms = randoms[1:]
+		return r
+	}
+	// spans without trace ID are not always kept, but randomly sampled one by one
+	decided := ts.receive([]request.Span{
+		{Type: request.EventTypeHTTP, Status: 200},
+		{Type: request.EventTypeHTTP, Status: 200},
+		{Type: request.EventTypeHTTP, Status: 200},
+		{Type: request.EventTypeHTTP, Status: 200},
+	})
+	require.Len(t, decided, 4)
+	assert.Zero(t, decided[0].IgnoreSpan)
+	assert.Equal(t, request.IgnoreTraces, decided[1].IgnoreSpan)
+	assert.Zero(t, decided[2].IgnoreSpan)
+	assert.Equal(t, request.IgnoreTraces, decided[3].IgnoreSpan)
+	assert.Empty(t, ts.pending)
+
+	// with the default random source, the zero trace ID is not always sampled
+	ts.random = rand.Float64
+	kept := 0
+	for i := 0; i < 1000; i++ {
+		if ts.keep([]request.Span{{Type: request.EventTypeHTTP, Status: 200}}) {
+			kept++
+		}
+	}
+	assert.Greater(t, kept, 0)
+	assert.Less(t, kept, 1000)
+}
+
+func TestTailSampler_MaxTraces(t *testing.T) {
+	ts, err := newTailSampler(&TailSamplingConfig{
+		Enabled:      true,
+		DecisionWait: time.Minute,
+		MaxTraces:    2,
+	})
+	require.NoError(t, err)
+	// spans already ignored for metrics are completely removed when sampled out
+	assert.Empty(t, ts.receive([]request.Span{
+		{TraceID: traceID(1), IgnoreSpan: request.IgnoreMetrics},
+		{TraceID: traceID(2)},
+		{TraceID: traceID(3)},
+	}))
+	// the oldest trace is decided in advance to make room for the others
+	assert.Empty(t, ts.flush(false))
+	assert.Len(t, ts.pending, 2)
+	assert.Equal(t, []trace2.TraceID{traceID(2), traceID(3)}, ts.order)
+}
+
+func TestTailSampler_Node(t *testing.T) {
+	node, err := TailSamplerProvider(&TailSamplingConfig{
+		Enabled:      true,
+		DecisionWait: 20 * time.Millisecond,
+		MaxTraces:    100,
+		Policies:     TailSamplingPolicies{Errors: true},
+	})()
+	require.NoError(t, err)
+	in, out := make(chan []request.Span, 10), make(chan []request.Span, 10)
+	defer close(in)
+	go node(in, out)
+	in <- []request.Span{
+		{TraceID: traceID(1), Type: request.EventTypeHTTP, Status: 500},
+		{TraceID: traceID(2), Type: request.EventTypeHTTP, Status: 200},
+	}
+	decided := testutil.ReadChannel(t, out, testTimeout)
+	require.Len(t, decided, 2)
+	assert.Zero(t, decided[0].IgnoreSpan)
+	assert.Equal(t, request.IgnoreTraces, decided[1].IgnoreSpan)
+}